@@ -55,6 +55,25 @@ func init() {
 		viper.SetDefault(key, defaultValue)
 	}
 
+	{
+		const (
+			key          = keys.APITokenKeySecret
+			longOpt      = "api-key-secret"
+			envVar       = release.ENVPREFIX + "_CIRCONUS_API_KEY_SECRET"
+			description  = "Circonus API Token Key kubernetes secret (namespace/name/key), watched for rotation"
+			defaultValue = defaults.APITokenKeySecret
+		)
+
+		rootCmd.PersistentFlags().String(longOpt, defaultValue, envDescription(description, envVar))
+		if err := viper.BindPFlag(key, rootCmd.PersistentFlags().Lookup(longOpt)); err != nil {
+			bindFlagError(longOpt, err)
+		}
+		if err := viper.BindEnv(key, envVar); err != nil {
+			bindEnvError(envVar, err)
+		}
+		viper.SetDefault(key, defaultValue)
+	}
+
 	{
 		const (
 			key          = keys.APITokenApp
@@ -112,6 +131,25 @@ func init() {
 		viper.SetDefault(key, defaultValue)
 	}
 
+	{
+		const (
+			key          = keys.APITimeout
+			longOpt      = "api-timeout"
+			envVar       = release.ENVPREFIX + "_CIRCONUS_API_TIMEOUT"
+			description  = "Circonus API operation timeout (0 no timeout)"
+			defaultValue = defaults.APITimeout
+		)
+
+		rootCmd.PersistentFlags().String(longOpt, defaultValue, envDescription(description, envVar))
+		if err := viper.BindPFlag(key, rootCmd.PersistentFlags().Lookup(longOpt)); err != nil {
+			bindFlagError(longOpt, err)
+		}
+		if err := viper.BindEnv(key, envVar); err != nil {
+			bindEnvError(envVar, err)
+		}
+		viper.SetDefault(key, defaultValue)
+	}
+
 	{
 		const (
 			key          = keys.APIDebug
@@ -311,14 +349,12 @@ func init() {
 			key          = keys.DryRun
 			longOpt      = "dry-run"
 			envVar       = release.ENVPREFIX + "_DRY_RUN"
-			description  = "Enable dry run (print metrics to stdout, rather than sending to circonus)"
+			description  = "Enable dry run (collect and write metrics to stdout or --dry-run-output, rather than sending to circonus)"
 			defaultValue = defaults.DryRun
 		)
 
 		rootCmd.PersistentFlags().Bool(longOpt, defaultValue, envDescription(description, envVar))
-		flag := rootCmd.PersistentFlags().Lookup(longOpt)
-		flag.Hidden = true
-		if err := viper.BindPFlag(key, flag); err != nil {
+		if err := viper.BindPFlag(key, rootCmd.PersistentFlags().Lookup(longOpt)); err != nil {
 			bindFlagError(longOpt, err)
 		}
 		if err := viper.BindEnv(key, envVar); err != nil {
@@ -357,26 +393,6 @@ func init() {
 	// 		defaultValue = defaults.DebugSubmissions
 	// 	)
 
-	// 	rootCmd.PersistentFlags().Bool(longOpt, defaultValue, envDescription(description, envVar))
-	// 	flag := rootCmd.PersistentFlags().Lookup(longOpt)
-	// 	flag.Hidden = true
-	// 	if err := viper.BindPFlag(key, flag); err != nil {
-	// 		bindFlagError(longOpt, err)
-	// 	}
-	// 	if err := viper.BindEnv(key, envVar); err != nil {
-	// 		bindEnvError(envVar, err)
-	// 	}
-	// 	viper.SetDefault(key, defaultValue)
-	// }
-	// {
-	// 	const (
-	// 		key          = keys.ConcurrentSubmissions
-	// 		longOpt      = "concurrent-submissions"
-	// 		envVar       = release.ENVPREFIX + "_CONCURRENT_SUBMISSIONS"
-	// 		description  = "Submit metrics concurrently (disable if gaps appear)"
-	// 		defaultValue = defaults.ConcurrentSubmissions
-	// 	)
-
 	// 	rootCmd.PersistentFlags().Bool(longOpt, defaultValue, envDescription(description, envVar))
 	// 	flag := rootCmd.PersistentFlags().Lookup(longOpt)
 	// 	flag.Hidden = true
@@ -428,4 +444,822 @@ func init() {
 		}
 		viper.SetDefault(key, defaultValue)
 	}
+
+	{
+		const (
+			key          = keys.SpoolDir
+			longOpt      = "spool-dir"
+			envVar       = release.ENVPREFIX + "_CIRCONUS_SPOOL_DIR"
+			description  = "Spool failed submissions to directory and replay them when the broker is reachable (blank disables)"
+			defaultValue = defaults.SpoolDir
+		)
+
+		rootCmd.PersistentFlags().String(longOpt, defaultValue, envDescription(description, envVar))
+		if err := viper.BindPFlag(key, rootCmd.PersistentFlags().Lookup(longOpt)); err != nil {
+			bindFlagError(longOpt, err)
+		}
+		if err := viper.BindEnv(key, envVar); err != nil {
+			bindEnvError(envVar, err)
+		}
+		viper.SetDefault(key, defaultValue)
+	}
+
+	{
+		const (
+			key          = keys.SpoolMaxSize
+			longOpt      = "spool-max-size"
+			envVar       = release.ENVPREFIX + "_CIRCONUS_SPOOL_MAX_SIZE"
+			description  = "Max size of the submission spool, oldest submissions are discarded"
+			defaultValue = defaults.SpoolMaxSize
+		)
+
+		rootCmd.PersistentFlags().String(longOpt, defaultValue, envDescription(description, envVar))
+		if err := viper.BindPFlag(key, rootCmd.PersistentFlags().Lookup(longOpt)); err != nil {
+			bindFlagError(longOpt, err)
+		}
+		if err := viper.BindEnv(key, envVar); err != nil {
+			bindEnvError(envVar, err)
+		}
+		viper.SetDefault(key, defaultValue)
+	}
+
+	{
+		const (
+			key          = keys.SubmitRetries
+			longOpt      = "submit-retries"
+			envVar       = release.ENVPREFIX + "_CIRCONUS_SUBMIT_RETRIES"
+			description  = "Max retries of a failed submission"
+			defaultValue = defaults.SubmitRetries
+		)
+
+		rootCmd.PersistentFlags().Uint(longOpt, defaultValue, envDescription(description, envVar))
+		if err := viper.BindPFlag(key, rootCmd.PersistentFlags().Lookup(longOpt)); err != nil {
+			bindFlagError(longOpt, err)
+		}
+		if err := viper.BindEnv(key, envVar); err != nil {
+			bindEnvError(envVar, err)
+		}
+		viper.SetDefault(key, defaultValue)
+	}
+
+	{
+		const (
+			key          = keys.SubmitBackoffMin
+			longOpt      = "submit-backoff-min"
+			envVar       = release.ENVPREFIX + "_CIRCONUS_SUBMIT_BACKOFF_MIN"
+			description  = "Initial wait between submission retries, doubled each retry"
+			defaultValue = defaults.SubmitBackoffMin
+		)
+
+		rootCmd.PersistentFlags().String(longOpt, defaultValue, envDescription(description, envVar))
+		if err := viper.BindPFlag(key, rootCmd.PersistentFlags().Lookup(longOpt)); err != nil {
+			bindFlagError(longOpt, err)
+		}
+		if err := viper.BindEnv(key, envVar); err != nil {
+			bindEnvError(envVar, err)
+		}
+		viper.SetDefault(key, defaultValue)
+	}
+
+	{
+		const (
+			key          = keys.SubmitBackoffMax
+			longOpt      = "submit-backoff-max"
+			envVar       = release.ENVPREFIX + "_CIRCONUS_SUBMIT_BACKOFF_MAX"
+			description  = "Max wait between submission retries"
+			defaultValue = defaults.SubmitBackoffMax
+		)
+
+		rootCmd.PersistentFlags().String(longOpt, defaultValue, envDescription(description, envVar))
+		if err := viper.BindPFlag(key, rootCmd.PersistentFlags().Lookup(longOpt)); err != nil {
+			bindFlagError(longOpt, err)
+		}
+		if err := viper.BindEnv(key, envVar); err != nil {
+			bindEnvError(envVar, err)
+		}
+		viper.SetDefault(key, defaultValue)
+	}
+
+	{
+		const (
+			key          = keys.SubmitJitter
+			longOpt      = "submit-jitter"
+			envVar       = release.ENVPREFIX + "_CIRCONUS_SUBMIT_JITTER"
+			description  = "Randomize the wait between submission retries"
+			defaultValue = defaults.SubmitJitter
+		)
+
+		rootCmd.PersistentFlags().Bool(longOpt, defaultValue, envDescription(description, envVar))
+		if err := viper.BindPFlag(key, rootCmd.PersistentFlags().Lookup(longOpt)); err != nil {
+			bindFlagError(longOpt, err)
+		}
+		if err := viper.BindEnv(key, envVar); err != nil {
+			bindEnvError(envVar, err)
+		}
+		viper.SetDefault(key, defaultValue)
+	}
+
+	{
+		const (
+			key          = keys.SubmitMaxBodySize
+			longOpt      = "submit-max-body-size"
+			envVar       = release.ENVPREFIX + "_CIRCONUS_SUBMIT_MAX_BODY_SIZE"
+			description  = "Split submissions larger than size (e.g. 10MB) into multiple submissions (blank for no limit)"
+			defaultValue = defaults.SubmitMaxBodySize
+		)
+
+		rootCmd.PersistentFlags().String(longOpt, defaultValue, envDescription(description, envVar))
+		if err := viper.BindPFlag(key, rootCmd.PersistentFlags().Lookup(longOpt)); err != nil {
+			bindFlagError(longOpt, err)
+		}
+		if err := viper.BindEnv(key, envVar); err != nil {
+			bindEnvError(envVar, err)
+		}
+		viper.SetDefault(key, defaultValue)
+	}
+
+	{
+		const (
+			key          = keys.DryRunOutput
+			longOpt      = "dry-run-output"
+			envVar       = release.ENVPREFIX + "_DRY_RUN_OUTPUT"
+			description  = "File dry run submissions are written to, one JSON document per submission (blank or - for stdout)"
+			defaultValue = defaults.DryRunOutput
+		)
+
+		rootCmd.PersistentFlags().String(longOpt, defaultValue, envDescription(description, envVar))
+		if err := viper.BindPFlag(key, rootCmd.PersistentFlags().Lookup(longOpt)); err != nil {
+			bindFlagError(longOpt, err)
+		}
+		if err := viper.BindEnv(key, envVar); err != nil {
+			bindEnvError(envVar, err)
+		}
+		viper.SetDefault(key, defaultValue)
+	}
+
+	{
+		const (
+			key          = keys.OTLPEndpoint
+			longOpt      = "otlp-endpoint"
+			envVar       = release.ENVPREFIX + "_OTLP_ENDPOINT"
+			description  = "Export metrics to an OpenTelemetry collector over OTLP/gRPC (host:port, blank disables)"
+			defaultValue = defaults.OTLPEndpoint
+		)
+
+		rootCmd.PersistentFlags().String(longOpt, defaultValue, envDescription(description, envVar))
+		if err := viper.BindPFlag(key, rootCmd.PersistentFlags().Lookup(longOpt)); err != nil {
+			bindFlagError(longOpt, err)
+		}
+		if err := viper.BindEnv(key, envVar); err != nil {
+			bindEnvError(envVar, err)
+		}
+		viper.SetDefault(key, defaultValue)
+	}
+
+	{
+		const (
+			key          = keys.OTLPInsecure
+			longOpt      = "otlp-insecure"
+			envVar       = release.ENVPREFIX + "_OTLP_INSECURE"
+			description  = "Use plaintext, rather than TLS, for the OTLP connection"
+			defaultValue = defaults.OTLPInsecure
+		)
+
+		rootCmd.PersistentFlags().Bool(longOpt, defaultValue, envDescription(description, envVar))
+		if err := viper.BindPFlag(key, rootCmd.PersistentFlags().Lookup(longOpt)); err != nil {
+			bindFlagError(longOpt, err)
+		}
+		if err := viper.BindEnv(key, envVar); err != nil {
+			bindEnvError(envVar, err)
+		}
+		viper.SetDefault(key, defaultValue)
+	}
+
+	{
+		const (
+			key          = keys.OTLPTimeout
+			longOpt      = "otlp-timeout"
+			envVar       = release.ENVPREFIX + "_OTLP_TIMEOUT"
+			description  = "Timeout of each OTLP export"
+			defaultValue = defaults.OTLPTimeout
+		)
+
+		rootCmd.PersistentFlags().String(longOpt, defaultValue, envDescription(description, envVar))
+		if err := viper.BindPFlag(key, rootCmd.PersistentFlags().Lookup(longOpt)); err != nil {
+			bindFlagError(longOpt, err)
+		}
+		if err := viper.BindEnv(key, envVar); err != nil {
+			bindEnvError(envVar, err)
+		}
+		viper.SetDefault(key, defaultValue)
+	}
+
+	{
+		const (
+			key          = keys.RemoteWriteURL
+			longOpt      = "remote-write-url"
+			envVar       = release.ENVPREFIX + "_REMOTE_WRITE_URL"
+			description  = "Export metrics to a prometheus remote write endpoint (blank disables)"
+			defaultValue = defaults.RemoteWriteURL
+		)
+
+		rootCmd.PersistentFlags().String(longOpt, defaultValue, envDescription(description, envVar))
+		if err := viper.BindPFlag(key, rootCmd.PersistentFlags().Lookup(longOpt)); err != nil {
+			bindFlagError(longOpt, err)
+		}
+		if err := viper.BindEnv(key, envVar); err != nil {
+			bindEnvError(envVar, err)
+		}
+		viper.SetDefault(key, defaultValue)
+	}
+
+	{
+		const (
+			key          = keys.RemoteWriteTimeout
+			longOpt      = "remote-write-timeout"
+			envVar       = release.ENVPREFIX + "_REMOTE_WRITE_TIMEOUT"
+			description  = "Timeout of each remote write request"
+			defaultValue = defaults.RemoteWriteTimeout
+		)
+
+		rootCmd.PersistentFlags().String(longOpt, defaultValue, envDescription(description, envVar))
+		if err := viper.BindPFlag(key, rootCmd.PersistentFlags().Lookup(longOpt)); err != nil {
+			bindFlagError(longOpt, err)
+		}
+		if err := viper.BindEnv(key, envVar); err != nil {
+			bindEnvError(envVar, err)
+		}
+		viper.SetDefault(key, defaultValue)
+	}
+
+	{
+		const (
+			key          = keys.RemoteWriteToken
+			longOpt      = "remote-write-bearer-token-file"
+			envVar       = release.ENVPREFIX + "_REMOTE_WRITE_BEARER_TOKEN_FILE"
+			description  = "File containing the bearer token for remote write requests"
+			defaultValue = defaults.RemoteWriteToken
+		)
+
+		rootCmd.PersistentFlags().String(longOpt, defaultValue, envDescription(description, envVar))
+		if err := viper.BindPFlag(key, rootCmd.PersistentFlags().Lookup(longOpt)); err != nil {
+			bindFlagError(longOpt, err)
+		}
+		if err := viper.BindEnv(key, envVar); err != nil {
+			bindEnvError(envVar, err)
+		}
+		viper.SetDefault(key, defaultValue)
+	}
+
+	{
+		const (
+			key          = keys.ForwardURL
+			longOpt      = "forward-url"
+			envVar       = release.ENVPREFIX + "_FORWARD_URL"
+			description  = "Forward metrics to a circonus-agent write endpoint (e.g. http://127.0.0.1:2609/write/kubernetes), rather than a broker"
+			defaultValue = defaults.ForwardURL
+		)
+
+		rootCmd.PersistentFlags().String(longOpt, defaultValue, envDescription(description, envVar))
+		if err := viper.BindPFlag(key, rootCmd.PersistentFlags().Lookup(longOpt)); err != nil {
+			bindFlagError(longOpt, err)
+		}
+		if err := viper.BindEnv(key, envVar); err != nil {
+			bindEnvError(envVar, err)
+		}
+		viper.SetDefault(key, defaultValue)
+	}
+
+	{
+		const (
+			key          = keys.ForwardStatsd
+			longOpt      = "forward-statsd"
+			envVar       = release.ENVPREFIX + "_FORWARD_STATSD"
+			description  = "Forward metrics to a statsd listener (host:port), rather than a broker"
+			defaultValue = defaults.ForwardStatsd
+		)
+
+		rootCmd.PersistentFlags().String(longOpt, defaultValue, envDescription(description, envVar))
+		if err := viper.BindPFlag(key, rootCmd.PersistentFlags().Lookup(longOpt)); err != nil {
+			bindFlagError(longOpt, err)
+		}
+		if err := viper.BindEnv(key, envVar); err != nil {
+			bindEnvError(envVar, err)
+		}
+		viper.SetDefault(key, defaultValue)
+	}
+
+	{
+		const (
+			key          = keys.CheckFilterSync
+			longOpt      = "check-metric-filters-sync"
+			envVar       = release.ENVPREFIX + "_CIRCONUS_CHECK_METRIC_FILTERS_SYNC"
+			description  = "Interval check bundle metric filters are reconciled with the configured metric filters (0 only at startup)"
+			defaultValue = defaults.CheckFilterSync
+		)
+
+		rootCmd.PersistentFlags().String(longOpt, defaultValue, envDescription(description, envVar))
+		if err := viper.BindPFlag(key, rootCmd.PersistentFlags().Lookup(longOpt)); err != nil {
+			bindFlagError(longOpt, err)
+		}
+		if err := viper.BindEnv(key, envVar); err != nil {
+			bindEnvError(envVar, err)
+		}
+		viper.SetDefault(key, defaultValue)
+	}
+
+	{
+		const (
+			key          = keys.CreateDashboards
+			longOpt      = "create-dashboards"
+			envVar       = release.ENVPREFIX + "_CIRCONUS_CREATE_DASHBOARDS"
+			description  = "Create the standard cluster dashboards (cluster overview, node health, workload health), if they do not exist"
+			defaultValue = defaults.CreateDashboards
+		)
+
+		rootCmd.PersistentFlags().Bool(longOpt, defaultValue, envDescription(description, envVar))
+		if err := viper.BindPFlag(key, rootCmd.PersistentFlags().Lookup(longOpt)); err != nil {
+			bindFlagError(longOpt, err)
+		}
+		if err := viper.BindEnv(key, envVar); err != nil {
+			bindEnvError(envVar, err)
+		}
+		viper.SetDefault(key, defaultValue)
+	}
+
+	{
+		const (
+			key          = keys.AlertsCreate
+			longOpt      = "create-alerts"
+			envVar       = release.ENVPREFIX + "_CIRCONUS_ALERTS_CREATE"
+			description  = "Create, or update, the curated alert rulesets (node not ready, pod crashloop, pvc near full, apiserver error rate) for the check"
+			defaultValue = defaults.AlertsCreate
+		)
+
+		rootCmd.PersistentFlags().Bool(longOpt, defaultValue, envDescription(description, envVar))
+		if err := viper.BindPFlag(key, rootCmd.PersistentFlags().Lookup(longOpt)); err != nil {
+			bindFlagError(longOpt, err)
+		}
+		if err := viper.BindEnv(key, envVar); err != nil {
+			bindEnvError(envVar, err)
+		}
+		viper.SetDefault(key, defaultValue)
+	}
+
+	{
+		const (
+			key          = keys.AlertsContactGroup
+			longOpt      = "alerts-contact-group"
+			envVar       = release.ENVPREFIX + "_CIRCONUS_ALERTS_CONTACT_GROUP"
+			description  = "Contact group CID notified by the alert rulesets (blank for none)"
+			defaultValue = defaults.AlertsContactGroup
+		)
+
+		rootCmd.PersistentFlags().String(longOpt, defaultValue, envDescription(description, envVar))
+		if err := viper.BindPFlag(key, rootCmd.PersistentFlags().Lookup(longOpt)); err != nil {
+			bindFlagError(longOpt, err)
+		}
+		if err := viper.BindEnv(key, envVar); err != nil {
+			bindEnvError(envVar, err)
+		}
+		viper.SetDefault(key, defaultValue)
+	}
+
+	{
+		const (
+			key          = keys.AlertsNodeNotReadyWait
+			longOpt      = "alerts-node-not-ready-wait"
+			envVar       = release.ENVPREFIX + "_CIRCONUS_ALERTS_NODE_NOT_READY_WAIT"
+			description  = "Minutes a node is not ready before alerting"
+			defaultValue = defaults.AlertsNodeNotReadyWait
+		)
+
+		rootCmd.PersistentFlags().Uint(longOpt, defaultValue, envDescription(description, envVar))
+		if err := viper.BindPFlag(key, rootCmd.PersistentFlags().Lookup(longOpt)); err != nil {
+			bindFlagError(longOpt, err)
+		}
+		if err := viper.BindEnv(key, envVar); err != nil {
+			bindEnvError(envVar, err)
+		}
+		viper.SetDefault(key, defaultValue)
+	}
+
+	{
+		const (
+			key          = keys.AlertsCrashLoopWait
+			longOpt      = "alerts-crashloop-wait"
+			envVar       = release.ENVPREFIX + "_CIRCONUS_ALERTS_CRASHLOOP_WAIT"
+			description  = "Minutes a container is in crashloop backoff before alerting"
+			defaultValue = defaults.AlertsCrashLoopWait
+		)
+
+		rootCmd.PersistentFlags().Uint(longOpt, defaultValue, envDescription(description, envVar))
+		if err := viper.BindPFlag(key, rootCmd.PersistentFlags().Lookup(longOpt)); err != nil {
+			bindFlagError(longOpt, err)
+		}
+		if err := viper.BindEnv(key, envVar); err != nil {
+			bindEnvError(envVar, err)
+		}
+		viper.SetDefault(key, defaultValue)
+	}
+
+	{
+		const (
+			key          = keys.AlertsPVCUsedPercent
+			longOpt      = "alerts-pvc-used-percent"
+			envVar       = release.ENVPREFIX + "_CIRCONUS_ALERTS_PVC_USED_PERCENT"
+			description  = "Persistent volume claim used percent alert threshold"
+			defaultValue = defaults.AlertsPVCUsedPercent
+		)
+
+		rootCmd.PersistentFlags().Float64(longOpt, defaultValue, envDescription(description, envVar))
+		if err := viper.BindPFlag(key, rootCmd.PersistentFlags().Lookup(longOpt)); err != nil {
+			bindFlagError(longOpt, err)
+		}
+		if err := viper.BindEnv(key, envVar); err != nil {
+			bindEnvError(envVar, err)
+		}
+		viper.SetDefault(key, defaultValue)
+	}
+
+	{
+		const (
+			key          = keys.AlertsAPIServerErrorRate
+			longOpt      = "alerts-apiserver-error-rate"
+			envVar       = release.ENVPREFIX + "_CIRCONUS_ALERTS_APISERVER_ERROR_RATE"
+			description  = "Api-server 5xx responses per second alert threshold"
+			defaultValue = defaults.AlertsAPIServerErrorRate
+		)
+
+		rootCmd.PersistentFlags().Float64(longOpt, defaultValue, envDescription(description, envVar))
+		if err := viper.BindPFlag(key, rootCmd.PersistentFlags().Lookup(longOpt)); err != nil {
+			bindFlagError(longOpt, err)
+		}
+		if err := viper.BindEnv(key, envVar); err != nil {
+			bindEnvError(envVar, err)
+		}
+		viper.SetDefault(key, defaultValue)
+	}
+
+	{
+		const (
+			key          = keys.CheckBrokerSelect
+			longOpt      = "check-broker-select"
+			envVar       = release.ENVPREFIX + "_CIRCONUS_CHECK_BROKER_SELECT"
+			description  = "Ordered list of broker selectors (tag:<category:value>,region:<region>,latency) used when creating a check, falls back to broker cid"
+			defaultValue = defaults.CheckBrokerSelect
+		)
+
+		rootCmd.PersistentFlags().String(longOpt, defaultValue, envDescription(description, envVar))
+		if err := viper.BindPFlag(key, rootCmd.PersistentFlags().Lookup(longOpt)); err != nil {
+			bindFlagError(longOpt, err)
+		}
+		if err := viper.BindEnv(key, envVar); err != nil {
+			bindEnvError(envVar, err)
+		}
+		viper.SetDefault(key, defaultValue)
+	}
+
+	{
+		const (
+			key          = keys.ProxyURL
+			longOpt      = "proxy-url"
+			envVar       = release.ENVPREFIX + "_CIRCONUS_PROXY_URL"
+			description  = "Proxy (http, https, socks5) for circonus api requests and broker submissions (blank uses HTTPS_PROXY)"
+			defaultValue = defaults.ProxyURL
+		)
+
+		rootCmd.PersistentFlags().String(longOpt, defaultValue, envDescription(description, envVar))
+		if err := viper.BindPFlag(key, rootCmd.PersistentFlags().Lookup(longOpt)); err != nil {
+			bindFlagError(longOpt, err)
+		}
+		if err := viper.BindEnv(key, envVar); err != nil {
+			bindEnvError(envVar, err)
+		}
+		viper.SetDefault(key, defaultValue)
+	}
+
+	{
+		const (
+			key          = keys.ProxyNoProxy
+			longOpt      = "no-proxy"
+			envVar       = release.ENVPREFIX + "_CIRCONUS_NO_PROXY"
+			description  = "Comma delimited hosts, domains, and cidrs not proxied (in-cluster hosts are always added)"
+			defaultValue = defaults.ProxyNoProxy
+		)
+
+		rootCmd.PersistentFlags().String(longOpt, defaultValue, envDescription(description, envVar))
+		if err := viper.BindPFlag(key, rootCmd.PersistentFlags().Lookup(longOpt)); err != nil {
+			bindFlagError(longOpt, err)
+		}
+		if err := viper.BindEnv(key, envVar); err != nil {
+			bindEnvError(envVar, err)
+		}
+		viper.SetDefault(key, defaultValue)
+	}
+
+	{
+		const (
+			key          = keys.CheckBrokerCert
+			longOpt      = "check-broker-cert-file"
+			envVar       = release.ENVPREFIX + "_CIRCONUS_CHECK_BROKER_CERT_FILE"
+			description  = "Client certificate file for brokers requiring mutual TLS (reloaded when changed)"
+			defaultValue = defaults.CheckBrokerCert
+		)
+
+		rootCmd.PersistentFlags().String(longOpt, defaultValue, envDescription(description, envVar))
+		if err := viper.BindPFlag(key, rootCmd.PersistentFlags().Lookup(longOpt)); err != nil {
+			bindFlagError(longOpt, err)
+		}
+		if err := viper.BindEnv(key, envVar); err != nil {
+			bindEnvError(envVar, err)
+		}
+		viper.SetDefault(key, defaultValue)
+	}
+
+	{
+		const (
+			key          = keys.CheckBrokerKey
+			longOpt      = "check-broker-key-file"
+			envVar       = release.ENVPREFIX + "_CIRCONUS_CHECK_BROKER_KEY_FILE"
+			description  = "Client key file for brokers requiring mutual TLS (reloaded when changed)"
+			defaultValue = defaults.CheckBrokerKey
+		)
+
+		rootCmd.PersistentFlags().String(longOpt, defaultValue, envDescription(description, envVar))
+		if err := viper.BindPFlag(key, rootCmd.PersistentFlags().Lookup(longOpt)); err != nil {
+			bindFlagError(longOpt, err)
+		}
+		if err := viper.BindEnv(key, envVar); err != nil {
+			bindEnvError(envVar, err)
+		}
+		viper.SetDefault(key, defaultValue)
+	}
+
+	{
+		const (
+			key          = keys.SubmitWorkers
+			longOpt      = "submit-workers"
+			envVar       = release.ENVPREFIX + "_CIRCONUS_SUBMIT_WORKERS"
+			description  = "Submission workers, max in-flight submissions"
+			defaultValue = defaults.SubmitWorkers
+		)
+
+		rootCmd.PersistentFlags().Uint(longOpt, defaultValue, envDescription(description, envVar))
+		if err := viper.BindPFlag(key, rootCmd.PersistentFlags().Lookup(longOpt)); err != nil {
+			bindFlagError(longOpt, err)
+		}
+		if err := viper.BindEnv(key, envVar); err != nil {
+			bindEnvError(envVar, err)
+		}
+		viper.SetDefault(key, defaultValue)
+	}
+
+	{
+		const (
+			key          = keys.SubmitTimeout
+			longOpt      = "submit-timeout"
+			envVar       = release.ENVPREFIX + "_CIRCONUS_SUBMIT_TIMEOUT"
+			description  = "Per-submission timeout, including retries (0 for no timeout)"
+			defaultValue = defaults.SubmitTimeout
+		)
+
+		rootCmd.PersistentFlags().String(longOpt, defaultValue, envDescription(description, envVar))
+		if err := viper.BindPFlag(key, rootCmd.PersistentFlags().Lookup(longOpt)); err != nil {
+			bindFlagError(longOpt, err)
+		}
+		if err := viper.BindEnv(key, envVar); err != nil {
+			bindEnvError(envVar, err)
+		}
+		viper.SetDefault(key, defaultValue)
+	}
+
+	{
+		const (
+			key          = keys.MaxMetricStreams
+			longOpt      = "max-metric-streams"
+			envVar       = release.ENVPREFIX + "_CIRCONUS_MAX_METRIC_STREAMS"
+			description  = "Max unique metric streams per collection interval, new streams over the limit are dropped (0 for no limit)"
+			defaultValue = defaults.MaxMetricStreams
+		)
+
+		rootCmd.PersistentFlags().Uint(longOpt, defaultValue, envDescription(description, envVar))
+		if err := viper.BindPFlag(key, rootCmd.PersistentFlags().Lookup(longOpt)); err != nil {
+			bindFlagError(longOpt, err)
+		}
+		if err := viper.BindEnv(key, envVar); err != nil {
+			bindEnvError(envVar, err)
+		}
+		viper.SetDefault(key, defaultValue)
+	}
+
+	{
+		const (
+			key          = keys.DeadLetterDir
+			longOpt      = "dead-letter-dir"
+			envVar       = release.ENVPREFIX + "_CIRCONUS_DEAD_LETTER_DIR"
+			description  = "Directory failed submissions are written to for manual replay (blank disables)"
+			defaultValue = defaults.DeadLetterDir
+		)
+
+		rootCmd.PersistentFlags().String(longOpt, defaultValue, envDescription(description, envVar))
+		if err := viper.BindPFlag(key, rootCmd.PersistentFlags().Lookup(longOpt)); err != nil {
+			bindFlagError(longOpt, err)
+		}
+		if err := viper.BindEnv(key, envVar); err != nil {
+			bindEnvError(envVar, err)
+		}
+		viper.SetDefault(key, defaultValue)
+	}
+
+	{
+		const (
+			key          = keys.DeadLetterGzip
+			longOpt      = "dead-letter-compress"
+			envVar       = release.ENVPREFIX + "_CIRCONUS_DEAD_LETTER_COMPRESS"
+			description  = "Gzip compress dead-letter payloads"
+			defaultValue = defaults.DeadLetterGzip
+		)
+
+		rootCmd.PersistentFlags().Bool(longOpt, defaultValue, envDescription(description, envVar))
+		if err := viper.BindPFlag(key, rootCmd.PersistentFlags().Lookup(longOpt)); err != nil {
+			bindFlagError(longOpt, err)
+		}
+		if err := viper.BindEnv(key, envVar); err != nil {
+			bindEnvError(envVar, err)
+		}
+		viper.SetDefault(key, defaultValue)
+	}
+
+	{
+		const (
+			key          = keys.TextOnChange
+			longOpt      = "text-on-change"
+			envVar       = release.ENVPREFIX + "_CIRCONUS_TEXT_ON_CHANGE"
+			description  = "Send text metrics only when their value changes"
+			defaultValue = defaults.TextOnChange
+		)
+
+		rootCmd.PersistentFlags().Bool(longOpt, defaultValue, envDescription(description, envVar))
+		if err := viper.BindPFlag(key, rootCmd.PersistentFlags().Lookup(longOpt)); err != nil {
+			bindFlagError(longOpt, err)
+		}
+		if err := viper.BindEnv(key, envVar); err != nil {
+			bindEnvError(envVar, err)
+		}
+		viper.SetDefault(key, defaultValue)
+	}
+
+	{
+		const (
+			key          = keys.TextResend
+			longOpt      = "text-resend"
+			envVar       = release.ENVPREFIX + "_CIRCONUS_TEXT_RESEND"
+			description  = "Interval unchanged text metrics are resent (0 never resends)"
+			defaultValue = defaults.TextResend
+		)
+
+		rootCmd.PersistentFlags().String(longOpt, defaultValue, envDescription(description, envVar))
+		if err := viper.BindPFlag(key, rootCmd.PersistentFlags().Lookup(longOpt)); err != nil {
+			bindFlagError(longOpt, err)
+		}
+		if err := viper.BindEnv(key, envVar); err != nil {
+			bindEnvError(envVar, err)
+		}
+		viper.SetDefault(key, defaultValue)
+	}
+
+	{
+		const (
+			key          = keys.CounterDeltas
+			longOpt      = "counter-deltas"
+			envVar       = release.ENVPREFIX + "_CIRCONUS_COUNTER_DELTAS"
+			description  = "Submit prometheus counters as the change since the previous collection"
+			defaultValue = defaults.CounterDeltas
+		)
+
+		rootCmd.PersistentFlags().Bool(longOpt, defaultValue, envDescription(description, envVar))
+		if err := viper.BindPFlag(key, rootCmd.PersistentFlags().Lookup(longOpt)); err != nil {
+			bindFlagError(longOpt, err)
+		}
+		if err := viper.BindEnv(key, envVar); err != nil {
+			bindEnvError(envVar, err)
+		}
+		viper.SetDefault(key, defaultValue)
+	}
+
+	{
+		const (
+			key          = keys.SelfCheckEnable
+			longOpt      = "self-check"
+			envVar       = release.ENVPREFIX + "_CIRCONUS_SELF_CHECK"
+			description  = "Send the agent's own metrics to a dedicated check"
+			defaultValue = defaults.SelfCheckEnable
+		)
+
+		rootCmd.PersistentFlags().Bool(longOpt, defaultValue, envDescription(description, envVar))
+		if err := viper.BindPFlag(key, rootCmd.PersistentFlags().Lookup(longOpt)); err != nil {
+			bindFlagError(longOpt, err)
+		}
+		if err := viper.BindEnv(key, envVar); err != nil {
+			bindEnvError(envVar, err)
+		}
+		viper.SetDefault(key, defaultValue)
+	}
+
+	{
+		const (
+			key          = keys.SelfCheckTarget
+			longOpt      = "self-check-target"
+			envVar       = release.ENVPREFIX + "_CIRCONUS_SELF_CHECK_TARGET"
+			description  = "Target of the dedicated agent metrics check (blank, the check target with an _agent suffix)"
+			defaultValue = defaults.SelfCheckTarget
+		)
+
+		rootCmd.PersistentFlags().String(longOpt, defaultValue, envDescription(description, envVar))
+		if err := viper.BindPFlag(key, rootCmd.PersistentFlags().Lookup(longOpt)); err != nil {
+			bindFlagError(longOpt, err)
+		}
+		if err := viper.BindEnv(key, envVar); err != nil {
+			bindEnvError(envVar, err)
+		}
+		viper.SetDefault(key, defaultValue)
+	}
+
+	{
+		const (
+			key          = keys.SelfCheckBundleCID
+			longOpt      = "self-check-bundle-cid"
+			envVar       = release.ENVPREFIX + "_CIRCONUS_SELF_CHECK_BUNDLE_CID"
+			description  = "Check bundle cid of the dedicated agent metrics check"
+			defaultValue = defaults.SelfCheckBundleCID
+		)
+
+		rootCmd.PersistentFlags().String(longOpt, defaultValue, envDescription(description, envVar))
+		if err := viper.BindPFlag(key, rootCmd.PersistentFlags().Lookup(longOpt)); err != nil {
+			bindFlagError(longOpt, err)
+		}
+		if err := viper.BindEnv(key, envVar); err != nil {
+			bindEnvError(envVar, err)
+		}
+		viper.SetDefault(key, defaultValue)
+	}
+
+	{
+		const (
+			key          = keys.SubmitBudget
+			longOpt      = "submit-budget"
+			envVar       = release.ENVPREFIX + "_CIRCONUS_SUBMIT_BUDGET"
+			description  = "Time allowed for the submissions of a collection interval, payloads not submitted in time are spooled (0 no budget)"
+			defaultValue = defaults.SubmitBudget
+		)
+
+		rootCmd.PersistentFlags().String(longOpt, defaultValue, envDescription(description, envVar))
+		if err := viper.BindPFlag(key, rootCmd.PersistentFlags().Lookup(longOpt)); err != nil {
+			bindFlagError(longOpt, err)
+		}
+		if err := viper.BindEnv(key, envVar); err != nil {
+			bindEnvError(envVar, err)
+		}
+		viper.SetDefault(key, defaultValue)
+	}
+
+	{
+		const (
+			key          = keys.SubmitCompression
+			longOpt      = "submit-compression"
+			envVar       = release.ENVPREFIX + "_CIRCONUS_SUBMIT_COMPRESSION"
+			description  = "Submission compression (gzip, zstd, none), zstd falls back to gzip if the broker does not support it"
+			defaultValue = defaults.SubmitCompression
+		)
+
+		rootCmd.PersistentFlags().String(longOpt, defaultValue, envDescription(description, envVar))
+		if err := viper.BindPFlag(key, rootCmd.PersistentFlags().Lookup(longOpt)); err != nil {
+			bindFlagError(longOpt, err)
+		}
+		if err := viper.BindEnv(key, envVar); err != nil {
+			bindEnvError(envVar, err)
+		}
+		viper.SetDefault(key, defaultValue)
+	}
+
+	{
+		const (
+			key          = keys.SubmitGzipLevel
+			longOpt      = "submit-gzip-level"
+			envVar       = release.ENVPREFIX + "_CIRCONUS_SUBMIT_GZIP_LEVEL"
+			description  = "Submission gzip compression level (1 fastest - 9 smallest, 0 gzip default)"
+			defaultValue = defaults.SubmitGzipLevel
+		)
+
+		rootCmd.PersistentFlags().Uint(longOpt, defaultValue, envDescription(description, envVar))
+		if err := viper.BindPFlag(key, rootCmd.PersistentFlags().Lookup(longOpt)); err != nil {
+			bindFlagError(longOpt, err)
+		}
+		if err := viper.BindEnv(key, envVar); err != nil {
+			bindEnvError(envVar, err)
+		}
+		viper.SetDefault(key, defaultValue)
+	}
+
 }
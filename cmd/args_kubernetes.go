@@ -264,6 +264,25 @@ func init() {
 		viper.SetDefault(key, defaultValue)
 	}
 
+	{
+		const (
+			key          = keys.K8SPodSelector
+			longOpt      = "k8s-pod-selector"
+			envVar       = release.ENVPREFIX + "_K8S_POD_SELECTOR"
+			description  = "Kubernetes pod label selector expression, only matching pods are collected"
+			defaultValue = defaults.K8SPodSelector
+		)
+
+		rootCmd.PersistentFlags().String(longOpt, defaultValue, envDescription(description, envVar))
+		if err := viper.BindPFlag(key, rootCmd.PersistentFlags().Lookup(longOpt)); err != nil {
+			bindFlagError(longOpt, err)
+		}
+		if err := viper.BindEnv(key, envVar); err != nil {
+			bindEnvError(envVar, err)
+		}
+		viper.SetDefault(key, defaultValue)
+	}
+
 	{
 		const (
 			key          = keys.K8SEnableNodeStats
@@ -302,6 +321,63 @@ func init() {
 		viper.SetDefault(key, defaultValue)
 	}
 
+	{
+		const (
+			key          = keys.K8SEnableKubeletOperationalMetrics
+			longOpt      = "k8s-enable-kubelet-operational-metrics"
+			envVar       = release.ENVPREFIX + "_K8S_ENABLE_KUBELET_OPERATIONAL_METRICS"
+			description  = "Kubernetes enable collection of kubelet operational metrics (pleg relist, runtime operation errors, pod start duration)"
+			defaultValue = defaults.K8SEnableKubeletOperationalMetrics
+		)
+
+		rootCmd.PersistentFlags().Bool(longOpt, defaultValue, envDescription(description, envVar))
+		if err := viper.BindPFlag(key, rootCmd.PersistentFlags().Lookup(longOpt)); err != nil {
+			bindFlagError(longOpt, err)
+		}
+		if err := viper.BindEnv(key, envVar); err != nil {
+			bindEnvError(envVar, err)
+		}
+		viper.SetDefault(key, defaultValue)
+	}
+
+	{
+		const (
+			key          = keys.K8SEnableVolumeStats
+			longOpt      = "k8s-enable-volume-stats"
+			envVar       = release.ENVPREFIX + "_K8S_ENABLE_VOLUME_STATS"
+			description  = "Kubernetes enable collection of persistent volume claim usage from kubelet /stats/summary (requires node stats)"
+			defaultValue = defaults.K8SEnableVolumeStats
+		)
+
+		rootCmd.PersistentFlags().Bool(longOpt, defaultValue, envDescription(description, envVar))
+		if err := viper.BindPFlag(key, rootCmd.PersistentFlags().Lookup(longOpt)); err != nil {
+			bindFlagError(longOpt, err)
+		}
+		if err := viper.BindEnv(key, envVar); err != nil {
+			bindEnvError(envVar, err)
+		}
+		viper.SetDefault(key, defaultValue)
+	}
+
+	{
+		const (
+			key          = keys.K8SEnableNodeNetworkSaturation
+			longOpt      = "k8s-enable-node-network-saturation"
+			envVar       = release.ENVPREFIX + "_K8S_ENABLE_NODE_NETWORK_SATURATION"
+			description  = "Kubernetes enable collection of node network packets, drops, and sockets from kubelet /metrics/cadvisor"
+			defaultValue = defaults.K8SEnableNodeNetworkSaturation
+		)
+
+		rootCmd.PersistentFlags().Bool(longOpt, defaultValue, envDescription(description, envVar))
+		if err := viper.BindPFlag(key, rootCmd.PersistentFlags().Lookup(longOpt)); err != nil {
+			bindFlagError(longOpt, err)
+		}
+		if err := viper.BindEnv(key, envVar); err != nil {
+			bindEnvError(envVar, err)
+		}
+		viper.SetDefault(key, defaultValue)
+	}
+
 	{
 		const (
 			key          = keys.K8SEnableCadvisorMetrics
@@ -321,6 +397,25 @@ func init() {
 		viper.SetDefault(key, defaultValue)
 	}
 
+	{
+		const (
+			key          = keys.K8SCadvisorMetricsFilter
+			longOpt      = "k8s-cadvisor-metrics-filter"
+			envVar       = release.ENVPREFIX + "_K8S_CADVISOR_METRICS_FILTER"
+			description  = "Regular expression of kubelet cadvisor metric families to collect (blank for all)"
+			defaultValue = defaults.K8SCadvisorMetricsFilter
+		)
+
+		rootCmd.PersistentFlags().String(longOpt, defaultValue, envDescription(description, envVar))
+		if err := viper.BindPFlag(key, rootCmd.PersistentFlags().Lookup(longOpt)); err != nil {
+			bindFlagError(longOpt, err)
+		}
+		if err := viper.BindEnv(key, envVar); err != nil {
+			bindEnvError(envVar, err)
+		}
+		viper.SetDefault(key, defaultValue)
+	}
+
 	{
 		const (
 			key          = keys.K8SEnableKubeDNSMetrics
@@ -397,6 +492,44 @@ func init() {
 		viper.SetDefault(key, defaultValue)
 	}
 
+	{
+		const (
+			key          = keys.K8SMetricPrefix
+			longOpt      = "k8s-metric-prefix"
+			envVar       = release.ENVPREFIX + "_K8S_METRIC_PREFIX"
+			description  = "Prefix added to all metric names submitted for the cluster (e.g. k8s`)"
+			defaultValue = defaults.K8SMetricPrefix
+		)
+
+		rootCmd.PersistentFlags().String(longOpt, defaultValue, envDescription(description, envVar))
+		if err := viper.BindPFlag(key, rootCmd.PersistentFlags().Lookup(longOpt)); err != nil {
+			bindFlagError(longOpt, err)
+		}
+		if err := viper.BindEnv(key, envVar); err != nil {
+			bindEnvError(envVar, err)
+		}
+		viper.SetDefault(key, defaultValue)
+	}
+
+	{
+		const (
+			key          = keys.K8SConfigResource
+			longOpt      = "k8s-config-resource"
+			envVar       = release.ENVPREFIX + "_K8S_CONFIG_RESOURCE"
+			description  = "CirconusCollection custom resource (namespace/name) collection settings are read from, changes are applied without a restart"
+			defaultValue = defaults.K8SConfigResource
+		)
+
+		rootCmd.PersistentFlags().String(longOpt, defaultValue, envDescription(description, envVar))
+		if err := viper.BindPFlag(key, rootCmd.PersistentFlags().Lookup(longOpt)); err != nil {
+			bindFlagError(longOpt, err)
+		}
+		if err := viper.BindEnv(key, envVar); err != nil {
+			bindEnvError(envVar, err)
+		}
+		viper.SetDefault(key, defaultValue)
+	}
+
 	{
 		const (
 			key          = keys.K8SIncludeContainers
@@ -454,4 +587,2284 @@ func init() {
 		viper.SetDefault(key, defaultValue)
 	}
 
+	{
+		const (
+			key          = keys.K8SEnableEtcd
+			longOpt      = "k8s-enable-etcd"
+			envVar       = release.ENVPREFIX + "_K8S_ENABLE_ETCD"
+			description  = "Kubernetes enable collection of etcd metrics"
+			defaultValue = defaults.K8SEnableEtcd
+		)
+
+		rootCmd.PersistentFlags().Bool(longOpt, defaultValue, envDescription(description, envVar))
+		if err := viper.BindPFlag(key, rootCmd.PersistentFlags().Lookup(longOpt)); err != nil {
+			bindFlagError(longOpt, err)
+		}
+		if err := viper.BindEnv(key, envVar); err != nil {
+			bindEnvError(envVar, err)
+		}
+		viper.SetDefault(key, defaultValue)
+	}
+
+	{
+		const (
+			key          = keys.K8SEtcdEndpoints
+			longOpt      = "k8s-etcd-endpoints"
+			envVar       = release.ENVPREFIX + "_K8S_ETCD_ENDPOINTS"
+			description  = "Comma separated list of etcd member URLs"
+			defaultValue = defaults.K8SEtcdEndpoints
+		)
+
+		rootCmd.PersistentFlags().String(longOpt, defaultValue, envDescription(description, envVar))
+		if err := viper.BindPFlag(key, rootCmd.PersistentFlags().Lookup(longOpt)); err != nil {
+			bindFlagError(longOpt, err)
+		}
+		if err := viper.BindEnv(key, envVar); err != nil {
+			bindEnvError(envVar, err)
+		}
+		viper.SetDefault(key, defaultValue)
+	}
+
+	{
+		const (
+			key          = keys.K8SEtcdCAFile
+			longOpt      = "k8s-etcd-ca-file"
+			envVar       = release.ENVPREFIX + "_K8S_ETCD_CA_FILE"
+			description  = "etcd CA File"
+			defaultValue = defaults.K8SEtcdCAFile
+		)
+
+		rootCmd.PersistentFlags().String(longOpt, defaultValue, envDescription(description, envVar))
+		if err := viper.BindPFlag(key, rootCmd.PersistentFlags().Lookup(longOpt)); err != nil {
+			bindFlagError(longOpt, err)
+		}
+		if err := viper.BindEnv(key, envVar); err != nil {
+			bindEnvError(envVar, err)
+		}
+		viper.SetDefault(key, defaultValue)
+	}
+
+	{
+		const (
+			key          = keys.K8SEtcdCertFile
+			longOpt      = "k8s-etcd-cert-file"
+			envVar       = release.ENVPREFIX + "_K8S_ETCD_CERT_FILE"
+			description  = "etcd client certificate file"
+			defaultValue = defaults.K8SEtcdCertFile
+		)
+
+		rootCmd.PersistentFlags().String(longOpt, defaultValue, envDescription(description, envVar))
+		if err := viper.BindPFlag(key, rootCmd.PersistentFlags().Lookup(longOpt)); err != nil {
+			bindFlagError(longOpt, err)
+		}
+		if err := viper.BindEnv(key, envVar); err != nil {
+			bindEnvError(envVar, err)
+		}
+		viper.SetDefault(key, defaultValue)
+	}
+
+	{
+		const (
+			key          = keys.K8SEtcdKeyFile
+			longOpt      = "k8s-etcd-key-file"
+			envVar       = release.ENVPREFIX + "_K8S_ETCD_KEY_FILE"
+			description  = "etcd client key file"
+			defaultValue = defaults.K8SEtcdKeyFile
+		)
+
+		rootCmd.PersistentFlags().String(longOpt, defaultValue, envDescription(description, envVar))
+		if err := viper.BindPFlag(key, rootCmd.PersistentFlags().Lookup(longOpt)); err != nil {
+			bindFlagError(longOpt, err)
+		}
+		if err := viper.BindEnv(key, envVar); err != nil {
+			bindEnvError(envVar, err)
+		}
+		viper.SetDefault(key, defaultValue)
+	}
+
+	{
+		const (
+			key          = keys.K8SEnableAPIServer
+			longOpt      = "k8s-enable-api-server"
+			envVar       = release.ENVPREFIX + "_K8S_ENABLE_API_SERVER"
+			description  = "Kubernetes enable collection of kube-apiserver metrics"
+			defaultValue = defaults.K8SEnableAPIServer
+		)
+
+		rootCmd.PersistentFlags().Bool(longOpt, defaultValue, envDescription(description, envVar))
+		if err := viper.BindPFlag(key, rootCmd.PersistentFlags().Lookup(longOpt)); err != nil {
+			bindFlagError(longOpt, err)
+		}
+		if err := viper.BindEnv(key, envVar); err != nil {
+			bindEnvError(envVar, err)
+		}
+		viper.SetDefault(key, defaultValue)
+	}
+
+	{
+		const (
+			key          = keys.K8SEnableKubeScheduler
+			longOpt      = "k8s-enable-kube-scheduler"
+			envVar       = release.ENVPREFIX + "_K8S_ENABLE_KUBE_SCHEDULER"
+			description  = "Kubernetes enable collection of kube-scheduler metrics"
+			defaultValue = defaults.K8SEnableKubeScheduler
+		)
+
+		rootCmd.PersistentFlags().Bool(longOpt, defaultValue, envDescription(description, envVar))
+		if err := viper.BindPFlag(key, rootCmd.PersistentFlags().Lookup(longOpt)); err != nil {
+			bindFlagError(longOpt, err)
+		}
+		if err := viper.BindEnv(key, envVar); err != nil {
+			bindEnvError(envVar, err)
+		}
+		viper.SetDefault(key, defaultValue)
+	}
+
+	{
+		const (
+			key          = keys.K8SKubeSchedulerNamespace
+			longOpt      = "k8s-kube-scheduler-namespace"
+			envVar       = release.ENVPREFIX + "_K8S_KUBE_SCHEDULER_NAMESPACE"
+			description  = "Namespace of kube-scheduler pods"
+			defaultValue = defaults.K8SKubeSchedulerNamespace
+		)
+
+		rootCmd.PersistentFlags().String(longOpt, defaultValue, envDescription(description, envVar))
+		if err := viper.BindPFlag(key, rootCmd.PersistentFlags().Lookup(longOpt)); err != nil {
+			bindFlagError(longOpt, err)
+		}
+		if err := viper.BindEnv(key, envVar); err != nil {
+			bindEnvError(envVar, err)
+		}
+		viper.SetDefault(key, defaultValue)
+	}
+
+	{
+		const (
+			key          = keys.K8SKubeSchedulerSelector
+			longOpt      = "k8s-kube-scheduler-selector"
+			envVar       = release.ENVPREFIX + "_K8S_KUBE_SCHEDULER_SELECTOR"
+			description  = "Label selector for kube-scheduler pods"
+			defaultValue = defaults.K8SKubeSchedulerSelector
+		)
+
+		rootCmd.PersistentFlags().String(longOpt, defaultValue, envDescription(description, envVar))
+		if err := viper.BindPFlag(key, rootCmd.PersistentFlags().Lookup(longOpt)); err != nil {
+			bindFlagError(longOpt, err)
+		}
+		if err := viper.BindEnv(key, envVar); err != nil {
+			bindEnvError(envVar, err)
+		}
+		viper.SetDefault(key, defaultValue)
+	}
+
+	{
+		const (
+			key          = keys.K8SKubeSchedulerPort
+			longOpt      = "k8s-kube-scheduler-port"
+			envVar       = release.ENVPREFIX + "_K8S_KUBE_SCHEDULER_PORT"
+			description  = "kube-scheduler metrics port (prefix with 'https:' for https)"
+			defaultValue = defaults.K8SKubeSchedulerPort
+		)
+
+		rootCmd.PersistentFlags().String(longOpt, defaultValue, envDescription(description, envVar))
+		if err := viper.BindPFlag(key, rootCmd.PersistentFlags().Lookup(longOpt)); err != nil {
+			bindFlagError(longOpt, err)
+		}
+		if err := viper.BindEnv(key, envVar); err != nil {
+			bindEnvError(envVar, err)
+		}
+		viper.SetDefault(key, defaultValue)
+	}
+
+	{
+		const (
+			key          = keys.K8SKubeSchedulerEndpoint
+			longOpt      = "k8s-kube-scheduler-endpoint"
+			envVar       = release.ENVPREFIX + "_K8S_KUBE_SCHEDULER_ENDPOINT"
+			description  = "kube-scheduler metrics URL, disables pod discovery via API proxy"
+			defaultValue = defaults.K8SKubeSchedulerEndpoint
+		)
+
+		rootCmd.PersistentFlags().String(longOpt, defaultValue, envDescription(description, envVar))
+		if err := viper.BindPFlag(key, rootCmd.PersistentFlags().Lookup(longOpt)); err != nil {
+			bindFlagError(longOpt, err)
+		}
+		if err := viper.BindEnv(key, envVar); err != nil {
+			bindEnvError(envVar, err)
+		}
+		viper.SetDefault(key, defaultValue)
+	}
+
+	{
+		const (
+			key          = keys.K8SEnableKubeControllerManager
+			longOpt      = "k8s-enable-kube-controller-manager"
+			envVar       = release.ENVPREFIX + "_K8S_ENABLE_KUBE_CONTROLLER_MANAGER"
+			description  = "Kubernetes enable collection of kube-controller-manager metrics"
+			defaultValue = defaults.K8SEnableKubeControllerManager
+		)
+
+		rootCmd.PersistentFlags().Bool(longOpt, defaultValue, envDescription(description, envVar))
+		if err := viper.BindPFlag(key, rootCmd.PersistentFlags().Lookup(longOpt)); err != nil {
+			bindFlagError(longOpt, err)
+		}
+		if err := viper.BindEnv(key, envVar); err != nil {
+			bindEnvError(envVar, err)
+		}
+		viper.SetDefault(key, defaultValue)
+	}
+
+	{
+		const (
+			key          = keys.K8SKubeControllerManagerNamespace
+			longOpt      = "k8s-kube-controller-manager-namespace"
+			envVar       = release.ENVPREFIX + "_K8S_KUBE_CONTROLLER_MANAGER_NAMESPACE"
+			description  = "Namespace of kube-controller-manager pods"
+			defaultValue = defaults.K8SKubeControllerManagerNamespace
+		)
+
+		rootCmd.PersistentFlags().String(longOpt, defaultValue, envDescription(description, envVar))
+		if err := viper.BindPFlag(key, rootCmd.PersistentFlags().Lookup(longOpt)); err != nil {
+			bindFlagError(longOpt, err)
+		}
+		if err := viper.BindEnv(key, envVar); err != nil {
+			bindEnvError(envVar, err)
+		}
+		viper.SetDefault(key, defaultValue)
+	}
+
+	{
+		const (
+			key          = keys.K8SKubeControllerManagerSelector
+			longOpt      = "k8s-kube-controller-manager-selector"
+			envVar       = release.ENVPREFIX + "_K8S_KUBE_CONTROLLER_MANAGER_SELECTOR"
+			description  = "Label selector for kube-controller-manager pods"
+			defaultValue = defaults.K8SKubeControllerManagerSelector
+		)
+
+		rootCmd.PersistentFlags().String(longOpt, defaultValue, envDescription(description, envVar))
+		if err := viper.BindPFlag(key, rootCmd.PersistentFlags().Lookup(longOpt)); err != nil {
+			bindFlagError(longOpt, err)
+		}
+		if err := viper.BindEnv(key, envVar); err != nil {
+			bindEnvError(envVar, err)
+		}
+		viper.SetDefault(key, defaultValue)
+	}
+
+	{
+		const (
+			key          = keys.K8SKubeControllerManagerPort
+			longOpt      = "k8s-kube-controller-manager-port"
+			envVar       = release.ENVPREFIX + "_K8S_KUBE_CONTROLLER_MANAGER_PORT"
+			description  = "kube-controller-manager metrics port (prefix with 'https:' for https)"
+			defaultValue = defaults.K8SKubeControllerManagerPort
+		)
+
+		rootCmd.PersistentFlags().String(longOpt, defaultValue, envDescription(description, envVar))
+		if err := viper.BindPFlag(key, rootCmd.PersistentFlags().Lookup(longOpt)); err != nil {
+			bindFlagError(longOpt, err)
+		}
+		if err := viper.BindEnv(key, envVar); err != nil {
+			bindEnvError(envVar, err)
+		}
+		viper.SetDefault(key, defaultValue)
+	}
+
+	{
+		const (
+			key          = keys.K8SEnableKubeProxy
+			longOpt      = "k8s-enable-kube-proxy"
+			envVar       = release.ENVPREFIX + "_K8S_ENABLE_KUBE_PROXY"
+			description  = "Kubernetes enable collection of kube-proxy metrics"
+			defaultValue = defaults.K8SEnableKubeProxy
+		)
+
+		rootCmd.PersistentFlags().Bool(longOpt, defaultValue, envDescription(description, envVar))
+		if err := viper.BindPFlag(key, rootCmd.PersistentFlags().Lookup(longOpt)); err != nil {
+			bindFlagError(longOpt, err)
+		}
+		if err := viper.BindEnv(key, envVar); err != nil {
+			bindEnvError(envVar, err)
+		}
+		viper.SetDefault(key, defaultValue)
+	}
+
+	{
+		const (
+			key          = keys.K8SKubeProxyNamespace
+			longOpt      = "k8s-kube-proxy-namespace"
+			envVar       = release.ENVPREFIX + "_K8S_KUBE_PROXY_NAMESPACE"
+			description  = "Namespace of kube-proxy pods"
+			defaultValue = defaults.K8SKubeProxyNamespace
+		)
+
+		rootCmd.PersistentFlags().String(longOpt, defaultValue, envDescription(description, envVar))
+		if err := viper.BindPFlag(key, rootCmd.PersistentFlags().Lookup(longOpt)); err != nil {
+			bindFlagError(longOpt, err)
+		}
+		if err := viper.BindEnv(key, envVar); err != nil {
+			bindEnvError(envVar, err)
+		}
+		viper.SetDefault(key, defaultValue)
+	}
+
+	{
+		const (
+			key          = keys.K8SKubeProxySelector
+			longOpt      = "k8s-kube-proxy-selector"
+			envVar       = release.ENVPREFIX + "_K8S_KUBE_PROXY_SELECTOR"
+			description  = "Label selector for kube-proxy pods"
+			defaultValue = defaults.K8SKubeProxySelector
+		)
+
+		rootCmd.PersistentFlags().String(longOpt, defaultValue, envDescription(description, envVar))
+		if err := viper.BindPFlag(key, rootCmd.PersistentFlags().Lookup(longOpt)); err != nil {
+			bindFlagError(longOpt, err)
+		}
+		if err := viper.BindEnv(key, envVar); err != nil {
+			bindEnvError(envVar, err)
+		}
+		viper.SetDefault(key, defaultValue)
+	}
+
+	{
+		const (
+			key          = keys.K8SKubeProxyPort
+			longOpt      = "k8s-kube-proxy-port"
+			envVar       = release.ENVPREFIX + "_K8S_KUBE_PROXY_PORT"
+			description  = "kube-proxy metrics port"
+			defaultValue = defaults.K8SKubeProxyPort
+		)
+
+		rootCmd.PersistentFlags().String(longOpt, defaultValue, envDescription(description, envVar))
+		if err := viper.BindPFlag(key, rootCmd.PersistentFlags().Lookup(longOpt)); err != nil {
+			bindFlagError(longOpt, err)
+		}
+		if err := viper.BindEnv(key, envVar); err != nil {
+			bindEnvError(envVar, err)
+		}
+		viper.SetDefault(key, defaultValue)
+	}
+
+	{
+		const (
+			key          = keys.K8SEnableNodeLocalDNS
+			longOpt      = "k8s-enable-node-local-dns"
+			envVar       = release.ENVPREFIX + "_K8S_ENABLE_NODE_LOCAL_DNS"
+			description  = "Kubernetes enable collection of NodeLocal DNSCache metrics"
+			defaultValue = defaults.K8SEnableNodeLocalDNS
+		)
+
+		rootCmd.PersistentFlags().Bool(longOpt, defaultValue, envDescription(description, envVar))
+		if err := viper.BindPFlag(key, rootCmd.PersistentFlags().Lookup(longOpt)); err != nil {
+			bindFlagError(longOpt, err)
+		}
+		if err := viper.BindEnv(key, envVar); err != nil {
+			bindEnvError(envVar, err)
+		}
+		viper.SetDefault(key, defaultValue)
+	}
+
+	{
+		const (
+			key          = keys.K8SNodeLocalDNSNamespace
+			longOpt      = "k8s-node-local-dns-namespace"
+			envVar       = release.ENVPREFIX + "_K8S_NODE_LOCAL_DNS_NAMESPACE"
+			description  = "Namespace of node-local-dns pods"
+			defaultValue = defaults.K8SNodeLocalDNSNamespace
+		)
+
+		rootCmd.PersistentFlags().String(longOpt, defaultValue, envDescription(description, envVar))
+		if err := viper.BindPFlag(key, rootCmd.PersistentFlags().Lookup(longOpt)); err != nil {
+			bindFlagError(longOpt, err)
+		}
+		if err := viper.BindEnv(key, envVar); err != nil {
+			bindEnvError(envVar, err)
+		}
+		viper.SetDefault(key, defaultValue)
+	}
+
+	{
+		const (
+			key          = keys.K8SNodeLocalDNSSelector
+			longOpt      = "k8s-node-local-dns-selector"
+			envVar       = release.ENVPREFIX + "_K8S_NODE_LOCAL_DNS_SELECTOR"
+			description  = "Label selector for node-local-dns pods"
+			defaultValue = defaults.K8SNodeLocalDNSSelector
+		)
+
+		rootCmd.PersistentFlags().String(longOpt, defaultValue, envDescription(description, envVar))
+		if err := viper.BindPFlag(key, rootCmd.PersistentFlags().Lookup(longOpt)); err != nil {
+			bindFlagError(longOpt, err)
+		}
+		if err := viper.BindEnv(key, envVar); err != nil {
+			bindEnvError(envVar, err)
+		}
+		viper.SetDefault(key, defaultValue)
+	}
+
+	{
+		const (
+			key          = keys.K8SNodeLocalDNSPort
+			longOpt      = "k8s-node-local-dns-port"
+			envVar       = release.ENVPREFIX + "_K8S_NODE_LOCAL_DNS_PORT"
+			description  = "node-local-dns metrics port"
+			defaultValue = defaults.K8SNodeLocalDNSPort
+		)
+
+		rootCmd.PersistentFlags().String(longOpt, defaultValue, envDescription(description, envVar))
+		if err := viper.BindPFlag(key, rootCmd.PersistentFlags().Lookup(longOpt)); err != nil {
+			bindFlagError(longOpt, err)
+		}
+		if err := viper.BindEnv(key, envVar); err != nil {
+			bindEnvError(envVar, err)
+		}
+		viper.SetDefault(key, defaultValue)
+	}
+
+	{
+		const (
+			key          = keys.K8SEnablePromScrape
+			longOpt      = "k8s-enable-prom-scrape"
+			envVar       = release.ENVPREFIX + "_K8S_ENABLE_PROM_SCRAPE"
+			description  = "Kubernetes enable collection of metrics from pods and services annotated prometheus.io/scrape=true"
+			defaultValue = defaults.K8SEnablePromScrape
+		)
+
+		rootCmd.PersistentFlags().Bool(longOpt, defaultValue, envDescription(description, envVar))
+		if err := viper.BindPFlag(key, rootCmd.PersistentFlags().Lookup(longOpt)); err != nil {
+			bindFlagError(longOpt, err)
+		}
+		if err := viper.BindEnv(key, envVar); err != nil {
+			bindEnvError(envVar, err)
+		}
+		viper.SetDefault(key, defaultValue)
+	}
+
+	{
+		const (
+			key          = keys.K8SPromScrapeNamespace
+			longOpt      = "k8s-prom-scrape-namespace"
+			envVar       = release.ENVPREFIX + "_K8S_PROM_SCRAPE_NAMESPACE"
+			description  = "Namespace for annotation based scraping (blank for all)"
+			defaultValue = defaults.K8SPromScrapeNamespace
+		)
+
+		rootCmd.PersistentFlags().String(longOpt, defaultValue, envDescription(description, envVar))
+		if err := viper.BindPFlag(key, rootCmd.PersistentFlags().Lookup(longOpt)); err != nil {
+			bindFlagError(longOpt, err)
+		}
+		if err := viper.BindEnv(key, envVar); err != nil {
+			bindEnvError(envVar, err)
+		}
+		viper.SetDefault(key, defaultValue)
+	}
+
+	{
+		const (
+			key          = keys.K8SEnablePromMonitors
+			longOpt      = "k8s-enable-prom-monitors"
+			envVar       = release.ENVPREFIX + "_K8S_ENABLE_PROM_MONITORS"
+			description  = "Kubernetes enable collection of metrics from prometheus-operator ServiceMonitor and PodMonitor targets"
+			defaultValue = defaults.K8SEnablePromMonitors
+		)
+
+		rootCmd.PersistentFlags().Bool(longOpt, defaultValue, envDescription(description, envVar))
+		if err := viper.BindPFlag(key, rootCmd.PersistentFlags().Lookup(longOpt)); err != nil {
+			bindFlagError(longOpt, err)
+		}
+		if err := viper.BindEnv(key, envVar); err != nil {
+			bindEnvError(envVar, err)
+		}
+		viper.SetDefault(key, defaultValue)
+	}
+
+	{
+		const (
+			key          = keys.K8SPromMonitorsNamespace
+			longOpt      = "k8s-prom-monitors-namespace"
+			envVar       = release.ENVPREFIX + "_K8S_PROM_MONITORS_NAMESPACE"
+			description  = "Namespace for ServiceMonitor and PodMonitor resources (blank for all)"
+			defaultValue = defaults.K8SPromMonitorsNamespace
+		)
+
+		rootCmd.PersistentFlags().String(longOpt, defaultValue, envDescription(description, envVar))
+		if err := viper.BindPFlag(key, rootCmd.PersistentFlags().Lookup(longOpt)); err != nil {
+			bindFlagError(longOpt, err)
+		}
+		if err := viper.BindEnv(key, envVar); err != nil {
+			bindEnvError(envVar, err)
+		}
+		viper.SetDefault(key, defaultValue)
+	}
+
+	{
+		const (
+			key          = keys.K8SEnableIngressNginx
+			longOpt      = "k8s-enable-ingress-nginx"
+			envVar       = release.ENVPREFIX + "_K8S_ENABLE_INGRESS_NGINX"
+			description  = "Kubernetes enable collection of ingress-nginx metrics"
+			defaultValue = defaults.K8SEnableIngressNginx
+		)
+
+		rootCmd.PersistentFlags().Bool(longOpt, defaultValue, envDescription(description, envVar))
+		if err := viper.BindPFlag(key, rootCmd.PersistentFlags().Lookup(longOpt)); err != nil {
+			bindFlagError(longOpt, err)
+		}
+		if err := viper.BindEnv(key, envVar); err != nil {
+			bindEnvError(envVar, err)
+		}
+		viper.SetDefault(key, defaultValue)
+	}
+
+	{
+		const (
+			key          = keys.K8SIngressNginxNamespace
+			longOpt      = "k8s-ingress-nginx-namespace"
+			envVar       = release.ENVPREFIX + "_K8S_INGRESS_NGINX_NAMESPACE"
+			description  = "Namespace of ingress-nginx pods (blank for all)"
+			defaultValue = defaults.K8SIngressNginxNamespace
+		)
+
+		rootCmd.PersistentFlags().String(longOpt, defaultValue, envDescription(description, envVar))
+		if err := viper.BindPFlag(key, rootCmd.PersistentFlags().Lookup(longOpt)); err != nil {
+			bindFlagError(longOpt, err)
+		}
+		if err := viper.BindEnv(key, envVar); err != nil {
+			bindEnvError(envVar, err)
+		}
+		viper.SetDefault(key, defaultValue)
+	}
+
+	{
+		const (
+			key          = keys.K8SIngressNginxSelector
+			longOpt      = "k8s-ingress-nginx-selector"
+			envVar       = release.ENVPREFIX + "_K8S_INGRESS_NGINX_SELECTOR"
+			description  = "Label selector for ingress-nginx pods"
+			defaultValue = defaults.K8SIngressNginxSelector
+		)
+
+		rootCmd.PersistentFlags().String(longOpt, defaultValue, envDescription(description, envVar))
+		if err := viper.BindPFlag(key, rootCmd.PersistentFlags().Lookup(longOpt)); err != nil {
+			bindFlagError(longOpt, err)
+		}
+		if err := viper.BindEnv(key, envVar); err != nil {
+			bindEnvError(envVar, err)
+		}
+		viper.SetDefault(key, defaultValue)
+	}
+
+	{
+		const (
+			key          = keys.K8SIngressNginxPort
+			longOpt      = "k8s-ingress-nginx-port"
+			envVar       = release.ENVPREFIX + "_K8S_INGRESS_NGINX_PORT"
+			description  = "ingress-nginx metrics port (prefix with 'https:' for https)"
+			defaultValue = defaults.K8SIngressNginxPort
+		)
+
+		rootCmd.PersistentFlags().String(longOpt, defaultValue, envDescription(description, envVar))
+		if err := viper.BindPFlag(key, rootCmd.PersistentFlags().Lookup(longOpt)); err != nil {
+			bindFlagError(longOpt, err)
+		}
+		if err := viper.BindEnv(key, envVar); err != nil {
+			bindEnvError(envVar, err)
+		}
+		viper.SetDefault(key, defaultValue)
+	}
+
+	{
+		const (
+			key          = keys.K8SEnableCertManager
+			longOpt      = "k8s-enable-cert-manager"
+			envVar       = release.ENVPREFIX + "_K8S_ENABLE_CERT_MANAGER"
+			description  = "Kubernetes enable collection of cert-manager metrics"
+			defaultValue = defaults.K8SEnableCertManager
+		)
+
+		rootCmd.PersistentFlags().Bool(longOpt, defaultValue, envDescription(description, envVar))
+		if err := viper.BindPFlag(key, rootCmd.PersistentFlags().Lookup(longOpt)); err != nil {
+			bindFlagError(longOpt, err)
+		}
+		if err := viper.BindEnv(key, envVar); err != nil {
+			bindEnvError(envVar, err)
+		}
+		viper.SetDefault(key, defaultValue)
+	}
+
+	{
+		const (
+			key          = keys.K8SCertManagerNamespace
+			longOpt      = "k8s-cert-manager-namespace"
+			envVar       = release.ENVPREFIX + "_K8S_CERT_MANAGER_NAMESPACE"
+			description  = "Namespace of cert-manager pods (blank for all)"
+			defaultValue = defaults.K8SCertManagerNamespace
+		)
+
+		rootCmd.PersistentFlags().String(longOpt, defaultValue, envDescription(description, envVar))
+		if err := viper.BindPFlag(key, rootCmd.PersistentFlags().Lookup(longOpt)); err != nil {
+			bindFlagError(longOpt, err)
+		}
+		if err := viper.BindEnv(key, envVar); err != nil {
+			bindEnvError(envVar, err)
+		}
+		viper.SetDefault(key, defaultValue)
+	}
+
+	{
+		const (
+			key          = keys.K8SCertManagerSelector
+			longOpt      = "k8s-cert-manager-selector"
+			envVar       = release.ENVPREFIX + "_K8S_CERT_MANAGER_SELECTOR"
+			description  = "Label selector for cert-manager pods"
+			defaultValue = defaults.K8SCertManagerSelector
+		)
+
+		rootCmd.PersistentFlags().String(longOpt, defaultValue, envDescription(description, envVar))
+		if err := viper.BindPFlag(key, rootCmd.PersistentFlags().Lookup(longOpt)); err != nil {
+			bindFlagError(longOpt, err)
+		}
+		if err := viper.BindEnv(key, envVar); err != nil {
+			bindEnvError(envVar, err)
+		}
+		viper.SetDefault(key, defaultValue)
+	}
+
+	{
+		const (
+			key          = keys.K8SCertManagerPort
+			longOpt      = "k8s-cert-manager-port"
+			envVar       = release.ENVPREFIX + "_K8S_CERT_MANAGER_PORT"
+			description  = "cert-manager metrics port (prefix with 'https:' for https)"
+			defaultValue = defaults.K8SCertManagerPort
+		)
+
+		rootCmd.PersistentFlags().String(longOpt, defaultValue, envDescription(description, envVar))
+		if err := viper.BindPFlag(key, rootCmd.PersistentFlags().Lookup(longOpt)); err != nil {
+			bindFlagError(longOpt, err)
+		}
+		if err := viper.BindEnv(key, envVar); err != nil {
+			bindEnvError(envVar, err)
+		}
+		viper.SetDefault(key, defaultValue)
+	}
+
+	{
+		const (
+			key          = keys.K8SEnableIstio
+			longOpt      = "k8s-enable-istio"
+			envVar       = release.ENVPREFIX + "_K8S_ENABLE_ISTIO"
+			description  = "Kubernetes enable collection of istio-proxy sidecar metrics"
+			defaultValue = defaults.K8SEnableIstio
+		)
+
+		rootCmd.PersistentFlags().Bool(longOpt, defaultValue, envDescription(description, envVar))
+		if err := viper.BindPFlag(key, rootCmd.PersistentFlags().Lookup(longOpt)); err != nil {
+			bindFlagError(longOpt, err)
+		}
+		if err := viper.BindEnv(key, envVar); err != nil {
+			bindEnvError(envVar, err)
+		}
+		viper.SetDefault(key, defaultValue)
+	}
+
+	{
+		const (
+			key          = keys.K8SIstioNamespaces
+			longOpt      = "k8s-istio-namespaces"
+			envVar       = release.ENVPREFIX + "_K8S_ISTIO_NAMESPACES"
+			description  = "Comma separated list of namespaces with istio sidecars (blank for all)"
+			defaultValue = defaults.K8SIstioNamespaces
+		)
+
+		rootCmd.PersistentFlags().String(longOpt, defaultValue, envDescription(description, envVar))
+		if err := viper.BindPFlag(key, rootCmd.PersistentFlags().Lookup(longOpt)); err != nil {
+			bindFlagError(longOpt, err)
+		}
+		if err := viper.BindEnv(key, envVar); err != nil {
+			bindEnvError(envVar, err)
+		}
+		viper.SetDefault(key, defaultValue)
+	}
+
+	{
+		const (
+			key          = keys.K8SIstioSelector
+			longOpt      = "k8s-istio-selector"
+			envVar       = release.ENVPREFIX + "_K8S_ISTIO_SELECTOR"
+			description  = "Label selector for pods with istio sidecars"
+			defaultValue = defaults.K8SIstioSelector
+		)
+
+		rootCmd.PersistentFlags().String(longOpt, defaultValue, envDescription(description, envVar))
+		if err := viper.BindPFlag(key, rootCmd.PersistentFlags().Lookup(longOpt)); err != nil {
+			bindFlagError(longOpt, err)
+		}
+		if err := viper.BindEnv(key, envVar); err != nil {
+			bindEnvError(envVar, err)
+		}
+		viper.SetDefault(key, defaultValue)
+	}
+
+	{
+		const (
+			key          = keys.K8SIstioPort
+			longOpt      = "k8s-istio-port"
+			envVar       = release.ENVPREFIX + "_K8S_ISTIO_PORT"
+			description  = "istio-proxy metrics port"
+			defaultValue = defaults.K8SIstioPort
+		)
+
+		rootCmd.PersistentFlags().String(longOpt, defaultValue, envDescription(description, envVar))
+		if err := viper.BindPFlag(key, rootCmd.PersistentFlags().Lookup(longOpt)); err != nil {
+			bindFlagError(longOpt, err)
+		}
+		if err := viper.BindEnv(key, envVar); err != nil {
+			bindEnvError(envVar, err)
+		}
+		viper.SetDefault(key, defaultValue)
+	}
+
+	{
+		const (
+			key          = keys.K8SEnableLinkerd
+			longOpt      = "k8s-enable-linkerd"
+			envVar       = release.ENVPREFIX + "_K8S_ENABLE_LINKERD"
+			description  = "Kubernetes enable collection of linkerd-proxy sidecar metrics"
+			defaultValue = defaults.K8SEnableLinkerd
+		)
+
+		rootCmd.PersistentFlags().Bool(longOpt, defaultValue, envDescription(description, envVar))
+		if err := viper.BindPFlag(key, rootCmd.PersistentFlags().Lookup(longOpt)); err != nil {
+			bindFlagError(longOpt, err)
+		}
+		if err := viper.BindEnv(key, envVar); err != nil {
+			bindEnvError(envVar, err)
+		}
+		viper.SetDefault(key, defaultValue)
+	}
+
+	{
+		const (
+			key          = keys.K8SLinkerdNamespaces
+			longOpt      = "k8s-linkerd-namespaces"
+			envVar       = release.ENVPREFIX + "_K8S_LINKERD_NAMESPACES"
+			description  = "Comma separated list of namespaces with linkerd sidecars (blank for all)"
+			defaultValue = defaults.K8SLinkerdNamespaces
+		)
+
+		rootCmd.PersistentFlags().String(longOpt, defaultValue, envDescription(description, envVar))
+		if err := viper.BindPFlag(key, rootCmd.PersistentFlags().Lookup(longOpt)); err != nil {
+			bindFlagError(longOpt, err)
+		}
+		if err := viper.BindEnv(key, envVar); err != nil {
+			bindEnvError(envVar, err)
+		}
+		viper.SetDefault(key, defaultValue)
+	}
+
+	{
+		const (
+			key          = keys.K8SLinkerdSelector
+			longOpt      = "k8s-linkerd-selector"
+			envVar       = release.ENVPREFIX + "_K8S_LINKERD_SELECTOR"
+			description  = "Label selector for pods with linkerd sidecars"
+			defaultValue = defaults.K8SLinkerdSelector
+		)
+
+		rootCmd.PersistentFlags().String(longOpt, defaultValue, envDescription(description, envVar))
+		if err := viper.BindPFlag(key, rootCmd.PersistentFlags().Lookup(longOpt)); err != nil {
+			bindFlagError(longOpt, err)
+		}
+		if err := viper.BindEnv(key, envVar); err != nil {
+			bindEnvError(envVar, err)
+		}
+		viper.SetDefault(key, defaultValue)
+	}
+
+	{
+		const (
+			key          = keys.K8SLinkerdPort
+			longOpt      = "k8s-linkerd-port"
+			envVar       = release.ENVPREFIX + "_K8S_LINKERD_PORT"
+			description  = "linkerd-proxy admin metrics port"
+			defaultValue = defaults.K8SLinkerdPort
+		)
+
+		rootCmd.PersistentFlags().String(longOpt, defaultValue, envDescription(description, envVar))
+		if err := viper.BindPFlag(key, rootCmd.PersistentFlags().Lookup(longOpt)); err != nil {
+			bindFlagError(longOpt, err)
+		}
+		if err := viper.BindEnv(key, envVar); err != nil {
+			bindEnvError(envVar, err)
+		}
+		viper.SetDefault(key, defaultValue)
+	}
+
+	{
+		const (
+			key          = keys.K8SEnableDCGM
+			longOpt      = "k8s-enable-dcgm"
+			envVar       = release.ENVPREFIX + "_K8S_ENABLE_DCGM"
+			description  = "Kubernetes enable collection of dcgm-exporter metrics"
+			defaultValue = defaults.K8SEnableDCGM
+		)
+
+		rootCmd.PersistentFlags().Bool(longOpt, defaultValue, envDescription(description, envVar))
+		if err := viper.BindPFlag(key, rootCmd.PersistentFlags().Lookup(longOpt)); err != nil {
+			bindFlagError(longOpt, err)
+		}
+		if err := viper.BindEnv(key, envVar); err != nil {
+			bindEnvError(envVar, err)
+		}
+		viper.SetDefault(key, defaultValue)
+	}
+
+	{
+		const (
+			key          = keys.K8SDCGMNamespace
+			longOpt      = "k8s-dcgm-namespace"
+			envVar       = release.ENVPREFIX + "_K8S_DCGM_NAMESPACE"
+			description  = "Namespace of dcgm-exporter pods (blank for all)"
+			defaultValue = defaults.K8SDCGMNamespace
+		)
+
+		rootCmd.PersistentFlags().String(longOpt, defaultValue, envDescription(description, envVar))
+		if err := viper.BindPFlag(key, rootCmd.PersistentFlags().Lookup(longOpt)); err != nil {
+			bindFlagError(longOpt, err)
+		}
+		if err := viper.BindEnv(key, envVar); err != nil {
+			bindEnvError(envVar, err)
+		}
+		viper.SetDefault(key, defaultValue)
+	}
+
+	{
+		const (
+			key          = keys.K8SDCGMSelector
+			longOpt      = "k8s-dcgm-selector"
+			envVar       = release.ENVPREFIX + "_K8S_DCGM_SELECTOR"
+			description  = "Label selector for dcgm-exporter pods"
+			defaultValue = defaults.K8SDCGMSelector
+		)
+
+		rootCmd.PersistentFlags().String(longOpt, defaultValue, envDescription(description, envVar))
+		if err := viper.BindPFlag(key, rootCmd.PersistentFlags().Lookup(longOpt)); err != nil {
+			bindFlagError(longOpt, err)
+		}
+		if err := viper.BindEnv(key, envVar); err != nil {
+			bindEnvError(envVar, err)
+		}
+		viper.SetDefault(key, defaultValue)
+	}
+
+	{
+		const (
+			key          = keys.K8SDCGMPort
+			longOpt      = "k8s-dcgm-port"
+			envVar       = release.ENVPREFIX + "_K8S_DCGM_PORT"
+			description  = "dcgm-exporter metrics port (prefix with 'https:' for https)"
+			defaultValue = defaults.K8SDCGMPort
+		)
+
+		rootCmd.PersistentFlags().String(longOpt, defaultValue, envDescription(description, envVar))
+		if err := viper.BindPFlag(key, rootCmd.PersistentFlags().Lookup(longOpt)); err != nil {
+			bindFlagError(longOpt, err)
+		}
+		if err := viper.BindEnv(key, envVar); err != nil {
+			bindEnvError(envVar, err)
+		}
+		viper.SetDefault(key, defaultValue)
+	}
+
+	{
+		const (
+			key          = keys.K8SEnableStorageInventory
+			longOpt      = "k8s-enable-storage-inventory"
+			envVar       = release.ENVPREFIX + "_K8S_ENABLE_STORAGE_INVENTORY"
+			description  = "Kubernetes enable collection of persistent volume and claim inventory"
+			defaultValue = defaults.K8SEnableStorageInventory
+		)
+
+		rootCmd.PersistentFlags().Bool(longOpt, defaultValue, envDescription(description, envVar))
+		if err := viper.BindPFlag(key, rootCmd.PersistentFlags().Lookup(longOpt)); err != nil {
+			bindFlagError(longOpt, err)
+		}
+		if err := viper.BindEnv(key, envVar); err != nil {
+			bindEnvError(envVar, err)
+		}
+		viper.SetDefault(key, defaultValue)
+	}
+
+	{
+		const (
+			key          = keys.K8SEnableHPA
+			longOpt      = "k8s-enable-hpa"
+			envVar       = release.ENVPREFIX + "_K8S_ENABLE_HPA"
+			description  = "Kubernetes enable collection of horizontal pod autoscaler status"
+			defaultValue = defaults.K8SEnableHPA
+		)
+
+		rootCmd.PersistentFlags().Bool(longOpt, defaultValue, envDescription(description, envVar))
+		if err := viper.BindPFlag(key, rootCmd.PersistentFlags().Lookup(longOpt)); err != nil {
+			bindFlagError(longOpt, err)
+		}
+		if err := viper.BindEnv(key, envVar); err != nil {
+			bindEnvError(envVar, err)
+		}
+		viper.SetDefault(key, defaultValue)
+	}
+
+	{
+		const (
+			key          = keys.K8SEnableJobs
+			longOpt      = "k8s-enable-jobs"
+			envVar       = release.ENVPREFIX + "_K8S_ENABLE_JOBS"
+			description  = "Kubernetes enable collection of job and cron job health"
+			defaultValue = defaults.K8SEnableJobs
+		)
+
+		rootCmd.PersistentFlags().Bool(longOpt, defaultValue, envDescription(description, envVar))
+		if err := viper.BindPFlag(key, rootCmd.PersistentFlags().Lookup(longOpt)); err != nil {
+			bindFlagError(longOpt, err)
+		}
+		if err := viper.BindEnv(key, envVar); err != nil {
+			bindEnvError(envVar, err)
+		}
+		viper.SetDefault(key, defaultValue)
+	}
+
+	{
+		const (
+			key          = keys.K8SEnableDeployments
+			longOpt      = "k8s-enable-deployments"
+			envVar       = release.ENVPREFIX + "_K8S_ENABLE_DEPLOYMENTS"
+			description  = "Kubernetes enable collection of deployment rollout status"
+			defaultValue = defaults.K8SEnableDeployments
+		)
+
+		rootCmd.PersistentFlags().Bool(longOpt, defaultValue, envDescription(description, envVar))
+		if err := viper.BindPFlag(key, rootCmd.PersistentFlags().Lookup(longOpt)); err != nil {
+			bindFlagError(longOpt, err)
+		}
+		if err := viper.BindEnv(key, envVar); err != nil {
+			bindEnvError(envVar, err)
+		}
+		viper.SetDefault(key, defaultValue)
+	}
+
+	{
+		const (
+			key          = keys.K8SEnableStatefulSets
+			longOpt      = "k8s-enable-statefulsets"
+			envVar       = release.ENVPREFIX + "_K8S_ENABLE_STATEFULSETS"
+			description  = "Kubernetes enable collection of statefulset readiness"
+			defaultValue = defaults.K8SEnableStatefulSets
+		)
+
+		rootCmd.PersistentFlags().Bool(longOpt, defaultValue, envDescription(description, envVar))
+		if err := viper.BindPFlag(key, rootCmd.PersistentFlags().Lookup(longOpt)); err != nil {
+			bindFlagError(longOpt, err)
+		}
+		if err := viper.BindEnv(key, envVar); err != nil {
+			bindEnvError(envVar, err)
+		}
+		viper.SetDefault(key, defaultValue)
+	}
+
+	{
+		const (
+			key          = keys.K8SEnableDaemonSets
+			longOpt      = "k8s-enable-daemonsets"
+			envVar       = release.ENVPREFIX + "_K8S_ENABLE_DAEMONSETS"
+			description  = "Kubernetes enable collection of daemonset coverage"
+			defaultValue = defaults.K8SEnableDaemonSets
+		)
+
+		rootCmd.PersistentFlags().Bool(longOpt, defaultValue, envDescription(description, envVar))
+		if err := viper.BindPFlag(key, rootCmd.PersistentFlags().Lookup(longOpt)); err != nil {
+			bindFlagError(longOpt, err)
+		}
+		if err := viper.BindEnv(key, envVar); err != nil {
+			bindEnvError(envVar, err)
+		}
+		viper.SetDefault(key, defaultValue)
+	}
+
+	{
+		const (
+			key          = keys.K8SEnableEndpoints
+			longOpt      = "k8s-enable-endpoints"
+			envVar       = release.ENVPREFIX + "_K8S_ENABLE_ENDPOINTS"
+			description  = "Kubernetes enable collection of service endpoint health"
+			defaultValue = defaults.K8SEnableEndpoints
+		)
+
+		rootCmd.PersistentFlags().Bool(longOpt, defaultValue, envDescription(description, envVar))
+		if err := viper.BindPFlag(key, rootCmd.PersistentFlags().Lookup(longOpt)); err != nil {
+			bindFlagError(longOpt, err)
+		}
+		if err := viper.BindEnv(key, envVar); err != nil {
+			bindEnvError(envVar, err)
+		}
+		viper.SetDefault(key, defaultValue)
+	}
+
+	{
+		const (
+			key          = keys.K8SEnableCustomResources
+			longOpt      = "k8s-enable-custom-resources"
+			envVar       = release.ENVPREFIX + "_K8S_ENABLE_CUSTOM_RESOURCES"
+			description  = "Kubernetes enable collection of custom resource state"
+			defaultValue = defaults.K8SEnableCustomResources
+		)
+
+		rootCmd.PersistentFlags().Bool(longOpt, defaultValue, envDescription(description, envVar))
+		if err := viper.BindPFlag(key, rootCmd.PersistentFlags().Lookup(longOpt)); err != nil {
+			bindFlagError(longOpt, err)
+		}
+		if err := viper.BindEnv(key, envVar); err != nil {
+			bindEnvError(envVar, err)
+		}
+		viper.SetDefault(key, defaultValue)
+	}
+
+	{
+		const (
+			key          = keys.K8SCustomResourcesFile
+			longOpt      = "k8s-custom-resources-file"
+			envVar       = release.ENVPREFIX + "_K8S_CUSTOM_RESOURCES_FILE"
+			description  = "JSON file defining custom resources (GroupVersionResource and JSONPath fields) to collect"
+			defaultValue = defaults.K8SCustomResourcesFile
+		)
+
+		rootCmd.PersistentFlags().String(longOpt, defaultValue, envDescription(description, envVar))
+		if err := viper.BindPFlag(key, rootCmd.PersistentFlags().Lookup(longOpt)); err != nil {
+			bindFlagError(longOpt, err)
+		}
+		if err := viper.BindEnv(key, envVar); err != nil {
+			bindEnvError(envVar, err)
+		}
+		viper.SetDefault(key, defaultValue)
+	}
+
+	{
+		const (
+			key          = keys.K8SEnableNodeProblemConditions
+			longOpt      = "k8s-enable-node-problem-conditions"
+			envVar       = release.ENVPREFIX + "_K8S_ENABLE_NODE_PROBLEM_CONDITIONS"
+			description  = "Kubernetes enable collection of node-problem-detector node conditions (e.g. KernelDeadlock, ReadonlyFilesystem)"
+			defaultValue = defaults.K8SEnableNodeProblemConditions
+		)
+
+		rootCmd.PersistentFlags().Bool(longOpt, defaultValue, envDescription(description, envVar))
+		if err := viper.BindPFlag(key, rootCmd.PersistentFlags().Lookup(longOpt)); err != nil {
+			bindFlagError(longOpt, err)
+		}
+		if err := viper.BindEnv(key, envVar); err != nil {
+			bindEnvError(envVar, err)
+		}
+		viper.SetDefault(key, defaultValue)
+	}
+
+	{
+		const (
+			key          = keys.K8SEnableContainerRestarts
+			longOpt      = "k8s-enable-container-restarts"
+			envVar       = release.ENVPREFIX + "_K8S_ENABLE_CONTAINER_RESTARTS"
+			description  = "Kubernetes enable collection of container restarts and termination reasons"
+			defaultValue = defaults.K8SEnableContainerRestarts
+		)
+
+		rootCmd.PersistentFlags().Bool(longOpt, defaultValue, envDescription(description, envVar))
+		if err := viper.BindPFlag(key, rootCmd.PersistentFlags().Lookup(longOpt)); err != nil {
+			bindFlagError(longOpt, err)
+		}
+		if err := viper.BindEnv(key, envVar); err != nil {
+			bindEnvError(envVar, err)
+		}
+		viper.SetDefault(key, defaultValue)
+	}
+
+	{
+		const (
+			key          = keys.K8SEnablePodPhases
+			longOpt      = "k8s-enable-pod-phases"
+			envVar       = release.ENVPREFIX + "_K8S_ENABLE_POD_PHASES"
+			description  = "Kubernetes enable collection of pod pending and container creating durations"
+			defaultValue = defaults.K8SEnablePodPhases
+		)
+
+		rootCmd.PersistentFlags().Bool(longOpt, defaultValue, envDescription(description, envVar))
+		if err := viper.BindPFlag(key, rootCmd.PersistentFlags().Lookup(longOpt)); err != nil {
+			bindFlagError(longOpt, err)
+		}
+		if err := viper.BindEnv(key, envVar); err != nil {
+			bindEnvError(envVar, err)
+		}
+		viper.SetDefault(key, defaultValue)
+	}
+
+	{
+		const (
+			key          = keys.K8SEnableImagePulls
+			longOpt      = "k8s-enable-image-pulls"
+			envVar       = release.ENVPREFIX + "_ENABLE_IMAGE_PULLS"
+			description  = "Kubernetes enable collection of image pull counts, failures, and durations from pod events"
+			defaultValue = defaults.K8SEnableImagePulls
+		)
+
+		rootCmd.PersistentFlags().Bool(longOpt, defaultValue, envDescription(description, envVar))
+		if err := viper.BindPFlag(key, rootCmd.PersistentFlags().Lookup(longOpt)); err != nil {
+			bindFlagError(longOpt, err)
+		}
+		if err := viper.BindEnv(key, envVar); err != nil {
+			bindEnvError(envVar, err)
+		}
+		viper.SetDefault(key, defaultValue)
+	}
+
+	{
+		const (
+			key          = keys.K8SEnableClusterAutoscaler
+			longOpt      = "k8s-enable-cluster-autoscaler"
+			envVar       = release.ENVPREFIX + "_K8S_ENABLE_CLUSTER_AUTOSCALER"
+			description  = "Kubernetes enable collection of cluster-autoscaler metrics"
+			defaultValue = defaults.K8SEnableClusterAutoscaler
+		)
+
+		rootCmd.PersistentFlags().Bool(longOpt, defaultValue, envDescription(description, envVar))
+		if err := viper.BindPFlag(key, rootCmd.PersistentFlags().Lookup(longOpt)); err != nil {
+			bindFlagError(longOpt, err)
+		}
+		if err := viper.BindEnv(key, envVar); err != nil {
+			bindEnvError(envVar, err)
+		}
+		viper.SetDefault(key, defaultValue)
+	}
+
+	{
+		const (
+			key          = keys.K8SClusterAutoscalerNamespace
+			longOpt      = "k8s-cluster-autoscaler-namespace"
+			envVar       = release.ENVPREFIX + "_K8S_CLUSTER_AUTOSCALER_NAMESPACE"
+			description  = "Namespace of cluster-autoscaler pods (blank for all)"
+			defaultValue = defaults.K8SClusterAutoscalerNamespace
+		)
+
+		rootCmd.PersistentFlags().String(longOpt, defaultValue, envDescription(description, envVar))
+		if err := viper.BindPFlag(key, rootCmd.PersistentFlags().Lookup(longOpt)); err != nil {
+			bindFlagError(longOpt, err)
+		}
+		if err := viper.BindEnv(key, envVar); err != nil {
+			bindEnvError(envVar, err)
+		}
+		viper.SetDefault(key, defaultValue)
+	}
+
+	{
+		const (
+			key          = keys.K8SClusterAutoscalerSelector
+			longOpt      = "k8s-cluster-autoscaler-selector"
+			envVar       = release.ENVPREFIX + "_K8S_CLUSTER_AUTOSCALER_SELECTOR"
+			description  = "Label selector for cluster-autoscaler pods"
+			defaultValue = defaults.K8SClusterAutoscalerSelector
+		)
+
+		rootCmd.PersistentFlags().String(longOpt, defaultValue, envDescription(description, envVar))
+		if err := viper.BindPFlag(key, rootCmd.PersistentFlags().Lookup(longOpt)); err != nil {
+			bindFlagError(longOpt, err)
+		}
+		if err := viper.BindEnv(key, envVar); err != nil {
+			bindEnvError(envVar, err)
+		}
+		viper.SetDefault(key, defaultValue)
+	}
+
+	{
+		const (
+			key          = keys.K8SClusterAutoscalerPort
+			longOpt      = "k8s-cluster-autoscaler-port"
+			envVar       = release.ENVPREFIX + "_K8S_CLUSTER_AUTOSCALER_PORT"
+			description  = "cluster-autoscaler metrics port (prefix with 'https:' for https)"
+			defaultValue = defaults.K8SClusterAutoscalerPort
+		)
+
+		rootCmd.PersistentFlags().String(longOpt, defaultValue, envDescription(description, envVar))
+		if err := viper.BindPFlag(key, rootCmd.PersistentFlags().Lookup(longOpt)); err != nil {
+			bindFlagError(longOpt, err)
+		}
+		if err := viper.BindEnv(key, envVar); err != nil {
+			bindEnvError(envVar, err)
+		}
+		viper.SetDefault(key, defaultValue)
+	}
+
+	{
+		const (
+			key          = keys.K8SEnableKarpenter
+			longOpt      = "k8s-enable-karpenter"
+			envVar       = release.ENVPREFIX + "_K8S_ENABLE_KARPENTER"
+			description  = "Kubernetes enable collection of karpenter metrics"
+			defaultValue = defaults.K8SEnableKarpenter
+		)
+
+		rootCmd.PersistentFlags().Bool(longOpt, defaultValue, envDescription(description, envVar))
+		if err := viper.BindPFlag(key, rootCmd.PersistentFlags().Lookup(longOpt)); err != nil {
+			bindFlagError(longOpt, err)
+		}
+		if err := viper.BindEnv(key, envVar); err != nil {
+			bindEnvError(envVar, err)
+		}
+		viper.SetDefault(key, defaultValue)
+	}
+
+	{
+		const (
+			key          = keys.K8SKarpenterNamespace
+			longOpt      = "k8s-karpenter-namespace"
+			envVar       = release.ENVPREFIX + "_K8S_KARPENTER_NAMESPACE"
+			description  = "Namespace of karpenter pods (blank for all)"
+			defaultValue = defaults.K8SKarpenterNamespace
+		)
+
+		rootCmd.PersistentFlags().String(longOpt, defaultValue, envDescription(description, envVar))
+		if err := viper.BindPFlag(key, rootCmd.PersistentFlags().Lookup(longOpt)); err != nil {
+			bindFlagError(longOpt, err)
+		}
+		if err := viper.BindEnv(key, envVar); err != nil {
+			bindEnvError(envVar, err)
+		}
+		viper.SetDefault(key, defaultValue)
+	}
+
+	{
+		const (
+			key          = keys.K8SKarpenterSelector
+			longOpt      = "k8s-karpenter-selector"
+			envVar       = release.ENVPREFIX + "_K8S_KARPENTER_SELECTOR"
+			description  = "Label selector for karpenter pods"
+			defaultValue = defaults.K8SKarpenterSelector
+		)
+
+		rootCmd.PersistentFlags().String(longOpt, defaultValue, envDescription(description, envVar))
+		if err := viper.BindPFlag(key, rootCmd.PersistentFlags().Lookup(longOpt)); err != nil {
+			bindFlagError(longOpt, err)
+		}
+		if err := viper.BindEnv(key, envVar); err != nil {
+			bindEnvError(envVar, err)
+		}
+		viper.SetDefault(key, defaultValue)
+	}
+
+	{
+		const (
+			key          = keys.K8SKarpenterPort
+			longOpt      = "k8s-karpenter-port"
+			envVar       = release.ENVPREFIX + "_K8S_KARPENTER_PORT"
+			description  = "karpenter metrics port (prefix with 'https:' for https)"
+			defaultValue = defaults.K8SKarpenterPort
+		)
+
+		rootCmd.PersistentFlags().String(longOpt, defaultValue, envDescription(description, envVar))
+		if err := viper.BindPFlag(key, rootCmd.PersistentFlags().Lookup(longOpt)); err != nil {
+			bindFlagError(longOpt, err)
+		}
+		if err := viper.BindEnv(key, envVar); err != nil {
+			bindEnvError(envVar, err)
+		}
+		viper.SetDefault(key, defaultValue)
+	}
+
+	{
+		const (
+			key          = keys.K8SEnableVelero
+			longOpt      = "k8s-enable-velero"
+			envVar       = release.ENVPREFIX + "_K8S_ENABLE_VELERO"
+			description  = "Kubernetes enable collection of velero backup and restore status"
+			defaultValue = defaults.K8SEnableVelero
+		)
+
+		rootCmd.PersistentFlags().Bool(longOpt, defaultValue, envDescription(description, envVar))
+		if err := viper.BindPFlag(key, rootCmd.PersistentFlags().Lookup(longOpt)); err != nil {
+			bindFlagError(longOpt, err)
+		}
+		if err := viper.BindEnv(key, envVar); err != nil {
+			bindEnvError(envVar, err)
+		}
+		viper.SetDefault(key, defaultValue)
+	}
+
+	{
+		const (
+			key          = keys.K8SVeleroNamespace
+			longOpt      = "k8s-velero-namespace"
+			envVar       = release.ENVPREFIX + "_K8S_VELERO_NAMESPACE"
+			description  = "Namespace of velero backup and restore resources"
+			defaultValue = defaults.K8SVeleroNamespace
+		)
+
+		rootCmd.PersistentFlags().String(longOpt, defaultValue, envDescription(description, envVar))
+		if err := viper.BindPFlag(key, rootCmd.PersistentFlags().Lookup(longOpt)); err != nil {
+			bindFlagError(longOpt, err)
+		}
+		if err := viper.BindEnv(key, envVar); err != nil {
+			bindEnvError(envVar, err)
+		}
+		viper.SetDefault(key, defaultValue)
+	}
+
+	{
+		const (
+			key          = keys.K8SEnableArgoCD
+			longOpt      = "k8s-enable-argocd"
+			envVar       = release.ENVPREFIX + "_K8S_ENABLE_ARGOCD"
+			description  = "Kubernetes enable collection of argo cd application sync and health status"
+			defaultValue = defaults.K8SEnableArgoCD
+		)
+
+		rootCmd.PersistentFlags().Bool(longOpt, defaultValue, envDescription(description, envVar))
+		if err := viper.BindPFlag(key, rootCmd.PersistentFlags().Lookup(longOpt)); err != nil {
+			bindFlagError(longOpt, err)
+		}
+		if err := viper.BindEnv(key, envVar); err != nil {
+			bindEnvError(envVar, err)
+		}
+		viper.SetDefault(key, defaultValue)
+	}
+
+	{
+		const (
+			key          = keys.K8SArgoCDNamespace
+			longOpt      = "k8s-argocd-namespace"
+			envVar       = release.ENVPREFIX + "_K8S_ARGOCD_NAMESPACE"
+			description  = "Namespace of argo cd application resources (blank for all)"
+			defaultValue = defaults.K8SArgoCDNamespace
+		)
+
+		rootCmd.PersistentFlags().String(longOpt, defaultValue, envDescription(description, envVar))
+		if err := viper.BindPFlag(key, rootCmd.PersistentFlags().Lookup(longOpt)); err != nil {
+			bindFlagError(longOpt, err)
+		}
+		if err := viper.BindEnv(key, envVar); err != nil {
+			bindEnvError(envVar, err)
+		}
+		viper.SetDefault(key, defaultValue)
+	}
+
+	{
+		const (
+			key          = keys.K8SEnableFlux
+			longOpt      = "k8s-enable-flux"
+			envVar       = release.ENVPREFIX + "_K8S_ENABLE_FLUX"
+			description  = "Kubernetes enable collection of flux kustomization and helm release reconciliation status"
+			defaultValue = defaults.K8SEnableFlux
+		)
+
+		rootCmd.PersistentFlags().Bool(longOpt, defaultValue, envDescription(description, envVar))
+		if err := viper.BindPFlag(key, rootCmd.PersistentFlags().Lookup(longOpt)); err != nil {
+			bindFlagError(longOpt, err)
+		}
+		if err := viper.BindEnv(key, envVar); err != nil {
+			bindEnvError(envVar, err)
+		}
+		viper.SetDefault(key, defaultValue)
+	}
+
+	{
+		const (
+			key          = keys.K8SFluxNamespace
+			longOpt      = "k8s-flux-namespace"
+			envVar       = release.ENVPREFIX + "_K8S_FLUX_NAMESPACE"
+			description  = "Namespace of flux kustomization and helm release resources (blank for all)"
+			defaultValue = defaults.K8SFluxNamespace
+		)
+
+		rootCmd.PersistentFlags().String(longOpt, defaultValue, envDescription(description, envVar))
+		if err := viper.BindPFlag(key, rootCmd.PersistentFlags().Lookup(longOpt)); err != nil {
+			bindFlagError(longOpt, err)
+		}
+		if err := viper.BindEnv(key, envVar); err != nil {
+			bindEnvError(envVar, err)
+		}
+		viper.SetDefault(key, defaultValue)
+	}
+
+	{
+		const (
+			key          = keys.K8SEnableScrapeTargets
+			longOpt      = "k8s-enable-scrape-targets"
+			envVar       = release.ENVPREFIX + "_K8S_ENABLE_SCRAPE_TARGETS"
+			description  = "Kubernetes enable scraping of the prometheus metrics endpoints defined in the scrape targets file"
+			defaultValue = defaults.K8SEnableScrapeTargets
+		)
+
+		rootCmd.PersistentFlags().Bool(longOpt, defaultValue, envDescription(description, envVar))
+		if err := viper.BindPFlag(key, rootCmd.PersistentFlags().Lookup(longOpt)); err != nil {
+			bindFlagError(longOpt, err)
+		}
+		if err := viper.BindEnv(key, envVar); err != nil {
+			bindEnvError(envVar, err)
+		}
+		viper.SetDefault(key, defaultValue)
+	}
+
+	{
+		const (
+			key          = keys.K8SScrapeTargetsFile
+			longOpt      = "k8s-scrape-targets-file"
+			envVar       = release.ENVPREFIX + "_K8S_SCRAPE_TARGETS_FILE"
+			description  = "Yaml file defining the prometheus metrics endpoints to scrape"
+			defaultValue = defaults.K8SScrapeTargetsFile
+		)
+
+		rootCmd.PersistentFlags().String(longOpt, defaultValue, envDescription(description, envVar))
+		if err := viper.BindPFlag(key, rootCmd.PersistentFlags().Lookup(longOpt)); err != nil {
+			bindFlagError(longOpt, err)
+		}
+		if err := viper.BindEnv(key, envVar); err != nil {
+			bindEnvError(envVar, err)
+		}
+		viper.SetDefault(key, defaultValue)
+	}
+
+	{
+		const (
+			key          = keys.K8SEnableDeprecatedAPIs
+			longOpt      = "k8s-enable-deprecated-apis"
+			envVar       = release.ENVPREFIX + "_K8S_ENABLE_DEPRECATED_APIS"
+			description  = "Kubernetes enable collection of deprecated api usage (requests per group/version/resource) from the api-server metrics"
+			defaultValue = defaults.K8SEnableDeprecatedAPIs
+		)
+
+		rootCmd.PersistentFlags().Bool(longOpt, defaultValue, envDescription(description, envVar))
+		if err := viper.BindPFlag(key, rootCmd.PersistentFlags().Lookup(longOpt)); err != nil {
+			bindFlagError(longOpt, err)
+		}
+		if err := viper.BindEnv(key, envVar); err != nil {
+			bindEnvError(envVar, err)
+		}
+		viper.SetDefault(key, defaultValue)
+	}
+
+	{
+		const (
+			key          = keys.K8SEnableTLSSecrets
+			longOpt      = "k8s-enable-tls-secrets"
+			envVar       = release.ENVPREFIX + "_K8S_ENABLE_TLS_SECRETS"
+			description  = "Kubernetes enable collection of days until expiry of certificates in kubernetes.io/tls secrets"
+			defaultValue = defaults.K8SEnableTLSSecrets
+		)
+
+		rootCmd.PersistentFlags().Bool(longOpt, defaultValue, envDescription(description, envVar))
+		if err := viper.BindPFlag(key, rootCmd.PersistentFlags().Lookup(longOpt)); err != nil {
+			bindFlagError(longOpt, err)
+		}
+		if err := viper.BindEnv(key, envVar); err != nil {
+			bindEnvError(envVar, err)
+		}
+		viper.SetDefault(key, defaultValue)
+	}
+
+	{
+		const (
+			key          = keys.K8STLSSecretsNamespaces
+			longOpt      = "k8s-tls-secrets-namespaces"
+			envVar       = release.ENVPREFIX + "_K8S_TLS_SECRETS_NAMESPACES"
+			description  = "Comma separated list of namespaces with tls secrets (blank for all)"
+			defaultValue = defaults.K8STLSSecretsNamespaces
+		)
+
+		rootCmd.PersistentFlags().String(longOpt, defaultValue, envDescription(description, envVar))
+		if err := viper.BindPFlag(key, rootCmd.PersistentFlags().Lookup(longOpt)); err != nil {
+			bindFlagError(longOpt, err)
+		}
+		if err := viper.BindEnv(key, envVar); err != nil {
+			bindEnvError(envVar, err)
+		}
+		viper.SetDefault(key, defaultValue)
+	}
+
+	{
+		const (
+			key          = keys.K8SEnableConfigInventory
+			longOpt      = "k8s-enable-config-inventory"
+			envVar       = release.ENVPREFIX + "_K8S_ENABLE_CONFIG_INVENTORY"
+			description  = "Kubernetes enable collection of secret and configmap counts and sizes per namespace"
+			defaultValue = defaults.K8SEnableConfigInventory
+		)
+
+		rootCmd.PersistentFlags().Bool(longOpt, defaultValue, envDescription(description, envVar))
+		if err := viper.BindPFlag(key, rootCmd.PersistentFlags().Lookup(longOpt)); err != nil {
+			bindFlagError(longOpt, err)
+		}
+		if err := viper.BindEnv(key, envVar); err != nil {
+			bindEnvError(envVar, err)
+		}
+		viper.SetDefault(key, defaultValue)
+	}
+
+	{
+		const (
+			key          = keys.K8SConfigInventorySizeThreshold
+			longOpt      = "k8s-config-inventory-size-threshold"
+			envVar       = release.ENVPREFIX + "_K8S_CONFIG_INVENTORY_SIZE_THRESHOLD"
+			description  = "Flag secrets and configmaps larger than this many bytes (0 to disable)"
+			defaultValue = defaults.K8SConfigInventorySizeThreshold
+		)
+
+		rootCmd.PersistentFlags().Uint(longOpt, defaultValue, envDescription(description, envVar))
+		if err := viper.BindPFlag(key, rootCmd.PersistentFlags().Lookup(longOpt)); err != nil {
+			bindFlagError(longOpt, err)
+		}
+		if err := viper.BindEnv(key, envVar); err != nil {
+			bindEnvError(envVar, err)
+		}
+		viper.SetDefault(key, defaultValue)
+	}
+
+	{
+		const (
+			key          = keys.K8SEnableNamespaceResources
+			longOpt      = "k8s-enable-namespace-resources"
+			envVar       = release.ENVPREFIX + "_K8S_ENABLE_NAMESPACE_RESOURCES"
+			description  = "Kubernetes enable collection of per namespace pod resource requests, limits, and usage (metrics-server)"
+			defaultValue = defaults.K8SEnableNamespaceResources
+		)
+
+		rootCmd.PersistentFlags().Bool(longOpt, defaultValue, envDescription(description, envVar))
+		if err := viper.BindPFlag(key, rootCmd.PersistentFlags().Lookup(longOpt)); err != nil {
+			bindFlagError(longOpt, err)
+		}
+		if err := viper.BindEnv(key, envVar); err != nil {
+			bindEnvError(envVar, err)
+		}
+		viper.SetDefault(key, defaultValue)
+	}
+
+	{
+		const (
+			key          = keys.K8SEnableCRI
+			longOpt      = "k8s-enable-cri"
+			envVar       = release.ENVPREFIX + "_K8S_ENABLE_CRI"
+			description  = "Kubernetes enable collection of container stats from the node container runtime socket (daemonset mode, see deploy/optional/daemonset.yaml)"
+			defaultValue = defaults.K8SEnableCRI
+		)
+
+		rootCmd.PersistentFlags().Bool(longOpt, defaultValue, envDescription(description, envVar))
+		if err := viper.BindPFlag(key, rootCmd.PersistentFlags().Lookup(longOpt)); err != nil {
+			bindFlagError(longOpt, err)
+		}
+		if err := viper.BindEnv(key, envVar); err != nil {
+			bindEnvError(envVar, err)
+		}
+		viper.SetDefault(key, defaultValue)
+	}
+
+	{
+		const (
+			key          = keys.K8SCRISocket
+			longOpt      = "k8s-cri-socket"
+			envVar       = release.ENVPREFIX + "_K8S_CRI_SOCKET"
+			description  = "Container runtime (CRI) socket path (e.g. /run/containerd/containerd.sock, /var/run/crio/crio.sock)"
+			defaultValue = defaults.K8SCRISocket
+		)
+
+		rootCmd.PersistentFlags().String(longOpt, defaultValue, envDescription(description, envVar))
+		if err := viper.BindPFlag(key, rootCmd.PersistentFlags().Lookup(longOpt)); err != nil {
+			bindFlagError(longOpt, err)
+		}
+		if err := viper.BindEnv(key, envVar); err != nil {
+			bindEnvError(envVar, err)
+		}
+		viper.SetDefault(key, defaultValue)
+	}
+
+	{
+		const (
+			key          = keys.K8SNodeName
+			longOpt      = "k8s-node-name"
+			envVar       = release.ENVPREFIX + "_K8S_NODE_NAME"
+			description  = "Name of the node the agent is running on, daemonset mode node collectors (set from spec.nodeName)"
+			defaultValue = defaults.K8SNodeName
+		)
+
+		rootCmd.PersistentFlags().String(longOpt, defaultValue, envDescription(description, envVar))
+		if err := viper.BindPFlag(key, rootCmd.PersistentFlags().Lookup(longOpt)); err != nil {
+			bindFlagError(longOpt, err)
+		}
+		if err := viper.BindEnv(key, envVar); err != nil {
+			bindEnvError(envVar, err)
+		}
+		viper.SetDefault(key, defaultValue)
+	}
+
+	{
+		const (
+			key          = keys.K8SEnablePodResources
+			longOpt      = "k8s-enable-pod-resources"
+			envVar       = release.ENVPREFIX + "_K8S_ENABLE_POD_RESOURCES"
+			description  = "Kubernetes enable collection of device plugin, exclusive cpu, and hugepage allocations from the kubelet pod resources socket (daemonset mode, see deploy/optional/daemonset.yaml)"
+			defaultValue = defaults.K8SEnablePodResources
+		)
+
+		rootCmd.PersistentFlags().Bool(longOpt, defaultValue, envDescription(description, envVar))
+		if err := viper.BindPFlag(key, rootCmd.PersistentFlags().Lookup(longOpt)); err != nil {
+			bindFlagError(longOpt, err)
+		}
+		if err := viper.BindEnv(key, envVar); err != nil {
+			bindEnvError(envVar, err)
+		}
+		viper.SetDefault(key, defaultValue)
+	}
+
+	{
+		const (
+			key          = keys.K8SPodResourcesSocket
+			longOpt      = "k8s-pod-resources-socket"
+			envVar       = release.ENVPREFIX + "_K8S_POD_RESOURCES_SOCKET"
+			description  = "Kubelet pod resources socket path"
+			defaultValue = defaults.K8SPodResourcesSocket
+		)
+
+		rootCmd.PersistentFlags().String(longOpt, defaultValue, envDescription(description, envVar))
+		if err := viper.BindPFlag(key, rootCmd.PersistentFlags().Lookup(longOpt)); err != nil {
+			bindFlagError(longOpt, err)
+		}
+		if err := viper.BindEnv(key, envVar); err != nil {
+			bindEnvError(envVar, err)
+		}
+		viper.SetDefault(key, defaultValue)
+	}
+
+	{
+		const (
+			key          = keys.K8SEnableCost
+			longOpt      = "k8s-enable-cost"
+			envVar       = release.ENVPREFIX + "_K8S_ENABLE_COST"
+			description  = "Kubernetes enable collection of hourly cost estimates (node capacity and pod requests priced with the cost prices file)"
+			defaultValue = defaults.K8SEnableCost
+		)
+
+		rootCmd.PersistentFlags().Bool(longOpt, defaultValue, envDescription(description, envVar))
+		if err := viper.BindPFlag(key, rootCmd.PersistentFlags().Lookup(longOpt)); err != nil {
+			bindFlagError(longOpt, err)
+		}
+		if err := viper.BindEnv(key, envVar); err != nil {
+			bindEnvError(envVar, err)
+		}
+		viper.SetDefault(key, defaultValue)
+	}
+
+	{
+		const (
+			key          = keys.K8SCostPricesFile
+			longOpt      = "k8s-cost-prices-file"
+			envVar       = release.ENVPREFIX + "_K8S_COST_PRICES_FILE"
+			description  = "Cost price table file (yaml)"
+			defaultValue = defaults.K8SCostPricesFile
+		)
+
+		rootCmd.PersistentFlags().String(longOpt, defaultValue, envDescription(description, envVar))
+		if err := viper.BindPFlag(key, rootCmd.PersistentFlags().Lookup(longOpt)); err != nil {
+			bindFlagError(longOpt, err)
+		}
+		if err := viper.BindEnv(key, envVar); err != nil {
+			bindEnvError(envVar, err)
+		}
+		viper.SetDefault(key, defaultValue)
+	}
+
+	{
+		const (
+			key          = keys.K8SCostLabels
+			longOpt      = "k8s-cost-labels"
+			envVar       = release.ENVPREFIX + "_K8S_COST_LABELS"
+			description  = "Comma separated list of pod label keys to roll up costs by, in addition to namespace"
+			defaultValue = defaults.K8SCostLabels
+		)
+
+		rootCmd.PersistentFlags().String(longOpt, defaultValue, envDescription(description, envVar))
+		if err := viper.BindPFlag(key, rootCmd.PersistentFlags().Lookup(longOpt)); err != nil {
+			bindFlagError(longOpt, err)
+		}
+		if err := viper.BindEnv(key, envVar); err != nil {
+			bindEnvError(envVar, err)
+		}
+		viper.SetDefault(key, defaultValue)
+	}
+
+	{
+		const (
+			key          = keys.K8SEnableHeadroom
+			longOpt      = "k8s-enable-headroom"
+			envVar       = release.ENVPREFIX + "_K8S_ENABLE_HEADROOM"
+			description  = "Kubernetes enable collection of capacity headroom (allocatable minus requested cpu, memory, and pods) per node pool and cluster wide"
+			defaultValue = defaults.K8SEnableHeadroom
+		)
+
+		rootCmd.PersistentFlags().Bool(longOpt, defaultValue, envDescription(description, envVar))
+		if err := viper.BindPFlag(key, rootCmd.PersistentFlags().Lookup(longOpt)); err != nil {
+			bindFlagError(longOpt, err)
+		}
+		if err := viper.BindEnv(key, envVar); err != nil {
+			bindEnvError(envVar, err)
+		}
+		viper.SetDefault(key, defaultValue)
+	}
+
+	{
+		const (
+			key          = keys.K8SHeadroomNodePoolLabel
+			longOpt      = "k8s-headroom-node-pool-label"
+			envVar       = release.ENVPREFIX + "_K8S_HEADROOM_NODE_POOL_LABEL"
+			description  = "Node label identifying the node pool (blank for well known eks/gke/aks/karpenter labels)"
+			defaultValue = defaults.K8SHeadroomNodePoolLabel
+		)
+
+		rootCmd.PersistentFlags().String(longOpt, defaultValue, envDescription(description, envVar))
+		if err := viper.BindPFlag(key, rootCmd.PersistentFlags().Lookup(longOpt)); err != nil {
+			bindFlagError(longOpt, err)
+		}
+		if err := viper.BindEnv(key, envVar); err != nil {
+			bindEnvError(envVar, err)
+		}
+		viper.SetDefault(key, defaultValue)
+	}
+
+	{
+		const (
+			key          = keys.K8SEnablePDB
+			longOpt      = "k8s-enable-pdb"
+			envVar       = release.ENVPREFIX + "_K8S_ENABLE_PDB"
+			description  = "Kubernetes enable collection of pod disruption budget status"
+			defaultValue = defaults.K8SEnablePDB
+		)
+
+		rootCmd.PersistentFlags().Bool(longOpt, defaultValue, envDescription(description, envVar))
+		if err := viper.BindPFlag(key, rootCmd.PersistentFlags().Lookup(longOpt)); err != nil {
+			bindFlagError(longOpt, err)
+		}
+		if err := viper.BindEnv(key, envVar); err != nil {
+			bindEnvError(envVar, err)
+		}
+		viper.SetDefault(key, defaultValue)
+	}
+
+	{
+		const (
+			key          = keys.K8SEnableSchedulingFailures
+			longOpt      = "k8s-enable-scheduling-failures"
+			envVar       = release.ENVPREFIX + "_K8S_ENABLE_SCHEDULING_FAILURES"
+			description  = "Kubernetes enable collection of pending pod scheduling failures by reason (insufficient resources, taints, affinity, volumes)"
+			defaultValue = defaults.K8SEnableSchedulingFailures
+		)
+
+		rootCmd.PersistentFlags().Bool(longOpt, defaultValue, envDescription(description, envVar))
+		if err := viper.BindPFlag(key, rootCmd.PersistentFlags().Lookup(longOpt)); err != nil {
+			bindFlagError(longOpt, err)
+		}
+		if err := viper.BindEnv(key, envVar); err != nil {
+			bindEnvError(envVar, err)
+		}
+		viper.SetDefault(key, defaultValue)
+	}
+
+	{
+		const (
+			key          = keys.K8SEnableEvictions
+			longOpt      = "k8s-enable-evictions"
+			envVar       = release.ENVPREFIX + "_K8S_ENABLE_EVICTIONS"
+			description  = "Kubernetes enable collection of pod eviction and preemption counts"
+			defaultValue = defaults.K8SEnableEvictions
+		)
+
+		rootCmd.PersistentFlags().Bool(longOpt, defaultValue, envDescription(description, envVar))
+		if err := viper.BindPFlag(key, rootCmd.PersistentFlags().Lookup(longOpt)); err != nil {
+			bindFlagError(longOpt, err)
+		}
+		if err := viper.BindEnv(key, envVar); err != nil {
+			bindEnvError(envVar, err)
+		}
+		viper.SetDefault(key, defaultValue)
+	}
+
+	{
+		const (
+			key          = keys.K8SEnableSpotInterruptions
+			longOpt      = "k8s-enable-spot-interruptions"
+			envVar       = release.ENVPREFIX + "_K8S_ENABLE_SPOT_INTERRUPTIONS"
+			description  = "Kubernetes enable collection of spot/preemptible node interruptions"
+			defaultValue = defaults.K8SEnableSpotInterruptions
+		)
+
+		rootCmd.PersistentFlags().Bool(longOpt, defaultValue, envDescription(description, envVar))
+		if err := viper.BindPFlag(key, rootCmd.PersistentFlags().Lookup(longOpt)); err != nil {
+			bindFlagError(longOpt, err)
+		}
+		if err := viper.BindEnv(key, envVar); err != nil {
+			bindEnvError(envVar, err)
+		}
+		viper.SetDefault(key, defaultValue)
+	}
+
+	{
+		const (
+			key          = keys.K8SEnableManagedControlPlane
+			longOpt      = "k8s-enable-managed-control-plane"
+			envVar       = release.ENVPREFIX + "_K8S_ENABLE_MANAGED_CONTROL_PLANE"
+			description  = "Kubernetes enable collection of managed control-plane health checks and provider fallbacks"
+			defaultValue = defaults.K8SEnableManagedControlPlane
+		)
+
+		rootCmd.PersistentFlags().Bool(longOpt, defaultValue, envDescription(description, envVar))
+		if err := viper.BindPFlag(key, rootCmd.PersistentFlags().Lookup(longOpt)); err != nil {
+			bindFlagError(longOpt, err)
+		}
+		if err := viper.BindEnv(key, envVar); err != nil {
+			bindEnvError(envVar, err)
+		}
+		viper.SetDefault(key, defaultValue)
+	}
+
+	{
+		const (
+			key          = keys.K8SManagedControlPlaneProvider
+			longOpt      = "k8s-managed-control-plane-provider"
+			envVar       = release.ENVPREFIX + "_K8S_MANAGED_CONTROL_PLANE_PROVIDER"
+			description  = "Kubernetes managed control-plane provider (auto, eks, gke, aks, none)"
+			defaultValue = defaults.K8SManagedControlPlaneProvider
+		)
+
+		rootCmd.PersistentFlags().String(longOpt, defaultValue, envDescription(description, envVar))
+		if err := viper.BindPFlag(key, rootCmd.PersistentFlags().Lookup(longOpt)); err != nil {
+			bindFlagError(longOpt, err)
+		}
+		if err := viper.BindEnv(key, envVar); err != nil {
+			bindEnvError(envVar, err)
+		}
+		viper.SetDefault(key, defaultValue)
+	}
+
+	{
+		const (
+			key          = keys.K8SEnableEKSCloudWatch
+			longOpt      = "k8s-enable-eks-cloudwatch"
+			envVar       = release.ENVPREFIX + "_K8S_ENABLE_EKS_CLOUDWATCH"
+			description  = "Kubernetes enable collection of EKS control-plane metrics from CloudWatch"
+			defaultValue = defaults.K8SEnableEKSCloudWatch
+		)
+
+		rootCmd.PersistentFlags().Bool(longOpt, defaultValue, envDescription(description, envVar))
+		if err := viper.BindPFlag(key, rootCmd.PersistentFlags().Lookup(longOpt)); err != nil {
+			bindFlagError(longOpt, err)
+		}
+		if err := viper.BindEnv(key, envVar); err != nil {
+			bindEnvError(envVar, err)
+		}
+		viper.SetDefault(key, defaultValue)
+	}
+
+	{
+		const (
+			key          = keys.K8SEKSClusterName
+			longOpt      = "k8s-eks-cluster-name"
+			envVar       = release.ENVPREFIX + "_K8S_EKS_CLUSTER_NAME"
+			description  = "Kubernetes EKS cluster name (for CloudWatch metrics)"
+			defaultValue = defaults.K8SEKSClusterName
+		)
+
+		rootCmd.PersistentFlags().String(longOpt, defaultValue, envDescription(description, envVar))
+		if err := viper.BindPFlag(key, rootCmd.PersistentFlags().Lookup(longOpt)); err != nil {
+			bindFlagError(longOpt, err)
+		}
+		if err := viper.BindEnv(key, envVar); err != nil {
+			bindEnvError(envVar, err)
+		}
+		viper.SetDefault(key, defaultValue)
+	}
+
+	{
+		const (
+			key          = keys.K8SEnableNodeVersions
+			longOpt      = "k8s-enable-node-versions"
+			envVar       = release.ENVPREFIX + "_K8S_ENABLE_NODE_VERSIONS"
+			description  = "Kubernetes enable collection of node kubelet, container runtime, kernel, and os image versions"
+			defaultValue = defaults.K8SEnableNodeVersions
+		)
+
+		rootCmd.PersistentFlags().Bool(longOpt, defaultValue, envDescription(description, envVar))
+		if err := viper.BindPFlag(key, rootCmd.PersistentFlags().Lookup(longOpt)); err != nil {
+			bindFlagError(longOpt, err)
+		}
+		if err := viper.BindEnv(key, envVar); err != nil {
+			bindEnvError(envVar, err)
+		}
+		viper.SetDefault(key, defaultValue)
+	}
+
+	{
+		const (
+			key          = keys.K8SEnableClusterVersion
+			longOpt      = "k8s-enable-cluster-version"
+			envVar       = release.ENVPREFIX + "_K8S_ENABLE_CLUSTER_VERSION"
+			description  = "Kubernetes enable collection of the cluster version and kubelet version skew"
+			defaultValue = defaults.K8SEnableClusterVersion
+		)
+
+		rootCmd.PersistentFlags().Bool(longOpt, defaultValue, envDescription(description, envVar))
+		if err := viper.BindPFlag(key, rootCmd.PersistentFlags().Lookup(longOpt)); err != nil {
+			bindFlagError(longOpt, err)
+		}
+		if err := viper.BindEnv(key, envVar); err != nil {
+			bindEnvError(envVar, err)
+		}
+		viper.SetDefault(key, defaultValue)
+	}
+
+	{
+		const (
+			key          = keys.K8SEnableOrphans
+			longOpt      = "k8s-enable-orphans"
+			envVar       = release.ENVPREFIX + "_K8S_ENABLE_ORPHANS"
+			description  = "Kubernetes enable collection of orphaned and lingering resource counts"
+			defaultValue = defaults.K8SEnableOrphans
+		)
+
+		rootCmd.PersistentFlags().Bool(longOpt, defaultValue, envDescription(description, envVar))
+		if err := viper.BindPFlag(key, rootCmd.PersistentFlags().Lookup(longOpt)); err != nil {
+			bindFlagError(longOpt, err)
+		}
+		if err := viper.BindEnv(key, envVar); err != nil {
+			bindEnvError(envVar, err)
+		}
+		viper.SetDefault(key, defaultValue)
+	}
+
+	{
+		const (
+			key          = keys.K8SOrphansLingerThreshold
+			longOpt      = "k8s-orphans-linger-threshold"
+			envVar       = release.ENVPREFIX + "_K8S_ORPHANS_LINGER_THRESHOLD"
+			description  = "Kubernetes how long evicted/completed pods linger before being counted as cleanup candidates"
+			defaultValue = defaults.K8SOrphansLingerThreshold
+		)
+
+		rootCmd.PersistentFlags().String(longOpt, defaultValue, envDescription(description, envVar))
+		if err := viper.BindPFlag(key, rootCmd.PersistentFlags().Lookup(longOpt)); err != nil {
+			bindFlagError(longOpt, err)
+		}
+		if err := viper.BindEnv(key, envVar); err != nil {
+			bindEnvError(envVar, err)
+		}
+		viper.SetDefault(key, defaultValue)
+	}
+
+	{
+		const (
+			key          = keys.K8SEnableTopN
+			longOpt      = "k8s-enable-top-n"
+			envVar       = release.ENVPREFIX + "_K8S_ENABLE_TOP_N"
+			description  = "Kubernetes enable collection of the top N pods and namespaces by cpu and memory usage"
+			defaultValue = defaults.K8SEnableTopN
+		)
+
+		rootCmd.PersistentFlags().Bool(longOpt, defaultValue, envDescription(description, envVar))
+		if err := viper.BindPFlag(key, rootCmd.PersistentFlags().Lookup(longOpt)); err != nil {
+			bindFlagError(longOpt, err)
+		}
+		if err := viper.BindEnv(key, envVar); err != nil {
+			bindEnvError(envVar, err)
+		}
+		viper.SetDefault(key, defaultValue)
+	}
+
+	{
+		const (
+			key          = keys.K8STopN
+			longOpt      = "k8s-top-n"
+			envVar       = release.ENVPREFIX + "_K8S_TOP_N"
+			description  = "Kubernetes number of pods and namespaces emitted per resource by the top-n collector"
+			defaultValue = defaults.K8STopN
+		)
+
+		rootCmd.PersistentFlags().Uint(longOpt, defaultValue, envDescription(description, envVar))
+		if err := viper.BindPFlag(key, rootCmd.PersistentFlags().Lookup(longOpt)); err != nil {
+			bindFlagError(longOpt, err)
+		}
+		if err := viper.BindEnv(key, envVar); err != nil {
+			bindEnvError(envVar, err)
+		}
+		viper.SetDefault(key, defaultValue)
+	}
+
+	{
+		const (
+			key          = keys.K8SEnableUtilization
+			longOpt      = "k8s-enable-utilization"
+			envVar       = release.ENVPREFIX + "_K8S_ENABLE_UTILIZATION"
+			description  = "Kubernetes enable collection of container usage/request and usage/limit ratios"
+			defaultValue = defaults.K8SEnableUtilization
+		)
+
+		rootCmd.PersistentFlags().Bool(longOpt, defaultValue, envDescription(description, envVar))
+		if err := viper.BindPFlag(key, rootCmd.PersistentFlags().Lookup(longOpt)); err != nil {
+			bindFlagError(longOpt, err)
+		}
+		if err := viper.BindEnv(key, envVar); err != nil {
+			bindEnvError(envVar, err)
+		}
+		viper.SetDefault(key, defaultValue)
+	}
+
+	{
+		const (
+			key          = keys.K8SEnableRecordingRules
+			longOpt      = "k8s-enable-recording-rules"
+			envVar       = release.ENVPREFIX + "_K8S_ENABLE_RECORDING_RULES"
+			description  = "Kubernetes enable recording rules (derived metrics)"
+			defaultValue = defaults.K8SEnableRecordingRules
+		)
+
+		rootCmd.PersistentFlags().Bool(longOpt, defaultValue, envDescription(description, envVar))
+		if err := viper.BindPFlag(key, rootCmd.PersistentFlags().Lookup(longOpt)); err != nil {
+			bindFlagError(longOpt, err)
+		}
+		if err := viper.BindEnv(key, envVar); err != nil {
+			bindEnvError(envVar, err)
+		}
+		viper.SetDefault(key, defaultValue)
+	}
+
+	{
+		const (
+			key          = keys.K8SRecordingRulesFile
+			longOpt      = "k8s-recording-rules-file"
+			envVar       = release.ENVPREFIX + "_K8S_RECORDING_RULES_FILE"
+			description  = "Kubernetes recording rules file"
+			defaultValue = defaults.K8SRecordingRulesFile
+		)
+
+		rootCmd.PersistentFlags().String(longOpt, defaultValue, envDescription(description, envVar))
+		if err := viper.BindPFlag(key, rootCmd.PersistentFlags().Lookup(longOpt)); err != nil {
+			bindFlagError(longOpt, err)
+		}
+		if err := viper.BindEnv(key, envVar); err != nil {
+			bindEnvError(envVar, err)
+		}
+		viper.SetDefault(key, defaultValue)
+	}
+
+	{
+		const (
+			key          = keys.K8SEnableSLOs
+			longOpt      = "k8s-enable-slos"
+			envVar       = release.ENVPREFIX + "_K8S_ENABLE_SLOS"
+			description  = "Enable SLO error budget burn rate metrics for the slos in the slos file"
+			defaultValue = defaults.K8SEnableSLOs
+		)
+
+		rootCmd.PersistentFlags().Bool(longOpt, defaultValue, envDescription(description, envVar))
+		if err := viper.BindPFlag(key, rootCmd.PersistentFlags().Lookup(longOpt)); err != nil {
+			bindFlagError(longOpt, err)
+		}
+		if err := viper.BindEnv(key, envVar); err != nil {
+			bindEnvError(envVar, err)
+		}
+		viper.SetDefault(key, defaultValue)
+	}
+
+	{
+		const (
+			key          = keys.K8SSLOsFile
+			longOpt      = "k8s-slos-file"
+			envVar       = release.ENVPREFIX + "_K8S_SLOS_FILE"
+			description  = "SLOs file"
+			defaultValue = defaults.K8SSLOsFile
+		)
+
+		rootCmd.PersistentFlags().String(longOpt, defaultValue, envDescription(description, envVar))
+		if err := viper.BindPFlag(key, rootCmd.PersistentFlags().Lookup(longOpt)); err != nil {
+			bindFlagError(longOpt, err)
+		}
+		if err := viper.BindEnv(key, envVar); err != nil {
+			bindEnvError(envVar, err)
+		}
+		viper.SetDefault(key, defaultValue)
+	}
+
+	{
+		const (
+			key          = keys.K8SEnableEtcdObjects
+			longOpt      = "k8s-enable-etcd-objects"
+			envVar       = release.ENVPREFIX + "_K8S_ENABLE_ETCD_OBJECTS"
+			description  = "Enable etcd object count per resource type collection (api-server apiserver_storage_objects)"
+			defaultValue = defaults.K8SEnableEtcdObjects
+		)
+
+		rootCmd.PersistentFlags().Bool(longOpt, defaultValue, envDescription(description, envVar))
+		if err := viper.BindPFlag(key, rootCmd.PersistentFlags().Lookup(longOpt)); err != nil {
+			bindFlagError(longOpt, err)
+		}
+		if err := viper.BindEnv(key, envVar); err != nil {
+			bindEnvError(envVar, err)
+		}
+		viper.SetDefault(key, defaultValue)
+	}
+
+	{
+		const (
+			key          = keys.K8SEnableAgentMetricFilters
+			longOpt      = "k8s-enable-agent-metric-filters"
+			envVar       = release.ENVPREFIX + "_K8S_ENABLE_AGENT_METRIC_FILTERS"
+			description  = "Enable agent side metric filtering (regex allow/deny rules per collector) before submission"
+			defaultValue = defaults.K8SEnableAgentMetricFilters
+		)
+
+		rootCmd.PersistentFlags().Bool(longOpt, defaultValue, envDescription(description, envVar))
+		if err := viper.BindPFlag(key, rootCmd.PersistentFlags().Lookup(longOpt)); err != nil {
+			bindFlagError(longOpt, err)
+		}
+		if err := viper.BindEnv(key, envVar); err != nil {
+			bindEnvError(envVar, err)
+		}
+		viper.SetDefault(key, defaultValue)
+	}
+
+	{
+		const (
+			key          = keys.K8SAgentMetricFiltersFile
+			longOpt      = "k8s-agent-metric-filters-file"
+			envVar       = release.ENVPREFIX + "_K8S_AGENT_METRIC_FILTERS_FILE"
+			description  = "Agent metric filters file"
+			defaultValue = defaults.K8SAgentMetricFiltersFile
+		)
+
+		rootCmd.PersistentFlags().String(longOpt, defaultValue, envDescription(description, envVar))
+		if err := viper.BindPFlag(key, rootCmd.PersistentFlags().Lookup(longOpt)); err != nil {
+			bindFlagError(longOpt, err)
+		}
+		if err := viper.BindEnv(key, envVar); err != nil {
+			bindEnvError(envVar, err)
+		}
+		viper.SetDefault(key, defaultValue)
+	}
+
+	{
+		const (
+			key          = keys.K8SEnableTagTransforms
+			longOpt      = "k8s-enable-tag-transforms"
+			envVar       = release.ENVPREFIX + "_K8S_ENABLE_TAG_TRANSFORMS"
+			description  = "Enable stream tag transforms (rename, drop, or add tag categories) for all metrics"
+			defaultValue = defaults.K8SEnableTagTransforms
+		)
+
+		rootCmd.PersistentFlags().Bool(longOpt, defaultValue, envDescription(description, envVar))
+		if err := viper.BindPFlag(key, rootCmd.PersistentFlags().Lookup(longOpt)); err != nil {
+			bindFlagError(longOpt, err)
+		}
+		if err := viper.BindEnv(key, envVar); err != nil {
+			bindEnvError(envVar, err)
+		}
+		viper.SetDefault(key, defaultValue)
+	}
+
+	{
+		const (
+			key          = keys.K8STagTransformsFile
+			longOpt      = "k8s-tag-transforms-file"
+			envVar       = release.ENVPREFIX + "_K8S_TAG_TRANSFORMS_FILE"
+			description  = "Tag transforms file"
+			defaultValue = defaults.K8STagTransformsFile
+		)
+
+		rootCmd.PersistentFlags().String(longOpt, defaultValue, envDescription(description, envVar))
+		if err := viper.BindPFlag(key, rootCmd.PersistentFlags().Lookup(longOpt)); err != nil {
+			bindFlagError(longOpt, err)
+		}
+		if err := viper.BindEnv(key, envVar); err != nil {
+			bindEnvError(envVar, err)
+		}
+		viper.SetDefault(key, defaultValue)
+	}
+
+	{
+		const (
+			key          = keys.K8SKubeconfig
+			longOpt      = "k8s-kubeconfig"
+			envVar       = release.ENVPREFIX + "_K8S_KUBECONFIG"
+			description  = "Kubeconfig file the api url and credentials are read from, for running outside of the cluster (e.g. ~/.kube/config)"
+			defaultValue = defaults.K8SKubeconfig
+		)
+
+		rootCmd.PersistentFlags().String(longOpt, defaultValue, envDescription(description, envVar))
+		if err := viper.BindPFlag(key, rootCmd.PersistentFlags().Lookup(longOpt)); err != nil {
+			bindFlagError(longOpt, err)
+		}
+		if err := viper.BindEnv(key, envVar); err != nil {
+			bindEnvError(envVar, err)
+		}
+		viper.SetDefault(key, defaultValue)
+	}
+
+	{
+		const (
+			key          = keys.K8SKubeconfigContext
+			longOpt      = "k8s-kubeconfig-context"
+			envVar       = release.ENVPREFIX + "_K8S_KUBECONFIG_CONTEXT"
+			description  = "Kubeconfig context to use (default the current context)"
+			defaultValue = defaults.K8SKubeconfigContext
+		)
+
+		rootCmd.PersistentFlags().String(longOpt, defaultValue, envDescription(description, envVar))
+		if err := viper.BindPFlag(key, rootCmd.PersistentFlags().Lookup(longOpt)); err != nil {
+			bindFlagError(longOpt, err)
+		}
+		if err := viper.BindEnv(key, envVar); err != nil {
+			bindEnvError(envVar, err)
+		}
+		viper.SetDefault(key, defaultValue)
+	}
+
+	{
+		const (
+			key          = keys.K8SIncludeNamespaces
+			longOpt      = "k8s-include-namespaces"
+			envVar       = release.ENVPREFIX + "_K8S_INCLUDE_NAMESPACES"
+			description  = "Only report metrics of these namespaces, comma separated, a trailing * matches a prefix (default all)"
+			defaultValue = defaults.K8SIncludeNamespaces
+		)
+
+		rootCmd.PersistentFlags().String(longOpt, defaultValue, envDescription(description, envVar))
+		if err := viper.BindPFlag(key, rootCmd.PersistentFlags().Lookup(longOpt)); err != nil {
+			bindFlagError(longOpt, err)
+		}
+		if err := viper.BindEnv(key, envVar); err != nil {
+			bindEnvError(envVar, err)
+		}
+		viper.SetDefault(key, defaultValue)
+	}
+
+	{
+		const (
+			key          = keys.K8SExcludeNamespaces
+			longOpt      = "k8s-exclude-namespaces"
+			envVar       = release.ENVPREFIX + "_K8S_EXCLUDE_NAMESPACES"
+			description  = "Do not report metrics of these namespaces, comma separated, a trailing * matches a prefix"
+			defaultValue = defaults.K8SExcludeNamespaces
+		)
+
+		rootCmd.PersistentFlags().String(longOpt, defaultValue, envDescription(description, envVar))
+		if err := viper.BindPFlag(key, rootCmd.PersistentFlags().Lookup(longOpt)); err != nil {
+			bindFlagError(longOpt, err)
+		}
+		if err := viper.BindEnv(key, envVar); err != nil {
+			bindEnvError(envVar, err)
+		}
+		viper.SetDefault(key, defaultValue)
+	}
+
 }
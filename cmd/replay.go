@@ -0,0 +1,82 @@
+// Copyright © 2019 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/circonus"
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/config"
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/config/keys"
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/k8s"
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/release"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+var (
+	replayShard string
+	replayKeep  bool
+)
+
+// replayCmd replays dead-letter payloads to the cluster's check
+var replayCmd = &cobra.Command{
+	Use:   "replay [file|dir ...]",
+	Short: "Replay failed submissions from the dead-letter directory",
+	Long: `Replay submissions which were written to the dead-letter directory, oldest
+first, stopping at the first failure. With no arguments all payloads in the
+dead-letter directory are replayed. The check is found using the same settings
+as the agent (check bundle cid, or target of the single kubernetes cluster).
+Replayed payloads are removed unless --keep is used.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		var cfg *config.Config
+		if err := viper.Unmarshal(&cfg); err != nil {
+			return errors.Wrap(err, "parsing config")
+		}
+
+		circCfg := cfg.Circonus
+		circCfg.UseGZIP = !viper.GetBool(keys.NoGZIP)
+		circCfg.DryRun = viper.GetBool(keys.DryRun)
+		circCfg.Check.Create = false // only replay to an existing check
+		if circCfg.Check.Title == "" {
+			circCfg.Check.Title = fmt.Sprintf("%s /%s", cfg.Kubernetes.Name, release.NAME)
+		}
+		if circCfg.Check.Target == "" {
+			circCfg.Check.Target = strings.Replace(cfg.Kubernetes.Name, " ", "_", -1)
+		}
+
+		if circCfg.API.KeySecret != "" {
+			key, err := k8s.SecretRefValue(&cfg.Kubernetes, circCfg.API.KeySecret)
+			if err != nil {
+				return errors.Wrap(err, "reading api key secret")
+			}
+			circCfg.API.Key = key
+		}
+
+		if err := circonus.ConfigureProxy(circCfg.Proxy, log.Logger); err != nil {
+			return errors.Wrap(err, "configuring proxy")
+		}
+
+		check, err := circonus.NewCheck(log.Logger, &circCfg)
+		if err != nil {
+			return errors.Wrap(err, "initializing check")
+		}
+
+		n, err := check.ReplayDeadLetters(context.Background(), replayShard, args, replayKeep)
+		log.Info().Int("replayed", n).Msg("dead-letter replay")
+		return err
+	},
+}
+
+func init() {
+	replayCmd.Flags().StringVar(&replayShard, "shard", "", "Replay to the named check shard")
+	replayCmd.Flags().BoolVar(&replayKeep, "keep", false, "Keep replayed payloads")
+	rootCmd.AddCommand(replayCmd)
+}
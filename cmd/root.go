@@ -173,6 +173,11 @@ func initConfig() {
 		if f != "" {
 			log.Fatal().Err(err).Str("config_file", f).Msg("unable to load config file")
 		}
+		return
+	}
+
+	if err := config.ExpandConfigFileEnv(); err != nil {
+		log.Fatal().Err(err).Str("config_file", viper.ConfigFileUsed()).Msg("expanding environment variables in config file")
 	}
 }
 
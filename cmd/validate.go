@@ -0,0 +1,60 @@
+// Copyright © 2019 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+package cmd
+
+import (
+	"os"
+	"time"
+
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/validate"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+var (
+	validateFormat  string
+	validateTimeout time.Duration
+)
+
+// validateCmd verifies the configuration, connectivity, and permissions
+var validateCmd = &cobra.Command{
+	Use:   "validate",
+	Short: "Validate the configuration, api connectivity, and permissions",
+	Long: `Validate the configuration (using the same config file, flags, and environment
+as the agent), then for each cluster verify the kubernetes api is reachable, the
+bearer token has the permissions the agent needs, the circonus api token is valid,
+and the broker is reachable. Prints a report (text or json) and exits non-zero if
+any step fails, for use as an init-container or CI gate. Nothing is created or
+changed.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		report := validate.Run(validateTimeout)
+
+		var err error
+		switch validateFormat {
+		case "json":
+			err = report.WriteJSON(os.Stdout)
+		case "text":
+			err = report.WriteText(os.Stdout)
+		default:
+			return errors.Errorf("invalid format %q (text, json)", validateFormat)
+		}
+		if err != nil {
+			return err
+		}
+
+		if !report.OK {
+			cmd.SilenceUsage = true
+			return errors.New("validation failed")
+		}
+		return nil
+	},
+}
+
+func init() {
+	validateCmd.Flags().StringVar(&validateFormat, "format", "text", "Report format (text, json)")
+	validateCmd.Flags().DurationVar(&validateTimeout, "timeout", 10*time.Second, "Timeout for each connectivity check")
+	rootCmd.AddCommand(validateCmd)
+}
@@ -14,15 +14,13 @@ import (
 	"os"
 	"os/signal"
 
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/circonus"
 	"github.com/circonus-labs/circonus-kubernetes-agent/internal/cluster"
 	"github.com/circonus-labs/circonus-kubernetes-agent/internal/config"
-	"github.com/circonus-labs/circonus-kubernetes-agent/internal/config/defaults"
-	"github.com/circonus-labs/circonus-kubernetes-agent/internal/config/keys"
 	"github.com/circonus-labs/circonus-kubernetes-agent/internal/release"
 	"github.com/pkg/errors"
 	"github.com/rs/zerolog"
 	"github.com/rs/zerolog/log"
-	"github.com/spf13/viper"
 	"golang.org/x/sync/errgroup"
 )
 
@@ -33,6 +31,7 @@ type Agent struct {
 	groupCancel context.CancelFunc
 	clusters    map[string]*cluster.Cluster
 	signalCh    chan os.Signal
+	reloadCh    chan struct{}
 	logger      zerolog.Logger
 }
 
@@ -41,56 +40,31 @@ func New() (*Agent, error) {
 	ctx, cancel := context.WithCancel(context.Background())
 	g, gctx := errgroup.WithContext(ctx)
 
-	var err error
 	a := Agent{
 		group:       g,
 		groupCtx:    gctx,
 		groupCancel: cancel,
 		clusters:    make(map[string]*cluster.Cluster),
 		signalCh:    make(chan os.Signal, 10),
+		reloadCh:    make(chan struct{}, 1),
 		logger:      log.With().Str("pkg", "agent").Logger(),
 	}
 
-	err = config.Validate()
+	cfg, err := loadConfig()
 	if err != nil {
 		return nil, err
 	}
 
-	var cfg *config.Config
-
-	if err := viper.Unmarshal(&cfg); err != nil {
-		return nil, errors.Wrap(err, "parsing config")
-	}
-
-	// Set the hidden settings based on viper
-	cfg.Circonus.ConcurrentSubmissions = defaults.ConcurrentSubmissions
-	cfg.Circonus.SerialSubmissions = defaults.SerialSubmissions
-	if viper.GetBool(keys.SerialSubmissions) != defaults.SerialSubmissions {
-		cfg.Circonus.SerialSubmissions = true
-		cfg.Circonus.ConcurrentSubmissions = false
-	}
-	cfg.Circonus.MaxMetricBucketSize = defaults.MaxMetricBucketSize
-	if viper.GetUint(keys.MaxMetricBucketSize) != defaults.MaxMetricBucketSize {
-		cfg.Circonus.MaxMetricBucketSize = viper.GetInt(keys.MaxMetricBucketSize)
-	}
-	cfg.Circonus.Base64Tags = defaults.Base64Tags
-	if viper.GetBool(keys.NoBase64) {
-		cfg.Circonus.Base64Tags = false
-	}
-	cfg.Circonus.UseGZIP = defaults.UseGZIP
-	if viper.GetBool(keys.NoGZIP) {
-		cfg.Circonus.UseGZIP = false
+	if err := circonus.ConfigureProxy(cfg.Circonus.Proxy, a.logger); err != nil {
+		return nil, errors.Wrap(err, "configuring proxy")
 	}
-	cfg.Circonus.DryRun = viper.GetBool(keys.DryRun)
-	// cfg.Circonus.StreamMetrics = viper.GetBool(keys.StreamMetrics)
-	cfg.Circonus.DebugSubmissions = viper.GetBool(keys.DebugSubmissions)
 
 	if len(cfg.Clusters) > 0 { // multiple clusters
 		for _, clusterConfig := range cfg.Clusters {
 			clusterConfig := clusterConfig
-			c, err := cluster.New(clusterConfig, cfg.Circonus, a.logger)
+			c, err := cluster.New(clusterConfig, config.ClusterCirconusConfig(cfg.Circonus, clusterConfig), a.logger)
 			if err != nil {
-				a.logger.Error().Err(err).Msg("configuring cluster, skipping...")
+				a.logger.Error().Err(err).Str("cluster_name", clusterConfig.Name).Msg("configuring cluster, skipping...")
 				continue
 			}
 			a.clusters[clusterConfig.Name] = c
@@ -109,10 +83,12 @@ func New() (*Agent, error) {
 	}
 
 	a.signalNotifySetup()
+	a.watchConfig()
 
 	go func() {
 		// NOTE: http://addr:8080/stats - application stats
 		//       http://addr:8080/health - liveness probe
+		//       http://addr:8080/metrics - agent metrics (openmetrics)
 		err := http.ListenAndServe(":8080",
 			http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 				switch r.Method {
@@ -120,6 +96,8 @@ func New() (*Agent, error) {
 					switch r.URL.Path {
 					case "/stats", "/stats/":
 						expvar.Handler().ServeHTTP(w, r)
+					case "/metrics", "/metrics/":
+						circonus.MetricsHandler().ServeHTTP(w, r)
 					case "/health", "/health/":
 						w.WriteHeader(http.StatusOK)
 						fmt.Fprintln(w, "Alive")
@@ -142,6 +120,7 @@ func New() (*Agent, error) {
 func (a *Agent) Start() error {
 
 	a.group.Go(a.handleSignals)
+	a.group.Go(a.handleReloads)
 
 	for id := range a.clusters {
 		id := id
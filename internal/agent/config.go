@@ -0,0 +1,144 @@
+// Copyright © 2019 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+package agent
+
+import (
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/config"
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/config/defaults"
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/config/keys"
+	"github.com/fsnotify/fsnotify"
+	"github.com/pkg/errors"
+	"github.com/spf13/viper"
+)
+
+// loadConfig validates and parses the configuration
+func loadConfig() (*config.Config, error) {
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+
+	var cfg *config.Config
+
+	if err := viper.Unmarshal(&cfg); err != nil {
+		return nil, errors.Wrap(err, "parsing config")
+	}
+
+	if len(cfg.Clusters) > 0 {
+		if err := config.ValidateClusters(cfg.Clusters); err != nil {
+			return nil, errors.Wrap(err, "clusters config")
+		}
+	}
+
+	// Set the hidden settings based on viper
+	cfg.Circonus.SerialSubmissions = defaults.SerialSubmissions
+	if viper.GetBool(keys.SerialSubmissions) != defaults.SerialSubmissions {
+		cfg.Circonus.SerialSubmissions = true
+	}
+	cfg.Circonus.MaxMetricBucketSize = defaults.MaxMetricBucketSize
+	if viper.GetUint(keys.MaxMetricBucketSize) != defaults.MaxMetricBucketSize {
+		cfg.Circonus.MaxMetricBucketSize = viper.GetInt(keys.MaxMetricBucketSize)
+	}
+	cfg.Circonus.Base64Tags = defaults.Base64Tags
+	if viper.GetBool(keys.NoBase64) {
+		cfg.Circonus.Base64Tags = false
+	}
+	cfg.Circonus.UseGZIP = defaults.UseGZIP
+	if viper.GetBool(keys.NoGZIP) {
+		cfg.Circonus.UseGZIP = false
+	}
+	cfg.Circonus.DryRun = viper.GetBool(keys.DryRun)
+	// cfg.Circonus.StreamMetrics = viper.GetBool(keys.StreamMetrics)
+	cfg.Circonus.DebugSubmissions = viper.GetBool(keys.DebugSubmissions)
+
+	return cfg, nil
+}
+
+// clusterConfigs returns the configuration of each cluster
+func clusterConfigs(cfg *config.Config) []config.Cluster {
+	if len(cfg.Clusters) > 0 {
+		return cfg.Clusters
+	}
+	return []config.Cluster{cfg.Kubernetes}
+}
+
+// watchConfig requests a reload when the config file changes (--watch-config)
+func (a *Agent) watchConfig() {
+	if !viper.GetBool(keys.WatchConfig) {
+		return
+	}
+	file := viper.ConfigFileUsed()
+	if file == "" {
+		a.logger.Warn().Msg("no config file, not watching for changes")
+		return
+	}
+	viper.OnConfigChange(func(e fsnotify.Event) {
+		a.logger.Info().Str("file", e.Name).Str("op", e.Op.String()).Msg("config file changed")
+		a.requestReload()
+	})
+	viper.WatchConfig()
+	a.logger.Info().Str("file", file).Msg("watching config file for changes")
+}
+
+// requestReload queues a configuration reload, requests made while a reload is
+// queued are combined
+func (a *Agent) requestReload() {
+	select {
+	case a.reloadCh <- struct{}{}:
+	default:
+	}
+}
+
+// handleReloads reloads the configuration when requested, it does not return
+// until the agent is stopped
+func (a *Agent) handleReloads() error {
+	for {
+		select {
+		case <-a.reloadCh:
+			a.reload()
+		case <-a.groupCtx.Done():
+			return nil
+		}
+	}
+}
+
+// reload re-reads the configuration and reloads each cluster's configuration,
+// clusters added to or removed from the configuration require a restart
+func (a *Agent) reload() {
+	if viper.ConfigFileUsed() != "" {
+		if err := viper.ReadInConfig(); err != nil {
+			a.logger.Error().Err(err).Msg("reading config file, keeping current configuration")
+			return
+		}
+		if err := config.ExpandConfigFileEnv(); err != nil {
+			a.logger.Error().Err(err).Msg("expanding config file, keeping current configuration")
+			return
+		}
+	}
+
+	cfg, err := loadConfig()
+	if err != nil {
+		a.logger.Error().Err(err).Msg("reloading config, keeping current configuration")
+		return
+	}
+
+	seen := make(map[string]bool)
+	for _, clusterConfig := range clusterConfigs(cfg) {
+		seen[clusterConfig.Name] = true
+		c, ok := a.clusters[clusterConfig.Name]
+		if !ok {
+			a.logger.Warn().Str("cluster_name", clusterConfig.Name).Msg("cluster added to config, restart required")
+			continue
+		}
+		if err := c.Reload(clusterConfig); err != nil {
+			a.logger.Error().Err(err).Str("cluster_name", clusterConfig.Name).Msg("reloading cluster, keeping current configuration")
+		}
+	}
+	for name := range a.clusters {
+		if !seen[name] {
+			a.logger.Warn().Str("cluster_name", name).Msg("cluster removed from config, restart required")
+		}
+	}
+}
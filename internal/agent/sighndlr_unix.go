@@ -3,6 +3,8 @@
 // license that can be found in the LICENSE file.
 //
 
+// +build !windows
+
 package agent
 
 import (
@@ -34,7 +36,9 @@ func (a *Agent) handleSignals() error {
 			switch sig {
 			case os.Interrupt, unix.SIGTERM:
 				a.Stop()
-			case unix.SIGPIPE, unix.SIGHUP:
+			case unix.SIGHUP:
+				a.requestReload()
+			case unix.SIGPIPE:
 				// Noop
 			case unix.SIGTRAP:
 				stacklen := runtime.Stack(buf, true)
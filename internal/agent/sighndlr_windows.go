@@ -0,0 +1,38 @@
+// Copyright © 2019 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+// +build windows
+
+package agent
+
+import (
+	"os"
+	"os/signal"
+
+	"github.com/rs/zerolog/log"
+	"golang.org/x/sys/windows"
+)
+
+func (a *Agent) signalNotifySetup() {
+	signal.Notify(a.signalCh, os.Interrupt, windows.SIGTERM)
+}
+
+// handleSignals runs the signal handler thread
+func (a *Agent) handleSignals() error {
+	for {
+		select {
+		case sig := <-a.signalCh:
+			log.Info().Str("signal", sig.String()).Msg("received signal")
+			switch sig {
+			case os.Interrupt, windows.SIGTERM:
+				a.Stop()
+			default:
+				log.Warn().Str("signal", sig.String()).Msg("unsupported")
+			}
+		case <-a.groupCtx.Done():
+			return nil
+		}
+	}
+}
@@ -0,0 +1,210 @@
+// Copyright © 2019 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+// Package apiserver is the kube-apiserver metrics collector
+package apiserver
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"regexp"
+	"sync"
+	"time"
+
+	cgm "github.com/circonus-labs/circonus-gometrics/v3"
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/circonus"
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/config"
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/config/defaults"
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/k8s"
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/promtext"
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/release"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog"
+)
+
+// metricFamilies are the api-server metric families forwarded, the full
+// api-server /metrics output is very large and mostly not useful
+var metricFamilies = []string{
+	"apiserver_request_duration_seconds", // request latency
+	"apiserver_request_total",            // request counts by verb/resource/code
+	"apiserver_current_inflight_requests",
+	"apiserver_longrunning_gauge",
+	"apiserver_registered_watchers",
+	// api priority and fairness, tagged by priority_level (and flow_schema)
+	"apiserver_flowcontrol_rejected_requests_total",       // requests rejected by reason (queue-full, time-out, concurrency-limit)
+	"apiserver_flowcontrol_request_wait_duration_seconds", // time spent queued
+	"apiserver_flowcontrol_request_execution_seconds",     // time spent executing
+	"apiserver_flowcontrol_current_inqueue_requests",      // requests waiting in queues
+	"apiserver_flowcontrol_current_executing_requests",    // requests executing
+	"apiserver_flowcontrol_request_concurrency_limit",     // concurrency limit of each priority level
+	"apiserver_flowcontrol_dispatched_requests_total",     // requests dispatched
+}
+
+type APIServer struct {
+	config       *config.Cluster
+	check        *circonus.Check
+	log          zerolog.Logger
+	familyFilter *regexp.Regexp
+	running      bool
+	apiTimelimit time.Duration
+	sync.Mutex
+	ts *time.Time
+}
+
+func New(cfg *config.Cluster, parentLog zerolog.Logger, check *circonus.Check) (*APIServer, error) {
+	if cfg == nil {
+		return nil, errors.New("invalid cluster config (nil)")
+	}
+	if check == nil {
+		return nil, errors.New("invalid check (nil)")
+	}
+
+	as := &APIServer{
+		config: cfg,
+		check:  check,
+		log:    parentLog.With().Str("collector", "api-server").Logger(),
+	}
+
+	rx, err := familyRegexp(metricFamilies)
+	if err != nil {
+		return nil, errors.Wrap(err, "compiling metric family filter")
+	}
+	as.familyFilter = rx
+
+	if cfg.APITimelimit != "" {
+		v, err := time.ParseDuration(cfg.APITimelimit)
+		if err != nil {
+			as.log.Error().Err(err).Msg("parsing api timelimit, using default")
+		} else {
+			as.apiTimelimit = v
+		}
+	}
+
+	if as.apiTimelimit == time.Duration(0) {
+		v, err := time.ParseDuration(defaults.K8SAPITimelimit)
+		if err != nil {
+			as.log.Fatal().Err(err).Msg("parsing DEFAULT api timelimit")
+		}
+		as.apiTimelimit = v
+	}
+
+	return as, nil
+}
+
+func (as *APIServer) ID() string {
+	return "api-server"
+}
+
+// Collect metrics from the api-server
+func (as *APIServer) Collect(ctx context.Context, tlsConfig *tls.Config, ts *time.Time) {
+	as.Lock()
+	if as.running {
+		as.log.Warn().Msg("already running")
+		as.Unlock()
+		return
+	}
+	as.running = true
+	as.ts = ts
+	as.Unlock()
+
+	defer func() {
+		if r := recover(); r != nil {
+			as.log.Error().Interface("panic", r).Msg("recover")
+			as.Lock()
+			as.running = false
+			as.Unlock()
+		}
+	}()
+
+	collectStart := time.Now()
+
+	metricURL := as.config.URL + "/metrics"
+	if err := as.metrics(ctx, tlsConfig, metricURL); err != nil {
+		as.log.Error().Err(err).Str("url", metricURL).Msg("api-server metrics")
+	}
+
+	as.check.AddHistSample("collect_latency", cgm.Tags{
+		cgm.Tag{Category: "source", Value: release.NAME},
+		cgm.Tag{Category: "op", Value: "collect_api-server"},
+		cgm.Tag{Category: "units", Value: "milliseconds"},
+	}, float64(time.Since(collectStart).Milliseconds()))
+	as.log.Debug().Str("duration", time.Since(collectStart).String()).Msg("api-server collect end")
+	as.Lock()
+	as.running = false
+	as.Unlock()
+}
+
+func (as *APIServer) metrics(ctx context.Context, tlsConfig *tls.Config, metricURL string) error {
+	client, err := k8s.NewAPIClient(tlsConfig, as.apiTimelimit)
+	if err != nil {
+		return errors.Wrap(err, "/metrics cli")
+	}
+	defer client.CloseIdleConnections()
+
+	as.log.Debug().Str("url", metricURL).Msg("metrics")
+	req, err := k8s.NewAPIRequest(as.config.Token(), metricURL)
+	if err != nil {
+		return errors.Wrap(err, "/metrics req")
+	}
+
+	start := time.Now()
+	resp, err := client.Do(req)
+	if err != nil {
+		as.check.IncrementCounter("collect_api_errors", cgm.Tags{
+			cgm.Tag{Category: "source", Value: release.NAME},
+			cgm.Tag{Category: "request", Value: "metrics"},
+			cgm.Tag{Category: "target", Value: "api-server"},
+		})
+		return err
+	}
+	defer resp.Body.Close()
+	as.check.AddHistSample("collect_latency", cgm.Tags{
+		cgm.Tag{Category: "source", Value: release.NAME},
+		cgm.Tag{Category: "request", Value: "metrics"},
+		cgm.Tag{Category: "target", Value: "api-server"},
+		cgm.Tag{Category: "units", Value: "milliseconds"},
+	}, float64(time.Since(start).Milliseconds()))
+
+	if resp.StatusCode != http.StatusOK {
+		as.check.IncrementCounter("collect_api_errors", cgm.Tags{
+			cgm.Tag{Category: "source", Value: release.NAME},
+			cgm.Tag{Category: "request", Value: "metrics"},
+			cgm.Tag{Category: "target", Value: "api-server"},
+			cgm.Tag{Category: "code", Value: fmt.Sprintf("%d", resp.StatusCode)},
+		})
+		data, err := ioutil.ReadAll(resp.Body)
+		if err != nil {
+			as.log.Error().Err(err).Str("url", metricURL).Msg("reading response")
+			return err
+		}
+		as.log.Warn().Str("status", resp.Status).RawJSON("response", data).Msg("error from API server")
+		return errors.New("error response from api server")
+	}
+
+	streamTags := []string{
+		"source:api-server",
+		"source_type:metrics",
+		"__rollup:false", // prevent high cardinality metrics from rolling up
+	}
+	measurementTags := []string{}
+
+	return promtext.QueueFilteredMetrics(ctx, as.check, as.log, resp.Body, as.familyFilter, streamTags, measurementTags, as.ts)
+}
+
+// familyRegexp returns an anchored regular expression matching any of the metric family names
+func familyRegexp(families []string) (*regexp.Regexp, error) {
+	expr := "^("
+	for i, f := range families {
+		if i > 0 {
+			expr += "|"
+		}
+		expr += regexp.QuoteMeta(f)
+	}
+	expr += ")$"
+	return regexp.Compile(expr)
+}
@@ -0,0 +1,33 @@
+// Copyright © 2019 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+package apiserver
+
+import "testing"
+
+func TestFamilyRegexp(t *testing.T) {
+	rx, err := familyRegexp(metricFamilies)
+	if err != nil {
+		t.Fatalf("unexpected error (%s)", err)
+	}
+
+	tests := []struct {
+		name  string
+		match bool
+	}{
+		{"apiserver_request_total", true},
+		{"apiserver_current_inflight_requests", true},
+		{"apiserver_flowcontrol_rejected_requests_total", true},
+		{"apiserver_flowcontrol_request_wait_duration_seconds", true},
+		{"apiserver_request_total_foo", false},
+		{"go_goroutines", false},
+	}
+
+	for _, tst := range tests {
+		if rx.MatchString(tst.name) != tst.match {
+			t.Fatalf("%s expected match=%t", tst.name, tst.match)
+		}
+	}
+}
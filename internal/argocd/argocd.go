@@ -0,0 +1,207 @@
+// Copyright © 2019 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+// Package argocd is the argo cd application health collector
+package argocd
+
+import (
+	"context"
+	"crypto/tls"
+	"sync"
+	"time"
+
+	cgm "github.com/circonus-labs/circonus-gometrics/v3"
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/circonus"
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/config"
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/config/defaults"
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/k8s"
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/release"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+)
+
+var applicationsResource = schema.GroupVersionResource{Group: "argoproj.io", Version: "v1alpha1", Resource: "applications"}
+
+type ArgoCD struct {
+	config       *config.Cluster
+	check        *circonus.Check
+	log          zerolog.Logger
+	dynamic      dynamic.Interface
+	running      bool
+	apiTimelimit time.Duration
+	sync.Mutex
+	ts *time.Time
+}
+
+// NOTES:
+// Application resources (argoproj.io/v1alpha1) are listed, status.sync.status (Synced, OutOfSync,
+// Unknown) and status.health.status (Healthy, Progressing, Degraded, Suspended, Missing, Unknown)
+// are emitted per application as 0/1 gauges tagged with the status, along with the number of
+// applications which are out of sync or degraded.
+
+func New(cfg *config.Cluster, parentLog zerolog.Logger, check *circonus.Check) (*ArgoCD, error) {
+	if cfg == nil {
+		return nil, errors.New("invalid cluster config (nil)")
+	}
+	if check == nil {
+		return nil, errors.New("invalid check (nil)")
+	}
+
+	ac := &ArgoCD{
+		config: cfg,
+		check:  check,
+		log:    parentLog.With().Str("collector", "argocd").Logger(),
+	}
+
+	if cfg.APITimelimit != "" {
+		v, err := time.ParseDuration(cfg.APITimelimit)
+		if err != nil {
+			ac.log.Error().Err(err).Msg("parsing api timelimit, using default")
+		} else {
+			ac.apiTimelimit = v
+		}
+	}
+
+	if ac.apiTimelimit == time.Duration(0) {
+		v, err := time.ParseDuration(defaults.K8SAPITimelimit)
+		if err != nil {
+			ac.log.Fatal().Err(err).Msg("parsing DEFAULT api timelimit")
+		}
+		ac.apiTimelimit = v
+	}
+
+	restCfg, err := k8s.RESTConfig(cfg)
+	if err != nil {
+		return nil, errors.Wrap(err, "rest config")
+	}
+	restCfg.Timeout = ac.apiTimelimit
+	dyn, err := dynamic.NewForConfig(restCfg)
+	if err != nil {
+		return nil, errors.Wrap(err, "dynamic client")
+	}
+	ac.dynamic = dyn
+
+	return ac, nil
+}
+
+func (ac *ArgoCD) ID() string {
+	return "argocd"
+}
+
+// Collect argo cd application sync and health status
+func (ac *ArgoCD) Collect(ctx context.Context, _ *tls.Config, ts *time.Time) {
+	ac.Lock()
+	if ac.running {
+		ac.log.Warn().Msg("already running")
+		ac.Unlock()
+		return
+	}
+	ac.running = true
+	ac.ts = ts
+	ac.Unlock()
+
+	defer func() {
+		if r := recover(); r != nil {
+			ac.log.Error().Interface("panic", r).Msg("recover")
+			ac.Lock()
+			ac.running = false
+			ac.Unlock()
+		}
+	}()
+
+	collectStart := time.Now()
+
+	ac.applicationMetrics(ctx)
+
+	ac.check.AddHistSample("collect_latency", cgm.Tags{
+		cgm.Tag{Category: "source", Value: release.NAME},
+		cgm.Tag{Category: "op", Value: "collect_argocd"},
+		cgm.Tag{Category: "units", Value: "milliseconds"},
+	}, float64(time.Since(collectStart).Milliseconds()))
+	ac.log.Debug().Str("duration", time.Since(collectStart).String()).Msg("argocd collect end")
+	ac.Lock()
+	ac.running = false
+	ac.Unlock()
+}
+
+// applicationMetrics emits the sync and health status of each application and the
+// number of applications out of sync or degraded
+func (ac *ArgoCD) applicationMetrics(ctx context.Context) {
+	apps, err := ac.dynamic.Resource(applicationsResource).Namespace(ac.config.ArgoCDNamespace).List(metav1.ListOptions{})
+	if err != nil {
+		ac.check.IncrementCounter("collect_api_errors", cgm.Tags{
+			cgm.Tag{Category: "source", Value: release.NAME},
+			cgm.Tag{Category: "request", Value: "application-list"},
+			cgm.Tag{Category: "target", Value: "api-server"},
+		})
+		ac.log.Error().Err(err).Msg("listing applications")
+		return
+	}
+
+	metrics := make(map[string]circonus.MetricSample)
+	outOfSync := uint64(0)
+	degraded := uint64(0)
+
+	for i := range apps.Items {
+		app := &apps.Items[i]
+		syncStatus, healthStatus := appStatus(app)
+		project, _, _ := unstructured.NestedString(app.Object, "spec", "project")
+		streamTags := []string{
+			"source:argocd",
+			"source_type:applications",
+			"namespace:" + app.GetNamespace(),
+			"application:" + app.GetName(),
+			"project:" + project,
+			"__rollup:false", // prevent high cardinality metrics from rolling up
+		}
+
+		synced := uint64(0)
+		switch syncStatus {
+		case "Synced":
+			synced = 1
+		case "OutOfSync":
+			outOfSync++
+		}
+		healthy := uint64(0)
+		switch healthStatus {
+		case "Healthy":
+			healthy = 1
+		case "Degraded":
+			degraded++
+		}
+
+		_ = ac.check.QueueMetricSample(metrics, "argocd_app_synced", circonus.MetricTypeUint64, append(streamTags, "sync_status:"+syncStatus), []string{}, synced, ac.ts)
+		_ = ac.check.QueueMetricSample(metrics, "argocd_app_healthy", circonus.MetricTypeUint64, append(streamTags, "health_status:"+healthStatus), []string{}, healthy, ac.ts)
+	}
+
+	streamTags := []string{
+		"source:argocd",
+		"source_type:applications",
+	}
+	_ = ac.check.QueueMetricSample(metrics, "argocd_apps", circonus.MetricTypeUint64, streamTags, []string{}, uint64(len(apps.Items)), ac.ts)
+	_ = ac.check.QueueMetricSample(metrics, "argocd_apps_out_of_sync", circonus.MetricTypeUint64, streamTags, []string{}, outOfSync, ac.ts)
+	_ = ac.check.QueueMetricSample(metrics, "argocd_apps_degraded", circonus.MetricTypeUint64, streamTags, []string{}, degraded, ac.ts)
+
+	if err := ac.check.SubmitQueue(ctx, metrics, ac.log.With().Str("type", "applications").Logger()); err != nil {
+		ac.log.Warn().Err(err).Msg("submitting metrics")
+	}
+}
+
+// appStatus returns the sync and health status of an application, Unknown if not reported yet
+func appStatus(app *unstructured.Unstructured) (string, string) {
+	syncStatus, found, err := unstructured.NestedString(app.Object, "status", "sync", "status")
+	if err != nil || !found || syncStatus == "" {
+		syncStatus = "Unknown"
+	}
+	healthStatus, found, err := unstructured.NestedString(app.Object, "status", "health", "status")
+	if err != nil || !found || healthStatus == "" {
+		healthStatus = "Unknown"
+	}
+	return syncStatus, healthStatus
+}
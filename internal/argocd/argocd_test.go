@@ -0,0 +1,48 @@
+// Copyright © 2019 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+package argocd
+
+import (
+	"testing"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func TestAppStatus(t *testing.T) {
+	tests := []struct {
+		name       string
+		status     map[string]interface{}
+		wantSync   string
+		wantHealth string
+	}{
+		{"no status", nil, "Unknown", "Unknown"},
+		{"synced healthy", map[string]interface{}{
+			"sync":   map[string]interface{}{"status": "Synced"},
+			"health": map[string]interface{}{"status": "Healthy"},
+		}, "Synced", "Healthy"},
+		{"out of sync degraded", map[string]interface{}{
+			"sync":   map[string]interface{}{"status": "OutOfSync"},
+			"health": map[string]interface{}{"status": "Degraded"},
+		}, "OutOfSync", "Degraded"},
+		{"health only", map[string]interface{}{
+			"health": map[string]interface{}{"status": "Progressing"},
+		}, "Unknown", "Progressing"},
+	}
+
+	for _, tst := range tests {
+		tst := tst
+		t.Run(tst.name, func(t *testing.T) {
+			app := &unstructured.Unstructured{Object: map[string]interface{}{}}
+			if tst.status != nil {
+				app.Object["status"] = tst.status
+			}
+			syncStatus, healthStatus := appStatus(app)
+			if syncStatus != tst.wantSync || healthStatus != tst.wantHealth {
+				t.Errorf("appStatus() = %s, %s, want %s, %s", syncStatus, healthStatus, tst.wantSync, tst.wantHealth)
+			}
+		})
+	}
+}
@@ -0,0 +1,268 @@
+// Copyright © 2019 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+// Package certmanager is the cert-manager metrics and certificate expiry collector
+package certmanager
+
+import (
+	"context"
+	"crypto/tls"
+	"regexp"
+	"sync"
+	"time"
+
+	cgm "github.com/circonus-labs/circonus-gometrics/v3"
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/circonus"
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/config"
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/config/defaults"
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/k8s"
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/release"
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/scrape"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/discovery"
+	"k8s.io/client-go/dynamic"
+)
+
+const certManagerGroup = "cert-manager.io"
+
+// only forward the cert-manager metric families (e.g. certificate expiration and ready
+// status, controller sync counts, acme client requests), not the go runtime/process metrics
+var familyFilter = regexp.MustCompile(`^certmanager_`)
+
+type CertManager struct {
+	config       *config.Cluster
+	check        *circonus.Check
+	log          zerolog.Logger
+	discovery    discovery.DiscoveryInterface
+	dynamic      dynamic.Interface
+	certificates *schema.GroupVersionResource
+	running      bool
+	apiTimelimit time.Duration
+	sync.Mutex
+	ts *time.Time
+}
+
+// NOTES:
+// cert-manager controller pods (labeled app.kubernetes.io/name=cert-manager) expose metrics on 9402,
+// they are fetched through the api-server pod proxy. Certificate resources (cert-manager.io, using
+// the preferred api version) are listed to emit the seconds until each certificate expires.
+
+func New(cfg *config.Cluster, parentLog zerolog.Logger, check *circonus.Check) (*CertManager, error) {
+	if cfg == nil {
+		return nil, errors.New("invalid cluster config (nil)")
+	}
+	if check == nil {
+		return nil, errors.New("invalid check (nil)")
+	}
+
+	cm := &CertManager{
+		config: cfg,
+		check:  check,
+		log:    parentLog.With().Str("collector", "cert-manager").Logger(),
+	}
+
+	if cfg.APITimelimit != "" {
+		v, err := time.ParseDuration(cfg.APITimelimit)
+		if err != nil {
+			cm.log.Error().Err(err).Msg("parsing api timelimit, using default")
+		} else {
+			cm.apiTimelimit = v
+		}
+	}
+
+	if cm.apiTimelimit == time.Duration(0) {
+		v, err := time.ParseDuration(defaults.K8SAPITimelimit)
+		if err != nil {
+			cm.log.Fatal().Err(err).Msg("parsing DEFAULT api timelimit")
+		}
+		cm.apiTimelimit = v
+	}
+
+	restCfg, err := k8s.RESTConfig(cfg)
+	if err != nil {
+		return nil, errors.Wrap(err, "rest config")
+	}
+	restCfg.Timeout = cm.apiTimelimit
+	dc, err := discovery.NewDiscoveryClientForConfig(restCfg)
+	if err != nil {
+		return nil, errors.Wrap(err, "discovery client")
+	}
+	cm.discovery = dc
+	dyn, err := dynamic.NewForConfig(restCfg)
+	if err != nil {
+		return nil, errors.Wrap(err, "dynamic client")
+	}
+	cm.dynamic = dyn
+
+	return cm, nil
+}
+
+func (cm *CertManager) ID() string {
+	return "cert-manager"
+}
+
+// Collect metrics from cert-manager pods and certificates
+func (cm *CertManager) Collect(ctx context.Context, tlsConfig *tls.Config, ts *time.Time) {
+	cm.Lock()
+	if cm.running {
+		cm.log.Warn().Msg("already running")
+		cm.Unlock()
+		return
+	}
+	cm.running = true
+	cm.ts = ts
+	cm.Unlock()
+
+	defer func() {
+		if r := recover(); r != nil {
+			cm.log.Error().Interface("panic", r).Msg("recover")
+			cm.Lock()
+			cm.running = false
+			cm.Unlock()
+		}
+	}()
+
+	collectStart := time.Now()
+
+	cm.podMetrics(ctx, tlsConfig)
+	cm.certificateMetrics(ctx)
+
+	cm.check.AddHistSample("collect_latency", cgm.Tags{
+		cgm.Tag{Category: "source", Value: release.NAME},
+		cgm.Tag{Category: "op", Value: "collect_cert-manager"},
+		cgm.Tag{Category: "units", Value: "milliseconds"},
+	}, float64(time.Since(collectStart).Milliseconds()))
+	cm.log.Debug().Str("duration", time.Since(collectStart).String()).Msg("cert-manager collect end")
+	cm.Lock()
+	cm.running = false
+	cm.Unlock()
+}
+
+// podMetrics collects metrics from each cert-manager pod via the api-server proxy
+func (cm *CertManager) podMetrics(ctx context.Context, tlsConfig *tls.Config) {
+	pods, err := scrape.Pods(cm.check, cm.log, cm.config, tlsConfig, cm.apiTimelimit, cm.config.CertManagerNamespace, cm.config.CertManagerSelector)
+	if err != nil {
+		cm.log.Error().Err(err).Msg("listing cert-manager pods")
+		return
+	}
+	if len(pods) == 0 {
+		cm.log.Warn().Str("selector", cm.config.CertManagerSelector).Msg("no cert-manager pods found")
+		return
+	}
+
+	targets := make([]scrape.Target, 0, len(pods))
+	for _, pod := range pods {
+		targets = append(targets, scrape.Target{
+			URL:          scrape.PodProxyURL(cm.config.URL, pod, cm.config.CertManagerPort, "/metrics"),
+			BearerToken:  cm.config.Token(),
+			Name:         "cert-manager",
+			Proxy:        "api-server",
+			FamilyFilter: familyFilter,
+			StreamTags: []string{
+				"source:cert-manager",
+				"source_type:metrics",
+				"pod:" + pod.Metadata.Name,
+				"node:" + pod.Spec.NodeName,
+				"__rollup:false", // prevent high cardinality metrics from rolling up
+			},
+		})
+	}
+
+	scrape.MetricsPool(ctx, cm.check, cm.log, tlsConfig, cm.apiTimelimit, targets, int(cm.config.NodePoolSize), cm.ts)
+}
+
+// certificateMetrics emits the seconds until expiry for each issued certificate
+func (cm *CertManager) certificateMetrics(ctx context.Context) {
+	gvr, err := cm.certificateResource()
+	if err != nil {
+		cm.log.Error().Err(err).Msg("certificate resource")
+		return
+	}
+
+	certs, err := cm.dynamic.Resource(*gvr).Namespace(metav1.NamespaceAll).List(metav1.ListOptions{})
+	if err != nil {
+		cm.check.IncrementCounter("collect_api_errors", cgm.Tags{
+			cgm.Tag{Category: "source", Value: release.NAME},
+			cgm.Tag{Category: "request", Value: "certificate-list"},
+			cgm.Tag{Category: "target", Value: "api-server"},
+		})
+		cm.log.Error().Err(err).Msg("listing certificates")
+		return
+	}
+
+	metrics := make(map[string]circonus.MetricSample)
+	now := time.Now()
+	for i := range certs.Items {
+		cert := &certs.Items[i]
+		secs, ok := expirySeconds(cert, now)
+		if !ok {
+			continue // not issued yet
+		}
+		issuer, _, _ := unstructured.NestedString(cert.Object, "spec", "issuerRef", "name")
+		streamTags := []string{
+			"source:cert-manager",
+			"source_type:certificates",
+			"namespace:" + cert.GetNamespace(),
+			"certificate:" + cert.GetName(),
+			"issuer:" + issuer,
+			"__rollup:false", // prevent high cardinality metrics from rolling up
+		}
+		_ = cm.check.QueueMetricSample(
+			metrics,
+			"certificate_expiry_seconds",
+			circonus.MetricTypeInt64,
+			streamTags, []string{},
+			secs,
+			cm.ts)
+	}
+
+	if len(metrics) == 0 {
+		return
+	}
+	if err := cm.check.SubmitQueue(ctx, metrics, cm.log.With().Str("type", "certificates").Logger()); err != nil {
+		cm.log.Warn().Err(err).Msg("submitting metrics")
+	}
+}
+
+// certificateResource returns the certificate resource using the preferred cert-manager.io api version
+func (cm *CertManager) certificateResource() (*schema.GroupVersionResource, error) {
+	cm.Lock()
+	defer cm.Unlock()
+	if cm.certificates != nil {
+		return cm.certificates, nil
+	}
+
+	groups, err := cm.discovery.ServerGroups()
+	if err != nil {
+		return nil, errors.Wrap(err, "api groups")
+	}
+	for _, g := range groups.Groups {
+		if g.Name != certManagerGroup {
+			continue
+		}
+		cm.certificates = &schema.GroupVersionResource{Group: certManagerGroup, Version: g.PreferredVersion.Version, Resource: "certificates"}
+		return cm.certificates, nil
+	}
+
+	return nil, errors.Errorf("api group %s not found", certManagerGroup)
+}
+
+// expirySeconds returns the seconds until the certificate expires (negative if expired), the
+// certificate status.notAfter is only set once a certificate has been issued
+func expirySeconds(cert *unstructured.Unstructured, now time.Time) (int64, bool) {
+	notAfter, found, err := unstructured.NestedString(cert.Object, "status", "notAfter")
+	if err != nil || !found || notAfter == "" {
+		return 0, false
+	}
+	t, err := time.Parse(time.RFC3339, notAfter)
+	if err != nil {
+		return 0, false
+	}
+	return int64(t.Sub(now).Seconds()), true
+}
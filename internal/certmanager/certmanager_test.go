@@ -0,0 +1,46 @@
+// Copyright © 2019 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+package certmanager
+
+import (
+	"testing"
+	"time"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func TestExpirySeconds(t *testing.T) {
+	now := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name   string
+		status map[string]interface{}
+		want   int64
+		ok     bool
+	}{
+		{"not issued", nil, 0, false},
+		{"invalid", map[string]interface{}{"notAfter": "foo"}, 0, false},
+		{"valid", map[string]interface{}{"notAfter": "2020-01-02T00:00:00Z"}, 86400, true},
+		{"expired", map[string]interface{}{"notAfter": "2019-12-31T23:00:00Z"}, -3600, true},
+	}
+
+	for _, tst := range tests {
+		tst := tst
+		t.Run(tst.name, func(t *testing.T) {
+			cert := &unstructured.Unstructured{Object: map[string]interface{}{}}
+			if tst.status != nil {
+				cert.Object["status"] = tst.status
+			}
+			got, ok := expirySeconds(cert, now)
+			if ok != tst.ok {
+				t.Fatalf("expirySeconds() ok = %t, want %t", ok, tst.ok)
+			}
+			if got != tst.want {
+				t.Errorf("expirySeconds() = %d, want %d", got, tst.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,197 @@
+// Copyright © 2019 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+package circonus
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/config"
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/release"
+	apiclient "github.com/circonus-labs/go-apiclient"
+	"github.com/pkg/errors"
+)
+
+// NOTES:
+// A curated set of rulesets can be created against the check's metrics. Rulesets are
+// matched to the check by name, missing rulesets are created and rulesets whose rules
+// (threshold, wait, severity), filter, or contact group differ from the configuration
+// are updated. The rulesets use metric patterns and tag filters, the metrics must be
+// allowed by the check bundle metric filters (e.g. node not ready and crashloop use
+// kube-state-metrics, which are not in the default filters).
+//
+//   node not ready    kube_node_status_condition{condition=Ready,status=true} < 1
+//   pod crashloop     kube_pod_container_status_waiting_reason{reason=CrashLoopBackOff} > 0
+//   pvc near full     used{resource=pvc,units=percent} > threshold
+//   apiserver errors  rate of apiserver_request_total{code=5xx} > threshold
+
+const (
+	ruleCriteriaMax = "max value"
+	ruleCriteriaMin = "min value"
+)
+
+type alertRule struct {
+	name     string
+	pattern  string // metric pattern (regex)
+	filter   string // tag filter
+	derive   string // blank, or counter (rate per second)
+	criteria string
+	value    string
+	wait     uint // minutes
+	severity uint
+}
+
+// alertRules returns the curated alert rules with the configured thresholds
+func alertRules(cfg config.Alerts) []alertRule {
+	return []alertRule{
+		{
+			name:     "node not ready",
+			pattern:  "^kube_node_status_condition$",
+			filter:   "and(condition:Ready,status:true)",
+			criteria: ruleCriteriaMin,
+			value:    "1",
+			wait:     cfg.NodeNotReadyWait,
+			severity: 1,
+		},
+		{
+			name:     "pod crashloop",
+			pattern:  "^kube_pod_container_status_waiting_reason$",
+			filter:   "and(reason:CrashLoopBackOff)",
+			criteria: ruleCriteriaMax,
+			value:    "0",
+			wait:     cfg.CrashLoopWait,
+			severity: 2,
+		},
+		{
+			name:     "pvc near full",
+			pattern:  "^used$",
+			filter:   "and(resource:pvc,units:percent)",
+			criteria: ruleCriteriaMax,
+			value:    strconv.FormatFloat(cfg.PVCUsedPercent, 'f', -1, 64),
+			wait:     5,
+			severity: 2,
+		},
+		{
+			name:     "apiserver error rate",
+			pattern:  "^apiserver_request_total$",
+			filter:   "and(code:5*)",
+			derive:   "counter",
+			criteria: ruleCriteriaMax,
+			value:    strconv.FormatFloat(cfg.APIServerErrorRate, 'f', -1, 64),
+			wait:     5,
+			severity: 2,
+		},
+	}
+}
+
+// ProvisionAlerts creates, or updates, the curated rulesets for the check
+func (c *Check) ProvisionAlerts() error {
+	return c.withAPITimeout("provision_alerts", c.provisionAlerts)
+}
+
+func (c *Check) provisionAlerts() error {
+	client := c.api()
+	if client == nil || c.checkCID == "" {
+		c.log.Warn().Msg("no check, alerts not created")
+		return nil
+	}
+
+	filter := apiclient.SearchFilterType{"f_check": []string{c.checkCID}}
+	existing, err := client.SearchRuleSets(nil, &filter)
+	if err != nil {
+		return errors.Wrap(err, "searching for check rulesets")
+	}
+	byName := make(map[string]*apiclient.RuleSet)
+	for i := range *existing {
+		rs := &(*existing)[i]
+		byName[rs.Name] = rs
+	}
+
+	for _, r := range alertRules(c.config.Alerts) {
+		want := c.alertRuleSet(r)
+		rs, found := byName[want.Name]
+		if !found {
+			created, err := client.CreateRuleSet(want)
+			if err != nil {
+				return errors.Wrapf(err, "creating ruleset (%s)", want.Name)
+			}
+			c.log.Info().Str("name", want.Name).Str("cid", created.CID).Msg("ruleset created")
+			continue
+		}
+		if !ruleSetChanged(rs, want) {
+			c.log.Debug().Str("name", want.Name).Str("cid", rs.CID).Msg("ruleset in sync")
+			continue
+		}
+		want.CID = rs.CID
+		if _, err := client.UpdateRuleSet(want); err != nil {
+			return errors.Wrapf(err, "updating ruleset (%s)", want.Name)
+		}
+		c.log.Info().Str("name", want.Name).Str("cid", rs.CID).Msg("ruleset updated")
+	}
+
+	return nil
+}
+
+// alertRuleSet returns the ruleset for an alert rule
+func (c *Check) alertRuleSet(r alertRule) *apiclient.RuleSet {
+	contactGroups := map[uint8][]string{1: {}, 2: {}, 3: {}, 4: {}, 5: {}}
+	if c.config.Alerts.ContactGroup != "" {
+		contactGroups[uint8(r.severity)] = []string{c.config.Alerts.ContactGroup}
+	}
+
+	notes := fmt.Sprintf("%s-%s", release.NAME, release.VERSION)
+	rs := &apiclient.RuleSet{
+		CheckCID:      c.checkCID,
+		ContactGroups: contactGroups,
+		Filter:        r.filter,
+		MetricPattern: prefixPattern(c.config.MetricPrefix, r.pattern),
+		MetricTags:    []string{},
+		MetricType:    "numeric",
+		Name:          fmt.Sprintf("%s /%s", r.name, release.NAME),
+		Notes:         &notes,
+		Rules: []apiclient.RuleSetRule{
+			{Criteria: r.criteria, Severity: r.severity, Value: r.value, Wait: r.wait},
+		},
+		Tags: []string{},
+	}
+	if r.derive != "" {
+		derive := r.derive
+		rs.Derive = &derive
+	}
+	return rs
+}
+
+// ruleSetChanged returns true if the rules, filter, or contact groups of the
+// existing ruleset do not match the wanted ruleset
+func ruleSetChanged(existing, want *apiclient.RuleSet) bool {
+	if existing.Filter != want.Filter || existing.MetricPattern != want.MetricPattern {
+		return true
+	}
+	if len(existing.Rules) != len(want.Rules) {
+		return true
+	}
+	for i, r := range want.Rules {
+		e := existing.Rules[i]
+		if e.Criteria != r.Criteria || e.Severity != r.Severity || e.Wait != r.Wait {
+			return true
+		}
+		if fmt.Sprint(e.Value) != fmt.Sprint(r.Value) { // api may return the value as a number
+			return true
+		}
+	}
+	for sev, groups := range want.ContactGroups {
+		eg := existing.ContactGroups[sev]
+		if len(eg) != len(groups) {
+			return true
+		}
+		for i := range groups {
+			if eg[i] != groups[i] {
+				return true
+			}
+		}
+	}
+	return false
+}
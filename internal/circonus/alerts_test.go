@@ -0,0 +1,54 @@
+// Copyright © 2019 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+package circonus
+
+import (
+	"testing"
+
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/config"
+)
+
+func TestRuleSetChanged(t *testing.T) {
+	c := &Check{
+		checkCID: "/check/1234",
+		config: &config.Circonus{Alerts: config.Alerts{
+			ContactGroup:   "/contact_group/1",
+			PVCUsedPercent: 90,
+		}},
+	}
+	var pvc alertRule
+	for _, r := range alertRules(c.config.Alerts) {
+		if r.name == "pvc near full" {
+			pvc = r
+		}
+	}
+	if pvc.value != "90" {
+		t.Fatalf("expected pvc threshold 90, got %s", pvc.value)
+	}
+
+	want := c.alertRuleSet(pvc)
+	if len(want.ContactGroups[2]) != 1 {
+		t.Fatalf("expected contact group for severity 2, got %v", want.ContactGroups)
+	}
+
+	same := c.alertRuleSet(pvc)
+	same.Rules[0].Value = 90.0 // api returns numbers
+	if ruleSetChanged(same, want) {
+		t.Fatal("expected no change")
+	}
+
+	threshold := c.alertRuleSet(pvc)
+	threshold.Rules[0].Value = "80"
+	if !ruleSetChanged(threshold, want) {
+		t.Fatal("expected threshold change")
+	}
+
+	noContact := c.alertRuleSet(pvc)
+	noContact.ContactGroups[2] = []string{}
+	if !ruleSetChanged(noContact, want) {
+		t.Fatal("expected contact group change")
+	}
+}
@@ -0,0 +1,208 @@
+// Copyright © 2019 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+package circonus
+
+import (
+	"fmt"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/config"
+	apiclient "github.com/circonus-labs/go-apiclient"
+	"github.com/pkg/errors"
+)
+
+// NOTES:
+// Rather than an explicit broker cid, the broker used when creating a check can be
+// selected with an ordered list of selectors (--check-broker-select), the first
+// selector matching at least one broker is used and the configured broker cid is
+// the fallback when no selector matches:
+//
+//   tag:<category:value>  brokers with the tag (e.g. tag:env:prod)
+//   region:<region>       brokers with a region:<region> tag, or region in their name
+//   latency               all brokers
+//
+// Only brokers with an active instance supporting httptrap are eligible. When more
+// than one broker matches, the broker with the lowest connect latency is used, so
+// one configuration template can be used for clusters in different regions.
+
+const (
+	brokerProbeTimeout = 2 * time.Second
+	defaultBrokerPort  = 43191
+)
+
+// brokerProbe returns the tcp connect latency of a broker address
+var brokerProbe = func(addr string) (time.Duration, error) {
+	start := time.Now()
+	conn, err := net.DialTimeout("tcp", addr, brokerProbeTimeout)
+	if err != nil {
+		return 0, err
+	}
+	conn.Close()
+	return time.Since(start), nil
+}
+
+// selectBroker returns the cid of the broker to use when creating a check
+func (c *Check) selectBroker(client *apiclient.API, cfg config.Check) (string, error) {
+	if cfg.BrokerSelect == "" {
+		return cfg.BrokerCID, nil
+	}
+
+	brokers, err := client.FetchBrokers()
+	if err != nil {
+		return "", errors.Wrap(err, "fetching brokers")
+	}
+
+	cid, selector, err := chooseBroker(cfg.BrokerSelect, *brokers)
+	if err != nil {
+		return "", err
+	}
+	if cid == "" {
+		c.log.Warn().Str("selectors", cfg.BrokerSelect).Str("broker_cid", cfg.BrokerCID).Msg("no brokers matched selectors, using broker cid")
+		return cfg.BrokerCID, nil
+	}
+
+	c.log.Info().Str("selector", selector).Str("broker_cid", cid).Msg("selected broker")
+	return cid, nil
+}
+
+// chooseBroker returns the cid of the broker matching the first selector (with the
+// lowest latency) and the selector, an empty cid if no brokers match
+func chooseBroker(selectors string, brokers []apiclient.Broker) (string, string, error) {
+	eligible := make([]apiclient.Broker, 0, len(brokers))
+	for _, b := range brokers {
+		if brokerAddr(b) != "" {
+			eligible = append(eligible, b)
+		}
+	}
+
+	for _, selector := range strings.Split(selectors, ",") {
+		selector = strings.TrimSpace(selector)
+		if selector == "" {
+			continue
+		}
+		match, err := brokerMatcher(selector)
+		if err != nil {
+			return "", "", err
+		}
+		var candidates []apiclient.Broker
+		for _, b := range eligible {
+			if match(b) {
+				candidates = append(candidates, b)
+			}
+		}
+		if cid := fastestBroker(candidates); cid != "" {
+			return cid, selector, nil
+		}
+	}
+
+	return "", "", nil
+}
+
+// brokerMatcher returns the broker match function for a selector
+func brokerMatcher(selector string) (func(apiclient.Broker) bool, error) {
+	parts := strings.SplitN(selector, ":", 2)
+	switch parts[0] {
+	case "latency":
+		return func(apiclient.Broker) bool { return true }, nil
+	case "tag":
+		if len(parts) != 2 || !strings.Contains(parts[1], ":") {
+			return nil, errors.Errorf("invalid broker selector (%s), tag:<category:value>", selector)
+		}
+		tag := strings.ToLower(parts[1])
+		return func(b apiclient.Broker) bool {
+			for _, t := range b.Tags {
+				if strings.ToLower(t) == tag {
+					return true
+				}
+			}
+			return false
+		}, nil
+	case "region":
+		if len(parts) != 2 || parts[1] == "" {
+			return nil, errors.Errorf("invalid broker selector (%s), region:<region>", selector)
+		}
+		region := strings.ToLower(parts[1])
+		return func(b apiclient.Broker) bool {
+			for _, t := range b.Tags {
+				if strings.ToLower(t) == "region:"+region {
+					return true
+				}
+			}
+			return strings.Contains(strings.ToLower(b.Name), region)
+		}, nil
+	default:
+		return nil, errors.Errorf("unknown broker selector (%s)", selector)
+	}
+}
+
+// fastestBroker returns the cid of the broker with the lowest connect latency, the
+// first broker if none could be probed
+func fastestBroker(brokers []apiclient.Broker) string {
+	if len(brokers) == 0 {
+		return ""
+	}
+	if len(brokers) == 1 {
+		return brokers[0].CID
+	}
+
+	cid := ""
+	var fastest time.Duration
+	for _, b := range brokers {
+		d, err := brokerProbe(brokerAddr(b))
+		if err != nil {
+			continue
+		}
+		if cid == "" || d < fastest {
+			cid = b.CID
+			fastest = d
+		}
+	}
+	if cid == "" {
+		return brokers[0].CID
+	}
+	return cid
+}
+
+// brokerAddr returns the address (host:port) of the broker's first active instance
+// supporting httptrap, empty if there is none
+func brokerAddr(b apiclient.Broker) string {
+	for _, d := range b.Details {
+		if d.Status != "active" {
+			continue
+		}
+		httptrap := false
+		for _, m := range d.Modules {
+			if m == "httptrap" {
+				httptrap = true
+				break
+			}
+		}
+		if !httptrap {
+			continue
+		}
+
+		host := ""
+		port := uint16(defaultBrokerPort)
+		switch {
+		case d.ExternalHost != nil && *d.ExternalHost != "":
+			host = *d.ExternalHost
+			if d.ExternalPort != 0 {
+				port = d.ExternalPort
+			}
+		case d.IP != nil && *d.IP != "":
+			host = *d.IP
+			if d.Port != nil && *d.Port != 0 {
+				port = *d.Port
+			}
+		default:
+			continue
+		}
+		return net.JoinHostPort(host, fmt.Sprintf("%d", port))
+	}
+	return ""
+}
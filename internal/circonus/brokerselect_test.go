@@ -0,0 +1,82 @@
+// Copyright © 2019 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+package circonus
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	apiclient "github.com/circonus-labs/go-apiclient"
+)
+
+func TestChooseBroker(t *testing.T) {
+	host := func(h string) *string { return &h }
+	broker := func(cid, name, addr, status string, tags ...string) apiclient.Broker {
+		return apiclient.Broker{
+			CID:  cid,
+			Name: name,
+			Tags: tags,
+			Details: []apiclient.BrokerDetail{
+				{Status: status, Modules: []string{"httptrap"}, ExternalHost: host(addr), ExternalPort: 43191},
+			},
+		}
+	}
+	brokers := []apiclient.Broker{
+		broker("/broker/1", "Ashburn", "b1", "active", "region:us-east", "env:prod"),
+		broker("/broker/2", "San Jose", "b2", "active", "region:us-west"),
+		broker("/broker/3", "Chicago", "b3", "active", "region:us-east"),
+		broker("/broker/4", "London", "b4", "inactive", "env:dev"),
+	}
+
+	latency := map[string]time.Duration{
+		"b1:43191": 30 * time.Millisecond,
+		"b2:43191": 10 * time.Millisecond,
+		"b3:43191": 20 * time.Millisecond,
+	}
+	origProbe := brokerProbe
+	brokerProbe = func(addr string) (time.Duration, error) {
+		if d, ok := latency[addr]; ok {
+			return d, nil
+		}
+		return 0, errors.New("unreachable")
+	}
+	defer func() { brokerProbe = origProbe }()
+
+	tests := []struct {
+		name      string
+		selectors string
+		want      string
+		wantErr   bool
+	}{
+		{"tag", "tag:env:prod", "/broker/1", false},
+		{"region lowest latency", "region:us-east", "/broker/3", false},
+		{"region by name", "region:san jose", "/broker/2", false},
+		{"latency", "latency", "/broker/2", false},
+		{"fallback order", "tag:env:stage, region:us-east", "/broker/3", false},
+		{"inactive not eligible", "tag:env:dev", "", false},
+		{"invalid tag", "tag:env", "", true},
+		{"unknown", "closest", "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, _, err := chooseBroker(tt.selectors, brokers)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("expected error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error (%s)", err)
+			}
+			if got != tt.want {
+				t.Fatalf("expected %q, got %q", tt.want, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,99 @@
+// Copyright © 2019 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+package circonus
+
+import (
+	"time"
+
+	cgm "github.com/circonus-labs/circonus-gometrics/v3"
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/release"
+	"github.com/pkg/errors"
+)
+
+// NOTES:
+// Timeouts and budgets keep a slow broker or api from pushing collection past the next
+// interval:
+//
+//   --api-timeout     each circonus api operation (finding or creating the check,
+//                     syncing metric filters, provisioning alerts and dashboards)
+//   --submit-timeout  each trap submission, including retries
+//   --submit-budget   all submissions of a collection interval
+//
+// With a submission budget, payloads queued during an interval must be submitted by the
+// interval start + budget. Payloads still queued (or waiting to be queued) at the deadline
+// are spooled (or written to the dead-letter dir, or dropped if neither is configured) and
+// counted in collect_submit_budget_exceeded. Submissions in progress at the deadline are
+// cancelled and spooled like any other failed submission.
+//
+// The apiclient does not support request contexts, an api operation which times out is
+// abandoned (it completes in the background) and counted in collect_api_timeouts.
+
+// StartSubmitBudget starts the submission budget of a collection interval (including
+// shards, destinations, and the agent metrics check)
+func (c *Check) StartSubmitBudget(start time.Time) {
+	for _, s := range c.shards {
+		s.check.StartSubmitBudget(start)
+	}
+	for _, d := range c.destinations {
+		d.check.StartSubmitBudget(start)
+	}
+	if c.selfCheck != nil {
+		c.selfCheck.StartSubmitBudget(start)
+	}
+	if c.submitBudget == 0 {
+		return
+	}
+	c.budgetmu.Lock()
+	c.budgetDeadline = start.Add(c.submitBudget)
+	c.budgetmu.Unlock()
+}
+
+// submitDeadline returns the deadline of the current submission budget, zero if
+// there is no budget
+func (c *Check) submitDeadline() time.Time {
+	c.budgetmu.Lock()
+	defer c.budgetmu.Unlock()
+	return c.budgetDeadline
+}
+
+// budgetExceeded spools the payload if the deadline of the interval it was queued in
+// has passed, returns true if the payload was spooled
+func (c *Check) budgetExceeded(ms MetricSet, now time.Time) bool {
+	if ms.Deadline.IsZero() || now.Before(ms.Deadline) {
+		return false
+	}
+	c.IncrementCounter("collect_submit_budget_exceeded", cgm.Tags{cgm.Tag{Category: "source", Value: release.NAME}})
+	ms.Logger.Warn().Str("deadline", ms.Deadline.String()).Msg("submission budget exceeded")
+	c.spoolPayload(ms.Metrics, ms.Logger)
+	return true
+}
+
+// withAPITimeout runs a circonus api operation, returning an error if it does not
+// complete within the api timeout
+func (c *Check) withAPITimeout(op string, fn func() error) error {
+	if c.apiTimeout == 0 {
+		return fn()
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		done <- fn()
+	}()
+
+	timer := time.NewTimer(c.apiTimeout)
+	defer timer.Stop()
+
+	select {
+	case err := <-done:
+		return err
+	case <-timer.C:
+		c.IncrementCounter("collect_api_timeouts", cgm.Tags{
+			cgm.Tag{Category: "op", Value: op},
+			cgm.Tag{Category: "source", Value: release.NAME},
+		})
+		return errors.Errorf("circonus api %s timed out after %s", op, c.apiTimeout)
+	}
+}
@@ -0,0 +1,156 @@
+// Copyright © 2019 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+package circonus
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	cgm "github.com/circonus-labs/circonus-gometrics/v3"
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/config"
+	"github.com/rs/zerolog"
+)
+
+func TestSubmitBudget(t *testing.T) {
+	c := &Check{metricQueue: make(chan MetricSet, 1)}
+
+	c.StartSubmitBudget(time.Now())
+	if !c.submitDeadline().IsZero() {
+		t.Fatal("expected no deadline without a budget")
+	}
+
+	start := time.Now()
+	c.submitBudget = 50 * time.Millisecond
+	c.StartSubmitBudget(start)
+	deadline := c.submitDeadline()
+	if !deadline.Equal(start.Add(c.submitBudget)) {
+		t.Fatalf("expected deadline %s, got %s", start.Add(c.submitBudget), deadline)
+	}
+
+	ms := MetricSet{Metrics: []byte(`{}`), Logger: zerolog.Nop()}
+	if c.budgetExceeded(ms, deadline.Add(time.Second)) {
+		t.Fatal("expected payload without a deadline to be submitted")
+	}
+	ms.Deadline = deadline
+	if c.budgetExceeded(ms, deadline.Add(-time.Millisecond)) {
+		t.Fatal("expected payload to be submitted before the deadline")
+	}
+	if !c.budgetExceeded(ms, deadline) {
+		t.Fatal("expected payload to be spooled at the deadline")
+	}
+
+	// queue full, the payload is spooled at the deadline rather than blocking
+	ctx := context.Background()
+	if err := c.AddMetricSet(ctx, []byte(`{"a":{"_value":1}}`), zerolog.Nop()); err != nil {
+		t.Fatalf("unexpected error (%s)", err)
+	}
+	done := make(chan error, 1)
+	go func() {
+		done <- c.AddMetricSet(ctx, []byte(`{"b":{"_value":2}}`), zerolog.Nop())
+	}()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("unexpected error (%s)", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected queueing to stop at the budget deadline")
+	}
+	if len(c.metricQueue) != 1 {
+		t.Fatalf("expected 1 queued payload, got %d", len(c.metricQueue))
+	}
+}
+
+func TestSubmitMetricSetReplay(t *testing.T) {
+	var submits int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&submits, 1)
+		_, _ = w.Write([]byte(`{"stats":1}`))
+	}))
+	defer ts.Close()
+
+	dir, err := ioutil.TempDir("", "replay")
+	if err != nil {
+		t.Fatalf("temp dir: %s", err)
+	}
+	defer os.RemoveAll(dir)
+	sp, err := newSpool(dir, 1024)
+	if err != nil {
+		t.Fatalf("unexpected error (%s)", err)
+	}
+	if _, err := sp.add([]byte(`{"a":{"_value":1}}`)); err != nil {
+		t.Fatalf("unexpected error (%s)", err)
+	}
+	rp, err := newRetryPolicy(0, "10ms", "10ms", false)
+	if err != nil {
+		t.Fatalf("unexpected error (%s)", err)
+	}
+	cfg := &cgm.Config{Interval: "0"}
+	cfg.CheckManager.Check.SubmissionURL = ts.URL
+	m, err := cgm.New(cfg)
+	if err != nil {
+		t.Fatalf("unexpected error (%s)", err)
+	}
+
+	c := &Check{
+		config:        &config.Circonus{},
+		submissionURL: ts.URL,
+		log:           zerolog.Nop(),
+		metrics:       m,
+		spool:         sp,
+		retry:         rp,
+	}
+
+	// the submission's context is released on return, the replay it starts continues
+	ms := MetricSet{Metrics: []byte(`{"b":{"_value":2}}`), Logger: zerolog.Nop(), Deadline: time.Now().Add(5 * time.Second)}
+	if err := c.submitMetricSet(context.Background(), ms); err != nil {
+		t.Fatalf("unexpected error (%s)", err)
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		files, err := sp.files()
+		if err != nil {
+			t.Fatalf("unexpected error (%s)", err)
+		}
+		if len(files) == 0 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("expected spooled payload to be replayed, %d submissions", atomic.LoadInt32(&submits))
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if n := atomic.LoadInt32(&submits); n != 2 {
+		t.Fatalf("expected 2 submissions, got %d", n)
+	}
+}
+
+func TestWithAPITimeout(t *testing.T) {
+	c := &Check{}
+	opErr := errors.New("api error")
+	if err := c.withAPITimeout("test", func() error { return opErr }); err != opErr {
+		t.Fatalf("expected %v, got %v", opErr, err)
+	}
+
+	c.apiTimeout = 10 * time.Millisecond
+	if err := c.withAPITimeout("test", func() error { return nil }); err != nil {
+		t.Fatalf("unexpected error (%s)", err)
+	}
+
+	release := make(chan struct{})
+	defer close(release)
+	if err := c.withAPITimeout("test", func() error { <-release; return nil }); err == nil {
+		t.Fatal("expected timeout error")
+	}
+}
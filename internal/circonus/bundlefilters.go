@@ -0,0 +1,121 @@
+// Copyright © 2019 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+package circonus
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	apiclient "github.com/circonus-labs/go-apiclient"
+	"github.com/pkg/errors"
+)
+
+// NOTES:
+// The deployment configuration is the "source of truth" for the check bundle metric
+// filters. The configured filters (circonus.check.metric_filters, the metric-filters.json
+// configmap file, or the defaults) are reconciled onto the check bundle when the check is
+// initialized. With a sync interval (--check-metric-filters-sync), the configured filters
+// are re-read and the check bundle is updated when they differ, e.g. the configmap was
+// edited or the filters were changed in the UI. The bundle is only updated when the
+// filters differ.
+
+// configuredMetricFilters returns the check bundle metric filters from the configuration
+func (c *Check) configuredMetricFilters() ([][]string, error) {
+	if c.config.Check.MetricFilters == "" {
+		return prefixMetricFilters(c.config.MetricPrefix, c.loadMetricFilters()), nil
+	}
+	var filters [][]string
+	if err := json.Unmarshal([]byte(c.config.Check.MetricFilters), &filters); err != nil {
+		return nil, errors.Wrap(err, "parsing check bundle metric filters")
+	}
+	return prefixMetricFilters(c.config.MetricPrefix, filters), nil
+}
+
+// reconcileMetricFilters updates the check bundle metric filters if they do not match
+// the configured filters, the (possibly updated) check bundle is returned
+func (c *Check) reconcileMetricFilters(client *apiclient.API, b *apiclient.CheckBundle) (*apiclient.CheckBundle, error) {
+	filters, err := c.configuredMetricFilters()
+	if err != nil {
+		return nil, err
+	}
+
+	if metricFiltersEqual(b.MetricFilters, filters) {
+		c.log.Debug().Str("bundle_cid", b.CID).Msg("check bundle metric filters in sync")
+		return b, nil
+	}
+
+	b.MetricFilters = filters
+	bundle, err := client.UpdateCheckBundle(b)
+	if err != nil {
+		return nil, errors.Wrap(err, "updating check bundle metric filters")
+	}
+	c.log.Info().Str("bundle_cid", bundle.CID).Int("filters", len(filters)).Msg("check bundle metric filters updated")
+
+	return bundle, nil
+}
+
+// SyncMetricFilters periodically reconciles the check bundle metric filters with the
+// configured filters, until the context is done
+func (c *Check) SyncMetricFilters(ctx context.Context) {
+	for _, s := range c.shards {
+		go s.check.SyncMetricFilters(ctx)
+	}
+	for _, d := range c.destinations {
+		d.startFilterSync(ctx)
+	}
+	if c.selfCheck != nil {
+		go c.selfCheck.SyncMetricFilters(ctx)
+	}
+
+	if c.api() == nil || c.checkBundleCID == "" || c.filterSync == 0 {
+		return
+	}
+
+	c.log.Info().Str("interval", c.filterSync.String()).Msg("check bundle metric filters sync")
+
+	ticker := time.NewTicker(c.filterSync)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			client := c.api() // recreated if the api key is rotated
+			cid := c.checkBundleCID
+			err := c.withAPITimeout("sync_metric_filters", func() error {
+				bundle, err := client.FetchCheckBundle(apiclient.CIDType(&cid))
+				if err != nil {
+					return errors.Wrap(err, "fetching check bundle")
+				}
+				_, err = c.reconcileMetricFilters(client, bundle)
+				return err
+			})
+			if err != nil {
+				c.log.Warn().Err(err).Str("bundle_cid", cid).Msg("syncing check bundle metric filters")
+			}
+		}
+	}
+}
+
+// metricFiltersEqual returns true if the filters are the same, filters are positional
+func metricFiltersEqual(a, b [][]string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if len(a[i]) != len(b[i]) {
+			return false
+		}
+		for j := range a[i] {
+			if a[i][j] != b[i][j] {
+				return false
+			}
+		}
+	}
+	return true
+}
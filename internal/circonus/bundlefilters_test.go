@@ -0,0 +1,53 @@
+// Copyright © 2019 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+package circonus
+
+import (
+	"testing"
+
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/config"
+)
+
+func TestMetricFiltersEqual(t *testing.T) {
+	base := [][]string{{"allow", "^collect_.*$", "agent"}, {"deny", "^.+$", "all other metrics"}}
+
+	tests := []struct {
+		name string
+		b    [][]string
+		want bool
+	}{
+		{"same", [][]string{{"allow", "^collect_.*$", "agent"}, {"deny", "^.+$", "all other metrics"}}, true},
+		{"reordered", [][]string{{"deny", "^.+$", "all other metrics"}, {"allow", "^collect_.*$", "agent"}}, false},
+		{"comment", [][]string{{"allow", "^collect_.*$", "agent stats"}, {"deny", "^.+$", "all other metrics"}}, false},
+		{"extra field", [][]string{{"allow", "^collect_.*$", "tags", "and(source:agent)", "agent"}, {"deny", "^.+$", "all other metrics"}}, false},
+		{"missing", [][]string{{"allow", "^collect_.*$", "agent"}}, false},
+		{"empty", nil, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := metricFiltersEqual(base, tt.b); got != tt.want {
+				t.Fatalf("expected %t, got %t", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestConfiguredMetricFilters(t *testing.T) {
+	c := &Check{config: &config.Circonus{Check: config.Check{MetricFilters: `[["deny","^$",""],["allow","^.+$",""]]`}}}
+	filters, err := c.configuredMetricFilters()
+	if err != nil {
+		t.Fatalf("unexpected error (%s)", err)
+	}
+	if !metricFiltersEqual(filters, [][]string{{"deny", "^$", ""}, {"allow", "^.+$", ""}}) {
+		t.Fatalf("unexpected filters %v", filters)
+	}
+
+	c.config.Check.MetricFilters = `["deny","^$",""]`
+	if _, err := c.configuredMetricFilters(); err == nil {
+		t.Fatal("expected error")
+	}
+}
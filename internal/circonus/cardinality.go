@@ -0,0 +1,134 @@
+// Copyright © 2019 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+package circonus
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	cgm "github.com/circonus-labs/circonus-gometrics/v3"
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/release"
+)
+
+// NOTES:
+// The cardinality guard caps the number of unique metric streams (tagged metric names)
+// queued per collection interval (--max-metric-streams). Collectors run concurrently,
+// so rather than dropping whichever streams arrive last, streams sent in the previous
+// interval are always admitted (their slots are reserved) and new streams are admitted
+// while there is room. The same streams are sent every interval, new streams are
+// dropped until existing ones go away. Dropped streams are reported with a counter
+// and a text metric listing the metric names with the most dropped streams.
+
+const maxDroppedNames = 10 // metric names listed in the dropped names text metric
+
+type cardinalityGuard struct {
+	sync.Mutex
+	max          int
+	previous     map[string]bool   // streams admitted in the previous interval
+	current      map[string]bool   // streams admitted in this interval
+	previousSeen int               // previous streams admitted in this interval
+	dropped      map[string]uint64 // dropped streams in this interval, by metric name
+	droppedTotal uint64
+}
+
+func newCardinalityGuard(max uint) *cardinalityGuard {
+	return &cardinalityGuard{
+		max:      int(max),
+		previous: make(map[string]bool),
+		current:  make(map[string]bool),
+		dropped:  make(map[string]uint64),
+	}
+}
+
+// admit returns true if the stream can be sent in this interval
+func (g *cardinalityGuard) admit(metricName, streamName string) bool {
+	g.Lock()
+	defer g.Unlock()
+
+	if g.current[streamName] {
+		return true
+	}
+	if g.previous[streamName] {
+		g.current[streamName] = true
+		g.previousSeen++
+		return true
+	}
+	if len(g.current)+(len(g.previous)-g.previousSeen) < g.max {
+		g.current[streamName] = true
+		return true
+	}
+	g.dropped[metricName]++
+	g.droppedTotal++
+	return false
+}
+
+// rotate starts a new interval, returning the streams admitted and dropped (by
+// metric name) in the interval which ended
+func (g *cardinalityGuard) rotate() (int, map[string]uint64) {
+	g.Lock()
+	defer g.Unlock()
+
+	streams := len(g.current)
+	dropped := g.dropped
+	g.previous = g.current
+	g.current = make(map[string]bool, len(g.previous))
+	g.previousSeen = 0
+	g.dropped = make(map[string]uint64)
+	return streams, dropped
+}
+
+// ReportCardinality emits the cardinality guard metrics for the collection interval
+// and starts a new interval, it is a no-op if the guard is not enabled
+func (c *Check) ReportCardinality() {
+	if c.cardinality == nil {
+		return
+	}
+
+	streams, dropped := c.cardinality.rotate()
+	c.cardinality.Lock()
+	droppedTotal := c.cardinality.droppedTotal
+	c.cardinality.Unlock()
+
+	tags := cgm.Tags{cgm.Tag{Category: "source", Value: release.NAME}}
+	c.AddGauge("collect_metric_streams", tags, uint64(streams))
+	c.SetCounter("collect_metric_streams_dropped", tags, droppedTotal)
+
+	if len(dropped) == 0 {
+		return
+	}
+
+	names := droppedNames(dropped)
+	c.AddText("collect_metric_streams_dropped_names", tags, names)
+	c.log.Warn().
+		Int("max_streams", c.cardinality.max).
+		Str("dropped", names).
+		Msg("max metric streams exceeded, dropped new streams")
+}
+
+// droppedNames returns the metric names with the most dropped streams (name=count),
+// sorted by count then name
+func droppedNames(dropped map[string]uint64) string {
+	names := make([]string, 0, len(dropped))
+	for name := range dropped {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool {
+		if dropped[names[i]] != dropped[names[j]] {
+			return dropped[names[i]] > dropped[names[j]]
+		}
+		return names[i] < names[j]
+	})
+	if len(names) > maxDroppedNames {
+		names = names[:maxDroppedNames]
+	}
+	list := make([]string, len(names))
+	for i, name := range names {
+		list[i] = fmt.Sprintf("%s=%d", name, dropped[name])
+	}
+	return strings.Join(list, ",")
+}
@@ -0,0 +1,52 @@
+// Copyright © 2019 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+package circonus
+
+import (
+	"testing"
+)
+
+func TestCardinalityGuard(t *testing.T) {
+	g := newCardinalityGuard(2)
+
+	for _, s := range []string{"a", "b", "c", "a"} {
+		g.admit("m", s)
+	}
+	streams, dropped := g.rotate()
+	if streams != 2 || dropped["m"] != 1 {
+		t.Fatalf("expected 2 streams and 1 dropped, got %d %v", streams, dropped)
+	}
+
+	// new stream arriving first does not displace previous streams
+	got := map[string]bool{}
+	for _, s := range []string{"c", "b", "a"} {
+		got[s] = g.admit("m", s)
+	}
+	if got["c"] || !got["a"] || !got["b"] {
+		t.Fatalf("expected a and b admitted, c dropped, got %v", got)
+	}
+	g.rotate()
+
+	// previous stream gone, its slot is available the next interval
+	if !g.admit("m", "b") || g.admit("m", "c") {
+		t.Fatal("expected b admitted, c dropped (a reserved)")
+	}
+	g.rotate()
+	if !g.admit("m", "c") {
+		t.Fatal("expected c admitted")
+	}
+	if g.droppedTotal != 3 {
+		t.Fatalf("expected 3 dropped in total, got %d", g.droppedTotal)
+	}
+}
+
+func TestDroppedNames(t *testing.T) {
+	dropped := map[string]uint64{"b": 5, "a": 5, "c": 10}
+	want := "c=10,a=5,b=5"
+	if got := droppedNames(dropped); got != want {
+		t.Fatalf("expected %s, got %s", want, got)
+	}
+}
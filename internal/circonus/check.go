@@ -15,11 +15,14 @@ import (
 	"fmt"
 	"io/ioutil"
 	stdlog "log"
+	"net"
 	"os"
 	"path"
 	"strings"
 	"sync"
+	"time"
 
+	"code.cloudfoundry.org/bytefmt"
 	cgm "github.com/circonus-labs/circonus-gometrics/v3"
 	"github.com/circonus-labs/circonus-kubernetes-agent/internal/config"
 	"github.com/circonus-labs/circonus-kubernetes-agent/internal/config/defaults"
@@ -43,8 +46,9 @@ type Stats struct {
 }
 
 type MetricSet struct {
-	Metrics []byte
-	Logger  zerolog.Logger
+	Metrics  []byte
+	Logger   zerolog.Logger
+	Deadline time.Time // submission budget deadline of the interval, zero if no budget
 }
 
 type Check struct {
@@ -52,6 +56,7 @@ type Check struct {
 	brokerTLSConfig *tls.Config
 	checkBundleCID  string
 	checkUUID       string
+	checkCID        string
 	submissionURL   string
 	log             zerolog.Logger
 	stats           Stats
@@ -59,6 +64,40 @@ type Check struct {
 	metrics         *cgm.CirconusMetrics
 	defaultTags     cgm.Tags
 	metricQueue     chan MetricSet
+	recorders       []Recorder
+	exporters       []Exporter
+	shards          []*shard
+	destinations    []*destination
+	selfCheck       *Check // agent metrics check, nil to send them with the cluster metrics
+	filters         []metricFilter
+	transforms      []tagTransform
+	namespaces      config.NamespaceFilter
+	pipelinemu      sync.RWMutex
+	spool           *spool
+	retry           retryPolicy
+	maxBodySize     uint64 // max submission size before metrics are split into multiple submissions, 0 no limit
+	dryrun          *dryRunOutput
+	statsd          net.Conn // forwarding to a statsd listener
+	apiClient       *apiclient.API
+	apiClientmu     sync.Mutex
+	filterSync      time.Duration // interval check bundle metric filters are reconciled, 0 only at startup
+	submitWorkers   int           // max in-flight submissions
+	submitTimeout   time.Duration // per-submission timeout, including retries, 0 no timeout
+	inFlight        int32         // submissions in progress (atomic)
+	submitBudget    time.Duration // time allowed for the submissions of an interval, 0 no budget
+	budgetDeadline  time.Time
+	budgetmu        sync.Mutex
+	apiTimeout      time.Duration // api operation timeout, 0 no timeout
+	cardinality     *cardinalityGuard
+	deadLetter      *deadLetter
+	textChanges     *textChanges // text metrics sent only on change, nil to always send
+	counterDeltas   *counterDeltas
+	compression     *compression // submission compression
+}
+
+// Recorder receives each numeric metric sample queued (e.g. for recording rules, slos)
+type Recorder interface {
+	Record(metricName string, streamTags []string, value interface{})
 }
 
 func NewCheck(parentLogger zerolog.Logger, cfg *config.Circonus) (*Check, error) {
@@ -66,9 +105,8 @@ func NewCheck(parentLogger zerolog.Logger, cfg *config.Circonus) (*Check, error)
 		return nil, errors.New("invalid circonus config (nil)")
 	}
 	c := &Check{
-		config:      cfg,
-		log:         parentLogger.With().Str("pkg", "circonus.check").Logger(),
-		metricQueue: make(chan MetricSet),
+		config: cfg,
+		log:    parentLogger.With().Str("pkg", "circonus.check").Logger(),
 	}
 
 	// output debug messages for hidden settings which are not DEFAULT
@@ -91,6 +129,102 @@ func NewCheck(parentLogger zerolog.Logger, cfg *config.Circonus) (*Check, error)
 		c.log.Info().Int("max_metric_bucket_size", cfg.MaxMetricBucketSize).Msg("max metric bucket size")
 	}
 
+	rp, err := newRetryPolicy(cfg.SubmitRetries, cfg.SubmitBackoffMin, cfg.SubmitBackoffMax, cfg.SubmitJitter)
+	if err != nil {
+		return nil, errors.Wrap(err, "submit retry policy")
+	}
+	c.retry = rp
+
+	c.submitWorkers = int(cfg.SubmitWorkers)
+	if c.submitWorkers == 0 || cfg.SerialSubmissions {
+		c.submitWorkers = 1
+	}
+	c.metricQueue = make(chan MetricSet, c.submitWorkers)
+	if cfg.SubmitTimeout != "" {
+		d, err := time.ParseDuration(cfg.SubmitTimeout)
+		if err != nil {
+			return nil, errors.Wrap(err, "parsing submit timeout")
+		}
+		c.submitTimeout = d
+	}
+
+	encoding := cfg.SubmitCompression
+	if !cfg.UseGZIP {
+		encoding = compressionNone
+	}
+	comp, err := newCompression(encoding, cfg.SubmitGzipLevel)
+	if err != nil {
+		return nil, errors.Wrap(err, "initializing submit compression")
+	}
+	c.compression = comp
+	if comp.encoding != compressionGzip || cfg.SubmitGzipLevel > 0 {
+		c.log.Info().Str("encoding", comp.encoding).Uint("gzip_level", cfg.SubmitGzipLevel).Msg("submit compression")
+	}
+
+	if cfg.SubmitBudget != "" {
+		d, err := time.ParseDuration(cfg.SubmitBudget)
+		if err != nil {
+			return nil, errors.Wrap(err, "parsing submit budget")
+		}
+		c.submitBudget = d
+	}
+
+	if cfg.API.Timeout != "" {
+		d, err := time.ParseDuration(cfg.API.Timeout)
+		if err != nil {
+			return nil, errors.Wrap(err, "parsing api timeout")
+		}
+		c.apiTimeout = d
+	}
+
+	if cfg.MaxMetricStreams > 0 {
+		c.cardinality = newCardinalityGuard(cfg.MaxMetricStreams)
+		c.log.Info().Uint("max_streams", cfg.MaxMetricStreams).Msg("metric cardinality guard")
+	}
+
+	if cfg.Check.FilterSync != "" {
+		d, err := time.ParseDuration(cfg.Check.FilterSync)
+		if err != nil {
+			return nil, errors.Wrap(err, "parsing check metric filters sync interval")
+		}
+		c.filterSync = d
+	}
+
+	if cfg.SubmitMaxBodySize != "" {
+		maxSize, err := bytefmt.ToBytes(cfg.SubmitMaxBodySize)
+		if err != nil {
+			return nil, errors.Wrap(err, "parsing submit max body size")
+		}
+		c.maxBodySize = maxSize
+	}
+
+	if cfg.TextOnChange {
+		var resend time.Duration
+		if cfg.TextResend != "" {
+			d, err := time.ParseDuration(cfg.TextResend)
+			if err != nil {
+				return nil, errors.Wrap(err, "parsing text metric resend interval")
+			}
+			resend = d
+		}
+		c.textChanges = newTextChanges(resend)
+		c.log.Info().Str("resend", resend.String()).Msg("text metrics sent on change")
+	}
+
+	if cfg.CounterDeltas {
+		c.counterDeltas = newCounterDeltas()
+		c.log.Info().Msg("prometheus counters submitted as deltas")
+	}
+
+	if cfg.DeadLetterDir != "" {
+		dl, err := newDeadLetter(cfg.DeadLetterDir, cfg.DeadLetterGzip)
+		if err != nil {
+			return nil, errors.Wrap(err, "initializing dead-letter dir")
+		}
+		c.deadLetter = dl
+		c.log.Info().Str("dir", cfg.DeadLetterDir).Bool("compress", cfg.DeadLetterGzip).Msg("dead-letter capture")
+	}
+
 	if cfg.DefaultStreamtags != "" {
 		ctags := cgm.Tags{}
 		tagList := strings.Split(cfg.DefaultStreamtags, ",")
@@ -103,18 +237,74 @@ func NewCheck(parentLogger zerolog.Logger, cfg *config.Circonus) (*Check, error)
 		c.defaultTags = ctags
 	}
 
+	if cfg.OTLP.Endpoint != "" {
+		e, err := newOTLPExporter(cfg.OTLP)
+		if err != nil {
+			return nil, errors.Wrap(err, "initializing otlp exporter")
+		}
+		c.AddExporter(e)
+		c.log.Info().Str("endpoint", cfg.OTLP.Endpoint).Msg("otlp export")
+	}
+
+	if cfg.RemoteWrite.URL != "" {
+		e, err := newRemoteWriteExporter(cfg.RemoteWrite, c.retry, c.log)
+		if err != nil {
+			return nil, errors.Wrap(err, "initializing remote write exporter")
+		}
+		c.AddExporter(e)
+		c.log.Info().Str("url", cfg.RemoteWrite.URL).Msg("remote write export")
+	}
+
 	if cfg.DryRun {
-		c.log.Info().Msg("dry run enabled, no check required")
+		c.log.Info().Str("output", cfg.DryRunOutput).Msg("dry run enabled, no check required")
+		c.dryrun = newDryRunOutput(cfg.DryRunOutput)
+		if err := c.initializeShards(parentLogger); err != nil {
+			return nil, err
+		}
 		return c, nil // not sending metrics to circonus
 	}
 
-	client, err := c.createAPIClient()
-	if err != nil {
-		return nil, errors.Wrap(err, "setting up circonus api client")
+	if cfg.ForwardStatsd != "" || cfg.ForwardURL != "" {
+		if len(cfg.Shards) > 0 {
+			return nil, errors.New("check shards are not supported when forwarding metrics")
+		}
+		if len(cfg.Destinations) > 0 {
+			return nil, errors.New("submission destinations are not supported when forwarding metrics")
+		}
+		if cfg.SelfCheck.Enable {
+			return nil, errors.New("agent metrics check is not supported when forwarding metrics")
+		}
+		if cfg.ForwardStatsd != "" && cfg.ForwardURL != "" {
+			return nil, errors.New("forward to a circonus-agent OR a statsd listener, not both")
+		}
 	}
 
-	if err := c.initializeCheckBundle(client); err != nil {
-		return nil, err
+	if cfg.ForwardStatsd != "" {
+		conn, err := net.Dial("udp", cfg.ForwardStatsd)
+		if err != nil {
+			return nil, errors.Wrap(err, "statsd forwarding address")
+		}
+		c.statsd = conn
+		c.log.Info().Str("addr", cfg.ForwardStatsd).Msg("forwarding metrics to statsd, no check required")
+		return c, nil
+	}
+
+	if cfg.ForwardURL != "" {
+		c.submissionURL = cfg.ForwardURL
+		c.log.Info().Str("url", cfg.ForwardURL).Msg("forwarding metrics to circonus-agent, no check required")
+	} else {
+		client, err := c.createAPIClient()
+		if err != nil {
+			return nil, errors.Wrap(err, "setting up circonus api client")
+		}
+
+		err = c.withAPITimeout("initialize_check", func() error {
+			return c.initializeCheckBundle(client)
+		})
+		if err != nil {
+			return nil, err
+		}
+		c.apiClient = client
 	}
 
 	{
@@ -132,6 +322,31 @@ func NewCheck(parentLogger zerolog.Logger, cfg *config.Circonus) (*Check, error)
 		c.metrics = m
 	}
 
+	if cfg.SpoolDir != "" {
+		maxSize, err := bytefmt.ToBytes(cfg.SpoolMaxSize)
+		if err != nil {
+			return nil, errors.Wrap(err, "parsing spool max size")
+		}
+		sp, err := newSpool(cfg.SpoolDir, maxSize)
+		if err != nil {
+			return nil, errors.Wrap(err, "initializing submission spool")
+		}
+		c.spool = sp
+		c.log.Info().Str("dir", cfg.SpoolDir).Str("max_size", cfg.SpoolMaxSize).Msg("submission spool")
+	}
+
+	if err := c.initializeDestinations(parentLogger); err != nil {
+		return nil, err
+	}
+
+	if err := c.initializeSelfCheck(parentLogger); err != nil {
+		return nil, err
+	}
+
+	if err := c.initializeShards(parentLogger); err != nil {
+		return nil, err
+	}
+
 	return c, nil
 }
 
@@ -140,15 +355,9 @@ func (c *Check) MaxMetricBucketSize() int {
 	return c.config.MaxMetricBucketSize
 }
 
-// ConcurrentSubmissions enable sending metrics to circonus concurrently
-// when disabled collection time is increased, when enabled may produce gaps
-func (c *Check) ConcurrentSubmissions() bool {
-	return c.config.ConcurrentSubmissions
-}
-
 // UseCompression indicates whether the data being sent should be compressed
 func (c *Check) UseCompression() bool {
-	return c.config.UseGZIP
+	return c.compression != nil && c.compression.contentEncoding() != ""
 }
 
 // DebugSubmissions will dump the submission request to stdout
@@ -185,6 +394,36 @@ func (c *Check) createAPIClient() (*apiclient.API, error) {
 	return client, nil
 }
 
+// api returns the circonus api client, nil if the check does not use the api
+func (c *Check) api() *apiclient.API {
+	c.apiClientmu.Lock()
+	defer c.apiClientmu.Unlock()
+	return c.apiClient
+}
+
+// SetAPIKey replaces the circonus api token (e.g. it was rotated), the api client is
+// recreated with the new token
+func (c *Check) SetAPIKey(key string) error {
+	c.apiClientmu.Lock()
+	defer c.apiClientmu.Unlock()
+
+	if key == "" {
+		return errors.New("invalid api key (empty)")
+	}
+	prev := c.config.API.Key
+	c.config.API.Key = key
+	if c.apiClient == nil {
+		return nil
+	}
+	client, err := c.createAPIClient()
+	if err != nil {
+		c.config.API.Key = prev
+		return errors.Wrap(err, "recreating api client")
+	}
+	c.apiClient = client
+	return nil
+}
+
 // initializeCheckBundle finds or creates a new check bundle
 func (c *Check) initializeCheckBundle(client *apiclient.API) error {
 	if client == nil {
@@ -202,6 +441,11 @@ func (c *Check) initializeCheckBundle(client *apiclient.API) error {
 			return errors.Errorf("invalid check bundle (%s), not active", bundle.CID)
 		}
 
+		bundle, err = c.reconcileMetricFilters(client, bundle)
+		if err != nil {
+			return err
+		}
+
 		return c.setSubmissionURL(client, bundle)
 	}
 
@@ -221,6 +465,9 @@ func (c *Check) setSubmissionURL(client *apiclient.API, bundle *apiclient.CheckB
 		return errors.Errorf("check bundle config does not have a submission_url (%#v)", bundle.Config)
 	}
 	c.checkBundleCID = bundle.CID
+	if len(bundle.Checks) > 0 {
+		c.checkCID = bundle.Checks[0]
+	}
 	if len(bundle.CheckUUIDs) == 1 {
 		c.checkUUID = bundle.CheckUUIDs[0]
 	} else {
@@ -231,6 +478,9 @@ func (c *Check) setSubmissionURL(client *apiclient.API, bundle *apiclient.CheckB
 	if err := c.initializeBroker(client, bundle); err != nil {
 		return errors.Wrap(err, "unable to initialize broker TLS configuration")
 	}
+	if err := c.configureBrokerClientCert(); err != nil {
+		return errors.Wrap(err, "unable to configure broker client certificate")
+	}
 	return nil
 }
 
@@ -283,28 +533,7 @@ func (c *Check) findOrCreateCheckBundle(client *apiclient.API, cfg *config.Circo
 		c.log.Warn().Str("alt_type", altCheckType).Str("bundle_cid", bundle.CID).Str("check_uuid", bundle.CheckUUIDs[0]).Msg("found alternate check type, using")
 	}
 
-	return c.updateMetricFilters(client, cfg, &bundle)
-}
-
-// updateMetricFilters forces check bundle metric filters to match what
-// is in deployment configuration which is "source of truth" for filters
-func (c *Check) updateMetricFilters(client *apiclient.API, cfg *config.Circonus, b *apiclient.CheckBundle) (*apiclient.CheckBundle, error) {
-	checkMetricFilters := c.loadMetricFilters()
-	if cfg.Check.MetricFilters != "" {
-		var filters [][]string
-		if e := json.Unmarshal([]byte(cfg.Check.MetricFilters), &filters); e != nil {
-			return nil, errors.Wrap(e, "parsing check bundle metric filters")
-		}
-		checkMetricFilters = filters
-	}
-
-	b.MetricFilters = checkMetricFilters
-	bundle, err := client.UpdateCheckBundle(b)
-	if err != nil {
-		return nil, errors.Wrap(err, "updating check bundle metric filters")
-	}
-
-	return bundle, nil
+	return c.reconcileMetricFilters(client, &bundle)
 }
 
 // createCheckBundle creates a new check bundle
@@ -317,17 +546,18 @@ func (c *Check) createCheckBundle(client *apiclient.API, cfg *config.Circonus) (
 
 	notes := fmt.Sprintf("%s-%s", release.NAME, release.VERSION)
 
-	checkMetricFilters := c.loadMetricFilters()
-	if cfg.Check.MetricFilters != "" {
-		var filters [][]string
-		if e := json.Unmarshal([]byte(cfg.Check.MetricFilters), &filters); e != nil {
-			return nil, errors.Wrap(e, "parsing check bundle metric filters")
-		}
-		checkMetricFilters = filters
+	checkMetricFilters, err := c.configuredMetricFilters()
+	if err != nil {
+		return nil, err
+	}
+
+	brokerCID, err := c.selectBroker(client, cfg.Check)
+	if err != nil {
+		return nil, err
 	}
 
 	checkConfig := &apiclient.CheckBundle{
-		Brokers: []string{cfg.Check.BrokerCID},
+		Brokers: []string{brokerCID},
 		Config: apiclient.CheckBundleConfig{
 			"asynch_metrics": "true",
 			"secret":         secret,
@@ -388,6 +618,65 @@ func (c *Check) loadMetricFilters() [][]string {
 		{"allow", "^collect_.*$", "agent collection stats"},
 		{"allow", "^events$", "events"},
 		{"allow", "^coredns_.*$", "kube-dns"},
+		{"allow", "^(kubedns|skydns|dnsmasq)_.*$", "kube-dns"},
+		{"allow", "^etcd_.*$", "etcd"},
+		{"allow", "^apiserver_.*$", "api-server"},
+		{"allow", "^scheduler_.*$", "kube-scheduler"},
+		{"allow", "^workqueue_.*$", "kube-controller-manager"},
+		{"allow", "^kubeproxy_.*$", "kube-proxy"},
+		{"allow", "^nodecache_.*$", "node-local-dns"},
+		{"allow", "^container_(cpu_cfs_.*|fs_(reads|writes).*|network_.*_(errors|packets_dropped)_total)$", "cadvisor"},
+		{"allow", "^kubelet_(pleg_relist_.*|runtime_operations_.*|pod_start_duration_seconds.*|pod_worker_.*)$", "kubelet operational"},
+		{"allow", "^.+$", "tags", "and(source:promscrape)", "annotated pods/services"},
+		{"allow", "^.+$", "tags", "and(source:monitors)", "service/pod monitors"},
+		{"allow", "^nginx_ingress_controller_.*$", "ingress-nginx"},
+		{"allow", "^certmanager_.*$", "cert-manager"},
+		{"allow", "^certificate_expiry_seconds$", "cert-manager certificates"},
+		{"allow", "^(istio|envoy_cluster_upstream)_.*$", "istio"},
+		{"allow", "^.+$", "tags", "and(source:linkerd)", "linkerd"},
+		{"allow", "^DCGM_FI_DEV_.*$", "gpu (dcgm-exporter)"},
+		{"allow", "^(used|capacity|free|inodes_used)$", "tags", "and(resource:pvc)", "persistent volume claims"},
+		{"allow", "^persistentvolume(claim)?_.*$", "tags", "and(source:storage)", "storage inventory"},
+		{"allow", "^hpa_.*$", "tags", "and(source:hpa)", "horizontal pod autoscalers"},
+		{"allow", "^(jobs?|cronjob)_.*$", "tags", "and(source:jobs)", "jobs and cron jobs"},
+		{"allow", "^deployment_.*$", "tags", "and(source:workloads)", "deployments"},
+		{"allow", "^statefulset_.*$", "tags", "and(source:workloads)", "statefulsets"},
+		{"allow", "^daemonset_.*$", "tags", "and(source:workloads)", "daemonsets"},
+		{"allow", "^service_(endpoints_.*|no_ready_endpoints)$", "tags", "and(source:endpoints)", "service endpoints"},
+		{"allow", "^.+$", "tags", "and(source:crstate)", "custom resources"},
+		{"allow", "^node_condition$", "node problem conditions"},
+		{"allow", "^container_(restarts|terminations|oom_kills)$", "tags", "and(source:restarts)", "container restarts"},
+		{"allow", "^pod_(pending|container_creating)_duration$", "pod phase durations"},
+		{"allow", "^image_pull(s|_failures|_duration)$", "tags", "and(source:image-pulls)", "image pulls"},
+		{"allow", "^cluster_autoscaler_.+$", "tags", "and(source:cluster-autoscaler)", "cluster-autoscaler"},
+		{"allow", "^karpenter_.+$", "tags", "and(source:karpenter)", "karpenter"},
+		{"allow", "^velero_.+$", "tags", "and(source:velero)", "velero"},
+		{"allow", "^argocd_.+$", "tags", "and(source:argocd)", "argo cd"},
+		{"allow", "^flux_.+$", "tags", "and(source:flux)", "flux"},
+		{"allow", "^.+$", "tags", "and(source:scrape-targets)", "scrape targets"},
+		{"allow", "^([rt]x|sockets)$", "tags", "and(resource:network,or(units:packets,units:drops,units:sockets),not(container_name:*))", "node network saturation"},
+		{"allow", "^deprecated_api(s|s_requested|_requests)$", "tags", "and(source:deprecated-apis)", "deprecated apis"},
+		{"allow", "^tls_secret(_expiry_days|s_invalid)$", "tags", "and(source:tls-secrets)", "tls secrets"},
+		{"allow", "^config_object(s|s_bytes|s_oversized|_oversized_bytes)$", "tags", "and(source:config-inventory)", "config inventory"},
+		{"allow", "^namespace_(pods|requests|limits|usage)$", "tags", "and(source:namespace-resources)", "namespace resources"},
+		{"allow", "^cri_container_.+$", "tags", "and(source:cri)", "container runtime"},
+		{"allow", "^pod_resources_(devices|cpus|memory)$", "tags", "and(source:pod-resources)", "pod resources"},
+		{"allow", "^cost_(node|cluster|unallocated|namespace|label)_hourly$", "tags", "and(source:cost)", "cost"},
+		{"allow", "^capacity_headroom(_percent)?$", "tags", "and(source:headroom)", "capacity headroom"},
+		{"allow", "^pdb_.+$", "tags", "and(source:pdb)", "pod disruption budgets"},
+		{"allow", "^(pending_pods(_unschedulable)?|scheduling_failure_events)$", "tags", "and(source:scheduling-failures)", "scheduling failures"},
+		{"allow", "^pod_evictions$", "tags", "and(source:evictions)", "pod evictions"},
+		{"allow", "^spot_(interruptions|node_drain_duration)$", "tags", "and(source:spot)", "spot interruptions"},
+		{"allow", "^control_plane_(check|healthy)$", "tags", "and(source:managed-control-plane)", "managed control-plane health"},
+		{"allow", "^eks_.+$", "tags", "and(source:managed-control-plane)", "eks control-plane (cloudwatch)"},
+		{"allow", "^(kubelet_version|container_runtime_version|kernel_version|os_image|node_versions)$", "node versions"},
+		{"allow", "^cluster_(version|version_skew|oldest_kubelet_version)$", "tags", "and(source:cluster-version)", "cluster version"},
+		{"allow", "^(orphaned_pods|lingering_pods|empty_replicasets)$", "tags", "and(source:orphans)", "cleanup candidates"},
+		{"allow", "^top_(pod|namespace)(_usage)?$", "tags", "and(source:top-n)", "top consumers"},
+		{"allow", "^container_(request|limit)_utilization$", "tags", "and(source:utilization)", "container utilization"},
+		{"allow", "^.+$", "tags", "and(source:recording-rules)", "recording rules"},
+		{"allow", "^slo_(burn_rate|error_ratio)$", "tags", "and(source:slo)", "slos"},
+		{"allow", "^etcd_objects(_total)?$", "tags", "and(source:etcd-objects)", "etcd objects"},
 		{"deny", "^.+$", "all other metrics}"},
 	}
 
@@ -0,0 +1,67 @@
+// Copyright © 2019 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+package circonus
+
+import (
+	"encoding/json"
+	"sort"
+
+	"github.com/pkg/errors"
+)
+
+// chunkMetrics encodes metrics into payloads of at most maxSize bytes, a single
+// metric larger than maxSize is sent in a payload of its own
+func chunkMetrics(metrics map[string]MetricSample, maxSize int) ([][]byte, error) {
+	names := make([]string, 0, len(metrics))
+	for name := range metrics {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	// an encoded map is "{\n" + entries joined with ",\n" + "\n}"
+	const overhead, separator = 4, 2
+
+	chunks := [][]byte{}
+	chunk := make(map[string]MetricSample)
+	size := overhead
+	flush := func() error {
+		if len(chunk) == 0 {
+			return nil
+		}
+		data, err := json.MarshalIndent(chunk, "", "  ")
+		if err != nil {
+			return errors.Wrap(err, "marshaling metrics")
+		}
+		chunks = append(chunks, data)
+		chunk = make(map[string]MetricSample)
+		size = overhead
+		return nil
+	}
+
+	for _, name := range names {
+		entry, err := json.MarshalIndent(map[string]MetricSample{name: metrics[name]}, "", "  ")
+		if err != nil {
+			return nil, errors.Wrapf(err, "marshaling metric %s", name)
+		}
+		entrySize := len(entry) - overhead
+		if len(chunk) > 0 {
+			entrySize += separator
+		}
+		if len(chunk) > 0 && size+entrySize > maxSize {
+			if err := flush(); err != nil {
+				return nil, err
+			}
+			entrySize -= separator
+		}
+		chunk[name] = metrics[name]
+		size += entrySize
+	}
+	if err := flush(); err != nil {
+		return nil, err
+	}
+
+	return chunks, nil
+}
@@ -0,0 +1,60 @@
+// Copyright © 2019 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+package circonus
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+)
+
+func TestChunkMetrics(t *testing.T) {
+	metrics := make(map[string]MetricSample)
+	for i := 0; i < 100; i++ {
+		metrics[fmt.Sprintf("metric_%03d", i)] = MetricSample{Value: i, Type: MetricTypeInt32, Timestamp: 1580000000000}
+	}
+
+	whole, err := json.MarshalIndent(metrics, "", "  ")
+	if err != nil {
+		t.Fatalf("unexpected error (%s)", err)
+	}
+
+	tests := []struct {
+		name       string
+		maxSize    int
+		wantChunks int
+	}{
+		{"no split", len(whole), 1},
+		{"split", len(whole) / 3, 4},
+		{"one per chunk", 1, 100},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			chunks, err := chunkMetrics(metrics, tt.maxSize)
+			if err != nil {
+				t.Fatalf("unexpected error (%s)", err)
+			}
+			if len(chunks) != tt.wantChunks {
+				t.Fatalf("expected %d chunks, got %d", tt.wantChunks, len(chunks))
+			}
+			seen := 0
+			for _, chunk := range chunks {
+				if len(chunk) > tt.maxSize && tt.maxSize > 1 {
+					t.Fatalf("chunk size %d exceeds max %d", len(chunk), tt.maxSize)
+				}
+				var m map[string]MetricSample
+				if err := json.Unmarshal(chunk, &m); err != nil {
+					t.Fatalf("unexpected error (%s)", err)
+				}
+				seen += len(m)
+			}
+			if seen != len(metrics) {
+				t.Fatalf("expected %d metrics, got %d", len(metrics), seen)
+			}
+		})
+	}
+}
@@ -0,0 +1,123 @@
+// Copyright © 2019 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+package circonus
+
+import (
+	"crypto/tls"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog"
+)
+
+// NOTES:
+// Enterprise brokers requiring mutual tls are sent a client certificate
+// (--check-broker-cert-file, --check-broker-key-file). The files are checked on each
+// tls handshake and the certificate is reloaded when either file changes, so rotated
+// certificates (e.g. a mounted secret updated by cert-manager) are used without a
+// restart. If a reload fails, the previous certificate continues to be used.
+
+// clientCertLoader provides the broker client certificate, reloading it when the
+// certificate or key file changes
+type clientCertLoader struct {
+	certFile string
+	keyFile  string
+	log      zerolog.Logger
+	sync.Mutex
+	cert    *tls.Certificate
+	certMod time.Time
+	keyMod  time.Time
+}
+
+// configureBrokerClientCert adds the client certificate, if configured, to the broker tls config
+func (c *Check) configureBrokerClientCert() error {
+	certFile, keyFile := c.config.Check.BrokerCert, c.config.Check.BrokerKey
+	if certFile == "" && keyFile == "" {
+		return nil
+	}
+	if certFile == "" || keyFile == "" {
+		return errors.New("broker client certificate requires both a cert file and a key file")
+	}
+
+	loader, err := newClientCertLoader(certFile, keyFile, c.log)
+	if err != nil {
+		return err
+	}
+
+	if c.brokerTLSConfig == nil {
+		c.brokerTLSConfig = &tls.Config{}
+	}
+	c.brokerTLSConfig.GetClientCertificate = loader.GetClientCertificate
+
+	c.log.Info().Str("cert_file", certFile).Str("key_file", keyFile).Msg("using broker client certificate")
+	return nil
+}
+
+// newClientCertLoader returns a loader with the certificate loaded
+func newClientCertLoader(certFile, keyFile string, logger zerolog.Logger) (*clientCertLoader, error) {
+	l := &clientCertLoader{
+		certFile: certFile,
+		keyFile:  keyFile,
+		log:      logger.With().Str("pkg", "broker-client-cert").Logger(),
+	}
+	certMod, keyMod, err := l.modTimes()
+	if err != nil {
+		return nil, err
+	}
+	if err := l.load(certMod, keyMod); err != nil {
+		return nil, err
+	}
+	return l, nil
+}
+
+// GetClientCertificate returns the client certificate, reloading it if the files have changed
+func (l *clientCertLoader) GetClientCertificate(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+	l.Lock()
+	defer l.Unlock()
+
+	certMod, keyMod, err := l.modTimes()
+	if err != nil {
+		l.log.Warn().Err(err).Msg("checking client certificate files, using current certificate")
+		return l.cert, nil
+	}
+	if certMod.Equal(l.certMod) && keyMod.Equal(l.keyMod) {
+		return l.cert, nil
+	}
+
+	if err := l.load(certMod, keyMod); err != nil {
+		l.log.Warn().Err(err).Msg("reloading client certificate, using current certificate")
+		return l.cert, nil
+	}
+	l.log.Info().Msg("client certificate reloaded")
+	return l.cert, nil
+}
+
+// load reads the certificate and key files
+func (l *clientCertLoader) load(certMod, keyMod time.Time) error {
+	cert, err := tls.LoadX509KeyPair(l.certFile, l.keyFile)
+	if err != nil {
+		return errors.Wrap(err, "loading broker client certificate")
+	}
+	l.cert = &cert
+	l.certMod = certMod
+	l.keyMod = keyMod
+	return nil
+}
+
+// modTimes returns the modification times of the certificate and key files
+func (l *clientCertLoader) modTimes() (time.Time, time.Time, error) {
+	ci, err := os.Stat(l.certFile)
+	if err != nil {
+		return time.Time{}, time.Time{}, errors.Wrap(err, "broker client cert file")
+	}
+	ki, err := os.Stat(l.keyFile)
+	if err != nil {
+		return time.Time{}, time.Time{}, errors.Wrap(err, "broker client key file")
+	}
+	return ci.ModTime(), ki.ModTime(), nil
+}
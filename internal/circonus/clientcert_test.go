@@ -0,0 +1,107 @@
+// Copyright © 2019 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+package circonus
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+func writeTestCert(t *testing.T, certFile, keyFile string, serial int64, mod time.Time) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("generating key (%s)", err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(serial),
+		Subject:      pkix.Name{CommonName: "agent"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("creating cert (%s)", err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatalf("marshaling key (%s)", err)
+	}
+	if err := ioutil.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600); err != nil {
+		t.Fatalf("writing cert (%s)", err)
+	}
+	if err := ioutil.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0600); err != nil {
+		t.Fatalf("writing key (%s)", err)
+	}
+	for _, f := range []string{certFile, keyFile} {
+		if err := os.Chtimes(f, mod, mod); err != nil {
+			t.Fatalf("setting mod time (%s)", err)
+		}
+	}
+}
+
+func TestClientCertLoader(t *testing.T) {
+	dir, err := ioutil.TempDir("", "clientcert")
+	if err != nil {
+		t.Fatalf("creating temp dir (%s)", err)
+	}
+	defer os.RemoveAll(dir)
+
+	certFile := filepath.Join(dir, "tls.crt")
+	keyFile := filepath.Join(dir, "tls.key")
+	now := time.Now()
+	writeTestCert(t, certFile, keyFile, 1, now.Add(-time.Minute))
+
+	l, err := newClientCertLoader(certFile, keyFile, zerolog.Nop())
+	if err != nil {
+		t.Fatalf("unexpected error (%s)", err)
+	}
+
+	serial := func() int64 {
+		cert, err := l.GetClientCertificate(nil)
+		if err != nil {
+			t.Fatalf("unexpected error (%s)", err)
+		}
+		x, err := x509.ParseCertificate(cert.Certificate[0])
+		if err != nil {
+			t.Fatalf("parsing cert (%s)", err)
+		}
+		return x.SerialNumber.Int64()
+	}
+
+	if s := serial(); s != 1 {
+		t.Fatalf("expected serial 1, got %d", s)
+	}
+
+	writeTestCert(t, certFile, keyFile, 2, now)
+	if s := serial(); s != 2 {
+		t.Fatalf("expected rotated serial 2, got %d", s)
+	}
+
+	// invalid rotation keeps the current certificate
+	if err := ioutil.WriteFile(keyFile, []byte("invalid"), 0600); err != nil {
+		t.Fatalf("writing key (%s)", err)
+	}
+	if err := os.Chtimes(keyFile, now.Add(time.Minute), now.Add(time.Minute)); err != nil {
+		t.Fatalf("setting mod time (%s)", err)
+	}
+	if s := serial(); s != 2 {
+		t.Fatalf("expected current serial 2, got %d", s)
+	}
+}
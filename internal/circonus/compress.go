@@ -0,0 +1,171 @@
+// Copyright © 2019 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+package circonus
+
+import (
+	"bytes"
+	"compress/gzip"
+	"net/http"
+	"regexp"
+	"sync"
+	"time"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/pkg/errors"
+)
+
+// NOTES:
+// Submissions larger than compressionThreshold are compressed with --submit-compression:
+//
+//   gzip  (default) at --submit-gzip-level (1 fastest - 9 smallest, 0 gzip's default)
+//   zstd  smaller payloads than gzip at a lower cpu cost, the broker must support it
+//   none  not compressed (same as --no-gzip)
+//
+// zstd is negotiated with the broker, if a zstd compressed submission is rejected as
+// unsupported (415, or 400 with a body saying the payload could not be decoded) the
+// submission is resent gzip compressed and the check uses gzip for a backoff (5m,
+// doubling on each consecutive rejection up to 1h) before trying zstd again, so a broker
+// upgraded to support zstd is picked up (logged and counted in
+// collect_submit_compression_fallback). Other 400s (e.g. invalid metrics) do not affect
+// the negotiation.
+
+const (
+	compressionGzip = "gzip"
+	compressionZstd = "zstd"
+	compressionNone = "none"
+
+	zstdRetryMin = 5 * time.Minute
+	zstdRetryMax = time.Hour
+)
+
+// zstdDecodeError matches broker 400 responses to a payload it could not decode
+var zstdDecodeError = regexp.MustCompile(`(?i)(decod|decompress|zstd|content.encoding)`)
+
+// compression is a check's submission compression
+type compression struct {
+	encoding    string
+	gzipLevel   int
+	zstd        *zstd.Encoder
+	zstdRetry   time.Time     // zstd rejected by the broker, use gzip until then
+	zstdBackoff time.Duration // backoff after the next rejection
+	sync.Mutex
+}
+
+// newCompression returns the submission compression for an encoding and gzip level
+func newCompression(encoding string, gzipLevel uint) (*compression, error) {
+	if gzipLevel > gzip.BestCompression {
+		return nil, errors.Errorf("invalid gzip level %d (1-9, 0 default)", gzipLevel)
+	}
+	comp := &compression{encoding: encoding, gzipLevel: gzip.DefaultCompression, zstdBackoff: zstdRetryMin}
+	if gzipLevel > 0 {
+		comp.gzipLevel = int(gzipLevel)
+	}
+
+	switch encoding {
+	case "", compressionGzip:
+		comp.encoding = compressionGzip
+	case compressionNone:
+	case compressionZstd:
+		enc, err := zstd.NewWriter(nil, zstd.WithEncoderLevel(zstd.SpeedDefault))
+		if err != nil {
+			return nil, errors.Wrap(err, "creating zstd encoder")
+		}
+		comp.zstd = enc
+	default:
+		return nil, errors.Errorf("invalid submit compression %q (gzip, zstd, none)", encoding)
+	}
+
+	return comp, nil
+}
+
+// contentEncoding returns the encoding payloads are compressed with, empty if they
+// are not compressed
+func (comp *compression) contentEncoding() string {
+	switch {
+	case comp.encoding == compressionNone:
+		return ""
+	case comp.encoding == compressionZstd && comp.zstdAllowed():
+		return compressionZstd
+	default:
+		return compressionGzip
+	}
+}
+
+// compress returns the compressed payload and the content encoding, the payload is
+// returned as is (with an empty encoding) if it is not compressed
+func (comp *compression) compress(data []byte) ([]byte, string, error) {
+	if len(data) <= compressionThreshold {
+		return data, "", nil
+	}
+
+	switch encoding := comp.contentEncoding(); encoding {
+	case compressionZstd:
+		return comp.zstd.EncodeAll(data, make([]byte, 0, len(data)/4)), encoding, nil
+	case compressionGzip:
+		var buf bytes.Buffer
+		zw, err := gzip.NewWriterLevel(&buf, comp.gzipLevel)
+		if err != nil {
+			return nil, "", errors.Wrap(err, "creating gzip writer")
+		}
+		if _, err := zw.Write(data); err != nil {
+			return nil, "", errors.Wrap(err, "compressing metrics")
+		}
+		if err := zw.Close(); err != nil {
+			return nil, "", errors.Wrap(err, "closing gzip writer")
+		}
+		return buf.Bytes(), encoding, nil
+	default:
+		return data, "", nil
+	}
+}
+
+// zstdAllowed returns false while backing off after the broker rejected zstd
+func (comp *compression) zstdAllowed() bool {
+	comp.Lock()
+	defer comp.Unlock()
+	return comp.zstdRetry.IsZero() || !time.Now().Before(comp.zstdRetry)
+}
+
+// rejected records the broker's response to a zstd compressed submission, returns true
+// if zstd was rejected as unsupported and the submission should be resent gzip compressed
+func (comp *compression) rejected(encoding string, statusCode int, body []byte) bool {
+	if encoding != compressionZstd {
+		return false
+	}
+
+	comp.Lock()
+	defer comp.Unlock()
+
+	switch {
+	case statusCode == http.StatusUnsupportedMediaType:
+	case statusCode == http.StatusBadRequest && zstdDecodeError.Match(body):
+	default:
+		if statusCode/100 == 2 { // negotiated
+			comp.zstdRetry = time.Time{}
+			comp.zstdBackoff = zstdRetryMin
+		}
+		return false
+	}
+
+	comp.zstdRetry = time.Now().Add(comp.zstdBackoff)
+	comp.zstdBackoff *= 2
+	if comp.zstdBackoff > zstdRetryMax {
+		comp.zstdBackoff = zstdRetryMax
+	}
+	return true
+}
+
+// traceExt returns the submit trace file extension for a content encoding
+func traceExt(encoding string) string {
+	switch encoding {
+	case compressionGzip:
+		return ".gz"
+	case compressionZstd:
+		return ".zst"
+	default:
+		return ""
+	}
+}
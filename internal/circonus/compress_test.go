@@ -0,0 +1,143 @@
+// Copyright © 2019 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+package circonus
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io/ioutil"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+func TestNewCompression(t *testing.T) {
+	tests := []struct {
+		name      string
+		encoding  string
+		gzipLevel uint
+		want      string
+		shouldErr bool
+	}{
+		{"default", "", 0, compressionGzip, false},
+		{"gzip level", "gzip", 9, compressionGzip, false},
+		{"zstd", "zstd", 0, compressionZstd, false},
+		{"none", "none", 0, "", false},
+		{"invalid encoding", "brotli", 0, "", true},
+		{"invalid gzip level", "gzip", 10, "", true},
+	}
+
+	for _, tt := range tests {
+		comp, err := newCompression(tt.encoding, tt.gzipLevel)
+		if tt.shouldErr {
+			if err == nil {
+				t.Fatalf("%s: expected error", tt.name)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("%s: unexpected error (%s)", tt.name, err)
+		}
+		if got := comp.contentEncoding(); got != tt.want {
+			t.Fatalf("%s: expected encoding %q, got %q", tt.name, tt.want, got)
+		}
+	}
+}
+
+func TestCompress(t *testing.T) {
+	data := bytes.Repeat([]byte(`{"metric":{"_type":"n","_value":1}},`), 100)
+
+	small := []byte(`{"metric":{"_type":"n","_value":1}}`)
+	gz, err := newCompression(compressionGzip, 1)
+	if err != nil {
+		t.Fatalf("unexpected error (%s)", err)
+	}
+	if payload, encoding, err := gz.compress(small); err != nil || encoding != "" || !bytes.Equal(payload, small) {
+		t.Fatalf("expected small payload as is, got %q %v", encoding, err)
+	}
+
+	payload, encoding, err := gz.compress(data)
+	if err != nil {
+		t.Fatalf("unexpected error (%s)", err)
+	}
+	if encoding != compressionGzip {
+		t.Fatalf("expected gzip, got %q", encoding)
+	}
+	zr, err := gzip.NewReader(bytes.NewReader(payload))
+	if err != nil {
+		t.Fatalf("unexpected error (%s)", err)
+	}
+	if got, err := ioutil.ReadAll(zr); err != nil || !bytes.Equal(got, data) {
+		t.Fatalf("gzip payload does not match (%v)", err)
+	}
+
+	zs, err := newCompression(compressionZstd, 0)
+	if err != nil {
+		t.Fatalf("unexpected error (%s)", err)
+	}
+	payload, encoding, err = zs.compress(data)
+	if err != nil {
+		t.Fatalf("unexpected error (%s)", err)
+	}
+	if encoding != compressionZstd {
+		t.Fatalf("expected zstd, got %q", encoding)
+	}
+	dec, err := zstd.NewReader(nil)
+	if err != nil {
+		t.Fatalf("unexpected error (%s)", err)
+	}
+	defer dec.Close()
+	if got, err := dec.DecodeAll(payload, nil); err != nil || !bytes.Equal(got, data) {
+		t.Fatalf("zstd payload does not match (%v)", err)
+	}
+
+	if zs.rejected(compressionZstd, http.StatusOK, nil) {
+		t.Fatal("expected accepted submission to not fall back")
+	}
+	if gz.rejected(compressionGzip, http.StatusBadRequest, []byte("unable to decode payload")) {
+		t.Fatal("expected gzip submission to not fall back")
+	}
+	if zs.rejected(compressionZstd, http.StatusBadRequest, []byte("invalid metric name")) {
+		t.Fatal("expected 400 not about the encoding to not fall back")
+	}
+	if _, encoding, _ := zs.compress(data); encoding != compressionZstd {
+		t.Fatalf("expected zstd after unrelated 400, got %q", encoding)
+	}
+	if !zs.rejected(compressionZstd, http.StatusUnsupportedMediaType, nil) {
+		t.Fatal("expected rejected zstd submission to fall back")
+	}
+	if _, encoding, _ := zs.compress(data); encoding != compressionGzip {
+		t.Fatalf("expected gzip after zstd rejected, got %q", encoding)
+	}
+	if zs.zstdBackoff != 2*zstdRetryMin {
+		t.Fatalf("expected backoff %s, got %s", 2*zstdRetryMin, zs.zstdBackoff)
+	}
+
+	zs.zstdRetry = time.Now().Add(-time.Second) // backoff elapsed
+	if _, encoding, _ := zs.compress(data); encoding != compressionZstd {
+		t.Fatalf("expected zstd retried after backoff, got %q", encoding)
+	}
+	if !zs.rejected(compressionZstd, http.StatusBadRequest, []byte("unable to decode payload")) {
+		t.Fatal("expected zstd decode error to fall back")
+	}
+	if zs.zstdBackoff != 4*zstdRetryMin {
+		t.Fatalf("expected backoff %s, got %s", 4*zstdRetryMin, zs.zstdBackoff)
+	}
+	zs.zstdRetry = time.Now().Add(-time.Second)
+	if zs.rejected(compressionZstd, http.StatusOK, nil) || zs.zstdBackoff != zstdRetryMin {
+		t.Fatalf("expected backoff reset after zstd accepted, got %s", zs.zstdBackoff)
+	}
+
+	none, err := newCompression(compressionNone, 0)
+	if err != nil {
+		t.Fatalf("unexpected error (%s)", err)
+	}
+	if payload, encoding, err := none.compress(data); err != nil || encoding != "" || !bytes.Equal(payload, data) {
+		t.Fatalf("expected payload as is, got %q %v", encoding, err)
+	}
+}
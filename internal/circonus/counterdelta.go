@@ -0,0 +1,102 @@
+// Copyright © 2019 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+package circonus
+
+import (
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// NOTES:
+// Prometheus counters (kube-state-metrics, kubelet, etc.) are cumulative. With
+// --counter-deltas the change since the previous collection is submitted instead of
+// the cumulative value, for views without a rate/counter derivative. Histogram and
+// summary _count and _sum series are cumulative as well and are handled the same way
+// (the buckets are sent as circonus cumulative histograms either way). The first sample
+// of a counter is not submitted (there is nothing to compare it to). A value lower
+// than the previous value is a counter reset (e.g. the process restarted) and the
+// value itself is the delta. Counters not seen for counterDeltaTTL are forgotten.
+// Deltas are applied as counter samples are queued, recorders (recording rules, slos)
+// receive the cumulative value.
+
+const counterDeltaTTL = time.Hour
+
+type counterDeltas struct {
+	sync.Mutex
+	last      map[string]counterSample
+	lastSweep time.Time
+}
+
+type counterSample struct {
+	value float64
+	seen  time.Time
+}
+
+func newCounterDeltas() *counterDeltas {
+	return &counterDeltas{
+		last:      make(map[string]counterSample),
+		lastSweep: time.Now(),
+	}
+}
+
+// delta returns the change in the counter since it was last seen, false if it is the
+// first sample of the counter
+func (d *counterDeltas) delta(key string, value float64, now time.Time) (float64, bool) {
+	d.Lock()
+	defer d.Unlock()
+
+	if now.Sub(d.lastSweep) >= counterDeltaTTL {
+		for k, s := range d.last {
+			if now.Sub(s.seen) >= counterDeltaTTL {
+				delete(d.last, k)
+			}
+		}
+		d.lastSweep = now
+	}
+
+	prev, found := d.last[key]
+	d.last[key] = counterSample{value: value, seen: now}
+	if !found {
+		return 0, false
+	}
+	if value < prev.value { // reset
+		return value, true
+	}
+	return value - prev.value, true
+}
+
+// counterDelta returns the value to queue for a cumulative counter sample, the change
+// since the previous collection with counter deltas enabled (false for the first sample
+// of a counter). The value is returned as is if counter deltas are not enabled.
+func (c *Check) counterDelta(metricName, metricType string, streamTags []string, value interface{}) (interface{}, bool) {
+	if c.counterDeltas == nil {
+		return value, true
+	}
+
+	var v float64
+	switch tv := value.(type) {
+	case float64:
+		v = tv
+	case uint64:
+		v = float64(tv)
+	default:
+		return value, true
+	}
+
+	tags := make([]string, len(streamTags))
+	copy(tags, streamTags)
+	sort.Strings(tags)
+	delta, ok := c.counterDeltas.delta(metricName+"|"+strings.Join(tags, ","), v, time.Now())
+	if !ok {
+		return nil, false
+	}
+	if metricType == MetricTypeUint64 {
+		return uint64(delta), true
+	}
+	return delta, true
+}
@@ -0,0 +1,91 @@
+// Copyright © 2019 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+package circonus
+
+import (
+	"testing"
+
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/config"
+)
+
+func TestCounterDelta(t *testing.T) {
+	c := &Check{counterDeltas: newCounterDeltas()}
+
+	tests := []struct {
+		name   string
+		tags   []string
+		value  float64
+		want   interface{}
+		wantOK bool
+	}{
+		{"first", []string{"a:1", "b:2"}, 10, nil, false},
+		{"delta", []string{"b:2", "a:1"}, 15, 5.0, true},
+		{"unchanged", []string{"a:1", "b:2"}, 15, 0.0, true},
+		{"reset", []string{"a:1", "b:2"}, 3, 3.0, true},
+		{"other stream", []string{"a:2"}, 7, nil, false},
+	}
+
+	for _, tt := range tests {
+		got, ok := c.counterDelta("requests_total", MetricTypeFloat64, tt.tags, tt.value)
+		if ok != tt.wantOK || got != tt.want {
+			t.Fatalf("%s: expected %v %v, got %v %v", tt.name, tt.want, tt.wantOK, got, ok)
+		}
+	}
+
+	if got, ok := c.counterDelta("requests_total", MetricTypeUint64, []string{"a:1", "b:2"}, uint64(8)); !ok || got != uint64(5) {
+		t.Fatalf("expected uint64 delta 5, got %v %v", got, ok)
+	}
+
+	disabled := &Check{}
+	if got, ok := disabled.counterDelta("requests_total", MetricTypeFloat64, nil, 42.0); !ok || got != 42.0 {
+		t.Fatalf("expected value as is when disabled, got %v %v", got, ok)
+	}
+}
+
+// recorder keeps the last value recorded for each metric
+type recorder struct {
+	values map[string]interface{}
+}
+
+func (r *recorder) Record(metricName string, streamTags []string, value interface{}) {
+	r.values[metricName] = value
+}
+
+func TestQueueCounterSample(t *testing.T) {
+	r := &recorder{values: make(map[string]interface{})}
+	c := &Check{
+		config:        &config.Circonus{},
+		counterDeltas: newCounterDeltas(),
+		recorders:     []Recorder{r},
+	}
+
+	tests := []struct {
+		name      string
+		value     float64
+		wantQueue interface{}
+	}{
+		{"first", 10, nil},
+		{"delta", 15, 5.0},
+		{"reset", 3, 3.0},
+	}
+
+	for _, tt := range tests {
+		metrics := make(map[string]MetricSample)
+		if err := c.QueueCounterSample(metrics, "requests_total", MetricTypeFloat64, []string{"code:200"}, nil, tt.value, nil); err != nil {
+			t.Fatalf("%s: unexpected error (%s)", tt.name, err)
+		}
+		var got interface{}
+		for _, ms := range metrics {
+			got = ms.Value
+		}
+		if got != tt.wantQueue {
+			t.Fatalf("%s: expected queued %v, got %v", tt.name, tt.wantQueue, got)
+		}
+		if r.values["requests_total"] != tt.value {
+			t.Fatalf("%s: expected recorded cumulative %v, got %v", tt.name, tt.value, r.values["requests_total"])
+		}
+	}
+}
@@ -0,0 +1,312 @@
+// Copyright © 2019 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+package circonus
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"text/template"
+
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/release"
+	apiclient "github.com/circonus-labs/go-apiclient"
+	"github.com/pkg/errors"
+)
+
+// NOTES:
+// A standard set of dashboards can be created, bound to the cluster's check. The
+// dashboards are rendered from the templates below (json, text/template) and are
+// only created if a dashboard with the same title does not already exist, so they
+// are created on the first run and dashboards edited in the UI are left alone.
+// Delete a dashboard to have it re-created from the template on the next start.
+//
+// Template data:
+//   .Cluster    cluster name
+//   .CheckUUID  the check's uuid
+//   metric      canonical name of a collector metric, default stream tags are added
+//               e.g. {{metric "pending_pods" "source:scheduling-failures" "source_type:scheduler"}}
+//   agentMetric canonical name of an agent metric, cluster and source tags are added
+//               e.g. {{agentMetric "collect_duration" "units:milliseconds"}}
+// Names are json escaped, use them inside quotes.
+
+type dashboardData struct {
+	Cluster   string
+	CheckUUID string
+}
+
+type dashboardTemplate struct {
+	name string
+	tmpl string
+}
+
+var dashboardTemplates = []dashboardTemplate{
+	{name: "cluster overview", tmpl: clusterOverviewDashboard},
+	{name: "node health", tmpl: nodeHealthDashboard},
+	{name: "workload health", tmpl: workloadHealthDashboard},
+}
+
+// ProvisionDashboards creates the standard dashboards for the cluster, dashboards
+// which already exist (by title) are not changed
+func (c *Check) ProvisionDashboards(cluster string) error {
+	return c.withAPITimeout("provision_dashboards", func() error {
+		return c.provisionDashboards(cluster)
+	})
+}
+
+func (c *Check) provisionDashboards(cluster string) error {
+	client := c.api()
+	if client == nil || c.checkUUID == "" {
+		c.log.Warn().Msg("no check, dashboards not created")
+		return nil
+	}
+
+	checkUUID := c.checkUUID
+	if i := strings.Index(checkUUID, ","); i > 0 {
+		checkUUID = checkUUID[:i] // multiple checks in bundle, use the first
+	}
+	data := dashboardData{Cluster: cluster, CheckUUID: checkUUID}
+
+	for _, dt := range dashboardTemplates {
+		title := fmt.Sprintf("%s %s /%s", cluster, dt.name, release.NAME)
+
+		filter := apiclient.SearchFilterType{"f_title": []string{title}}
+		found, err := client.SearchDashboards(nil, &filter)
+		if err != nil {
+			return errors.Wrapf(err, "searching for dashboard (%s)", title)
+		}
+		if len(*found) > 0 {
+			c.log.Debug().Str("title", title).Str("cid", (*found)[0].CID).Msg("dashboard exists")
+			continue
+		}
+
+		dash, err := c.renderDashboard(dt, data)
+		if err != nil {
+			return err
+		}
+		dash.Title = title
+
+		d, err := client.CreateDashboard(dash)
+		if err != nil {
+			return errors.Wrapf(err, "creating dashboard (%s)", title)
+		}
+		c.log.Info().Str("title", title).Str("cid", d.CID).Msg("dashboard created")
+	}
+
+	return nil
+}
+
+// renderDashboard renders a dashboard template
+func (c *Check) renderDashboard(dt dashboardTemplate, data dashboardData) (*apiclient.Dashboard, error) {
+	funcs := template.FuncMap{
+		"metric": func(name string, tags ...string) string {
+			return c.dashboardMetricName(name, tags)
+		},
+		"agentMetric": func(name string, tags ...string) string {
+			return c.dashboardMetricName(name, append([]string{"cluster:" + data.Cluster, "source:" + release.NAME}, tags...))
+		},
+	}
+
+	tmpl, err := template.New(dt.name).Funcs(funcs).Parse(dt.tmpl)
+	if err != nil {
+		return nil, errors.Wrapf(err, "parsing %s dashboard template", dt.name)
+	}
+
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return nil, errors.Wrapf(err, "rendering %s dashboard template", dt.name)
+	}
+
+	var dash apiclient.Dashboard
+	if err := json.Unmarshal(buf.Bytes(), &dash); err != nil {
+		return nil, errors.Wrapf(err, "parsing rendered %s dashboard", dt.name)
+	}
+
+	return &dash, nil
+}
+
+// dashboardMetricName returns the canonical (sorted, not base64 encoded) stream tagged
+// name of a metric, json escaped without quotes
+func (c *Check) dashboardMetricName(name string, tags []string) string {
+	streamTags := []string{}
+	for _, t := range c.transformTags(append(strings.Split(c.config.DefaultStreamtags, ","), tags...)) {
+		if t != "" {
+			streamTags = append(streamTags, t)
+		}
+	}
+	sort.Strings(streamTags)
+
+	metricName := c.config.MetricPrefix + name
+	if len(streamTags) > 0 {
+		metricName += "|ST[" + strings.Join(streamTags, ",") + "]"
+	}
+
+	data, _ := json.Marshal(metricName)
+	return string(data[1 : len(data)-1])
+}
+
+const clusterOverviewDashboard = `{
+  "grid_layout": {"height": 2, "width": 4},
+  "options": {"access_configs": [], "linkages": [], "scale_text": true, "text_size": 16},
+  "shared": false,
+  "widgets": [
+    {
+      "active": true, "height": 1, "width": 1, "name": "Gauge", "origin": "a0", "type": "gauge", "widget_id": "w1",
+      "settings": {
+        "check_uuid": "{{.CheckUUID}}",
+        "metric_name": "{{agentMetric "collect_duration" "units:milliseconds"}}",
+        "metric_display_name": "collection duration (ms)",
+        "title": "Collection duration", "type": "bar", "value_type": "gauge",
+        "thresholds": {"colors": [], "flip": false, "values": []}
+      }
+    },
+    {
+      "active": true, "height": 1, "width": 1, "name": "Gauge", "origin": "b0", "type": "gauge", "widget_id": "w2",
+      "settings": {
+        "check_uuid": "{{.CheckUUID}}",
+        "metric_name": "{{agentMetric "collect_metrics"}}",
+        "metric_display_name": "metrics",
+        "title": "Metrics collected", "type": "bar", "value_type": "gauge",
+        "thresholds": {"colors": [], "flip": false, "values": []}
+      }
+    },
+    {
+      "active": true, "height": 1, "width": 1, "name": "Gauge", "origin": "c0", "type": "gauge", "widget_id": "w3",
+      "settings": {
+        "check_uuid": "{{.CheckUUID}}",
+        "metric_name": "{{metric "pending_pods" "source:scheduling-failures" "source_type:scheduler"}}",
+        "metric_display_name": "pods",
+        "title": "Pending pods", "type": "bar", "value_type": "gauge",
+        "thresholds": {"colors": ["#008000", "#ffcc00", "#ee0000"], "flip": false, "values": ["1", "10"]}
+      }
+    },
+    {
+      "active": true, "height": 1, "width": 1, "name": "Gauge", "origin": "d0", "type": "gauge", "widget_id": "w4",
+      "settings": {
+        "check_uuid": "{{.CheckUUID}}",
+        "metric_name": "{{metric "cluster_version_skew" "source:cluster-version" "units:minor_versions"}}",
+        "metric_display_name": "minor versions",
+        "title": "Kubelet version skew", "type": "bar", "value_type": "gauge",
+        "range_low": 0, "range_high": 3,
+        "thresholds": {"colors": ["#008000", "#ffcc00", "#ee0000"], "flip": false, "values": ["1", "2"]}
+      }
+    },
+    {
+      "active": true, "height": 1, "width": 2, "name": "Text", "origin": "a1", "type": "text", "widget_id": "w5",
+      "settings": {
+        "title_format": "Cluster headroom (%)", "use_default": true, "value_type": "gauge",
+        "datapoints": [
+          {"_metric_type": "numeric", "label": "cpu", "metric": "{{.CheckUUID}}:{{metric "capacity_headroom_percent" "source:headroom" "source_type:capacity" "scope:cluster" "resource:cpu" "units:percent"}}"},
+          {"_metric_type": "numeric", "label": "memory", "metric": "{{.CheckUUID}}:{{metric "capacity_headroom_percent" "source:headroom" "source_type:capacity" "scope:cluster" "resource:memory" "units:percent"}}"},
+          {"_metric_type": "numeric", "label": "pods", "metric": "{{.CheckUUID}}:{{metric "capacity_headroom_percent" "source:headroom" "source_type:capacity" "scope:cluster" "resource:pods" "units:percent"}}"}
+        ]
+      }
+    },
+    {
+      "active": true, "height": 1, "width": 2, "name": "Text", "origin": "c1", "type": "text", "widget_id": "w6",
+      "settings": {
+        "title_format": "Agent", "use_default": true, "value_type": "gauge",
+        "datapoints": [
+          {"_metric_type": "numeric", "label": "sent (bytes)", "metric": "{{.CheckUUID}}:{{agentMetric "collect_sent" "units:bytes"}}"},
+          {"_metric_type": "numeric", "label": "max rss (bytes)", "metric": "{{.CheckUUID}}:{{agentMetric "collect_max_rss" "units:bytes"}}"},
+          {"_metric_type": "numeric", "label": "goroutines", "metric": "{{.CheckUUID}}:{{agentMetric "collect_ngr"}}"}
+        ]
+      }
+    }
+  ]
+}`
+
+const nodeHealthDashboard = `{
+  "grid_layout": {"height": 2, "width": 3},
+  "options": {"access_configs": [], "linkages": [], "scale_text": true, "text_size": 16},
+  "shared": false,
+  "widgets": [
+    {
+      "active": true, "height": 1, "width": 1, "name": "Gauge", "origin": "a0", "type": "gauge", "widget_id": "w1",
+      "settings": {
+        "check_uuid": "{{.CheckUUID}}",
+        "metric_name": "{{metric "capacity_headroom_percent" "source:headroom" "source_type:capacity" "scope:cluster" "resource:cpu" "units:percent"}}",
+        "metric_display_name": "cpu headroom (%)",
+        "title": "Allocatable cpu headroom", "type": "bar", "value_type": "gauge",
+        "range_low": 0, "range_high": 100,
+        "thresholds": {"colors": ["#ee0000", "#ffcc00", "#008000"], "flip": false, "values": ["10", "25"]}
+      }
+    },
+    {
+      "active": true, "height": 1, "width": 1, "name": "Gauge", "origin": "b0", "type": "gauge", "widget_id": "w2",
+      "settings": {
+        "check_uuid": "{{.CheckUUID}}",
+        "metric_name": "{{metric "capacity_headroom_percent" "source:headroom" "source_type:capacity" "scope:cluster" "resource:memory" "units:percent"}}",
+        "metric_display_name": "memory headroom (%)",
+        "title": "Allocatable memory headroom", "type": "bar", "value_type": "gauge",
+        "range_low": 0, "range_high": 100,
+        "thresholds": {"colors": ["#ee0000", "#ffcc00", "#008000"], "flip": false, "values": ["10", "25"]}
+      }
+    },
+    {
+      "active": true, "height": 1, "width": 1, "name": "Gauge", "origin": "c0", "type": "gauge", "widget_id": "w3",
+      "settings": {
+        "check_uuid": "{{.CheckUUID}}",
+        "metric_name": "{{metric "capacity_headroom_percent" "source:headroom" "source_type:capacity" "scope:cluster" "resource:pods" "units:percent"}}",
+        "metric_display_name": "pods headroom (%)",
+        "title": "Allocatable pods headroom", "type": "bar", "value_type": "gauge",
+        "range_low": 0, "range_high": 100,
+        "thresholds": {"colors": ["#ee0000", "#ffcc00", "#008000"], "flip": false, "values": ["10", "25"]}
+      }
+    },
+    {
+      "active": true, "height": 1, "width": 3, "name": "Text", "origin": "a1", "type": "text", "widget_id": "w4",
+      "settings": {
+        "title_format": "Cluster headroom", "use_default": true, "value_type": "gauge",
+        "datapoints": [
+          {"_metric_type": "numeric", "label": "cpu (cores)", "metric": "{{.CheckUUID}}:{{metric "capacity_headroom" "source:headroom" "source_type:capacity" "scope:cluster" "resource:cpu" "units:cores"}}"},
+          {"_metric_type": "numeric", "label": "memory (bytes)", "metric": "{{.CheckUUID}}:{{metric "capacity_headroom" "source:headroom" "source_type:capacity" "scope:cluster" "resource:memory" "units:bytes"}}"},
+          {"_metric_type": "numeric", "label": "pods", "metric": "{{.CheckUUID}}:{{metric "capacity_headroom" "source:headroom" "source_type:capacity" "scope:cluster" "resource:pods" "units:pods"}}"}
+        ]
+      }
+    }
+  ]
+}`
+
+const workloadHealthDashboard = `{
+  "grid_layout": {"height": 1, "width": 3},
+  "options": {"access_configs": [], "linkages": [], "scale_text": true, "text_size": 16},
+  "shared": false,
+  "widgets": [
+    {
+      "active": true, "height": 1, "width": 1, "name": "Gauge", "origin": "a0", "type": "gauge", "widget_id": "w1",
+      "settings": {
+        "check_uuid": "{{.CheckUUID}}",
+        "metric_name": "{{metric "pending_pods" "source:scheduling-failures" "source_type:scheduler"}}",
+        "metric_display_name": "pods",
+        "title": "Pending pods", "type": "bar", "value_type": "gauge",
+        "thresholds": {"colors": ["#008000", "#ffcc00", "#ee0000"], "flip": false, "values": ["1", "10"]}
+      }
+    },
+    {
+      "active": true, "height": 1, "width": 1, "name": "Gauge", "origin": "b0", "type": "gauge", "widget_id": "w2",
+      "settings": {
+        "check_uuid": "{{.CheckUUID}}",
+        "metric_name": "{{metric "pending_pods_unschedulable" "source:scheduling-failures" "source_type:scheduler"}}",
+        "metric_display_name": "pods",
+        "title": "Unschedulable pods", "type": "bar", "value_type": "gauge",
+        "thresholds": {"colors": ["#008000", "#ee0000"], "flip": false, "values": ["1"]}
+      }
+    },
+    {
+      "active": true, "height": 1, "width": 1, "name": "Gauge", "origin": "c0", "type": "gauge", "widget_id": "w3",
+      "settings": {
+        "check_uuid": "{{.CheckUUID}}",
+        "metric_name": "{{metric "capacity_headroom_percent" "source:headroom" "source_type:capacity" "scope:cluster" "resource:pods" "units:percent"}}",
+        "metric_display_name": "pod headroom (%)",
+        "title": "Pod capacity headroom", "type": "bar", "value_type": "gauge",
+        "range_low": 0, "range_high": 100,
+        "thresholds": {"colors": ["#ee0000", "#ffcc00", "#008000"], "flip": false, "values": ["10", "25"]}
+      }
+    }
+  ]
+}`
@@ -0,0 +1,77 @@
+// Copyright © 2019 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+package circonus
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/config"
+)
+
+func TestDashboardMetricName(t *testing.T) {
+	tests := []struct {
+		name       string
+		prefix     string
+		streamtags string
+		tags       []string
+		want       string
+	}{
+		{"no tags", "", "", nil, "foo"},
+		{"sorted", "", "", []string{"source:b", "resource:a"}, "foo|ST[resource:a,source:b]"},
+		{"default tags", "", "env:prod", []string{"source:b"}, "foo|ST[env:prod,source:b]"},
+		{"escaped", "", "", []string{`q:"x"`}, `foo|ST[q:\"x\"]`},
+		{"prefix", "k8s_", "", nil, "k8s_foo"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &Check{config: &config.Circonus{MetricPrefix: tt.prefix, DefaultStreamtags: tt.streamtags}}
+			if got := c.dashboardMetricName("foo", tt.tags); got != tt.want {
+				t.Fatalf("expected %s, got %s", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestRenderDashboards(t *testing.T) {
+	c := &Check{config: &config.Circonus{DefaultStreamtags: "env:prod"}}
+	data := dashboardData{Cluster: "test", CheckUUID: "01234567-89ab-cdef-0123-456789abcdef"}
+
+	for _, dt := range dashboardTemplates {
+		t.Run(dt.name, func(t *testing.T) {
+			dash, err := c.renderDashboard(dt, data)
+			if err != nil {
+				t.Fatalf("unexpected error (%s)", err)
+			}
+			if len(dash.Widgets) == 0 {
+				t.Fatal("expected widgets")
+			}
+			ids := make(map[string]bool)
+			for _, w := range dash.Widgets {
+				if ids[w.WidgetID] {
+					t.Fatalf("duplicate widget id %s", w.WidgetID)
+				}
+				ids[w.WidgetID] = true
+				switch w.Type {
+				case "gauge":
+					if w.Settings.CheckUUID != data.CheckUUID {
+						t.Fatalf("%s: expected check uuid %s, got %s", w.Settings.Title, data.CheckUUID, w.Settings.CheckUUID)
+					}
+					if !strings.Contains(w.Settings.MetricName, "env:prod") {
+						t.Fatalf("%s: expected default stream tags (%s)", w.Settings.Title, w.Settings.MetricName)
+					}
+				case "text":
+					for _, dp := range w.Settings.Datapoints {
+						if !strings.HasPrefix(dp.Metric, data.CheckUUID+":") {
+							t.Fatalf("%s: expected check uuid prefix (%s)", dp.Label, dp.Metric)
+						}
+					}
+				}
+			}
+		})
+	}
+}
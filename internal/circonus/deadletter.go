@@ -0,0 +1,211 @@
+// Copyright © 2019 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+package circonus
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	cgm "github.com/circonus-labs/circonus-gometrics/v3"
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/release"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog"
+)
+
+// NOTES:
+// When a submission fails after exhausting its retries and the spool is not enabled (or
+// the payload is discarded from the spool), or the broker rejected it (4xx, which would
+// not succeed when replayed automatically), the payload is written to the dead-letter directory (--dead-letter-dir), one file
+// per submission, optionally gzip compressed (--dead-letter-compress). Nothing is
+// replayed automatically, the payloads are replayed manually once the problem is
+// fixed with the replay subcommand, e.g.
+//
+//   circonus-kubernetes-agent replay                  (all payloads in the dead-letter dir)
+//   circonus-kubernetes-agent replay <file|dir> ...
+//   circonus-kubernetes-agent replay --shard <name>   (payloads of a check shard)
+//
+// Payloads of check shards are written to shard_<name> subdirectories. Replayed
+// payloads are removed unless --keep is used. The directory is not size limited.
+
+const deadLetterGzipExt = ".gz"
+
+type deadLetter struct {
+	dir      string
+	compress bool
+}
+
+// newDeadLetter returns a dead-letter writer using dir, the directory is created if needed
+func newDeadLetter(dir string, compress bool) (*deadLetter, error) {
+	if dir == "" {
+		return nil, errors.New("invalid dead-letter dir (empty)")
+	}
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, errors.Wrap(err, "creating dead-letter dir")
+	}
+	return &deadLetter{dir: dir, compress: compress}, nil
+}
+
+// add writes a payload to the dead-letter directory
+func (d *deadLetter) add(data []byte) (string, error) {
+	name := fmt.Sprintf("%020d%s", time.Now().UnixNano(), spoolExt)
+	if d.compress {
+		var buf bytes.Buffer
+		zw := gzip.NewWriter(&buf)
+		if _, err := zw.Write(data); err != nil {
+			return "", errors.Wrap(err, "compressing dead-letter payload")
+		}
+		if err := zw.Close(); err != nil {
+			return "", errors.Wrap(err, "compressing dead-letter payload")
+		}
+		data = buf.Bytes()
+		name += deadLetterGzipExt
+	}
+
+	tmp := filepath.Join(d.dir, "."+name)
+	if err := ioutil.WriteFile(tmp, data, 0600); err != nil {
+		return "", errors.Wrap(err, "writing dead-letter file")
+	}
+	fn := filepath.Join(d.dir, name)
+	if err := os.Rename(tmp, fn); err != nil {
+		return "", errors.Wrap(err, "renaming dead-letter file")
+	}
+	return fn, nil
+}
+
+// deadLetterPayload writes a failed submission to the dead-letter directory, if enabled
+func (c *Check) deadLetterPayload(data []byte, resultLogger zerolog.Logger) {
+	if c.deadLetter == nil {
+		return
+	}
+	fn, err := c.deadLetter.add(data)
+	if err != nil {
+		resultLogger.Error().Err(err).Msg("writing dead-letter payload")
+		return
+	}
+	c.IncrementCounter("collect_submit_dead_letters", cgm.Tags{cgm.Tag{Category: "source", Value: release.NAME}})
+	resultLogger.Warn().Str("file", fn).Msg("submission failed, metrics written to dead-letter dir")
+}
+
+// ReplayDeadLetters submits dead-letter payloads (files, or directories of payloads),
+// oldest first, to the check or the named shard's check, stopping at the first failure.
+// With no paths the check's dead-letter directory is used. Replayed payloads are
+// removed unless keep is set. Returns the number of payloads replayed.
+func (c *Check) ReplayDeadLetters(ctx context.Context, shardName string, paths []string, keep bool) (int, error) {
+	check := c
+	if shardName != "" {
+		check = nil
+		for _, s := range c.shards {
+			if s.name == shardName {
+				check = s.check
+				break
+			}
+		}
+		if check == nil {
+			return 0, errors.Errorf("unknown shard (%s)", shardName)
+		}
+	}
+
+	if len(paths) == 0 {
+		if check.deadLetter == nil {
+			return 0, errors.New("no dead-letter dir configured and no files specified")
+		}
+		paths = []string{check.deadLetter.dir}
+	}
+
+	files, err := deadLetterFiles(paths)
+	if err != nil {
+		return 0, err
+	}
+
+	replayed := 0
+	for _, fn := range files {
+		if done(ctx) {
+			return replayed, ctx.Err()
+		}
+		data, err := readDeadLetter(fn)
+		if err != nil {
+			return replayed, err
+		}
+		if err := check.submit(ctx, bytes.NewReader(data), check.log.With().Str("dead_letter_file", fn).Logger(), false); err != nil {
+			return replayed, errors.Wrapf(err, "replaying %s", fn)
+		}
+		replayed++
+		if keep {
+			continue
+		}
+		if err := os.Remove(fn); err != nil && !os.IsNotExist(err) {
+			return replayed, errors.Wrap(err, "removing replayed payload")
+		}
+	}
+
+	return replayed, nil
+}
+
+// deadLetterFiles returns the payload files, directories are expanded (not recursively),
+// sorted oldest first by name
+func deadLetterFiles(paths []string) ([]string, error) {
+	var files []string
+	for _, p := range paths {
+		fi, err := os.Stat(p)
+		if err != nil {
+			return nil, errors.Wrap(err, "dead-letter path")
+		}
+		if !fi.IsDir() {
+			files = append(files, p)
+			continue
+		}
+		entries, err := ioutil.ReadDir(p)
+		if err != nil {
+			return nil, errors.Wrap(err, "reading dead-letter dir")
+		}
+		for _, e := range entries {
+			name := e.Name()
+			if e.IsDir() || strings.HasPrefix(name, ".") {
+				continue
+			}
+			if !strings.HasSuffix(name, spoolExt) && !strings.HasSuffix(name, spoolExt+deadLetterGzipExt) {
+				continue
+			}
+			files = append(files, filepath.Join(p, name))
+		}
+	}
+	sort.SliceStable(files, func(i, j int) bool { return filepath.Base(files[i]) < filepath.Base(files[j]) })
+	return files, nil
+}
+
+// readDeadLetter returns the payload of a dead-letter file, decompressing it if needed
+func readDeadLetter(fn string) ([]byte, error) {
+	fh, err := os.Open(fn)
+	if err != nil {
+		return nil, errors.Wrap(err, "opening dead-letter file")
+	}
+	defer fh.Close()
+
+	var r io.Reader = fh
+	if strings.HasSuffix(fn, deadLetterGzipExt) {
+		zr, err := gzip.NewReader(fh)
+		if err != nil {
+			return nil, errors.Wrapf(err, "decompressing %s", fn)
+		}
+		defer zr.Close()
+		r = zr
+	}
+
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, errors.Wrapf(err, "reading %s", fn)
+	}
+	return data, nil
+}
@@ -0,0 +1,64 @@
+// Copyright © 2019 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+package circonus
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestDeadLetter(t *testing.T) {
+	dir, err := ioutil.TempDir("", "deadletter")
+	if err != nil {
+		t.Fatalf("temp dir: %s", err)
+	}
+	defer os.RemoveAll(dir)
+
+	payloads := []string{`{"a":{"_value":1}}`, `{"b":{"_value":2}}`}
+	var written []string
+	for i, p := range payloads {
+		d, err := newDeadLetter(dir, i == 1)
+		if err != nil {
+			t.Fatalf("unexpected error (%s)", err)
+		}
+		fn, err := d.add([]byte(p))
+		if err != nil {
+			t.Fatalf("unexpected error (%s)", err)
+		}
+		written = append(written, fn)
+	}
+	if !strings.HasSuffix(written[1], spoolExt+deadLetterGzipExt) {
+		t.Fatalf("expected compressed payload, got %s", written[1])
+	}
+
+	// ignored, temp file and other files
+	for _, name := range []string{".123.json", "notes.txt"} {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte("x"), 0600); err != nil {
+			t.Fatalf("unexpected error (%s)", err)
+		}
+	}
+
+	files, err := deadLetterFiles([]string{dir})
+	if err != nil {
+		t.Fatalf("unexpected error (%s)", err)
+	}
+	if len(files) != 2 || files[0] != written[0] || files[1] != written[1] {
+		t.Fatalf("expected %v, got %v", written, files)
+	}
+
+	for i, fn := range files {
+		data, err := readDeadLetter(fn)
+		if err != nil {
+			t.Fatalf("unexpected error (%s)", err)
+		}
+		if string(data) != payloads[i] {
+			t.Fatalf("expected %s, got %s", payloads[i], data)
+		}
+	}
+}
@@ -0,0 +1,149 @@
+// Copyright © 2019 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+package circonus
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/config"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog"
+)
+
+// NOTES:
+// Destinations are additional circonus accounts (e.g. a mirror of the production
+// account) every submission is sent to. Each destination is a check of its own, found
+// or created with the destination's api token, with its own submission workers, retry
+// state, spool, and dead-letter dir (a destination_<name> subdirectory), so a failing
+// destination does not hold up the others. Payloads of shards are mirrored as well. If
+// a destination's queue is full the payload is spooled (or written to the dead-letter
+// dir) for it rather than blocking the primary check. Submission stats only count the
+// primary check and its shards.
+
+// the primary check and its shards share the destinations, workers and the metric
+// filter sync are started once
+type destination struct {
+	name       string
+	check      *Check
+	workers    sync.Once
+	filterSync sync.Once
+}
+
+// initializeDestinations creates the checks for the configured destinations
+func (c *Check) initializeDestinations(parentLogger zerolog.Logger) error {
+	names := make(map[string]bool)
+	for i, dc := range c.config.Destinations {
+		if dc.Name == "" {
+			return errors.Errorf("invalid destination %d, name is required", i)
+		}
+		if names[dc.Name] {
+			return errors.Errorf("invalid destination %s, duplicate name", dc.Name)
+		}
+		names[dc.Name] = true
+		if dc.API.Key == "" {
+			return errors.Errorf("invalid destination %s, api key is required", dc.Name)
+		}
+
+		cfg := destinationConfig(*c.config, dc)
+		check, err := NewCheck(parentLogger.With().Str("destination", dc.Name).Logger(), &cfg)
+		if err != nil {
+			return errors.Wrapf(err, "initializing destination %s", dc.Name)
+		}
+		check.metrics = c.metrics // submission metrics are sent with the primary check's
+
+		c.destinations = append(c.destinations, &destination{name: dc.Name, check: check})
+		c.log.Info().Str("destination", dc.Name).Str("api_url", cfg.API.URL).Str("target", cfg.Check.Target).Msg("submission destination")
+	}
+	return nil
+}
+
+// destinationConfig returns the circonus configuration of a destination, api and check
+// settings not set for the destination are taken from the primary configuration (except
+// the bundle and broker cids, which are specific to an account)
+func destinationConfig(primary config.Circonus, dc config.Destination) config.Circonus {
+	cfg := primary
+	cfg.Shards = nil
+	cfg.Destinations = nil
+	cfg.OTLP = config.OTLP{} // exported by the primary check
+	cfg.RemoteWrite = config.RemoteWrite{}
+	cfg.Alerts.Create = false // provisioned for the primary check only
+	cfg.CreateDashboards = false
+
+	cfg.API.Key = dc.API.Key
+	cfg.API.KeySecret = ""
+	if dc.API.App != "" {
+		cfg.API.App = dc.API.App
+	}
+	if dc.API.URL != "" {
+		cfg.API.URL = dc.API.URL
+	}
+	if dc.API.CAFile != "" {
+		cfg.API.CAFile = dc.API.CAFile
+	}
+
+	cfg.Check.BundleCID = dc.Check.BundleCID
+	cfg.Check.BrokerCID = dc.Check.BrokerCID
+	if dc.Check.Target != "" {
+		cfg.Check.Target = dc.Check.Target
+	}
+	if dc.Check.Title != "" {
+		cfg.Check.Title = dc.Check.Title
+	}
+	if dc.Check.BrokerSelect != "" {
+		cfg.Check.BrokerSelect = dc.Check.BrokerSelect
+	}
+	if dc.Check.BrokerCAFile != "" {
+		cfg.Check.BrokerCAFile = dc.Check.BrokerCAFile
+	}
+	if dc.Check.BrokerCert != "" {
+		cfg.Check.BrokerCert = dc.Check.BrokerCert
+		cfg.Check.BrokerKey = dc.Check.BrokerKey
+	}
+	if dc.Check.MetricFilters != "" {
+		cfg.Check.MetricFilters = dc.Check.MetricFilters
+	}
+	if dc.Check.Tags != "" {
+		cfg.Check.Tags = dc.Check.Tags
+	}
+
+	subdir := "destination_" + strings.Replace(dc.Name, " ", "_", -1)
+	if cfg.SpoolDir != "" {
+		cfg.SpoolDir = filepath.Join(cfg.SpoolDir, subdir)
+	}
+	if cfg.DeadLetterDir != "" {
+		cfg.DeadLetterDir = filepath.Join(cfg.DeadLetterDir, subdir)
+	}
+
+	return cfg
+}
+
+// startSubmitter starts the destination's submission workers
+func (d *destination) startSubmitter(ctx context.Context) {
+	d.workers.Do(func() {
+		go d.check.Submitter(ctx)
+	})
+}
+
+// startFilterSync starts the destination's check bundle metric filter sync
+func (d *destination) startFilterSync(ctx context.Context) {
+	d.filterSync.Do(func() {
+		go d.check.SyncMetricFilters(ctx)
+	})
+}
+
+// mirror queues an encoded payload for the destination's submission workers, if the
+// queue is full the payload is spooled for the destination
+func (d *destination) mirror(data []byte, logger zerolog.Logger) {
+	select {
+	case d.check.metricQueue <- MetricSet{Metrics: data, Logger: logger, Deadline: d.check.submitDeadline()}:
+	default:
+		logger.Warn().Msg("destination submission queue full")
+		d.check.spoolPayload(data, logger)
+	}
+}
@@ -0,0 +1,63 @@
+// Copyright © 2019 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+package circonus
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/config"
+	"github.com/rs/zerolog"
+)
+
+func TestDestinationConfig(t *testing.T) {
+	primary := config.Circonus{
+		API:      config.API{Key: "primary", App: "cka", URL: "https://api.circonus.com/v2/"},
+		Check:    config.Check{BundleCID: "/check_bundle/1", BrokerCID: "/broker/1", Target: "prod", Title: "prod /cka", Tags: "a:b"},
+		Shards:   []config.Shard{{Name: "ksm"}},
+		SpoolDir: "/spool",
+	}
+	primary.Alerts.Create = true
+
+	cfg := destinationConfig(primary, config.Destination{
+		Name:  "mirror",
+		API:   config.API{Key: "mirror", URL: "https://mirror.example.com/v2/"},
+		Check: config.Check{Title: "prod mirror"},
+	})
+
+	if cfg.API.Key != "mirror" || cfg.API.URL != "https://mirror.example.com/v2/" || cfg.API.App != "cka" {
+		t.Fatalf("unexpected api config %+v", cfg.API)
+	}
+	if cfg.Check.BundleCID != "" || cfg.Check.BrokerCID != "" {
+		t.Fatalf("expected account specific cids to be cleared, got %+v", cfg.Check)
+	}
+	if cfg.Check.Target != "prod" || cfg.Check.Title != "prod mirror" || cfg.Check.Tags != "a:b" {
+		t.Fatalf("unexpected check config %+v", cfg.Check)
+	}
+	if len(cfg.Shards) != 0 || cfg.Alerts.Create {
+		t.Fatal("expected shards and alerts to be left to the primary check")
+	}
+	if cfg.SpoolDir != filepath.Join("/spool", "destination_mirror") || cfg.DeadLetterDir != "" {
+		t.Fatalf("unexpected spool %q dead-letter %q dirs", cfg.SpoolDir, cfg.DeadLetterDir)
+	}
+	if primary.API.Key != "primary" {
+		t.Fatal("expected primary config to be unchanged")
+	}
+}
+
+func TestDestinationMirror(t *testing.T) {
+	d := &destination{name: "mirror", check: &Check{metricQueue: make(chan MetricSet, 1)}}
+
+	d.mirror([]byte(`{"a":{"_value":1}}`), zerolog.Nop())
+	d.mirror([]byte(`{"b":{"_value":2}}`), zerolog.Nop()) // queue full, must not block
+
+	if len(d.check.metricQueue) != 1 {
+		t.Fatalf("expected 1 queued payload, got %d", len(d.check.metricQueue))
+	}
+	if ms := <-d.check.metricQueue; string(ms.Metrics) != `{"a":{"_value":1}}` {
+		t.Fatalf("unexpected payload %s", ms.Metrics)
+	}
+}
@@ -0,0 +1,53 @@
+// Copyright © 2019 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+package circonus
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+// dryRunOutput writes the would-be submissions of a dry run, one JSON document
+// per submission, to stdout or a file (opened on the first write, appending)
+type dryRunOutput struct {
+	file string
+	w    io.Writer
+	sync.Mutex
+}
+
+func newDryRunOutput(file string) *dryRunOutput {
+	if file == "" || file == "-" {
+		return &dryRunOutput{w: os.Stdout}
+	}
+	return &dryRunOutput{file: file}
+}
+
+// write writes a submission, submissions are serialized so concurrent
+// collectors do not interleave their output
+func (d *dryRunOutput) write(data []byte) error {
+	d.Lock()
+	defer d.Unlock()
+
+	if d.w == nil {
+		fh, err := os.OpenFile(d.file, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+		if err != nil {
+			return errors.Wrap(err, "opening dry run output")
+		}
+		d.w = fh
+	}
+
+	if _, err := d.w.Write(bytes.TrimRight(data, "\n")); err != nil {
+		return errors.Wrap(err, "writing dry run output")
+	}
+	if _, err := d.w.Write([]byte("\n")); err != nil {
+		return errors.Wrap(err, "writing dry run output")
+	}
+	return nil
+}
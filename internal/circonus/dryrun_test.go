@@ -0,0 +1,42 @@
+// Copyright © 2019 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+package circonus
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestDryRunOutput(t *testing.T) {
+	dir, err := ioutil.TempDir("", "dryrun")
+	if err != nil {
+		t.Fatalf("temp dir: %s", err)
+	}
+	defer os.RemoveAll(dir)
+
+	fn := filepath.Join(dir, "submissions.json")
+	d := newDryRunOutput(fn)
+	for _, data := range []string{"{\"a\":{\"_value\":1}}\n", "{\"b\":{\"_value\":2}}"} {
+		if err := d.write([]byte(data)); err != nil {
+			t.Fatalf("unexpected error (%s)", err)
+		}
+	}
+
+	got, err := ioutil.ReadFile(fn)
+	if err != nil {
+		t.Fatalf("unexpected error (%s)", err)
+	}
+	want := "{\"a\":{\"_value\":1}}\n{\"b\":{\"_value\":2}}\n"
+	if string(got) != want {
+		t.Fatalf("expected %q, got %q", want, got)
+	}
+
+	if d := newDryRunOutput("-"); d.w != os.Stdout {
+		t.Fatal("expected stdout")
+	}
+}
@@ -0,0 +1,99 @@
+// Copyright © 2019 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+package circonus
+
+import (
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// NOTES:
+// Watchers (e.g. image pulls, evictions, spot interruptions) receive events between
+// collections. Counts and samples of the events are accumulated and queued by the
+// watcher's Collect, as cluster check metrics of the interval, so they go through the
+// same filters, shards, exporters, and cardinality guard as any other collector's
+// metrics. Counts are of the events received during the interval (like cgm counters),
+// samples are sent as a histogram of the interval. Nothing is queued for a stream
+// without events.
+
+// EventMetrics accumulates event counts and samples between collections
+type EventMetrics struct {
+	counts  map[string]*eventCount
+	samples map[string]*eventSamples
+	sync.Mutex
+}
+
+type eventCount struct {
+	metricName string
+	streamTags []string
+	count      uint64
+}
+
+type eventSamples struct {
+	metricName string
+	streamTags []string
+	values     []float64
+}
+
+// NewEventMetrics returns an empty event metrics accumulator
+func NewEventMetrics() *EventMetrics {
+	return &EventMetrics{
+		counts:  make(map[string]*eventCount),
+		samples: make(map[string]*eventSamples),
+	}
+}
+
+// Increment adds n to the count of events for the metric stream
+func (em *EventMetrics) Increment(metricName string, streamTags []string, n uint64) {
+	key := eventKey(metricName, streamTags)
+	em.Lock()
+	defer em.Unlock()
+	ec, found := em.counts[key]
+	if !found {
+		ec = &eventCount{metricName: metricName, streamTags: streamTags}
+		em.counts[key] = ec
+	}
+	ec.count += n
+}
+
+// AddSample adds a sample (e.g. a duration) to the histogram of the metric stream
+func (em *EventMetrics) AddSample(metricName string, streamTags []string, value float64) {
+	key := eventKey(metricName, streamTags)
+	em.Lock()
+	defer em.Unlock()
+	es, found := em.samples[key]
+	if !found {
+		es = &eventSamples{metricName: metricName, streamTags: streamTags}
+		em.samples[key] = es
+	}
+	es.values = append(es.values, value)
+}
+
+// Queue queues the counts and samples accumulated since the previous call and resets them
+func (em *EventMetrics) Queue(check *Check, metrics map[string]MetricSample, ts *time.Time) {
+	em.Lock()
+	counts, samples := em.counts, em.samples
+	em.counts = make(map[string]*eventCount)
+	em.samples = make(map[string]*eventSamples)
+	em.Unlock()
+
+	for _, ec := range counts {
+		_ = check.QueueMetricSample(metrics, ec.metricName, MetricTypeUint64, ec.streamTags, []string{}, ec.count, ts)
+	}
+	for _, es := range samples {
+		_ = check.QueueMetricSample(metrics, es.metricName, MetricTypeHistogram, es.streamTags, []string{}, HistogramBins(es.values), ts)
+	}
+}
+
+// eventKey returns the key of a metric stream
+func eventKey(metricName string, streamTags []string) string {
+	tags := make([]string, len(streamTags))
+	copy(tags, streamTags)
+	sort.Strings(tags)
+	return metricName + "|" + strings.Join(tags, ",")
+}
@@ -0,0 +1,49 @@
+// Copyright © 2019 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+package circonus
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/config"
+)
+
+func TestEventMetrics(t *testing.T) {
+	c := &Check{config: &config.Circonus{}}
+	em := NewEventMetrics()
+
+	em.Increment("image_pulls", []string{"source:image-pulls", "registry:docker.io"}, 1)
+	em.Increment("image_pulls", []string{"registry:docker.io", "source:image-pulls"}, 2)
+	em.AddSample("image_pull_duration", []string{"registry:docker.io"}, 1.01)
+	em.AddSample("image_pull_duration", []string{"registry:docker.io"}, 1.09)
+
+	metrics := make(map[string]MetricSample)
+	em.Queue(c, metrics, nil)
+	if len(metrics) != 2 {
+		t.Fatalf("expected 2 metrics, got %v", metrics)
+	}
+	for name, ms := range metrics {
+		switch ms.Type {
+		case MetricTypeUint64:
+			if ms.Value != uint64(3) {
+				t.Fatalf("%s: expected count 3, got %v", name, ms.Value)
+			}
+		case MetricTypeHistogram:
+			if !reflect.DeepEqual(ms.Value, []string{"H[1.05e+00]=2"}) {
+				t.Fatalf("%s: unexpected histogram %v", name, ms.Value)
+			}
+		default:
+			t.Fatalf("%s: unexpected type %s", name, ms.Type)
+		}
+	}
+
+	metrics = make(map[string]MetricSample)
+	em.Queue(c, metrics, nil)
+	if len(metrics) != 0 {
+		t.Fatalf("expected nothing queued without new events, got %v", metrics)
+	}
+}
@@ -0,0 +1,99 @@
+// Copyright © 2019 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+package circonus
+
+import (
+	"context"
+
+	cgm "github.com/circonus-labs/circonus-gometrics/v3"
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/release"
+	"github.com/rs/zerolog"
+)
+
+// NOTES:
+// Exporters receive the numeric metric samples of each queue submitted, after
+// tag transforms and agent metric filters, so collected metrics can be written
+// to other backends in parallel with circonus. Histograms, text metrics, and the
+// agent's own metrics are not exported.
+
+// ExportSample is a numeric metric sample with its (untagged) name and stream tags
+type ExportSample struct {
+	Name      string
+	Tags      []string // category:value
+	Type      string
+	Value     interface{}
+	Timestamp uint64 // ms, 0 if not set
+}
+
+// Exporter sends metric samples to another backend
+type Exporter interface {
+	Name() string
+	Export(ctx context.Context, samples []ExportSample) error
+}
+
+// AddExporter adds an exporter receiving the metric samples submitted, exporters
+// must be added before collection starts
+func (c *Check) AddExporter(e Exporter) {
+	c.exporters = append(c.exporters, e)
+}
+
+// export sends the numeric samples of a metric queue to the exporters
+func (c *Check) export(ctx context.Context, metrics map[string]MetricSample, resultLogger zerolog.Logger) {
+	samples := make([]ExportSample, 0, len(metrics))
+	for _, ms := range metrics {
+		if ms.name == "" {
+			continue
+		}
+		switch ms.Type {
+		case MetricTypeString, MetricTypeHistogram, MetricTypeCumulativeHistogram:
+			continue
+		}
+		samples = append(samples, ExportSample{
+			Name:      ms.name,
+			Tags:      ms.tags,
+			Type:      ms.Type,
+			Value:     ms.Value,
+			Timestamp: ms.Timestamp,
+		})
+	}
+	if len(samples) == 0 {
+		return
+	}
+
+	for _, e := range c.exporters {
+		if err := e.Export(ctx, samples); err != nil {
+			resultLogger.Warn().Err(err).Str("exporter", e.Name()).Msg("exporting metrics")
+			c.IncrementCounter("collect_export_errors", cgm.Tags{
+				cgm.Tag{Category: "exporter", Value: e.Name()},
+				cgm.Tag{Category: "source", Value: release.NAME},
+			})
+		}
+	}
+}
+
+// exportFloat returns a numeric sample value as a float64
+func exportFloat(v interface{}) (float64, bool) {
+	switch n := v.(type) {
+	case float64:
+		return n, true
+	case float32:
+		return float64(n), true
+	case int:
+		return float64(n), true
+	case int32:
+		return float64(n), true
+	case int64:
+		return float64(n), true
+	case uint:
+		return float64(n), true
+	case uint32:
+		return float64(n), true
+	case uint64:
+		return float64(n), true
+	default:
+		return 0, false
+	}
+}
@@ -0,0 +1,165 @@
+// Copyright © 2019 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+package circonus
+
+import (
+	"io/ioutil"
+	"regexp"
+	"strings"
+
+	"github.com/pkg/errors"
+	yaml "gopkg.in/yaml.v2"
+)
+
+// Example agent metric filters file:
+//
+//   filters:
+//     - action: deny
+//       regex: ^container_network_
+//       collectors: [nodes]
+//     - action: deny
+//       regex: ^apiserver_request_duration_seconds$
+//       tags: [resource:events]
+//
+// Agent metric filters are applied as metrics are queued, before submission, so high
+// cardinality series can be dropped at the source rather than by the check bundle
+// metric filters. Filters are positional, the first filter matching a metric wins,
+// metrics not matching any filter are allowed. Collectors are source stream tag values
+// and tags are stream tags which must all be present, a tag value ending in * matches
+// any value with the prefix (e.g. namespace:kube-*). Denied metrics are still available
+// to recording rules and slos.
+
+type metricFilterFile struct {
+	Filters []metricFilterConfig `yaml:"filters"`
+}
+
+type metricFilterConfig struct {
+	Action     string   `yaml:"action"`
+	Regex      string   `yaml:"regex"`
+	Collectors []string `yaml:"collectors"`
+	Tags       []string `yaml:"tags"`
+}
+
+type metricFilter struct {
+	allow      bool
+	rx         *regexp.Regexp
+	collectors map[string]bool
+	tags       []string
+}
+
+// LoadAgentMetricFilters loads the agent side metric filters, replacing the current
+// filters (e.g. when the configuration is reloaded)
+func (c *Check) LoadAgentMetricFilters(file string) error {
+	if file == "" {
+		return errors.New("invalid agent metric filters file (empty)")
+	}
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		return errors.Wrap(err, "reading agent metric filters file")
+	}
+	filters, err := parseMetricFilters(data)
+	if err != nil {
+		return err
+	}
+	c.pipelinemu.Lock()
+	c.filters = filters
+	c.pipelinemu.Unlock()
+	c.log.Info().Int("filters", len(filters)).Msg("agent metric filters loaded")
+	return nil
+}
+
+// parseMetricFilters parses and validates agent metric filters
+func parseMetricFilters(data []byte) ([]metricFilter, error) {
+	var mff metricFilterFile
+	if err := yaml.Unmarshal(data, &mff); err != nil {
+		return nil, errors.Wrap(err, "parsing agent metric filters")
+	}
+
+	filters := make([]metricFilter, 0, len(mff.Filters))
+	for i, fc := range mff.Filters {
+		f := metricFilter{tags: fc.Tags}
+		switch fc.Action {
+		case "allow":
+			f.allow = true
+		case "deny":
+		default:
+			return nil, errors.Errorf("invalid agent metric filter %d, unknown action (%s)", i, fc.Action)
+		}
+		if fc.Regex == "" {
+			return nil, errors.Errorf("invalid agent metric filter %d, regex is required", i)
+		}
+		rx, err := regexp.Compile(fc.Regex)
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid agent metric filter %d regex", i)
+		}
+		f.rx = rx
+		if len(fc.Collectors) > 0 {
+			f.collectors = make(map[string]bool)
+			for _, collector := range fc.Collectors {
+				f.collectors[collector] = true
+			}
+		}
+		filters = append(filters, f)
+	}
+	return filters, nil
+}
+
+// matches returns true if the filter applies to the metric
+func (f metricFilter) matches(metricName string, streamTags []string) bool {
+	if !f.rx.MatchString(metricName) {
+		return false
+	}
+	if f.collectors != nil {
+		found := false
+		for _, tag := range streamTags {
+			if strings.HasPrefix(tag, "source:") && f.collectors[strings.TrimPrefix(tag, "source:")] {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	for _, want := range f.tags {
+		found := false
+		for _, tag := range streamTags {
+			if tag == want || (strings.HasSuffix(want, "*") && strings.HasPrefix(tag, strings.TrimSuffix(want, "*"))) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// allowMetric returns false if the first agent metric filter matching the metric denies it
+func (c *Check) allowMetric(metricName string, streamTags []string) bool {
+	for _, f := range c.metricFilters() {
+		if f.matches(metricName, streamTags) {
+			return f.allow
+		}
+	}
+	return true
+}
+
+// allowNamespace returns false if the metric's namespace stream tag is not allowed by
+// the namespace filter
+func (c *Check) allowNamespace(streamTags []string) bool {
+	nf := c.namespaceFilter()
+	if nf.Empty() {
+		return true
+	}
+	for _, tag := range streamTags {
+		if strings.HasPrefix(tag, "namespace:") {
+			return nf.Allowed(strings.TrimPrefix(tag, "namespace:"))
+		}
+	}
+	return true
+}
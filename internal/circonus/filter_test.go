@@ -0,0 +1,64 @@
+// Copyright © 2019 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+package circonus
+
+import (
+	"testing"
+)
+
+func TestAllowMetric(t *testing.T) {
+	filters, err := parseMetricFilters([]byte(`
+filters:
+  - action: allow
+    regex: ^container_network_receive_bytes_total$
+  - action: deny
+    regex: ^container_network_
+    collectors: [nodes]
+  - action: deny
+    regex: ^apiserver_request_duration_seconds$
+    tags: [resource:events, verb:*]
+`))
+	if err != nil {
+		t.Fatalf("unexpected error (%s)", err)
+	}
+	c := &Check{filters: filters}
+
+	tests := []struct {
+		name       string
+		metricName string
+		streamTags []string
+		want       bool
+	}{
+		{"allowed first", "container_network_receive_bytes_total", []string{"source:nodes"}, true},
+		{"denied collector", "container_network_transmit_errors_total", []string{"source:nodes"}, false},
+		{"other collector", "container_network_transmit_errors_total", []string{"source:promscrape"}, true},
+		{"denied tags", "apiserver_request_duration_seconds", []string{"source:api-server", "resource:events", "verb:LIST"}, false},
+		{"missing tag", "apiserver_request_duration_seconds", []string{"source:api-server", "resource:events"}, true},
+		{"no match", "kube_pod_info", []string{"source:kube-state-metrics"}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := c.allowMetric(tt.metricName, tt.streamTags); got != tt.want {
+				t.Errorf("allowMetric() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseMetricFiltersInvalid(t *testing.T) {
+	tests := []string{
+		"filters: [{action: drop, regex: ^x$}]",
+		"filters: [{action: deny}]",
+		"filters: [{action: deny, regex: '('}]",
+	}
+
+	for _, tt := range tests {
+		if _, err := parseMetricFilters([]byte(tt)); err == nil {
+			t.Errorf("parseMetricFilters(%q) expected error", tt)
+		}
+	}
+}
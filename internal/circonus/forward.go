@@ -0,0 +1,143 @@
+// Copyright © 2019 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+package circonus
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog"
+)
+
+// NOTES:
+// Rather than submitting to a broker, metrics can be forwarded to a node-local
+// circonus-agent (its /write/<id> endpoint accepts the same payloads, the agent's
+// own check is used) or to a statsd listener. Statsd only supports numeric samples,
+// they are sent as gauges with the stream tags in the |#category:value,... format.
+// Text and histogram samples, and the agent's own metrics, are not sent to statsd.
+
+// maxStatsdPacket keeps statsd datagrams under a typical network mtu
+const maxStatsdPacket = 1432
+
+// forwardStatsd sends a metric payload to the statsd listener
+func (c *Check) forwardStatsd(metrics io.Reader, resultLogger zerolog.Logger) error {
+	data, err := ioutil.ReadAll(metrics)
+	if err != nil {
+		return errors.Wrap(err, "reading metric data")
+	}
+	var samples map[string]MetricSample
+	if err := json.Unmarshal(data, &samples); err != nil {
+		return errors.Wrap(err, "parsing metric data")
+	}
+
+	lines, skipped := statsdLines(samples)
+	if skipped > 0 {
+		resultLogger.Debug().Int("skipped", skipped).Msg("non-numeric metrics not sent to statsd")
+	}
+
+	sent := 0
+	for _, packet := range statsdPackets(lines) {
+		n, err := c.statsd.Write(packet)
+		if err != nil {
+			return errors.Wrap(err, "sending to statsd")
+		}
+		sent += n
+	}
+
+	c.statsmu.Lock()
+	c.stats.Metrics += uint64(len(lines))
+	c.stats.SentBytes += uint64(sent)
+	c.statsmu.Unlock()
+
+	return nil
+}
+
+// statsdLines returns a gauge line for each numeric sample (sorted) and the number
+// of samples skipped
+func statsdLines(samples map[string]MetricSample) ([]string, int) {
+	lines := make([]string, 0, len(samples))
+	skipped := 0
+	for taggedName, ms := range samples {
+		switch ms.Type {
+		case MetricTypeString, MetricTypeHistogram, MetricTypeCumulativeHistogram:
+			skipped++
+			continue
+		}
+		v, ok := exportFloat(ms.Value)
+		if !ok {
+			skipped++
+			continue
+		}
+		name, tags := decodeTaggedName(taggedName)
+		line := fmt.Sprintf("%s:%v|g", name, v)
+		if len(tags) > 0 {
+			line += "|#" + strings.Join(tags, ",")
+		}
+		lines = append(lines, line)
+	}
+	sort.Strings(lines)
+	return lines, skipped
+}
+
+// statsdPackets joins lines into datagrams of at most maxStatsdPacket bytes, a
+// longer line is sent in a datagram of its own
+func statsdPackets(lines []string) [][]byte {
+	packets := [][]byte{}
+	var packet []byte
+	for _, line := range lines {
+		if len(packet) > 0 && len(packet)+1+len(line) > maxStatsdPacket {
+			packets = append(packets, packet)
+			packet = nil
+		}
+		if len(packet) > 0 {
+			packet = append(packet, '\n')
+		}
+		packet = append(packet, line...)
+	}
+	if len(packet) > 0 {
+		packets = append(packets, packet)
+	}
+	return packets
+}
+
+// decodeTaggedName returns the metric name and the stream and measurement tags
+// (category:value) of a tagged metric name, base64 encoded tags are decoded
+func decodeTaggedName(taggedName string) (string, []string) {
+	parts := strings.Split(taggedName, "|")
+	name := parts[0]
+	var tags []string
+	for _, p := range parts[1:] {
+		if !strings.HasSuffix(p, "]") || !(strings.HasPrefix(p, "ST[") || strings.HasPrefix(p, "MT[")) {
+			continue
+		}
+		for _, tag := range strings.Split(p[3:len(p)-1], ",") {
+			kv := strings.SplitN(tag, ":", 2)
+			if len(kv) != 2 {
+				continue
+			}
+			tags = append(tags, decodeTagPart(kv[0])+":"+decodeTagPart(kv[1]))
+		}
+	}
+	return name, tags
+}
+
+// decodeTagPart decodes a b"<base64>" tag category or value
+func decodeTagPart(s string) string {
+	if !strings.HasPrefix(s, `b"`) || !strings.HasSuffix(s, `"`) || len(s) < 3 {
+		return s
+	}
+	data, err := base64.StdEncoding.DecodeString(s[2 : len(s)-1])
+	if err != nil {
+		return s
+	}
+	return string(data)
+}
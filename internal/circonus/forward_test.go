@@ -0,0 +1,68 @@
+// Copyright © 2019 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+package circonus
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestDecodeTaggedName(t *testing.T) {
+	tests := []struct {
+		taggedName string
+		wantName   string
+		wantTags   []string
+	}{
+		{"pods", "pods", nil},
+		{"pods|ST[namespace:default,source:nodes]", "pods", []string{"namespace:default", "source:nodes"}},
+		{`pods|ST[b"bmFtZXNwYWNl":b"ZGVmYXVsdA=="]|MT[b"dW5pdHM=":b"Ynl0ZXM="]`, "pods", []string{"namespace:default", "units:bytes"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.taggedName, func(t *testing.T) {
+			name, tags := decodeTaggedName(tt.taggedName)
+			if name != tt.wantName {
+				t.Fatalf("expected name %s, got %s", tt.wantName, name)
+			}
+			if !reflect.DeepEqual(tags, tt.wantTags) {
+				t.Fatalf("expected tags %v, got %v", tt.wantTags, tags)
+			}
+		})
+	}
+}
+
+func TestStatsdLines(t *testing.T) {
+	samples := map[string]MetricSample{
+		"pods|ST[source:nodes]": {Type: MetricTypeUint64, Value: float64(3)},
+		"cpu":                   {Type: MetricTypeFloat64, Value: 0.5},
+		"version":               {Type: MetricTypeString, Value: "v1.17"},
+		"latency":               {Type: MetricTypeCumulativeHistogram, Value: []string{"H[1.0e+00]=1"}},
+	}
+
+	lines, skipped := statsdLines(samples)
+	want := []string{"cpu:0.5|g", "pods:3|g|#source:nodes"}
+	if !reflect.DeepEqual(lines, want) {
+		t.Fatalf("expected %v, got %v", want, lines)
+	}
+	if skipped != 2 {
+		t.Fatalf("expected 2 skipped, got %d", skipped)
+	}
+}
+
+func TestStatsdPackets(t *testing.T) {
+	line := strings.Repeat("x", 500)
+	packets := statsdPackets([]string{line, line, line, strings.Repeat("y", 2000)})
+	if len(packets) != 3 {
+		t.Fatalf("expected 3 packets, got %d", len(packets))
+	}
+	if string(packets[0]) != line+"\n"+line {
+		t.Fatalf("unexpected first packet (%d bytes)", len(packets[0]))
+	}
+	if len(packets[2]) != 2000 {
+		t.Fatalf("expected oversized line in its own packet, got %d bytes", len(packets[2]))
+	}
+}
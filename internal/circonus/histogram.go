@@ -0,0 +1,178 @@
+// Copyright © 2019 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+package circonus
+
+import (
+	"fmt"
+	"math"
+	"sort"
+)
+
+// HistogramBucket is a cumulative (prometheus style) histogram bucket, the
+// number of samples less than or equal to the upper bound
+type HistogramBucket struct {
+	UpperBound      float64
+	CumulativeCount uint64
+}
+
+// NOTES:
+// Circonus log-linear histogram bins have two significant digits, e.g. bin 1.2e-01
+// holds values in [0.12, 0.13). A prometheus bucket usually spans many bins, its
+// samples are spread evenly over the bucket's range (the same linear interpolation
+// prometheus histogram_quantile uses) and rounded so the total count is exact. The
+// first bucket is assumed to start one decade below its upper bound, samples in the
+// +Inf bucket are placed in the bin of the largest finite upper bound.
+
+// LogLinearBins translates cumulative histogram buckets, in ascending upper bound
+// order, into circonus log-linear histogram bins (H[value]=count) for submission
+// as a MetricTypeCumulativeHistogram sample
+func LogLinearBins(buckets []HistogramBucket) []string {
+	var bins []llBin
+	var prevCount uint64
+	lower := math.NaN()
+	for _, b := range buckets {
+		if b.CumulativeCount < prevCount {
+			continue // invalid, counts must not decrease
+		}
+		n := b.CumulativeCount - prevCount
+		prevCount = b.CumulativeCount
+		upper := b.UpperBound
+		if n > 0 {
+			switch {
+			case math.IsInf(upper, +1):
+				if math.IsNaN(lower) {
+					lower = 0
+				}
+				bins = addBin(bins, binOf(lower), n)
+			case upper <= 0:
+				bins = addBin(bins, binOf(upper), n)
+			default:
+				lo := lower
+				if math.IsNaN(lo) || lo <= 0 {
+					lo = upper / 10
+				}
+				bins = spread(bins, lo, upper, n)
+			}
+		}
+		lower = upper
+	}
+
+	ret := make([]string, 0, len(bins))
+	for _, b := range bins {
+		ret = append(ret, fmt.Sprintf("H[%.2e]=%d", b.value(), b.count))
+	}
+	return ret
+}
+
+// HistogramBins returns the circonus log-linear histogram bins (H[value]=count) of
+// the samples, for submission as a MetricTypeHistogram sample
+func HistogramBins(samples []float64) []string {
+	sorted := make([]float64, len(samples))
+	copy(sorted, samples)
+	sort.Float64s(sorted)
+
+	var bins []llBin
+	for _, v := range sorted {
+		bins = addBin(bins, binOf(v), 1)
+	}
+
+	ret := make([]string, 0, len(bins))
+	for _, b := range bins {
+		ret = append(ret, fmt.Sprintf("H[%.2e]=%d", b.value(), b.count))
+	}
+	return ret
+}
+
+// llBin is a log-linear bin, mantissa (10-99, 0 for the zero bin, negative for
+// negative values) and exponent, value = mantissa/10 * 10^exp
+type llBin struct {
+	mantissa int
+	exp      int
+	count    uint64
+}
+
+// value returns the midpoint of the bin, so the bin is unambiguous when parsed
+func (b llBin) value() float64 {
+	if b.mantissa == 0 {
+		return 0
+	}
+	mid := float64(b.mantissa) + 0.5
+	if b.mantissa < 0 {
+		mid = float64(b.mantissa) - 0.5
+	}
+	return mid * math.Pow10(b.exp-1)
+}
+
+// lower returns the lower edge of a (positive) bin
+func (b llBin) lower() float64 {
+	return float64(b.mantissa) * math.Pow10(b.exp-1)
+}
+
+// next returns the following (positive) bin
+func (b llBin) next() llBin {
+	if b.mantissa == 99 {
+		return llBin{mantissa: 10, exp: b.exp + 1}
+	}
+	return llBin{mantissa: b.mantissa + 1, exp: b.exp}
+}
+
+// binOf returns the bin holding v
+func binOf(v float64) llBin {
+	if v == 0 || math.IsNaN(v) || math.IsInf(v, 0) {
+		return llBin{}
+	}
+	sign := 1
+	if v < 0 {
+		sign = -1
+		v = -v
+	}
+	exp := int(math.Floor(math.Log10(v)))
+	m := int(math.Floor(v / math.Pow10(exp-1)))
+	// correct for floating point error at decade edges
+	if m < 10 {
+		m, exp = 99, exp-1
+	} else if m > 99 {
+		m, exp = 10, exp+1
+	}
+	return llBin{mantissa: sign * m, exp: exp}
+}
+
+// spread distributes n samples evenly over the (positive) range lower..upper
+func spread(bins []llBin, lower, upper float64, n uint64) []llBin {
+	width := upper - lower
+	var weight float64 // cumulative weight of the bins so far
+	var placed uint64  // samples placed so far
+	for b := binOf(lower); b.lower() < upper; b = b.next() {
+		lo := math.Max(b.lower(), lower)
+		hi := math.Min(b.next().lower(), upper)
+		if hi <= lo {
+			continue
+		}
+		weight += (hi - lo) / width
+		total := uint64(math.Round(weight * float64(n)))
+		if total > n {
+			total = n
+		}
+		if c := total - placed; c > 0 {
+			bins = addBin(bins, b, c)
+			placed = total
+		}
+	}
+	if placed < n { // rounding remainder
+		bins = addBin(bins, binOf(upper*0.999), n-placed)
+	}
+	return bins
+}
+
+// addBin adds count to the bin, merging with the last bin if it is the same
+func addBin(bins []llBin, b llBin, count uint64) []llBin {
+	if l := len(bins); l > 0 && bins[l-1].mantissa == b.mantissa && bins[l-1].exp == b.exp {
+		bins[l-1].count += count
+		return bins
+	}
+	b.count = count
+	return append(bins, b)
+}
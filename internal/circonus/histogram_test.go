@@ -0,0 +1,115 @@
+// Copyright © 2019 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+package circonus
+
+import (
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestLogLinearBins(t *testing.T) {
+	tests := []struct {
+		name    string
+		buckets []HistogramBucket
+		want    []string
+	}{
+		{
+			name:    "single bin",
+			buckets: []HistogramBucket{{1, 0}, {1.1, 3}, {math.Inf(+1), 4}},
+			want:    []string{"H[1.05e+00]=3", "H[1.15e+00]=1"},
+		},
+		{
+			name:    "spread",
+			buckets: []HistogramBucket{{1, 0}, {1.2, 4}},
+			want:    []string{"H[1.05e+00]=2", "H[1.15e+00]=2"},
+		},
+		{
+			name:    "empty",
+			buckets: []HistogramBucket{{0.1, 0}, {math.Inf(+1), 0}},
+			want:    []string{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := LogLinearBins(tt.buckets); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("LogLinearBins() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLogLinearBinsCount(t *testing.T) {
+	// api-server request duration style buckets
+	buckets := []HistogramBucket{
+		{0.005, 10}, {0.025, 17}, {0.05, 40}, {0.1, 100}, {0.2, 130},
+		{0.4, 131}, {0.6, 131}, {1, 140}, {5, 141}, {60, 145}, {math.Inf(+1), 146},
+	}
+
+	var total uint64
+	prev := math.Inf(-1)
+	for _, bin := range LogLinearBins(buckets) {
+		i := strings.Index(bin, "]=")
+		v, err := strconv.ParseFloat(bin[2:i], 64)
+		if err != nil {
+			t.Fatalf("invalid bin value %s (%s)", bin, err)
+		}
+		if v <= prev {
+			t.Errorf("bin %s out of order", bin)
+		}
+		prev = v
+		n, err := strconv.ParseUint(bin[i+2:], 10, 64)
+		if err != nil {
+			t.Fatalf("invalid bin count %s (%s)", bin, err)
+		}
+		total += n
+	}
+	if total != 146 {
+		t.Errorf("total count = %d, want 146", total)
+	}
+}
+
+func TestBinOf(t *testing.T) {
+	tests := []struct {
+		v    float64
+		want llBin
+	}{
+		{0.3, llBin{mantissa: 30, exp: -1}},
+		{0.29999, llBin{mantissa: 29, exp: -1}},
+		{1000, llBin{mantissa: 10, exp: 3}},
+		{-1.5, llBin{mantissa: -15, exp: 0}},
+		{0, llBin{}},
+	}
+
+	for _, tt := range tests {
+		if got := binOf(tt.v); got != tt.want {
+			t.Errorf("binOf(%v) = %+v, want %+v", tt.v, got, tt.want)
+		}
+	}
+}
+
+func TestHistogramBins(t *testing.T) {
+	tests := []struct {
+		name    string
+		samples []float64
+		want    []string
+	}{
+		{"empty", nil, []string{}},
+		{"same bin", []float64{1.01, 1.09, 1.05}, []string{"H[1.05e+00]=3"}},
+		{"unsorted", []float64{12, 0, 1.1, 12.5}, []string{"H[0.00e+00]=1", "H[1.15e+00]=1", "H[1.25e+01]=2"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := HistogramBins(tt.samples); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("HistogramBins() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
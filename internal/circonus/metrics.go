@@ -57,6 +57,9 @@ type MetricSample struct {
 	Value     interface{} `json:"_value"`
 	Type      string      `json:"_type"`
 	Timestamp uint64      `json:"_ts,omitempty"`
+	shard     int         // destination check, see route
+	name      string      // untagged metric name, set when exporters are configured
+	tags      []string    // stream tags, set when exporters are configured
 }
 
 var (
@@ -74,16 +77,20 @@ var (
 
 // AddGauge to queue for submission
 func (c *Check) AddGauge(metricName string, tags cgm.Tags, value interface{}) {
+	tags = append(tags, c.defaultTags...)
+	tags = c.transformCGMTags(tags)
+	selfMetrics.gauge(metricName, tags, value)
 	if c.metrics != nil {
-		tags = append(tags, c.defaultTags...)
 		c.metrics.GaugeWithTags(metricName, tags, value)
 	}
 }
 
 // AddHistSample to queue for submission
 func (c *Check) AddHistSample(metricName string, tags cgm.Tags, value float64) {
+	tags = append(tags, c.defaultTags...)
+	tags = c.transformCGMTags(tags)
+	selfMetrics.observe(metricName, tags, value)
 	if c.metrics != nil {
-		tags = append(tags, c.defaultTags...)
 		c.metrics.TimingWithTags(metricName, tags, value)
 	}
 }
@@ -92,22 +99,27 @@ func (c *Check) AddHistSample(metricName string, tags cgm.Tags, value float64) {
 func (c *Check) AddText(metricName string, tags cgm.Tags, value string) {
 	if c.metrics != nil {
 		tags = append(tags, c.defaultTags...)
+		tags = c.transformCGMTags(tags)
 		c.metrics.SetTextWithTags(metricName, tags, value)
 	}
 }
 
 // IncrementCounter to queue for submission
 func (c *Check) IncrementCounter(metricName string, tags cgm.Tags) {
+	tags = append(tags, c.defaultTags...)
+	tags = c.transformCGMTags(tags)
+	selfMetrics.increment(metricName, tags)
 	if c.metrics != nil {
-		tags = append(tags, c.defaultTags...)
 		c.metrics.IncrementWithTags(metricName, tags)
 	}
 }
 
 // SetCounter to queue for submission
 func (c *Check) SetCounter(metricName string, tags cgm.Tags, value uint64) {
+	tags = append(tags, c.defaultTags...)
+	tags = c.transformCGMTags(tags)
+	selfMetrics.setCounter(metricName, tags, value)
 	if c.metrics != nil {
-		tags = append(tags, c.defaultTags...)
 		c.metrics.SetWithTags(metricName, tags, value)
 	}
 }
@@ -122,6 +134,34 @@ func (c *Check) QueueMetricSample(
 	value interface{},
 	timestamp *time.Time) error {
 
+	return c.queueSample(metrics, metricName, metricType, streamTags, measurementTags, value, false, timestamp)
+}
+
+// QueueCounterSample to queue a cumulative counter sample for submission, with counter
+// deltas enabled the change since the previous collection is queued. Recorders always
+// receive the cumulative value.
+func (c *Check) QueueCounterSample(
+	metrics map[string]MetricSample,
+	metricName,
+	metricType string,
+	streamTags,
+	measurementTags []string,
+	value interface{},
+	timestamp *time.Time) error {
+
+	return c.queueSample(metrics, metricName, metricType, streamTags, measurementTags, value, true, timestamp)
+}
+
+func (c *Check) queueSample(
+	metrics map[string]MetricSample,
+	metricName,
+	metricType string,
+	streamTags,
+	measurementTags []string,
+	value interface{},
+	counter bool,
+	timestamp *time.Time) error {
+
 	if metrics == nil {
 		return errors.New("invalid metrics queue (nil)")
 	}
@@ -134,6 +174,11 @@ func (c *Check) QueueMetricSample(
 
 	streamTagList := strings.Split(c.config.DefaultStreamtags, ",")
 	streamTagList = append(streamTagList, streamTags...)
+	streamTagList = c.transformTags(streamTagList)
+
+	if !c.allowNamespace(streamTagList) {
+		return nil
+	}
 
 	if len(streamTagList)+len(measurementTags) > MaxTags {
 		c.log.Warn().
@@ -146,7 +191,7 @@ func (c *Check) QueueMetricSample(
 		return nil
 	}
 
-	taggedMetricName := c.taggedName(metricName, streamTagList, measurementTags)
+	taggedMetricName := c.taggedName(c.config.MetricPrefix+metricName, streamTagList, measurementTags)
 
 	if len(taggedMetricName) > MaxMetricNameLen {
 		c.log.Warn().
@@ -166,6 +211,11 @@ func (c *Check) QueueMetricSample(
 		val = value.(string) //fmt.Sprintf("%s", value.(string))
 	}
 
+	queue := true
+	if counter {
+		val, queue = c.counterDelta(metricName, metricType, streamTagList, value)
+	}
+
 	if _, found := metrics[taggedMetricName]; found {
 		c.log.Warn().
 			Str("metric_name", metricName).
@@ -180,15 +230,40 @@ func (c *Check) QueueMetricSample(
 		Value: val,
 	}
 
+	if len(c.exporters) > 0 {
+		metricSample.name = metricName
+		metricSample.tags = streamTagList
+	}
+
 	if timestamp != nil && (metricType != MetricTypeHistogram && metricType != MetricTypeCumulativeHistogram) {
 		metricSample.Timestamp = makeTimestamp(timestamp)
 	}
 
-	metrics[taggedMetricName] = metricSample
+	if queue && c.allowMetric(metricName, streamTagList) &&
+		(c.cardinality == nil || c.cardinality.admit(metricName, taggedMetricName)) &&
+		c.textChanged(metricType, taggedMetricName, val) {
+		if len(c.shards) > 0 {
+			metricSample.shard = c.route(metricName, streamTagList)
+		}
+		metrics[taggedMetricName] = metricSample
+	}
+
+	if recorders := c.sampleRecorders(); len(recorders) > 0 && metricType != MetricTypeString && metricType != MetricTypeHistogram && metricType != MetricTypeCumulativeHistogram {
+		for _, r := range recorders {
+			r.Record(metricName, streamTagList, value)
+		}
+	}
 
 	return nil
 }
 
+// AddRecorder adds a recorder receiving queued metric samples
+func (c *Check) AddRecorder(r Recorder) {
+	c.pipelinemu.Lock()
+	c.recorders = append(c.recorders, r)
+	c.pipelinemu.Unlock()
+}
+
 // makeTimestamp returns timestamp in ms units for _ts metric value
 func makeTimestamp(ts *time.Time) uint64 {
 	return uint64(ts.UTC().UnixNano() / (int64(time.Millisecond) / int64(time.Nanosecond)))
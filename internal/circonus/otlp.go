@@ -0,0 +1,267 @@
+// Copyright © 2019 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+package circonus
+
+import (
+	"context"
+	"crypto/tls"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/config"
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/release"
+	"github.com/golang/protobuf/proto"
+	"github.com/pkg/errors"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+)
+
+// The OpenTelemetry protocol (go.opentelemetry.io/proto/otlp) modules require newer
+// protobuf and grpc modules than the agent uses, the subset of the metrics v1 messages
+// used is defined here. Field numbers must match the opentelemetry-proto definitions.
+//
+// NOTES:
+// Samples are exported as gauges, the agent does not know whether a metric is a
+// monotonic counter. Stream tags are exported as attributes (category=key).
+
+const otlpExportMethod = "/opentelemetry.proto.collector.metrics.v1.MetricsService/Export"
+
+type otlpExportRequest struct {
+	ResourceMetrics []*otlpResourceMetrics `protobuf:"bytes,1,rep,name=resource_metrics,json=resourceMetrics,proto3"`
+}
+
+func (m *otlpExportRequest) Reset()         { *m = otlpExportRequest{} }
+func (m *otlpExportRequest) String() string { return proto.CompactTextString(m) }
+func (*otlpExportRequest) ProtoMessage()    {}
+
+type otlpExportResponse struct {
+	PartialSuccess *otlpPartialSuccess `protobuf:"bytes,1,opt,name=partial_success,json=partialSuccess,proto3"`
+}
+
+func (m *otlpExportResponse) Reset()         { *m = otlpExportResponse{} }
+func (m *otlpExportResponse) String() string { return proto.CompactTextString(m) }
+func (*otlpExportResponse) ProtoMessage()    {}
+
+type otlpPartialSuccess struct {
+	RejectedDataPoints int64  `protobuf:"varint,1,opt,name=rejected_data_points,json=rejectedDataPoints,proto3"`
+	ErrorMessage       string `protobuf:"bytes,2,opt,name=error_message,json=errorMessage,proto3"`
+}
+
+func (m *otlpPartialSuccess) Reset()         { *m = otlpPartialSuccess{} }
+func (m *otlpPartialSuccess) String() string { return proto.CompactTextString(m) }
+func (*otlpPartialSuccess) ProtoMessage()    {}
+
+type otlpResourceMetrics struct {
+	Resource     *otlpResource       `protobuf:"bytes,1,opt,name=resource,proto3"`
+	ScopeMetrics []*otlpScopeMetrics `protobuf:"bytes,2,rep,name=scope_metrics,json=scopeMetrics,proto3"`
+}
+
+func (m *otlpResourceMetrics) Reset()         { *m = otlpResourceMetrics{} }
+func (m *otlpResourceMetrics) String() string { return proto.CompactTextString(m) }
+func (*otlpResourceMetrics) ProtoMessage()    {}
+
+type otlpResource struct {
+	Attributes []*otlpKeyValue `protobuf:"bytes,1,rep,name=attributes,proto3"`
+}
+
+func (m *otlpResource) Reset()         { *m = otlpResource{} }
+func (m *otlpResource) String() string { return proto.CompactTextString(m) }
+func (*otlpResource) ProtoMessage()    {}
+
+type otlpScopeMetrics struct {
+	Scope   *otlpScope    `protobuf:"bytes,1,opt,name=scope,proto3"`
+	Metrics []*otlpMetric `protobuf:"bytes,2,rep,name=metrics,proto3"`
+}
+
+func (m *otlpScopeMetrics) Reset()         { *m = otlpScopeMetrics{} }
+func (m *otlpScopeMetrics) String() string { return proto.CompactTextString(m) }
+func (*otlpScopeMetrics) ProtoMessage()    {}
+
+type otlpScope struct {
+	Name    string `protobuf:"bytes,1,opt,name=name,proto3"`
+	Version string `protobuf:"bytes,2,opt,name=version,proto3"`
+}
+
+func (m *otlpScope) Reset()         { *m = otlpScope{} }
+func (m *otlpScope) String() string { return proto.CompactTextString(m) }
+func (*otlpScope) ProtoMessage()    {}
+
+type otlpMetric struct {
+	Name  string     `protobuf:"bytes,1,opt,name=name,proto3"`
+	Gauge *otlpGauge `protobuf:"bytes,5,opt,name=gauge,proto3"`
+}
+
+func (m *otlpMetric) Reset()         { *m = otlpMetric{} }
+func (m *otlpMetric) String() string { return proto.CompactTextString(m) }
+func (*otlpMetric) ProtoMessage()    {}
+
+type otlpGauge struct {
+	DataPoints []*otlpNumberDataPoint `protobuf:"bytes,1,rep,name=data_points,json=dataPoints,proto3"`
+}
+
+func (m *otlpGauge) Reset()         { *m = otlpGauge{} }
+func (m *otlpGauge) String() string { return proto.CompactTextString(m) }
+func (*otlpGauge) ProtoMessage()    {}
+
+type otlpNumberDataPoint struct {
+	TimeUnixNano uint64            `protobuf:"fixed64,3,opt,name=time_unix_nano,json=timeUnixNano,proto3"`
+	Value        isOTLPNumberValue `protobuf_oneof:"value"`
+	Attributes   []*otlpKeyValue   `protobuf:"bytes,7,rep,name=attributes,proto3"`
+}
+
+func (m *otlpNumberDataPoint) Reset()         { *m = otlpNumberDataPoint{} }
+func (m *otlpNumberDataPoint) String() string { return proto.CompactTextString(m) }
+func (*otlpNumberDataPoint) ProtoMessage()    {}
+
+// XXX_OneofWrappers is for the internal use of the proto package
+func (*otlpNumberDataPoint) XXX_OneofWrappers() []interface{} {
+	return []interface{}{(*otlpAsDouble)(nil)}
+}
+
+type isOTLPNumberValue interface {
+	isOTLPNumberValue()
+}
+
+// otlpAsDouble is a oneof so a zero value is still encoded
+type otlpAsDouble struct {
+	AsDouble float64 `protobuf:"fixed64,4,opt,name=as_double,json=asDouble,proto3,oneof"`
+}
+
+func (*otlpAsDouble) isOTLPNumberValue() {}
+
+type otlpKeyValue struct {
+	Key   string        `protobuf:"bytes,1,opt,name=key,proto3"`
+	Value *otlpAnyValue `protobuf:"bytes,2,opt,name=value,proto3"`
+}
+
+func (m *otlpKeyValue) Reset()         { *m = otlpKeyValue{} }
+func (m *otlpKeyValue) String() string { return proto.CompactTextString(m) }
+func (*otlpKeyValue) ProtoMessage()    {}
+
+type otlpAnyValue struct {
+	StringValue string `protobuf:"bytes,1,opt,name=string_value,json=stringValue,proto3"`
+}
+
+func (m *otlpAnyValue) Reset()         { *m = otlpAnyValue{} }
+func (m *otlpAnyValue) String() string { return proto.CompactTextString(m) }
+func (*otlpAnyValue) ProtoMessage()    {}
+
+// otlpExporter exports metric samples to an OpenTelemetry collector over OTLP/gRPC
+type otlpExporter struct {
+	conn     *grpc.ClientConn
+	timeout  time.Duration
+	resource *otlpResource
+}
+
+// newOTLPExporter returns an exporter sending to the configured endpoint, the
+// connection is established (and re-established) as needed
+func newOTLPExporter(cfg config.OTLP) (*otlpExporter, error) {
+	if cfg.Endpoint == "" {
+		return nil, errors.New("invalid otlp endpoint (empty)")
+	}
+	timeout, err := time.ParseDuration(cfg.Timeout)
+	if err != nil {
+		return nil, errors.Wrap(err, "parsing otlp timeout")
+	}
+
+	opts := []grpc.DialOption{}
+	if cfg.Insecure {
+		opts = append(opts, grpc.WithInsecure())
+	} else {
+		opts = append(opts, grpc.WithTransportCredentials(credentials.NewTLS(&tls.Config{})))
+	}
+	conn, err := grpc.Dial(cfg.Endpoint, opts...)
+	if err != nil {
+		return nil, errors.Wrap(err, "otlp endpoint")
+	}
+
+	return &otlpExporter{
+		conn:    conn,
+		timeout: timeout,
+		resource: &otlpResource{Attributes: []*otlpKeyValue{
+			otlpAttribute("service.name", release.NAME),
+			otlpAttribute("service.version", release.VERSION),
+		}},
+	}, nil
+}
+
+func (e *otlpExporter) Name() string {
+	return "otlp"
+}
+
+// Export sends the samples to the collector
+func (e *otlpExporter) Export(ctx context.Context, samples []ExportSample) error {
+	req := otlpRequest(e.resource, samples, time.Now())
+	if len(req.ResourceMetrics[0].ScopeMetrics[0].Metrics) == 0 {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, e.timeout)
+	defer cancel()
+
+	resp := &otlpExportResponse{}
+	if err := e.conn.Invoke(ctx, otlpExportMethod, req, resp); err != nil {
+		return errors.Wrap(err, "otlp export")
+	}
+	if ps := resp.PartialSuccess; ps != nil && ps.RejectedDataPoints > 0 {
+		return errors.Errorf("otlp export, %d data points rejected (%s)", ps.RejectedDataPoints, ps.ErrorMessage)
+	}
+	return nil
+}
+
+// otlpRequest returns an export request with a gauge for each metric name, samples
+// without a timestamp use ts
+func otlpRequest(resource *otlpResource, samples []ExportSample, ts time.Time) *otlpExportRequest {
+	metrics := make(map[string]*otlpMetric)
+	for _, s := range samples {
+		v, ok := exportFloat(s.Value)
+		if !ok {
+			continue
+		}
+		m, found := metrics[s.Name]
+		if !found {
+			m = &otlpMetric{Name: s.Name, Gauge: &otlpGauge{}}
+			metrics[s.Name] = m
+		}
+		dp := &otlpNumberDataPoint{
+			TimeUnixNano: uint64(ts.UnixNano()),
+			Value:        &otlpAsDouble{AsDouble: v},
+		}
+		if s.Timestamp > 0 {
+			dp.TimeUnixNano = s.Timestamp * uint64(time.Millisecond)
+		}
+		for _, tag := range s.Tags {
+			if tag == "" {
+				continue
+			}
+			kv := strings.SplitN(tag, ":", 2)
+			if len(kv) == 1 {
+				kv = append(kv, "")
+			}
+			dp.Attributes = append(dp.Attributes, otlpAttribute(kv[0], kv[1]))
+		}
+		m.Gauge.DataPoints = append(m.Gauge.DataPoints, dp)
+	}
+
+	names := make([]string, 0, len(metrics))
+	for name := range metrics {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	sm := &otlpScopeMetrics{Scope: &otlpScope{Name: release.NAME, Version: release.VERSION}}
+	for _, name := range names {
+		sm.Metrics = append(sm.Metrics, metrics[name])
+	}
+
+	return &otlpExportRequest{
+		ResourceMetrics: []*otlpResourceMetrics{{Resource: resource, ScopeMetrics: []*otlpScopeMetrics{sm}}},
+	}
+}
+
+func otlpAttribute(key, value string) *otlpKeyValue {
+	return &otlpKeyValue{Key: key, Value: &otlpAnyValue{StringValue: value}}
+}
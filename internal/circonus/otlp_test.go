@@ -0,0 +1,67 @@
+// Copyright © 2019 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+package circonus
+
+import (
+	"testing"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+)
+
+func TestOTLPRequest(t *testing.T) {
+	ts := time.Unix(1580000000, 0)
+	samples := []ExportSample{
+		{Name: "pods", Tags: []string{"", "source:nodes", "namespace:default"}, Type: MetricTypeUint64, Value: uint64(3), Timestamp: 1580000001000},
+		{Name: "pods", Tags: []string{"source:nodes", "namespace:kube-system"}, Type: MetricTypeUint64, Value: uint64(0)},
+		{Name: "cpu", Tags: []string{"source:nodes", "spot"}, Type: MetricTypeFloat64, Value: 0.5},
+		{Name: "bad", Type: MetricTypeString, Value: "x"},
+	}
+
+	req := otlpRequest(&otlpResource{}, samples, ts)
+
+	data, err := proto.Marshal(req)
+	if err != nil {
+		t.Fatalf("unexpected error (%s)", err)
+	}
+	got := &otlpExportRequest{}
+	if err := proto.Unmarshal(data, got); err != nil {
+		t.Fatalf("unexpected error (%s)", err)
+	}
+
+	metrics := got.ResourceMetrics[0].ScopeMetrics[0].Metrics
+	if len(metrics) != 2 {
+		t.Fatalf("expected 2 metrics, got %d", len(metrics))
+	}
+	if metrics[0].Name != "cpu" || metrics[1].Name != "pods" {
+		t.Fatalf("expected cpu, pods got %s, %s", metrics[0].Name, metrics[1].Name)
+	}
+
+	cpu := metrics[0].Gauge.DataPoints[0]
+	if v := cpu.Value.(*otlpAsDouble).AsDouble; v != 0.5 {
+		t.Fatalf("expected 0.5, got %v", v)
+	}
+	if cpu.TimeUnixNano != uint64(ts.UnixNano()) {
+		t.Fatalf("expected default timestamp %d, got %d", ts.UnixNano(), cpu.TimeUnixNano)
+	}
+	if len(cpu.Attributes) != 2 || cpu.Attributes[1].Key != "spot" || cpu.Attributes[1].Value.StringValue != "" {
+		t.Fatalf("unexpected attributes %v", cpu.Attributes)
+	}
+
+	pods := metrics[1].Gauge.DataPoints
+	if len(pods) != 2 {
+		t.Fatalf("expected 2 data points, got %d", len(pods))
+	}
+	if pods[0].TimeUnixNano != 1580000001000*uint64(time.Millisecond) {
+		t.Fatalf("expected sample timestamp, got %d", pods[0].TimeUnixNano)
+	}
+	if len(pods[0].Attributes) != 2 || pods[0].Attributes[1].Key != "namespace" || pods[0].Attributes[1].Value.StringValue != "default" {
+		t.Fatalf("unexpected attributes %v", pods[0].Attributes)
+	}
+	if v, ok := pods[1].Value.(*otlpAsDouble); !ok || v.AsDouble != 0 {
+		t.Fatalf("expected zero value to be encoded, got %v", pods[1].Value)
+	}
+}
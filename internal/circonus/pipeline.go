@@ -0,0 +1,77 @@
+// Copyright © 2019 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+package circonus
+
+import (
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/config"
+)
+
+// NOTES:
+// The agent metric filters, namespace filter, tag transforms, and recorders (recording rules, slos) are
+// replaced when the cluster configuration is reloaded. Watchers (e.g. events) queue
+// samples between collections, so they are read and replaced under pipelinemu.
+
+// metricFilters returns the current agent metric filters
+func (c *Check) metricFilters() []metricFilter {
+	c.pipelinemu.RLock()
+	defer c.pipelinemu.RUnlock()
+	return c.filters
+}
+
+// namespaceFilter returns the current namespace filter
+func (c *Check) namespaceFilter() config.NamespaceFilter {
+	c.pipelinemu.RLock()
+	defer c.pipelinemu.RUnlock()
+	return c.namespaces
+}
+
+// tagTransforms returns the current tag transforms
+func (c *Check) tagTransforms() []tagTransform {
+	c.pipelinemu.RLock()
+	defer c.pipelinemu.RUnlock()
+	return c.transforms
+}
+
+// sampleRecorders returns the current recorders
+func (c *Check) sampleRecorders() []Recorder {
+	c.pipelinemu.RLock()
+	defer c.pipelinemu.RUnlock()
+	return c.recorders
+}
+
+// SetRecorders replaces the recorders receiving queued metric samples
+func (c *Check) SetRecorders(recorders []Recorder) {
+	c.pipelinemu.Lock()
+	c.recorders = recorders
+	c.pipelinemu.Unlock()
+}
+
+// ClearAgentMetricFilters removes the agent metric filters, all metrics are allowed
+func (c *Check) ClearAgentMetricFilters() {
+	c.pipelinemu.Lock()
+	c.filters = nil
+	c.pipelinemu.Unlock()
+}
+
+// ClearTagTransforms removes the tag transforms
+func (c *Check) ClearTagTransforms() {
+	c.pipelinemu.Lock()
+	c.transforms = nil
+	c.pipelinemu.Unlock()
+}
+
+// SetNamespaceFilter replaces the namespace include/exclude lists metrics are queued with
+func (c *Check) SetNamespaceFilter(f config.NamespaceFilter) {
+	c.pipelinemu.Lock()
+	c.namespaces = f
+	c.pipelinemu.Unlock()
+}
+
+// AllowNamespace returns true if metrics of the namespace are reported (e.g. for
+// watchers which do not tag by namespace)
+func (c *Check) AllowNamespace(namespace string) bool {
+	return c.namespaceFilter().Allowed(namespace)
+}
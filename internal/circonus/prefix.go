@@ -0,0 +1,46 @@
+// Copyright © 2019 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+package circonus
+
+import (
+	"regexp"
+	"strings"
+)
+
+// NOTES:
+// With a metric prefix (--k8s-metric-prefix, e.g. "k8s`") every metric name submitted
+// for the cluster, including the agent's own collect_* metrics, is prefixed so that
+// multiple agents feeding shared dashboards do not collide with the hosts' own metrics.
+// Agent metric filters, shard routing, recording rules, and exporters (OTLP, remote
+// write) use the unprefixed names. Anchored check bundle metric filter and alert rule
+// patterns (^name) are prefixed so the configured filters and rules keep matching.
+
+// prefixPattern returns the pattern with the metric prefix inserted after a leading
+// anchor, unanchored patterns are returned as is
+func prefixPattern(prefix, pattern string) string {
+	if prefix == "" || !strings.HasPrefix(pattern, "^") {
+		return pattern
+	}
+	return "^" + regexp.QuoteMeta(prefix) + pattern[1:]
+}
+
+// prefixMetricFilters returns the check bundle metric filters with the metric prefix
+// applied to the filter patterns
+func prefixMetricFilters(prefix string, filters [][]string) [][]string {
+	if prefix == "" {
+		return filters
+	}
+	prefixed := make([][]string, len(filters))
+	for i, f := range filters {
+		pf := make([]string, len(f))
+		copy(pf, f)
+		if len(pf) > 1 {
+			pf[1] = prefixPattern(prefix, pf[1])
+		}
+		prefixed[i] = pf
+	}
+	return prefixed
+}
@@ -0,0 +1,38 @@
+// Copyright © 2019 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+package circonus
+
+import (
+	"testing"
+)
+
+func TestPrefixPattern(t *testing.T) {
+	tests := []struct {
+		prefix  string
+		pattern string
+		want    string
+	}{
+		{"", "^used$", "^used$"},
+		{"k8s`", "^used$", "^k8s`used$"},
+		{"k8s.", "^(used|capacity)$", "^k8s\\.(used|capacity)$"},
+		{"k8s`", "used", "used"},
+	}
+
+	for _, tt := range tests {
+		if got := prefixPattern(tt.prefix, tt.pattern); got != tt.want {
+			t.Fatalf("%q %q: expected %q, got %q", tt.prefix, tt.pattern, tt.want, got)
+		}
+	}
+
+	filters := [][]string{{"allow", "^collect_.*$", "agent"}, {"deny", "^.+$"}}
+	prefixed := prefixMetricFilters("k8s`", filters)
+	if !metricFiltersEqual(prefixed, [][]string{{"allow", "^k8s`collect_.*$", "agent"}, {"deny", "^k8s`.+$"}}) {
+		t.Fatalf("unexpected prefixed filters %v", prefixed)
+	}
+	if filters[0][1] != "^collect_.*$" {
+		t.Fatalf("expected configured filters to be unchanged, got %v", filters)
+	}
+}
@@ -0,0 +1,103 @@
+// Copyright © 2019 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+package circonus
+
+import (
+	"net/url"
+	"os"
+	"strings"
+
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/config"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog"
+)
+
+// NOTES:
+// The circonus api client, broker submissions, forwarding, and exporters all use the
+// proxy from the environment (HTTPS_PROXY, HTTP_PROXY, NO_PROXY). The api client does
+// not accept a transport, so an explicit proxy (--proxy-url, --no-proxy) is applied by
+// setting the environment before any requests are made (net/http reads it once).
+//
+// The environment is process wide, in-cluster requests (api-server, kubelet, pod
+// endpoints) use it as well. The api-server service host, localhost, .svc, and
+// .cluster.local are always added to NO_PROXY, pod and service CIDRs of clusters
+// whose endpoints are scraped directly should be added with --no-proxy.
+
+// inClusterNoProxy are never proxied when an explicit proxy is configured
+var inClusterNoProxy = []string{"localhost", "127.0.0.1", ".svc", ".cluster.local"}
+
+// ConfigureProxy sets the proxy environment from the proxy configuration, it must be
+// called before any http requests are made
+func ConfigureProxy(cfg config.Proxy, parentLogger zerolog.Logger) error {
+	logger := parentLogger.With().Str("pkg", "proxy").Logger()
+
+	if cfg.URL == "" {
+		for _, name := range []string{"HTTPS_PROXY", "https_proxy", "HTTP_PROXY", "http_proxy"} {
+			if v := os.Getenv(name); v != "" {
+				logger.Info().Str(name, v).Str("no_proxy", noProxyEnv()).Msg("using proxy from environment")
+				break
+			}
+		}
+		return nil
+	}
+
+	u, err := url.Parse(cfg.URL)
+	if err != nil {
+		return errors.Wrap(err, "parsing proxy url")
+	}
+	switch u.Scheme {
+	case "http", "https", "socks5":
+	default:
+		return errors.Errorf("invalid proxy url scheme (%s), http, https, or socks5", u.Scheme)
+	}
+	if u.Host == "" {
+		return errors.Errorf("invalid proxy url (%s), no host", cfg.URL)
+	}
+
+	for name, value := range proxyEnvironment(cfg, noProxyEnv(), os.Getenv("KUBERNETES_SERVICE_HOST")) {
+		if err := os.Setenv(name, value); err != nil {
+			return errors.Wrapf(err, "setting %s", name)
+		}
+	}
+
+	logger.Info().Str("proxy", u.Scheme+"://"+u.Host).Str("no_proxy", os.Getenv("NO_PROXY")).Msg("using proxy")
+	return nil
+}
+
+// proxyEnvironment returns the proxy environment variables for an explicit proxy, the
+// existing no proxy list, configured no proxy list, and in-cluster hosts are merged
+func proxyEnvironment(cfg config.Proxy, existingNoProxy, kubeHost string) map[string]string {
+	seen := make(map[string]bool)
+	var noProxy []string
+	add := func(list ...string) {
+		for _, h := range list {
+			h = strings.TrimSpace(h)
+			if h == "" || seen[h] {
+				continue
+			}
+			seen[h] = true
+			noProxy = append(noProxy, h)
+		}
+	}
+	add(strings.Split(existingNoProxy, ",")...)
+	add(strings.Split(cfg.NoProxy, ",")...)
+	add(inClusterNoProxy...)
+	add(kubeHost)
+
+	return map[string]string{
+		"HTTPS_PROXY": cfg.URL,
+		"HTTP_PROXY":  cfg.URL,
+		"NO_PROXY":    strings.Join(noProxy, ","),
+	}
+}
+
+// noProxyEnv returns the no proxy list from the environment
+func noProxyEnv() string {
+	if v := os.Getenv("NO_PROXY"); v != "" {
+		return v
+	}
+	return os.Getenv("no_proxy")
+}
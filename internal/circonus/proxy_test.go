@@ -0,0 +1,38 @@
+// Copyright © 2019 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+package circonus
+
+import (
+	"testing"
+
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/config"
+)
+
+func TestProxyEnvironment(t *testing.T) {
+	tests := []struct {
+		name     string
+		cfg      config.Proxy
+		existing string
+		kubeHost string
+		want     string
+	}{
+		{"defaults", config.Proxy{URL: "http://proxy:3128"}, "", "", "localhost,127.0.0.1,.svc,.cluster.local"},
+		{"kube host", config.Proxy{URL: "http://proxy:3128"}, "", "10.96.0.1", "localhost,127.0.0.1,.svc,.cluster.local,10.96.0.1"},
+		{"merged", config.Proxy{URL: "http://proxy:3128", NoProxy: "10.0.0.0/8, .internal"}, "example.com,localhost", "", "example.com,localhost,10.0.0.0/8,.internal,127.0.0.1,.svc,.cluster.local"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := proxyEnvironment(tt.cfg, tt.existing, tt.kubeHost)
+			if env["HTTPS_PROXY"] != tt.cfg.URL || env["HTTP_PROXY"] != tt.cfg.URL {
+				t.Fatalf("expected proxy %s, got %v", tt.cfg.URL, env)
+			}
+			if env["NO_PROXY"] != tt.want {
+				t.Fatalf("expected no proxy %q, got %q", tt.want, env["NO_PROXY"])
+			}
+		})
+	}
+}
@@ -0,0 +1,207 @@
+// Copyright © 2019 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+package circonus
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/config"
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/release"
+	"github.com/golang/protobuf/proto"
+	"github.com/golang/snappy"
+	"github.com/hashicorp/go-retryablehttp"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog"
+)
+
+// The prometheus remote write protocol messages (prompb) are only published with the
+// prometheus server module, the subset used is defined here. Field numbers must match
+// prompb/types.proto and prompb/remote.proto.
+//
+// NOTES:
+// Stream tags are mapped back to labels (category:value to category="value"), the
+// reverse of how prometheus labels are collected. Tags without a value are dropped,
+// and invalid characters in metric and label names are replaced with underscores.
+
+type rwWriteRequest struct {
+	Timeseries []*rwTimeSeries `protobuf:"bytes,1,rep,name=timeseries,proto3"`
+}
+
+func (m *rwWriteRequest) Reset()         { *m = rwWriteRequest{} }
+func (m *rwWriteRequest) String() string { return proto.CompactTextString(m) }
+func (*rwWriteRequest) ProtoMessage()    {}
+
+type rwTimeSeries struct {
+	Labels  []*rwLabel  `protobuf:"bytes,1,rep,name=labels,proto3"`
+	Samples []*rwSample `protobuf:"bytes,2,rep,name=samples,proto3"`
+}
+
+func (m *rwTimeSeries) Reset()         { *m = rwTimeSeries{} }
+func (m *rwTimeSeries) String() string { return proto.CompactTextString(m) }
+func (*rwTimeSeries) ProtoMessage()    {}
+
+type rwLabel struct {
+	Name  string `protobuf:"bytes,1,opt,name=name,proto3"`
+	Value string `protobuf:"bytes,2,opt,name=value,proto3"`
+}
+
+func (m *rwLabel) Reset()         { *m = rwLabel{} }
+func (m *rwLabel) String() string { return proto.CompactTextString(m) }
+func (*rwLabel) ProtoMessage()    {}
+
+type rwSample struct {
+	Value     float64 `protobuf:"fixed64,1,opt,name=value,proto3"`
+	Timestamp int64   `protobuf:"varint,2,opt,name=timestamp,proto3"` // ms
+}
+
+func (m *rwSample) Reset()         { *m = rwSample{} }
+func (m *rwSample) String() string { return proto.CompactTextString(m) }
+func (*rwSample) ProtoMessage()    {}
+
+// remoteWriteExporter exports metric samples to a prometheus remote write endpoint
+type remoteWriteExporter struct {
+	url       string
+	tokenFile string
+	client    *retryablehttp.Client
+}
+
+// newRemoteWriteExporter returns an exporter sending to the configured url, failed
+// writes are retried with the submission retry policy
+func newRemoteWriteExporter(cfg config.RemoteWrite, rp retryPolicy, logger zerolog.Logger) (*remoteWriteExporter, error) {
+	if cfg.URL == "" {
+		return nil, errors.New("invalid remote write url (empty)")
+	}
+	timeout, err := time.ParseDuration(cfg.Timeout)
+	if err != nil {
+		return nil, errors.Wrap(err, "parsing remote write timeout")
+	}
+
+	client := retryablehttp.NewClient()
+	client.HTTPClient.Timeout = timeout
+	client.Logger = logshim{logh: logger.With().Str("pkg", "retryablehttp").Logger()}
+	rp.apply(client)
+
+	return &remoteWriteExporter{
+		url:       cfg.URL,
+		tokenFile: cfg.BearerTokenFile,
+		client:    client,
+	}, nil
+}
+
+func (e *remoteWriteExporter) Name() string {
+	return "remote_write"
+}
+
+// Export sends the samples to the remote write endpoint
+func (e *remoteWriteExporter) Export(ctx context.Context, samples []ExportSample) error {
+	wr := remoteWriteRequest(samples, time.Now())
+	if len(wr.Timeseries) == 0 {
+		return nil
+	}
+
+	data, err := proto.Marshal(wr)
+	if err != nil {
+		return errors.Wrap(err, "encoding remote write request")
+	}
+
+	req, err := retryablehttp.NewRequest("POST", e.url, snappy.Encode(nil, data))
+	if err != nil {
+		return errors.Wrap(err, "creating remote write request")
+	}
+	req = req.WithContext(ctx)
+	req.Header.Set("User-Agent", release.NAME+"/"+release.VERSION)
+	req.Header.Set("Content-Type", "application/x-protobuf")
+	req.Header.Set("Content-Encoding", "snappy")
+	req.Header.Set("X-Prometheus-Remote-Write-Version", "0.1.0")
+	if e.tokenFile != "" {
+		token, err := ioutil.ReadFile(e.tokenFile) // re-read, tokens may be rotated
+		if err != nil {
+			return errors.Wrap(err, "reading remote write bearer token")
+		}
+		req.Header.Set("Authorization", "Bearer "+strings.TrimSpace(string(token)))
+	}
+
+	resp, err := e.client.Do(req)
+	if err != nil {
+		return errors.Wrap(err, "remote write")
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode/100 != 2 {
+		body, _ := ioutil.ReadAll(resp.Body)
+		return errors.Errorf("remote write (%s %s)", resp.Status, string(bytes.TrimSpace(body)))
+	}
+	return nil
+}
+
+// remoteWriteRequest returns a write request with a series for each sample, samples
+// without a timestamp use ts
+func remoteWriteRequest(samples []ExportSample, ts time.Time) *rwWriteRequest {
+	wr := &rwWriteRequest{Timeseries: make([]*rwTimeSeries, 0, len(samples))}
+	for _, s := range samples {
+		v, ok := exportFloat(s.Value)
+		if !ok {
+			continue
+		}
+		sample := &rwSample{Value: v, Timestamp: ts.UnixNano() / int64(time.Millisecond)}
+		if s.Timestamp > 0 {
+			sample.Timestamp = int64(s.Timestamp)
+		}
+		wr.Timeseries = append(wr.Timeseries, &rwTimeSeries{
+			Labels:  promLabels(s.Name, s.Tags),
+			Samples: []*rwSample{sample},
+		})
+	}
+	return wr
+}
+
+// promLabels returns the sorted labels of a metric, the metric name is the __name__
+// label, the first value of a repeated tag category is used
+func promLabels(name string, tags []string) []*rwLabel {
+	labels := []*rwLabel{{Name: "__name__", Value: promName(name, true)}}
+	seen := map[string]bool{"__name__": true}
+	for _, tag := range tags {
+		kv := strings.SplitN(tag, ":", 2)
+		if len(kv) != 2 || kv[0] == "" || kv[1] == "" {
+			continue
+		}
+		ln := promName(kv[0], false)
+		if seen[ln] {
+			continue
+		}
+		seen[ln] = true
+		labels = append(labels, &rwLabel{Name: ln, Value: kv[1]})
+	}
+	sort.Slice(labels, func(i, j int) bool { return labels[i].Name < labels[j].Name })
+	return labels
+}
+
+// promName replaces characters not valid in prometheus metric (colons allowed)
+// or label names with underscores, names cannot start with a digit
+func promName(name string, metric bool) string {
+	var b strings.Builder
+	for i, r := range name {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r == '_':
+			b.WriteRune(r)
+		case r == ':' && metric:
+			b.WriteRune(r)
+		case r >= '0' && r <= '9':
+			if i == 0 {
+				b.WriteRune('_')
+			}
+			b.WriteRune(r)
+		default:
+			b.WriteRune('_')
+		}
+	}
+	return b.String()
+}
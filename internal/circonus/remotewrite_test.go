@@ -0,0 +1,89 @@
+// Copyright © 2019 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+package circonus
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/config"
+	"github.com/golang/protobuf/proto"
+	"github.com/golang/snappy"
+	"github.com/rs/zerolog"
+)
+
+func TestPromLabels(t *testing.T) {
+	labels := promLabels("kube-pods.count", []string{"", "source:nodes", "k8s.namespace:default", "source:other", "spot", "2xx:1"})
+	want := []rwLabel{
+		{Name: "_2xx", Value: "1"},
+		{Name: "__name__", Value: "kube_pods_count"},
+		{Name: "k8s_namespace", Value: "default"},
+		{Name: "source", Value: "nodes"},
+	}
+	if len(labels) != len(want) {
+		t.Fatalf("expected %d labels, got %v", len(want), labels)
+	}
+	for i, l := range labels {
+		if *l != want[i] {
+			t.Fatalf("label %d, expected %v, got %v", i, want[i], *l)
+		}
+	}
+}
+
+func TestRemoteWriteExport(t *testing.T) {
+	var got rwWriteRequest
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Content-Encoding") != "snappy" {
+			t.Errorf("expected snappy encoding, got %q", r.Header.Get("Content-Encoding"))
+		}
+		body, err := ioutil.ReadAll(r.Body)
+		if err != nil {
+			t.Errorf("reading body: %s", err)
+		}
+		data, err := snappy.Decode(nil, body)
+		if err != nil {
+			t.Errorf("decoding body: %s", err)
+		}
+		if err := proto.Unmarshal(data, &got); err != nil {
+			t.Errorf("unmarshaling body: %s", err)
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer ts.Close()
+
+	rp, err := newRetryPolicy(0, "10ms", "10ms", false)
+	if err != nil {
+		t.Fatalf("unexpected error (%s)", err)
+	}
+	e, err := newRemoteWriteExporter(config.RemoteWrite{URL: ts.URL, Timeout: "5s"}, rp, zerolog.Nop())
+	if err != nil {
+		t.Fatalf("unexpected error (%s)", err)
+	}
+
+	samples := []ExportSample{
+		{Name: "pods", Tags: []string{"source:nodes"}, Type: MetricTypeUint64, Value: uint64(3), Timestamp: 1580000001000},
+		{Name: "cpu", Type: MetricTypeFloat64, Value: 0.5},
+	}
+	if err := e.Export(context.Background(), samples); err != nil {
+		t.Fatalf("unexpected error (%s)", err)
+	}
+
+	if len(got.Timeseries) != 2 {
+		t.Fatalf("expected 2 series, got %d", len(got.Timeseries))
+	}
+	pods := got.Timeseries[0]
+	if len(pods.Labels) != 2 || pods.Labels[0].Value != "pods" || pods.Samples[0].Value != 3 || pods.Samples[0].Timestamp != 1580000001000 {
+		t.Fatalf("unexpected series %v", pods)
+	}
+	cpu := got.Timeseries[1]
+	if cpu.Samples[0].Value != 0.5 || time.Since(time.Unix(0, cpu.Samples[0].Timestamp*int64(time.Millisecond))) > time.Minute {
+		t.Fatalf("unexpected series %v", cpu)
+	}
+}
@@ -0,0 +1,97 @@
+// Copyright © 2019 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+package circonus
+
+import (
+	"context"
+	"math/rand"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/go-retryablehttp"
+	"github.com/pkg/errors"
+)
+
+// retry failure classes, used as the reason tag of collect_submit_retries
+const (
+	retryTimeout           = "timeout"
+	retryConnectionRefused = "connection_refused"
+	retryConnection        = "connection"
+	retryServerError       = "5xx"
+	retryOther             = "other"
+)
+
+// retryPolicy is the submission retry policy
+type retryPolicy struct {
+	max        int
+	backoffMin time.Duration
+	backoffMax time.Duration
+	jitter     bool
+}
+
+// newRetryPolicy parses the submission retry settings
+func newRetryPolicy(maxRetries uint, backoffMin, backoffMax string, jitter bool) (retryPolicy, error) {
+	rp := retryPolicy{max: int(maxRetries), jitter: jitter}
+
+	var err error
+	if rp.backoffMin, err = time.ParseDuration(backoffMin); err != nil {
+		return rp, errors.Wrap(err, "parsing submit backoff min")
+	}
+	if rp.backoffMax, err = time.ParseDuration(backoffMax); err != nil {
+		return rp, errors.Wrap(err, "parsing submit backoff max")
+	}
+	if rp.backoffMin <= 0 {
+		return rp, errors.Errorf("invalid submit backoff min (%s), must be > 0", backoffMin)
+	}
+	if rp.backoffMax < rp.backoffMin {
+		return rp, errors.Errorf("invalid submit backoff max (%s), must be >= min (%s)", backoffMax, backoffMin)
+	}
+
+	return rp, nil
+}
+
+// apply sets the retry policy on a retryablehttp client
+func (rp retryPolicy) apply(client *retryablehttp.Client) {
+	client.RetryMax = rp.max
+	client.RetryWaitMin = rp.backoffMin
+	client.RetryWaitMax = rp.backoffMax
+	if rp.jitter {
+		client.Backoff = jitterBackoff
+	}
+}
+
+// jitterBackoff is the default exponential backoff with a random wait between
+// half and all of the computed wait, so agents do not retry in lockstep
+func jitterBackoff(min, max time.Duration, attemptNum int, resp *http.Response) time.Duration {
+	wait := retryablehttp.DefaultBackoff(min, max, attemptNum, resp)
+	half := int64(wait / 2)
+	if half <= 0 {
+		return wait
+	}
+	return time.Duration(half + rand.Int63n(half+1))
+}
+
+// retryClass returns the failure class of a submission attempt
+func retryClass(ctx context.Context, resp *http.Response, err error) string {
+	if err != nil {
+		if ctx.Err() == context.DeadlineExceeded {
+			return retryTimeout
+		}
+		if ne, ok := err.(net.Error); ok && ne.Timeout() {
+			return retryTimeout
+		}
+		if strings.Contains(err.Error(), "connection refused") {
+			return retryConnectionRefused
+		}
+		return retryConnection
+	}
+	if resp != nil && resp.StatusCode >= http.StatusInternalServerError {
+		return retryServerError
+	}
+	return retryOther
+}
@@ -0,0 +1,91 @@
+// Copyright © 2019 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+package circonus
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+)
+
+type timeoutError struct{}
+
+func (timeoutError) Error() string   { return "i/o timeout" }
+func (timeoutError) Timeout() bool   { return true }
+func (timeoutError) Temporary() bool { return true }
+
+func TestRetryClass(t *testing.T) {
+	refused := &url.Error{Op: "Put", URL: "https://broker", Err: &net.OpError{Op: "dial", Net: "tcp", Err: errors.New("connect: connection refused")}}
+	timeout := &url.Error{Op: "Put", URL: "https://broker", Err: timeoutError{}}
+	reset := &url.Error{Op: "Put", URL: "https://broker", Err: errors.New("connection reset by peer")}
+
+	tests := []struct {
+		name string
+		resp *http.Response
+		err  error
+		want string
+	}{
+		{"timeout", nil, timeout, retryTimeout},
+		{"refused", nil, refused, retryConnectionRefused},
+		{"reset", nil, reset, retryConnection},
+		{"503", &http.Response{StatusCode: http.StatusServiceUnavailable}, nil, retryServerError},
+		{"429", &http.Response{StatusCode: http.StatusTooManyRequests}, nil, retryOther},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := retryClass(context.Background(), tt.resp, tt.err); got != tt.want {
+				t.Fatalf("expected %s, got %s", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestNewRetryPolicy(t *testing.T) {
+	tests := []struct {
+		name    string
+		min     string
+		max     string
+		wantErr bool
+	}{
+		{"defaults", "50ms", "1s", false},
+		{"invalid min", "abc", "1s", true},
+		{"zero min", "0s", "1s", true},
+		{"max < min", "2s", "1s", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := newRetryPolicy(10, tt.min, tt.max, false)
+			if tt.wantErr && err == nil {
+				t.Fatal("expected error")
+			}
+			if !tt.wantErr && err != nil {
+				t.Fatalf("unexpected error (%s)", err)
+			}
+		})
+	}
+}
+
+func TestJitterBackoff(t *testing.T) {
+	min, max := 100*time.Millisecond, time.Second
+	for attempt := 0; attempt < 6; attempt++ {
+		want := min << uint(attempt)
+		if want > max {
+			want = max
+		}
+		for i := 0; i < 20; i++ {
+			got := jitterBackoff(min, max, attempt, nil)
+			if got < want/2 || got > want {
+				t.Fatalf("attempt %d, expected wait between %s and %s, got %s", attempt, want/2, want, got)
+			}
+		}
+	}
+}
@@ -0,0 +1,92 @@
+// Copyright © 2019 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+package circonus
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/config"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog"
+)
+
+// NOTES:
+// With --self-check the agent's own operational metrics (collect_*, e.g. duration,
+// heap, submission retries, collector api errors) are sent to a dedicated check rather
+// than the cluster check, so agent health alerting is independent of the (large) cluster
+// check. The check is found or created by target (the primary target with an _agent
+// suffix unless set) or bundle cid, using the primary check's api and broker settings.
+// All agent metrics are allowed by the check's metric filters. Agent metrics are not
+// mirrored to submission destinations. Only collect_* metrics go to the agent check,
+// cluster data a collector sends through cgm stays on the cluster check.
+
+// selfCheckMetricFilters allow all of the agent's metrics
+const selfCheckMetricFilters = `[["allow","^.+$","agent metrics"]]`
+
+// initializeSelfCheck creates the dedicated check for the agent's own metrics
+func (c *Check) initializeSelfCheck(parentLogger zerolog.Logger) error {
+	if !c.config.SelfCheck.Enable {
+		return nil
+	}
+
+	cfg := selfCheckConfig(*c.config)
+	check, err := NewCheck(parentLogger.With().Str("check", "agent").Logger(), &cfg)
+	if err != nil {
+		return errors.Wrap(err, "initializing agent metrics check")
+	}
+	check.metrics = c.metrics // submission metrics are sent with the rest of the agent's metrics
+
+	c.selfCheck = check
+	c.log.Info().Str("target", cfg.Check.Target).Msg("agent metrics check")
+	return nil
+}
+
+// selfCheckConfig returns the circonus configuration of the agent metrics check,
+// check settings not set are derived from the primary check
+func selfCheckConfig(primary config.Circonus) config.Circonus {
+	cfg := primary
+	cfg.Shards = nil
+	cfg.Destinations = nil
+	cfg.SelfCheck = config.SelfCheck{}
+	cfg.OTLP = config.OTLP{} // exported by the primary check
+	cfg.RemoteWrite = config.RemoteWrite{}
+	cfg.Alerts.Create = false // provisioned for the primary check only
+	cfg.CreateDashboards = false
+
+	cfg.Check.BundleCID = primary.SelfCheck.BundleCID
+	cfg.Check.Target = primary.SelfCheck.Target
+	if cfg.Check.Target == "" {
+		cfg.Check.Target = primary.Check.Target + "_agent"
+	}
+	cfg.Check.Title = primary.SelfCheck.Title
+	if cfg.Check.Title == "" {
+		cfg.Check.Title = fmt.Sprintf("%s (agent)", primary.Check.Title)
+	}
+	cfg.Check.MetricFilters = selfCheckMetricFilters
+
+	if cfg.SpoolDir != "" {
+		cfg.SpoolDir = filepath.Join(cfg.SpoolDir, "agent")
+	}
+	if cfg.DeadLetterDir != "" {
+		cfg.DeadLetterDir = filepath.Join(cfg.DeadLetterDir, "agent")
+	}
+
+	return cfg
+}
+
+// agentMetricPrefix is the name prefix of the agent's own metrics
+const agentMetricPrefix = "collect_"
+
+// cgmCheck returns the check a cgm metric is sent to, the agent's own metrics go to the
+// self check (if enabled), anything else is cluster data and stays on the cluster check
+func (c *Check) cgmCheck(metricName string) *Check {
+	if c.selfCheck != nil && strings.HasPrefix(metricName, agentMetricPrefix) {
+		return c.selfCheck
+	}
+	return c
+}
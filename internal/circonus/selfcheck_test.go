@@ -0,0 +1,50 @@
+// Copyright © 2019 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+package circonus
+
+import (
+	"testing"
+
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/config"
+)
+
+func TestSelfCheckConfig(t *testing.T) {
+	primary := config.Circonus{
+		Check:    config.Check{BundleCID: "/check_bundle/1", Target: "prod", Title: "prod /cka", MetricFilters: `[["allow","^kube_.+$",""]]`},
+		SpoolDir: "/spool",
+	}
+	primary.SelfCheck.Enable = true
+
+	cfg := selfCheckConfig(primary)
+	if cfg.Check.BundleCID != "" || cfg.Check.Target != "prod_agent" || cfg.Check.Title != "prod /cka (agent)" {
+		t.Fatalf("unexpected check config %+v", cfg.Check)
+	}
+	if cfg.Check.MetricFilters != selfCheckMetricFilters {
+		t.Fatalf("expected all agent metrics to be allowed, got %s", cfg.Check.MetricFilters)
+	}
+	if cfg.SelfCheck.Enable || cfg.SpoolDir != "/spool/agent" {
+		t.Fatalf("unexpected self check %+v spool dir %q", cfg.SelfCheck, cfg.SpoolDir)
+	}
+
+	primary.SelfCheck.BundleCID = "/check_bundle/2"
+	primary.SelfCheck.Target = "cka-health"
+	cfg = selfCheckConfig(primary)
+	if cfg.Check.BundleCID != "/check_bundle/2" || cfg.Check.Target != "cka-health" {
+		t.Fatalf("unexpected check config %+v", cfg.Check)
+	}
+
+	c := &Check{}
+	if c.cgmCheck("collect_latency") != c {
+		t.Fatal("expected agent metrics to go to the check without a self check")
+	}
+	c.selfCheck = &Check{}
+	if c.cgmCheck("collect_latency|ST[op:collect_nodes]") != c.selfCheck {
+		t.Fatal("expected agent metrics to go to the self check")
+	}
+	if c.cgmCheck("pod_evictions|ST[reason:memory]") != c {
+		t.Fatal("expected cluster metrics to stay on the cluster check")
+	}
+}
@@ -0,0 +1,177 @@
+// Copyright © 2019 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+package circonus
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+
+	cgm "github.com/circonus-labs/circonus-gometrics/v3"
+)
+
+// NOTES:
+// The metrics sent with the check's internal (cgm) metrics, e.g. collect_duration,
+// collect_latency, collect_api_errors, and collect_submit_retries, are also kept
+// cumulatively, for all clusters, and served in the OpenMetrics text format so the
+// agent can be scraped by prometheus. Counters are exposed with a _total suffix,
+// histogram samples as summaries (_count and _sum), and text metrics are not exposed.
+// Tags are exposed as labels, the same as remote write.
+
+const (
+	selfCounter = "counter"
+	selfGauge   = "gauge"
+	selfSummary = "summary"
+)
+
+type selfMetric struct {
+	kind   string
+	labels string // encoded, {k="v",...}
+	value  float64
+	count  uint64
+}
+
+type selfRegistry struct {
+	metrics map[string]map[string]*selfMetric // name -> labels -> metric
+	kinds   map[string]string
+	sync.Mutex
+}
+
+var selfMetrics = &selfRegistry{
+	metrics: make(map[string]map[string]*selfMetric),
+	kinds:   make(map[string]string),
+}
+
+// MetricsHandler serves the agent's own metrics in the OpenMetrics text format
+func MetricsHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/openmetrics-text; version=1.0.0; charset=utf-8")
+		selfMetrics.write(w)
+	})
+}
+
+// get returns the metric, creating it if needed, nil if the name is already
+// used by a metric of a different kind
+func (r *selfRegistry) get(kind, name string, tags cgm.Tags) *selfMetric {
+	name = promName(name, true)
+	if kind == selfCounter {
+		name = strings.TrimSuffix(name, "_total")
+	}
+	if k, ok := r.kinds[name]; ok && k != kind {
+		return nil
+	}
+	r.kinds[name] = kind
+	labels := selfLabels(tags)
+	series, ok := r.metrics[name]
+	if !ok {
+		series = make(map[string]*selfMetric)
+		r.metrics[name] = series
+	}
+	m, ok := series[labels]
+	if !ok {
+		m = &selfMetric{kind: kind, labels: labels}
+		series[labels] = m
+	}
+	return m
+}
+
+func (r *selfRegistry) increment(name string, tags cgm.Tags) {
+	r.Lock()
+	defer r.Unlock()
+	if m := r.get(selfCounter, name, tags); m != nil {
+		m.value++
+	}
+}
+
+func (r *selfRegistry) setCounter(name string, tags cgm.Tags, value uint64) {
+	r.Lock()
+	defer r.Unlock()
+	if m := r.get(selfCounter, name, tags); m != nil {
+		m.value = float64(value)
+	}
+}
+
+func (r *selfRegistry) gauge(name string, tags cgm.Tags, value interface{}) {
+	v, ok := exportFloat(value)
+	if !ok {
+		return
+	}
+	r.Lock()
+	defer r.Unlock()
+	if m := r.get(selfGauge, name, tags); m != nil {
+		m.value = v
+	}
+}
+
+func (r *selfRegistry) observe(name string, tags cgm.Tags, value float64) {
+	r.Lock()
+	defer r.Unlock()
+	if m := r.get(selfSummary, name, tags); m != nil {
+		m.count++
+		m.value += value
+	}
+}
+
+// write writes the metrics, sorted by name and labels, in the OpenMetrics text format
+func (r *selfRegistry) write(w io.Writer) {
+	r.Lock()
+	defer r.Unlock()
+
+	names := make([]string, 0, len(r.metrics))
+	for name := range r.metrics {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		series := r.metrics[name]
+		labels := make([]string, 0, len(series))
+		for l := range series {
+			labels = append(labels, l)
+		}
+		sort.Strings(labels)
+
+		fmt.Fprintf(w, "# TYPE %s %s\n", name, r.kinds[name])
+		for _, l := range labels {
+			m := series[l]
+			switch m.kind {
+			case selfCounter:
+				fmt.Fprintf(w, "%s_total%s %v\n", name, l, m.value)
+			case selfGauge:
+				fmt.Fprintf(w, "%s%s %v\n", name, l, m.value)
+			case selfSummary:
+				fmt.Fprintf(w, "%s_count%s %d\n", name, l, m.count)
+				fmt.Fprintf(w, "%s_sum%s %v\n", name, l, m.value)
+			}
+		}
+	}
+	fmt.Fprint(w, "# EOF\n")
+}
+
+// selfLabels returns the encoded labels of tags
+func selfLabels(tags cgm.Tags) string {
+	st := make([]string, 0, len(tags))
+	for _, t := range tags {
+		st = append(st, t.Category+":"+t.Value)
+	}
+	labels := promLabels("", st)
+	parts := make([]string, 0, len(labels))
+	for _, l := range labels {
+		if l.Name == "__name__" {
+			continue
+		}
+		parts = append(parts, l.Name+`="`+labelEscaper.Replace(l.Value)+`"`)
+	}
+	if len(parts) == 0 {
+		return ""
+	}
+	return "{" + strings.Join(parts, ",") + "}"
+}
+
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
@@ -0,0 +1,50 @@
+// Copyright © 2019 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+package circonus
+
+import (
+	"bytes"
+	"testing"
+
+	cgm "github.com/circonus-labs/circonus-gometrics/v3"
+)
+
+func TestSelfRegistryWrite(t *testing.T) {
+	r := &selfRegistry{
+		metrics: make(map[string]map[string]*selfMetric),
+		kinds:   make(map[string]string),
+	}
+
+	src := cgm.Tag{Category: "source", Value: "agent"}
+	r.increment("collect_submit_retries", cgm.Tags{cgm.Tag{Category: "reason", Value: "5xx"}, src})
+	r.increment("collect_submit_retries", cgm.Tags{cgm.Tag{Category: "reason", Value: "5xx"}, src})
+	r.increment("collect_submit_retries", cgm.Tags{cgm.Tag{Category: "reason", Value: "timeout"}, src})
+	r.setCounter("collect_api_errors_total", cgm.Tags{src}, 3)
+	r.gauge("collect_duration", cgm.Tags{cgm.Tag{Category: "cluster", Value: `a"b`}}, 1.5)
+	r.gauge("collect_duration", cgm.Tags{cgm.Tag{Category: "cluster", Value: "c"}}, "text") // not numeric, ignored
+	r.observe("collect_latency", nil, 10)
+	r.observe("collect_latency", nil, 5)
+	r.increment("collect_latency", nil) // kind mismatch, ignored
+
+	want := `# TYPE collect_api_errors counter
+collect_api_errors_total{source="agent"} 3
+# TYPE collect_duration gauge
+collect_duration{cluster="a\"b"} 1.5
+# TYPE collect_latency summary
+collect_latency_count 2
+collect_latency_sum 15
+# TYPE collect_submit_retries counter
+collect_submit_retries_total{reason="5xx",source="agent"} 2
+collect_submit_retries_total{reason="timeout",source="agent"} 1
+# EOF
+`
+
+	var buf bytes.Buffer
+	r.write(&buf)
+	if got := buf.String(); got != want {
+		t.Fatalf("expected\n%s\ngot\n%s", want, got)
+	}
+}
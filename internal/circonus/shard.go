@@ -0,0 +1,194 @@
+// Copyright © 2019 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+package circonus
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/config"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog"
+)
+
+// NOTES:
+// Shards are additional checks metrics are routed to, so a very large cluster does not
+// overload a single httptrap check. Each shard is a check of its own (found or created
+// by target, the primary target with a _<shard name> suffix unless set) sharing the
+// primary check's api settings. Each metric goes to the first shard with a matching
+// route, metrics without one go to the primary check, as do the agent's own metrics.
+
+type shard struct {
+	name           string
+	check          *Check
+	namespaces     map[string]bool
+	collectors     map[string]bool
+	metricPrefixes []string
+}
+
+// initializeShards creates the checks for the configured shards
+func (c *Check) initializeShards(parentLogger zerolog.Logger) error {
+	names := make(map[string]bool)
+	for i, sc := range c.config.Shards {
+		if sc.Name == "" {
+			return errors.Errorf("invalid shard %d, name is required", i)
+		}
+		if names[sc.Name] {
+			return errors.Errorf("invalid shard %s, duplicate name", sc.Name)
+		}
+		names[sc.Name] = true
+		if len(sc.Namespaces)+len(sc.MetricPrefixes)+len(sc.Collectors) == 0 {
+			return errors.Errorf("invalid shard %s, no routes (namespaces, metric_prefixes, or collectors)", sc.Name)
+		}
+
+		cfg := *c.config
+		cfg.Check = shardCheckConfig(c.config.Check, sc)
+		cfg.Shards = nil
+		cfg.Destinations = nil
+		cfg.OTLP = config.OTLP{} // exported by the primary check
+		cfg.RemoteWrite = config.RemoteWrite{}
+		if cfg.SpoolDir != "" {
+			cfg.SpoolDir = filepath.Join(cfg.SpoolDir, "shard_"+sc.Name)
+		}
+		if cfg.DeadLetterDir != "" {
+			cfg.DeadLetterDir = filepath.Join(cfg.DeadLetterDir, "shard_"+sc.Name)
+		}
+
+		check, err := NewCheck(parentLogger.With().Str("shard", sc.Name).Logger(), &cfg)
+		if err != nil {
+			return errors.Wrapf(err, "initializing shard %s", sc.Name)
+		}
+		check.metrics = c.metrics           // submission metrics are sent with the primary check's
+		check.dryrun = c.dryrun             // dry run output is shared with the primary check
+		check.destinations = c.destinations // shard payloads are mirrored to the destinations
+
+		c.shards = append(c.shards, newShard(sc, check))
+		c.log.Info().Str("shard", sc.Name).Str("target", cfg.Check.Target).Msg("check shard")
+	}
+	return nil
+}
+
+// newShard returns a shard routing metrics to the check
+func newShard(sc config.Shard, check *Check) *shard {
+	s := &shard{
+		name:           sc.Name,
+		check:          check,
+		namespaces:     make(map[string]bool),
+		collectors:     make(map[string]bool),
+		metricPrefixes: sc.MetricPrefixes,
+	}
+	for _, ns := range sc.Namespaces {
+		s.namespaces[ns] = true
+	}
+	for _, collector := range sc.Collectors {
+		s.collectors[collector] = true
+	}
+	return s
+}
+
+// shardCheckConfig returns the check configuration of a shard, settings not set
+// for the shard are taken from the primary check (except the bundle cid)
+func shardCheckConfig(primary config.Check, sc config.Shard) config.Check {
+	cfg := primary
+	cfg.BundleCID = sc.Check.BundleCID
+	cfg.Target = sc.Check.Target
+	if cfg.Target == "" {
+		cfg.Target = primary.Target + "_" + strings.Replace(sc.Name, " ", "_", -1)
+	}
+	cfg.Title = sc.Check.Title
+	if cfg.Title == "" {
+		cfg.Title = fmt.Sprintf("%s (%s)", primary.Title, sc.Name)
+	}
+	if sc.Check.BrokerCID != "" {
+		cfg.BrokerCID = sc.Check.BrokerCID
+		cfg.BrokerSelect = ""
+	}
+	if sc.Check.BrokerCAFile != "" {
+		cfg.BrokerCAFile = sc.Check.BrokerCAFile
+	}
+	if sc.Check.BrokerCert != "" {
+		cfg.BrokerCert = sc.Check.BrokerCert
+		cfg.BrokerKey = sc.Check.BrokerKey
+	}
+	if sc.Check.MetricFilters != "" {
+		cfg.MetricFilters = sc.Check.MetricFilters
+	}
+	if sc.Check.Tags != "" {
+		cfg.Tags = sc.Check.Tags
+	}
+	return cfg
+}
+
+// matches returns true if the metric matches any of the shard's routes
+func (s *shard) matches(metricName string, streamTags []string) bool {
+	for _, prefix := range s.metricPrefixes {
+		if strings.HasPrefix(metricName, prefix) {
+			return true
+		}
+	}
+	for _, tag := range streamTags {
+		kv := strings.SplitN(tag, ":", 2)
+		if len(kv) != 2 {
+			continue
+		}
+		switch kv[0] {
+		case "namespace":
+			if s.namespaces[kv[1]] {
+				return true
+			}
+		case "source":
+			if s.collectors[kv[1]] {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// route returns the destination of a metric, 0 for the primary check or
+// the shard index + 1
+func (c *Check) route(metricName string, streamTags []string) int {
+	for i, s := range c.shards {
+		if s.matches(metricName, streamTags) {
+			return i + 1
+		}
+	}
+	return 0
+}
+
+// submitShards partitions the metrics by destination and submits each set to its check
+func (c *Check) submitShards(ctx context.Context, metrics map[string]MetricSample, resultLogger zerolog.Logger) error {
+	queues := make([]map[string]MetricSample, len(c.shards)+1)
+	for name, ms := range metrics {
+		if queues[ms.shard] == nil {
+			queues[ms.shard] = make(map[string]MetricSample)
+		}
+		queues[ms.shard][name] = ms
+	}
+
+	var err error
+	for i, q := range queues {
+		if len(q) == 0 {
+			continue
+		}
+		if i == 0 {
+			if e := c.submitQueue(ctx, q, resultLogger); e != nil && err == nil {
+				err = e
+			}
+			continue
+		}
+		s := c.shards[i-1]
+		if e := s.check.submitQueue(ctx, q, resultLogger.With().Str("shard", s.name).Logger()); e != nil {
+			resultLogger.Error().Err(e).Str("shard", s.name).Msg("submitting metrics to shard")
+			if err == nil {
+				err = errors.Wrapf(e, "shard %s", s.name)
+			}
+		}
+	}
+	return err
+}
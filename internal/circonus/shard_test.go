@@ -0,0 +1,63 @@
+// Copyright © 2019 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+package circonus
+
+import (
+	"testing"
+
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/config"
+)
+
+func TestRoute(t *testing.T) {
+	c := &Check{
+		shards: []*shard{
+			newShard(config.Shard{Name: "ksm", Collectors: []string{"kube-state-metrics"}}, nil),
+			newShard(config.Shard{Name: "tenants", Namespaces: []string{"tenant-a", "tenant-b"}, MetricPrefixes: []string{"istio_"}}, nil),
+		},
+	}
+
+	tests := []struct {
+		name       string
+		metricName string
+		streamTags []string
+		want       int
+	}{
+		{"collector", "kube_pod_info", []string{"source:kube-state-metrics", "namespace:tenant-a"}, 1},
+		{"namespace", "container_cpu", []string{"source:nodes", "namespace:tenant-b"}, 2},
+		{"prefix", "istio_requests_total", []string{"source:istio"}, 2},
+		{"primary", "container_cpu", []string{"source:nodes", "namespace:default"}, 0},
+		{"no tags", "collect_latency", nil, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := c.route(tt.metricName, tt.streamTags); got != tt.want {
+				t.Errorf("route() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestShardCheckConfig(t *testing.T) {
+	primary := config.Check{
+		BrokerCID: "/broker/1",
+		BundleCID: "/check_bundle/1",
+		Target:    "prod",
+		Title:     "prod /circonus-kubernetes-agent",
+		Tags:      "env:prod",
+	}
+
+	got := shardCheckConfig(primary, config.Shard{Name: "tenant a", Check: config.Check{BrokerCID: "/broker/2"}})
+	want := config.Check{
+		BrokerCID: "/broker/2",
+		Target:    "prod_tenant_a",
+		Title:     "prod /circonus-kubernetes-agent (tenant a)",
+		Tags:      "env:prod",
+	}
+	if got != want {
+		t.Errorf("shardCheckConfig() = %+v, want %+v", got, want)
+	}
+}
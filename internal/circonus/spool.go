@@ -0,0 +1,223 @@
+// Copyright © 2019 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+package circonus
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	cgm "github.com/circonus-labs/circonus-gometrics/v3"
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/release"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog"
+)
+
+// NOTES:
+// When a submission fails (after retries, e.g. the broker is unreachable) the metric
+// payload is written to the spool directory, one file per submission. After the next
+// successful submission the spooled payloads are replayed, oldest first, until one
+// fails. Payloads keep their original metric timestamps (_ts), histograms do not carry
+// timestamps and are recorded at the time of the replay. The spool is bounded, when it
+// exceeds the max size the oldest payloads are discarded. Discarded payloads, and payloads
+// which cannot be spooled (larger than the max size, write errors), are written to the
+// dead-letter directory if enabled, otherwise they are dropped, counted (collect_spool_dropped),
+// and logged. Use a volume (e.g. emptyDir) for the spool directory.
+
+const spoolExt = ".json"
+
+type spool struct {
+	dir       string
+	maxSize   uint64
+	replaying bool
+	sync.Mutex
+}
+
+// newSpool returns a spool using dir, the directory is created if needed
+func newSpool(dir string, maxSize uint64) (*spool, error) {
+	if dir == "" {
+		return nil, errors.New("invalid spool dir (empty)")
+	}
+	if maxSize == 0 {
+		return nil, errors.New("invalid spool max size (0)")
+	}
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, errors.Wrap(err, "creating spool dir")
+	}
+	return &spool{dir: dir, maxSize: maxSize}, nil
+}
+
+// add writes a payload to the spool and discards the oldest payloads if the spool
+// exceeds the max size, the discarded payloads are returned (nil if a payload could
+// not be read)
+func (s *spool) add(data []byte) ([][]byte, error) {
+	if uint64(len(data)) > s.maxSize {
+		return nil, errors.Errorf("payload size (%d) exceeds spool max size (%d)", len(data), s.maxSize)
+	}
+
+	s.Lock()
+	defer s.Unlock()
+
+	name := fmt.Sprintf("%020d", time.Now().UnixNano())
+	tmp := filepath.Join(s.dir, "."+name)
+	if err := ioutil.WriteFile(tmp, data, 0600); err != nil {
+		_ = os.Remove(tmp)
+		return nil, errors.Wrap(err, "writing spool file")
+	}
+	if err := os.Rename(tmp, filepath.Join(s.dir, name+spoolExt)); err != nil {
+		_ = os.Remove(tmp)
+		return nil, errors.Wrap(err, "renaming spool file")
+	}
+
+	return s.enforce()
+}
+
+// enforce removes the oldest payloads until the spool is within the max size, the
+// removed payloads are returned
+func (s *spool) enforce() ([][]byte, error) {
+	files, err := s.files()
+	if err != nil {
+		return nil, err
+	}
+	var size uint64
+	for _, f := range files {
+		size += uint64(f.Size())
+	}
+	var discarded [][]byte
+	for i := 0; size > s.maxSize && i < len(files); i++ {
+		fn := filepath.Join(s.dir, files[i].Name())
+		data, err := ioutil.ReadFile(fn)
+		if err != nil {
+			if os.IsNotExist(err) { // replayed
+				size -= uint64(files[i].Size())
+				continue
+			}
+			data = nil
+		}
+		if err := os.Remove(fn); err != nil && !os.IsNotExist(err) {
+			return discarded, errors.Wrap(err, "removing spool file")
+		}
+		discarded = append(discarded, data)
+		size -= uint64(files[i].Size())
+	}
+	return discarded, nil
+}
+
+// files returns the spooled payloads, oldest first
+func (s *spool) files() ([]os.FileInfo, error) {
+	entries, err := ioutil.ReadDir(s.dir)
+	if err != nil {
+		return nil, errors.Wrap(err, "reading spool dir")
+	}
+	files := make([]os.FileInfo, 0, len(entries))
+	for _, e := range entries {
+		if e.IsDir() || !strings.HasSuffix(e.Name(), spoolExt) || strings.HasPrefix(e.Name(), ".") {
+			continue
+		}
+		files = append(files, e)
+	}
+	sort.Slice(files, func(i, j int) bool { return files[i].Name() < files[j].Name() })
+	return files, nil
+}
+
+// startReplay returns false if a replay is already in progress
+func (s *spool) startReplay() bool {
+	s.Lock()
+	defer s.Unlock()
+	if s.replaying {
+		return false
+	}
+	s.replaying = true
+	return true
+}
+
+func (s *spool) endReplay() {
+	s.Lock()
+	s.replaying = false
+	s.Unlock()
+}
+
+// spoolPayload writes a failed submission to the spool, if enabled, otherwise to
+// the dead-letter directory, if enabled
+func (c *Check) spoolPayload(data []byte, resultLogger zerolog.Logger) {
+	if c.spool == nil {
+		c.deadLetterPayload(data, resultLogger)
+		return
+	}
+	discarded, err := c.spool.add(data)
+	for _, d := range discarded {
+		c.spoolDropped(d, resultLogger)
+	}
+	if err != nil {
+		resultLogger.Error().Err(err).Msg("spooling metrics")
+		c.spoolDropped(data, resultLogger)
+		return
+	}
+	c.IncrementCounter("collect_submit_spooled", cgm.Tags{cgm.Tag{Category: "source", Value: release.NAME}})
+	resultLogger.Warn().Str("dir", c.spool.dir).Msg("submission failed, metrics spooled")
+}
+
+// spoolDropped writes a payload the spool could not keep to the dead-letter directory,
+// if enabled, otherwise the payload is dropped
+func (c *Check) spoolDropped(data []byte, resultLogger zerolog.Logger) {
+	if c.deadLetter != nil && data != nil {
+		c.deadLetterPayload(data, resultLogger)
+		return
+	}
+	c.IncrementCounter("collect_spool_dropped", cgm.Tags{cgm.Tag{Category: "source", Value: release.NAME}})
+	resultLogger.Warn().Str("dir", c.spool.dir).Msg("spool full or not writable, metrics dropped")
+}
+
+// replaySpool submits the spooled payloads, oldest first, stopping at the first failure
+func (c *Check) replaySpool(ctx context.Context) {
+	if c.spool == nil || !c.spool.startReplay() {
+		return
+	}
+	defer c.spool.endReplay()
+
+	files, err := c.spool.files()
+	if err != nil {
+		c.log.Error().Err(err).Msg("listing spooled metrics")
+		return
+	}
+	for _, f := range files {
+		if done(ctx) {
+			return
+		}
+		fn := filepath.Join(c.spool.dir, f.Name())
+		data, err := ioutil.ReadFile(fn)
+		if err != nil {
+			if !os.IsNotExist(err) { // discarded by enforce
+				c.log.Error().Err(err).Str("file", fn).Msg("reading spooled metrics")
+			}
+			continue
+		}
+		if err := c.submit(ctx, bytes.NewReader(data), c.log.With().Str("spool_file", f.Name()).Logger(), false); err != nil {
+			c.log.Warn().Err(err).Msg("replaying spooled metrics, will retry after next successful submission")
+			return
+		}
+		if err := os.Remove(fn); err != nil && !os.IsNotExist(err) {
+			c.log.Error().Err(err).Str("file", fn).Msg("removing replayed metrics")
+		}
+		c.IncrementCounter("collect_submit_replayed", cgm.Tags{cgm.Tag{Category: "source", Value: release.NAME}})
+	}
+}
+
+func done(ctx context.Context) bool {
+	select {
+	case <-ctx.Done():
+		return true
+	default:
+		return false
+	}
+}
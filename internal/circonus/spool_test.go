@@ -0,0 +1,121 @@
+// Copyright © 2019 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+package circonus
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/config"
+	"github.com/rs/zerolog"
+)
+
+func TestSpool(t *testing.T) {
+	dir, err := ioutil.TempDir("", "spool")
+	if err != nil {
+		t.Fatalf("temp dir: %s", err)
+	}
+	defer os.RemoveAll(dir)
+
+	if _, err := newSpool("", 10); err == nil {
+		t.Fatal("expected error (empty dir)")
+	}
+	if _, err := newSpool(dir, 0); err == nil {
+		t.Fatal("expected error (zero max size)")
+	}
+
+	s, err := newSpool(dir, 25)
+	if err != nil {
+		t.Fatalf("unexpected error (%s)", err)
+	}
+
+	payloads := [][]byte{
+		[]byte(`{"a":{"_value":1}}`),
+		[]byte(`{"b":{"_value":2}}`),
+	}
+	var discarded [][]byte
+	for _, p := range payloads {
+		d, err := s.add(p)
+		if err != nil {
+			t.Fatalf("unexpected error (%s)", err)
+		}
+		discarded = append(discarded, d...)
+	}
+	if len(discarded) != 1 || !bytes.Equal(discarded[0], payloads[0]) {
+		t.Fatalf("expected oldest payload discarded, got %q", discarded)
+	}
+
+	if _, err := s.add([]byte(`{"too_large":{"_value":1}}`)); err == nil {
+		t.Fatal("expected error (payload larger than max size)")
+	}
+
+	// each payload is 18 bytes, only the newest fits in 25
+	files, err := s.files()
+	if err != nil {
+		t.Fatalf("unexpected error (%s)", err)
+	}
+	if len(files) != 1 {
+		t.Fatalf("expected 1 spooled payload, got %d", len(files))
+	}
+	data, err := ioutil.ReadFile(filepath.Join(dir, files[0].Name()))
+	if err != nil {
+		t.Fatalf("unexpected error (%s)", err)
+	}
+	if !bytes.Equal(data, payloads[1]) {
+		t.Fatalf("expected newest payload %s, got %s", payloads[1], data)
+	}
+
+	if !s.startReplay() {
+		t.Fatal("expected replay to start")
+	}
+	if s.startReplay() {
+		t.Fatal("expected replay in progress")
+	}
+	s.endReplay()
+	if !s.startReplay() {
+		t.Fatal("expected replay to start after end")
+	}
+}
+
+func TestSpoolDropped(t *testing.T) {
+	dir, err := ioutil.TempDir("", "spool")
+	if err != nil {
+		t.Fatalf("temp dir: %s", err)
+	}
+	defer os.RemoveAll(dir)
+
+	s, err := newSpool(filepath.Join(dir, "spool"), 25)
+	if err != nil {
+		t.Fatalf("unexpected error (%s)", err)
+	}
+	dl, err := newDeadLetter(filepath.Join(dir, "dead"), false)
+	if err != nil {
+		t.Fatalf("unexpected error (%s)", err)
+	}
+	c := &Check{config: &config.Circonus{}, spool: s, deadLetter: dl}
+
+	c.spoolPayload([]byte(`{"a":{"_value":1}}`), zerolog.Nop())
+	c.spoolPayload([]byte(`{"b":{"_value":2}}`), zerolog.Nop())         // discards a
+	c.spoolPayload([]byte(`{"too_large":{"_value":3}}`), zerolog.Nop()) // not spooled
+
+	files, err := deadLetterFiles([]string{dl.dir})
+	if err != nil {
+		t.Fatalf("unexpected error (%s)", err)
+	}
+	if len(files) != 2 {
+		t.Fatalf("expected 2 dead-letter payloads, got %d", len(files))
+	}
+	data, err := readDeadLetter(files[0])
+	if err != nil {
+		t.Fatalf("unexpected error (%s)", err)
+	}
+	if string(data) != `{"a":{"_value":1}}` {
+		t.Fatalf("expected discarded payload, got %s", data)
+	}
+}
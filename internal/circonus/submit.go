@@ -7,7 +7,6 @@ package circonus
 
 import (
 	"bytes"
-	"compress/gzip"
 	"context"
 	"encoding/json"
 	"fmt"
@@ -18,6 +17,8 @@ import (
 	"os"
 	"path"
 	"strconv"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"code.cloudfoundry.org/bytefmt"
@@ -41,27 +42,123 @@ const (
 	traceTSFormat        = "20060102_150405.000000000"
 )
 
-func (c *Check) AddMetricSet(metrics []byte, logger zerolog.Logger) {
-	c.metricQueue <- MetricSet{Metrics: metrics, Logger: logger}
+// AddMetricSet queues an encoded payload for the submission workers, it blocks while
+// the queue is full. The payload is mirrored to each submission destination.
+func (c *Check) AddMetricSet(ctx context.Context, metrics []byte, logger zerolog.Logger) error {
+	for _, d := range c.destinations {
+		d.mirror(metrics, logger.With().Str("destination", d.name).Logger())
+	}
+
+	ms := MetricSet{Metrics: metrics, Logger: logger, Deadline: c.submitDeadline()}
+	var deadline <-chan time.Time // nil, blocks until queued if there is no budget
+	if !ms.Deadline.IsZero() {
+		timer := time.NewTimer(time.Until(ms.Deadline))
+		defer timer.Stop()
+		deadline = timer.C
+	}
+
+	select {
+	case c.metricQueue <- ms:
+		c.recordSubmitQueue()
+		return nil
+	case <-deadline:
+		c.budgetExceeded(ms, time.Now())
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
 }
+
+// Submitter starts the submission workers (and those of the shards, destinations, and
+// agent metrics check), it returns when the context is done and the workers have stopped
 func (c *Check) Submitter(ctx context.Context) {
+	for _, s := range c.shards {
+		go s.check.Submitter(ctx)
+	}
+	for _, d := range c.destinations {
+		d.startSubmitter(ctx)
+	}
+	if c.selfCheck != nil {
+		go c.selfCheck.Submitter(ctx)
+	}
+	var wg sync.WaitGroup
+	for i := 0; i < c.submitWorkers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			c.submitWorker(ctx)
+		}()
+	}
+	wg.Wait()
+}
+
+// submitWorker submits queued payloads until the context is done
+func (c *Check) submitWorker(ctx context.Context) {
 	for {
 		select {
 		case <-ctx.Done():
 			return
 		case ms := <-c.metricQueue:
-			if err := c.Submit(ctx, bytes.NewReader(ms.Metrics), ms.Logger); err != nil {
+			if c.budgetExceeded(ms, time.Now()) {
+				c.recordSubmitQueue()
+				continue
+			}
+			atomic.AddInt32(&c.inFlight, 1)
+			c.recordSubmitQueue()
+			if err := c.submitMetricSet(ctx, ms); err != nil {
 				ms.Logger.Error().Err(err).Msg("submitting metric set")
 			}
+			atomic.AddInt32(&c.inFlight, -1)
 		}
 	}
 }
 
+// submitMetricSet submits a queued payload, a submission still in progress at the
+// submission budget deadline is cancelled (and the payload spooled). A successful
+// submission starts a replay of spooled payloads, the replay has its own context so
+// it continues after the submission returns, within the same budget deadline.
+func (c *Check) submitMetricSet(ctx context.Context, ms MetricSet) error {
+	submitCtx := ctx
+	if !ms.Deadline.IsZero() {
+		var cancel context.CancelFunc
+		submitCtx, cancel = context.WithDeadline(ctx, ms.Deadline)
+		defer cancel()
+	}
+
+	if err := c.Submit(submitCtx, bytes.NewReader(ms.Metrics), ms.Logger); err != nil {
+		return err
+	}
+
+	if c.spool != nil {
+		go func() {
+			replayCtx := ctx
+			if !ms.Deadline.IsZero() {
+				var cancel context.CancelFunc
+				replayCtx, cancel = context.WithDeadline(ctx, ms.Deadline)
+				defer cancel()
+			}
+			c.replaySpool(replayCtx)
+		}()
+	}
+
+	return nil
+}
+
+// recordSubmitQueue records the submission queue depth and in-flight submissions
+func (c *Check) recordSubmitQueue() {
+	tags := cgm.Tags{cgm.Tag{Category: "source", Value: release.NAME}}
+	c.AddGauge("collect_submit_queue_depth", tags, len(c.metricQueue))
+	c.AddGauge("collect_submit_in_flight", tags, atomic.LoadInt32(&c.inFlight))
+}
+
 func (c *Check) FlushCGM(ctx context.Context, ts *time.Time) {
 	if c.metrics != nil {
 		// TODO: add timestamp support to CGM (e.g. FlushMetricsWithTimestamp(ts))
-		metrics := make(map[string]MetricSample)
+		sets := make(map[*Check]map[string]MetricSample)
 		for mn, mv := range *(c.metrics.FlushMetrics()) {
+			if !c.textChanged(mv.Type, mn, mv.Value) {
+				continue
+			}
 			ms := MetricSample{
 				Value: mv.Value,
 				Type:  mv.Type,
@@ -69,39 +166,61 @@ func (c *Check) FlushCGM(ctx context.Context, ts *time.Time) {
 			if ms.Type != MetricTypeHistogram {
 				ms.Timestamp = makeTimestamp(ts)
 			}
-			metrics[mn] = ms
+			check := c.cgmCheck(mn)
+			if sets[check] == nil {
+				sets[check] = make(map[string]MetricSample)
+			}
+			sets[check][c.config.MetricPrefix+mn] = ms
 		}
 
-		data, err := json.Marshal(metrics)
-		if err != nil {
-			c.log.Warn().Err(err).Msg("encoding metrics")
-			return
-		}
-		if c.ConcurrentSubmissions() {
-			if err := c.Submit(ctx, bytes.NewReader(data), c.log); err != nil {
-				c.log.Error().Err(err).Msg("submitting cgm metrics")
+		for check, metrics := range sets {
+			data, err := json.Marshal(metrics)
+			if err != nil {
+				c.log.Warn().Err(err).Msg("encoding metrics")
+				continue
+			}
+			if err := check.AddMetricSet(ctx, data, c.log); err != nil {
+				c.log.Error().Err(err).Msg("queueing cgm metrics")
 			}
-		} else {
-			c.AddMetricSet(data, c.log)
 		}
 	}
 }
 
 func (c *Check) ResetSubmitStats() {
 	c.statsmu.Lock()
-	defer c.statsmu.Unlock()
 	c.stats.Metrics = 0
 	c.stats.SentBytes = 0
+	c.statsmu.Unlock()
+	for _, s := range c.shards {
+		s.check.ResetSubmitStats()
+	}
+	if c.selfCheck != nil {
+		c.selfCheck.ResetSubmitStats()
+	}
 }
 
+// SubmitStats returns the submission stats, including all shards and the agent metrics check
 func (c *Check) SubmitStats() Stats {
 	c.statsmu.Lock()
-	defer c.statsmu.Unlock()
-	return Stats{
+	stats := Stats{
 		Metrics:   c.stats.Metrics,
 		SentBytes: c.stats.SentBytes,
-		SentSize:  bytefmt.ByteSize(c.stats.SentBytes),
 	}
+	c.statsmu.Unlock()
+	checks := make([]*Check, 0, len(c.shards)+1)
+	for _, s := range c.shards {
+		checks = append(checks, s.check)
+	}
+	if c.selfCheck != nil {
+		checks = append(checks, c.selfCheck)
+	}
+	for _, check := range checks {
+		ss := check.SubmitStats()
+		stats.Metrics += ss.Metrics
+		stats.SentBytes += ss.SentBytes
+	}
+	stats.SentSize = bytefmt.ByteSize(stats.SentBytes)
+	return stats
 }
 
 func (c *Check) SubmitQueue(ctx context.Context, metrics map[string]MetricSample, resultLogger zerolog.Logger) error {
@@ -109,31 +228,79 @@ func (c *Check) SubmitQueue(ctx context.Context, metrics map[string]MetricSample
 		return errors.New("invalid metrics (nil)")
 	}
 
+	if len(c.exporters) > 0 {
+		c.export(ctx, metrics, resultLogger)
+	}
+
+	if len(c.shards) > 0 {
+		return c.submitShards(ctx, metrics, resultLogger)
+	}
+
+	return c.submitQueue(ctx, metrics, resultLogger)
+}
+
+// submitQueue encodes the metrics and submits them to the check
+func (c *Check) submitQueue(ctx context.Context, metrics map[string]MetricSample, resultLogger zerolog.Logger) error {
 	data, err := json.MarshalIndent(metrics, "", "  ")
 	if err != nil {
 		return errors.Wrap(err, "marshaling metrics")
 	}
 
-	if c.ConcurrentSubmissions() {
-		return c.Submit(ctx, bytes.NewReader(data), resultLogger)
+	if c.maxBodySize == 0 || uint64(len(data)) <= c.maxBodySize {
+		return c.submitPayload(ctx, data, resultLogger)
 	}
 
-	c.AddMetricSet(data, resultLogger)
-	return nil
+	chunks, err := chunkMetrics(metrics, int(c.maxBodySize))
+	if err != nil {
+		return err
+	}
+	resultLogger.Debug().Int("size", len(data)).Int("chunks", len(chunks)).Msg("splitting submission")
+
+	var submitErr error
+	for i, chunk := range chunks {
+		if err := c.submitPayload(ctx, chunk, resultLogger.With().Int("chunk", i+1).Logger()); err != nil && submitErr == nil {
+			submitErr = err
+		}
+	}
+	return submitErr
+}
+
+// submitPayload queues an encoded payload for the submission workers
+func (c *Check) submitPayload(ctx context.Context, data []byte, resultLogger zerolog.Logger) error {
+	return c.AddMetricSet(ctx, data, resultLogger)
 }
 
 // Submit sends metrics to a circonus trap
 func (c *Check) Submit(ctx context.Context, metrics io.Reader, resultLogger zerolog.Logger) error {
+	return c.submit(ctx, metrics, resultLogger, true)
+}
+
+// incrementSubmitCounter increments a submission counter, the tags are used as is
+func (c *Check) incrementSubmitCounter(metricName string, tags cgm.Tags) {
+	selfMetrics.increment(metricName, tags)
+	c.metrics.IncrementWithTags(metricName, tags)
+}
+
+// submit sends metrics to a circonus trap, spooling them if the submission
+// fails and spoolOnError is set (false when replaying spooled metrics)
+func (c *Check) submit(ctx context.Context, metrics io.Reader, resultLogger zerolog.Logger, spoolOnError bool) error {
 	if metrics == nil {
 		return errors.New("invalid metrics (nil)")
 	}
 
 	start := time.Now()
 
+	if c.statsd != nil {
+		return c.forwardStatsd(metrics, resultLogger)
+	}
+
 	if c.submissionURL == "" {
 		if c.config.DryRun {
-			_, err := io.Copy(os.Stdout, metrics)
-			return err
+			data, err := ioutil.ReadAll(metrics)
+			if err != nil {
+				return errors.Wrap(err, "reading metric data")
+			}
+			return c.dryrun.write(data)
 		}
 		return errors.New("no submission url and not in dry-run mode")
 	}
@@ -184,35 +351,20 @@ func (c *Check) Submit(ctx context.Context, metrics io.Reader, resultLogger zero
 		return errors.Wrap(err, "reading metric data")
 	}
 
-	payloadIsCompressed := false
-
-	var subData *bytes.Buffer
-	if c.UseCompression() && len(rawData) > compressionThreshold {
-		subData = bytes.NewBuffer([]byte{})
-		zw := gzip.NewWriter(subData)
-		n, e1 := zw.Write(rawData)
-		if e1 != nil {
-			resultLogger.Error().Err(e1).Msg("compressing metrics")
-			return errors.Wrap(e1, "compressing metrics")
-		}
-		if n != len(rawData) {
-			resultLogger.Error().Int("data_len", len(rawData)).Int("written", n).Msg("gzip write length mismatch")
-			return errors.Errorf("write length mismatch data length %d != written length %d", len(rawData), n)
-		}
-		if e2 := zw.Close(); e2 != nil {
-			resultLogger.Error().Err(e2).Msg("closing gzip writer")
-			return errors.Wrap(e2, "closing gzip writer")
+	var encoding string // content encoding, empty if not compressed
+	payload := rawData
+	if c.compression != nil {
+		payload, encoding, err = c.compression.compress(rawData)
+		if err != nil {
+			resultLogger.Error().Err(err).Msg("compressing metrics")
+			return err
 		}
-		payloadIsCompressed = true
-	} else {
-		subData = bytes.NewBuffer(rawData)
 	}
+	subData := bytes.NewBuffer(payload)
 
 	if dumpDir := c.config.TraceSubmits; dumpDir != "" {
 		fn := path.Join(dumpDir, time.Now().UTC().Format(traceTSFormat)+"_"+submitUUID.String()+".json")
-		if payloadIsCompressed {
-			fn += ".gz"
-		}
+		fn += traceExt(encoding)
 
 		if fh, e1 := os.Create(fn); e1 != nil {
 			c.log.Error().Err(e1).Str("file", fn).Msg("skipping submit trace")
@@ -235,19 +387,25 @@ func (c *Check) Submit(ctx context.Context, metrics io.Reader, resultLogger zero
 		resultLogger.Error().Err(err).Msg("creating submission request")
 		return err
 	}
-	req = req.WithContext(ctx)
+	reqCtx := ctx
+	if c.submitTimeout > 0 {
+		var cancel context.CancelFunc
+		reqCtx, cancel = context.WithTimeout(ctx, c.submitTimeout)
+		defer cancel()
+	}
+	req = req.WithContext(reqCtx)
 	req.Header.Set("User-Agent", release.NAME+"/"+release.VERSION)
 	req.Header.Set("Content-Type", "application/json")
 	req.Header.Set("Accept", "application/json")
 	req.Header.Set("Connection", "close")
 	req.Header.Set("Content-Length", strconv.Itoa(dataLen))
-	if payloadIsCompressed {
-		req.Header.Set("Content-Encoding", "gzip")
+	if encoding != "" {
+		req.Header.Set("Content-Encoding", encoding)
 	}
 
 	// doesn't work with retryablehttp
 	// if c.DebugSubmissions() {
-	// 	dump, e := httputil.DumpRequestOut(req.Request, !payloadIsCompressed)
+	// 	dump, e := httputil.DumpRequestOut(req.Request, encoding == "")
 	// 	if e != nil {
 	// 		resultLogger.Error().Err(e).Msg("dumping request")
 	// 		return e
@@ -259,14 +417,20 @@ func (c *Check) Submit(ctx context.Context, metrics io.Reader, resultLogger zero
 	retryClient := retryablehttp.NewClient()
 	retryClient.HTTPClient = client
 	retryClient.Logger = logshim{logh: c.log.With().Str("pkg", "retryablehttp").Logger()}
-	retryClient.RetryWaitMin = 50 * time.Millisecond
-	retryClient.RetryWaitMax = 1 * time.Second
-	retryClient.RetryMax = 10
+	c.retry.apply(retryClient)
+	retryReason := retryOther // failure class of the previous attempt
+	retryClient.CheckRetry = func(ctx context.Context, resp *http.Response, err error) (bool, error) {
+		retryReason = retryClass(ctx, resp, err)
+		return retryablehttp.DefaultRetryPolicy(ctx, resp, err)
+	}
 	retryClient.RequestLogHook = func(l retryablehttp.Logger, r *http.Request, attempt int) {
 		if attempt > 0 {
-			c.metrics.IncrementWithTags("collect_submit_retries", cgm.Tags{cgm.Tag{Category: "source", Value: release.NAME}})
+			c.incrementSubmitCounter("collect_submit_retries", cgm.Tags{
+				cgm.Tag{Category: "reason", Value: retryReason},
+				cgm.Tag{Category: "source", Value: release.NAME},
+			})
 			reqStart = time.Now()
-			resultLogger.Warn().Str("url", r.URL.String()).Int("retry", attempt).Msg("retrying...")
+			resultLogger.Warn().Str("url", r.URL.String()).Int("retry", attempt).Str("reason", retryReason).Msg("retrying...")
 		}
 	}
 	retryClient.ResponseLogHook = func(l retryablehttp.Logger, r *http.Response) {
@@ -276,7 +440,7 @@ func (c *Check) Submit(ctx context.Context, metrics io.Reader, resultLogger zero
 			cgm.Tag{Category: "units", Value: "milliseconds"},
 		}, float64(time.Since(reqStart).Milliseconds()))
 		if r.StatusCode != http.StatusOK {
-			c.metrics.IncrementWithTags("collect_submit_errors", cgm.Tags{
+			c.incrementSubmitCounter("collect_submit_errors", cgm.Tags{
 				cgm.Tag{Category: "code", Value: fmt.Sprintf("%d", r.StatusCode)},
 				cgm.Tag{Category: "source", Value: release.NAME},
 			})
@@ -289,9 +453,12 @@ func (c *Check) Submit(ctx context.Context, metrics io.Reader, resultLogger zero
 	resp, err := retryClient.Do(req)
 	if err != nil {
 		resultLogger.Error().Err(err).Msg("making request")
-		c.metrics.IncrementWithTags("collect_submit_fails", cgm.Tags{
+		c.incrementSubmitCounter("collect_submit_fails", cgm.Tags{
 			cgm.Tag{Category: "source", Value: release.NAME},
 		})
+		if spoolOnError {
+			c.spoolPayload(rawData, resultLogger)
+		}
 		return err
 	}
 
@@ -302,19 +469,41 @@ func (c *Check) Submit(ctx context.Context, metrics io.Reader, resultLogger zero
 		return err
 	}
 
-	if resp.StatusCode != http.StatusOK {
-		c.metrics.IncrementWithTags("collect_submit_fails", cgm.Tags{
+	forwarded := c.config.ForwardURL != "" && resp.StatusCode/100 == 2 // circonus-agent responds 204
+
+	if c.compression != nil && c.compression.rejected(encoding, resp.StatusCode, body) {
+		c.incrementSubmitCounter("collect_submit_compression_fallback", cgm.Tags{
+			cgm.Tag{Category: "source", Value: release.NAME},
+		})
+		resultLogger.Warn().Str("status", resp.Status).Str("body", string(body)).Msg("zstd compression not supported by broker, using gzip until retry")
+		return c.submit(ctx, bytes.NewReader(rawData), resultLogger, spoolOnError)
+	}
+
+	if resp.StatusCode != http.StatusOK && !forwarded {
+		c.incrementSubmitCounter("collect_submit_fails", cgm.Tags{
 			cgm.Tag{Category: "code", Value: fmt.Sprintf("%d", resp.StatusCode)},
 			cgm.Tag{Category: "source", Value: release.NAME},
 		})
 		resultLogger.Error().Str("url", c.submissionURL).Str("status", resp.Status).Str("body", string(body)).Msg("submitting telemetry")
+		if spoolOnError {
+			if resp.StatusCode >= http.StatusInternalServerError {
+				c.spoolPayload(rawData, resultLogger)
+			} else {
+				c.deadLetterPayload(rawData, resultLogger)
+			}
+		}
 		return errors.Errorf("submitting metrics (%s %s)", c.submissionURL, resp.Status)
 	}
 
-	c.metrics.IncrementWithTags("collect_submits", cgm.Tags{cgm.Tag{Category: "source", Value: release.NAME}})
+	c.incrementSubmitCounter("collect_submits", cgm.Tags{cgm.Tag{Category: "source", Value: release.NAME}})
 
 	var result TrapResult
-	if err := json.Unmarshal(body, &result); err != nil {
+	if forwarded {
+		var sent map[string]json.RawMessage
+		if err := json.Unmarshal(rawData, &sent); err == nil {
+			result.Stats = uint64(len(sent))
+		}
+	} else if err := json.Unmarshal(body, &result); err != nil {
 		resultLogger.Error().Err(err).Str("body", string(body)).Msg("parsing response")
 		return errors.Wrapf(err, "parsing response (%s)", string(body))
 	}
@@ -0,0 +1,70 @@
+// Copyright © 2019 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+package circonus
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/config"
+	"github.com/rs/zerolog"
+)
+
+func TestSubmitter(t *testing.T) {
+	var out bytes.Buffer
+	c := &Check{
+		config:        &config.Circonus{DryRun: true},
+		dryrun:        &dryRunOutput{w: &out},
+		log:           zerolog.Nop(),
+		submitWorkers: 2,
+		metricQueue:   make(chan MetricSet, 2),
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		c.Submitter(ctx)
+		close(done)
+	}()
+
+	for i := 0; i < 10; i++ {
+		if err := c.AddMetricSet(ctx, []byte(fmt.Sprintf(`{"m%d":{"_value":%d}}`, i, i)), zerolog.Nop()); err != nil {
+			t.Fatalf("unexpected error (%s)", err)
+		}
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		c.dryrun.Lock()
+		n := strings.Count(out.String(), "\n")
+		c.dryrun.Unlock()
+		if n == 10 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("expected 10 submissions, got %d", n)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected submitter to stop")
+	}
+
+	// queue full, workers stopped
+	c.metricQueue <- MetricSet{}
+	c.metricQueue <- MetricSet{}
+	if err := c.AddMetricSet(ctx, []byte(`{}`), zerolog.Nop()); err == nil {
+		t.Fatal("expected error, context done")
+	}
+}
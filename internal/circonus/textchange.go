@@ -0,0 +1,76 @@
+// Copyright © 2019 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+package circonus
+
+import (
+	"sync"
+	"time"
+)
+
+// NOTES:
+// Text metrics (node versions, conditions, collect_agent, etc.) rarely change but are
+// queued every interval. With --text-on-change a text metric is only sent when its
+// value differs from the last value sent, or when it has not been sent for the resend
+// interval (--text-resend), so it is refreshed periodically. Circonus only stores text
+// values when they change, so nothing is lost. Streams not seen for the resend interval
+// are forgotten.
+
+type textChanges struct {
+	sync.Mutex
+	resend    time.Duration
+	last      map[string]textSent
+	lastSweep time.Time
+}
+
+type textSent struct {
+	value string
+	sent  time.Time
+}
+
+func newTextChanges(resend time.Duration) *textChanges {
+	return &textChanges{
+		resend:    resend,
+		last:      make(map[string]textSent),
+		lastSweep: time.Now(),
+	}
+}
+
+// changed returns true if the value of the stream should be sent, the value is
+// recorded as sent
+func (t *textChanges) changed(streamName, value string, now time.Time) bool {
+	t.Lock()
+	defer t.Unlock()
+
+	if t.resend > 0 && now.Sub(t.lastSweep) >= t.resend {
+		for name, ts := range t.last {
+			if now.Sub(ts.sent) >= t.resend {
+				delete(t.last, name)
+			}
+		}
+		t.lastSweep = now
+	}
+
+	if ts, found := t.last[streamName]; found && ts.value == value {
+		if t.resend == 0 || now.Sub(ts.sent) < t.resend {
+			return false
+		}
+	}
+
+	t.last[streamName] = textSent{value: value, sent: now}
+	return true
+}
+
+// textChanged returns true if a metric should be sent, non-text metrics are always sent
+func (c *Check) textChanged(metricType, streamName string, value interface{}) bool {
+	if c.textChanges == nil || metricType != MetricTypeString {
+		return true
+	}
+	s, ok := value.(string)
+	if !ok {
+		return true
+	}
+	return c.textChanges.changed(streamName, s, time.Now())
+}
@@ -0,0 +1,43 @@
+// Copyright © 2019 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+package circonus
+
+import (
+	"testing"
+	"time"
+)
+
+func TestTextChanges(t *testing.T) {
+	now := time.Now()
+	tc := newTextChanges(time.Hour)
+
+	tests := []struct {
+		name   string
+		stream string
+		value  string
+		at     time.Duration
+		want   bool
+	}{
+		{"first", "version", "v1.17", 0, true},
+		{"unchanged", "version", "v1.17", time.Minute, false},
+		{"other stream", "kernel", "5.4", time.Minute, true},
+		{"changed", "version", "v1.18", 2 * time.Minute, true},
+		{"unchanged after change", "version", "v1.18", 3 * time.Minute, false},
+		{"resend", "version", "v1.18", 2*time.Minute + time.Hour, true},
+		{"swept, sent again", "kernel", "5.4", 2*time.Minute + time.Hour, true},
+	}
+
+	for _, tt := range tests {
+		if got := tc.changed(tt.stream, tt.value, now.Add(tt.at)); got != tt.want {
+			t.Fatalf("%s: expected %v, got %v", tt.name, tt.want, got)
+		}
+	}
+
+	c := &Check{textChanges: tc}
+	if !c.textChanged(MetricTypeUint64, "version", uint64(1)) {
+		t.Fatal("expected non-text metric to be sent")
+	}
+}
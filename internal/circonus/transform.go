@@ -0,0 +1,191 @@
+// Copyright © 2019 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+package circonus
+
+import (
+	"io/ioutil"
+	"strings"
+
+	cgm "github.com/circonus-labs/circonus-gometrics/v3"
+	"github.com/pkg/errors"
+	yaml "gopkg.in/yaml.v2"
+)
+
+// Example tag transforms file:
+//
+//   transforms:
+//     - rename: k8s_namespace
+//       to: namespace
+//     - drop: pod_uid
+//     - add: team:platform
+//       collectors: [kube-state-metrics]
+//
+// Tag transforms are applied, in order, to the stream tags of every metric queued,
+// before agent metric filters and recording rules. A rename changes a tag category,
+// keeping the value, a drop removes all tags of a category, and an add appends a tag
+// if the metric does not already have a tag of the same category. Collectors, if set,
+// restrict a transform to metrics with those source stream tag values.
+
+type tagTransformFile struct {
+	Transforms []tagTransformConfig `yaml:"transforms"`
+}
+
+type tagTransformConfig struct {
+	Rename     string   `yaml:"rename"`
+	To         string   `yaml:"to"`
+	Drop       string   `yaml:"drop"`
+	Add        string   `yaml:"add"`
+	Collectors []string `yaml:"collectors"`
+}
+
+type tagTransform struct {
+	rename     string
+	to         string
+	drop       string
+	add        cgm.Tag
+	collectors map[string]bool
+}
+
+// LoadTagTransforms loads the stream tag transforms, replacing the current transforms
+// (e.g. when the configuration is reloaded)
+func (c *Check) LoadTagTransforms(file string) error {
+	if file == "" {
+		return errors.New("invalid tag transforms file (empty)")
+	}
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		return errors.Wrap(err, "reading tag transforms file")
+	}
+	transforms, err := parseTagTransforms(data)
+	if err != nil {
+		return err
+	}
+	c.pipelinemu.Lock()
+	c.transforms = transforms
+	c.pipelinemu.Unlock()
+	c.log.Info().Int("transforms", len(transforms)).Msg("tag transforms loaded")
+	return nil
+}
+
+// parseTagTransforms parses and validates tag transforms
+func parseTagTransforms(data []byte) ([]tagTransform, error) {
+	var ttf tagTransformFile
+	if err := yaml.Unmarshal(data, &ttf); err != nil {
+		return nil, errors.Wrap(err, "parsing tag transforms")
+	}
+
+	transforms := make([]tagTransform, 0, len(ttf.Transforms))
+	for i, tc := range ttf.Transforms {
+		t := tagTransform{}
+		actions := 0
+		if tc.Rename != "" {
+			if tc.To == "" {
+				return nil, errors.Errorf("invalid tag transform %d, rename requires to", i)
+			}
+			t.rename, t.to = tc.Rename, tc.To
+			actions++
+		}
+		if tc.Drop != "" {
+			t.drop = tc.Drop
+			actions++
+		}
+		if tc.Add != "" {
+			kv := strings.SplitN(tc.Add, ":", 2)
+			if len(kv) != 2 || kv[0] == "" || kv[1] == "" {
+				return nil, errors.Errorf("invalid tag transform %d, add requires category:value (%s)", i, tc.Add)
+			}
+			t.add = cgm.Tag{Category: kv[0], Value: kv[1]}
+			actions++
+		}
+		if actions != 1 {
+			return nil, errors.Errorf("invalid tag transform %d, one of rename, drop, or add is required", i)
+		}
+		if len(tc.Collectors) > 0 {
+			t.collectors = make(map[string]bool)
+			for _, collector := range tc.Collectors {
+				t.collectors[collector] = true
+			}
+		}
+		transforms = append(transforms, t)
+	}
+	return transforms, nil
+}
+
+// transformTags applies the tag transforms to stream tags (category:value)
+func (c *Check) transformTags(streamTags []string) []string {
+	if len(c.tagTransforms()) == 0 {
+		return streamTags
+	}
+	tags := make(cgm.Tags, 0, len(streamTags))
+	for _, st := range streamTags {
+		kv := strings.SplitN(st, ":", 2)
+		if len(kv) == 2 {
+			tags = append(tags, cgm.Tag{Category: kv[0], Value: kv[1]})
+		} else {
+			tags = append(tags, cgm.Tag{Category: st})
+		}
+	}
+	tags = c.transformCGMTags(tags)
+	ret := make([]string, 0, len(tags))
+	for _, t := range tags {
+		if t.Value == "" {
+			ret = append(ret, t.Category)
+			continue
+		}
+		ret = append(ret, t.Category+":"+t.Value)
+	}
+	return ret
+}
+
+// transformCGMTags applies the tag transforms to tags
+func (c *Check) transformCGMTags(tags cgm.Tags) cgm.Tags {
+	transforms := c.tagTransforms()
+	if len(transforms) == 0 {
+		return tags
+	}
+	source := ""
+	for _, t := range tags {
+		if t.Category == "source" {
+			source = t.Value
+			break
+		}
+	}
+	ret := make(cgm.Tags, len(tags))
+	copy(ret, tags)
+	for _, tt := range transforms {
+		if tt.collectors != nil && !tt.collectors[source] {
+			continue
+		}
+		switch {
+		case tt.rename != "":
+			for i := range ret {
+				if ret[i].Category == tt.rename {
+					ret[i].Category = tt.to
+				}
+			}
+		case tt.drop != "":
+			kept := ret[:0]
+			for _, t := range ret {
+				if t.Category != tt.drop {
+					kept = append(kept, t)
+				}
+			}
+			ret = kept
+		default:
+			found := false
+			for _, t := range ret {
+				if t.Category == tt.add.Category {
+					found = true
+					break
+				}
+			}
+			if !found {
+				ret = append(ret, tt.add)
+			}
+		}
+	}
+	return ret
+}
@@ -0,0 +1,60 @@
+// Copyright © 2019 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+package circonus
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestTransformTags(t *testing.T) {
+	transforms, err := parseTagTransforms([]byte(`
+transforms:
+  - rename: k8s_namespace
+    to: namespace
+  - drop: pod_uid
+  - add: team:platform
+    collectors: [kube-state-metrics]
+`))
+	if err != nil {
+		t.Fatalf("unexpected error (%s)", err)
+	}
+	c := &Check{transforms: transforms}
+
+	tests := []struct {
+		name string
+		tags []string
+		want []string
+	}{
+		{"rename and drop", []string{"source:promscrape", "k8s_namespace:default", "pod_uid:1234"}, []string{"source:promscrape", "namespace:default"}},
+		{"add", []string{"source:kube-state-metrics", "namespace:default"}, []string{"source:kube-state-metrics", "namespace:default", "team:platform"}},
+		{"add present", []string{"source:kube-state-metrics", "team:apps"}, []string{"source:kube-state-metrics", "team:apps"}},
+		{"unchanged", []string{"", "source:nodes", "__rollup:false"}, []string{"", "source:nodes", "__rollup:false"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := c.transformTags(tt.tags); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("transformTags() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseTagTransformsInvalid(t *testing.T) {
+	tests := []string{
+		"transforms: [{rename: a}]",
+		"transforms: [{add: team}]",
+		"transforms: [{drop: a, add: b:c}]",
+		"transforms: [{collectors: [nodes]}]",
+	}
+
+	for _, tt := range tests {
+		if _, err := parseTagTransforms([]byte(tt)); err == nil {
+			t.Errorf("parseTagTransforms(%q) expected error", tt)
+		}
+	}
+}
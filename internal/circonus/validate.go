@@ -0,0 +1,142 @@
+// Copyright © 2019 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+package circonus
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"time"
+
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/config"
+	apiclient "github.com/circonus-labs/go-apiclient"
+	apiclicfg "github.com/circonus-labs/go-apiclient/config"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog"
+)
+
+// NOTES:
+// Used by the validate command, these checks have no side effects: the check is not
+// created when it does not exist and its metric filters are not changed.
+
+// ErrNoCheck is returned when validating the broker of a check which does not exist
+var ErrNoCheck = errors.New("check not found")
+
+// ValidateAPIToken verifies the api token, url, and app by fetching the token's user,
+// returns the user's email
+func ValidateAPIToken(cfg *config.Circonus) (string, error) {
+	c := &Check{config: cfg, log: zerolog.Nop()}
+	client, err := c.createAPIClient()
+	if err != nil {
+		return "", err
+	}
+	user, err := client.FetchUser(nil)
+	if err != nil {
+		return "", errors.Wrap(err, "fetching api token user")
+	}
+	return user.Email, nil
+}
+
+// ValidateBroker finds the check (by bundle cid or target) and verifies the broker
+// its submissions are sent to is reachable, returns the broker address. If the check
+// does not exist the configured broker (broker cid) is verified, otherwise ErrNoCheck
+// is returned since the broker is selected when the check is created.
+func ValidateBroker(cfg *config.Circonus, timeout time.Duration) (string, error) {
+	c := &Check{config: cfg, log: zerolog.Nop()}
+	client, err := c.createAPIClient()
+	if err != nil {
+		return "", err
+	}
+
+	bundle, err := findCheckBundle(client, cfg)
+	if err != nil {
+		return "", err
+	}
+
+	var addr string
+	if bundle != nil {
+		surl, ok := bundle.Config[apiclicfg.SubmissionURL]
+		if !ok {
+			return "", errors.Errorf("check bundle %s does not have a submission_url", bundle.CID)
+		}
+		addr, err = submissionAddr(surl)
+		if err != nil {
+			return "", err
+		}
+	} else {
+		if cfg.Check.BrokerCID == "" {
+			return "", ErrNoCheck
+		}
+		cid := cfg.Check.BrokerCID
+		broker, err := client.FetchBroker(apiclient.CIDType(&cid))
+		if err != nil {
+			return "", errors.Wrap(err, "fetching broker")
+		}
+		addr = brokerAddr(*broker)
+		if addr == "" {
+			return "", errors.Errorf("broker %s has no active httptrap instances", broker.CID)
+		}
+	}
+
+	conn, err := net.DialTimeout("tcp", addr, timeout)
+	if err != nil {
+		return addr, errors.Wrapf(err, "connecting to broker %s", addr)
+	}
+	conn.Close()
+
+	return addr, nil
+}
+
+// findCheckBundle returns the configured check bundle, or the active check bundle
+// for the target, nil if there is none
+func findCheckBundle(client *apiclient.API, cfg *config.Circonus) (*apiclient.CheckBundle, error) {
+	if cfg.Check.BundleCID != "" {
+		cid := cfg.Check.BundleCID
+		bundle, err := client.FetchCheckBundle(apiclient.CIDType(&cid))
+		if err != nil {
+			return nil, errors.Wrap(err, "fetching configured check bundle")
+		}
+		if bundle.Status != checkStatusActive {
+			return nil, errors.Errorf("invalid check bundle (%s), not active", bundle.CID)
+		}
+		return bundle, nil
+	}
+
+	for _, ct := range []string{checkType, altCheckType} {
+		criteria := apiclient.SearchQueryType(fmt.Sprintf(`(active:1)(type:"%s")(host:%s)`, ct, cfg.Check.Target))
+		bundles, err := client.SearchCheckBundles(&criteria, nil)
+		if err != nil {
+			return nil, errors.Wrapf(err, "searching for check (%s)", criteria)
+		}
+		for _, bundle := range *bundles {
+			if bundle.Status == checkStatusActive {
+				bundle := bundle
+				return &bundle, nil
+			}
+		}
+	}
+
+	return nil, nil
+}
+
+// submissionAddr returns the host:port of a check's submission url
+func submissionAddr(surl string) (string, error) {
+	u, err := url.Parse(surl)
+	if err != nil {
+		return "", errors.Wrap(err, "parsing submission url")
+	}
+	if u.Host == "" {
+		return "", errors.Errorf("invalid submission url (%s)", surl)
+	}
+	port := u.Port()
+	if port == "" {
+		port = "443"
+		if u.Scheme == "http" {
+			port = "80"
+		}
+	}
+	return net.JoinHostPort(u.Hostname(), port), nil
+}
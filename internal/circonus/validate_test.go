@@ -0,0 +1,37 @@
+// Copyright © 2019 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+package circonus
+
+import "testing"
+
+func TestSubmissionAddr(t *testing.T) {
+	tests := []struct {
+		surl      string
+		want      string
+		shouldErr bool
+	}{
+		{"https://trap.noit.circonus.net/module/httptrap/abc/secret", "trap.noit.circonus.net:443", false},
+		{"https://10.0.0.1:43191/module/httptrap/abc/secret", "10.0.0.1:43191", false},
+		{"http://broker.example.com/module/httptrap/abc/secret", "broker.example.com:80", false},
+		{"/module/httptrap/abc/secret", "", true},
+	}
+
+	for _, tt := range tests {
+		got, err := submissionAddr(tt.surl)
+		if tt.shouldErr {
+			if err == nil {
+				t.Fatalf("%s: expected error", tt.surl)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("%s: unexpected error (%s)", tt.surl, err)
+		}
+		if got != tt.want {
+			t.Fatalf("%s: expected %q, got %q", tt.surl, tt.want, got)
+		}
+	}
+}
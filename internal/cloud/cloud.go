@@ -0,0 +1,197 @@
+// Copyright © 2019 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+// Package cloud is the managed control-plane (EKS/GKE/AKS) collector, providing
+// fallbacks when the control-plane components are not directly scrapeable
+package cloud
+
+import (
+	"context"
+	"crypto/tls"
+	"sync"
+	"time"
+
+	cgm "github.com/circonus-labs/circonus-gometrics/v3"
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/circonus"
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/config"
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/config/defaults"
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/k8s"
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/release"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog"
+	"k8s.io/client-go/kubernetes"
+)
+
+const (
+	providerAuto = "auto"
+	providerEKS  = "eks"
+	providerGKE  = "gke"
+	providerAKS  = "aks"
+	providerNone = "none"
+)
+
+type Cloud struct {
+	config       *config.Cluster
+	check        *circonus.Check
+	log          zerolog.Logger
+	clientset    *kubernetes.Clientset
+	provider     string
+	cloudWatch   *cloudWatch
+	running      bool
+	apiTimelimit time.Duration
+	sync.Mutex
+	ts *time.Time
+}
+
+// NOTES:
+// Managed control-planes do not expose kube-scheduler, kube-controller-manager, or etcd
+// to the cluster. For all providers the api-server readiness and liveness checks are
+// collected (the checks include etcd and the api-server post-start hooks). Provider fallbacks:
+//   eks - scheduler and controller-manager metrics through the api-server (metrics.eks.amazonaws.com,
+//         kubernetes v1.28+) and, optionally, the AWS/EKS control-plane metrics from CloudWatch
+//   gke, aks - control-plane metrics are only published to Cloud Monitoring/Azure Monitor,
+//         which are not collected, the readiness and liveness checks are the only fallback
+
+func New(cfg *config.Cluster, parentLog zerolog.Logger, check *circonus.Check) (*Cloud, error) {
+	if cfg == nil {
+		return nil, errors.New("invalid cluster config (nil)")
+	}
+	if check == nil {
+		return nil, errors.New("invalid check (nil)")
+	}
+
+	c := &Cloud{
+		config: cfg,
+		check:  check,
+		log:    parentLog.With().Str("collector", "managed-control-plane").Logger(),
+	}
+
+	switch cfg.ManagedControlPlaneProvider {
+	case "", providerAuto:
+		c.provider = providerAuto
+	case providerEKS, providerGKE, providerAKS, providerNone:
+		c.provider = cfg.ManagedControlPlaneProvider
+	default:
+		return nil, errors.Errorf("invalid managed control-plane provider (%s)", cfg.ManagedControlPlaneProvider)
+	}
+
+	if cfg.APITimelimit != "" {
+		v, err := time.ParseDuration(cfg.APITimelimit)
+		if err != nil {
+			c.log.Error().Err(err).Msg("parsing api timelimit, using default")
+		} else {
+			c.apiTimelimit = v
+		}
+	}
+
+	if c.apiTimelimit == time.Duration(0) {
+		v, err := time.ParseDuration(defaults.K8SAPITimelimit)
+		if err != nil {
+			c.log.Fatal().Err(err).Msg("parsing DEFAULT api timelimit")
+		}
+		c.apiTimelimit = v
+	}
+
+	restCfg, err := k8s.RESTConfig(cfg)
+	if err != nil {
+		return nil, errors.Wrap(err, "rest config")
+	}
+	restCfg.Timeout = c.apiTimelimit
+	clientset, err := kubernetes.NewForConfig(restCfg)
+	if err != nil {
+		return nil, errors.Wrap(err, "clientset")
+	}
+	c.clientset = clientset
+
+	if cfg.EnableEKSCloudWatch {
+		if cfg.EKSClusterName == "" {
+			return nil, errors.New("invalid eks cluster name (empty), required for cloudwatch metrics")
+		}
+		cw, err := newCloudWatch(cfg.EKSClusterName, c.apiTimelimit)
+		if err != nil {
+			return nil, errors.Wrap(err, "initializing cloudwatch client")
+		}
+		c.cloudWatch = cw
+	}
+
+	return c, nil
+}
+
+func (c *Cloud) ID() string {
+	return "managed-control-plane"
+}
+
+// Collect managed control-plane health and provider specific metrics
+func (c *Cloud) Collect(ctx context.Context, tlsConfig *tls.Config, ts *time.Time) {
+	c.Lock()
+	if c.running {
+		c.log.Warn().Msg("already running")
+		c.Unlock()
+		return
+	}
+	c.running = true
+	c.ts = ts
+	c.Unlock()
+
+	defer func() {
+		if r := recover(); r != nil {
+			c.log.Error().Interface("panic", r).Msg("recover")
+			c.Lock()
+			c.running = false
+			c.Unlock()
+		}
+	}()
+
+	collectStart := time.Now()
+
+	if c.provider == providerAuto {
+		provider, err := c.detectProvider()
+		if err != nil {
+			c.log.Warn().Err(err).Msg("detecting managed control-plane provider")
+		} else {
+			c.log.Info().Str("provider", provider).Msg("managed control-plane provider")
+			c.provider = provider
+		}
+	}
+
+	c.healthChecks(ctx, tlsConfig)
+
+	if c.provider == providerEKS {
+		c.eksMetrics(ctx, tlsConfig)
+		if c.cloudWatch != nil {
+			c.cloudWatchMetrics(ctx)
+		}
+	}
+
+	c.check.AddHistSample("collect_latency", cgm.Tags{
+		cgm.Tag{Category: "source", Value: release.NAME},
+		cgm.Tag{Category: "op", Value: "collect_managed-control-plane"},
+		cgm.Tag{Category: "units", Value: "milliseconds"},
+	}, float64(time.Since(collectStart).Milliseconds()))
+	c.log.Debug().Str("duration", time.Since(collectStart).String()).Msg("managed control-plane collect end")
+	c.Lock()
+	c.running = false
+	c.Unlock()
+}
+
+// streamTags returns the common stream tags for managed control-plane metrics
+func (c *Cloud) streamTags(extra ...string) []string {
+	provider := c.provider
+	if provider == providerAuto {
+		provider = "unknown"
+	}
+	return append([]string{
+		"source:managed-control-plane",
+		"provider:" + provider,
+	}, extra...)
+}
+
+func (c *Cloud) apiError(request string) {
+	c.check.IncrementCounter("collect_api_errors", cgm.Tags{
+		cgm.Tag{Category: "source", Value: release.NAME},
+		cgm.Tag{Category: "request", Value: request},
+		cgm.Tag{Category: "target", Value: "api-server"},
+	})
+}
@@ -0,0 +1,57 @@
+// Copyright © 2019 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+package cloud
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseChecks(t *testing.T) {
+	body := `[+]ping ok
+[+]log ok
+[-]etcd failed: reason withheld
+[+]poststarthook/start-kube-apiserver-admission-initializer ok
+readyz check failed
+`
+	want := []healthCheck{
+		{name: "ping", ok: true},
+		{name: "log", ok: true},
+		{name: "etcd", ok: false},
+		{name: "poststarthook/start-kube-apiserver-admission-initializer", ok: true},
+	}
+	got, err := parseChecks(strings.NewReader(body))
+	if err != nil {
+		t.Fatalf("unexpected error (%s)", err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("parseChecks() = %v, want %v", got, want)
+	}
+}
+
+func TestProvider(t *testing.T) {
+	tests := []struct {
+		name       string
+		gitVersion string
+		providerID string
+		labels     map[string]string
+		want       string
+	}{
+		{"eks", "v1.27.4-eks-2d98532", "aws:///us-east-1a/i-0123", nil, providerEKS},
+		{"gke", "v1.27.3-gke.100", "gce://project/us-central1-a/node", nil, providerGKE},
+		{"aks", "v1.27.3", "azure:///subscriptions/x/vm", map[string]string{"kubernetes.azure.com/cluster": "MC_rg_cluster"}, providerAKS},
+		{"self-managed aws", "v1.27.4", "aws:///us-east-1a/i-0123", nil, providerNone},
+		{"self-managed azure", "v1.27.3", "azure:///subscriptions/x/vm", nil, providerNone},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := provider(tt.gitVersion, tt.providerID, tt.labels); got != tt.want {
+				t.Errorf("provider() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
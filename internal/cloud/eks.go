@@ -0,0 +1,162 @@
+// Copyright © 2019 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+package cloud
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net/http"
+	"regexp"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/cloudwatch"
+	cgm "github.com/circonus-labs/circonus-gometrics/v3"
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/circonus"
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/release"
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/scrape"
+	"github.com/pkg/errors"
+)
+
+// eksEndpoints are the control-plane component metrics exposed by EKS (kubernetes v1.28+)
+// through the api-server, only component specific families are forwarded
+var eksEndpoints = []struct {
+	component string
+	path      string
+	filter    *regexp.Regexp
+}{
+	{"kube-scheduler", "/apis/metrics.eks.amazonaws.com/v1/ksh/container/metrics", regexp.MustCompile(`^scheduler_`)},
+	{"kube-controller-manager", "/apis/metrics.eks.amazonaws.com/v1/kcm/container/metrics", regexp.MustCompile(`^workqueue_`)},
+}
+
+// eksMetrics collects scheduler and controller-manager metrics through the api-server
+func (c *Cloud) eksMetrics(ctx context.Context, tlsConfig *tls.Config) {
+	var wg sync.WaitGroup
+	for _, ep := range eksEndpoints {
+		wg.Add(1)
+		target := scrape.Target{
+			URL:          c.config.URL + ep.path,
+			BearerToken:  c.config.Token(),
+			Name:         ep.component,
+			Proxy:        "api-server",
+			FamilyFilter: ep.filter,
+			StreamTags: []string{
+				"source:" + ep.component,
+				"source_type:metrics",
+				"provider:eks",
+				"__rollup:false", // prevent high cardinality metrics from rolling up
+			},
+		}
+		go func(target scrape.Target) {
+			defer wg.Done()
+			// not found on clusters before v1.28
+			if err := scrape.Metrics(ctx, c.check, c.log, tlsConfig, c.apiTimelimit, target, c.ts); err != nil {
+				c.log.Warn().Err(err).Str("url", target.URL).Msg(target.Name + " metrics")
+			}
+		}(target)
+	}
+	wg.Wait()
+}
+
+// cloudWatchMetrics are the AWS/EKS control-plane metrics and the statistic requested
+var cloudWatchMetrics = map[string]string{
+	"apiserver_request_total":                   cloudwatch.StatisticSum,
+	"apiserver_request_total_4XX":               cloudwatch.StatisticSum,
+	"apiserver_request_total_5XX":               cloudwatch.StatisticSum,
+	"apiserver_request_total_429":               cloudwatch.StatisticSum,
+	"apiserver_storage_size_bytes":              cloudwatch.StatisticMaximum,
+	"scheduler_schedule_attempts_total":         cloudwatch.StatisticSum,
+	"scheduler_schedule_attempts_SCHEDULED":     cloudwatch.StatisticSum,
+	"scheduler_schedule_attempts_UNSCHEDULABLE": cloudwatch.StatisticSum,
+	"scheduler_schedule_attempts_ERROR":         cloudwatch.StatisticSum,
+	"scheduler_pending_pods":                    cloudwatch.StatisticMaximum,
+}
+
+type cloudWatch struct {
+	svc         *cloudwatch.CloudWatch
+	clusterName string
+}
+
+// newCloudWatch creates a cloudwatch client, region and credentials are from the
+// default aws chain (e.g. AWS_REGION and IAM roles for service accounts)
+func newCloudWatch(clusterName string, timelimit time.Duration) (*cloudWatch, error) {
+	sess, err := session.NewSessionWithOptions(session.Options{
+		Config:            aws.Config{HTTPClient: &http.Client{Timeout: timelimit}},
+		SharedConfigState: session.SharedConfigEnable,
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "aws session")
+	}
+	return &cloudWatch{
+		svc:         cloudwatch.New(sess),
+		clusterName: clusterName,
+	}, nil
+}
+
+// cloudWatchMetrics emits the latest AWS/EKS control-plane metric values
+func (c *Cloud) cloudWatchMetrics(ctx context.Context) {
+	end := time.Now().Truncate(time.Minute)
+	input := &cloudwatch.GetMetricDataInput{
+		StartTime: aws.Time(end.Add(-5 * time.Minute)),
+		EndTime:   aws.Time(end),
+		ScanBy:    aws.String(cloudwatch.ScanByTimestampDescending),
+	}
+	names := make(map[string]string)
+	i := 0
+	for name, stat := range cloudWatchMetrics {
+		id := fmt.Sprintf("m%d", i)
+		names[id] = name
+		i++
+		input.MetricDataQueries = append(input.MetricDataQueries, &cloudwatch.MetricDataQuery{
+			Id: aws.String(id),
+			MetricStat: &cloudwatch.MetricStat{
+				Metric: &cloudwatch.Metric{
+					Namespace:  aws.String("AWS/EKS"),
+					MetricName: aws.String(name),
+					Dimensions: []*cloudwatch.Dimension{
+						{Name: aws.String("ClusterName"), Value: aws.String(c.cloudWatch.clusterName)},
+					},
+				},
+				Period: aws.Int64(60),
+				Stat:   aws.String(stat),
+			},
+		})
+	}
+
+	out, err := c.cloudWatch.svc.GetMetricDataWithContext(ctx, input)
+	if err != nil {
+		c.check.IncrementCounter("collect_api_errors", cgm.Tags{
+			cgm.Tag{Category: "source", Value: release.NAME},
+			cgm.Tag{Category: "request", Value: "get-metric-data"},
+			cgm.Tag{Category: "target", Value: "cloudwatch"},
+		})
+		c.log.Error().Err(err).Msg("cloudwatch metrics")
+		return
+	}
+
+	metrics := make(map[string]circonus.MetricSample)
+	streamTags := c.streamTags("source_type:cloudwatch", "cluster_name:"+c.cloudWatch.clusterName)
+	for _, result := range out.MetricDataResults {
+		if result.Id == nil || len(result.Values) == 0 || result.Values[0] == nil {
+			continue
+		}
+		name, ok := names[*result.Id]
+		if !ok {
+			continue
+		}
+		_ = c.check.QueueMetricSample(metrics, "eks_"+name, circonus.MetricTypeFloat64, streamTags, []string{}, *result.Values[0], c.ts)
+	}
+
+	if len(metrics) == 0 {
+		return
+	}
+	if err := c.check.SubmitQueue(ctx, metrics, c.log.With().Str("type", "cloudwatch").Logger()); err != nil {
+		c.log.Warn().Err(err).Msg("submitting metrics")
+	}
+}
@@ -0,0 +1,114 @@
+// Copyright © 2019 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+package cloud
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"io"
+	"strings"
+
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/circonus"
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/k8s"
+	"github.com/pkg/errors"
+)
+
+// healthCheck is a single api-server readiness/liveness check (e.g. etcd, ping, poststarthook/...)
+type healthCheck struct {
+	name string
+	ok   bool
+}
+
+// healthChecks emits the api-server readiness and liveness checks, each check as a 0/1 gauge
+// and the overall result
+func (c *Cloud) healthChecks(ctx context.Context, tlsConfig *tls.Config) {
+	metrics := make(map[string]circonus.MetricSample)
+
+	for _, endpoint := range []string{"readyz", "livez"} {
+		checks, err := c.fetchChecks(tlsConfig, endpoint)
+		if err != nil {
+			c.log.Error().Err(err).Str("endpoint", endpoint).Msg("api-server health checks")
+			continue
+		}
+		healthy := uint64(1)
+		for _, hc := range checks {
+			v := uint64(0)
+			if hc.ok {
+				v = 1
+			} else {
+				healthy = 0
+			}
+			streamTags := c.streamTags("endpoint:"+endpoint, "check:"+hc.name)
+			_ = c.check.QueueMetricSample(metrics, "control_plane_check", circonus.MetricTypeUint64, streamTags, []string{}, v, c.ts)
+		}
+		_ = c.check.QueueMetricSample(metrics, "control_plane_healthy", circonus.MetricTypeUint64, c.streamTags("endpoint:"+endpoint), []string{}, healthy, c.ts)
+	}
+
+	if len(metrics) == 0 {
+		return
+	}
+	if err := c.check.SubmitQueue(ctx, metrics, c.log.With().Str("type", "health").Logger()); err != nil {
+		c.log.Warn().Err(err).Msg("submitting metrics")
+	}
+}
+
+// fetchChecks requests the verbose output of an api-server health endpoint, a failing
+// endpoint responds with a 500 and the same verbose body
+func (c *Cloud) fetchChecks(tlsConfig *tls.Config, endpoint string) ([]healthCheck, error) {
+	client, err := k8s.NewAPIClient(tlsConfig, c.apiTimelimit)
+	if err != nil {
+		return nil, errors.Wrap(err, endpoint+" cli")
+	}
+	defer client.CloseIdleConnections()
+
+	reqURL := c.config.URL + "/" + endpoint + "?verbose"
+	req, err := k8s.NewAPIRequest(c.config.Token(), reqURL)
+	if err != nil {
+		return nil, errors.Wrap(err, endpoint+" req")
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		c.apiError(endpoint)
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	checks, err := parseChecks(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if len(checks) == 0 {
+		c.apiError(endpoint)
+		return nil, errors.Errorf("no checks in %s response (%s)", endpoint, resp.Status)
+	}
+	return checks, nil
+}
+
+// parseChecks parses verbose health endpoint output, one check per line
+// (e.g. "[+]ping ok", "[-]etcd failed: reason withheld")
+func parseChecks(r io.Reader) ([]healthCheck, error) {
+	var checks []healthCheck
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if len(line) < 4 || line[0] != '[' || line[2] != ']' {
+			continue
+		}
+		name := line[3:]
+		if i := strings.Index(name, " "); i > 0 {
+			name = name[:i]
+		}
+		switch line[1] {
+		case '+':
+			checks = append(checks, healthCheck{name: name, ok: true})
+		case '-':
+			checks = append(checks, healthCheck{name: name, ok: false})
+		}
+	}
+	return checks, scanner.Err()
+}
@@ -0,0 +1,52 @@
+// Copyright © 2019 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+package cloud
+
+import (
+	"strings"
+
+	"github.com/pkg/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// detectProvider identifies the managed control-plane provider from the api-server
+// version (e.g. v1.27.4-eks-2d98532, v1.27.3-gke.100) and node provider ids/labels
+func (c *Cloud) detectProvider() (string, error) {
+	version, err := c.clientset.Discovery().ServerVersion()
+	if err != nil {
+		c.apiError("version")
+		return "", errors.Wrap(err, "api-server version")
+	}
+
+	nodes, err := c.clientset.CoreV1().Nodes().List(metav1.ListOptions{Limit: 1})
+	if err != nil {
+		c.apiError("node-list")
+		return "", errors.Wrap(err, "listing nodes")
+	}
+
+	providerID := ""
+	var labels map[string]string
+	if len(nodes.Items) > 0 {
+		providerID = nodes.Items[0].Spec.ProviderID
+		labels = nodes.Items[0].Labels
+	}
+
+	return provider(version.GitVersion, providerID, labels), nil
+}
+
+// provider returns the managed control-plane provider, none if the control-plane is not
+// managed (e.g. self-managed clusters running in aws or gcp)
+func provider(gitVersion, providerID string, labels map[string]string) string {
+	switch {
+	case strings.Contains(gitVersion, "-eks-"):
+		return providerEKS
+	case strings.Contains(gitVersion, "-gke."):
+		return providerGKE
+	case strings.HasPrefix(providerID, "azure://") && labels["kubernetes.azure.com/cluster"] != "":
+		return providerAKS
+	}
+	return providerNone
+}
@@ -0,0 +1,38 @@
+// Copyright © 2019 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+package cluster
+
+import (
+	"context"
+
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/config"
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/k8s"
+)
+
+// NOTES:
+// The circonus api token can be read from a kubernetes secret (--api-key-secret
+// namespace/name/key) rather than being set in the deployment manifest. The secret is
+// read when the cluster is initialized, taking precedence over --api-key and
+// --api-key-file, and then watched. When the token is rotated the check's api client
+// is recreated with the new token, no restart is required (submissions to the broker
+// do not use the token). The service account needs get, list, and watch on the secret
+// (see deploy/authrbac.yaml).
+
+// watchAPIKeySecret updates the check's api key when the secret changes, it does not
+// return until ctx is done
+func (c *Cluster) watchAPIKeySecret(ctx context.Context, cfg *config.Cluster) {
+	ref := c.circCfg.API.KeySecret
+	logger := c.logger.With().Str("secret", ref).Logger()
+	logger.Info().Msg("watching api key secret")
+
+	k8s.WatchSecretRef(ctx, cfg, ref, c.apiKey, func(apiKey string) {
+		if err := c.check.SetAPIKey(apiKey); err != nil {
+			logger.Error().Err(err).Msg("updating api key")
+			return
+		}
+		logger.Info().Msg("api key rotated")
+	}, logger)
+}
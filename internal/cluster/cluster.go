@@ -9,23 +9,16 @@ package cluster
 import (
 	"context"
 	"crypto/tls"
-	"crypto/x509"
 	"fmt"
-	"io/ioutil"
 	"runtime"
 	"strings"
 	"sync"
-	"syscall"
 	"time"
 
 	cgm "github.com/circonus-labs/circonus-gometrics/v3"
 	"github.com/circonus-labs/circonus-kubernetes-agent/internal/circonus"
 	"github.com/circonus-labs/circonus-kubernetes-agent/internal/config"
-	"github.com/circonus-labs/circonus-kubernetes-agent/internal/dns"
-	"github.com/circonus-labs/circonus-kubernetes-agent/internal/events"
-	"github.com/circonus-labs/circonus-kubernetes-agent/internal/ksm"
-	"github.com/circonus-labs/circonus-kubernetes-agent/internal/ms"
-	"github.com/circonus-labs/circonus-kubernetes-agent/internal/nodes"
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/k8s"
 	"github.com/circonus-labs/circonus-kubernetes-agent/internal/release"
 	"github.com/pkg/errors"
 	"github.com/rs/zerolog"
@@ -33,14 +26,24 @@ import (
 
 type Cluster struct {
 	tlsConfig  *tls.Config
-	cfg        config.Cluster
+	cfg        *config.Cluster
 	check      *circonus.Check
 	circCfg    config.Circonus
+	apiKey     string // read from the api key secret
 	logger     zerolog.Logger
 	interval   time.Duration
+	schedule   *schedule
+	runs       map[string]*collectorRun // collector id, in-progress state
 	lastStart  *time.Time
+	lastRotate time.Time // cardinality guard rotated
 	collectors []Collector
-	running    bool
+	evaluators []Evaluator
+	watchers   []Watcher
+	pending    *collection // reloaded configuration, applied before the next collection
+	base       config.Cluster
+	overlay    map[string]interface{} // collection resource settings applied to base
+	reloadmu   sync.Mutex
+	evalmu     sync.Mutex // evaluators and agent metrics of overlapping intervals run one at a time
 	sync.Mutex
 }
 type Collector interface {
@@ -48,51 +51,40 @@ type Collector interface {
 	Collect(context.Context, *tls.Config, *time.Time)
 }
 
+// Evaluator derives metrics from the samples queued during an interval, it is
+// evaluated after all collectors have finished (e.g. recording rules, slos)
+type Evaluator interface {
+	circonus.Recorder
+	Evaluate(context.Context, *time.Time)
+}
+
+// Watcher is implemented by collectors which watch resources between collections,
+// Start is called once when the cluster starts and does not return until ctx is done
+type Watcher interface {
+	Start(context.Context, *tls.Config)
+}
+
 func New(cfg config.Cluster, circCfg config.Circonus, parentLog zerolog.Logger) (*Cluster, error) {
 	if cfg.Name == "" {
 		return nil, errors.New("invalid cluster config (empty name)")
 	}
-	if cfg.BearerToken == "" && cfg.BearerTokenFile == "" {
-		return nil, errors.New("invalid bearer credentials (empty)")
-	}
 
-	c := &Cluster{
-		cfg:     cfg,
-		circCfg: circCfg,
-		logger:  parentLog.With().Str("pkg", "cluster").Str("cluster_name", cfg.Name).Logger(),
-	}
-
-	if c.cfg.BearerToken == "" && c.cfg.BearerTokenFile != "" {
-		token, err := ioutil.ReadFile(c.cfg.BearerTokenFile)
-		if err != nil {
-			return nil, errors.Wrap(err, "bearer token file")
-		}
-		c.cfg.BearerToken = string(token)
-	}
-	c.logger.Debug().Str("token", c.cfg.BearerToken[0:8]+"...").Msg("using bearer token")
+	logger := parentLog.With().Str("pkg", "cluster").Str("cluster_name", cfg.Name).Logger()
 
-	if c.cfg.CAFile != "" {
-		cert, err := ioutil.ReadFile(c.cfg.CAFile)
-		if err != nil {
-			return nil, errors.Wrap(err, "configuring k8s api tls")
-		}
-		cp := x509.NewCertPool()
-		if !cp.AppendCertsFromPEM(cert) {
-			return nil, errors.New("unable to add k8s api CA Certificate to x509 cert pool")
-		}
-		c.tlsConfig = &tls.Config{
-			RootCAs: cp,
-			// InsecureSkipVerify: true,
-		}
-		c.logger.Debug().Str("cert", c.cfg.CAFile).Msg("adding CA cert to TLS config")
+	base := cfg
+	tlsConfig, err := k8s.ConfigureCredentials(&cfg, logger)
+	if err != nil {
+		return nil, err
 	}
 
-	d, err := time.ParseDuration(c.cfg.Interval)
-	if err != nil {
-		return nil, errors.Wrap(err, "invalid duration in cluster configuration")
+	c := &Cluster{
+		cfg:       &cfg,
+		base:      base,
+		circCfg:   circCfg,
+		tlsConfig: tlsConfig,
+		logger:    logger,
+		runs:      make(map[string]*collectorRun),
 	}
-	c.interval = d
-	c.logger.Debug().Str("interval", d.String()).Msg("using interval")
 
 	// set check title if it has not been explicitly set by user
 	if circCfg.Check.Title == "" {
@@ -102,90 +94,79 @@ func New(cfg config.Cluster, circCfg config.Circonus, parentLog zerolog.Logger)
 	if circCfg.Check.Target == "" {
 		circCfg.Check.Target = strings.Replace(cfg.Name, " ", "_", -1)
 	}
+	if circCfg.API.KeySecret != "" {
+		key, err := k8s.SecretRefValue(c.cfg, circCfg.API.KeySecret)
+		if err != nil {
+			return nil, errors.Wrap(err, "reading api key secret")
+		}
+		circCfg.API.Key = key
+		c.apiKey = key
+	}
+
+	circCfg.MetricPrefix = cfg.MetricPrefix
 	check, err := circonus.NewCheck(c.logger, &circCfg)
 	if err != nil {
 		return nil, errors.Wrapf(err, "unable to initialize circonus for cluster (%s)", cfg.Name)
 	}
 	c.check = check
 
-	if c.cfg.EnableNodes {
-		// node metrics, as well as, pod and container metrics (both optional)
-		collector, err := nodes.New(&c.cfg, c.logger, c.check)
-		if err != nil {
-			return nil, errors.Wrap(err, "initializing node collector")
+	if circCfg.CreateDashboards {
+		if err := c.check.ProvisionDashboards(cfg.Name); err != nil {
+			c.logger.Warn().Err(err).Msg("creating dashboards")
 		}
-		c.collectors = append(c.collectors, collector)
 	}
 
-	if c.cfg.EnableKubeStateMetrics {
-		// TODO: does this allow "watching"?
-		collector, err := ksm.New(&c.cfg, c.logger, c.check)
-		if err != nil {
-			return nil, errors.Wrap(err, "initializing kube-state-metrics collector")
+	if circCfg.Alerts.Create {
+		if err := c.check.ProvisionAlerts(); err != nil {
+			c.logger.Warn().Err(err).Msg("creating alert rulesets")
 		}
-		c.collectors = append(c.collectors, collector)
 	}
 
-	if c.cfg.EnableMetricServer {
-		// TODO: does this allow "watching"?
-		collector, err := ms.New(&c.cfg, c.logger, c.check)
-		if err != nil {
-			return nil, errors.Wrap(err, "initializing kube-state-metrics collector")
-		}
-		c.collectors = append(c.collectors, collector)
-	}
-
-	if c.cfg.EnableKubeDNSMetrics {
-		collector, err := dns.New(&c.cfg, c.logger, c.check)
-		if err != nil {
-			return nil, errors.Wrap(err, "initializing kube-dns metrics collector")
-		}
-		c.collectors = append(c.collectors, collector)
+	set, err := newCollection(c.cfg, c.logger, c.check)
+	if err != nil {
+		return nil, err
 	}
+	c.use(set)
+	c.logger.Debug().Str("interval", c.interval.String()).Msg("using interval")
 
-	if len(c.collectors) == 0 {
-		return nil, errors.Errorf("no collectors enabled for cluster %s", c.cfg.Name)
+	if err := c.configureCheck(); err != nil {
+		return nil, err
 	}
 
 	return c, nil
 }
 
 func (c *Cluster) Start(ctx context.Context) error {
-	// create a errgroup context based on ctx
-	// if events enabled, create event watcher and add to errgroup
-	// if >0 collectors, start collector goroutine and add to errgroup
-	// errgroup wait
-
-	var eventWatcher *events.Events
-	if c.cfg.EnableEvents {
-		// TODO: events needs to be a separate thing started
-		//       in cluster.Start. It will not return if event
-		//       'watching' works as a stream collector. Which
-		//       means it does not need to be fired every
-		//       cluster.Interval like everything else.
-		ew, err := events.New(&c.cfg, c.logger, c.check)
-		if err != nil {
-			return errors.Wrap(err, "initializing events collector")
-		}
-		eventWatcher = ew
-	}
-
-	if len(c.collectors) == 0 && eventWatcher == nil {
+	if len(c.collectors) == 0 && len(c.watchers) == 0 {
 		return errors.New("invalid cluster (zero collectors)")
 	}
 
-	if eventWatcher != nil {
-		go eventWatcher.Start(ctx, c.tlsConfig)
+	if c.circCfg.API.KeySecret != "" {
+		go c.watchAPIKeySecret(ctx, c.cfg)
 	}
 
-	if !c.check.ConcurrentSubmissions() {
-		go c.check.Submitter(ctx)
+	if c.cfg.ConfigResource != "" {
+		go c.watchConfigResource(ctx, c.cfg)
 	}
 
+	// watchers are stopped when a reloaded configuration is applied
+	stopWatchers := c.startWatchers(ctx)
+
+	go c.check.Submitter(ctx)
+
+	go c.check.SyncMetricFilters(ctx)
+
 	c.logger.Info().Str("collection_interval", c.interval.String()).Time("next_collection", time.Now().Add(c.interval)).Msg("client started")
 
+	// collectors with their own interval, restarted when a reloaded configuration is applied
+	stopTickers := c.startCollectorTickers(ctx, c.collectors, c.schedule)
+
 	ticker := time.NewTicker(c.interval)
-	defer ticker.Stop()
+	defer func() {
+		ticker.Stop()
+		stopWatchers()
+		stopTickers()
+	}()
 
 	for {
 		select {
@@ -205,43 +186,81 @@ func (c *Cluster) Start(ctx context.Context) error {
 					continue
 				}
 			}
-			if c.running {
-				c.Unlock()
-				c.logger.Warn().
-					Str("started", c.lastStart.String()).
-					Str("elapsed", time.Since(*c.lastStart).String()).
-					Msg("collection in progress, not starting another")
-				continue
+
+			if set := c.pending; set != nil {
+				c.pending = nil
+				stopWatchers()
+				stopTickers()
+				prevInterval := c.interval
+				c.use(set)
+				if err := c.configureCheck(); err != nil {
+					c.logger.Error().Err(err).Msg("applying reloaded configuration")
+				}
+				stopWatchers = c.startWatchers(ctx)
+				stopTickers = c.startCollectorTickers(ctx, c.collectors, c.schedule)
+				if c.interval != prevInterval {
+					ticker.Stop()
+					ticker = time.NewTicker(c.interval)
+				}
+				c.logger.Info().
+					Str("collection_interval", c.interval.String()).
+					Int("collectors", len(c.collectors)).
+					Msg("reloaded configuration applied")
 			}
 
 			start := time.Now()
+			var collectors []Collector
+			for _, collector := range c.collectors {
+				if !c.schedule.ownTicker(collector.ID()) {
+					collectors = append(collectors, collector)
+				}
+			}
+			rotate := c.lastRotate.IsZero() || start.Sub(c.lastRotate) >= c.schedule.longest-c.interval/2
+			if rotate {
+				c.lastRotate = start
+			}
 			c.lastStart = &start
-			c.running = true
+			evaluators := c.evaluators
+			interval := c.interval
+			clusterName := c.cfg.Name
 			c.Unlock()
 
+			c.check.StartSubmitBudget(start)
+
 			// reset submit retries metric
 			c.check.SetCounter("collect_submit_retries", cgm.Tags{cgm.Tag{Category: "source", Value: release.NAME}}, 0)
 
 			go func() {
 				var wg sync.WaitGroup
-				wg.Add(len(c.collectors))
-				for _, collector := range c.collectors {
+				for _, collector := range collectors {
 					if collector.ID() == "events" {
 						continue
 					}
+					wg.Add(1)
 					go func(collector Collector) {
-						collector.Collect(ctx, c.tlsConfig, &start)
-						wg.Done()
+						defer wg.Done()
+						c.runCollector(ctx, collector, start)
 					}(collector)
 				}
 				wg.Wait()
 
+				c.evalmu.Lock()
+				defer c.evalmu.Unlock()
+
+				// in order, slos can use recording rule results
+				for _, e := range evaluators {
+					e.Evaluate(ctx, &start)
+				}
+
+				if rotate {
+					c.check.ReportCardinality()
+				}
 				cstats := c.check.SubmitStats()
 				c.check.ResetSubmitStats()
 				dur := time.Since(start)
 
 				baseStreamTags := cgm.Tags{
-					cgm.Tag{Category: "cluster", Value: c.cfg.Name},
+					cgm.Tag{Category: "cluster", Value: clusterName},
 					cgm.Tag{Category: "source", Value: release.NAME},
 				}
 				c.check.AddText("collect_agent", baseStreamTags, release.NAME+"_"+release.VERSION)
@@ -260,9 +279,8 @@ func (c *Cluster) Start(ctx context.Context) error {
 					c.check.AddGauge("collect_heap_released", streamTags, ms.HeapReleased)
 					c.check.AddGauge("collect_stack_sys", streamTags, ms.StackSys)
 					c.check.AddGauge("collect_other_sys", streamTags, ms.OtherSys)
-					var mem syscall.Rusage
-					if err := syscall.Getrusage(syscall.RUSAGE_SELF, &mem); err == nil {
-						c.check.AddGauge("collect_max_rss", streamTags, uint64(mem.Maxrss*1024))
+					if rss, err := maxRSS(); err == nil {
+						c.check.AddGauge("collect_max_rss", streamTags, rss)
 					} else {
 						c.logger.Warn().Err(err).Msg("collecting rss from system")
 					}
@@ -272,7 +290,7 @@ func (c *Cluster) Start(ctx context.Context) error {
 					streamTags = append(streamTags, baseStreamTags...)
 					streamTags = append(streamTags, cgm.Tag{Category: "units", Value: "milliseconds"})
 					c.check.AddGauge("collect_duration", streamTags, uint64(dur.Milliseconds()))
-					c.check.AddGauge("collect_interval", streamTags, uint64(c.interval.Milliseconds()))
+					c.check.AddGauge("collect_interval", streamTags, uint64(interval.Milliseconds()))
 				}
 
 				c.check.FlushCGM(ctx, &start)
@@ -281,10 +299,59 @@ func (c *Cluster) Start(ctx context.Context) error {
 					Interface("metrics_sent", cstats).
 					Str("duration", dur.String()).
 					Msg("collection complete")
-				c.Lock()
-				c.running = false
-				c.Unlock()
 			}()
 		}
 	}
 }
+
+// startCollectorTickers starts the tickers of the collectors with their own interval,
+// the tickers run until the returned func is called (or ctx is done), collections until
+// ctx is done
+func (c *Cluster) startCollectorTickers(ctx context.Context, collectors []Collector, sched *schedule) context.CancelFunc {
+	tickCtx, cancel := context.WithCancel(ctx)
+	for _, collector := range collectors {
+		if !sched.ownTicker(collector.ID()) {
+			continue
+		}
+		go c.collectorTicker(tickCtx, ctx, collector, sched.collectorInterval(collector.ID()))
+	}
+	return cancel
+}
+
+// collectorTicker runs a collector every interval until tickCtx is done
+func (c *Cluster) collectorTicker(tickCtx, ctx context.Context, collector Collector, interval time.Duration) {
+	c.logger.Debug().Str("collector", collector.ID()).Str("interval", interval.String()).Msg("collector interval")
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-tickCtx.Done():
+			return
+		case <-ticker.C:
+			go c.runCollector(ctx, collector, time.Now())
+		}
+	}
+}
+
+// runCollector runs a collection of a collector, unless the collector is still running
+func (c *Cluster) runCollector(ctx context.Context, collector Collector, start time.Time) {
+	c.Lock()
+	run, ok := c.runs[collector.ID()]
+	if !ok {
+		run = &collectorRun{}
+		c.runs[collector.ID()] = run
+	}
+	c.Unlock()
+
+	if started, ok := run.begin(start); !ok {
+		c.logger.Warn().
+			Str("collector", collector.ID()).
+			Str("started", started.String()).
+			Str("elapsed", time.Since(started).String()).
+			Msg("collection in progress, not starting another")
+		return
+	}
+	defer run.end()
+
+	collector.Collect(ctx, c.tlsConfig, &start)
+}
@@ -0,0 +1,570 @@
+// Copyright © 2019 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+package cluster
+
+import (
+	"time"
+
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/apiserver"
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/argocd"
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/certmanager"
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/circonus"
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/cloud"
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/clusterautoscaler"
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/clusterversion"
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/config"
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/configinventory"
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/cost"
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/cri"
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/crstate"
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/dcgm"
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/deprecatedapis"
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/dns"
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/endpoints"
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/etcd"
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/etcdobjects"
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/events"
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/evictions"
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/flux"
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/headroom"
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/hpa"
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/imagepulls"
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/ingressnginx"
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/istio"
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/jobs"
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/k8s"
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/karpenter"
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/kcm"
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/ksm"
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/kubeproxy"
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/linkerd"
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/monitors"
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/ms"
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/nodelocaldns"
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/nodes"
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/nsresources"
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/orphans"
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/pdb"
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/podphases"
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/podresources"
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/promscrape"
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/restarts"
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/rules"
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/schedfailures"
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/scheduler"
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/scrapetargets"
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/slo"
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/spot"
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/storage"
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/tlssecrets"
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/topn"
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/utilization"
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/velero"
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/workloads"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog"
+)
+
+// collection is the set of collectors built from a cluster configuration, it is
+// replaced as a whole when the configuration is reloaded
+type collection struct {
+	cfg        *config.Cluster
+	interval   time.Duration
+	schedule   *schedule
+	collectors []Collector
+	evaluators []Evaluator
+	watchers   []Watcher // collectors watching resources between collections, and events
+}
+
+// newCollection builds the collectors enabled in a cluster configuration
+func newCollection(cfg *config.Cluster, logger zerolog.Logger, check *circonus.Check) (*collection, error) {
+	d, err := time.ParseDuration(cfg.Interval)
+	if err != nil {
+		return nil, errors.Wrap(err, "invalid duration in cluster configuration")
+	}
+
+	sched, err := newSchedule(d, cfg.CollectorIntervals)
+	if err != nil {
+		return nil, errors.Wrap(err, "invalid collector intervals in cluster configuration")
+	}
+
+	if err := k8s.ValidateSelectors(cfg); err != nil {
+		return nil, errors.Wrap(err, "invalid label selector in cluster configuration")
+	}
+
+	set := &collection{cfg: cfg, interval: d, schedule: sched}
+
+	if cfg.EnableNodes {
+		// node metrics, as well as, pod and container metrics (both optional)
+		collector, err := nodes.New(cfg, logger, check)
+		if err != nil {
+			return nil, errors.Wrap(err, "initializing node collector")
+		}
+		set.collectors = append(set.collectors, collector)
+	}
+
+	if cfg.EnableKubeStateMetrics {
+		// TODO: does this allow "watching"?
+		collector, err := ksm.New(cfg, logger, check)
+		if err != nil {
+			return nil, errors.Wrap(err, "initializing kube-state-metrics collector")
+		}
+		set.collectors = append(set.collectors, collector)
+	}
+
+	if cfg.EnableMetricServer {
+		// TODO: does this allow "watching"?
+		collector, err := ms.New(cfg, logger, check)
+		if err != nil {
+			return nil, errors.Wrap(err, "initializing kube-state-metrics collector")
+		}
+		set.collectors = append(set.collectors, collector)
+	}
+
+	if cfg.EnableKubeDNSMetrics {
+		collector, err := dns.New(cfg, logger, check)
+		if err != nil {
+			return nil, errors.Wrap(err, "initializing kube-dns metrics collector")
+		}
+		set.collectors = append(set.collectors, collector)
+	}
+
+	if cfg.EnableAPIServer {
+		collector, err := apiserver.New(cfg, logger, check)
+		if err != nil {
+			return nil, errors.Wrap(err, "initializing api-server metrics collector")
+		}
+		set.collectors = append(set.collectors, collector)
+	}
+
+	if cfg.EnableEtcd {
+		collector, err := etcd.New(cfg, logger, check)
+		if err != nil {
+			return nil, errors.Wrap(err, "initializing etcd metrics collector")
+		}
+		set.collectors = append(set.collectors, collector)
+	}
+
+	if cfg.EnableKubeScheduler {
+		collector, err := scheduler.New(cfg, logger, check)
+		if err != nil {
+			return nil, errors.Wrap(err, "initializing kube-scheduler metrics collector")
+		}
+		set.collectors = append(set.collectors, collector)
+	}
+
+	if cfg.EnableKubeControllerManager {
+		collector, err := kcm.New(cfg, logger, check)
+		if err != nil {
+			return nil, errors.Wrap(err, "initializing kube-controller-manager metrics collector")
+		}
+		set.collectors = append(set.collectors, collector)
+	}
+
+	if cfg.EnableKubeProxy {
+		collector, err := kubeproxy.New(cfg, logger, check)
+		if err != nil {
+			return nil, errors.Wrap(err, "initializing kube-proxy metrics collector")
+		}
+		set.collectors = append(set.collectors, collector)
+	}
+
+	if cfg.EnableNodeLocalDNS {
+		collector, err := nodelocaldns.New(cfg, logger, check)
+		if err != nil {
+			return nil, errors.Wrap(err, "initializing node-local-dns metrics collector")
+		}
+		set.collectors = append(set.collectors, collector)
+	}
+
+	if cfg.EnablePromScrape {
+		collector, err := promscrape.New(cfg, logger, check)
+		if err != nil {
+			return nil, errors.Wrap(err, "initializing promscrape collector")
+		}
+		set.collectors = append(set.collectors, collector)
+	}
+
+	if cfg.EnablePromMonitors {
+		collector, err := monitors.New(cfg, logger, check)
+		if err != nil {
+			return nil, errors.Wrap(err, "initializing service/pod monitors collector")
+		}
+		set.collectors = append(set.collectors, collector)
+	}
+
+	if cfg.EnableIngressNginx {
+		collector, err := ingressnginx.New(cfg, logger, check)
+		if err != nil {
+			return nil, errors.Wrap(err, "initializing ingress-nginx metrics collector")
+		}
+		set.collectors = append(set.collectors, collector)
+	}
+
+	if cfg.EnableCertManager {
+		collector, err := certmanager.New(cfg, logger, check)
+		if err != nil {
+			return nil, errors.Wrap(err, "initializing cert-manager metrics collector")
+		}
+		set.collectors = append(set.collectors, collector)
+	}
+
+	if cfg.EnableIstio {
+		collector, err := istio.New(cfg, logger, check)
+		if err != nil {
+			return nil, errors.Wrap(err, "initializing istio metrics collector")
+		}
+		set.collectors = append(set.collectors, collector)
+	}
+
+	if cfg.EnableLinkerd {
+		collector, err := linkerd.New(cfg, logger, check)
+		if err != nil {
+			return nil, errors.Wrap(err, "initializing linkerd metrics collector")
+		}
+		set.collectors = append(set.collectors, collector)
+	}
+
+	if cfg.EnableDCGM {
+		collector, err := dcgm.New(cfg, logger, check)
+		if err != nil {
+			return nil, errors.Wrap(err, "initializing dcgm-exporter metrics collector")
+		}
+		set.collectors = append(set.collectors, collector)
+	}
+
+	if cfg.EnableStorageInventory {
+		collector, err := storage.New(cfg, logger, check)
+		if err != nil {
+			return nil, errors.Wrap(err, "initializing storage inventory collector")
+		}
+		set.collectors = append(set.collectors, collector)
+	}
+
+	if cfg.EnableHPA {
+		collector, err := hpa.New(cfg, logger, check)
+		if err != nil {
+			return nil, errors.Wrap(err, "initializing hpa status collector")
+		}
+		set.collectors = append(set.collectors, collector)
+	}
+
+	if cfg.EnableJobs {
+		collector, err := jobs.New(cfg, logger, check)
+		if err != nil {
+			return nil, errors.Wrap(err, "initializing job health collector")
+		}
+		set.collectors = append(set.collectors, collector)
+	}
+
+	if cfg.EnableDeployments || cfg.EnableStatefulSets || cfg.EnableDaemonSets {
+		collector, err := workloads.New(cfg, logger, check)
+		if err != nil {
+			return nil, errors.Wrap(err, "initializing workload status collector")
+		}
+		set.collectors = append(set.collectors, collector)
+	}
+
+	if cfg.EnableEndpoints {
+		collector, err := endpoints.New(cfg, logger, check)
+		if err != nil {
+			return nil, errors.Wrap(err, "initializing endpoint health collector")
+		}
+		set.collectors = append(set.collectors, collector)
+	}
+
+	if cfg.EnableCustomResources {
+		collector, err := crstate.New(cfg, logger, check)
+		if err != nil {
+			return nil, errors.Wrap(err, "initializing custom resource state collector")
+		}
+		set.collectors = append(set.collectors, collector)
+	}
+
+	if cfg.EnableContainerRestarts {
+		collector, err := restarts.New(cfg, logger, check)
+		if err != nil {
+			return nil, errors.Wrap(err, "initializing container restart collector")
+		}
+		set.collectors = append(set.collectors, collector)
+	}
+
+	if cfg.EnablePodPhases {
+		collector, err := podphases.New(cfg, logger, check)
+		if err != nil {
+			return nil, errors.Wrap(err, "initializing pod phase duration collector")
+		}
+		set.collectors = append(set.collectors, collector)
+	}
+
+	if cfg.EnableImagePulls {
+		collector, err := imagepulls.New(cfg, logger, check)
+		if err != nil {
+			return nil, errors.Wrap(err, "initializing image pull collector")
+		}
+		set.collectors = append(set.collectors, collector)
+	}
+
+	if cfg.EnableClusterAutoscaler {
+		collector, err := clusterautoscaler.New(cfg, logger, check)
+		if err != nil {
+			return nil, errors.Wrap(err, "initializing cluster-autoscaler metrics collector")
+		}
+		set.collectors = append(set.collectors, collector)
+	}
+
+	if cfg.EnableKarpenter {
+		collector, err := karpenter.New(cfg, logger, check)
+		if err != nil {
+			return nil, errors.Wrap(err, "initializing karpenter metrics collector")
+		}
+		set.collectors = append(set.collectors, collector)
+	}
+
+	if cfg.EnableVelero {
+		collector, err := velero.New(cfg, logger, check)
+		if err != nil {
+			return nil, errors.Wrap(err, "initializing velero backup status collector")
+		}
+		set.collectors = append(set.collectors, collector)
+	}
+
+	if cfg.EnableArgoCD {
+		collector, err := argocd.New(cfg, logger, check)
+		if err != nil {
+			return nil, errors.Wrap(err, "initializing argo cd application health collector")
+		}
+		set.collectors = append(set.collectors, collector)
+	}
+
+	if cfg.EnableFlux {
+		collector, err := flux.New(cfg, logger, check)
+		if err != nil {
+			return nil, errors.Wrap(err, "initializing flux reconciliation collector")
+		}
+		set.collectors = append(set.collectors, collector)
+	}
+
+	if cfg.EnableScrapeTargets {
+		collector, err := scrapetargets.New(cfg, logger, check)
+		if err != nil {
+			return nil, errors.Wrap(err, "initializing scrape targets collector")
+		}
+		set.collectors = append(set.collectors, collector)
+	}
+
+	if cfg.EnableDeprecatedAPIs {
+		collector, err := deprecatedapis.New(cfg, logger, check)
+		if err != nil {
+			return nil, errors.Wrap(err, "initializing deprecated apis collector")
+		}
+		set.collectors = append(set.collectors, collector)
+	}
+
+	if cfg.EnableTLSSecrets {
+		collector, err := tlssecrets.New(cfg, logger, check)
+		if err != nil {
+			return nil, errors.Wrap(err, "initializing tls secrets collector")
+		}
+		set.collectors = append(set.collectors, collector)
+	}
+
+	if cfg.EnableConfigInventory {
+		collector, err := configinventory.New(cfg, logger, check)
+		if err != nil {
+			return nil, errors.Wrap(err, "initializing config inventory collector")
+		}
+		set.collectors = append(set.collectors, collector)
+	}
+
+	if cfg.EnableNamespaceResources {
+		collector, err := nsresources.New(cfg, logger, check)
+		if err != nil {
+			return nil, errors.Wrap(err, "initializing namespace resources collector")
+		}
+		set.collectors = append(set.collectors, collector)
+	}
+
+	if cfg.EnableCRI {
+		collector, err := cri.New(cfg, logger, check)
+		if err != nil {
+			return nil, errors.Wrap(err, "initializing cri collector")
+		}
+		set.collectors = append(set.collectors, collector)
+	}
+
+	if cfg.EnablePodResources {
+		collector, err := podresources.New(cfg, logger, check)
+		if err != nil {
+			return nil, errors.Wrap(err, "initializing pod resources collector")
+		}
+		set.collectors = append(set.collectors, collector)
+	}
+
+	if cfg.EnableCost {
+		collector, err := cost.New(cfg, logger, check)
+		if err != nil {
+			return nil, errors.Wrap(err, "initializing cost collector")
+		}
+		set.collectors = append(set.collectors, collector)
+	}
+
+	if cfg.EnableHeadroom {
+		collector, err := headroom.New(cfg, logger, check)
+		if err != nil {
+			return nil, errors.Wrap(err, "initializing headroom collector")
+		}
+		set.collectors = append(set.collectors, collector)
+	}
+
+	if cfg.EnablePDB {
+		collector, err := pdb.New(cfg, logger, check)
+		if err != nil {
+			return nil, errors.Wrap(err, "initializing pod disruption budget collector")
+		}
+		set.collectors = append(set.collectors, collector)
+	}
+
+	if cfg.EnableSchedulingFailures {
+		collector, err := schedfailures.New(cfg, logger, check)
+		if err != nil {
+			return nil, errors.Wrap(err, "initializing scheduling failures collector")
+		}
+		set.collectors = append(set.collectors, collector)
+	}
+
+	if cfg.EnableEvictions {
+		collector, err := evictions.New(cfg, logger, check)
+		if err != nil {
+			return nil, errors.Wrap(err, "initializing eviction collector")
+		}
+		set.collectors = append(set.collectors, collector)
+	}
+
+	if cfg.EnableSpotInterruptions {
+		collector, err := spot.New(cfg, logger, check)
+		if err != nil {
+			return nil, errors.Wrap(err, "initializing spot interruption collector")
+		}
+		set.collectors = append(set.collectors, collector)
+	}
+
+	if cfg.EnableManagedControlPlane {
+		collector, err := cloud.New(cfg, logger, check)
+		if err != nil {
+			return nil, errors.Wrap(err, "initializing managed control-plane collector")
+		}
+		set.collectors = append(set.collectors, collector)
+	}
+
+	if cfg.EnableClusterVersion {
+		collector, err := clusterversion.New(cfg, logger, check)
+		if err != nil {
+			return nil, errors.Wrap(err, "initializing cluster version collector")
+		}
+		set.collectors = append(set.collectors, collector)
+	}
+
+	if cfg.EnableOrphans {
+		collector, err := orphans.New(cfg, logger, check)
+		if err != nil {
+			return nil, errors.Wrap(err, "initializing orphaned resource collector")
+		}
+		set.collectors = append(set.collectors, collector)
+	}
+
+	if cfg.EnableTopN {
+		collector, err := topn.New(cfg, logger, check)
+		if err != nil {
+			return nil, errors.Wrap(err, "initializing top-n collector")
+		}
+		set.collectors = append(set.collectors, collector)
+	}
+
+	if cfg.EnableUtilization {
+		collector, err := utilization.New(cfg, logger, check)
+		if err != nil {
+			return nil, errors.Wrap(err, "initializing utilization collector")
+		}
+		set.collectors = append(set.collectors, collector)
+	}
+
+	if cfg.EnableEtcdObjects {
+		collector, err := etcdobjects.New(cfg, logger, check)
+		if err != nil {
+			return nil, errors.Wrap(err, "initializing etcd objects collector")
+		}
+		set.collectors = append(set.collectors, collector)
+	}
+
+	if len(set.collectors) == 0 {
+		return nil, errors.Errorf("no collectors enabled for cluster %s", cfg.Name)
+	}
+
+	for id, d := range cfg.CollectorIntervals {
+		found := false
+		for _, collector := range set.collectors {
+			if collector.ID() == id {
+				found = true
+				break
+			}
+		}
+		if !found {
+			logger.Warn().Str("collector", id).Str("interval", d).Msg("collector interval set for a collector which is not enabled")
+		}
+	}
+
+	if cfg.EnableRecordingRules {
+		r, err := rules.New(cfg, logger, check)
+		if err != nil {
+			return nil, errors.Wrap(err, "initializing recording rules")
+		}
+		set.evaluators = append(set.evaluators, r)
+	}
+
+	if cfg.EnableSLOs {
+		s, err := slo.New(cfg, logger, check)
+		if err != nil {
+			return nil, errors.Wrap(err, "initializing slos")
+		}
+		set.evaluators = append(set.evaluators, s)
+	}
+
+	for _, collector := range set.collectors {
+		if w, ok := collector.(Watcher); ok {
+			set.watchers = append(set.watchers, w)
+		}
+	}
+
+	if cfg.EnableEvents {
+		ew, err := events.New(cfg, logger, check)
+		if err != nil {
+			return nil, errors.Wrap(err, "initializing events collector")
+		}
+		set.watchers = append(set.watchers, ew)
+	}
+
+	return set, nil
+}
+
+// recorders returns the current evaluators as check recorders
+func (c *Cluster) recorders() []circonus.Recorder {
+	recorders := make([]circonus.Recorder, 0, len(c.evaluators))
+	for _, e := range c.evaluators {
+		recorders = append(recorders, e)
+	}
+	return recorders
+}
+
+// use makes a collection the cluster's current collection
+func (c *Cluster) use(set *collection) {
+	c.cfg = set.cfg
+	c.interval = set.interval
+	c.schedule = set.schedule
+	c.collectors = set.collectors
+	c.evaluators = set.evaluators
+	c.watchers = set.watchers
+}
@@ -0,0 +1,50 @@
+// Copyright © 2019 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+package cluster
+
+import (
+	"context"
+
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/config"
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/k8s"
+)
+
+// NOTES:
+// With --k8s-config-resource namespace/name the cluster's collection settings are read
+// from a CirconusCollection custom resource (deploy/optional/circonuscollection.yaml),
+// so what is collected can be managed with the rest of the cluster's resources (e.g.
+// GitOps). The resource's spec holds cluster settings by their config file keys (e.g.
+// interval: 5m, enable_kube_state_metrics: true) and is applied on top of the
+// configuration (file, flags, environment). Changes to the resource are applied like a
+// reloaded configuration, between collections. An invalid spec is logged and the
+// current settings are kept, deleting the resource reverts to the configuration. The
+// service account needs get, list, and watch on circonuscollections.
+
+// watchConfigResource applies the collection resource settings when the resource
+// changes, it does not return until ctx is done
+func (c *Cluster) watchConfigResource(ctx context.Context, cfg *config.Cluster) {
+	ref := cfg.ConfigResource
+	logger := c.logger.With().Str("config_resource", ref).Logger()
+	logger.Info().Msg("watching collection resource")
+
+	k8s.WatchCirconusCollection(ctx, cfg, ref, func(spec map[string]interface{}) {
+		c.reloadmu.Lock()
+		defer c.reloadmu.Unlock()
+
+		prev := c.overlay
+		c.overlay = spec
+		if err := c.reload(); err != nil {
+			c.overlay = prev
+			logger.Error().Err(err).Msg("applying collection resource, keeping current settings")
+			return
+		}
+		if spec == nil {
+			logger.Info().Msg("collection resource deleted, using configuration")
+			return
+		}
+		logger.Info().Msg("collection resource applied")
+	}, logger)
+}
@@ -0,0 +1,19 @@
+// Copyright © 2019 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+// +build !windows
+
+package cluster
+
+import "syscall"
+
+// maxRSS returns the maximum resident set size of the agent process in bytes
+func maxRSS() (uint64, error) {
+	var mem syscall.Rusage
+	if err := syscall.Getrusage(syscall.RUSAGE_SELF, &mem); err != nil {
+		return 0, err
+	}
+	return uint64(mem.Maxrss * 1024), nil
+}
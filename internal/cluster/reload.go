@@ -0,0 +1,112 @@
+// Copyright © 2019 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+package cluster
+
+import (
+	"context"
+
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/config"
+	"github.com/pkg/errors"
+)
+
+// NOTES:
+// The configuration is reloaded on SIGHUP, or when the config file changes with
+// --watch-config. A reloaded cluster configuration (interval, enabled collectors and
+// their settings, agent metric filters, tag transforms, recording rules, slos) is
+// validated and its collectors are built right away, an invalid configuration is
+// logged and the current one is kept. The new collectors replace the current ones
+// before the next collection starts, never during a collection: watchers of the
+// current collectors (and events) are stopped and those of the new collectors are
+// started. Collector state (e.g. counter deltas kept by a collector) starts over. A
+// collector with its own interval still running completes with the current settings,
+// the new collector with the same id does not start until it has.
+//
+// The cluster name, api url, credentials, ca file, metric prefix, and collection
+// resource, as well as the circonus settings (check, api, submission), are not
+// reloaded, they require a restart.
+
+// Reload validates a changed cluster configuration and builds its collectors, they
+// replace the current collectors before the next collection
+func (c *Cluster) Reload(cfg config.Cluster) error {
+	c.reloadmu.Lock()
+	defer c.reloadmu.Unlock()
+	c.base = cfg
+	return c.reload()
+}
+
+// reload builds the collectors of the base configuration with the collection resource
+// settings applied, reloadmu must be held
+func (c *Cluster) reload() error {
+	next := c.base
+	if c.overlay != nil {
+		cfg, err := config.ApplyClusterOverlay(next, c.overlay)
+		if err != nil {
+			return err
+		}
+		next = cfg
+	}
+
+	c.Lock()
+	current := c.cfg
+	c.Unlock()
+
+	next.Name = current.Name
+	next.URL = current.URL
+	next.CAFile = current.CAFile
+	next.BearerToken = current.BearerToken
+	next.BearerTokenFile = current.BearerTokenFile
+	next.TokenSource = current.TokenSource
+	next.MetricPrefix = current.MetricPrefix
+	next.ConfigResource = current.ConfigResource
+	next.Kubeconfig = current.Kubeconfig
+	next.KubeconfigContext = current.KubeconfigContext
+
+	set, err := newCollection(&next, c.logger, c.check)
+	if err != nil {
+		return errors.Wrap(err, "reloading cluster configuration")
+	}
+
+	c.Lock()
+	c.pending = set
+	c.Unlock()
+
+	c.logger.Info().Int("collectors", len(set.collectors)).Msg("configuration reloaded, applied at next collection")
+	return nil
+}
+
+// configureCheck loads the tag transforms, agent metric filters, and namespace filter of
+// the current configuration and sets the evaluators as the check's recorders
+func (c *Cluster) configureCheck() error {
+	if c.cfg.EnableTagTransforms {
+		if err := c.check.LoadTagTransforms(c.cfg.TagTransformsFile); err != nil {
+			return errors.Wrap(err, "loading tag transforms")
+		}
+	} else {
+		c.check.ClearTagTransforms()
+	}
+
+	if c.cfg.EnableAgentMetricFilters {
+		if err := c.check.LoadAgentMetricFilters(c.cfg.AgentMetricFiltersFile); err != nil {
+			return errors.Wrap(err, "loading agent metric filters")
+		}
+	} else {
+		c.check.ClearAgentMetricFilters()
+	}
+
+	c.check.SetNamespaceFilter(c.cfg.NamespaceFilter())
+	c.check.SetRecorders(c.recorders())
+	return nil
+}
+
+// startWatchers starts the watchers of the current collectors, they run until ctx is done
+// or the returned func is called (a reloaded configuration is applied)
+func (c *Cluster) startWatchers(ctx context.Context) context.CancelFunc {
+	watchCtx, cancel := context.WithCancel(ctx)
+	for _, w := range c.watchers {
+		go w.Start(watchCtx, c.tlsConfig)
+	}
+	return cancel
+}
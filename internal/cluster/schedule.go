@@ -0,0 +1,113 @@
+// Copyright © 2019 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+package cluster
+
+import (
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// NOTES:
+// Collectors run every cluster interval unless collector_intervals (config file or
+// collection resource) sets an interval for the collector's id, e.g.
+//
+//   collector_intervals:
+//     kube-state-metrics: 5m
+//     nodes: 30s
+//
+// Collectors at the cluster interval run together on the cluster ticker, evaluators,
+// agent metrics, and the flush run after they complete. A collector with its own
+// interval runs on a ticker of its own, its samples are evaluated with the next
+// cluster interval. Each collector has its own in-progress state, a collector still
+// running when it is due again is skipped (and logged) without holding up the others,
+// so a slow kube-state-metrics does not delay nodes at a shorter interval.
+//
+// The cardinality guard is rotated at the longest interval (cluster or collector), so
+// streams of collectors running less often than the cluster interval keep their
+// reservations between runs.
+
+// schedule is when the collectors of a collection run
+type schedule struct {
+	interval  time.Duration            // cluster interval, the default
+	intervals map[string]time.Duration // collector id, interval (different from the cluster interval)
+	longest   time.Duration            // longest cluster or collector interval
+}
+
+// newSchedule returns the schedule for the cluster interval and collector intervals
+func newSchedule(interval time.Duration, collectorIntervals map[string]string) (*schedule, error) {
+	if interval < time.Second {
+		return nil, errors.Errorf("invalid interval %s, minimum 1s", interval)
+	}
+
+	s := &schedule{
+		interval:  interval,
+		intervals: make(map[string]time.Duration),
+		longest:   interval,
+	}
+	for id, v := range collectorIntervals {
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid collector interval for %s", id)
+		}
+		if d < time.Second {
+			return nil, errors.Errorf("invalid collector interval for %s (%s), minimum 1s", id, d)
+		}
+		if d == interval {
+			continue
+		}
+		s.intervals[id] = d
+		if d > s.longest {
+			s.longest = d
+		}
+	}
+
+	return s, nil
+}
+
+// collectorInterval returns the interval a collector runs at
+func (s *schedule) collectorInterval(id string) time.Duration {
+	if d, ok := s.intervals[id]; ok {
+		return d
+	}
+	return s.interval
+}
+
+// ownTicker returns true if the collector runs on a ticker of its own rather than with
+// the collectors at the cluster interval
+func (s *schedule) ownTicker(id string) bool {
+	_, ok := s.intervals[id]
+	return ok
+}
+
+// collectorRun is the in-progress state of a collector, shared by the collector
+// instances of reloaded configurations (by id)
+type collectorRun struct {
+	running bool
+	start   time.Time
+	sync.Mutex
+}
+
+// begin marks the collector as running, returns false (and the start of the run in
+// progress) if it is already running
+func (r *collectorRun) begin(now time.Time) (time.Time, bool) {
+	r.Lock()
+	defer r.Unlock()
+	if r.running {
+		return r.start, false
+	}
+	r.running = true
+	r.start = now
+	return now, true
+}
+
+// end marks the collector as done
+func (r *collectorRun) end() {
+	r.Lock()
+	r.running = false
+	r.Unlock()
+}
@@ -0,0 +1,76 @@
+// Copyright © 2019 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+package cluster
+
+import (
+	"testing"
+	"time"
+)
+
+func TestNewSchedule(t *testing.T) {
+	tests := []struct {
+		name        string
+		interval    time.Duration
+		overrides   map[string]string
+		wantLongest time.Duration
+		wantOwn     []string
+		shouldErr   bool
+	}{
+		{"default", time.Minute, nil, time.Minute, nil, false},
+		{"longer", time.Minute, map[string]string{"kube-state-metrics": "5m"}, 5 * time.Minute, []string{"kube-state-metrics"}, false},
+		{"shorter", time.Minute, map[string]string{"kube-state-metrics": "5m", "nodes": "30s"}, 5 * time.Minute, []string{"kube-state-metrics", "nodes"}, false},
+		{"same as cluster", time.Minute, map[string]string{"nodes": "1m"}, time.Minute, nil, false},
+		{"invalid", time.Minute, map[string]string{"nodes": "fast"}, 0, nil, true},
+		{"too short", time.Minute, map[string]string{"nodes": "100ms"}, 0, nil, true},
+	}
+
+	for _, tt := range tests {
+		s, err := newSchedule(tt.interval, tt.overrides)
+		if tt.shouldErr {
+			if err == nil {
+				t.Fatalf("%s: expected error", tt.name)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("%s: unexpected error (%s)", tt.name, err)
+		}
+		if s.longest != tt.wantLongest {
+			t.Fatalf("%s: expected longest %s, got %s", tt.name, tt.wantLongest, s.longest)
+		}
+		if len(s.intervals) != len(tt.wantOwn) {
+			t.Fatalf("%s: expected own tickers %v, got %v", tt.name, tt.wantOwn, s.intervals)
+		}
+		for _, id := range tt.wantOwn {
+			if !s.ownTicker(id) || s.collectorInterval(id) != s.intervals[id] {
+				t.Fatalf("%s: expected %s to have its own ticker", tt.name, id)
+			}
+		}
+		if s.ownTicker("pods") || s.collectorInterval("pods") != tt.interval {
+			t.Fatalf("%s: expected pods at the cluster interval", tt.name)
+		}
+	}
+}
+
+func TestCollectorRun(t *testing.T) {
+	var r collectorRun
+	start := time.Date(2020, 2, 1, 12, 0, 0, 0, time.UTC)
+
+	if _, ok := r.begin(start); !ok {
+		t.Fatal("expected first run to start")
+	}
+	started, ok := r.begin(start.Add(30 * time.Second))
+	if ok {
+		t.Fatal("expected run in progress to not start another")
+	}
+	if !started.Equal(start) {
+		t.Fatalf("expected start of run in progress %s, got %s", start, started)
+	}
+	r.end()
+	if _, ok := r.begin(start.Add(time.Minute)); !ok {
+		t.Fatal("expected run to start after the previous one ended")
+	}
+}
@@ -0,0 +1,129 @@
+// Copyright © 2019 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+// Package clusterautoscaler is the cluster-autoscaler metrics collector
+package clusterautoscaler
+
+import (
+	"context"
+	"crypto/tls"
+	"regexp"
+	"sync"
+	"time"
+
+	cgm "github.com/circonus-labs/circonus-gometrics/v3"
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/circonus"
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/config"
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/config/defaults"
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/release"
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/scrape"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog"
+)
+
+// only forward cluster health, node (group) counts and limits, unschedulable pods, and
+// scale-up/scale-down activity metric families
+var familyFilter = regexp.MustCompile(`^cluster_autoscaler_(cluster_safe_to_autoscale|nodes_count|node_groups_count|node_group_(min|max)_count|max_nodes_count|unschedulable_pods_count|unneeded_nodes_count|scaled_up_nodes_total|scaled_down_nodes_total|failed_scale_ups_total|last_activity)$`)
+
+type ClusterAutoscaler struct {
+	config       *config.Cluster
+	check        *circonus.Check
+	log          zerolog.Logger
+	running      bool
+	apiTimelimit time.Duration
+	sync.Mutex
+	ts *time.Time
+}
+
+// NOTES:
+// cluster-autoscaler serves metrics on 8085 /metrics, only the elected leader runs the
+// autoscaling loop so standby replicas report idle values (the pod stream tag distinguishes them).
+
+func New(cfg *config.Cluster, parentLog zerolog.Logger, check *circonus.Check) (*ClusterAutoscaler, error) {
+	if cfg == nil {
+		return nil, errors.New("invalid cluster config (nil)")
+	}
+	if check == nil {
+		return nil, errors.New("invalid check (nil)")
+	}
+
+	ca := &ClusterAutoscaler{
+		config: cfg,
+		check:  check,
+		log:    parentLog.With().Str("collector", "cluster-autoscaler").Logger(),
+	}
+
+	if cfg.APITimelimit != "" {
+		v, err := time.ParseDuration(cfg.APITimelimit)
+		if err != nil {
+			ca.log.Error().Err(err).Msg("parsing api timelimit, using default")
+		} else {
+			ca.apiTimelimit = v
+		}
+	}
+
+	if ca.apiTimelimit == time.Duration(0) {
+		v, err := time.ParseDuration(defaults.K8SAPITimelimit)
+		if err != nil {
+			ca.log.Fatal().Err(err).Msg("parsing DEFAULT api timelimit")
+		}
+		ca.apiTimelimit = v
+	}
+
+	return ca, nil
+}
+
+func (ca *ClusterAutoscaler) ID() string {
+	return "cluster-autoscaler"
+}
+
+// Collect metrics from cluster-autoscaler pods
+func (ca *ClusterAutoscaler) Collect(ctx context.Context, tlsConfig *tls.Config, ts *time.Time) {
+	ca.Lock()
+	if ca.running {
+		ca.log.Warn().Msg("already running")
+		ca.Unlock()
+		return
+	}
+	ca.running = true
+	ca.ts = ts
+	ca.Unlock()
+
+	defer func() {
+		if r := recover(); r != nil {
+			ca.log.Error().Interface("panic", r).Msg("recover")
+			ca.Lock()
+			ca.running = false
+			ca.Unlock()
+		}
+	}()
+
+	collectStart := time.Now()
+
+	ca.podMetrics(ctx, tlsConfig)
+
+	ca.check.AddHistSample("collect_latency", cgm.Tags{
+		cgm.Tag{Category: "source", Value: release.NAME},
+		cgm.Tag{Category: "op", Value: "collect_cluster-autoscaler"},
+		cgm.Tag{Category: "units", Value: "milliseconds"},
+	}, float64(time.Since(collectStart).Milliseconds()))
+	ca.log.Debug().Str("duration", time.Since(collectStart).String()).Msg("cluster-autoscaler collect end")
+	ca.Lock()
+	ca.running = false
+	ca.Unlock()
+}
+
+// podMetrics collects metrics from each cluster-autoscaler pod via the api-server proxy
+func (ca *ClusterAutoscaler) podMetrics(ctx context.Context, tlsConfig *tls.Config) {
+	scrape.PodMetrics(ctx, ca.check, ca.log, ca.config, tlsConfig, ca.apiTimelimit, scrape.Component{
+		Name:         "cluster-autoscaler",
+		Namespaces:   []string{ca.config.ClusterAutoscalerNamespace},
+		Selector:     ca.config.ClusterAutoscalerSelector,
+		Port:         ca.config.ClusterAutoscalerPort,
+		FamilyFilter: familyFilter,
+		PodTag:       true,
+		Workers:      int(ca.config.NodePoolSize),
+	}, ca.ts)
+}
@@ -0,0 +1,76 @@
+// Copyright © 2019 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+package clusterautoscaler
+
+import (
+	"context"
+	"crypto/tls"
+	"testing"
+
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/config"
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/scrape/scrapetest"
+	"github.com/rs/zerolog"
+)
+
+func TestFamilyFilter(t *testing.T) {
+	tests := []struct {
+		family string
+		want   bool
+	}{
+		{"cluster_autoscaler_cluster_safe_to_autoscale", true},
+		{"cluster_autoscaler_nodes_count", true},
+		{"cluster_autoscaler_node_group_min_count", true},
+		{"cluster_autoscaler_node_group_max_count", true},
+		{"cluster_autoscaler_unschedulable_pods_count", true},
+		{"cluster_autoscaler_scaled_up_nodes_total", true},
+		{"cluster_autoscaler_last_activity", true},
+		{"cluster_autoscaler_function_duration_seconds", false},
+		{"cluster_autoscaler_errors_total", false},
+	}
+
+	for _, tt := range tests {
+		if got := familyFilter.MatchString(tt.family); got != tt.want {
+			t.Errorf("%s: expected %v, got %v", tt.family, tt.want, got)
+		}
+	}
+}
+
+func TestCollect(t *testing.T) {
+	// with leader election, the standby replica only reports its own activity
+	ts := scrapetest.NewServer(map[string]string{
+		"/api/v1/namespaces/kube-system/pods": `{"items":[` +
+			`{"metadata":{"name":"cluster-autoscaler-abcde","namespace":"kube-system"},"spec":{"nodeName":"cp1"},"status":{"phase":"Running","podIP":"127.0.0.1"}},` +
+			`{"metadata":{"name":"cluster-autoscaler-fghij","namespace":"kube-system"},"spec":{"nodeName":"cp2"},"status":{"phase":"Running","podIP":"127.0.0.2"}}]}`,
+		"/api/v1/namespaces/kube-system/pods/cluster-autoscaler-abcde:8085/proxy/metrics": `# TYPE cluster_autoscaler_nodes_count gauge
+cluster_autoscaler_nodes_count{state="ready"} 5
+`,
+		"/api/v1/namespaces/kube-system/pods/cluster-autoscaler-fghij:8085/proxy/metrics": `# TYPE cluster_autoscaler_last_activity gauge
+cluster_autoscaler_last_activity{activity="main"} 1.5e+09
+`,
+	})
+	defer ts.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	check, rec := scrapetest.NewCheck(ctx, t)
+
+	ca, err := New(&config.Cluster{
+		URL:                        ts.URL,
+		ClusterAutoscalerNamespace: "kube-system",
+		ClusterAutoscalerSelector:  "app.kubernetes.io/name=cluster-autoscaler",
+		ClusterAutoscalerPort:      "8085",
+	}, zerolog.Nop(), check)
+	if err != nil {
+		t.Fatalf("unexpected error (%s)", err)
+	}
+	ca.Collect(ctx, &tls.Config{}, nil)
+
+	// replicas are told apart by the pod tag
+	scrapetest.ExpectTags(t, rec, "cluster_autoscaler_nodes_count",
+		"source:cluster-autoscaler", "pod:cluster-autoscaler-abcde", "node:cp1", "state:ready")
+	scrapetest.ExpectTags(t, rec, "cluster_autoscaler_last_activity",
+		"source:cluster-autoscaler", "pod:cluster-autoscaler-fghij", "node:cp2", "activity:main")
+}
@@ -0,0 +1,211 @@
+// Copyright © 2019 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+// Package clusterversion is the cluster version and upgrade skew collector
+package clusterversion
+
+import (
+	"context"
+	"crypto/tls"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	cgm "github.com/circonus-labs/circonus-gometrics/v3"
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/circonus"
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/config"
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/config/defaults"
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/k8s"
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/release"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+type ClusterVersion struct {
+	config       *config.Cluster
+	check        *circonus.Check
+	log          zerolog.Logger
+	clientset    *kubernetes.Clientset
+	running      bool
+	apiTimelimit time.Duration
+	sync.Mutex
+	ts *time.Time
+}
+
+// NOTES:
+// The skew is the number of minor versions the oldest kubelet is behind the api-server,
+// kubernetes supports kubelets up to two (three as of v1.28) minor versions older than
+// the api-server and none newer (negative skew).
+
+func New(cfg *config.Cluster, parentLog zerolog.Logger, check *circonus.Check) (*ClusterVersion, error) {
+	if cfg == nil {
+		return nil, errors.New("invalid cluster config (nil)")
+	}
+	if check == nil {
+		return nil, errors.New("invalid check (nil)")
+	}
+
+	cv := &ClusterVersion{
+		config: cfg,
+		check:  check,
+		log:    parentLog.With().Str("collector", "cluster-version").Logger(),
+	}
+
+	if cfg.APITimelimit != "" {
+		v, err := time.ParseDuration(cfg.APITimelimit)
+		if err != nil {
+			cv.log.Error().Err(err).Msg("parsing api timelimit, using default")
+		} else {
+			cv.apiTimelimit = v
+		}
+	}
+
+	if cv.apiTimelimit == time.Duration(0) {
+		v, err := time.ParseDuration(defaults.K8SAPITimelimit)
+		if err != nil {
+			cv.log.Fatal().Err(err).Msg("parsing DEFAULT api timelimit")
+		}
+		cv.apiTimelimit = v
+	}
+
+	restCfg, err := k8s.RESTConfig(cfg)
+	if err != nil {
+		return nil, errors.Wrap(err, "rest config")
+	}
+	restCfg.Timeout = cv.apiTimelimit
+	clientset, err := kubernetes.NewForConfig(restCfg)
+	if err != nil {
+		return nil, errors.Wrap(err, "clientset")
+	}
+	cv.clientset = clientset
+
+	return cv, nil
+}
+
+func (cv *ClusterVersion) ID() string {
+	return "cluster-version"
+}
+
+// Collect the api-server version and kubelet version skew
+func (cv *ClusterVersion) Collect(ctx context.Context, _ *tls.Config, ts *time.Time) {
+	cv.Lock()
+	if cv.running {
+		cv.log.Warn().Msg("already running")
+		cv.Unlock()
+		return
+	}
+	cv.running = true
+	cv.ts = ts
+	cv.Unlock()
+
+	defer func() {
+		if r := recover(); r != nil {
+			cv.log.Error().Interface("panic", r).Msg("recover")
+			cv.Lock()
+			cv.running = false
+			cv.Unlock()
+		}
+	}()
+
+	collectStart := time.Now()
+
+	cv.versionMetrics(ctx)
+
+	cv.check.AddHistSample("collect_latency", cgm.Tags{
+		cgm.Tag{Category: "source", Value: release.NAME},
+		cgm.Tag{Category: "op", Value: "collect_cluster-version"},
+		cgm.Tag{Category: "units", Value: "milliseconds"},
+	}, float64(time.Since(collectStart).Milliseconds()))
+	cv.log.Debug().Str("duration", time.Since(collectStart).String()).Msg("cluster-version collect end")
+	cv.Lock()
+	cv.running = false
+	cv.Unlock()
+}
+
+// versionMetrics emits the api-server version and the skew between the api-server and the oldest kubelet
+func (cv *ClusterVersion) versionMetrics(ctx context.Context) {
+	info, err := cv.clientset.Discovery().ServerVersion()
+	if err != nil {
+		cv.apiError("version")
+		cv.log.Error().Err(err).Msg("api-server version")
+		return
+	}
+
+	nodes, err := cv.clientset.CoreV1().Nodes().List(metav1.ListOptions{LabelSelector: cv.config.NodeSelector})
+	if err != nil {
+		cv.apiError("node-list")
+		cv.log.Error().Err(err).Msg("listing nodes")
+		return
+	}
+
+	metrics := make(map[string]circonus.MetricSample)
+	streamTags := []string{"source:cluster-version"}
+	_ = cv.check.QueueMetricSample(metrics, "cluster_version", circonus.MetricTypeString, streamTags, []string{}, info.GitVersion, cv.ts)
+
+	kubeletVersions := make([]string, 0, len(nodes.Items))
+	for _, node := range nodes.Items {
+		kubeletVersions = append(kubeletVersions, node.Status.NodeInfo.KubeletVersion)
+	}
+	if oldest, skew, ok := versionSkew(info.GitVersion, kubeletVersions); ok {
+		_ = cv.check.QueueMetricSample(metrics, "cluster_oldest_kubelet_version", circonus.MetricTypeString, streamTags, []string{}, oldest, cv.ts)
+		_ = cv.check.QueueMetricSample(metrics, "cluster_version_skew", circonus.MetricTypeInt32, append(streamTags, "units:minor_versions"), []string{}, int32(skew), cv.ts)
+	}
+
+	if err := cv.check.SubmitQueue(ctx, metrics, cv.log.With().Str("type", "cluster-version").Logger()); err != nil {
+		cv.log.Warn().Err(err).Msg("submitting metrics")
+	}
+}
+
+func (cv *ClusterVersion) apiError(request string) {
+	cv.check.IncrementCounter("collect_api_errors", cgm.Tags{
+		cgm.Tag{Category: "source", Value: release.NAME},
+		cgm.Tag{Category: "request", Value: request},
+		cgm.Tag{Category: "target", Value: "api-server"},
+	})
+}
+
+// versionSkew returns the oldest kubelet version and the number of minor versions it is
+// behind the api-server, false if the versions cannot be parsed
+func versionSkew(serverVersion string, kubeletVersions []string) (string, int, bool) {
+	srvMajor, srvMinor, ok := majorMinor(serverVersion)
+	if !ok {
+		return "", 0, false
+	}
+	oldest := ""
+	oldMajor, oldMinor := 0, 0
+	for _, v := range kubeletVersions {
+		major, minor, ok := majorMinor(v)
+		if !ok {
+			continue
+		}
+		if oldest == "" || major < oldMajor || (major == oldMajor && minor < oldMinor) {
+			oldest, oldMajor, oldMinor = v, major, minor
+		}
+	}
+	if oldest == "" || oldMajor != srvMajor {
+		return "", 0, false
+	}
+	return oldest, srvMinor - oldMinor, true
+}
+
+// majorMinor returns the major and minor of a kubernetes version (e.g. v1.27.4-eks-2d98532 is 1, 27)
+func majorMinor(version string) (int, int, bool) {
+	parts := strings.SplitN(strings.TrimPrefix(version, "v"), ".", 3)
+	if len(parts) < 2 {
+		return 0, 0, false
+	}
+	major, err := strconv.Atoi(parts[0])
+	if err != nil {
+		return 0, 0, false
+	}
+	minor, err := strconv.Atoi(strings.TrimRight(parts[1], "+")) // e.g. gke 1.27+
+	if err != nil {
+		return 0, 0, false
+	}
+	return major, minor, true
+}
@@ -0,0 +1,33 @@
+// Copyright © 2019 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+package clusterversion
+
+import "testing"
+
+func TestVersionSkew(t *testing.T) {
+	tests := []struct {
+		name     string
+		server   string
+		kubelets []string
+		oldest   string
+		skew     int
+		ok       bool
+	}{
+		{"same", "v1.27.4", []string{"v1.27.4", "v1.27.4"}, "v1.27.4", 0, true},
+		{"upgrading", "v1.28.2-eks-2d98532", []string{"v1.28.2-eks-2d98532", "v1.26.9-eks-1a2b3c4", "v1.27.5-eks-1a2b3c4"}, "v1.26.9-eks-1a2b3c4", 2, true},
+		{"kubelet newer", "v1.27.4", []string{"v1.28.1"}, "v1.28.1", -1, true},
+		{"no nodes", "v1.27.4", nil, "", 0, false},
+		{"invalid server", "unknown", []string{"v1.27.4"}, "", 0, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			oldest, skew, ok := versionSkew(tt.server, tt.kubelets)
+			if oldest != tt.oldest || skew != tt.skew || ok != tt.ok {
+				t.Errorf("versionSkew() = %q, %d, %v, want %q, %d, %v", oldest, skew, ok, tt.oldest, tt.skew, tt.ok)
+			}
+		})
+	}
+}
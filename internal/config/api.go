@@ -8,6 +8,7 @@ package config
 import (
 	"io/ioutil"
 	"net/url"
+	"strings"
 
 	"github.com/circonus-labs/circonus-kubernetes-agent/internal/config/defaults"
 	"github.com/circonus-labs/circonus-kubernetes-agent/internal/config/keys"
@@ -15,11 +16,18 @@ import (
 	"github.com/spf13/viper"
 )
 
-func validateAPIOptions(apiKey, apiKeyFile, apiApp, apiURL, apiCAFile string) error {
-	if apiKeyFile == "" && apiKey == "" {
+func validateAPIOptions(apiKey, apiKeyFile, apiKeySecret, apiApp, apiURL, apiCAFile string) error {
+	if apiKeyFile == "" && apiKey == "" && apiKeySecret == "" {
 		return errors.New("API key is required")
 	}
 
+	// the key is read from the secret when the cluster is initialized
+	if apiKeySecret != "" {
+		if _, _, _, err := ParseSecretRef(apiKeySecret); err != nil {
+			return err
+		}
+	}
+
 	if apiApp == "" {
 		return errors.New("API app is required")
 	}
@@ -28,7 +36,7 @@ func validateAPIOptions(apiKey, apiKeyFile, apiApp, apiURL, apiCAFile string) er
 		return errors.New("API URL is required")
 	}
 
-	if apiKeyFile != "" && apiKey == "" {
+	if apiKeyFile != "" && apiKey == "" && apiKeySecret == "" {
 		f, err := verifyFile(apiKeyFile)
 		if err != nil {
 			return err
@@ -62,9 +70,30 @@ func validateAPIOptions(apiKey, apiKeyFile, apiApp, apiURL, apiCAFile string) er
 		viper.Set(keys.APICAFile, f)
 	}
 
-	viper.Set(keys.APITokenKey, apiKey)
+	// with a secret the key is read when the cluster is initialized
+	if apiKey != "" {
+		viper.Set(keys.APITokenKey, apiKey)
+	}
 	viper.Set(keys.APITokenApp, apiApp)
 	viper.Set(keys.APIURL, apiURL)
 
 	return nil
 }
+
+// ParseSecretRef parses a kubernetes secret key reference (namespace/name/key)
+func ParseSecretRef(ref string) (string, string, string, error) {
+	parts := strings.Split(ref, "/")
+	if len(parts) != 3 || parts[0] == "" || parts[1] == "" || parts[2] == "" {
+		return "", "", "", errors.Errorf("invalid secret reference (%s), expected namespace/name/key", ref)
+	}
+	return parts[0], parts[1], parts[2], nil
+}
+
+// ParseObjectRef parses a kubernetes object reference (namespace/name)
+func ParseObjectRef(ref string) (string, string, error) {
+	parts := strings.Split(ref, "/")
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return "", "", errors.Errorf("invalid object reference (%s), expected namespace/name", ref)
+	}
+	return parts[0], parts[1], nil
+}
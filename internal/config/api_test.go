@@ -11,11 +11,12 @@ import (
 
 func Test_validateAPIOptions(t *testing.T) {
 	type args struct {
-		apiKey     string
-		apiKeyFile string
-		apiApp     string
-		apiURL     string
-		apiCAFile  string
+		apiKey       string
+		apiKeyFile   string
+		apiKeySecret string
+		apiApp       string
+		apiURL       string
+		apiCAFile    string
 	}
 	tests := []struct {
 		name    string
@@ -31,12 +32,14 @@ func Test_validateAPIOptions(t *testing.T) {
 		{"invalid url", args{apiKey: "test-key", apiApp: "test-app", apiURL: "foo"}, true},
 		{"invalid url", args{apiKey: "test-key", apiApp: "test-app", apiURL: "foo_bar://herp/derp"}, true},
 		{"invalid (missing ca file)", args{apiKey: "test-key", apiApp: "test-app", apiURL: "http://foo.com/bar", apiCAFile: "testdtaa/missing"}, true},
+		{"invalid (key secret)", args{apiKeySecret: "default/cka-secrets-v1", apiApp: "test-app", apiURL: "http://foo.com/bar"}, true},
 		{"valid", args{apiKey: "test-key", apiApp: "test-app", apiURL: "http://foo.com/bar"}, false},
+		{"valid (key secret)", args{apiKeySecret: "default/cka-secrets-v1/circonus-api-key", apiApp: "test-app", apiURL: "http://foo.com/bar"}, false},
 	}
 	for _, tt := range tests {
 		tt := tt
 		t.Run(tt.name, func(t *testing.T) {
-			if err := validateAPIOptions(tt.args.apiKey, tt.args.apiKeyFile, tt.args.apiApp, tt.args.apiURL, tt.args.apiCAFile); (err != nil) != tt.wantErr {
+			if err := validateAPIOptions(tt.args.apiKey, tt.args.apiKeyFile, tt.args.apiKeySecret, tt.args.apiApp, tt.args.apiURL, tt.args.apiCAFile); (err != nil) != tt.wantErr {
 				t.Errorf("validateAPIOptions() error = %v, wantErr %v", err, tt.wantErr)
 			}
 		})
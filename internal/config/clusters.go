@@ -0,0 +1,123 @@
+// Copyright © 2019 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+package config
+
+import (
+	"path/filepath"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// NOTES:
+// With clusters (config file only) one agent collects from several clusters, each with
+// its own api url and credentials (e.g. a central deployment monitoring a fleet of small
+// clusters). Each cluster gets its own check, found or created by the cluster's target
+// (the cluster name unless set in the cluster's circonus settings). The circonus settings
+// are shared by all clusters, a cluster's circonus section overrides the api token (e.g.
+// clusters in different accounts) and check settings. The check bundle cid, self check,
+// shard, and destination targets and bundle cids are specific to a cluster, they are only
+// taken from the cluster's circonus section, otherwise derived from the cluster's check.
+// Spool and dead-letter dirs get a subdirectory per cluster.
+
+// ValidateClusters verifies the clusters have unique names and checks
+func ValidateClusters(clusters []Cluster) error {
+	names := make(map[string]bool)
+	targets := make(map[string]string)
+	for i, cluster := range clusters {
+		if cluster.Name == "" {
+			return errors.Errorf("invalid cluster %d, name is required", i)
+		}
+		if names[cluster.Name] {
+			return errors.Errorf("invalid cluster %s, duplicate name", cluster.Name)
+		}
+		names[cluster.Name] = true
+
+		target := clusterTarget(cluster)
+		if other, ok := targets[target]; ok {
+			return errors.Errorf("invalid cluster %s, check target %s used by cluster %s", cluster.Name, target, other)
+		}
+		targets[target] = cluster.Name
+	}
+	return nil
+}
+
+// ClusterCirconusConfig returns the circonus configuration of one of multiple clusters
+func ClusterCirconusConfig(shared Circonus, cluster Cluster) Circonus {
+	cfg := shared
+
+	cc := cluster.Circonus
+	if cc.API.Key != "" || cc.API.KeySecret != "" {
+		cfg.API.Key = cc.API.Key
+		cfg.API.KeySecret = cc.API.KeySecret
+	}
+	if cc.API.App != "" {
+		cfg.API.App = cc.API.App
+	}
+	if cc.API.URL != "" {
+		cfg.API.URL = cc.API.URL
+	}
+	if cc.API.CAFile != "" {
+		cfg.API.CAFile = cc.API.CAFile
+	}
+
+	cfg.Check.BundleCID = cc.Check.BundleCID
+	cfg.Check.Target = clusterTarget(cluster)
+	cfg.Check.Title = cc.Check.Title // blank, derived from the cluster name
+	if cc.Check.BrokerCID != "" {
+		cfg.Check.BrokerCID = cc.Check.BrokerCID
+	}
+	if cc.Check.BrokerSelect != "" {
+		cfg.Check.BrokerSelect = cc.Check.BrokerSelect
+	}
+	if cc.Check.BrokerCAFile != "" {
+		cfg.Check.BrokerCAFile = cc.Check.BrokerCAFile
+	}
+	if cc.Check.BrokerCert != "" {
+		cfg.Check.BrokerCert = cc.Check.BrokerCert
+		cfg.Check.BrokerKey = cc.Check.BrokerKey
+	}
+	if cc.Check.MetricFilters != "" {
+		cfg.Check.MetricFilters = cc.Check.MetricFilters
+	}
+	if cc.Check.Tags != "" {
+		cfg.Check.Tags = cc.Check.Tags
+	}
+
+	cfg.SelfCheck.BundleCID = ""
+	cfg.SelfCheck.Target = ""
+
+	cfg.Shards = make([]Shard, len(shared.Shards))
+	for i, shard := range shared.Shards {
+		shard.Check.BundleCID = ""
+		shard.Check.Target = ""
+		cfg.Shards[i] = shard
+	}
+	cfg.Destinations = make([]Destination, len(shared.Destinations))
+	for i, dest := range shared.Destinations {
+		dest.Check.BundleCID = ""
+		dest.Check.Target = ""
+		cfg.Destinations[i] = dest
+	}
+
+	subdir := "cluster_" + cfg.Check.Target
+	if cfg.SpoolDir != "" {
+		cfg.SpoolDir = filepath.Join(cfg.SpoolDir, subdir)
+	}
+	if cfg.DeadLetterDir != "" {
+		cfg.DeadLetterDir = filepath.Join(cfg.DeadLetterDir, subdir)
+	}
+
+	return cfg
+}
+
+// clusterTarget returns the check target of one of multiple clusters
+func clusterTarget(cluster Cluster) string {
+	if cluster.Circonus.Check.Target != "" {
+		return cluster.Circonus.Check.Target
+	}
+	return strings.Replace(cluster.Name, " ", "_", -1)
+}
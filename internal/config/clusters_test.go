@@ -0,0 +1,76 @@
+// Copyright © 2019 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+package config
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestValidateClusters(t *testing.T) {
+	tests := []struct {
+		name      string
+		clusters  []Cluster
+		shouldErr bool
+	}{
+		{"valid", []Cluster{{Name: "east"}, {Name: "west"}}, false},
+		{"no name", []Cluster{{Name: "east"}, {}}, true},
+		{"duplicate name", []Cluster{{Name: "east"}, {Name: "east"}}, true},
+		{"duplicate target", []Cluster{{Name: "east 1"}, {Name: "east", Circonus: ClusterCirconus{Check: Check{Target: "east_1"}}}}, true},
+	}
+
+	for _, tt := range tests {
+		err := ValidateClusters(tt.clusters)
+		if tt.shouldErr && err == nil {
+			t.Fatalf("%s: expected error", tt.name)
+		}
+		if !tt.shouldErr && err != nil {
+			t.Fatalf("%s: unexpected error (%s)", tt.name, err)
+		}
+	}
+}
+
+func TestClusterCirconusConfig(t *testing.T) {
+	shared := Circonus{
+		API:          API{Key: "shared", App: "cka", URL: "https://api.circonus.com/v2/"},
+		Check:        Check{BundleCID: "/check_bundle/1", BrokerCID: "/broker/1", Target: "fleet", Title: "fleet", Tags: "a:b"},
+		Shards:       []Shard{{Name: "ksm", Check: Check{Target: "fleet_ksm"}}},
+		Destinations: []Destination{{Name: "mirror", Check: Check{BundleCID: "/check_bundle/2"}}},
+		SelfCheck:    SelfCheck{Enable: true, Target: "fleet_agent"},
+		SpoolDir:     "/spool",
+	}
+
+	cfg := ClusterCirconusConfig(shared, Cluster{Name: "edge 1"})
+	if cfg.API.Key != "shared" || cfg.Check.BrokerCID != "/broker/1" || cfg.Check.Tags != "a:b" {
+		t.Fatalf("expected shared settings, got %+v", cfg)
+	}
+	if cfg.Check.BundleCID != "" || cfg.Check.Target != "edge_1" || cfg.Check.Title != "" {
+		t.Fatalf("expected check derived from the cluster, got %+v", cfg.Check)
+	}
+	if cfg.Shards[0].Check.Target != "" || cfg.Destinations[0].Check.BundleCID != "" || cfg.SelfCheck.Target != "" {
+		t.Fatal("expected shard, destination, and self check targets to be derived from the cluster's check")
+	}
+	if cfg.SpoolDir != filepath.Join("/spool", "cluster_edge_1") || cfg.DeadLetterDir != "" {
+		t.Fatalf("unexpected spool %q dead-letter %q dirs", cfg.SpoolDir, cfg.DeadLetterDir)
+	}
+	if shared.Shards[0].Check.Target != "fleet_ksm" || shared.Destinations[0].Check.BundleCID != "/check_bundle/2" {
+		t.Fatal("expected shared config to be unchanged")
+	}
+
+	cfg = ClusterCirconusConfig(shared, Cluster{
+		Name: "edge 2",
+		Circonus: ClusterCirconus{
+			API:   API{Key: "edge"},
+			Check: Check{BundleCID: "/check_bundle/3", Target: "edge", Title: "edge cluster"},
+		},
+	})
+	if cfg.API.Key != "edge" || cfg.API.App != "cka" {
+		t.Fatalf("unexpected api config %+v", cfg.API)
+	}
+	if cfg.Check.BundleCID != "/check_bundle/3" || cfg.Check.Target != "edge" || cfg.Check.Title != "edge cluster" {
+		t.Fatalf("unexpected check config %+v", cfg.Check)
+	}
+}
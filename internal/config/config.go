@@ -26,33 +26,188 @@ type Config struct {
 	Clusters   []Cluster `json:"clusters" toml:"clusters" yaml:"clusters"`       // multiple clusters (use kubernetes OR clusters, not both)
 	Debug      bool      `json:"debug" toml:"debug" yaml:"debug"`                // global debugging
 	Log        Log       `json:"log" toml:"log" yaml:"log"`                      // logging options
+	// reload the configuration when the config file changes (also reloaded on SIGHUP)
+	WatchConfig bool `mapstructure:"watch_config" json:"watch_config" toml:"watch_config" yaml:"watch_config"`
 }
 
 // Cluster defines the kubernetes cluster configuration options
 type Cluster struct {
-	BearerToken            string `mapstructure:"bearer_token" json:"bearer_token" toml:"bearer_token" yaml:"bearer_token"`
-	BearerTokenFile        string `mapstructure:"bearer_token_file" json:"bearer_token_file" toml:"bearer_token_file" yaml:"bearer_token_file"`
-	EnableEvents           bool   `mapstructure:"enable_events" json:"enable_events" toml:"enable_events" yaml:"enable_events"`
-	EnableKubeStateMetrics bool   `mapstructure:"enable_kube_state_metrics" json:"enable_kube_state_metrics" toml:"enable_kube_state_metrics" yaml:"enable_kube_state_metrics"`
-	KSMMetricsPortName     string `mapstructure:"ksm_metrics_port_name" json:"ksm_metrics_port_name" toml:"ksm_metrics_port_name" yaml:"ksm_metrics_port_name"`
-	KSMTelemetryPortName   string `mapstructure:"ksm_telemetry_port_name" json:"ksm_telemetry_port_name" toml:"ksm_telemetry_port_name" yaml:"ksm_telemetry_port_name"`
-	EnableMetricServer     bool   `mapstructure:"enable_metrics_server" json:"enable_metrics_server" toml:"enable_metrics_server" yaml:"enable_metrics_server"`
-	EnableNodes            bool   `mapstructure:"enable_nodes" json:"enable_nodes" toml:"enable_nodes" yaml:"enable_nodes"`
-	NodeSelector           string `mapstructure:"node_selector" json:"node_selector" toml:"node_selector" yaml:"node_selector"`
-	EnableNodeStats        bool   `mapstructure:"enable_node_stats" json:"enable_node_stats" toml:"enable_node_stats" yaml:"enable_node_stats"`
-	EnableNodeMetrics      bool   `mapstructure:"enable_node_metrics" json:"enable_node_metrics" toml:"enable_node_metrics" yaml:"enable_node_metrics"`
-	EnableCadvisorMetrics  bool   `mapstructure:"enable_cadvisor_metrics" json:"enable_cadvisor_metrics" toml:"enable_cadvisor_metrics" yaml:"enable_cadvisor_metrics"`
-	EnableKubeDNSMetrics   bool   `mapstructure:"enable_kube_dns_metrics" json:"enable_kube_dns_metrics" toml:"enable_kube_dns_metrics" yaml:"enable_kube_dns_metrics"`
-	IncludeContainers      bool   `mapstructure:"include_container_metrics" json:"include_container_metrics" toml:"include_container_metrics" yaml:"include_container_metrics"`
-	IncludePods            bool   `mapstructure:"include_pod_metrics" json:"include_pod_metrics" toml:"include_pod_metrics" yaml:"include_pod_metrics"`
-	PodLabelKey            string `mapstructure:"pod_label_key" json:"pod_label_key" toml:"pod_label" yaml:"pod_label_key"`
-	PodLabelVal            string `mapstructure:"pod_label_val" json:"pod_label_val" toml:"pod_label" yaml:"pod_label_val"`
-	Name                   string `json:"name" toml:"name" yaml:"name"`
-	Interval               string `json:"interval" toml:"interval" yaml:"interval"`
-	NodePoolSize           uint   `mapstructure:"node_pool_size" json:"node_pool_size" toml:"node_pool_size" yaml:"node_pool_size"`
-	URL                    string `mapstructure:"api_url" json:"api_url" toml:"api_url" yaml:"api_url"`
-	CAFile                 string `mapstructure:"api_ca_file" json:"api_ca_file" toml:"api_ca_file" yaml:"api_ca_file"`
-	APITimelimit           string `mapstructure:"api_timelimit" json:"api_timelimit" toml:"api_timelimit" yaml:"api_timelimit"`
+	BearerToken                     string `mapstructure:"bearer_token" json:"bearer_token" toml:"bearer_token" yaml:"bearer_token"`
+	BearerTokenFile                 string `mapstructure:"bearer_token_file" json:"bearer_token_file" toml:"bearer_token_file" yaml:"bearer_token_file"`
+	EnableEvents                    bool   `mapstructure:"enable_events" json:"enable_events" toml:"enable_events" yaml:"enable_events"`
+	EnableKubeStateMetrics          bool   `mapstructure:"enable_kube_state_metrics" json:"enable_kube_state_metrics" toml:"enable_kube_state_metrics" yaml:"enable_kube_state_metrics"`
+	KSMMetricsPortName              string `mapstructure:"ksm_metrics_port_name" json:"ksm_metrics_port_name" toml:"ksm_metrics_port_name" yaml:"ksm_metrics_port_name"`
+	KSMTelemetryPortName            string `mapstructure:"ksm_telemetry_port_name" json:"ksm_telemetry_port_name" toml:"ksm_telemetry_port_name" yaml:"ksm_telemetry_port_name"`
+	EnableMetricServer              bool   `mapstructure:"enable_metrics_server" json:"enable_metrics_server" toml:"enable_metrics_server" yaml:"enable_metrics_server"`
+	EnableNodes                     bool   `mapstructure:"enable_nodes" json:"enable_nodes" toml:"enable_nodes" yaml:"enable_nodes"`
+	NodeSelector                    string `mapstructure:"node_selector" json:"node_selector" toml:"node_selector" yaml:"node_selector"`
+	PodSelector                     string `mapstructure:"pod_selector" json:"pod_selector" toml:"pod_selector" yaml:"pod_selector"`
+	EnableNodeStats                 bool   `mapstructure:"enable_node_stats" json:"enable_node_stats" toml:"enable_node_stats" yaml:"enable_node_stats"`
+	EnableNodeMetrics               bool   `mapstructure:"enable_node_metrics" json:"enable_node_metrics" toml:"enable_node_metrics" yaml:"enable_node_metrics"`
+	EnableKubeletOperationalMetrics bool   `mapstructure:"enable_kubelet_operational_metrics" json:"enable_kubelet_operational_metrics" toml:"enable_kubelet_operational_metrics" yaml:"enable_kubelet_operational_metrics"`
+	EnableVolumeStats               bool   `mapstructure:"enable_volume_stats" json:"enable_volume_stats" toml:"enable_volume_stats" yaml:"enable_volume_stats"`
+	EnableNodeNetworkSaturation     bool   `mapstructure:"enable_node_network_saturation" json:"enable_node_network_saturation" toml:"enable_node_network_saturation" yaml:"enable_node_network_saturation"`
+	EnableCadvisorMetrics           bool   `mapstructure:"enable_cadvisor_metrics" json:"enable_cadvisor_metrics" toml:"enable_cadvisor_metrics" yaml:"enable_cadvisor_metrics"`
+	CadvisorMetricsFilter           string `mapstructure:"cadvisor_metrics_filter" json:"cadvisor_metrics_filter" toml:"cadvisor_metrics_filter" yaml:"cadvisor_metrics_filter"`
+	EnableKubeDNSMetrics            bool   `mapstructure:"enable_kube_dns_metrics" json:"enable_kube_dns_metrics" toml:"enable_kube_dns_metrics" yaml:"enable_kube_dns_metrics"`
+	EnableAPIServer                 bool   `mapstructure:"enable_api_server" json:"enable_api_server" toml:"enable_api_server" yaml:"enable_api_server"`
+	EnableEtcd                      bool   `mapstructure:"enable_etcd" json:"enable_etcd" toml:"enable_etcd" yaml:"enable_etcd"`
+	EtcdEndpoints                   string `mapstructure:"etcd_endpoints" json:"etcd_endpoints" toml:"etcd_endpoints" yaml:"etcd_endpoints"`
+	EtcdCAFile                      string `mapstructure:"etcd_ca_file" json:"etcd_ca_file" toml:"etcd_ca_file" yaml:"etcd_ca_file"`
+	EtcdCertFile                    string `mapstructure:"etcd_cert_file" json:"etcd_cert_file" toml:"etcd_cert_file" yaml:"etcd_cert_file"`
+	EtcdKeyFile                     string `mapstructure:"etcd_key_file" json:"etcd_key_file" toml:"etcd_key_file" yaml:"etcd_key_file"`
+	EnableKubeScheduler             bool   `mapstructure:"enable_kube_scheduler" json:"enable_kube_scheduler" toml:"enable_kube_scheduler" yaml:"enable_kube_scheduler"`
+	KubeSchedulerNamespace          string `mapstructure:"kube_scheduler_namespace" json:"kube_scheduler_namespace" toml:"kube_scheduler_namespace" yaml:"kube_scheduler_namespace"`
+	KubeSchedulerSelector           string `mapstructure:"kube_scheduler_selector" json:"kube_scheduler_selector" toml:"kube_scheduler_selector" yaml:"kube_scheduler_selector"`
+	KubeSchedulerPort               string `mapstructure:"kube_scheduler_port" json:"kube_scheduler_port" toml:"kube_scheduler_port" yaml:"kube_scheduler_port"`
+	KubeSchedulerEndpoint           string `mapstructure:"kube_scheduler_endpoint" json:"kube_scheduler_endpoint" toml:"kube_scheduler_endpoint" yaml:"kube_scheduler_endpoint"`
+	EnableKubeControllerManager     bool   `mapstructure:"enable_kube_controller_manager" json:"enable_kube_controller_manager" toml:"enable_kube_controller_manager" yaml:"enable_kube_controller_manager"`
+	KubeControllerManagerNamespace  string `mapstructure:"kube_controller_manager_namespace" json:"kube_controller_manager_namespace" toml:"kube_controller_manager_namespace" yaml:"kube_controller_manager_namespace"`
+	KubeControllerManagerSelector   string `mapstructure:"kube_controller_manager_selector" json:"kube_controller_manager_selector" toml:"kube_controller_manager_selector" yaml:"kube_controller_manager_selector"`
+	KubeControllerManagerPort       string `mapstructure:"kube_controller_manager_port" json:"kube_controller_manager_port" toml:"kube_controller_manager_port" yaml:"kube_controller_manager_port"`
+	EnableKubeProxy                 bool   `mapstructure:"enable_kube_proxy" json:"enable_kube_proxy" toml:"enable_kube_proxy" yaml:"enable_kube_proxy"`
+	KubeProxyNamespace              string `mapstructure:"kube_proxy_namespace" json:"kube_proxy_namespace" toml:"kube_proxy_namespace" yaml:"kube_proxy_namespace"`
+	KubeProxySelector               string `mapstructure:"kube_proxy_selector" json:"kube_proxy_selector" toml:"kube_proxy_selector" yaml:"kube_proxy_selector"`
+	KubeProxyPort                   string `mapstructure:"kube_proxy_port" json:"kube_proxy_port" toml:"kube_proxy_port" yaml:"kube_proxy_port"`
+	EnableNodeLocalDNS              bool   `mapstructure:"enable_node_local_dns" json:"enable_node_local_dns" toml:"enable_node_local_dns" yaml:"enable_node_local_dns"`
+	NodeLocalDNSNamespace           string `mapstructure:"node_local_dns_namespace" json:"node_local_dns_namespace" toml:"node_local_dns_namespace" yaml:"node_local_dns_namespace"`
+	NodeLocalDNSSelector            string `mapstructure:"node_local_dns_selector" json:"node_local_dns_selector" toml:"node_local_dns_selector" yaml:"node_local_dns_selector"`
+	NodeLocalDNSPort                string `mapstructure:"node_local_dns_port" json:"node_local_dns_port" toml:"node_local_dns_port" yaml:"node_local_dns_port"`
+	EnablePromScrape                bool   `mapstructure:"enable_prom_scrape" json:"enable_prom_scrape" toml:"enable_prom_scrape" yaml:"enable_prom_scrape"`
+	PromScrapeNamespace             string `mapstructure:"prom_scrape_namespace" json:"prom_scrape_namespace" toml:"prom_scrape_namespace" yaml:"prom_scrape_namespace"`
+	EnablePromMonitors              bool   `mapstructure:"enable_prom_monitors" json:"enable_prom_monitors" toml:"enable_prom_monitors" yaml:"enable_prom_monitors"`
+	PromMonitorsNamespace           string `mapstructure:"prom_monitors_namespace" json:"prom_monitors_namespace" toml:"prom_monitors_namespace" yaml:"prom_monitors_namespace"`
+	EnableIngressNginx              bool   `mapstructure:"enable_ingress_nginx" json:"enable_ingress_nginx" toml:"enable_ingress_nginx" yaml:"enable_ingress_nginx"`
+	IngressNginxNamespace           string `mapstructure:"ingress_nginx_namespace" json:"ingress_nginx_namespace" toml:"ingress_nginx_namespace" yaml:"ingress_nginx_namespace"`
+	IngressNginxSelector            string `mapstructure:"ingress_nginx_selector" json:"ingress_nginx_selector" toml:"ingress_nginx_selector" yaml:"ingress_nginx_selector"`
+	IngressNginxPort                string `mapstructure:"ingress_nginx_port" json:"ingress_nginx_port" toml:"ingress_nginx_port" yaml:"ingress_nginx_port"`
+	EnableCertManager               bool   `mapstructure:"enable_cert_manager" json:"enable_cert_manager" toml:"enable_cert_manager" yaml:"enable_cert_manager"`
+	CertManagerNamespace            string `mapstructure:"cert_manager_namespace" json:"cert_manager_namespace" toml:"cert_manager_namespace" yaml:"cert_manager_namespace"`
+	CertManagerSelector             string `mapstructure:"cert_manager_selector" json:"cert_manager_selector" toml:"cert_manager_selector" yaml:"cert_manager_selector"`
+	CertManagerPort                 string `mapstructure:"cert_manager_port" json:"cert_manager_port" toml:"cert_manager_port" yaml:"cert_manager_port"`
+	EnableIstio                     bool   `mapstructure:"enable_istio" json:"enable_istio" toml:"enable_istio" yaml:"enable_istio"`
+	IstioNamespaces                 string `mapstructure:"istio_namespaces" json:"istio_namespaces" toml:"istio_namespaces" yaml:"istio_namespaces"`
+	IstioSelector                   string `mapstructure:"istio_selector" json:"istio_selector" toml:"istio_selector" yaml:"istio_selector"`
+	IstioPort                       string `mapstructure:"istio_port" json:"istio_port" toml:"istio_port" yaml:"istio_port"`
+	EnableLinkerd                   bool   `mapstructure:"enable_linkerd" json:"enable_linkerd" toml:"enable_linkerd" yaml:"enable_linkerd"`
+	LinkerdNamespaces               string `mapstructure:"linkerd_namespaces" json:"linkerd_namespaces" toml:"linkerd_namespaces" yaml:"linkerd_namespaces"`
+	LinkerdSelector                 string `mapstructure:"linkerd_selector" json:"linkerd_selector" toml:"linkerd_selector" yaml:"linkerd_selector"`
+	LinkerdPort                     string `mapstructure:"linkerd_port" json:"linkerd_port" toml:"linkerd_port" yaml:"linkerd_port"`
+	EnableDCGM                      bool   `mapstructure:"enable_dcgm" json:"enable_dcgm" toml:"enable_dcgm" yaml:"enable_dcgm"`
+	DCGMNamespace                   string `mapstructure:"dcgm_namespace" json:"dcgm_namespace" toml:"dcgm_namespace" yaml:"dcgm_namespace"`
+	DCGMSelector                    string `mapstructure:"dcgm_selector" json:"dcgm_selector" toml:"dcgm_selector" yaml:"dcgm_selector"`
+	DCGMPort                        string `mapstructure:"dcgm_port" json:"dcgm_port" toml:"dcgm_port" yaml:"dcgm_port"`
+	EnableStorageInventory          bool   `mapstructure:"enable_storage_inventory" json:"enable_storage_inventory" toml:"enable_storage_inventory" yaml:"enable_storage_inventory"`
+	EnableHPA                       bool   `mapstructure:"enable_hpa" json:"enable_hpa" toml:"enable_hpa" yaml:"enable_hpa"`
+	EnableJobs                      bool   `mapstructure:"enable_jobs" json:"enable_jobs" toml:"enable_jobs" yaml:"enable_jobs"`
+	EnableDeployments               bool   `mapstructure:"enable_deployments" json:"enable_deployments" toml:"enable_deployments" yaml:"enable_deployments"`
+	EnableStatefulSets              bool   `mapstructure:"enable_statefulsets" json:"enable_statefulsets" toml:"enable_statefulsets" yaml:"enable_statefulsets"`
+	EnableDaemonSets                bool   `mapstructure:"enable_daemonsets" json:"enable_daemonsets" toml:"enable_daemonsets" yaml:"enable_daemonsets"`
+	EnableEndpoints                 bool   `mapstructure:"enable_endpoints" json:"enable_endpoints" toml:"enable_endpoints" yaml:"enable_endpoints"`
+	EnableCustomResources           bool   `mapstructure:"enable_custom_resources" json:"enable_custom_resources" toml:"enable_custom_resources" yaml:"enable_custom_resources"`
+	CustomResourcesFile             string `mapstructure:"custom_resources_file" json:"custom_resources_file" toml:"custom_resources_file" yaml:"custom_resources_file"`
+	EnableNodeProblemConditions     bool   `mapstructure:"enable_node_problem_conditions" json:"enable_node_problem_conditions" toml:"enable_node_problem_conditions" yaml:"enable_node_problem_conditions"`
+	EnableContainerRestarts         bool   `mapstructure:"enable_container_restarts" json:"enable_container_restarts" toml:"enable_container_restarts" yaml:"enable_container_restarts"`
+	EnablePodPhases                 bool   `mapstructure:"enable_pod_phases" json:"enable_pod_phases" toml:"enable_pod_phases" yaml:"enable_pod_phases"`
+	EnableImagePulls                bool   `mapstructure:"enable_image_pulls" json:"enable_image_pulls" toml:"enable_image_pulls" yaml:"enable_image_pulls"`
+	EnableClusterAutoscaler         bool   `mapstructure:"enable_cluster_autoscaler" json:"enable_cluster_autoscaler" toml:"enable_cluster_autoscaler" yaml:"enable_cluster_autoscaler"`
+	ClusterAutoscalerNamespace      string `mapstructure:"cluster_autoscaler_namespace" json:"cluster_autoscaler_namespace" toml:"cluster_autoscaler_namespace" yaml:"cluster_autoscaler_namespace"`
+	ClusterAutoscalerSelector       string `mapstructure:"cluster_autoscaler_selector" json:"cluster_autoscaler_selector" toml:"cluster_autoscaler_selector" yaml:"cluster_autoscaler_selector"`
+	ClusterAutoscalerPort           string `mapstructure:"cluster_autoscaler_port" json:"cluster_autoscaler_port" toml:"cluster_autoscaler_port" yaml:"cluster_autoscaler_port"`
+	EnableKarpenter                 bool   `mapstructure:"enable_karpenter" json:"enable_karpenter" toml:"enable_karpenter" yaml:"enable_karpenter"`
+	KarpenterNamespace              string `mapstructure:"karpenter_namespace" json:"karpenter_namespace" toml:"karpenter_namespace" yaml:"karpenter_namespace"`
+	KarpenterSelector               string `mapstructure:"karpenter_selector" json:"karpenter_selector" toml:"karpenter_selector" yaml:"karpenter_selector"`
+	KarpenterPort                   string `mapstructure:"karpenter_port" json:"karpenter_port" toml:"karpenter_port" yaml:"karpenter_port"`
+	EnableVelero                    bool   `mapstructure:"enable_velero" json:"enable_velero" toml:"enable_velero" yaml:"enable_velero"`
+	VeleroNamespace                 string `mapstructure:"velero_namespace" json:"velero_namespace" toml:"velero_namespace" yaml:"velero_namespace"`
+	EnableArgoCD                    bool   `mapstructure:"enable_argocd" json:"enable_argocd" toml:"enable_argocd" yaml:"enable_argocd"`
+	ArgoCDNamespace                 string `mapstructure:"argocd_namespace" json:"argocd_namespace" toml:"argocd_namespace" yaml:"argocd_namespace"`
+	EnableFlux                      bool   `mapstructure:"enable_flux" json:"enable_flux" toml:"enable_flux" yaml:"enable_flux"`
+	FluxNamespace                   string `mapstructure:"flux_namespace" json:"flux_namespace" toml:"flux_namespace" yaml:"flux_namespace"`
+	EnableScrapeTargets             bool   `mapstructure:"enable_scrape_targets" json:"enable_scrape_targets" toml:"enable_scrape_targets" yaml:"enable_scrape_targets"`
+	ScrapeTargetsFile               string `mapstructure:"scrape_targets_file" json:"scrape_targets_file" toml:"scrape_targets_file" yaml:"scrape_targets_file"`
+	EnableDeprecatedAPIs            bool   `mapstructure:"enable_deprecated_apis" json:"enable_deprecated_apis" toml:"enable_deprecated_apis" yaml:"enable_deprecated_apis"`
+	EnableTLSSecrets                bool   `mapstructure:"enable_tls_secrets" json:"enable_tls_secrets" toml:"enable_tls_secrets" yaml:"enable_tls_secrets"`
+	TLSSecretsNamespaces            string `mapstructure:"tls_secrets_namespaces" json:"tls_secrets_namespaces" toml:"tls_secrets_namespaces" yaml:"tls_secrets_namespaces"`
+	EnableConfigInventory           bool   `mapstructure:"enable_config_inventory" json:"enable_config_inventory" toml:"enable_config_inventory" yaml:"enable_config_inventory"`
+	ConfigInventorySizeThreshold    uint   `mapstructure:"config_inventory_size_threshold" json:"config_inventory_size_threshold" toml:"config_inventory_size_threshold" yaml:"config_inventory_size_threshold"`
+	EnableNamespaceResources        bool   `mapstructure:"enable_namespace_resources" json:"enable_namespace_resources" toml:"enable_namespace_resources" yaml:"enable_namespace_resources"`
+	EnableCRI                       bool   `mapstructure:"enable_cri" json:"enable_cri" toml:"enable_cri" yaml:"enable_cri"`
+	CRISocket                       string `mapstructure:"cri_socket" json:"cri_socket" toml:"cri_socket" yaml:"cri_socket"`
+	NodeName                        string `mapstructure:"node_name" json:"node_name" toml:"node_name" yaml:"node_name"`
+	EnablePodResources              bool   `mapstructure:"enable_pod_resources" json:"enable_pod_resources" toml:"enable_pod_resources" yaml:"enable_pod_resources"`
+	PodResourcesSocket              string `mapstructure:"pod_resources_socket" json:"pod_resources_socket" toml:"pod_resources_socket" yaml:"pod_resources_socket"`
+	EnableCost                      bool   `mapstructure:"enable_cost" json:"enable_cost" toml:"enable_cost" yaml:"enable_cost"`
+	CostPricesFile                  string `mapstructure:"cost_prices_file" json:"cost_prices_file" toml:"cost_prices_file" yaml:"cost_prices_file"`
+	CostLabels                      string `mapstructure:"cost_labels" json:"cost_labels" toml:"cost_labels" yaml:"cost_labels"`
+	EnableHeadroom                  bool   `mapstructure:"enable_headroom" json:"enable_headroom" toml:"enable_headroom" yaml:"enable_headroom"`
+	HeadroomNodePoolLabel           string `mapstructure:"headroom_node_pool_label" json:"headroom_node_pool_label" toml:"headroom_node_pool_label" yaml:"headroom_node_pool_label"`
+	EnablePDB                       bool   `mapstructure:"enable_pdb" json:"enable_pdb" toml:"enable_pdb" yaml:"enable_pdb"`
+	EnableSchedulingFailures        bool   `mapstructure:"enable_scheduling_failures" json:"enable_scheduling_failures" toml:"enable_scheduling_failures" yaml:"enable_scheduling_failures"`
+	EnableEvictions                 bool   `mapstructure:"enable_evictions" json:"enable_evictions" toml:"enable_evictions" yaml:"enable_evictions"`
+	EnableSpotInterruptions         bool   `mapstructure:"enable_spot_interruptions" json:"enable_spot_interruptions" toml:"enable_spot_interruptions" yaml:"enable_spot_interruptions"`
+	EnableManagedControlPlane       bool   `mapstructure:"enable_managed_control_plane" json:"enable_managed_control_plane" toml:"enable_managed_control_plane" yaml:"enable_managed_control_plane"`
+	ManagedControlPlaneProvider     string `mapstructure:"managed_control_plane_provider" json:"managed_control_plane_provider" toml:"managed_control_plane_provider" yaml:"managed_control_plane_provider"`
+	EnableEKSCloudWatch             bool   `mapstructure:"enable_eks_cloudwatch" json:"enable_eks_cloudwatch" toml:"enable_eks_cloudwatch" yaml:"enable_eks_cloudwatch"`
+	EKSClusterName                  string `mapstructure:"eks_cluster_name" json:"eks_cluster_name" toml:"eks_cluster_name" yaml:"eks_cluster_name"`
+	EnableNodeVersions              bool   `mapstructure:"enable_node_versions" json:"enable_node_versions" toml:"enable_node_versions" yaml:"enable_node_versions"`
+	EnableClusterVersion            bool   `mapstructure:"enable_cluster_version" json:"enable_cluster_version" toml:"enable_cluster_version" yaml:"enable_cluster_version"`
+	EnableOrphans                   bool   `mapstructure:"enable_orphans" json:"enable_orphans" toml:"enable_orphans" yaml:"enable_orphans"`
+	OrphansLingerThreshold          string `mapstructure:"orphans_linger_threshold" json:"orphans_linger_threshold" toml:"orphans_linger_threshold" yaml:"orphans_linger_threshold"`
+	EnableTopN                      bool   `mapstructure:"enable_top_n" json:"enable_top_n" toml:"enable_top_n" yaml:"enable_top_n"`
+	TopN                            uint   `mapstructure:"top_n" json:"top_n" toml:"top_n" yaml:"top_n"`
+	EnableUtilization               bool   `mapstructure:"enable_utilization" json:"enable_utilization" toml:"enable_utilization" yaml:"enable_utilization"`
+	EnableRecordingRules            bool   `mapstructure:"enable_recording_rules" json:"enable_recording_rules" toml:"enable_recording_rules" yaml:"enable_recording_rules"`
+	RecordingRulesFile              string `mapstructure:"recording_rules_file" json:"recording_rules_file" toml:"recording_rules_file" yaml:"recording_rules_file"`
+	EnableSLOs                      bool   `mapstructure:"enable_slos" json:"enable_slos" toml:"enable_slos" yaml:"enable_slos"`
+	SLOsFile                        string `mapstructure:"slos_file" json:"slos_file" toml:"slos_file" yaml:"slos_file"`
+	EnableEtcdObjects               bool   `mapstructure:"enable_etcd_objects" json:"enable_etcd_objects" toml:"enable_etcd_objects" yaml:"enable_etcd_objects"`
+	EnableAgentMetricFilters        bool   `mapstructure:"enable_agent_metric_filters" json:"enable_agent_metric_filters" toml:"enable_agent_metric_filters" yaml:"enable_agent_metric_filters"`
+	AgentMetricFiltersFile          string `mapstructure:"agent_metric_filters_file" json:"agent_metric_filters_file" toml:"agent_metric_filters_file" yaml:"agent_metric_filters_file"`
+	EnableTagTransforms             bool   `mapstructure:"enable_tag_transforms" json:"enable_tag_transforms" toml:"enable_tag_transforms" yaml:"enable_tag_transforms"`
+	TagTransformsFile               string `mapstructure:"tag_transforms_file" json:"tag_transforms_file" toml:"tag_transforms_file" yaml:"tag_transforms_file"`
+	IncludeContainers               bool   `mapstructure:"include_container_metrics" json:"include_container_metrics" toml:"include_container_metrics" yaml:"include_container_metrics"`
+	IncludePods                     bool   `mapstructure:"include_pod_metrics" json:"include_pod_metrics" toml:"include_pod_metrics" yaml:"include_pod_metrics"`
+	PodLabelKey                     string `mapstructure:"pod_label_key" json:"pod_label_key" toml:"pod_label" yaml:"pod_label_key"`
+	PodLabelVal                     string `mapstructure:"pod_label_val" json:"pod_label_val" toml:"pod_label" yaml:"pod_label_val"`
+	MetricPrefix                    string `mapstructure:"metric_prefix" json:"metric_prefix" toml:"metric_prefix" yaml:"metric_prefix"`
+	ConfigResource                  string `mapstructure:"config_resource" json:"config_resource" toml:"config_resource" yaml:"config_resource"`
+	Kubeconfig                      string `mapstructure:"kubeconfig" json:"kubeconfig" toml:"kubeconfig" yaml:"kubeconfig"`
+	KubeconfigContext               string `mapstructure:"kubeconfig_context" json:"kubeconfig_context" toml:"kubeconfig_context" yaml:"kubeconfig_context"`
+	IncludeNamespaces               string `mapstructure:"include_namespaces" json:"include_namespaces" toml:"include_namespaces" yaml:"include_namespaces"`
+	ExcludeNamespaces               string `mapstructure:"exclude_namespaces" json:"exclude_namespaces" toml:"exclude_namespaces" yaml:"exclude_namespaces"`
+	Name                            string `json:"name" toml:"name" yaml:"name"`
+	Interval                        string `json:"interval" toml:"interval" yaml:"interval"`
+	NodePoolSize                    uint   `mapstructure:"node_pool_size" json:"node_pool_size" toml:"node_pool_size" yaml:"node_pool_size"`
+	URL                             string `mapstructure:"api_url" json:"api_url" toml:"api_url" yaml:"api_url"`
+	CAFile                          string `mapstructure:"api_ca_file" json:"api_ca_file" toml:"api_ca_file" yaml:"api_ca_file"`
+	APITimelimit                    string `mapstructure:"api_timelimit" json:"api_timelimit" toml:"api_timelimit" yaml:"api_timelimit"`
+	// collector id (e.g. nodes, kube-state-metrics) interval, blank or missing is the cluster interval (config file only)
+	CollectorIntervals map[string]string `mapstructure:"collector_intervals" json:"collector_intervals" toml:"collector_intervals" yaml:"collector_intervals"`
+	// circonus settings of the cluster when collecting from multiple clusters (config file only)
+	Circonus ClusterCirconus `json:"circonus" toml:"circonus" yaml:"circonus"`
+	// provides the api bearer token when it is refreshed (e.g. an exec credential plugin), not configurable
+	TokenSource TokenSource `mapstructure:"-" json:"-" toml:"-" yaml:"-"`
+}
+
+// TokenSource provides a refreshed api bearer token
+type TokenSource interface {
+	Token() string
+}
+
+// Token returns the bearer token for api requests
+func (c *Cluster) Token() string {
+	if c.TokenSource != nil {
+		return c.TokenSource.Token()
+	}
+	return c.BearerToken
+}
+
+// ClusterCirconus defines a cluster's circonus api token and check when the agent
+// collects from multiple clusters, blank settings are taken from the circonus settings
+type ClusterCirconus struct {
+	API   API   `json:"api" toml:"api" yaml:"api"`
+	Check Check `json:"check" toml:"check" yaml:"check"` // blank target is the cluster name
 }
 
 // LabelFilters defines labels to include and exclude
@@ -63,42 +218,137 @@ type LabelFilters struct {
 
 // Circonus defines the circonus specific configuration options
 type Circonus struct {
-	API               API    `json:"api" toml:"api" yaml:"api"`
-	Check             Check  `json:"check" toml:"check" yaml:"check"`
-	TraceSubmits      string `mapstructure:"trace_submits" json:"trace_submits" toml:"trace_submits" yaml:"trace_submits"` // trace metrics being sent to circonus
-	DefaultStreamtags string `mapstructure:"default_streamtags" json:"default_streamtags" toml:"default_streamtags" yaml:"default_streamtags"`
+	API               API     `json:"api" toml:"api" yaml:"api"`
+	Check             Check   `json:"check" toml:"check" yaml:"check"`
+	Alerts            Alerts  `json:"alerts" toml:"alerts" yaml:"alerts"`
+	Proxy             Proxy   `json:"proxy" toml:"proxy" yaml:"proxy"`
+	TraceSubmits      string  `mapstructure:"trace_submits" json:"trace_submits" toml:"trace_submits" yaml:"trace_submits"` // trace metrics being sent to circonus
+	SpoolDir          string  `mapstructure:"spool_dir" json:"spool_dir" toml:"spool_dir" yaml:"spool_dir"`                 // spool failed submissions for replay, blank to disable
+	SpoolMaxSize      string  `mapstructure:"spool_max_size" json:"spool_max_size" toml:"spool_max_size" yaml:"spool_max_size"`
+	SubmitRetries     uint    `mapstructure:"submit_retries" json:"submit_retries" toml:"submit_retries" yaml:"submit_retries"`                 // max retries of a failed submission
+	SubmitBackoffMin  string  `mapstructure:"submit_backoff_min" json:"submit_backoff_min" toml:"submit_backoff_min" yaml:"submit_backoff_min"` // initial wait between retries (doubles each retry)
+	SubmitBackoffMax  string  `mapstructure:"submit_backoff_max" json:"submit_backoff_max" toml:"submit_backoff_max" yaml:"submit_backoff_max"` // max wait between retries
+	SubmitJitter      bool    `mapstructure:"submit_jitter" json:"submit_jitter" toml:"submit_jitter" yaml:"submit_jitter"`                     // randomize the wait between retries
+	SubmitMaxBodySize string  `mapstructure:"submit_max_body_size" json:"submit_max_body_size" toml:"submit_max_body_size" yaml:"submit_max_body_size"`
+	SubmitWorkers     uint    `mapstructure:"submit_workers" json:"submit_workers" toml:"submit_workers" yaml:"submit_workers"` // max in-flight submissions
+	SubmitTimeout     string  `mapstructure:"submit_timeout" json:"submit_timeout" toml:"submit_timeout" yaml:"submit_timeout"` // per-submission timeout, including retries
+	MaxMetricStreams  uint    `mapstructure:"max_metric_streams" json:"max_metric_streams" toml:"max_metric_streams" yaml:"max_metric_streams"`
+	DeadLetterDir     string  `mapstructure:"dead_letter_dir" json:"dead_letter_dir" toml:"dead_letter_dir" yaml:"dead_letter_dir"`
+	DeadLetterGzip    bool    `mapstructure:"dead_letter_compress" json:"dead_letter_compress" toml:"dead_letter_compress" yaml:"dead_letter_compress"`
+	TextOnChange      bool    `mapstructure:"text_on_change" json:"text_on_change" toml:"text_on_change" yaml:"text_on_change"`
+	TextResend        string  `mapstructure:"text_resend" json:"text_resend" toml:"text_resend" yaml:"text_resend"`
+	CounterDeltas     bool    `mapstructure:"counter_deltas" json:"counter_deltas" toml:"counter_deltas" yaml:"counter_deltas"`
+	SubmitBudget      string  `mapstructure:"submit_budget" json:"submit_budget" toml:"submit_budget" yaml:"submit_budget"`
+	SubmitCompression string  `mapstructure:"submit_compression" json:"submit_compression" toml:"submit_compression" yaml:"submit_compression"`
+	SubmitGzipLevel   uint    `mapstructure:"submit_gzip_level" json:"submit_gzip_level" toml:"submit_gzip_level" yaml:"submit_gzip_level"`
+	ForwardURL        string  `mapstructure:"forward_url" json:"forward_url" toml:"forward_url" yaml:"forward_url"`
+	ForwardStatsd     string  `mapstructure:"forward_statsd" json:"forward_statsd" toml:"forward_statsd" yaml:"forward_statsd"`
+	DryRunOutput      string  `mapstructure:"dry_run_output" json:"dry_run_output" toml:"dry_run_output" yaml:"dry_run_output"`
+	DefaultStreamtags string  `mapstructure:"default_streamtags" json:"default_streamtags" toml:"default_streamtags" yaml:"default_streamtags"`
+	CreateDashboards  bool    `mapstructure:"create_dashboards" json:"create_dashboards" toml:"create_dashboards" yaml:"create_dashboards"`
+	Shards            []Shard `json:"shards" toml:"shards" yaml:"shards"` // additional checks metrics are routed to (config file only)
+	// export metrics to other backends, in parallel with circonus
+	OTLP        OTLP        `json:"otlp" toml:"otlp" yaml:"otlp"`
+	RemoteWrite RemoteWrite `mapstructure:"remote_write" json:"remote_write" toml:"remote_write" yaml:"remote_write"`
+	// additional circonus accounts every submission is mirrored to (config file only)
+	Destinations []Destination `json:"destinations" toml:"destinations" yaml:"destinations"`
+	// the agent's own operational metrics are sent to a dedicated check
+	SelfCheck SelfCheck `mapstructure:"self_check" json:"self_check" toml:"self_check" yaml:"self_check"`
 	// hidden circonus settings for development and debugging
 	Base64Tags bool `json:"-" toml:"-" yaml:"-"` //`mapstructure:"base64_tags" json:"base64_tags" toml:"base64_tags" yaml:"base64_tags"`
 	DryRun     bool `json:"-" toml:"-" yaml:"-"` //`mapstructure:"dry_run" json:"dry_run" toml:"dry_run" yaml:"dry_run"`                             // simulate sending metrics, print them to stdout
 	// StreamMetrics         bool `json:"-" toml:"-" yaml:"-"` //`mapstructure:"stream_metrics" json:"stream_metrics" toml:"stream_metrics" yaml:"stream_metrics"` // use streaming metric submission format (applicable when using _ts)
-	UseGZIP               bool `json:"-" toml:"-" yaml:"-"` //`mapstructure:"use_gzip" json:"use_gzip" toml:"use_gzip" yaml:"use_gzip"`                         // compress metrics using gzip when submitting (broker may not support)
-	DebugSubmissions      bool `json:"-" toml:"-" yaml:"-"`
-	ConcurrentSubmissions bool `json:"-" toml:"-" yaml:"-"`
-	SerialSubmissions     bool `json:"-" toml:"-" yaml:"-"`
-	MaxMetricBucketSize   int  `json:"-" toml:"-" yaml:"-"`
+	UseGZIP             bool `json:"-" toml:"-" yaml:"-"` //`mapstructure:"use_gzip" json:"use_gzip" toml:"use_gzip" yaml:"use_gzip"`                         // compress metrics using gzip when submitting (broker may not support)
+	DebugSubmissions    bool `json:"-" toml:"-" yaml:"-"`
+	SerialSubmissions   bool `json:"-" toml:"-" yaml:"-"`
+	MaxMetricBucketSize int  `json:"-" toml:"-" yaml:"-"`
+	// set from the cluster configuration
+	MetricPrefix string `json:"-" toml:"-" yaml:"-"`
 }
 
 // API defines the circonus api configuration options
 type API struct {
-	App    string `json:"app" toml:"app" yaml:"app"`
-	CAFile string `mapstructure:"ca_file" json:"ca_file" toml:"ca_file" yaml:"ca_file"`
-	Debug  bool   `json:"debug" toml:"debug" yaml:"debug"`
-	Key    string `json:"key" toml:"key" yaml:"key"`
-	URL    string `json:"url" toml:"url" yaml:"url"`
+	App       string `json:"app" toml:"app" yaml:"app"`
+	CAFile    string `mapstructure:"ca_file" json:"ca_file" toml:"ca_file" yaml:"ca_file"`
+	Debug     bool   `json:"debug" toml:"debug" yaml:"debug"`
+	Key       string `json:"key" toml:"key" yaml:"key"`
+	KeySecret string `mapstructure:"key_secret" json:"key_secret" toml:"key_secret" yaml:"key_secret"` // namespace/name/key of a secret with the key, watched for rotation
+	URL       string `json:"url" toml:"url" yaml:"url"`
+	Timeout   string `json:"timeout" toml:"timeout" yaml:"timeout"` // api operation timeout (e.g. finding or creating the check)
 }
 
 // Check defines the circonus check configuration options
 type Check struct {
 	BrokerCID     string `mapstructure:"broker_cid" json:"broker_cid" toml:"broker_cid" yaml:"broker_cid"`
+	BrokerSelect  string `mapstructure:"broker_select" json:"broker_select" toml:"broker_select" yaml:"broker_select"`
 	BrokerCAFile  string `mapstructure:"broker_ca_file" json:"broker_ca_file" toml:"broker_ca_file" yaml:"broker_ca_file"`
+	BrokerCert    string `mapstructure:"broker_cert_file" json:"broker_cert_file" toml:"broker_cert_file" yaml:"broker_cert_file"`
+	BrokerKey     string `mapstructure:"broker_key_file" json:"broker_key_file" toml:"broker_key_file" yaml:"broker_key_file"`
 	BundleCID     string `mapstructure:"bundle_cid" json:"bundle_cid" toml:"bundle_cid" yaml:"bundle_cid"`
 	Create        bool   `mapstructure:"create" json:"create" toml:"create" yaml:"create" `
 	MetricFilters string `mapstructure:"metric_filters" json:"metric_filters" toml:"metric_filters" yaml:"metric_filters"` // needs to be json embedded in a string because rules are positional
+	FilterSync    string `mapstructure:"metric_filters_sync" json:"metric_filters_sync" toml:"metric_filters_sync" yaml:"metric_filters_sync"`
 	Tags          string `json:"tags" toml:"tags" yaml:"tags"`
 	Target        string `mapstructure:"target" json:"target" toml:"target" yaml:"target"`
 	Title         string `json:"title" toml:"title" yaml:"title"`
 }
 
+// Alerts defines the alert rule (ruleset) provisioning options
+type Alerts struct {
+	Create             bool    `json:"create" toml:"create" yaml:"create"`
+	ContactGroup       string  `mapstructure:"contact_group" json:"contact_group" toml:"contact_group" yaml:"contact_group"`                         // contact group cid notified, blank for none
+	NodeNotReadyWait   uint    `mapstructure:"node_not_ready_wait" json:"node_not_ready_wait" toml:"node_not_ready_wait" yaml:"node_not_ready_wait"` // minutes
+	CrashLoopWait      uint    `mapstructure:"crashloop_wait" json:"crashloop_wait" toml:"crashloop_wait" yaml:"crashloop_wait"`                     // minutes
+	PVCUsedPercent     float64 `mapstructure:"pvc_used_percent" json:"pvc_used_percent" toml:"pvc_used_percent" yaml:"pvc_used_percent"`
+	APIServerErrorRate float64 `mapstructure:"apiserver_error_rate" json:"apiserver_error_rate" toml:"apiserver_error_rate" yaml:"apiserver_error_rate"` // 5xx responses per second
+}
+
+// Proxy defines the proxy used for circonus api requests and broker submissions
+type Proxy struct {
+	URL     string `json:"url" toml:"url" yaml:"url"`                                        // http, https, or socks5 proxy url, blank to use the environment (HTTPS_PROXY)
+	NoProxy string `mapstructure:"no_proxy" json:"no_proxy" toml:"no_proxy" yaml:"no_proxy"` // comma delimited hosts, domains, and cidrs not proxied
+}
+
+// OTLP defines the OpenTelemetry (OTLP/gRPC) metrics export options
+type OTLP struct {
+	Endpoint string `json:"endpoint" toml:"endpoint" yaml:"endpoint"` // host:port, blank disables
+	Insecure bool   `json:"insecure" toml:"insecure" yaml:"insecure"` // plaintext, no tls
+	Timeout  string `json:"timeout" toml:"timeout" yaml:"timeout"`
+}
+
+// RemoteWrite defines the prometheus remote write export options
+type RemoteWrite struct {
+	URL             string `json:"url" toml:"url" yaml:"url"` // blank disables
+	Timeout         string `json:"timeout" toml:"timeout" yaml:"timeout"`
+	BearerTokenFile string `mapstructure:"bearer_token_file" json:"bearer_token_file" toml:"bearer_token_file" yaml:"bearer_token_file"`
+}
+
+// Shard defines an additional check metrics are routed to, metrics matching any of
+// the routes (namespaces, metric prefixes, or collectors) are sent to the shard's check
+type Shard struct {
+	Name           string   `json:"name" toml:"name" yaml:"name"`
+	Check          Check    `json:"check" toml:"check" yaml:"check"`                                                              // blank target and title are derived from the primary check
+	Namespaces     []string `json:"namespaces" toml:"namespaces" yaml:"namespaces"`                                               // namespace stream tag values
+	MetricPrefixes []string `mapstructure:"metric_prefixes" json:"metric_prefixes" toml:"metric_prefixes" yaml:"metric_prefixes"` // metric name prefixes
+	Collectors     []string `json:"collectors" toml:"collectors" yaml:"collectors"`                                               // source stream tag values (e.g. kube-state-metrics)
+}
+
+// Destination defines an additional circonus account (api token and check) all
+// metrics are submitted to
+type Destination struct {
+	Name  string `json:"name" toml:"name" yaml:"name"`
+	API   API    `json:"api" toml:"api" yaml:"api"`       // blank url, app, and ca file are taken from the primary api settings
+	Check Check  `json:"check" toml:"check" yaml:"check"` // blank target and title are taken from the primary check
+}
+
+// SelfCheck defines the dedicated check for the agent's own metrics
+type SelfCheck struct {
+	Enable    bool   `json:"enable" toml:"enable" yaml:"enable"`
+	BundleCID string `mapstructure:"bundle_cid" json:"bundle_cid" toml:"bundle_cid" yaml:"bundle_cid"`
+	Target    string `json:"target" toml:"target" yaml:"target"` // blank, the primary target with an _agent suffix
+	Title     string `json:"title" toml:"title" yaml:"title"`    // blank, the primary title with an (agent) suffix
+}
+
 // Log defines the logging configuration options
 type Log struct {
 	Level  string `json:"level" yaml:"level" toml:"level"`
@@ -108,9 +358,15 @@ type Log struct {
 // Validate verifies the required portions of the configuration
 func Validate() error {
 
+	// metrics forwarded to a circonus-agent or statsd listener do not need a check
+	if viper.GetString(keys.ForwardURL) != "" || viper.GetString(keys.ForwardStatsd) != "" {
+		return nil
+	}
+
 	err := validateAPIOptions(
 		viper.GetString(keys.APITokenKey),
 		viper.GetString(keys.APITokenKeyFile),
+		viper.GetString(keys.APITokenKeySecret),
 		viper.GetString(keys.APITokenApp),
 		viper.GetString(keys.APIURL),
 		viper.GetString(keys.APICAFile))
@@ -144,6 +400,9 @@ func StatConfig() error {
 			if cfg.Clusters[idx].BearerToken != "" {
 				cfg.Clusters[idx].BearerToken = "..."
 			}
+			if cfg.Clusters[idx].Circonus.API.Key != "" {
+				cfg.Clusters[idx].Circonus.API.Key = "..."
+			}
 		}
 	}
 
@@ -20,39 +20,85 @@ const (
 
 	APITokenKey        = ""
 	APITokenKeyFile    = ""
+	APITokenKeySecret  = ""
 	APITokenApp        = release.NAME
 	APIURL             = "https://api.circonus.com/v2/"
+	APITimeout         = "2m"
 	APIDebug           = false
 	APICAFile          = ""
 	CheckBundleCID     = ""
 	CheckCreate        = true
 	CheckBrokerCID     = "/broker/35" // circonus public httptrap broker
+	CheckBrokerSelect  = ""
 	CheckBrokerCAFile  = ""
+	CheckBrokerCert    = ""
+	CheckBrokerKey     = ""
 	CheckMetricFilters = ""
+	CheckFilterSync    = "5m"
 	CheckTags          = ""
 	CheckTarget        = "" // defaults to cluster name
 	DefaultStreamtags  = ""
 	CheckTitle         = ""
 	TraceSubmits       = ""
+	SpoolDir           = ""
+	SpoolMaxSize       = "100MB"
+	SubmitRetries      = 10
+	SubmitBackoffMin   = "50ms"
+	SubmitBackoffMax   = "1s"
+	SubmitJitter       = false
+	SubmitMaxBodySize  = ""
+	SubmitWorkers      = 4
+	SubmitTimeout      = "2m"
+	MaxMetricStreams   = 0
+	DeadLetterDir      = ""
+	DeadLetterGzip     = false
+	TextOnChange       = false
+	TextResend         = "1h"
+	CounterDeltas      = false
+	SubmitBudget       = "0" // no budget
+	SubmitCompression  = "gzip"
+	SubmitGzipLevel    = 0 // gzip default level
+	SelfCheckEnable    = false
+	SelfCheckBundleCID = ""
+	SelfCheckTarget    = "" // primary target with an _agent suffix
+	DryRunOutput       = "" // stdout
+	ForwardURL         = ""
+	ForwardStatsd      = ""
+	CreateDashboards   = false
+	AlertsCreate       = false
+	AlertsContactGroup = ""
+	ProxyURL           = ""
+	ProxyNoProxy       = ""
+	OTLPEndpoint       = ""
+	OTLPInsecure       = false
+	OTLPTimeout        = "10s"
+	RemoteWriteURL     = ""
+	RemoteWriteTimeout = "30s"
+	RemoteWriteToken   = ""
+	// alert rule thresholds
+	AlertsNodeNotReadyWait   = 5  // minutes
+	AlertsCrashLoopWait      = 10 // minutes
+	AlertsPVCUsedPercent     = 90.0
+	AlertsAPIServerErrorRate = 1.0 // 5xx responses per second
 	// hidden circonus settings for development and debugging
 	DryRun = false
 	// StreamMetrics = false
 	// these hidden settings are mainly for debugging
 	// the features default to ON and can be toggled OFF
-	ConcurrentSubmissions = true
-	SerialSubmissions     = false
-	MaxMetricBucketSize   = 0
-	NoBase64              = false
-	Base64Tags            = true
-	NoGZIP                = false
-	UseGZIP               = true
-	DebugSubmissions      = false
+	SerialSubmissions   = false
+	MaxMetricBucketSize = 0
+	NoBase64            = false
+	Base64Tags          = true
+	NoGZIP              = false
+	UseGZIP             = true
+	DebugSubmissions    = false
 
 	// General defaults
 
-	Debug     = false
-	LogLevel  = "info"
-	LogPretty = false
+	Debug       = false
+	LogLevel    = "info"
+	LogPretty   = false
+	WatchConfig = false
 
 	// Kubernetes cluster
 
@@ -65,28 +111,155 @@ const (
 		namespace of ck8sa: /var/run/secrets/kubernetes.io/serviceaccount/namespace
 	*/
 
-	K8SName                   = ""
-	K8SInterval               = "1m"
-	K8SAPIURL                 = "https://kubernetes"
-	K8SAPICAFile              = "/var/run/secrets/kubernetes.io/serviceaccount/ca.crt"
-	K8SBearerToken            = ""
-	K8SBearerTokenFile        = "/var/run/secrets/kubernetes.io/serviceaccount/token" //nolint:gosec
-	K8SEnableEvents           = false
-	K8SEnableKubeStateMetrics = false
-	K8SKSMMetricsPortName     = "http-metrics" // default from 'standard' service deployment, https://github.com/kubernetes/kube-state-metrics/blob/master/examples/standard/service.yaml#L11
-	K8SKSMTelemetryPortName   = "telemetry"    // default from 'standard' service deployment, https://github.com/kubernetes/kube-state-metrics/blob/master/examples/standard/service.yaml#L11
-	K8SEnableMetricsServer    = false
-	K8SEnableNodes            = true
-	K8SEnableNodeStats        = true
-	K8SEnableNodeMetrics      = true
-	K8SEnableCadvisorMetrics  = false
-	K8SEnableKubeDNSMetrics   = false
-	K8SNodeSelector           = "" // blank=all
-	K8SIncludePods            = true
-	K8SPodLabelKey            = "" // blank=all
-	K8SPodLabelVal            = "" // blank=all
-	K8SIncludeContainers      = false
-	K8SAPITimelimit           = "10s"
+	K8SName                            = ""
+	K8SInterval                        = "1m"
+	K8SAPIURL                          = "https://kubernetes"
+	K8SAPICAFile                       = "/var/run/secrets/kubernetes.io/serviceaccount/ca.crt"
+	K8SBearerToken                     = ""
+	K8SBearerTokenFile                 = "/var/run/secrets/kubernetes.io/serviceaccount/token" //nolint:gosec
+	K8SEnableEvents                    = false
+	K8SEnableKubeStateMetrics          = false
+	K8SKSMMetricsPortName              = "http-metrics" // default from 'standard' service deployment, https://github.com/kubernetes/kube-state-metrics/blob/master/examples/standard/service.yaml#L11
+	K8SKSMTelemetryPortName            = "telemetry"    // default from 'standard' service deployment, https://github.com/kubernetes/kube-state-metrics/blob/master/examples/standard/service.yaml#L11
+	K8SEnableMetricsServer             = false
+	K8SEnableNodes                     = true
+	K8SEnableNodeStats                 = true
+	K8SEnableNodeMetrics               = true
+	K8SEnableKubeletOperationalMetrics = false
+	K8SEnableVolumeStats               = false
+	K8SEnableNodeNetworkSaturation     = false
+	K8SEnableCadvisorMetrics           = false
+	K8SCadvisorMetricsFilter           = `^container_(cpu_cfs_(periods|throttled_periods|throttled_seconds)_total|fs_(reads|writes)(_bytes)?_total|network_(receive|transmit)_(errors|packets_dropped)_total)$` // cpu throttling, fs i/o, network errors - blank=all
+	K8SEnableKubeDNSMetrics            = false
+	K8SEnableAPIServer                 = false
+	K8SEnableEtcd                      = false
+	K8SEtcdEndpoints                   = "" // comma separated list of member urls
+	K8SEtcdCAFile                      = ""
+	K8SEtcdCertFile                    = ""
+	K8SEtcdKeyFile                     = ""
+	K8SEnableKubeScheduler             = false
+	K8SKubeSchedulerNamespace          = "kube-system"
+	K8SKubeSchedulerSelector           = "component=kube-scheduler"
+	K8SKubeSchedulerPort               = "10251"
+	K8SKubeSchedulerEndpoint           = "" // blank=discover pods, use api-server proxy
+	K8SEnableKubeControllerManager     = false
+	K8SKubeControllerManagerNamespace  = "kube-system"
+	K8SKubeControllerManagerSelector   = "component=kube-controller-manager"
+	K8SKubeControllerManagerPort       = "10252"
+	K8SEnableKubeProxy                 = false
+	K8SKubeProxyNamespace              = "kube-system"
+	K8SKubeProxySelector               = "k8s-app=kube-proxy"
+	K8SKubeProxyPort                   = "10249"
+	K8SEnableNodeLocalDNS              = false
+	K8SNodeLocalDNSNamespace           = "kube-system"
+	K8SNodeLocalDNSSelector            = "k8s-app=node-local-dns"
+	K8SNodeLocalDNSPort                = "9253"
+	K8SEnablePromScrape                = false
+	K8SPromScrapeNamespace             = "" // blank=all
+	K8SEnablePromMonitors              = false
+	K8SPromMonitorsNamespace           = "" // blank=all
+	K8SEnableIngressNginx              = false
+	K8SIngressNginxNamespace           = "ingress-nginx"
+	K8SIngressNginxSelector            = "app.kubernetes.io/name=ingress-nginx"
+	K8SIngressNginxPort                = "10254"
+	K8SEnableCertManager               = false
+	K8SCertManagerNamespace            = "cert-manager"
+	K8SCertManagerSelector             = "app.kubernetes.io/name=cert-manager"
+	K8SCertManagerPort                 = "9402"
+	K8SEnableIstio                     = false
+	K8SIstioNamespaces                 = "" // blank=all
+	K8SIstioSelector                   = "security.istio.io/tlsMode=istio"
+	K8SIstioPort                       = "15090"
+	K8SEnableLinkerd                   = false
+	K8SLinkerdNamespaces               = "" // blank=all
+	K8SLinkerdSelector                 = "linkerd.io/control-plane-ns"
+	K8SLinkerdPort                     = "4191"
+	K8SEnableDCGM                      = false
+	K8SDCGMNamespace                   = "" // blank=all
+	K8SDCGMSelector                    = "app=nvidia-dcgm-exporter"
+	K8SDCGMPort                        = "9400"
+	K8SEnableStorageInventory          = false
+	K8SEnableHPA                       = false
+	K8SEnableJobs                      = false
+	K8SEnableDeployments               = false
+	K8SEnableStatefulSets              = false
+	K8SEnableDaemonSets                = false
+	K8SEnableEndpoints                 = false
+	K8SEnableCustomResources           = false
+	K8SCustomResourcesFile             = "/ck8sa/custom-resources.json"
+	K8SEnableNodeProblemConditions     = false
+	K8SEnableContainerRestarts         = false
+	K8SEnablePodPhases                 = false
+	K8SEnableImagePulls                = false
+	K8SEnableClusterAutoscaler         = false
+	K8SClusterAutoscalerNamespace      = "kube-system"
+	K8SClusterAutoscalerSelector       = "app=cluster-autoscaler"
+	K8SClusterAutoscalerPort           = "8085"
+	K8SEnableKarpenter                 = false
+	K8SKarpenterNamespace              = "" // blank=all
+	K8SKarpenterSelector               = "app.kubernetes.io/name=karpenter"
+	K8SKarpenterPort                   = "8000"
+	K8SEnableVelero                    = false
+	K8SVeleroNamespace                 = "velero"
+	K8SEnableArgoCD                    = false
+	K8SArgoCDNamespace                 = "" // blank=all
+	K8SEnableFlux                      = false
+	K8SFluxNamespace                   = "" // blank=all
+	K8SEnableScrapeTargets             = false
+	K8SScrapeTargetsFile               = "/ck8sa/scrape-targets.yaml"
+	K8SEnableDeprecatedAPIs            = false
+	K8SEnableTLSSecrets                = false
+	K8STLSSecretsNamespaces            = "" // blank=all
+	K8SEnableConfigInventory           = false
+	K8SConfigInventorySizeThreshold    = 1048576 // 1MiB
+	K8SEnableNamespaceResources        = false
+	K8SEnableCRI                       = false
+	K8SCRISocket                       = "/run/containerd/containerd.sock"
+	K8SNodeName                        = ""
+	K8SEnablePodResources              = false
+	K8SPodResourcesSocket              = "/var/lib/kubelet/pod-resources/kubelet.sock"
+	K8SEnableCost                      = false
+	K8SCostPricesFile                  = "/ck8sa/cost-prices.yaml"
+	K8SCostLabels                      = ""
+	K8SEnableHeadroom                  = false
+	K8SHeadroomNodePoolLabel           = ""
+	K8SEnablePDB                       = false
+	K8SEnableSchedulingFailures        = false
+	K8SEnableEvictions                 = false
+	K8SEnableSpotInterruptions         = false
+	K8SEnableManagedControlPlane       = false
+	K8SManagedControlPlaneProvider     = "auto"
+	K8SEnableEKSCloudWatch             = false
+	K8SEKSClusterName                  = ""
+	K8SEnableNodeVersions              = false
+	K8SEnableClusterVersion            = false
+	K8SEnableOrphans                   = false
+	K8SOrphansLingerThreshold          = "24h"
+	K8SEnableTopN                      = false
+	K8STopN                            = 10
+	K8SEnableUtilization               = false
+	K8SEnableRecordingRules            = false
+	K8SRecordingRulesFile              = "/ck8sa/recording-rules.yaml"
+	K8SEnableSLOs                      = false
+	K8SSLOsFile                        = "/ck8sa/slos.yaml"
+	K8SEnableEtcdObjects               = false
+	K8SEnableAgentMetricFilters        = false
+	K8SAgentMetricFiltersFile          = "/ck8sa/agent-metric-filters.yaml"
+	K8SEnableTagTransforms             = false
+	K8STagTransformsFile               = "/ck8sa/tag-transforms.yaml"
+	K8SNodeSelector                    = "" // blank=all
+	K8SPodSelector                     = "" // blank=all
+	K8SIncludePods                     = true
+	K8SPodLabelKey                     = "" // blank=all
+	K8SPodLabelVal                     = "" // blank=all
+	K8SMetricPrefix                    = "" // blank=none
+	K8SConfigResource                  = "" // blank=none
+	K8SKubeconfig                      = "" // blank=none
+	K8SKubeconfigContext               = "" // blank=current context
+	K8SIncludeNamespaces               = "" // blank=all
+	K8SExcludeNamespaces               = "" // blank=none
+	K8SIncludeContainers               = false
+	K8SAPITimelimit                    = "10s"
 )
 
 var (
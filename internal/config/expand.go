@@ -0,0 +1,144 @@
+// Copyright © 2019 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+package config
+
+import (
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/viper"
+)
+
+// NOTES:
+// Config file values may reference environment variables, so one config file (e.g. a
+// ConfigMap template) can serve many clusters with values injected via the pod spec:
+//
+//   ${VAR}            value of VAR, an error if VAR is not set
+//   ${VAR:-default}   value of VAR, default if VAR is not set or empty
+//   $${VAR}           literal ${VAR}
+//
+// Only the braced form is expanded, $VAR (e.g. in a regular expression) and ${1}
+// (e.g. a tag transform replacement) are left as is. Only config file values are
+// expanded, command line and environment settings are used as is.
+
+var envRefRx = regexp.MustCompile(`\$\$\{|\$\{([A-Za-z_][A-Za-z0-9_]*)(:-([^}]*))?\}`)
+
+// ExpandConfigFileEnv expands environment variable references in the values of the
+// config file read by viper
+func ExpandConfigFileEnv() error {
+	file := viper.ConfigFileUsed()
+	if file == "" {
+		return nil
+	}
+
+	v := viper.New()
+	v.SetConfigFile(file)
+	if err := v.ReadInConfig(); err != nil {
+		return errors.Wrapf(err, "reading config file (%s)", file)
+	}
+
+	settings := v.AllSettings()
+	changed, err := expandSettings(settings, os.LookupEnv)
+	if err != nil {
+		return errors.Wrapf(err, "config file (%s)", file)
+	}
+	if !changed {
+		return nil
+	}
+
+	return errors.Wrap(viper.MergeConfigMap(settings), "merging expanded config")
+}
+
+// expandSettings expands the environment variable references in the string values
+// of nested settings, returns true if any value changed
+func expandSettings(settings map[string]interface{}, lookup func(string) (string, bool)) (bool, error) {
+	changed := false
+	for key, val := range settings {
+		newVal, valChanged, err := expandValue(val, lookup)
+		if err != nil {
+			return false, errors.Wrap(err, key)
+		}
+		if valChanged {
+			settings[key] = newVal
+			changed = true
+		}
+	}
+	return changed, nil
+}
+
+// expandValue expands the environment variable references in a setting value
+func expandValue(val interface{}, lookup func(string) (string, bool)) (interface{}, bool, error) {
+	switch v := val.(type) {
+	case string:
+		s, err := expandEnv(v, lookup)
+		if err != nil {
+			return nil, false, err
+		}
+		return s, s != v, nil
+	case map[string]interface{}:
+		changed, err := expandSettings(v, lookup)
+		return v, changed, err
+	case map[interface{}]interface{}: // yaml
+		changed := false
+		for key, item := range v {
+			newItem, itemChanged, err := expandValue(item, lookup)
+			if err != nil {
+				return nil, false, err
+			}
+			if itemChanged {
+				v[key] = newItem
+				changed = true
+			}
+		}
+		return v, changed, nil
+	case []interface{}:
+		changed := false
+		for i, item := range v {
+			newItem, itemChanged, err := expandValue(item, lookup)
+			if err != nil {
+				return nil, false, err
+			}
+			if itemChanged {
+				v[i] = newItem
+				changed = true
+			}
+		}
+		return v, changed, nil
+	default:
+		return val, false, nil
+	}
+}
+
+// expandEnv expands the ${VAR} and ${VAR:-default} references in a string
+func expandEnv(s string, lookup func(string) (string, bool)) (string, error) {
+	if !strings.Contains(s, "${") {
+		return s, nil
+	}
+
+	var missing []string
+	expanded := envRefRx.ReplaceAllStringFunc(s, func(ref string) string {
+		if ref == "$${" {
+			return "${"
+		}
+		m := envRefRx.FindStringSubmatch(ref)
+		name, hasDefault, def := m[1], m[2] != "", m[3]
+		if val, ok := lookup(name); ok && (val != "" || !hasDefault) {
+			return val
+		}
+		if hasDefault {
+			return def
+		}
+		missing = append(missing, name)
+		return ref
+	})
+
+	if len(missing) > 0 {
+		return "", errors.Errorf("environment variable(s) not set: %s", strings.Join(missing, ", "))
+	}
+	return expanded, nil
+}
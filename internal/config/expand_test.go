@@ -0,0 +1,86 @@
+// Copyright © 2019 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+package config
+
+import (
+	"testing"
+)
+
+func TestExpandEnv(t *testing.T) {
+	env := map[string]string{"CLUSTER": "prod-east", "EMPTY": ""}
+	lookup := func(name string) (string, bool) {
+		val, ok := env[name]
+		return val, ok
+	}
+
+	tests := []struct {
+		name      string
+		value     string
+		want      string
+		shouldErr bool
+	}{
+		{"none", "prod", "prod", false},
+		{"var", "${CLUSTER}", "prod-east", false},
+		{"embedded", "k8s_${CLUSTER}_agent", "k8s_prod-east_agent", false},
+		{"default unused", "${CLUSTER:-dev}", "prod-east", false},
+		{"default unset", "${REGION:-us-east-1}", "us-east-1", false},
+		{"default empty", "${EMPTY:-dev}", "dev", false},
+		{"empty", "${EMPTY}", "", false},
+		{"escaped", "$${CLUSTER}", "${CLUSTER}", false},
+		{"regex", "^foo$", "^foo$", false},
+		{"unbraced", "$CLUSTER", "$CLUSTER", false},
+		{"replacement", "${1}", "${1}", false},
+		{"unset", "${TOKEN}", "", true},
+	}
+
+	for _, tt := range tests {
+		got, err := expandEnv(tt.value, lookup)
+		if tt.shouldErr {
+			if err == nil {
+				t.Fatalf("%s: expected error", tt.name)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("%s: unexpected error (%s)", tt.name, err)
+		}
+		if got != tt.want {
+			t.Fatalf("%s: expected %q, got %q", tt.name, tt.want, got)
+		}
+	}
+}
+
+func TestExpandSettings(t *testing.T) {
+	lookup := func(name string) (string, bool) {
+		if name == "CLUSTER" {
+			return "prod", true
+		}
+		return "", false
+	}
+
+	settings := map[string]interface{}{
+		"kubernetes": map[string]interface{}{"name": "${CLUSTER}", "interval": "1m"},
+		"clusters":   []interface{}{map[interface{}]interface{}{"name": "${CLUSTER}-2"}},
+		"debug":      true,
+	}
+	changed, err := expandSettings(settings, lookup)
+	if err != nil {
+		t.Fatalf("unexpected error (%s)", err)
+	}
+	if !changed {
+		t.Fatal("expected settings to change")
+	}
+	if got := settings["kubernetes"].(map[string]interface{})["name"]; got != "prod" {
+		t.Fatalf("expected prod, got %v", got)
+	}
+	if got := settings["clusters"].([]interface{})[0].(map[interface{}]interface{})["name"]; got != "prod-2" {
+		t.Fatalf("expected prod-2, got %v", got)
+	}
+
+	if _, err := expandSettings(map[string]interface{}{"token": "${TOKEN}"}, lookup); err == nil {
+		t.Fatal("expected error, unset variable")
+	}
+}
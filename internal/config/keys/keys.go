@@ -23,6 +23,12 @@ const (
 	// APITokenKeyFile circonus api token key in a file
 	APITokenKeyFile = "circonus.api.key_file" //nolint:gosec
 
+	// APITokenKeySecret circonus api token key in a kubernetes secret (namespace/name/key)
+	APITokenKeySecret = "circonus.api.key_secret" //nolint:gosec
+
+	// APITimeout circonus api operation timeout
+	APITimeout = "circonus.api.timeout"
+
 	// APITokenApp circonus api token key application name
 	APITokenApp = "circonus.api.app" //nolint:gosec
 
@@ -63,31 +69,168 @@ const (
 	//  `metric_filters = '''[["deny","$^",""],["allow","^.+$",""]]'''`
 	CheckMetricFilters = "circonus.check.metric_filters"
 
+	// CheckFilterSync interval the check bundle metric filters are reconciled with the
+	// configured metric filters (0 reconciles only at startup)
+	CheckFilterSync = "circonus.check.metric_filters_sync"
+
 	// CheckCreate toggles creating a new check bundle when a check bundle id is not supplied
 	CheckCreate = "circonus.check.create"
 
 	// CheckBrokerCID a specific broker ID to use when creating a new check bundle
 	CheckBrokerCID = "circonus.check.broker_cid"
 
+	// CheckBrokerSelect ordered, comma delimited, list of selectors (tag:<category:value>,
+	// region:<region>, latency) used to select the broker when creating a new check
+	// bundle, falls back to CheckBrokerCID when no brokers match
+	CheckBrokerSelect = "circonus.check.broker_select"
+
 	// CheckBrokerCAFile broker ca file if self-signed, used for TLS config
 	CheckBrokerCAFile = "circonus.check.broker_ca_file"
 
+	// CheckBrokerCert client certificate file sent to brokers requiring mutual tls
+	CheckBrokerCert = "circonus.check.broker_cert_file"
+
+	// CheckBrokerKey client key file for the broker client certificate
+	CheckBrokerKey = "circonus.check.broker_key_file"
+
 	// CheckTitle a specific title to use when creating a new check bundle
 	CheckTitle = "circonus.check.title"
 
 	// CheckTags a specific set of tags to use when creating a new check bundle
 	CheckTags = "circonus.check.tags"
 
+	// CheckShards is a list of additional checks metrics are routed to (config file only)
+	CheckShards = "circonus.shards"
+
 	// DefaultStreamtags a specific set of tags to include with _all_ metrics collected
 	DefaultStreamtags = "circonus.default_streamtags"
 
 	// TraceSubmits enables writing all metrics sent to circonus to files
 	TraceSubmits = "circonus.trace_submits"
 
+	// SpoolDir directory failed submissions are spooled to for replay (blank disables)
+	SpoolDir = "circonus.spool_dir"
+
+	// SpoolMaxSize max size of the submission spool, oldest submissions are discarded
+	SpoolMaxSize = "circonus.spool_max_size"
+
+	// SubmitRetries max retries of a failed submission
+	SubmitRetries = "circonus.submit_retries"
+
+	// SubmitBackoffMin initial wait between submission retries, doubled each retry
+	SubmitBackoffMin = "circonus.submit_backoff_min"
+
+	// SubmitBackoffMax max wait between submission retries
+	SubmitBackoffMax = "circonus.submit_backoff_max"
+
+	// SubmitJitter randomizes the wait between submission retries
+	SubmitJitter = "circonus.submit_jitter"
+
+	// SubmitMaxBodySize submissions larger than the size are split into multiple submissions
+	SubmitMaxBodySize = "circonus.submit_max_body_size"
+
+	// SubmitWorkers number of submission workers (max in-flight submissions)
+	SubmitWorkers = "circonus.submit_workers"
+
+	// SubmitTimeout per-submission timeout, including retries (0 for no timeout)
+	SubmitTimeout = "circonus.submit_timeout"
+
+	// MaxMetricStreams max unique metric streams queued per collection interval, new streams
+	// over the limit are dropped (0 for no limit)
+	MaxMetricStreams = "circonus.max_metric_streams"
+
+	// DeadLetterDir directory failed submissions are written to for manual replay (blank disables)
+	DeadLetterDir = "circonus.dead_letter_dir"
+
+	// DeadLetterGzip gzip compress dead-letter payloads
+	DeadLetterGzip = "circonus.dead_letter_compress"
+
+	// TextOnChange send text metrics only when their value changes
+	TextOnChange = "circonus.text_on_change"
+
+	// TextResend interval unchanged text metrics are resent (0 never resends)
+	TextResend = "circonus.text_resend"
+
+	// CounterDeltas submit prometheus counters as the change since the previous collection
+	CounterDeltas = "circonus.counter_deltas"
+
+	// SubmitBudget time allowed for the submissions of a collection interval, payloads not
+	// submitted in time are spooled (0 no budget)
+	SubmitBudget = "circonus.submit_budget"
+
+	// SubmitCompression compression of submissions (gzip, zstd, none)
+	SubmitCompression = "circonus.submit_compression"
+
+	// SubmitGzipLevel gzip compression level of submissions (1-9, 0 default)
+	SubmitGzipLevel = "circonus.submit_gzip_level"
+
+	// SelfCheckEnable send the agent's own metrics to a dedicated check
+	SelfCheckEnable = "circonus.self_check.enable"
+
+	// SelfCheckBundleCID check bundle cid of the dedicated agent metrics check
+	SelfCheckBundleCID = "circonus.self_check.bundle_cid"
+
+	// SelfCheckTarget target of the dedicated agent metrics check
+	SelfCheckTarget = "circonus.self_check.target"
+
+	// DryRunOutput file dry run submissions are written to (blank or - for stdout)
+	DryRunOutput = "circonus.dry_run_output"
+
+	// ForwardURL circonus-agent write endpoint metrics are forwarded to, rather than a broker
+	ForwardURL = "circonus.forward_url"
+
+	// ForwardStatsd statsd listener (host:port) metrics are forwarded to, rather than a broker
+	ForwardStatsd = "circonus.forward_statsd"
+
+	// CreateDashboards create the standard dashboards (cluster overview, node health, workload
+	// health) for the cluster's check, if they do not already exist
+	CreateDashboards = "circonus.create_dashboards"
+
+	// AlertsCreate create, or update, the curated alert rulesets for the cluster's check
+	AlertsCreate = "circonus.alerts.create"
+
+	// AlertsContactGroup contact group cid notified by the alert rulesets (blank for none)
+	AlertsContactGroup = "circonus.alerts.contact_group"
+
+	// AlertsNodeNotReadyWait minutes a node is not ready before alerting
+	AlertsNodeNotReadyWait = "circonus.alerts.node_not_ready_wait"
+
+	// AlertsCrashLoopWait minutes a container is in crashloop backoff before alerting
+	AlertsCrashLoopWait = "circonus.alerts.crashloop_wait"
+
+	// AlertsPVCUsedPercent persistent volume claim used percent alert threshold
+	AlertsPVCUsedPercent = "circonus.alerts.pvc_used_percent"
+
+	// AlertsAPIServerErrorRate api-server 5xx responses per second alert threshold
+	AlertsAPIServerErrorRate = "circonus.alerts.apiserver_error_rate"
+
+	// ProxyURL proxy used for circonus api requests and broker submissions (blank uses
+	// the HTTPS_PROXY environment variable)
+	ProxyURL = "circonus.proxy.url"
+
+	// ProxyNoProxy comma delimited list of hosts, domains, and cidrs which are not proxied
+	ProxyNoProxy = "circonus.proxy.no_proxy"
+
+	// OTLPEndpoint opentelemetry collector (host:port) metrics are exported to over OTLP/gRPC (blank disables)
+	OTLPEndpoint = "circonus.otlp.endpoint"
+
+	// OTLPInsecure use plaintext, rather than tls, for the OTLP connection
+	OTLPInsecure = "circonus.otlp.insecure"
+
+	// OTLPTimeout timeout of each OTLP export
+	OTLPTimeout = "circonus.otlp.timeout"
+
+	// RemoteWriteURL prometheus remote write endpoint metrics are exported to (blank disables)
+	RemoteWriteURL = "circonus.remote_write.url"
+
+	// RemoteWriteTimeout timeout of each remote write request
+	RemoteWriteTimeout = "circonus.remote_write.timeout"
+
+	// RemoteWriteToken file containing the bearer token for remote write requests
+	RemoteWriteToken = "circonus.remote_write.bearer_token_file"
+
 	// hidden circonus settings for development and debugging
 
-	// ConcurrentSubmissions submit metrics to circonus concurrently
-	ConcurrentSubmissions = "circonus.concurrent_submissions"
 	// SerialSubmissions submit metrics serially
 	SerialSubmissions = "circonus.serial_submissions"
 
@@ -101,7 +244,7 @@ const (
 	// NoBase64 disables using base64 encoding for stream tags (debugging)
 	NoBase64 = "circonus.no_base64"
 
-	// DryRun print metrics to stdout rather than sending to circonu
+	// DryRun write metrics to stdout (or DryRunOutput) rather than sending to circonus
 	DryRun = "circonus.dry_run"
 
 	// StreamMetrics use streaming metric submission format
@@ -145,11 +288,310 @@ const (
 	// K8SEnableNodeMetrics - kublet /metrics observation metrics
 	K8SEnableNodeMetrics = "kubernetes.enable_node_metrics"
 
+	// K8SEnableKubeletOperationalMetrics - kubelet /metrics operational metrics (pleg, runtime operations, pod start)
+	K8SEnableKubeletOperationalMetrics = "kubernetes.enable_kubelet_operational_metrics"
+
+	// K8SEnableVolumeStats - kubelet /stats/summary per persistent volume claim usage (capacity, available, inodes)
+	// NOTE: requires K8SEnableNodeStats, independent of K8SIncludePods
+	K8SEnableVolumeStats = "kubernetes.enable_volume_stats"
+
+	// K8SEnableNodeNetworkSaturation - kubelet /metrics/cadvisor node level (root cgroup) packets, drops, and sockets
+	// NOTE: independent of K8SEnableCadvisorMetrics
+	K8SEnableNodeNetworkSaturation = "kubernetes.enable_node_network_saturation"
+
 	// K8SEnableCadvisorMetrics - kublet /metrics/cadvisor metrics
 	K8SEnableCadvisorMetrics = "kubernetes.enable_cadvisor_metrics"
+	// K8SCadvisorMetricsFilter - regular expression of cadvisor metric families to forward
+	K8SCadvisorMetricsFilter = "kubernetes.cadvisor_metrics_filter"
 
 	// K8SEnableKubeDNSMetrics - collect kube-dns metrics
-	K8SEnableKubeDNSMetrics = "kubernetes.enable_kube_dns"
+	K8SEnableKubeDNSMetrics = "kubernetes.enable_kube_dns_metrics"
+
+	// K8SEnableAPIServer - collect kube-apiserver metrics
+	K8SEnableAPIServer = "kubernetes.enable_api_server"
+
+	// K8SEnableEtcd - collect etcd member metrics
+	K8SEnableEtcd = "kubernetes.enable_etcd"
+	// K8SEtcdEndpoints - comma separated list of etcd member urls (e.g. https://10.0.0.1:2379)
+	K8SEtcdEndpoints = "kubernetes.etcd_endpoints"
+	// K8SEtcdCAFile - etcd ca cert file
+	K8SEtcdCAFile = "kubernetes.etcd_ca_file"
+	// K8SEtcdCertFile - etcd client cert file
+	K8SEtcdCertFile = "kubernetes.etcd_cert_file"
+	// K8SEtcdKeyFile - etcd client key file
+	K8SEtcdKeyFile = "kubernetes.etcd_key_file"
+
+	// K8SEnableKubeScheduler - collect kube-scheduler metrics
+	K8SEnableKubeScheduler = "kubernetes.enable_kube_scheduler"
+	// K8SKubeSchedulerNamespace - namespace of kube-scheduler pods
+	K8SKubeSchedulerNamespace = "kubernetes.kube_scheduler_namespace"
+	// K8SKubeSchedulerSelector - label selector for kube-scheduler pods
+	K8SKubeSchedulerSelector = "kubernetes.kube_scheduler_selector"
+	// K8SKubeSchedulerPort - kube-scheduler metrics port (prefix with 'https:' for https)
+	K8SKubeSchedulerPort = "kubernetes.kube_scheduler_port"
+	// K8SKubeSchedulerEndpoint - explicit kube-scheduler metrics url, disables pod discovery
+	K8SKubeSchedulerEndpoint = "kubernetes.kube_scheduler_endpoint"
+
+	// K8SEnableKubeControllerManager - collect kube-controller-manager metrics
+	K8SEnableKubeControllerManager = "kubernetes.enable_kube_controller_manager"
+	// K8SKubeControllerManagerNamespace - namespace of kube-controller-manager pods
+	K8SKubeControllerManagerNamespace = "kubernetes.kube_controller_manager_namespace"
+	// K8SKubeControllerManagerSelector - label selector for kube-controller-manager pods
+	K8SKubeControllerManagerSelector = "kubernetes.kube_controller_manager_selector"
+	// K8SKubeControllerManagerPort - kube-controller-manager metrics port (prefix with 'https:' for https)
+	K8SKubeControllerManagerPort = "kubernetes.kube_controller_manager_port"
+
+	// K8SEnableKubeProxy - collect kube-proxy metrics
+	K8SEnableKubeProxy = "kubernetes.enable_kube_proxy"
+	// K8SKubeProxyNamespace - namespace of kube-proxy pods
+	K8SKubeProxyNamespace = "kubernetes.kube_proxy_namespace"
+	// K8SKubeProxySelector - label selector for kube-proxy pods
+	K8SKubeProxySelector = "kubernetes.kube_proxy_selector"
+	// K8SKubeProxyPort - kube-proxy metrics port
+	K8SKubeProxyPort = "kubernetes.kube_proxy_port"
+
+	// K8SEnableNodeLocalDNS - collect NodeLocal DNSCache metrics
+	K8SEnableNodeLocalDNS = "kubernetes.enable_node_local_dns"
+	// K8SNodeLocalDNSNamespace - namespace of node-local-dns pods
+	K8SNodeLocalDNSNamespace = "kubernetes.node_local_dns_namespace"
+	// K8SNodeLocalDNSSelector - label selector for node-local-dns pods
+	K8SNodeLocalDNSSelector = "kubernetes.node_local_dns_selector"
+	// K8SNodeLocalDNSPort - node-local-dns metrics port
+	K8SNodeLocalDNSPort = "kubernetes.node_local_dns_port"
+
+	// K8SEnablePromScrape - collect metrics from pods/services annotated prometheus.io/scrape=true
+	K8SEnablePromScrape = "kubernetes.enable_prom_scrape"
+	// K8SPromScrapeNamespace - limit annotation based scraping to a namespace
+	K8SPromScrapeNamespace = "kubernetes.prom_scrape_namespace"
+
+	// K8SEnablePromMonitors - collect metrics from prometheus-operator ServiceMonitor and PodMonitor targets
+	K8SEnablePromMonitors = "kubernetes.enable_prom_monitors"
+	// K8SPromMonitorsNamespace - limit ServiceMonitor/PodMonitor watching to a namespace
+	K8SPromMonitorsNamespace = "kubernetes.prom_monitors_namespace"
+
+	// K8SEnableIngressNginx - collect ingress-nginx metrics
+	K8SEnableIngressNginx = "kubernetes.enable_ingress_nginx"
+	// K8SIngressNginxNamespace - namespace of ingress-nginx pods (blank for all)
+	K8SIngressNginxNamespace = "kubernetes.ingress_nginx_namespace"
+	// K8SIngressNginxSelector - label selector for ingress-nginx pods
+	K8SIngressNginxSelector = "kubernetes.ingress_nginx_selector"
+	// K8SIngressNginxPort - ingress-nginx metrics port (prefix with 'https:' for https)
+	K8SIngressNginxPort = "kubernetes.ingress_nginx_port"
+
+	// K8SEnableCertManager - collect cert-manager metrics
+	K8SEnableCertManager = "kubernetes.enable_cert_manager"
+	// K8SCertManagerNamespace - namespace of cert-manager pods (blank for all)
+	K8SCertManagerNamespace = "kubernetes.cert_manager_namespace"
+	// K8SCertManagerSelector - label selector for cert-manager pods
+	K8SCertManagerSelector = "kubernetes.cert_manager_selector"
+	// K8SCertManagerPort - cert-manager metrics port (prefix with 'https:' for https)
+	K8SCertManagerPort = "kubernetes.cert_manager_port"
+
+	// K8SEnableIstio - collect istio-proxy sidecar metrics
+	K8SEnableIstio = "kubernetes.enable_istio"
+	// K8SIstioNamespaces - comma separated list of namespaces with istio sidecars (blank for all)
+	K8SIstioNamespaces = "kubernetes.istio_namespaces"
+	// K8SIstioSelector - label selector for pods with istio sidecars
+	K8SIstioSelector = "kubernetes.istio_selector"
+	// K8SIstioPort - istio-proxy metrics port
+	K8SIstioPort = "kubernetes.istio_port"
+
+	// K8SEnableLinkerd - collect linkerd-proxy sidecar metrics
+	K8SEnableLinkerd = "kubernetes.enable_linkerd"
+	// K8SLinkerdNamespaces - comma separated list of namespaces with linkerd sidecars (blank for all)
+	K8SLinkerdNamespaces = "kubernetes.linkerd_namespaces"
+	// K8SLinkerdSelector - label selector for pods with linkerd sidecars
+	K8SLinkerdSelector = "kubernetes.linkerd_selector"
+	// K8SLinkerdPort - linkerd-proxy admin metrics port
+	K8SLinkerdPort = "kubernetes.linkerd_port"
+
+	// K8SEnableDCGM - collect dcgm-exporter metrics
+	K8SEnableDCGM = "kubernetes.enable_dcgm"
+	// K8SDCGMNamespace - namespace of dcgm-exporter pods (blank for all)
+	K8SDCGMNamespace = "kubernetes.dcgm_namespace"
+	// K8SDCGMSelector - label selector for dcgm-exporter pods
+	K8SDCGMSelector = "kubernetes.dcgm_selector"
+	// K8SDCGMPort - dcgm-exporter metrics port (prefix with 'https:' for https)
+	K8SDCGMPort = "kubernetes.dcgm_port"
+
+	// K8SEnableStorageInventory - persistent volume and claim inventory (counts, capacity by storage class and phase)
+	K8SEnableStorageInventory = "kubernetes.enable_storage_inventory"
+
+	// K8SEnableHPA - horizontal pod autoscaler status (replicas, metric target vs current, conditions)
+	K8SEnableHPA = "kubernetes.enable_hpa"
+
+	// K8SEnableJobs - job and cron job health (completion/failure counts, active duration, last successful schedule age)
+	K8SEnableJobs = "kubernetes.enable_jobs"
+
+	// K8SEnableDeployments - deployment rollout status (desired/ready/updated/unavailable replicas, rollout stuck)
+	K8SEnableDeployments = "kubernetes.enable_deployments"
+
+	// K8SEnableStatefulSets - statefulset readiness (replicas, ready, current/updated revision, ordinal ordering waits)
+	K8SEnableStatefulSets = "kubernetes.enable_statefulsets"
+
+	// K8SEnableDaemonSets - daemonset coverage (desired vs ready vs misscheduled pods)
+	K8SEnableDaemonSets = "kubernetes.enable_daemonsets"
+
+	// K8SEnableEndpoints - service endpoint health (ready vs not ready endpoints per service)
+	K8SEnableEndpoints = "kubernetes.enable_endpoints"
+
+	// K8SEnableCustomResources - config driven custom resource state (see custom_resources_file)
+	K8SEnableCustomResources = "kubernetes.enable_custom_resources"
+	// K8SCustomResourcesFile - json file defining custom resources and fields to collect
+	K8SCustomResourcesFile = "kubernetes.custom_resources_file"
+
+	// K8SEnableNodeProblemConditions - node-problem-detector (custom) node conditions as 0/1 gauges
+	K8SEnableNodeProblemConditions = "kubernetes.enable_node_problem_conditions"
+
+	// K8SEnableContainerRestarts - container restarts and termination reasons (e.g. OOMKilled) since the previous collection
+	K8SEnableContainerRestarts = "kubernetes.enable_container_restarts"
+
+	// K8SEnablePodPhases - pod pending and container creating duration histograms per namespace
+	K8SEnablePodPhases = "kubernetes.enable_pod_phases"
+
+	// K8SEnableImagePulls - image pull counts, failures, and durations by registry (from pod events)
+	K8SEnableImagePulls = "kubernetes.enable_image_pulls"
+
+	// K8SEnableClusterAutoscaler - collect cluster-autoscaler metrics
+	K8SEnableClusterAutoscaler = "kubernetes.enable_cluster_autoscaler"
+	// K8SClusterAutoscalerNamespace - namespace of cluster-autoscaler pods (blank for all)
+	K8SClusterAutoscalerNamespace = "kubernetes.cluster_autoscaler_namespace"
+	// K8SClusterAutoscalerSelector - label selector for cluster-autoscaler pods
+	K8SClusterAutoscalerSelector = "kubernetes.cluster_autoscaler_selector"
+	// K8SClusterAutoscalerPort - cluster-autoscaler metrics port (prefix with 'https:' for https)
+	K8SClusterAutoscalerPort = "kubernetes.cluster_autoscaler_port"
+
+	// K8SEnableKarpenter - collect karpenter metrics
+	K8SEnableKarpenter = "kubernetes.enable_karpenter"
+	// K8SKarpenterNamespace - namespace of karpenter pods (blank for all)
+	K8SKarpenterNamespace = "kubernetes.karpenter_namespace"
+	// K8SKarpenterSelector - label selector for karpenter pods
+	K8SKarpenterSelector = "kubernetes.karpenter_selector"
+	// K8SKarpenterPort - karpenter metrics port (prefix with 'https:' for https)
+	K8SKarpenterPort = "kubernetes.karpenter_port"
+
+	// K8SEnableVelero - collect velero backup and restore status
+	K8SEnableVelero = "kubernetes.enable_velero"
+	// K8SVeleroNamespace - namespace of velero backup and restore resources
+	K8SVeleroNamespace = "kubernetes.velero_namespace"
+
+	// K8SEnableArgoCD - collect argo cd application sync and health status
+	K8SEnableArgoCD = "kubernetes.enable_argocd"
+	// K8SArgoCDNamespace - namespace of argo cd application resources (blank for all)
+	K8SArgoCDNamespace = "kubernetes.argocd_namespace"
+
+	// K8SEnableFlux - collect flux kustomization and helm release reconciliation status
+	K8SEnableFlux = "kubernetes.enable_flux"
+	// K8SFluxNamespace - namespace of flux kustomization and helm release resources (blank for all)
+	K8SFluxNamespace = "kubernetes.flux_namespace"
+
+	// K8SEnableScrapeTargets - scrape the prometheus metrics endpoints defined in the scrape targets file
+	K8SEnableScrapeTargets = "kubernetes.enable_scrape_targets"
+	// K8SScrapeTargetsFile - yaml file defining the scrape targets
+	K8SScrapeTargetsFile = "kubernetes.scrape_targets_file"
+
+	// K8SEnableDeprecatedAPIs - collect deprecated api usage from the api-server metrics
+	K8SEnableDeprecatedAPIs = "kubernetes.enable_deprecated_apis"
+
+	// K8SEnableTLSSecrets - collect days until expiry of certificates in kubernetes.io/tls secrets
+	K8SEnableTLSSecrets = "kubernetes.enable_tls_secrets"
+	// K8STLSSecretsNamespaces - comma separated list of namespaces with tls secrets (blank for all)
+	K8STLSSecretsNamespaces = "kubernetes.tls_secrets_namespaces"
+
+	// K8SEnableConfigInventory - collect secret and configmap counts and sizes per namespace
+	K8SEnableConfigInventory = "kubernetes.enable_config_inventory"
+	// K8SConfigInventorySizeThreshold - secrets and configmaps larger than this many bytes are flagged (0 to disable)
+	K8SConfigInventorySizeThreshold = "kubernetes.config_inventory_size_threshold"
+
+	// K8SEnableNamespaceResources - collect per namespace pod resource requests, limits, and usage
+	K8SEnableNamespaceResources = "kubernetes.enable_namespace_resources"
+
+	// K8SEnableCRI - collect container stats from the node container runtime (CRI) socket, daemonset only
+	K8SEnableCRI = "kubernetes.enable_cri"
+	// K8SCRISocket - path of the container runtime (CRI) socket
+	K8SCRISocket = "kubernetes.cri_socket"
+	// K8SNodeName - name of the node the agent is running on, daemonset mode (downward api spec.nodeName)
+	K8SNodeName = "kubernetes.node_name"
+
+	// K8SEnablePodResources - collect device, cpu, and memory allocations from the kubelet pod resources socket, daemonset only
+	K8SEnablePodResources = "kubernetes.enable_pod_resources"
+	// K8SPodResourcesSocket - path of the kubelet pod resources socket
+	K8SPodResourcesSocket = "kubernetes.pod_resources_socket"
+
+	// K8SEnableCost - collect hourly cost estimates from node capacity and pod requests
+	K8SEnableCost = "kubernetes.enable_cost"
+	// K8SCostPricesFile - yaml file defining the cost price table
+	K8SCostPricesFile = "kubernetes.cost_prices_file"
+	// K8SCostLabels - comma separated list of pod label keys to roll up costs by (blank for namespace only)
+	K8SCostLabels = "kubernetes.cost_labels"
+
+	// K8SEnableHeadroom - collect scheduling capacity headroom per node pool and cluster wide
+	K8SEnableHeadroom = "kubernetes.enable_headroom"
+	// K8SHeadroomNodePoolLabel - node label identifying the node pool (blank for well known cloud/karpenter labels)
+	K8SHeadroomNodePoolLabel = "kubernetes.headroom_node_pool_label"
+
+	// K8SEnablePDB - collect pod disruption budget status
+	K8SEnablePDB = "kubernetes.enable_pdb"
+
+	// K8SEnableSchedulingFailures - collect pending pod scheduling failures by reason
+	K8SEnableSchedulingFailures = "kubernetes.enable_scheduling_failures"
+
+	// K8SEnableEvictions - pod evictions and preemptions by node and reason (from pod status and events)
+	K8SEnableEvictions = "kubernetes.enable_evictions"
+
+	// K8SEnableSpotInterruptions - spot/preemptible node interruption counts and node drain durations (from node taints and events)
+	K8SEnableSpotInterruptions = "kubernetes.enable_spot_interruptions"
+
+	// K8SEnableManagedControlPlane - managed control-plane (eks/gke/aks) health checks and provider fallbacks
+	K8SEnableManagedControlPlane = "kubernetes.enable_managed_control_plane"
+	// K8SManagedControlPlaneProvider - managed control-plane provider (auto, eks, gke, aks, none)
+	K8SManagedControlPlaneProvider = "kubernetes.managed_control_plane_provider"
+	// K8SEnableEKSCloudWatch - collect eks control-plane metrics from cloudwatch (requires aws credentials)
+	K8SEnableEKSCloudWatch = "kubernetes.enable_eks_cloudwatch"
+	// K8SEKSClusterName - eks cluster name, used for cloudwatch metric dimensions
+	K8SEKSClusterName = "kubernetes.eks_cluster_name"
+
+	// K8SEnableNodeVersions - per node kubelet, container runtime, kernel, and os image versions and node counts per version
+	K8SEnableNodeVersions = "kubernetes.enable_node_versions"
+
+	// K8SEnableClusterVersion - api-server version and kubelet version skew
+	K8SEnableClusterVersion = "kubernetes.enable_cluster_version"
+
+	// K8SEnableOrphans - orphaned pods, lingering evicted/completed pods, and empty replicasets (cleanup candidates)
+	K8SEnableOrphans = "kubernetes.enable_orphans"
+	// K8SOrphansLingerThreshold - how long evicted/completed pods linger before being counted as cleanup candidates
+	K8SOrphansLingerThreshold = "kubernetes.orphans_linger_threshold"
+
+	// K8SEnableTopN - top N pods and namespaces by cpu and memory usage (requires metrics-server)
+	K8SEnableTopN = "kubernetes.enable_top_n"
+	// K8STopN - number of pods and namespaces emitted per resource by the top-n collector
+	K8STopN = "kubernetes.top_n"
+
+	// K8SEnableUtilization - container usage/request and usage/limit ratios (requires metrics-server)
+	K8SEnableUtilization = "kubernetes.enable_utilization"
+
+	// K8SEnableRecordingRules - derived metrics from recording rules evaluated over each interval's samples
+	K8SEnableRecordingRules = "kubernetes.enable_recording_rules"
+	// K8SRecordingRulesFile - recording rules file
+	K8SRecordingRulesFile = "kubernetes.recording_rules_file"
+
+	// K8SEnableSLOs - error budget burn rates for the slos in the slos file
+	K8SEnableSLOs = "kubernetes.enable_slos"
+	// K8SSLOsFile - slos file
+	K8SSLOsFile = "kubernetes.slos_file"
+
+	// K8SEnableEtcdObjects - collect etcd object counts per resource type from the api-server metrics
+	K8SEnableEtcdObjects = "kubernetes.enable_etcd_objects"
+
+	// K8SEnableAgentMetricFilters - drop metrics matching the agent metric filters before submission
+	K8SEnableAgentMetricFilters = "kubernetes.enable_agent_metric_filters"
+	// K8SAgentMetricFiltersFile - agent metric filters file
+	K8SAgentMetricFiltersFile = "kubernetes.agent_metric_filters_file"
+
+	// K8SEnableTagTransforms - apply the stream tag transforms (rename, drop, add) to every metric
+	K8SEnableTagTransforms = "kubernetes.enable_tag_transforms"
+	// K8STagTransformsFile - tag transforms file
+	K8STagTransformsFile = "kubernetes.tag_transforms_file"
 
 	// K8SEnableEvents enable events
 	K8SEnableEvents = "kubernetes.enable_events"
@@ -172,6 +614,26 @@ const (
 	// K8SPodLabelVal include pod if label value matches
 	K8SPodLabelVal = "kubernetes.pod_label_val"
 
+	// K8SMetricPrefix prefix added to all metric names submitted for the cluster
+	K8SMetricPrefix = "kubernetes.metric_prefix"
+
+	// K8SConfigResource CirconusCollection custom resource (namespace/name) the cluster's
+	// collection settings are read from
+	K8SConfigResource = "kubernetes.config_resource"
+
+	// K8SKubeconfig kubeconfig file the cluster's api url and credentials are read from,
+	// for running outside of the cluster
+	K8SKubeconfig = "kubernetes.kubeconfig"
+
+	// K8SKubeconfigContext kubeconfig context to use (blank, the current context)
+	K8SKubeconfigContext = "kubernetes.kubeconfig_context"
+
+	// K8SIncludeNamespaces only report metrics of these namespaces (comma separated, blank for all)
+	K8SIncludeNamespaces = "kubernetes.include_namespaces"
+
+	// K8SExcludeNamespaces do not report metrics of these namespaces (comma separated)
+	K8SExcludeNamespaces = "kubernetes.exclude_namespaces"
+
 	// K8SIncludeContainers include container metrics
 	// NOTE: will not be included unless include_pods is true
 	K8SIncludeContainers = "kubernetes.include_container_metrics"
@@ -180,6 +642,10 @@ const (
 	// See: https://kubernetes.io/docs/concepts/overview/working-with-objects/labels/#list-and-watch-filtering
 	K8SNodeSelector = "kubernetes.node_selector"
 
+	// K8SPodSelector pod label(s) to use as a Selector (empty=all)
+	// See: https://kubernetes.io/docs/concepts/overview/working-with-objects/labels/#list-and-watch-filtering
+	K8SPodSelector = "kubernetes.pod_selector"
+
 	// K8SNodePoolSize size of the node collector pool
 	K8SNodePoolSize = "kubernetes.node_pool_size"
 
@@ -210,6 +676,9 @@ const (
 	// Debug enables debug messages
 	Debug = "debug"
 
+	// WatchConfig reload the configuration when the config file changes
+	WatchConfig = "watch_config"
+
 	//
 	// Informational
 	// NOTE: these ARE NOT included in the configuration file as they
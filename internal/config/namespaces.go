@@ -0,0 +1,77 @@
+// Copyright © 2019 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+package config
+
+import (
+	"strings"
+)
+
+// NOTES:
+// --k8s-include-namespaces and --k8s-exclude-namespaces scope what an agent reports
+// (e.g. one agent per tenant in a multi-tenant cluster). Both are comma separated lists
+// of namespaces, a namespace ending in * matches any namespace with the prefix (e.g.
+// kube-*). Exclusions win, with an include list only the listed namespaces are reported.
+// Metrics are matched on their namespace stream tag (after tag transforms) as they are
+// queued, so the lists apply to every collector (and to recording rules and slos). Events
+// are matched on the event's namespace. Metrics without a namespace tag (e.g. node and
+// cluster level metrics) are always reported.
+
+// NamespaceFilter is the cluster's namespace include/exclude lists
+type NamespaceFilter struct {
+	include []string
+	exclude []string
+}
+
+// NewNamespaceFilter returns the filter for comma separated include and exclude lists
+func NewNamespaceFilter(include, exclude string) NamespaceFilter {
+	return NamespaceFilter{
+		include: namespaceList(include),
+		exclude: namespaceList(exclude),
+	}
+}
+
+// NamespaceFilter returns the cluster's namespace filter
+func (c *Cluster) NamespaceFilter() NamespaceFilter {
+	return NewNamespaceFilter(c.IncludeNamespaces, c.ExcludeNamespaces)
+}
+
+// Empty returns true if all namespaces are allowed
+func (f NamespaceFilter) Empty() bool {
+	return len(f.include) == 0 && len(f.exclude) == 0
+}
+
+// Allowed returns true if metrics of the namespace are reported, a blank namespace
+// (cluster scoped objects) is always allowed
+func (f NamespaceFilter) Allowed(namespace string) bool {
+	if namespace == "" {
+		return true
+	}
+	if matchNamespace(f.exclude, namespace) {
+		return false
+	}
+	return len(f.include) == 0 || matchNamespace(f.include, namespace)
+}
+
+// namespaceList parses a comma separated list of namespaces
+func namespaceList(list string) []string {
+	var namespaces []string
+	for _, ns := range strings.Split(list, ",") {
+		if ns = strings.TrimSpace(ns); ns != "" {
+			namespaces = append(namespaces, ns)
+		}
+	}
+	return namespaces
+}
+
+// matchNamespace returns true if the namespace is in the list
+func matchNamespace(list []string, namespace string) bool {
+	for _, ns := range list {
+		if ns == namespace || (strings.HasSuffix(ns, "*") && strings.HasPrefix(namespace, strings.TrimSuffix(ns, "*"))) {
+			return true
+		}
+	}
+	return false
+}
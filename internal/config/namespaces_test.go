@@ -0,0 +1,41 @@
+// Copyright © 2019 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+package config
+
+import (
+	"testing"
+)
+
+func TestNamespaceFilter(t *testing.T) {
+	tests := []struct {
+		name      string
+		include   string
+		exclude   string
+		namespace string
+		want      bool
+	}{
+		{"no lists", "", "", "default", true},
+		{"included", "team-a, team-b", "", "team-b", true},
+		{"not included", "team-a,team-b", "", "default", false},
+		{"included prefix", "team-*", "", "team-c", true},
+		{"excluded", "", "kube-system", "kube-system", false},
+		{"excluded prefix", "", "kube-*", "kube-public", false},
+		{"not excluded", "", "kube-*", "default", true},
+		{"exclude wins", "team-*", "team-test", "team-test", false},
+		{"cluster scoped", "team-a", "", "", true},
+	}
+
+	for _, tt := range tests {
+		f := NewNamespaceFilter(tt.include, tt.exclude)
+		if got := f.Allowed(tt.namespace); got != tt.want {
+			t.Fatalf("%s: expected %v, got %v", tt.name, tt.want, got)
+		}
+	}
+
+	if !NewNamespaceFilter(" , ", "").Empty() {
+		t.Fatal("expected blank lists to be empty")
+	}
+}
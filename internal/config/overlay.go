@@ -0,0 +1,38 @@
+// Copyright © 2019 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+
+	"github.com/pkg/errors"
+)
+
+// ApplyClusterOverlay returns the cluster configuration with the settings of an overlay
+// (e.g. the spec of a CirconusCollection resource) applied. Overlay keys are the cluster
+// configuration file keys (e.g. interval, enable_nodes), unknown keys are an error.
+func ApplyClusterOverlay(base Cluster, overlay map[string]interface{}) (Cluster, error) {
+	data, err := json.Marshal(overlay)
+	if err != nil {
+		return base, errors.Wrap(err, "encoding cluster settings")
+	}
+
+	cfg := base
+	if base.CollectorIntervals != nil { // merged, not shared with base
+		cfg.CollectorIntervals = make(map[string]string, len(base.CollectorIntervals))
+		for id, interval := range base.CollectorIntervals {
+			cfg.CollectorIntervals[id] = interval
+		}
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&cfg); err != nil {
+		return base, errors.Wrap(err, "applying cluster settings")
+	}
+
+	return cfg, nil
+}
@@ -0,0 +1,79 @@
+// Copyright © 2019 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+package config
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestApplyClusterOverlay(t *testing.T) {
+	base := Cluster{Name: "test", Interval: "1m", EnableNodes: true, CollectorIntervals: map[string]string{"nodes": "30s"}}
+
+	tests := []struct {
+		name      string
+		overlay   map[string]interface{}
+		want      Cluster
+		shouldErr bool
+	}{
+		{"nil", nil, base, false},
+		{"empty", map[string]interface{}{}, base, false},
+		{"settings", map[string]interface{}{"interval": "5m", "enable_nodes": false, "enable_events": true, "node_pool_size": 2},
+			Cluster{Name: "test", Interval: "5m", EnableEvents: true, NodePoolSize: 2, CollectorIntervals: map[string]string{"nodes": "30s"}}, false},
+		{"collector intervals", map[string]interface{}{"collector_intervals": map[string]interface{}{"kube-state-metrics": "5m"}},
+			Cluster{Name: "test", Interval: "1m", EnableNodes: true, CollectorIntervals: map[string]string{"nodes": "30s", "kube-state-metrics": "5m"}}, false},
+		{"unknown key", map[string]interface{}{"enable_everything": true}, base, true},
+		{"invalid type", map[string]interface{}{"enable_nodes": "yes"}, base, true},
+	}
+
+	for _, tt := range tests {
+		got, err := ApplyClusterOverlay(base, tt.overlay)
+		if tt.shouldErr {
+			if err == nil {
+				t.Fatalf("%s: expected error", tt.name)
+			}
+		} else if err != nil {
+			t.Fatalf("%s: unexpected error (%s)", tt.name, err)
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Fatalf("%s: expected %+v, got %+v", tt.name, tt.want, got)
+		}
+	}
+
+	if len(base.CollectorIntervals) != 1 {
+		t.Fatalf("expected base to be unchanged, got %v", base.CollectorIntervals)
+	}
+}
+
+func TestParseObjectRef(t *testing.T) {
+	tests := []struct {
+		ref       string
+		namespace string
+		name      string
+		shouldErr bool
+	}{
+		{"default/agent", "default", "agent", false},
+		{"default", "", "", true},
+		{"default/", "", "", true},
+		{"a/b/c", "", "", true},
+	}
+
+	for _, tt := range tests {
+		namespace, name, err := ParseObjectRef(tt.ref)
+		if tt.shouldErr {
+			if err == nil {
+				t.Fatalf("%s: expected error", tt.ref)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("%s: unexpected error (%s)", tt.ref, err)
+		}
+		if namespace != tt.namespace || name != tt.name {
+			t.Fatalf("%s: expected %s/%s, got %s/%s", tt.ref, tt.namespace, tt.name, namespace, name)
+		}
+	}
+}
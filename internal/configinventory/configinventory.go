@@ -0,0 +1,261 @@
+// Copyright © 2019 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+// Package configinventory is the secret and configmap inventory collector
+package configinventory
+
+import (
+	"context"
+	"crypto/tls"
+	"sync"
+	"time"
+
+	cgm "github.com/circonus-labs/circonus-gometrics/v3"
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/circonus"
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/config"
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/config/defaults"
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/k8s"
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/release"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+type ConfigInventory struct {
+	config       *config.Cluster
+	check        *circonus.Check
+	log          zerolog.Logger
+	clientset    kubernetes.Interface
+	running      bool
+	apiTimelimit time.Duration
+	sync.Mutex
+	ts *time.Time
+}
+
+// NOTES:
+// Secrets and ConfigMaps are listed from the api-server to emit per namespace counts and total
+// data sizes. Objects larger than the size threshold are emitted individually, large objects are
+// stored in etcd and sent to every watcher on each change (etcd limits objects to ~1.5MiB).
+// Only the length of secret data is used, values are never logged or submitted.
+
+func New(cfg *config.Cluster, parentLog zerolog.Logger, check *circonus.Check) (*ConfigInventory, error) {
+	if cfg == nil {
+		return nil, errors.New("invalid cluster config (nil)")
+	}
+	if check == nil {
+		return nil, errors.New("invalid check (nil)")
+	}
+
+	ci := &ConfigInventory{
+		config: cfg,
+		check:  check,
+		log:    parentLog.With().Str("collector", "config-inventory").Logger(),
+	}
+
+	if cfg.APITimelimit != "" {
+		v, err := time.ParseDuration(cfg.APITimelimit)
+		if err != nil {
+			ci.log.Error().Err(err).Msg("parsing api timelimit, using default")
+		} else {
+			ci.apiTimelimit = v
+		}
+	}
+
+	if ci.apiTimelimit == time.Duration(0) {
+		v, err := time.ParseDuration(defaults.K8SAPITimelimit)
+		if err != nil {
+			ci.log.Fatal().Err(err).Msg("parsing DEFAULT api timelimit")
+		}
+		ci.apiTimelimit = v
+	}
+
+	restCfg, err := k8s.RESTConfig(cfg)
+	if err != nil {
+		return nil, errors.Wrap(err, "rest config")
+	}
+	restCfg.Timeout = ci.apiTimelimit
+	clientset, err := kubernetes.NewForConfig(restCfg)
+	if err != nil {
+		return nil, errors.Wrap(err, "clientset")
+	}
+	ci.clientset = clientset
+
+	return ci, nil
+}
+
+func (ci *ConfigInventory) ID() string {
+	return "config-inventory"
+}
+
+// Collect secret and configmap inventory
+func (ci *ConfigInventory) Collect(ctx context.Context, _ *tls.Config, ts *time.Time) {
+	ci.Lock()
+	if ci.running {
+		ci.log.Warn().Msg("already running")
+		ci.Unlock()
+		return
+	}
+	ci.running = true
+	ci.ts = ts
+	ci.Unlock()
+
+	defer func() {
+		if r := recover(); r != nil {
+			ci.log.Error().Interface("panic", r).Msg("recover")
+			ci.Lock()
+			ci.running = false
+			ci.Unlock()
+		}
+	}()
+
+	collectStart := time.Now()
+
+	ci.inventoryMetrics(ctx)
+
+	ci.check.AddHistSample("collect_latency", cgm.Tags{
+		cgm.Tag{Category: "source", Value: release.NAME},
+		cgm.Tag{Category: "op", Value: "collect_config-inventory"},
+		cgm.Tag{Category: "units", Value: "milliseconds"},
+	}, float64(time.Since(collectStart).Milliseconds()))
+	ci.log.Debug().Str("duration", time.Since(collectStart).String()).Msg("config-inventory collect end")
+	ci.Lock()
+	ci.running = false
+	ci.Unlock()
+}
+
+// object is the size of a secret or configmap
+type object struct {
+	namespace string
+	name      string
+	size      uint64
+}
+
+// inventory is the count, total size, and oversized objects of a kind in a namespace
+type inventory struct {
+	count     uint64
+	bytes     uint64
+	oversized []object
+}
+
+// inventoryMetrics emits the per namespace inventory of secrets and configmaps
+func (ci *ConfigInventory) inventoryMetrics(ctx context.Context) {
+	metrics := make(map[string]circonus.MetricSample)
+
+	secrets, err := ci.clientset.CoreV1().Secrets(metav1.NamespaceAll).List(metav1.ListOptions{})
+	if err != nil {
+		ci.check.IncrementCounter("collect_api_errors", cgm.Tags{
+			cgm.Tag{Category: "source", Value: release.NAME},
+			cgm.Tag{Category: "request", Value: "secret-list"},
+			cgm.Tag{Category: "target", Value: "api-server"},
+		})
+		ci.log.Error().Err(err).Msg("listing secrets")
+	} else {
+		objects := make([]object, 0, len(secrets.Items))
+		for i := range secrets.Items {
+			objects = append(objects, object{
+				namespace: secrets.Items[i].Namespace,
+				name:      secrets.Items[i].Name,
+				size:      secretSize(&secrets.Items[i]),
+			})
+		}
+		ci.queueInventory(metrics, "secret", summarize(objects, uint64(ci.config.ConfigInventorySizeThreshold)))
+	}
+
+	configMaps, err := ci.clientset.CoreV1().ConfigMaps(metav1.NamespaceAll).List(metav1.ListOptions{})
+	if err != nil {
+		ci.check.IncrementCounter("collect_api_errors", cgm.Tags{
+			cgm.Tag{Category: "source", Value: release.NAME},
+			cgm.Tag{Category: "request", Value: "configmap-list"},
+			cgm.Tag{Category: "target", Value: "api-server"},
+		})
+		ci.log.Error().Err(err).Msg("listing configmaps")
+	} else {
+		objects := make([]object, 0, len(configMaps.Items))
+		for i := range configMaps.Items {
+			objects = append(objects, object{
+				namespace: configMaps.Items[i].Namespace,
+				name:      configMaps.Items[i].Name,
+				size:      configMapSize(&configMaps.Items[i]),
+			})
+		}
+		ci.queueInventory(metrics, "configmap", summarize(objects, uint64(ci.config.ConfigInventorySizeThreshold)))
+	}
+
+	if len(metrics) == 0 {
+		return
+	}
+	if err := ci.check.SubmitQueue(ctx, metrics, ci.log.With().Str("type", "config-inventory").Logger()); err != nil {
+		ci.log.Warn().Err(err).Msg("submitting metrics")
+	}
+}
+
+// queueInventory queues the namespace inventories of a kind (secret or configmap)
+func (ci *ConfigInventory) queueInventory(metrics map[string]circonus.MetricSample, kind string, namespaces map[string]*inventory) {
+	for ns, inv := range namespaces {
+		streamTags := []string{
+			"source:config-inventory",
+			"source_type:inventory",
+			"kind:" + kind,
+			"namespace:" + ns,
+		}
+		_ = ci.check.QueueMetricSample(metrics, "config_objects", circonus.MetricTypeUint64, streamTags, []string{}, inv.count, ci.ts)
+		_ = ci.check.QueueMetricSample(metrics, "config_objects_bytes", circonus.MetricTypeUint64, append(streamTags, "units:bytes"), []string{}, inv.bytes, ci.ts)
+		_ = ci.check.QueueMetricSample(metrics, "config_objects_oversized", circonus.MetricTypeUint64, streamTags, []string{}, uint64(len(inv.oversized)), ci.ts)
+		for _, obj := range inv.oversized {
+			objStreamTags := []string{
+				"source:config-inventory",
+				"source_type:inventory",
+				"kind:" + kind,
+				"namespace:" + obj.namespace,
+				"name:" + obj.name,
+				"units:bytes",
+				"__rollup:false", // prevent high cardinality metrics from rolling up
+			}
+			_ = ci.check.QueueMetricSample(metrics, "config_object_oversized_bytes", circonus.MetricTypeUint64, objStreamTags, []string{}, obj.size, ci.ts)
+		}
+	}
+}
+
+// summarize returns the inventory of objects by namespace, objects larger than
+// threshold bytes are oversized (a threshold of 0 disables flagging)
+func summarize(objects []object, threshold uint64) map[string]*inventory {
+	namespaces := make(map[string]*inventory)
+	for _, obj := range objects {
+		inv, ok := namespaces[obj.namespace]
+		if !ok {
+			inv = &inventory{}
+			namespaces[obj.namespace] = inv
+		}
+		inv.count++
+		inv.bytes += obj.size
+		if threshold > 0 && obj.size > threshold {
+			inv.oversized = append(inv.oversized, obj)
+		}
+	}
+	return namespaces
+}
+
+// secretSize returns the size of the secret data keys and values
+func secretSize(secret *corev1.Secret) uint64 {
+	var size uint64
+	for k, v := range secret.Data {
+		size += uint64(len(k) + len(v))
+	}
+	return size
+}
+
+// configMapSize returns the size of the configmap data and binary data keys and values
+func configMapSize(cm *corev1.ConfigMap) uint64 {
+	var size uint64
+	for k, v := range cm.Data {
+		size += uint64(len(k) + len(v))
+	}
+	for k, v := range cm.BinaryData {
+		size += uint64(len(k) + len(v))
+	}
+	return size
+}
@@ -0,0 +1,63 @@
+// Copyright © 2019 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+package configinventory
+
+import (
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+func TestSummarize(t *testing.T) {
+	objects := []object{
+		{namespace: "default", name: "small", size: 10},
+		{namespace: "default", name: "large", size: 2000},
+		{namespace: "kube-system", name: "other", size: 100},
+	}
+
+	tests := []struct {
+		name      string
+		threshold uint64
+		oversized int
+	}{
+		{"disabled", 0, 0},
+		{"threshold", 1000, 1},
+		{"at threshold", 2000, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			namespaces := summarize(objects, tt.threshold)
+			if len(namespaces) != 2 {
+				t.Fatalf("expected 2 namespaces, got %d", len(namespaces))
+			}
+			inv := namespaces["default"]
+			if inv.count != 2 || inv.bytes != 2010 {
+				t.Errorf("default count=%d bytes=%d, want 2/2010", inv.count, inv.bytes)
+			}
+			if len(inv.oversized) != tt.oversized {
+				t.Errorf("default oversized=%d, want %d", len(inv.oversized), tt.oversized)
+			}
+			if len(namespaces["kube-system"].oversized) != 0 {
+				t.Error("kube-system should have no oversized objects")
+			}
+		})
+	}
+}
+
+func TestObjectSize(t *testing.T) {
+	secret := &corev1.Secret{Data: map[string][]byte{"key": []byte("value")}}
+	if s := secretSize(secret); s != 8 {
+		t.Errorf("secretSize() = %d, want 8", s)
+	}
+
+	cm := &corev1.ConfigMap{
+		Data:       map[string]string{"a": "12345"},
+		BinaryData: map[string][]byte{"b": {1, 2, 3}},
+	}
+	if s := configMapSize(cm); s != 10 {
+		t.Errorf("configMapSize() = %d, want 10", s)
+	}
+}
@@ -0,0 +1,264 @@
+// Copyright © 2019 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+// Package cost is the cluster cost estimation collector
+package cost
+
+import (
+	"context"
+	"crypto/tls"
+	"strings"
+	"sync"
+	"time"
+
+	cgm "github.com/circonus-labs/circonus-gometrics/v3"
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/circonus"
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/config"
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/config/defaults"
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/k8s"
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/release"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+type Cost struct {
+	config       *config.Cluster
+	check        *circonus.Check
+	log          zerolog.Logger
+	clientset    kubernetes.Interface
+	prices       *priceTable
+	labels       []string
+	running      bool
+	apiTimelimit time.Duration
+	sync.Mutex
+	ts *time.Time
+}
+
+// NOTES:
+// Hourly cost estimates are derived from a price table (see prices.go), not a cloud pricing
+// api. Each node costs its cpu and memory capacity at the node's rates, pods cost their cpu
+// and memory requests at the rates of the node they are scheduled on. Pod costs are rolled up
+// per namespace and per value of the configured pod labels, the node cost not requested by
+// pods is emitted as unallocated.
+
+func New(cfg *config.Cluster, parentLog zerolog.Logger, check *circonus.Check) (*Cost, error) {
+	if cfg == nil {
+		return nil, errors.New("invalid cluster config (nil)")
+	}
+	if check == nil {
+		return nil, errors.New("invalid check (nil)")
+	}
+
+	cc := &Cost{
+		config: cfg,
+		check:  check,
+		log:    parentLog.With().Str("collector", "cost").Logger(),
+	}
+
+	prices, err := loadPrices(cfg.CostPricesFile)
+	if err != nil {
+		return nil, errors.Wrap(err, "loading cost prices")
+	}
+	cc.prices = prices
+
+	for _, l := range strings.Split(cfg.CostLabels, ",") {
+		l = strings.TrimSpace(l)
+		if l != "" {
+			cc.labels = append(cc.labels, l)
+		}
+	}
+
+	if cfg.APITimelimit != "" {
+		v, err := time.ParseDuration(cfg.APITimelimit)
+		if err != nil {
+			cc.log.Error().Err(err).Msg("parsing api timelimit, using default")
+		} else {
+			cc.apiTimelimit = v
+		}
+	}
+
+	if cc.apiTimelimit == time.Duration(0) {
+		v, err := time.ParseDuration(defaults.K8SAPITimelimit)
+		if err != nil {
+			cc.log.Fatal().Err(err).Msg("parsing DEFAULT api timelimit")
+		}
+		cc.apiTimelimit = v
+	}
+
+	restCfg, err := k8s.RESTConfig(cfg)
+	if err != nil {
+		return nil, errors.Wrap(err, "rest config")
+	}
+	restCfg.Timeout = cc.apiTimelimit
+	clientset, err := kubernetes.NewForConfig(restCfg)
+	if err != nil {
+		return nil, errors.Wrap(err, "clientset")
+	}
+	cc.clientset = clientset
+
+	return cc, nil
+}
+
+func (cc *Cost) ID() string {
+	return "cost"
+}
+
+// Collect hourly cost estimates
+func (cc *Cost) Collect(ctx context.Context, _ *tls.Config, ts *time.Time) {
+	cc.Lock()
+	if cc.running {
+		cc.log.Warn().Msg("already running")
+		cc.Unlock()
+		return
+	}
+	cc.running = true
+	cc.ts = ts
+	cc.Unlock()
+
+	defer func() {
+		if r := recover(); r != nil {
+			cc.log.Error().Interface("panic", r).Msg("recover")
+			cc.Lock()
+			cc.running = false
+			cc.Unlock()
+		}
+	}()
+
+	collectStart := time.Now()
+
+	cc.costMetrics(ctx)
+
+	cc.check.AddHistSample("collect_latency", cgm.Tags{
+		cgm.Tag{Category: "source", Value: release.NAME},
+		cgm.Tag{Category: "op", Value: "collect_cost"},
+		cgm.Tag{Category: "units", Value: "milliseconds"},
+	}, float64(time.Since(collectStart).Milliseconds()))
+	cc.log.Debug().Str("duration", time.Since(collectStart).String()).Msg("cost collect end")
+	cc.Lock()
+	cc.running = false
+	cc.Unlock()
+}
+
+// costMetrics emits the hourly cost of each node, the cluster, each namespace, each
+// configured pod label value, and the unallocated (not requested) node capacity
+func (cc *Cost) costMetrics(ctx context.Context) {
+	nodes, err := cc.clientset.CoreV1().Nodes().List(metav1.ListOptions{LabelSelector: cc.config.NodeSelector})
+	if err != nil {
+		cc.apiError("node-list")
+		cc.log.Error().Err(err).Msg("listing nodes")
+		return
+	}
+	pods, err := cc.clientset.CoreV1().Pods(metav1.NamespaceAll).List(metav1.ListOptions{LabelSelector: cc.config.PodSelector})
+	if err != nil {
+		cc.apiError("pod-list")
+		cc.log.Error().Err(err).Msg("listing pods")
+		return
+	}
+
+	baseStreamTags := []string{
+		"source:cost",
+		"source_type:estimate",
+		"currency:" + cc.prices.Currency,
+		"units:hourly",
+	}
+	metrics := make(map[string]circonus.MetricSample)
+
+	nodeRates := make(map[string]rates)
+	var clusterCost float64
+	for i := range nodes.Items {
+		node := &nodes.Items[i]
+		r := cc.prices.nodeRates(node)
+		nodeRates[node.Name] = r
+		nodeCost := r.cost(float64(node.Status.Capacity.Cpu().MilliValue())/1000, float64(node.Status.Capacity.Memory().Value()))
+		clusterCost += nodeCost
+		instance := instanceType(node)
+		if instance == "" {
+			instance = "unknown"
+		}
+		streamTags := append(append([]string{}, baseStreamTags...), "node:"+node.Name, "instance_type:"+instance)
+		_ = cc.check.QueueMetricSample(metrics, "cost_node_hourly", circonus.MetricTypeFloat64, streamTags, []string{}, nodeCost, cc.ts)
+	}
+
+	namespaces := make(map[string]float64)
+	labels := make(map[string]map[string]float64)
+	var allocated float64
+	for i := range pods.Items {
+		pod := &pods.Items[i]
+		r, ok := nodeRates[pod.Spec.NodeName]
+		if !ok {
+			continue // not scheduled (or node gone)
+		}
+		if pod.Status.Phase == corev1.PodSucceeded || pod.Status.Phase == corev1.PodFailed {
+			continue // completed pods do not hold resources
+		}
+		cpu, memory := podRequests(pod)
+		podCost := r.cost(cpu, memory)
+		allocated += podCost
+		namespaces[pod.Namespace] += podCost
+		for _, l := range cc.labels {
+			v := pod.Labels[l]
+			if v == "" {
+				v = "none"
+			}
+			if labels[l] == nil {
+				labels[l] = make(map[string]float64)
+			}
+			labels[l][v] += podCost
+		}
+	}
+
+	_ = cc.check.QueueMetricSample(metrics, "cost_cluster_hourly", circonus.MetricTypeFloat64, baseStreamTags, []string{}, clusterCost, cc.ts)
+	unallocated := clusterCost - allocated
+	if unallocated < 0 {
+		unallocated = 0 // requests can exceed capacity on over-committed custom rates
+	}
+	_ = cc.check.QueueMetricSample(metrics, "cost_unallocated_hourly", circonus.MetricTypeFloat64, baseStreamTags, []string{}, unallocated, cc.ts)
+	for ns, v := range namespaces {
+		streamTags := append(append([]string{}, baseStreamTags...), "namespace:"+ns)
+		_ = cc.check.QueueMetricSample(metrics, "cost_namespace_hourly", circonus.MetricTypeFloat64, streamTags, []string{}, v, cc.ts)
+	}
+	for l, values := range labels {
+		for v, c := range values {
+			streamTags := append(append([]string{}, baseStreamTags...), "label:"+l, "label_value:"+v)
+			_ = cc.check.QueueMetricSample(metrics, "cost_label_hourly", circonus.MetricTypeFloat64, streamTags, []string{}, c, cc.ts)
+		}
+	}
+
+	if err := cc.check.SubmitQueue(ctx, metrics, cc.log.With().Str("type", "cost").Logger()); err != nil {
+		cc.log.Warn().Err(err).Msg("submitting metrics")
+	}
+}
+
+func (cc *Cost) apiError(request string) {
+	cc.check.IncrementCounter("collect_api_errors", cgm.Tags{
+		cgm.Tag{Category: "source", Value: release.NAME},
+		cgm.Tag{Category: "request", Value: request},
+		cgm.Tag{Category: "target", Value: "api-server"},
+	})
+}
+
+// podRequests returns the effective pod cpu (cores) and memory (bytes) requests, the larger of
+// the sum of the containers or the largest init container, plus the pod overhead
+func podRequests(pod *corev1.Pod) (float64, float64) {
+	var cpu, memory float64
+	for _, c := range pod.Spec.Containers {
+		cpu += float64(c.Resources.Requests.Cpu().MilliValue()) / 1000
+		memory += float64(c.Resources.Requests.Memory().Value())
+	}
+	for _, c := range pod.Spec.InitContainers {
+		if v := float64(c.Resources.Requests.Cpu().MilliValue()) / 1000; v > cpu {
+			cpu = v
+		}
+		if v := float64(c.Resources.Requests.Memory().Value()); v > memory {
+			memory = v
+		}
+	}
+	cpu += float64(pod.Spec.Overhead.Cpu().MilliValue()) / 1000
+	memory += float64(pod.Spec.Overhead.Memory().Value())
+	return cpu, memory
+}
@@ -0,0 +1,146 @@
+// Copyright © 2019 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+package cost
+
+import (
+	"io/ioutil"
+
+	"github.com/pkg/errors"
+	yaml "gopkg.in/yaml.v2"
+	corev1 "k8s.io/api/core/v1"
+)
+
+// Example price table file:
+//
+//   currency: USD
+//   default:
+//     cpu_hour: 0.031611
+//     memory_gib_hour: 0.004237
+//   instance_types:
+//     m5.large:
+//       cpu_hour: 0.024
+//       memory_gib_hour: 0.006
+//     p3.2xlarge:
+//       node_hour: 3.06
+//
+// Prices are per hour. Nodes are matched on the node.kubernetes.io/instance-type label
+// (or the deprecated beta.kubernetes.io/instance-type label), nodes without a matching
+// instance type use the default prices. A node_hour price is split evenly between the
+// node cpu and memory capacity to derive the cpu and memory rates.
+
+const (
+	instanceTypeLabel     = "node.kubernetes.io/instance-type"
+	betaInstanceTypeLabel = "beta.kubernetes.io/instance-type"
+	bytesPerGiB           = 1024 * 1024 * 1024
+)
+
+type priceTable struct {
+	Currency      string            `yaml:"currency"`
+	Default       *price            `yaml:"default"`
+	InstanceTypes map[string]*price `yaml:"instance_types"`
+}
+
+type price struct {
+	NodeHour      float64 `yaml:"node_hour"`
+	CPUHour       float64 `yaml:"cpu_hour"`        // per core
+	MemoryGiBHour float64 `yaml:"memory_gib_hour"` // per GiB
+}
+
+// rates are the hourly cost of a node's cpu cores and memory GiB
+type rates struct {
+	cpu    float64
+	memory float64
+}
+
+// loadPrices reads and validates the price table file
+func loadPrices(file string) (*priceTable, error) {
+	if file == "" {
+		return nil, errors.New("invalid cost prices file (empty)")
+	}
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, errors.Wrap(err, "reading cost prices file")
+	}
+	return parsePrices(data)
+}
+
+// parsePrices parses and validates the price table
+func parsePrices(data []byte) (*priceTable, error) {
+	var pt priceTable
+	if err := yaml.Unmarshal(data, &pt); err != nil {
+		return nil, errors.Wrap(err, "parsing cost prices")
+	}
+	if pt.Default == nil {
+		return nil, errors.New("invalid cost prices, default prices are required")
+	}
+	if err := pt.Default.validate(); err != nil {
+		return nil, errors.Wrap(err, "default prices")
+	}
+	for name, p := range pt.InstanceTypes {
+		if p == nil {
+			return nil, errors.Errorf("invalid cost prices for instance type (%s), no prices", name)
+		}
+		if err := p.validate(); err != nil {
+			return nil, errors.Wrapf(err, "instance type (%s) prices", name)
+		}
+	}
+	if pt.Currency == "" {
+		pt.Currency = "USD"
+	}
+	return &pt, nil
+}
+
+func (p *price) validate() error {
+	if p.NodeHour < 0 || p.CPUHour < 0 || p.MemoryGiBHour < 0 {
+		return errors.New("prices must not be negative")
+	}
+	if p.NodeHour > 0 && (p.CPUHour > 0 || p.MemoryGiBHour > 0) {
+		return errors.New("node_hour OR cpu_hour/memory_gib_hour, not both")
+	}
+	if p.NodeHour == 0 && p.CPUHour == 0 && p.MemoryGiBHour == 0 {
+		return errors.New("node_hour or cpu_hour/memory_gib_hour is required")
+	}
+	return nil
+}
+
+// instanceType returns the instance type of a node, blank if not labeled
+func instanceType(node *corev1.Node) string {
+	if it := node.Labels[instanceTypeLabel]; it != "" {
+		return it
+	}
+	return node.Labels[betaInstanceTypeLabel]
+}
+
+// nodeRates returns the hourly cpu core and memory GiB rates for a node
+func (pt *priceTable) nodeRates(node *corev1.Node) rates {
+	p := pt.Default
+	if ip, ok := pt.InstanceTypes[instanceType(node)]; ok {
+		p = ip
+	}
+
+	if p.NodeHour == 0 {
+		return rates{cpu: p.CPUHour, memory: p.MemoryGiBHour}
+	}
+
+	var r rates
+	cpu := float64(node.Status.Capacity.Cpu().MilliValue()) / 1000
+	memory := float64(node.Status.Capacity.Memory().Value()) / bytesPerGiB
+	switch {
+	case cpu > 0 && memory > 0:
+		r.cpu = p.NodeHour / 2 / cpu
+		r.memory = p.NodeHour / 2 / memory
+	case cpu > 0:
+		r.cpu = p.NodeHour / cpu
+	case memory > 0:
+		r.memory = p.NodeHour / memory
+	}
+	return r
+}
+
+// cost returns the hourly cost of cpu cores and memory bytes at the rates
+func (r rates) cost(cpu, memory float64) float64 {
+	return cpu*r.cpu + memory/bytesPerGiB*r.memory
+}
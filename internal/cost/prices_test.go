@@ -0,0 +1,91 @@
+// Copyright © 2019 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+package cost
+
+import (
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestParsePrices(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		wantErr bool
+	}{
+		{"valid", "default:\n  cpu_hour: 0.03\n  memory_gib_hour: 0.004\ninstance_types:\n  p3.2xlarge:\n    node_hour: 3.06\n", false},
+		{"no default", "instance_types:\n  m5.large:\n    node_hour: 0.096\n", true},
+		{"empty default", "default: {}\n", true},
+		{"node and cpu", "default:\n  node_hour: 1\n  cpu_hour: 0.03\n", true},
+		{"negative", "default:\n  cpu_hour: -1\n", true},
+		{"empty instance type", "default:\n  cpu_hour: 0.03\ninstance_types:\n  m5.large:\n", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pt, err := parsePrices([]byte(tt.data))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parsePrices() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err == nil && pt.Currency != "USD" {
+				t.Errorf("expected default currency USD, got %s", pt.Currency)
+			}
+		})
+	}
+}
+
+func TestNodeRates(t *testing.T) {
+	pt, err := parsePrices([]byte("default:\n  cpu_hour: 0.5\n  memory_gib_hour: 0.25\ninstance_types:\n  big:\n    node_hour: 4\n"))
+	if err != nil {
+		t.Fatalf("unexpected error (%s)", err)
+	}
+
+	node := func(instance string) *corev1.Node {
+		return &corev1.Node{
+			ObjectMeta: metav1.ObjectMeta{Labels: map[string]string{instanceTypeLabel: instance}},
+			Status: corev1.NodeStatus{Capacity: corev1.ResourceList{
+				corev1.ResourceCPU:    resource.MustParse("4"),
+				corev1.ResourceMemory: resource.MustParse("8Gi"),
+			}},
+		}
+	}
+
+	r := pt.nodeRates(node("small"))
+	if r.cpu != 0.5 || r.memory != 0.25 {
+		t.Errorf("default rates = %+v", r)
+	}
+	if c := r.cost(4, 8*bytesPerGiB); c != 4 {
+		t.Errorf("default node cost = %f, want 4", c)
+	}
+
+	r = pt.nodeRates(node("big"))
+	if r.cpu != 0.5 || r.memory != 0.25 {
+		t.Errorf("node_hour rates = %+v, want half of 4/hour on 4 cores and 8GiB", r)
+	}
+}
+
+func TestPodRequests(t *testing.T) {
+	pod := &corev1.Pod{Spec: corev1.PodSpec{
+		Containers: []corev1.Container{
+			{Resources: corev1.ResourceRequirements{Requests: corev1.ResourceList{
+				corev1.ResourceCPU:    resource.MustParse("500m"),
+				corev1.ResourceMemory: resource.MustParse("1Gi"),
+			}}},
+		},
+		InitContainers: []corev1.Container{
+			{Resources: corev1.ResourceRequirements{Requests: corev1.ResourceList{
+				corev1.ResourceCPU: resource.MustParse("2"),
+			}}},
+		},
+	}}
+
+	cpu, memory := podRequests(pod)
+	if cpu != 2 || memory != bytesPerGiB {
+		t.Errorf("podRequests() = %f, %f, want 2, %d", cpu, memory, bytesPerGiB)
+	}
+}
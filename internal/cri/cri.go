@@ -0,0 +1,228 @@
+// Copyright © 2019 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+// Package cri is the container runtime (CRI) container stats collector
+package cri
+
+import (
+	"context"
+	"crypto/tls"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	cgm "github.com/circonus-labs/circonus-gometrics/v3"
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/circonus"
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/config"
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/config/defaults"
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/release"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog"
+	"google.golang.org/grpc"
+	runtimeapi "k8s.io/cri-api/pkg/apis/runtime/v1alpha2"
+)
+
+// pod labels set by the kubelet on each container
+const (
+	podNamespaceLabel = "io.kubernetes.pod.namespace"
+	podNameLabel      = "io.kubernetes.pod.name"
+)
+
+type CRI struct {
+	config       *config.Cluster
+	check        *circonus.Check
+	log          zerolog.Logger
+	socket       string
+	node         string
+	running      bool
+	apiTimelimit time.Duration
+	sync.Mutex
+	ts *time.Time
+}
+
+// NOTES:
+// The CRI collector runs in the agent daemonset (see deploy/optional/daemonset.yaml), each agent
+// pod mounts the node container runtime socket (containerd or cri-o) and queries it directly
+// for container cpu, memory, and writable layer stats. It is an alternative source when the
+// kubelet summary api is disabled or truncated, the node name is passed with the downward api.
+
+func New(cfg *config.Cluster, parentLog zerolog.Logger, check *circonus.Check) (*CRI, error) {
+	if cfg == nil {
+		return nil, errors.New("invalid cluster config (nil)")
+	}
+	if check == nil {
+		return nil, errors.New("invalid check (nil)")
+	}
+	if cfg.CRISocket == "" {
+		return nil, errors.New("invalid cri socket (empty)")
+	}
+	if cfg.NodeName == "" {
+		return nil, errors.New("invalid node name (empty)")
+	}
+
+	cr := &CRI{
+		config: cfg,
+		check:  check,
+		socket: strings.TrimPrefix(cfg.CRISocket, "unix://"),
+		node:   cfg.NodeName,
+		log:    parentLog.With().Str("collector", "cri").Logger(),
+	}
+
+	if cfg.APITimelimit != "" {
+		v, err := time.ParseDuration(cfg.APITimelimit)
+		if err != nil {
+			cr.log.Error().Err(err).Msg("parsing api timelimit, using default")
+		} else {
+			cr.apiTimelimit = v
+		}
+	}
+
+	if cr.apiTimelimit == time.Duration(0) {
+		v, err := time.ParseDuration(defaults.K8SAPITimelimit)
+		if err != nil {
+			cr.log.Fatal().Err(err).Msg("parsing DEFAULT api timelimit")
+		}
+		cr.apiTimelimit = v
+	}
+
+	return cr, nil
+}
+
+func (cr *CRI) ID() string {
+	return "cri"
+}
+
+// Collect container stats from the container runtime
+func (cr *CRI) Collect(ctx context.Context, _ *tls.Config, ts *time.Time) {
+	cr.Lock()
+	if cr.running {
+		cr.log.Warn().Msg("already running")
+		cr.Unlock()
+		return
+	}
+	cr.running = true
+	cr.ts = ts
+	cr.Unlock()
+
+	defer func() {
+		if r := recover(); r != nil {
+			cr.log.Error().Interface("panic", r).Msg("recover")
+			cr.Lock()
+			cr.running = false
+			cr.Unlock()
+		}
+	}()
+
+	collectStart := time.Now()
+
+	if err := cr.containerMetrics(ctx); err != nil {
+		cr.log.Error().Err(err).Str("socket", cr.socket).Msg("container stats")
+	}
+
+	cr.check.AddHistSample("collect_latency", cgm.Tags{
+		cgm.Tag{Category: "source", Value: release.NAME},
+		cgm.Tag{Category: "op", Value: "collect_cri"},
+		cgm.Tag{Category: "units", Value: "milliseconds"},
+	}, float64(time.Since(collectStart).Milliseconds()))
+	cr.log.Debug().Str("duration", time.Since(collectStart).String()).Msg("cri collect end")
+	cr.Lock()
+	cr.running = false
+	cr.Unlock()
+}
+
+// containerMetrics emits cpu, memory, and writable layer usage for each container on the node
+func (cr *CRI) containerMetrics(ctx context.Context) error {
+	reqCtx, cancel := context.WithTimeout(ctx, cr.apiTimelimit)
+	defer cancel()
+
+	conn, err := grpc.DialContext(reqCtx, cr.socket,
+		grpc.WithInsecure(),
+		grpc.WithBlock(),
+		grpc.WithContextDialer(func(ctx context.Context, addr string) (net.Conn, error) {
+			var d net.Dialer
+			return d.DialContext(ctx, "unix", addr)
+		}))
+	if err != nil {
+		cr.apiError("dial")
+		return errors.Wrap(err, "connecting to runtime")
+	}
+	defer conn.Close()
+
+	client := runtimeapi.NewRuntimeServiceClient(conn)
+
+	runtimeName := "unknown"
+	if v, err := client.Version(reqCtx, &runtimeapi.VersionRequest{}); err != nil {
+		cr.log.Warn().Err(err).Msg("runtime version")
+	} else {
+		runtimeName = v.RuntimeName
+	}
+
+	start := time.Now()
+	resp, err := client.ListContainerStats(reqCtx, &runtimeapi.ListContainerStatsRequest{})
+	if err != nil {
+		cr.apiError("container-stats")
+		return errors.Wrap(err, "listing container stats")
+	}
+	cr.check.AddHistSample("collect_latency", cgm.Tags{
+		cgm.Tag{Category: "source", Value: release.NAME},
+		cgm.Tag{Category: "request", Value: "container-stats"},
+		cgm.Tag{Category: "target", Value: "cri"},
+		cgm.Tag{Category: "units", Value: "milliseconds"},
+	}, float64(time.Since(start).Milliseconds()))
+
+	metrics := make(map[string]circonus.MetricSample)
+	for _, stats := range resp.GetStats() {
+		streamTags := cr.streamTags(stats.GetAttributes(), runtimeName)
+		if streamTags == nil {
+			continue // not a kubernetes container
+		}
+		if v := stats.GetCpu().GetUsageCoreNanoSeconds(); v != nil {
+			_ = cr.check.QueueMetricSample(metrics, "cri_container_cpu_usage", circonus.MetricTypeUint64, append(streamTags, "units:nanoseconds"), []string{}, v.GetValue(), cr.ts)
+		}
+		if v := stats.GetMemory().GetWorkingSetBytes(); v != nil {
+			_ = cr.check.QueueMetricSample(metrics, "cri_container_memory_working_set", circonus.MetricTypeUint64, append(streamTags, "units:bytes"), []string{}, v.GetValue(), cr.ts)
+		}
+		if v := stats.GetWritableLayer().GetUsedBytes(); v != nil {
+			_ = cr.check.QueueMetricSample(metrics, "cri_container_fs_used", circonus.MetricTypeUint64, append(streamTags, "units:bytes"), []string{}, v.GetValue(), cr.ts)
+		}
+		if v := stats.GetWritableLayer().GetInodesUsed(); v != nil {
+			_ = cr.check.QueueMetricSample(metrics, "cri_container_fs_inodes_used", circonus.MetricTypeUint64, append(streamTags, "units:inodes"), []string{}, v.GetValue(), cr.ts)
+		}
+	}
+
+	if len(metrics) == 0 {
+		return nil
+	}
+	return cr.check.SubmitQueue(ctx, metrics, cr.log.With().Str("type", "cri").Logger())
+}
+
+// streamTags returns the stream tags for a container, nil if the container
+// does not belong to a kubernetes pod
+func (cr *CRI) streamTags(attrs *runtimeapi.ContainerAttributes, runtimeName string) []string {
+	labels := attrs.GetLabels()
+	namespace, pod := labels[podNamespaceLabel], labels[podNameLabel]
+	if namespace == "" || pod == "" {
+		return nil
+	}
+	return []string{
+		"source:cri",
+		"source_type:containers",
+		"runtime:" + runtimeName,
+		"node:" + cr.node,
+		"namespace:" + namespace,
+		"pod:" + pod,
+		"container_name:" + attrs.GetMetadata().GetName(),
+		"__rollup:false", // prevent high cardinality metrics from rolling up
+	}
+}
+
+func (cr *CRI) apiError(request string) {
+	cr.check.IncrementCounter("collect_api_errors", cgm.Tags{
+		cgm.Tag{Category: "source", Value: release.NAME},
+		cgm.Tag{Category: "request", Value: request},
+		cgm.Tag{Category: "target", Value: "cri"},
+	})
+}
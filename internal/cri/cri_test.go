@@ -0,0 +1,49 @@
+// Copyright © 2019 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+package cri
+
+import (
+	"reflect"
+	"testing"
+
+	runtimeapi "k8s.io/cri-api/pkg/apis/runtime/v1alpha2"
+)
+
+func TestStreamTags(t *testing.T) {
+	cr := &CRI{node: "node1"}
+
+	tests := []struct {
+		name  string
+		attrs *runtimeapi.ContainerAttributes
+		want  []string
+	}{
+		{"nil", nil, nil},
+		{"not a pod container", &runtimeapi.ContainerAttributes{Labels: map[string]string{"foo": "bar"}}, nil},
+		{"pod container", &runtimeapi.ContainerAttributes{
+			Metadata: &runtimeapi.ContainerMetadata{Name: "app"},
+			Labels: map[string]string{
+				podNamespaceLabel: "default",
+				podNameLabel:      "web-1",
+			},
+		}, []string{
+			"source:cri",
+			"source_type:containers",
+			"runtime:containerd",
+			"node:node1",
+			"namespace:default",
+			"pod:web-1",
+			"container_name:app",
+			"__rollup:false",
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := cr.streamTags(tt.attrs, "containerd"); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("streamTags() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,198 @@
+// Copyright © 2019 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+// Package crstate is the config driven custom resource state collector
+package crstate
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"sync"
+	"time"
+
+	cgm "github.com/circonus-labs/circonus-gometrics/v3"
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/circonus"
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/config"
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/config/defaults"
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/k8s"
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/release"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+)
+
+type CRState struct {
+	config       *config.Cluster
+	check        *circonus.Check
+	log          zerolog.Logger
+	dynamic      dynamic.Interface
+	resources    []*resourceDef
+	running      bool
+	apiTimelimit time.Duration
+	sync.Mutex
+	ts *time.Time
+}
+
+// NOTES:
+// Custom resources to collect are defined in a json file (see definitions.go), each defines a
+// GroupVersionResource and JSONPath expressions for fields to emit as gauge or text metrics and
+// fields to use as stream tags. The agent service account needs list access to the resources.
+
+func New(cfg *config.Cluster, parentLog zerolog.Logger, check *circonus.Check) (*CRState, error) {
+	if cfg == nil {
+		return nil, errors.New("invalid cluster config (nil)")
+	}
+	if check == nil {
+		return nil, errors.New("invalid check (nil)")
+	}
+
+	cr := &CRState{
+		config: cfg,
+		check:  check,
+		log:    parentLog.With().Str("collector", "custom-resources").Logger(),
+	}
+
+	if cfg.APITimelimit != "" {
+		v, err := time.ParseDuration(cfg.APITimelimit)
+		if err != nil {
+			cr.log.Error().Err(err).Msg("parsing api timelimit, using default")
+		} else {
+			cr.apiTimelimit = v
+		}
+	}
+
+	if cr.apiTimelimit == time.Duration(0) {
+		v, err := time.ParseDuration(defaults.K8SAPITimelimit)
+		if err != nil {
+			cr.log.Fatal().Err(err).Msg("parsing DEFAULT api timelimit")
+		}
+		cr.apiTimelimit = v
+	}
+
+	resources, err := loadDefinitions(cfg.CustomResourcesFile)
+	if err != nil {
+		return nil, errors.Wrap(err, "loading custom resource definitions")
+	}
+	if len(resources) == 0 {
+		return nil, errors.Errorf("no custom resources defined (%s)", cfg.CustomResourcesFile)
+	}
+	cr.resources = resources
+
+	restCfg, err := k8s.RESTConfig(cfg)
+	if err != nil {
+		return nil, errors.Wrap(err, "rest config")
+	}
+	restCfg.Timeout = cr.apiTimelimit
+	dyn, err := dynamic.NewForConfig(restCfg)
+	if err != nil {
+		return nil, errors.Wrap(err, "dynamic client")
+	}
+	cr.dynamic = dyn
+
+	return cr, nil
+}
+
+func (cr *CRState) ID() string {
+	return "custom-resources"
+}
+
+// Collect custom resource state
+func (cr *CRState) Collect(ctx context.Context, _ *tls.Config, ts *time.Time) {
+	cr.Lock()
+	if cr.running {
+		cr.log.Warn().Msg("already running")
+		cr.Unlock()
+		return
+	}
+	cr.running = true
+	cr.ts = ts
+	cr.Unlock()
+
+	defer func() {
+		if r := recover(); r != nil {
+			cr.log.Error().Interface("panic", r).Msg("recover")
+			cr.Lock()
+			cr.running = false
+			cr.Unlock()
+		}
+	}()
+
+	collectStart := time.Now()
+
+	cr.resourceMetrics(ctx)
+
+	cr.check.AddHistSample("collect_latency", cgm.Tags{
+		cgm.Tag{Category: "source", Value: release.NAME},
+		cgm.Tag{Category: "op", Value: "collect_custom-resources"},
+		cgm.Tag{Category: "units", Value: "milliseconds"},
+	}, float64(time.Since(collectStart).Milliseconds()))
+	cr.log.Debug().Str("duration", time.Since(collectStart).String()).Msg("custom-resources collect end")
+	cr.Lock()
+	cr.running = false
+	cr.Unlock()
+}
+
+// resourceMetrics emits the configured fields of each object for each custom resource definition
+func (cr *CRState) resourceMetrics(ctx context.Context) {
+	for _, def := range cr.resources {
+		if ctx.Err() != nil {
+			return
+		}
+		gvr := schema.GroupVersionResource{Group: def.Group, Version: def.Version, Resource: def.Resource}
+		list, err := cr.dynamic.Resource(gvr).Namespace(def.Namespace).List(metav1.ListOptions{LabelSelector: def.LabelSelector})
+		if err != nil {
+			cr.check.IncrementCounter("collect_api_errors", cgm.Tags{
+				cgm.Tag{Category: "source", Value: release.NAME},
+				cgm.Tag{Category: "request", Value: def.Resource + "-list"},
+				cgm.Tag{Category: "target", Value: "api-server"},
+			})
+			cr.log.Error().Err(err).Str("resource", gvr.String()).Msg("listing custom resources")
+			continue
+		}
+
+		metrics := make(map[string]circonus.MetricSample)
+		for i := range list.Items {
+			obj := &list.Items[i]
+			streamTags := []string{
+				"source:crstate",
+				"source_type:" + def.Resource,
+				"namespace:" + obj.GetNamespace(),
+				"name:" + obj.GetName(),
+				"__rollup:false", // prevent high cardinality metrics from rolling up
+			}
+			for _, tag := range def.Tags {
+				if v, ok := tag.value(obj.Object); ok {
+					streamTags = append(streamTags, tag.Name+":"+fmt.Sprintf("%v", v))
+				}
+			}
+			for _, m := range def.Metrics {
+				v, ok := m.value(obj.Object)
+				if !ok {
+					continue
+				}
+				if m.Type == metricTypeText {
+					_ = cr.check.QueueMetricSample(metrics, m.Name, circonus.MetricTypeString, streamTags, []string{}, fmt.Sprintf("%v", v), cr.ts)
+					continue
+				}
+				gauge, ok := gaugeValue(v)
+				if !ok {
+					cr.log.Debug().Str("metric", m.Name).Interface("value", v).Msg("non-numeric value, skipping")
+					continue
+				}
+				_ = cr.check.QueueMetricSample(metrics, m.Name, circonus.MetricTypeFloat64, streamTags, []string{}, gauge, cr.ts)
+			}
+		}
+
+		if len(metrics) == 0 {
+			continue
+		}
+		if err := cr.check.SubmitQueue(ctx, metrics, cr.log.With().Str("type", gvr.String()).Logger()); err != nil {
+			cr.log.Warn().Err(err).Msg("submitting metrics")
+		}
+	}
+}
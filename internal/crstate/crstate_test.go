@@ -0,0 +1,87 @@
+// Copyright © 2019 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+package crstate
+
+import (
+	"testing"
+)
+
+func TestParseDefinitions(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		wantErr bool
+	}{
+		{"valid", `{"custom_resources":[{"group":"example.com","version":"v1","resource":"widgets","metrics":[{"name":"ready","path":"{.status.ready}"}]}]}`, false},
+		{"invalid json", `{`, true},
+		{"no resource", `{"custom_resources":[{"group":"example.com","version":"v1","metrics":[{"name":"ready","path":"{.status.ready}"}]}]}`, true},
+		{"no metrics", `{"custom_resources":[{"group":"example.com","version":"v1","resource":"widgets"}]}`, true},
+		{"bad type", `{"custom_resources":[{"version":"v1","resource":"widgets","metrics":[{"name":"ready","path":"{.status.ready}","type":"histogram"}]}]}`, true},
+		{"bad path", `{"custom_resources":[{"version":"v1","resource":"widgets","metrics":[{"name":"ready","path":"{.status.ready"}]}]}`, true},
+	}
+
+	for _, tst := range tests {
+		tst := tst
+		t.Run(tst.name, func(t *testing.T) {
+			_, err := parseDefinitions([]byte(tst.data))
+			if (err != nil) != tst.wantErr {
+				t.Errorf("parseDefinitions() error = %v, wantErr %t", err, tst.wantErr)
+			}
+		})
+	}
+}
+
+func TestFieldValue(t *testing.T) {
+	defs, err := parseDefinitions([]byte(`{"custom_resources":[{"version":"v1","resource":"widgets","metrics":[
+		{"name":"replicas","path":"{.status.replicas}"},
+		{"name":"missing","path":"{.status.missing}"}]}]}`))
+	if err != nil {
+		t.Fatalf("unexpected error (%s)", err)
+	}
+
+	obj := map[string]interface{}{
+		"status": map[string]interface{}{"replicas": int64(3)},
+	}
+
+	v, ok := defs[0].Metrics[0].value(obj)
+	if !ok {
+		t.Fatal("expected value")
+	}
+	if g, ok := gaugeValue(v); !ok || g != 3 {
+		t.Errorf("gaugeValue() = %v, %t, want 3, true", g, ok)
+	}
+
+	if _, ok := defs[0].Metrics[1].value(obj); ok {
+		t.Error("expected no value for missing field")
+	}
+}
+
+func TestGaugeValue(t *testing.T) {
+	tests := []struct {
+		name string
+		v    interface{}
+		want float64
+		ok   bool
+	}{
+		{"float", 1.5, 1.5, true},
+		{"int64", int64(2), 2, true},
+		{"true", true, 1, true},
+		{"false string", "False", 0, true},
+		{"numeric string", "42", 42, true},
+		{"text", "Ready", 0, false},
+		{"map", map[string]interface{}{}, 0, false},
+	}
+
+	for _, tst := range tests {
+		tst := tst
+		t.Run(tst.name, func(t *testing.T) {
+			got, ok := gaugeValue(tst.v)
+			if got != tst.want || ok != tst.ok {
+				t.Errorf("gaugeValue() = %v, %t, want %v, %t", got, ok, tst.want, tst.ok)
+			}
+		})
+	}
+}
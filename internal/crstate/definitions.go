@@ -0,0 +1,180 @@
+// Copyright © 2019 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+package crstate
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"strconv"
+
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/k8s"
+	"github.com/pkg/errors"
+	"k8s.io/client-go/util/jsonpath"
+)
+
+// Example definitions file:
+//
+//   {
+//     "custom_resources": [
+//       {
+//         "group": "example.com",
+//         "version": "v1",
+//         "resource": "widgets",
+//         "namespace": "",
+//         "label_selector": "",
+//         "metrics": [
+//           {"name": "widget_ready_replicas", "path": "{.status.readyReplicas}"},
+//           {"name": "widget_phase", "path": "{.status.phase}", "type": "text"}
+//         ],
+//         "tags": [
+//           {"name": "owner", "path": "{.metadata.labels.owner}"}
+//         ]
+//       }
+//     ]
+//   }
+//
+// Metric type defaults to gauge, gauge values must be numeric or boolean (true=1, false=0).
+
+const (
+	metricTypeGauge = "gauge"
+	metricTypeText  = "text"
+)
+
+type definitions struct {
+	Resources []*resourceDef `json:"custom_resources"`
+}
+
+type resourceDef struct {
+	Group         string      `json:"group"`
+	Version       string      `json:"version"`
+	Resource      string      `json:"resource"`
+	Namespace     string      `json:"namespace"`      // blank for all namespaces
+	LabelSelector string      `json:"label_selector"` // blank for all objects
+	Metrics       []*fieldDef `json:"metrics"`
+	Tags          []*fieldDef `json:"tags"`
+}
+
+type fieldDef struct {
+	Name string `json:"name"`
+	Path string `json:"path"`
+	Type string `json:"type"` // metrics only, gauge (default) or text
+	jp   *jsonpath.JSONPath
+}
+
+// loadDefinitions reads and validates the custom resource definitions file
+func loadDefinitions(file string) ([]*resourceDef, error) {
+	if file == "" {
+		return nil, errors.New("invalid custom resources file (empty)")
+	}
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, errors.Wrap(err, "reading custom resources file")
+	}
+	return parseDefinitions(data)
+}
+
+// Access returns the api accesses needed to list the custom resources in the definitions file
+func Access(file string) ([]k8s.Access, error) {
+	defs, err := loadDefinitions(file)
+	if err != nil {
+		return nil, err
+	}
+	access := make([]k8s.Access, 0, len(defs))
+	for _, def := range defs {
+		access = append(access, k8s.Access{Verb: "list", Group: def.Group, Resource: def.Resource})
+	}
+	return access, nil
+}
+
+// parseDefinitions parses the custom resource definitions and compiles the jsonpath expressions
+func parseDefinitions(data []byte) ([]*resourceDef, error) {
+	var defs definitions
+	if err := json.Unmarshal(data, &defs); err != nil {
+		return nil, errors.Wrap(err, "parsing custom resources")
+	}
+
+	for _, def := range defs.Resources {
+		if def.Version == "" || def.Resource == "" {
+			return nil, errors.Errorf("invalid custom resource (%s/%s/%s), version and resource are required", def.Group, def.Version, def.Resource)
+		}
+		if len(def.Metrics) == 0 {
+			return nil, errors.Errorf("invalid custom resource (%s), no metrics defined", def.Resource)
+		}
+		for _, m := range def.Metrics {
+			if m.Type == "" {
+				m.Type = metricTypeGauge
+			}
+			if m.Type != metricTypeGauge && m.Type != metricTypeText {
+				return nil, errors.Errorf("invalid metric type (%s) for %s, must be gauge or text", m.Type, m.Name)
+			}
+			if err := m.compile(); err != nil {
+				return nil, err
+			}
+		}
+		for _, t := range def.Tags {
+			if err := t.compile(); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return defs.Resources, nil
+}
+
+// compile parses the field jsonpath expression
+func (f *fieldDef) compile() error {
+	if f.Name == "" || f.Path == "" {
+		return errors.Errorf("invalid field (%s/%s), name and path are required", f.Name, f.Path)
+	}
+	jp := jsonpath.New(f.Name)
+	jp.AllowMissingKeys(true)
+	if err := jp.Parse(f.Path); err != nil {
+		return errors.Wrapf(err, "parsing path for %s", f.Name)
+	}
+	f.jp = jp
+	return nil
+}
+
+// value returns the first value matching the field jsonpath in the object
+func (f *fieldDef) value(obj map[string]interface{}) (interface{}, bool) {
+	results, err := f.jp.FindResults(obj)
+	if err != nil {
+		return nil, false
+	}
+	for _, r := range results {
+		for _, v := range r {
+			if v.IsValid() && v.CanInterface() {
+				return v.Interface(), true
+			}
+		}
+	}
+	return nil, false
+}
+
+// gaugeValue converts a field value to a float, numeric strings and booleans are converted
+func gaugeValue(v interface{}) (float64, bool) {
+	switch tv := v.(type) {
+	case float64:
+		return tv, true
+	case int64:
+		return float64(tv), true
+	case int:
+		return float64(tv), true
+	case bool:
+		if tv {
+			return 1, true
+		}
+		return 0, true
+	case string:
+		if b, err := strconv.ParseBool(tv); err == nil {
+			return gaugeValue(b)
+		}
+		if f, err := strconv.ParseFloat(tv, 64); err == nil {
+			return f, true
+		}
+	}
+	return 0, false
+}
@@ -0,0 +1,129 @@
+// Copyright © 2019 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+// Package dcgm is the nvidia dcgm-exporter (GPU) metrics collector
+package dcgm
+
+import (
+	"context"
+	"crypto/tls"
+	"regexp"
+	"sync"
+	"time"
+
+	cgm "github.com/circonus-labs/circonus-gometrics/v3"
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/circonus"
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/config"
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/config/defaults"
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/release"
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/scrape"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog"
+)
+
+// only forward GPU utilization, frame buffer memory, and ECC error metric families, the
+// gpu (index), UUID, and modelName labels are carried through as stream tags
+var familyFilter = regexp.MustCompile(`^DCGM_FI_DEV_(GPU_UTIL|MEM_COPY_UTIL|FB_(USED|FREE)|ECC_(SBE|DBE)_(VOL|AGG)_TOTAL)$`)
+
+type DCGM struct {
+	config       *config.Cluster
+	check        *circonus.Check
+	log          zerolog.Logger
+	running      bool
+	apiTimelimit time.Duration
+	sync.Mutex
+	ts *time.Time
+}
+
+// NOTES:
+// nvidia-dcgm-exporter runs as a daemonset on GPU nodes (e.g. deployed by the nvidia gpu-operator)
+// and serves one stream per GPU on 9400 /metrics.
+
+func New(cfg *config.Cluster, parentLog zerolog.Logger, check *circonus.Check) (*DCGM, error) {
+	if cfg == nil {
+		return nil, errors.New("invalid cluster config (nil)")
+	}
+	if check == nil {
+		return nil, errors.New("invalid check (nil)")
+	}
+
+	dc := &DCGM{
+		config: cfg,
+		check:  check,
+		log:    parentLog.With().Str("collector", "dcgm-exporter").Logger(),
+	}
+
+	if cfg.APITimelimit != "" {
+		v, err := time.ParseDuration(cfg.APITimelimit)
+		if err != nil {
+			dc.log.Error().Err(err).Msg("parsing api timelimit, using default")
+		} else {
+			dc.apiTimelimit = v
+		}
+	}
+
+	if dc.apiTimelimit == time.Duration(0) {
+		v, err := time.ParseDuration(defaults.K8SAPITimelimit)
+		if err != nil {
+			dc.log.Fatal().Err(err).Msg("parsing DEFAULT api timelimit")
+		}
+		dc.apiTimelimit = v
+	}
+
+	return dc, nil
+}
+
+func (dc *DCGM) ID() string {
+	return "dcgm-exporter"
+}
+
+// Collect metrics from dcgm-exporter pods
+func (dc *DCGM) Collect(ctx context.Context, tlsConfig *tls.Config, ts *time.Time) {
+	dc.Lock()
+	if dc.running {
+		dc.log.Warn().Msg("already running")
+		dc.Unlock()
+		return
+	}
+	dc.running = true
+	dc.ts = ts
+	dc.Unlock()
+
+	defer func() {
+		if r := recover(); r != nil {
+			dc.log.Error().Interface("panic", r).Msg("recover")
+			dc.Lock()
+			dc.running = false
+			dc.Unlock()
+		}
+	}()
+
+	collectStart := time.Now()
+
+	dc.podMetrics(ctx, tlsConfig)
+
+	dc.check.AddHistSample("collect_latency", cgm.Tags{
+		cgm.Tag{Category: "source", Value: release.NAME},
+		cgm.Tag{Category: "op", Value: "collect_dcgm-exporter"},
+		cgm.Tag{Category: "units", Value: "milliseconds"},
+	}, float64(time.Since(collectStart).Milliseconds()))
+	dc.log.Debug().Str("duration", time.Since(collectStart).String()).Msg("dcgm-exporter collect end")
+	dc.Lock()
+	dc.running = false
+	dc.Unlock()
+}
+
+// podMetrics collects metrics from each dcgm-exporter pod via the api-server proxy,
+// one per GPU node so use the same number of workers as node collection
+func (dc *DCGM) podMetrics(ctx context.Context, tlsConfig *tls.Config) {
+	scrape.PodMetrics(ctx, dc.check, dc.log, dc.config, tlsConfig, dc.apiTimelimit, scrape.Component{
+		Name:         "dcgm-exporter",
+		Namespaces:   []string{dc.config.DCGMNamespace},
+		Selector:     dc.config.DCGMSelector,
+		Port:         dc.config.DCGMPort,
+		FamilyFilter: familyFilter,
+		Workers:      int(dc.config.NodePoolSize),
+	}, dc.ts)
+}
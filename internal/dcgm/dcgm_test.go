@@ -0,0 +1,70 @@
+// Copyright © 2019 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+package dcgm
+
+import (
+	"context"
+	"crypto/tls"
+	"testing"
+
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/config"
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/scrape/scrapetest"
+	"github.com/rs/zerolog"
+)
+
+func TestFamilyFilter(t *testing.T) {
+	tests := []struct {
+		family string
+		want   bool
+	}{
+		{"DCGM_FI_DEV_GPU_UTIL", true},
+		{"DCGM_FI_DEV_MEM_COPY_UTIL", true},
+		{"DCGM_FI_DEV_FB_USED", true},
+		{"DCGM_FI_DEV_FB_FREE", true},
+		{"DCGM_FI_DEV_ECC_SBE_VOL_TOTAL", true},
+		{"DCGM_FI_DEV_ECC_DBE_AGG_TOTAL", true},
+		{"DCGM_FI_DEV_SM_CLOCK", false},
+		{"DCGM_FI_DEV_FB_USED_PERCENT", false},
+	}
+
+	for _, tt := range tests {
+		if got := familyFilter.MatchString(tt.family); got != tt.want {
+			t.Errorf("%s: expected %v, got %v", tt.family, tt.want, got)
+		}
+	}
+}
+
+func TestCollect(t *testing.T) {
+	ts := scrapetest.NewServer(map[string]string{
+		"/api/v1/namespaces/gpu-operator/pods": `{"items":[{"metadata":{"name":"nvidia-dcgm-exporter-abcde","namespace":"gpu-operator"},"spec":{"nodeName":"gpu1"},"status":{"phase":"Running","podIP":"127.0.0.1"}}]}`,
+		"/api/v1/namespaces/gpu-operator/pods/nvidia-dcgm-exporter-abcde:9400/proxy/metrics": `# TYPE DCGM_FI_DEV_GPU_UTIL gauge
+DCGM_FI_DEV_GPU_UTIL{gpu="0",UUID="GPU-1234",modelName="Tesla T4"} 87
+`,
+	})
+	defer ts.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	check, rec := scrapetest.NewCheck(ctx, t)
+
+	dc, err := New(&config.Cluster{
+		URL:           ts.URL,
+		DCGMNamespace: "gpu-operator",
+		DCGMSelector:  "app=nvidia-dcgm-exporter",
+		DCGMPort:      "9400",
+	}, zerolog.Nop(), check)
+	if err != nil {
+		t.Fatalf("unexpected error (%s)", err)
+	}
+	dc.Collect(ctx, &tls.Config{}, nil)
+
+	// the exporter is per node, gpus are identified by the exporter's labels
+	scrapetest.ExpectTags(t, rec, "DCGM_FI_DEV_GPU_UTIL",
+		"source:dcgm-exporter", "node:gpu1", "gpu:0", "UUID:GPU-1234", "modelName:Tesla T4")
+	if tags, _ := rec.Tags("DCGM_FI_DEV_GPU_UTIL"); scrapetest.HasTag(tags, "pod:nvidia-dcgm-exporter-abcde") {
+		t.Errorf("expected no pod tag, got %v", tags)
+	}
+}
@@ -0,0 +1,286 @@
+// Copyright © 2019 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+// Package deprecatedapis is the deprecated api usage collector
+package deprecatedapis
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	cgm "github.com/circonus-labs/circonus-gometrics/v3"
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/circonus"
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/config"
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/config/defaults"
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/k8s"
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/release"
+	"github.com/pkg/errors"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/prometheus/common/expfmt"
+	"github.com/rs/zerolog"
+)
+
+type DeprecatedAPIs struct {
+	config       *config.Cluster
+	check        *circonus.Check
+	log          zerolog.Logger
+	running      bool
+	apiTimelimit time.Duration
+	sync.Mutex
+	ts *time.Time
+}
+
+// NOTES:
+// The api-server (v1.19+) sets apiserver_requested_deprecated_apis for each deprecated
+// group/version/resource requested since it started, the request count for each is taken from
+// apiserver_request_total. The api-server metrics do not identify clients, so the text metric
+// lists the deprecated apis in use (and the release removing them) rather than user agents, use
+// the api-server audit log (k8s.io/deprecated annotation) to find the clients.
+
+func New(cfg *config.Cluster, parentLog zerolog.Logger, check *circonus.Check) (*DeprecatedAPIs, error) {
+	if cfg == nil {
+		return nil, errors.New("invalid cluster config (nil)")
+	}
+	if check == nil {
+		return nil, errors.New("invalid check (nil)")
+	}
+
+	da := &DeprecatedAPIs{
+		config: cfg,
+		check:  check,
+		log:    parentLog.With().Str("collector", "deprecated-apis").Logger(),
+	}
+
+	if cfg.APITimelimit != "" {
+		v, err := time.ParseDuration(cfg.APITimelimit)
+		if err != nil {
+			da.log.Error().Err(err).Msg("parsing api timelimit, using default")
+		} else {
+			da.apiTimelimit = v
+		}
+	}
+
+	if da.apiTimelimit == time.Duration(0) {
+		v, err := time.ParseDuration(defaults.K8SAPITimelimit)
+		if err != nil {
+			da.log.Fatal().Err(err).Msg("parsing DEFAULT api timelimit")
+		}
+		da.apiTimelimit = v
+	}
+
+	return da, nil
+}
+
+func (da *DeprecatedAPIs) ID() string {
+	return "deprecated-apis"
+}
+
+// Collect deprecated api usage from the api-server metrics
+func (da *DeprecatedAPIs) Collect(ctx context.Context, tlsConfig *tls.Config, ts *time.Time) {
+	da.Lock()
+	if da.running {
+		da.log.Warn().Msg("already running")
+		da.Unlock()
+		return
+	}
+	da.running = true
+	da.ts = ts
+	da.Unlock()
+
+	defer func() {
+		if r := recover(); r != nil {
+			da.log.Error().Interface("panic", r).Msg("recover")
+			da.Lock()
+			da.running = false
+			da.Unlock()
+		}
+	}()
+
+	collectStart := time.Now()
+
+	metricURL := da.config.URL + "/metrics"
+	if err := da.metrics(ctx, tlsConfig, metricURL); err != nil {
+		da.log.Error().Err(err).Str("url", metricURL).Msg("deprecated api usage")
+	}
+
+	da.check.AddHistSample("collect_latency", cgm.Tags{
+		cgm.Tag{Category: "source", Value: release.NAME},
+		cgm.Tag{Category: "op", Value: "collect_deprecated-apis"},
+		cgm.Tag{Category: "units", Value: "milliseconds"},
+	}, float64(time.Since(collectStart).Milliseconds()))
+	da.log.Debug().Str("duration", time.Since(collectStart).String()).Msg("deprecated-apis collect end")
+	da.Lock()
+	da.running = false
+	da.Unlock()
+}
+
+func (da *DeprecatedAPIs) metrics(ctx context.Context, tlsConfig *tls.Config, metricURL string) error {
+	client, err := k8s.NewAPIClient(tlsConfig, da.apiTimelimit)
+	if err != nil {
+		return errors.Wrap(err, "/metrics cli")
+	}
+	defer client.CloseIdleConnections()
+
+	da.log.Debug().Str("url", metricURL).Msg("metrics")
+	req, err := k8s.NewAPIRequest(da.config.Token(), metricURL)
+	if err != nil {
+		return errors.Wrap(err, "/metrics req")
+	}
+	req = req.WithContext(ctx)
+
+	start := time.Now()
+	resp, err := client.Do(req)
+	if err != nil {
+		da.check.IncrementCounter("collect_api_errors", cgm.Tags{
+			cgm.Tag{Category: "source", Value: release.NAME},
+			cgm.Tag{Category: "request", Value: "metrics"},
+			cgm.Tag{Category: "target", Value: "api-server"},
+		})
+		return err
+	}
+	defer resp.Body.Close()
+	da.check.AddHistSample("collect_latency", cgm.Tags{
+		cgm.Tag{Category: "source", Value: release.NAME},
+		cgm.Tag{Category: "request", Value: "metrics"},
+		cgm.Tag{Category: "target", Value: "api-server"},
+		cgm.Tag{Category: "units", Value: "milliseconds"},
+	}, float64(time.Since(start).Milliseconds()))
+
+	if resp.StatusCode != http.StatusOK {
+		da.check.IncrementCounter("collect_api_errors", cgm.Tags{
+			cgm.Tag{Category: "source", Value: release.NAME},
+			cgm.Tag{Category: "request", Value: "metrics"},
+			cgm.Tag{Category: "target", Value: "api-server"},
+			cgm.Tag{Category: "code", Value: fmt.Sprintf("%d", resp.StatusCode)},
+		})
+		data, err := ioutil.ReadAll(resp.Body)
+		if err != nil {
+			da.log.Error().Err(err).Str("url", metricURL).Msg("reading response")
+			return err
+		}
+		da.log.Warn().Str("status", resp.Status).RawJSON("response", data).Msg("error from API server")
+		return errors.New("error response from api server")
+	}
+
+	usage, err := deprecatedUsage(resp.Body)
+	if err != nil {
+		return errors.Wrap(err, "parsing api-server metrics")
+	}
+
+	metrics := make(map[string]circonus.MetricSample)
+	inUse := make([]string, 0, len(usage))
+	for _, u := range usage {
+		streamTags := []string{
+			"source:deprecated-apis",
+			"source_type:api-server",
+			"group:" + u.group,
+			"version:" + u.version,
+			"resource:" + u.resource,
+			"removed_release:" + u.removedRelease,
+		}
+		_ = da.check.QueueMetricSample(metrics, "deprecated_api_requests", circonus.MetricTypeUint64, streamTags, []string{}, u.requests, da.ts)
+		inUse = append(inUse, u.String())
+	}
+
+	streamTags := []string{
+		"source:deprecated-apis",
+		"source_type:api-server",
+	}
+	_ = da.check.QueueMetricSample(metrics, "deprecated_apis_requested", circonus.MetricTypeUint64, streamTags, []string{}, uint64(len(usage)), da.ts)
+	if len(inUse) > 0 {
+		_ = da.check.QueueMetricSample(metrics, "deprecated_apis", circonus.MetricTypeString, streamTags, []string{}, strings.Join(inUse, ","), da.ts)
+	}
+
+	return da.check.SubmitQueue(ctx, metrics, da.log.With().Str("type", "deprecated_apis").Logger())
+}
+
+type apiUsage struct {
+	group          string
+	version        string
+	resource       string
+	removedRelease string
+	requests       uint64
+}
+
+func (u apiUsage) String() string {
+	s := u.group + "/" + u.version + "/" + u.resource
+	if u.removedRelease != "" {
+		s += " (removed in " + u.removedRelease + ")"
+	}
+	return s
+}
+
+// deprecatedUsage returns the deprecated apis requested and the number of requests for each,
+// from api-server prometheus text metrics, sorted by group/version/resource
+func deprecatedUsage(data io.Reader) ([]apiUsage, error) {
+	var parser expfmt.TextParser
+	families, err := parser.TextToMetricFamilies(data)
+	if err != nil {
+		return nil, err
+	}
+
+	deprecated, ok := families["apiserver_requested_deprecated_apis"]
+	if !ok {
+		return nil, nil
+	}
+
+	usage := make(map[string]*apiUsage)
+	for _, m := range deprecated.Metric {
+		u := &apiUsage{
+			group:          groupName(labelValue(m, "group")),
+			version:        labelValue(m, "version"),
+			resource:       labelValue(m, "resource"),
+			removedRelease: labelValue(m, "removed_release"),
+		}
+		usage[u.group+"/"+u.version+"/"+u.resource] = u
+	}
+
+	if requests, ok := families["apiserver_request_total"]; ok {
+		for _, m := range requests.Metric {
+			key := groupName(labelValue(m, "group")) + "/" + labelValue(m, "version") + "/" + labelValue(m, "resource")
+			if u, ok := usage[key]; ok {
+				u.requests += uint64(m.GetCounter().GetValue())
+			}
+		}
+	}
+
+	keys := make([]string, 0, len(usage))
+	for key := range usage {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	result := make([]apiUsage, 0, len(keys))
+	for _, key := range keys {
+		result = append(result, *usage[key])
+	}
+	return result, nil
+}
+
+// groupName returns the api group name, core for the legacy (blank) group
+func groupName(group string) string {
+	if group == "" {
+		return "core"
+	}
+	return group
+}
+
+// labelValue returns the value of the named label, blank if not found
+func labelValue(m *dto.Metric, name string) string {
+	for _, l := range m.GetLabel() {
+		if l.GetName() == name {
+			return l.GetValue()
+		}
+	}
+	return ""
+}
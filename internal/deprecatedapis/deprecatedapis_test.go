@@ -0,0 +1,51 @@
+// Copyright © 2019 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+package deprecatedapis
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestDeprecatedUsage(t *testing.T) {
+	data := `# TYPE apiserver_requested_deprecated_apis gauge
+apiserver_requested_deprecated_apis{group="extensions",removed_release="1.22",resource="ingresses",subresource="",version="v1beta1"} 1
+apiserver_requested_deprecated_apis{group="policy",removed_release="1.25",resource="podsecuritypolicies",subresource="",version="v1beta1"} 1
+# TYPE apiserver_request_total counter
+apiserver_request_total{code="200",component="apiserver",group="extensions",resource="ingresses",scope="cluster",subresource="",verb="LIST",version="v1beta1"} 10
+apiserver_request_total{code="200",component="apiserver",group="extensions",resource="ingresses",scope="cluster",subresource="",verb="WATCH",version="v1beta1"} 5
+apiserver_request_total{code="200",component="apiserver",group="networking.k8s.io",resource="ingresses",scope="cluster",subresource="",verb="LIST",version="v1"} 100
+apiserver_request_total{code="200",component="apiserver",group="",resource="pods",scope="cluster",subresource="",verb="LIST",version="v1"} 100
+`
+
+	got, err := deprecatedUsage(strings.NewReader(data))
+	if err != nil {
+		t.Fatalf("unexpected error (%s)", err)
+	}
+
+	want := []apiUsage{
+		{group: "extensions", version: "v1beta1", resource: "ingresses", removedRelease: "1.22", requests: 15},
+		{group: "policy", version: "v1beta1", resource: "podsecuritypolicies", removedRelease: "1.25", requests: 0},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("deprecatedUsage() = %+v, want %+v", got, want)
+	}
+
+	if s := got[0].String(); s != "extensions/v1beta1/ingresses (removed in 1.22)" {
+		t.Errorf("String() = %s", s)
+	}
+}
+
+func TestDeprecatedUsageNone(t *testing.T) {
+	got, err := deprecatedUsage(strings.NewReader("# TYPE apiserver_request_total counter\napiserver_request_total{group=\"\",resource=\"pods\",version=\"v1\"} 1\n"))
+	if err != nil {
+		t.Fatalf("unexpected error (%s)", err)
+	}
+	if len(got) != 0 {
+		t.Errorf("deprecatedUsage() = %+v, want none", got)
+	}
+}
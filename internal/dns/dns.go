@@ -3,7 +3,7 @@
 // license that can be found in the LICENSE file.
 //
 
-// Package dns is the kube-dns collector
+// Package dns is the cluster dns (CoreDNS or kube-dns) collector
 package dns
 
 import (
@@ -14,6 +14,10 @@ import (
 	"io/ioutil"
 	"net/http"
 	"net/url"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
 	"sync"
 	"time"
 
@@ -22,12 +26,27 @@ import (
 	"github.com/circonus-labs/circonus-kubernetes-agent/internal/config"
 	"github.com/circonus-labs/circonus-kubernetes-agent/internal/config/defaults"
 	"github.com/circonus-labs/circonus-kubernetes-agent/internal/k8s"
-	"github.com/circonus-labs/circonus-kubernetes-agent/internal/promtext"
 	"github.com/circonus-labs/circonus-kubernetes-agent/internal/release"
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/scrape"
 	"github.com/pkg/errors"
 	"github.com/rs/zerolog"
 )
 
+const (
+	providerCoreDNS    = "coredns"
+	providerKubeDNS    = "kube-dns"
+	corednsMetricsPort = "9153"
+)
+
+var (
+	// coredns plugin metric families (e.g. coredns_dns_request_duration_seconds,
+	// coredns_cache_hits_total, coredns_forward_healthcheck_failures_total)
+	corednsFamilies = regexp.MustCompile(`^coredns_`)
+	// kube-dns exposes kubedns metrics on 10055 and the dnsmasq sidecar metrics on 10054
+	kubednsMetricsPorts = []string{"10055", "10054"}
+	kubednsFamilies     = regexp.MustCompile(`^(kubedns|skydns|dnsmasq)_`)
+)
+
 type DNS struct {
 	config       *config.Cluster
 	check        *circonus.Check
@@ -113,26 +132,11 @@ func (dns *DNS) Collect(ctx context.Context, tlsConfig *tls.Config, ts *time.Tim
 		return
 	}
 
-	metricPath := "/proxy/metrics"
-	metricPortName := ""
-	for _, p := range svc.Spec.Ports {
-		if p.Name == "metrics" {
-			metricPortName = p.Name
-		}
-	}
-
-	if metricPortName == "" {
-		dns.log.Error().Msg("invalid service definition, port named 'metrics' not found")
-		dns.Lock()
-		dns.running = false
-		dns.Unlock()
-		return
-	}
-
-	metricURL := dns.config.URL + svc.Metadata.SelfLink + ":" + metricPortName + metricPath
-
-	if err := dns.metrics(ctx, tlsConfig, metricURL); err != nil {
-		dns.log.Error().Err(err).Str("url", metricURL).Msg("http-metrics")
+	switch dnsProvider(svc) {
+	case providerCoreDNS:
+		dns.corednsMetrics(ctx, tlsConfig, svc)
+	default:
+		dns.kubednsMetrics(ctx, tlsConfig, svc)
 	}
 
 	dns.check.AddHistSample("collect_latency", cgm.Tags{
@@ -164,7 +168,7 @@ func (dns *DNS) getServiceDefinition(tlsConfig *tls.Config) (*k8s.Service, error
 
 	reqURL := u.String()
 	dns.log.Debug().Str("url", reqURL).Msg("service")
-	req, err := k8s.NewAPIRequest(dns.config.BearerToken, reqURL)
+	req, err := k8s.NewAPIRequest(dns.config.Token(), reqURL)
 	if err != nil {
 		return nil, errors.Wrap(err, "service definition req")
 	}
@@ -212,66 +216,104 @@ func (dns *DNS) getServiceDefinition(tlsConfig *tls.Config) (*k8s.Service, error
 	return s.Items[0], nil
 }
 
-func (dns *DNS) metrics(ctx context.Context, tlsConfig *tls.Config, metricURL string) error {
-	client, err := k8s.NewAPIClient(tlsConfig, dns.apiTimelimit)
-	if err != nil {
-		return errors.Wrap(err, "/metrics cli")
+// corednsMetrics collects the coredns plugin metrics (e.g. request duration, cache hits/misses,
+// forward failures) from each coredns pod, using the service 'metrics' port
+func (dns *DNS) corednsMetrics(ctx context.Context, tlsConfig *tls.Config, svc *k8s.Service) {
+	port := ""
+	for _, p := range svc.Spec.Ports {
+		if p.Name == "metrics" {
+			port = targetPort(p)
+			break
+		}
+	}
+	if port == "" {
+		dns.log.Warn().Str("default", corednsMetricsPort).Msg("service port named 'metrics' not found, using default")
+		port = corednsMetricsPort
 	}
-	defer client.CloseIdleConnections()
 
-	dns.log.Debug().Str("url", metricURL).Msg("metrics")
-	req, err := k8s.NewAPIRequest(dns.config.BearerToken, metricURL)
-	if err != nil {
-		return errors.Wrap(err, "/metrics req")
+	dns.podMetrics(ctx, tlsConfig, svc, providerCoreDNS, []string{port}, corednsFamilies)
+}
+
+// kubednsMetrics collects the kubedns and dnsmasq (sidecar) metrics from each kube-dns pod
+func (dns *DNS) kubednsMetrics(ctx context.Context, tlsConfig *tls.Config, svc *k8s.Service) {
+	dns.podMetrics(ctx, tlsConfig, svc, providerKubeDNS, kubednsMetricsPorts, kubednsFamilies)
+}
+
+func (dns *DNS) podMetrics(ctx context.Context, tlsConfig *tls.Config, svc *k8s.Service, provider string, ports []string, familyFilter *regexp.Regexp) {
+	if len(svc.Spec.Selector) == 0 {
+		dns.log.Error().Str("service", svc.Metadata.Name).Msg("invalid service definition, no selector")
+		return
 	}
 
-	start := time.Now()
-	resp, err := client.Do(req)
+	pods, err := scrape.Pods(dns.check, dns.log, dns.config, tlsConfig, dns.apiTimelimit, svc.Metadata.Namespace, labelSelector(svc.Spec.Selector))
 	if err != nil {
-		dns.check.IncrementCounter("collect_api_errors", cgm.Tags{
-			cgm.Tag{Category: "source", Value: release.NAME},
-			cgm.Tag{Category: "request", Value: "metrics"},
-			cgm.Tag{Category: "proxy", Value: "api-server"},
-			cgm.Tag{Category: "target", Value: "kube-dns"},
-		})
-		return err
+		dns.log.Error().Err(err).Msg("listing dns pods")
+		return
+	}
+	if len(pods) == 0 {
+		dns.log.Warn().Str("provider", provider).Msg("no dns pods found")
+		return
 	}
-	defer resp.Body.Close()
-	dns.check.AddHistSample("collect_latency", cgm.Tags{
-		cgm.Tag{Category: "source", Value: release.NAME},
-		cgm.Tag{Category: "request", Value: "metrics"},
-		cgm.Tag{Category: "proxy", Value: "api-server"},
-		cgm.Tag{Category: "target", Value: "kube-dns"},
-		cgm.Tag{Category: "units", Value: "milliseconds"},
-	}, float64(time.Since(start).Milliseconds()))
 
-	if resp.StatusCode != http.StatusOK {
-		dns.check.IncrementCounter("collect_api_errors", cgm.Tags{
-			cgm.Tag{Category: "source", Value: release.NAME},
-			cgm.Tag{Category: "request", Value: "metrics"},
-			cgm.Tag{Category: "proxy", Value: "api-server"},
-			cgm.Tag{Category: "target", Value: "kube-dns"},
-			cgm.Tag{Category: "code", Value: fmt.Sprintf("%d", resp.StatusCode)},
-		})
-		data, err := ioutil.ReadAll(resp.Body)
-		if err != nil {
-			dns.log.Error().Err(err).Str("url", metricURL).Msg("reading response")
-			return err
+	targets := make([]scrape.Target, 0, len(pods)*len(ports))
+	for _, pod := range pods {
+		for _, port := range ports {
+			targets = append(targets, scrape.Target{
+				URL:          scrape.PodProxyURL(dns.config.URL, pod, port, "/metrics"),
+				BearerToken:  dns.config.Token(),
+				Name:         "kube-dns",
+				Proxy:        "api-server",
+				FamilyFilter: familyFilter,
+				StreamTags: []string{
+					"source:kube-dns",
+					"source_type:metrics",
+					"dns_provider:" + provider,
+					"pod:" + pod.Metadata.Name,
+					"__rollup:false", // prevent high cardinality metrics from rolling up
+				},
+			})
 		}
-		dns.log.Warn().Str("status", resp.Status).RawJSON("response", data).Msg("error from API server")
-		return errors.New("error response from api server")
 	}
 
-	streamTags := []string{
-		"source:kube-dns",
-		"source_type:metrics",
-		"__rollup:false", // prevent high cardinality metrics from rolling up
+	scrape.MetricsPool(ctx, dns.check, dns.log, tlsConfig, dns.apiTimelimit, targets, len(targets), dns.ts)
+}
+
+// dnsProvider uses the service labels to determine whether the cluster dns is CoreDNS or kube-dns.
+// CoreDNS deployments keep the 'kube-dns' service name for compatibility and add the label
+// kubernetes.io/name=CoreDNS.
+func dnsProvider(svc *k8s.Service) string {
+	if svc == nil {
+		return providerKubeDNS
+	}
+	if strings.EqualFold(svc.Metadata.Labels["kubernetes.io/name"], "coredns") {
+		return providerCoreDNS
 	}
-	measurementTags := []string{}
+	if strings.EqualFold(svc.Metadata.Labels["k8s-app"], "coredns") {
+		return providerCoreDNS
+	}
+	return providerKubeDNS
+}
 
-	if err := promtext.QueueMetrics(ctx, dns.check, dns.log, resp.Body, streamTags, measurementTags, dns.ts); err != nil {
-		return err
+// targetPort returns the service port target port (number or name)
+func targetPort(p k8s.ServicePort) string {
+	switch tp := p.TargetPort.(type) {
+	case float64: // json numbers
+		return strconv.Itoa(int(tp))
+	case string:
+		return tp
 	}
+	if p.Port > 0 {
+		return strconv.Itoa(int(p.Port))
+	}
+	return ""
+}
 
-	return nil
+// labelSelector returns a label selector string for the service selector
+func labelSelector(selector map[string]string) string {
+	sel := make([]string, 0, len(selector))
+	for k, v := range selector {
+		sel = append(sel, k+"="+v)
+	}
+	sort.Strings(sel)
+	return strings.Join(sel, ",")
 }
@@ -5,8 +5,64 @@
 
 package dns
 
-import "testing"
+import (
+	"testing"
+
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/k8s"
+)
 
 func Test(t *testing.T) {
 	t.Log("Placeholder...nothing to test currently")
 }
+
+func TestDNSProvider(t *testing.T) {
+	tests := []struct {
+		name   string
+		labels map[string]string
+		want   string
+	}{
+		{"no labels", nil, providerKubeDNS},
+		{"kube-dns", map[string]string{"k8s-app": "kube-dns", "kubernetes.io/name": "KubeDNS"}, providerKubeDNS},
+		{"coredns name", map[string]string{"k8s-app": "kube-dns", "kubernetes.io/name": "CoreDNS"}, providerCoreDNS},
+		{"coredns app", map[string]string{"k8s-app": "coredns"}, providerCoreDNS},
+	}
+
+	for _, tst := range tests {
+		tst := tst
+		t.Run(tst.name, func(t *testing.T) {
+			svc := &k8s.Service{Metadata: k8s.ServiceMetadata{Name: "kube-dns", Labels: tst.labels}}
+			if got := dnsProvider(svc); got != tst.want {
+				t.Errorf("dnsProvider() = %s, want %s", got, tst.want)
+			}
+		})
+	}
+}
+
+func TestTargetPort(t *testing.T) {
+	tests := []struct {
+		name string
+		port k8s.ServicePort
+		want string
+	}{
+		{"number", k8s.ServicePort{Port: 9153, TargetPort: float64(9153)}, "9153"},
+		{"name", k8s.ServicePort{Port: 9153, TargetPort: "metrics"}, "metrics"},
+		{"no target", k8s.ServicePort{Port: 9153}, "9153"},
+		{"none", k8s.ServicePort{}, ""},
+	}
+
+	for _, tst := range tests {
+		tst := tst
+		t.Run(tst.name, func(t *testing.T) {
+			if got := targetPort(tst.port); got != tst.want {
+				t.Errorf("targetPort() = %s, want %s", got, tst.want)
+			}
+		})
+	}
+}
+
+func TestLabelSelector(t *testing.T) {
+	want := "app=foo,k8s-app=kube-dns"
+	if got := labelSelector(map[string]string{"k8s-app": "kube-dns", "app": "foo"}); got != want {
+		t.Errorf("labelSelector() = %s, want %s", got, want)
+	}
+}
@@ -0,0 +1,251 @@
+// Copyright © 2019 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+// Package endpoints is the service endpoint health collector
+package endpoints
+
+import (
+	"context"
+	"crypto/tls"
+	"strings"
+	"sync"
+	"time"
+
+	cgm "github.com/circonus-labs/circonus-gometrics/v3"
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/circonus"
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/config"
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/config/defaults"
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/k8s"
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/release"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog"
+	discoveryv1beta1 "k8s.io/api/discovery/v1beta1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+type Endpoints struct {
+	config       *config.Cluster
+	check        *circonus.Check
+	log          zerolog.Logger
+	clientset    kubernetes.Interface
+	running      bool
+	apiTimelimit time.Duration
+	sync.Mutex
+	ts *time.Time
+}
+
+// NOTES:
+// EndpointSlices (discovery.k8s.io/v1beta1) are listed from the api-server and aggregated per service
+// (kubernetes.io/service-name label). If endpoint slices are not available, Endpoints are used.
+
+func New(cfg *config.Cluster, parentLog zerolog.Logger, check *circonus.Check) (*Endpoints, error) {
+	if cfg == nil {
+		return nil, errors.New("invalid cluster config (nil)")
+	}
+	if check == nil {
+		return nil, errors.New("invalid check (nil)")
+	}
+
+	e := &Endpoints{
+		config: cfg,
+		check:  check,
+		log:    parentLog.With().Str("collector", "endpoints").Logger(),
+	}
+
+	if cfg.APITimelimit != "" {
+		v, err := time.ParseDuration(cfg.APITimelimit)
+		if err != nil {
+			e.log.Error().Err(err).Msg("parsing api timelimit, using default")
+		} else {
+			e.apiTimelimit = v
+		}
+	}
+
+	if e.apiTimelimit == time.Duration(0) {
+		v, err := time.ParseDuration(defaults.K8SAPITimelimit)
+		if err != nil {
+			e.log.Fatal().Err(err).Msg("parsing DEFAULT api timelimit")
+		}
+		e.apiTimelimit = v
+	}
+
+	restCfg, err := k8s.RESTConfig(cfg)
+	if err != nil {
+		return nil, errors.Wrap(err, "rest config")
+	}
+	restCfg.Timeout = e.apiTimelimit
+	clientset, err := kubernetes.NewForConfig(restCfg)
+	if err != nil {
+		return nil, errors.Wrap(err, "clientset")
+	}
+	e.clientset = clientset
+
+	return e, nil
+}
+
+func (e *Endpoints) ID() string {
+	return "endpoints"
+}
+
+// Collect service endpoint health
+func (e *Endpoints) Collect(ctx context.Context, _ *tls.Config, ts *time.Time) {
+	e.Lock()
+	if e.running {
+		e.log.Warn().Msg("already running")
+		e.Unlock()
+		return
+	}
+	e.running = true
+	e.ts = ts
+	e.Unlock()
+
+	defer func() {
+		if r := recover(); r != nil {
+			e.log.Error().Interface("panic", r).Msg("recover")
+			e.Lock()
+			e.running = false
+			e.Unlock()
+		}
+	}()
+
+	collectStart := time.Now()
+
+	e.endpointMetrics(ctx)
+
+	e.check.AddHistSample("collect_latency", cgm.Tags{
+		cgm.Tag{Category: "source", Value: release.NAME},
+		cgm.Tag{Category: "op", Value: "collect_endpoints"},
+		cgm.Tag{Category: "units", Value: "milliseconds"},
+	}, float64(time.Since(collectStart).Milliseconds()))
+	e.log.Debug().Str("duration", time.Since(collectStart).String()).Msg("endpoints collect end")
+	e.Lock()
+	e.running = false
+	e.Unlock()
+}
+
+// readiness is the ready and not ready endpoint counts for a service
+type readiness struct {
+	ready    uint64
+	notReady uint64
+}
+
+// endpointMetrics emits ready and not ready endpoint counts per service, and whether the
+// service has no ready endpoints
+func (e *Endpoints) endpointMetrics(ctx context.Context) {
+	services, err := e.sliceReadiness()
+	if err != nil {
+		if !apierrors.IsNotFound(err) {
+			e.apiError("endpointslice-list")
+			e.log.Error().Err(err).Msg("listing endpoint slices")
+			return
+		}
+		// endpoint slices not available (feature disabled or cluster < v1.17), use endpoints
+		services, err = e.endpointsReadiness()
+		if err != nil {
+			e.apiError("endpoints-list")
+			e.log.Error().Err(err).Msg("listing endpoints")
+			return
+		}
+	}
+
+	metrics := make(map[string]circonus.MetricSample)
+	for svc, r := range services {
+		parts := strings.SplitN(svc, "/", 2)
+		streamTags := []string{
+			"source:endpoints",
+			"source_type:service",
+			"namespace:" + parts[0],
+			"service:" + parts[1],
+			"__rollup:false", // prevent high cardinality metrics from rolling up
+		}
+		noReady := uint64(0)
+		if r.ready == 0 {
+			noReady = 1
+		}
+		_ = e.check.QueueMetricSample(metrics, "service_endpoints_ready", circonus.MetricTypeUint64, streamTags, []string{}, r.ready, e.ts)
+		_ = e.check.QueueMetricSample(metrics, "service_endpoints_not_ready", circonus.MetricTypeUint64, streamTags, []string{}, r.notReady, e.ts)
+		_ = e.check.QueueMetricSample(metrics, "service_no_ready_endpoints", circonus.MetricTypeUint64, streamTags, []string{}, noReady, e.ts)
+	}
+
+	if len(metrics) == 0 {
+		return
+	}
+	if err := e.check.SubmitQueue(ctx, metrics, e.log.With().Str("type", "endpoints").Logger()); err != nil {
+		e.log.Warn().Err(err).Msg("submitting metrics")
+	}
+}
+
+// sliceReadiness returns the endpoint readiness for each service (namespace/name) from endpoint slices
+func (e *Endpoints) sliceReadiness() (map[string]*readiness, error) {
+	slices, err := e.clientset.DiscoveryV1beta1().EndpointSlices(metav1.NamespaceAll).List(metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	services := make(map[string]*readiness)
+	for i := range slices.Items {
+		slice := &slices.Items[i]
+		name, ok := slice.Labels[discoveryv1beta1.LabelServiceName]
+		if !ok {
+			continue // not managed for a service
+		}
+		key := slice.Namespace + "/" + name
+		r, ok := services[key]
+		if !ok {
+			r = &readiness{}
+			services[key] = r
+		}
+		ready, notReady := sliceCounts(slice)
+		r.ready += ready
+		r.notReady += notReady
+	}
+
+	return services, nil
+}
+
+// endpointsReadiness returns the endpoint readiness for each service (namespace/name) from endpoints
+func (e *Endpoints) endpointsReadiness() (map[string]*readiness, error) {
+	endpoints, err := e.clientset.CoreV1().Endpoints(metav1.NamespaceAll).List(metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	services := make(map[string]*readiness)
+	for i := range endpoints.Items {
+		ep := &endpoints.Items[i]
+		r := &readiness{}
+		for _, subset := range ep.Subsets {
+			r.ready += uint64(len(subset.Addresses))
+			r.notReady += uint64(len(subset.NotReadyAddresses))
+		}
+		services[ep.Namespace+"/"+ep.Name] = r
+	}
+
+	return services, nil
+}
+
+func (e *Endpoints) apiError(request string) {
+	e.check.IncrementCounter("collect_api_errors", cgm.Tags{
+		cgm.Tag{Category: "source", Value: release.NAME},
+		cgm.Tag{Category: "request", Value: request},
+		cgm.Tag{Category: "target", Value: "api-server"},
+	})
+}
+
+// sliceCounts returns the number of ready and not ready endpoints in an endpoint slice,
+// an endpoint without a ready condition is ready (see EndpointConditions)
+func sliceCounts(slice *discoveryv1beta1.EndpointSlice) (uint64, uint64) {
+	var ready, notReady uint64
+	for _, ep := range slice.Endpoints {
+		if ep.Conditions.Ready == nil || *ep.Conditions.Ready {
+			ready++
+		} else {
+			notReady++
+		}
+	}
+	return ready, notReady
+}
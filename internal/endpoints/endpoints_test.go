@@ -0,0 +1,30 @@
+// Copyright © 2019 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+package endpoints
+
+import (
+	"testing"
+
+	discoveryv1beta1 "k8s.io/api/discovery/v1beta1"
+)
+
+func TestSliceCounts(t *testing.T) {
+	yes := true
+	no := false
+
+	slice := &discoveryv1beta1.EndpointSlice{
+		Endpoints: []discoveryv1beta1.Endpoint{
+			{Conditions: discoveryv1beta1.EndpointConditions{Ready: &yes}},
+			{Conditions: discoveryv1beta1.EndpointConditions{}},
+			{Conditions: discoveryv1beta1.EndpointConditions{Ready: &no}},
+		},
+	}
+
+	ready, notReady := sliceCounts(slice)
+	if ready != 2 || notReady != 1 {
+		t.Errorf("sliceCounts() = %d, %d, want 2, 1", ready, notReady)
+	}
+}
@@ -0,0 +1,201 @@
+// Copyright © 2019 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+// Package etcd is the etcd member metrics collector
+package etcd
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"io/ioutil"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	cgm "github.com/circonus-labs/circonus-gometrics/v3"
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/circonus"
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/config"
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/config/defaults"
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/release"
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/scrape"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog"
+)
+
+type Etcd struct {
+	config       *config.Cluster
+	check        *circonus.Check
+	log          zerolog.Logger
+	tlsConfig    *tls.Config
+	endpoints    []string
+	running      bool
+	apiTimelimit time.Duration
+	sync.Mutex
+	ts *time.Time
+}
+
+// NOTES:
+// etcd members are not reachable through the api-server proxy, the agent
+// needs direct network access to the member client urls and a client
+// certificate signed by the etcd ca (e.g. kubeadm /etc/kubernetes/pki/etcd/healthcheck-client.crt)
+
+func New(cfg *config.Cluster, parentLog zerolog.Logger, check *circonus.Check) (*Etcd, error) {
+	if cfg == nil {
+		return nil, errors.New("invalid cluster config (nil)")
+	}
+	if check == nil {
+		return nil, errors.New("invalid check (nil)")
+	}
+
+	e := &Etcd{
+		config: cfg,
+		check:  check,
+		log:    parentLog.With().Str("collector", "etcd").Logger(),
+	}
+
+	for _, ep := range strings.Split(cfg.EtcdEndpoints, ",") {
+		ep = strings.TrimSpace(ep)
+		if ep == "" {
+			continue
+		}
+		if _, err := url.Parse(ep); err != nil {
+			return nil, errors.Wrapf(err, "invalid etcd endpoint (%s)", ep)
+		}
+		e.endpoints = append(e.endpoints, strings.TrimSuffix(ep, "/"))
+	}
+	if len(e.endpoints) == 0 {
+		return nil, errors.New("invalid etcd endpoints (empty)")
+	}
+
+	tlsConfig, err := e.createTLSConfig()
+	if err != nil {
+		return nil, errors.Wrap(err, "configuring etcd tls")
+	}
+	e.tlsConfig = tlsConfig
+
+	if cfg.APITimelimit != "" {
+		v, err := time.ParseDuration(cfg.APITimelimit)
+		if err != nil {
+			e.log.Error().Err(err).Msg("parsing api timelimit, using default")
+		} else {
+			e.apiTimelimit = v
+		}
+	}
+
+	if e.apiTimelimit == time.Duration(0) {
+		v, err := time.ParseDuration(defaults.K8SAPITimelimit)
+		if err != nil {
+			e.log.Fatal().Err(err).Msg("parsing DEFAULT api timelimit")
+		}
+		e.apiTimelimit = v
+	}
+
+	return e, nil
+}
+
+func (e *Etcd) ID() string {
+	return "etcd"
+}
+
+// Collect metrics from etcd members
+func (e *Etcd) Collect(ctx context.Context, _ *tls.Config, ts *time.Time) {
+	e.Lock()
+	if e.running {
+		e.log.Warn().Msg("already running")
+		e.Unlock()
+		return
+	}
+	e.running = true
+	e.ts = ts
+	e.Unlock()
+
+	defer func() {
+		if r := recover(); r != nil {
+			e.log.Error().Interface("panic", r).Msg("recover")
+			e.Lock()
+			e.running = false
+			e.Unlock()
+		}
+	}()
+
+	collectStart := time.Now()
+
+	var wg sync.WaitGroup
+	for _, endpoint := range e.endpoints {
+		wg.Add(1)
+		go func(endpoint string) {
+			defer wg.Done()
+			metricURL := endpoint + "/metrics"
+			if err := e.metrics(ctx, metricURL); err != nil {
+				e.log.Error().Err(err).Str("url", metricURL).Msg("etcd metrics")
+			}
+		}(endpoint)
+	}
+	wg.Wait()
+
+	e.check.AddHistSample("collect_latency", cgm.Tags{
+		cgm.Tag{Category: "source", Value: release.NAME},
+		cgm.Tag{Category: "op", Value: "collect_etcd"},
+		cgm.Tag{Category: "units", Value: "milliseconds"},
+	}, float64(time.Since(collectStart).Milliseconds()))
+	e.log.Debug().Str("duration", time.Since(collectStart).String()).Msg("etcd collect end")
+	e.Lock()
+	e.running = false
+	e.Unlock()
+}
+
+// metrics fetches the metrics of an etcd member, using the etcd tls config
+func (e *Etcd) metrics(ctx context.Context, metricURL string) error {
+	u, err := url.Parse(metricURL)
+	if err != nil {
+		return errors.Wrap(err, "parsing member url")
+	}
+
+	target := scrape.Target{
+		URL:  metricURL,
+		Name: "etcd",
+		StreamTags: []string{
+			"source:etcd",
+			"source_type:metrics",
+			"etcd_member:" + u.Hostname(),
+			"__rollup:false", // prevent high cardinality metrics from rolling up
+		},
+	}
+
+	return scrape.Metrics(ctx, e.check, e.log, e.tlsConfig, e.apiTimelimit, target, e.ts)
+}
+
+// createTLSConfig builds the tls config used to talk to etcd members, using
+// the etcd ca (if provided) and client certificate/key
+func (e *Etcd) createTLSConfig() (*tls.Config, error) {
+	tlsConfig := &tls.Config{}
+
+	if e.config.EtcdCAFile != "" {
+		cert, err := ioutil.ReadFile(e.config.EtcdCAFile)
+		if err != nil {
+			return nil, errors.Wrap(err, "reading etcd ca file")
+		}
+		cp := x509.NewCertPool()
+		if !cp.AppendCertsFromPEM(cert) {
+			return nil, errors.New("unable to add etcd CA Certificate to x509 cert pool")
+		}
+		tlsConfig.RootCAs = cp
+	}
+
+	if e.config.EtcdCertFile != "" || e.config.EtcdKeyFile != "" {
+		if e.config.EtcdCertFile == "" || e.config.EtcdKeyFile == "" {
+			return nil, errors.New("etcd client cert AND key are required")
+		}
+		cert, err := tls.LoadX509KeyPair(e.config.EtcdCertFile, e.config.EtcdKeyFile)
+		if err != nil {
+			return nil, errors.Wrap(err, "loading etcd client cert")
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+
+	return tlsConfig, nil
+}
@@ -0,0 +1,78 @@
+// Copyright © 2019 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+package etcd
+
+import (
+	"context"
+	"crypto/tls"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/circonus"
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/config"
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/scrape/scrapetest"
+	"github.com/rs/zerolog"
+)
+
+func TestNew(t *testing.T) {
+	check := &circonus.Check{}
+
+	tests := []struct {
+		name      string
+		cfg       config.Cluster
+		endpoints int
+		shouldErr bool
+	}{
+		{"no endpoints", config.Cluster{EtcdEndpoints: " , "}, 0, true},
+		{"endpoints", config.Cluster{EtcdEndpoints: "https://10.0.0.1:2379/, https://10.0.0.2:2379"}, 2, false},
+		{"cert without key", config.Cluster{EtcdEndpoints: "https://10.0.0.1:2379", EtcdCertFile: "client.crt"}, 0, true},
+		{"missing ca", config.Cluster{EtcdEndpoints: "https://10.0.0.1:2379", EtcdCAFile: "/nonexistent/ca.crt"}, 0, true},
+	}
+
+	for _, tt := range tests {
+		e, err := New(&tt.cfg, zerolog.Nop(), check)
+		if tt.shouldErr {
+			if err == nil {
+				t.Fatalf("%s: expected error", tt.name)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("%s: unexpected error (%s)", tt.name, err)
+		}
+		if len(e.endpoints) != tt.endpoints {
+			t.Fatalf("%s: expected %d endpoints, got %v", tt.name, tt.endpoints, e.endpoints)
+		}
+	}
+}
+
+func TestCollect(t *testing.T) {
+	member := scrapetest.NewServer(map[string]string{
+		"/metrics": "# TYPE etcd_server_has_leader gauge\netcd_server_has_leader 1\n",
+	})
+	defer member.Close()
+	down := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "unavailable", http.StatusServiceUnavailable)
+	}))
+	defer down.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	check, rec := scrapetest.NewCheck(ctx, t)
+
+	// one member down does not prevent collecting the others
+	e, err := New(&config.Cluster{EtcdEndpoints: member.URL + "," + down.URL}, zerolog.Nop(), check)
+	if err != nil {
+		t.Fatalf("unexpected error (%s)", err)
+	}
+	e.Collect(ctx, &tls.Config{}, nil)
+
+	scrapetest.ExpectTags(t, rec, "etcd_server_has_leader", "source:etcd", "etcd_member:127.0.0.1")
+	if rec.Len() != 1 {
+		t.Errorf("expected 1 metric, got %d", rec.Len())
+	}
+}
@@ -0,0 +1,258 @@
+// Copyright © 2019 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+// Package etcdobjects is the etcd object count collector
+package etcdobjects
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	cgm "github.com/circonus-labs/circonus-gometrics/v3"
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/circonus"
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/config"
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/config/defaults"
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/k8s"
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/release"
+	"github.com/pkg/errors"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/prometheus/common/expfmt"
+	"github.com/rs/zerolog"
+)
+
+type EtcdObjects struct {
+	config       *config.Cluster
+	check        *circonus.Check
+	log          zerolog.Logger
+	running      bool
+	apiTimelimit time.Duration
+	sync.Mutex
+	ts *time.Time
+}
+
+// NOTES:
+// The api-server reports the number of objects stored in etcd for each resource type,
+// apiserver_storage_objects (v1.21+) or etcd_object_counts (earlier releases). Counts are
+// taken from the api-server, so etcd member access is not required. A count of -1 means
+// the api-server could not determine it, those resources are skipped.
+
+// objectFamilies are the object count metric families, in order of preference
+var objectFamilies = []string{"apiserver_storage_objects", "etcd_object_counts"}
+
+func New(cfg *config.Cluster, parentLog zerolog.Logger, check *circonus.Check) (*EtcdObjects, error) {
+	if cfg == nil {
+		return nil, errors.New("invalid cluster config (nil)")
+	}
+	if check == nil {
+		return nil, errors.New("invalid check (nil)")
+	}
+
+	eo := &EtcdObjects{
+		config: cfg,
+		check:  check,
+		log:    parentLog.With().Str("collector", "etcd-objects").Logger(),
+	}
+
+	if cfg.APITimelimit != "" {
+		v, err := time.ParseDuration(cfg.APITimelimit)
+		if err != nil {
+			eo.log.Error().Err(err).Msg("parsing api timelimit, using default")
+		} else {
+			eo.apiTimelimit = v
+		}
+	}
+
+	if eo.apiTimelimit == time.Duration(0) {
+		v, err := time.ParseDuration(defaults.K8SAPITimelimit)
+		if err != nil {
+			eo.log.Fatal().Err(err).Msg("parsing DEFAULT api timelimit")
+		}
+		eo.apiTimelimit = v
+	}
+
+	return eo, nil
+}
+
+func (eo *EtcdObjects) ID() string {
+	return "etcd-objects"
+}
+
+// Collect etcd object counts from the api-server metrics
+func (eo *EtcdObjects) Collect(ctx context.Context, tlsConfig *tls.Config, ts *time.Time) {
+	eo.Lock()
+	if eo.running {
+		eo.log.Warn().Msg("already running")
+		eo.Unlock()
+		return
+	}
+	eo.running = true
+	eo.ts = ts
+	eo.Unlock()
+
+	defer func() {
+		if r := recover(); r != nil {
+			eo.log.Error().Interface("panic", r).Msg("recover")
+			eo.Lock()
+			eo.running = false
+			eo.Unlock()
+		}
+	}()
+
+	collectStart := time.Now()
+
+	metricURL := eo.config.URL + "/metrics"
+	if err := eo.metrics(ctx, tlsConfig, metricURL); err != nil {
+		eo.log.Error().Err(err).Str("url", metricURL).Msg("etcd object counts")
+	}
+
+	eo.check.AddHistSample("collect_latency", cgm.Tags{
+		cgm.Tag{Category: "source", Value: release.NAME},
+		cgm.Tag{Category: "op", Value: "collect_etcd-objects"},
+		cgm.Tag{Category: "units", Value: "milliseconds"},
+	}, float64(time.Since(collectStart).Milliseconds()))
+	eo.log.Debug().Str("duration", time.Since(collectStart).String()).Msg("etcd-objects collect end")
+	eo.Lock()
+	eo.running = false
+	eo.Unlock()
+}
+
+func (eo *EtcdObjects) metrics(ctx context.Context, tlsConfig *tls.Config, metricURL string) error {
+	client, err := k8s.NewAPIClient(tlsConfig, eo.apiTimelimit)
+	if err != nil {
+		return errors.Wrap(err, "/metrics cli")
+	}
+	defer client.CloseIdleConnections()
+
+	eo.log.Debug().Str("url", metricURL).Msg("metrics")
+	req, err := k8s.NewAPIRequest(eo.config.Token(), metricURL)
+	if err != nil {
+		return errors.Wrap(err, "/metrics req")
+	}
+	req = req.WithContext(ctx)
+
+	start := time.Now()
+	resp, err := client.Do(req)
+	if err != nil {
+		eo.check.IncrementCounter("collect_api_errors", cgm.Tags{
+			cgm.Tag{Category: "source", Value: release.NAME},
+			cgm.Tag{Category: "request", Value: "metrics"},
+			cgm.Tag{Category: "target", Value: "api-server"},
+		})
+		return err
+	}
+	defer resp.Body.Close()
+	eo.check.AddHistSample("collect_latency", cgm.Tags{
+		cgm.Tag{Category: "source", Value: release.NAME},
+		cgm.Tag{Category: "request", Value: "metrics"},
+		cgm.Tag{Category: "target", Value: "api-server"},
+		cgm.Tag{Category: "units", Value: "milliseconds"},
+	}, float64(time.Since(start).Milliseconds()))
+
+	if resp.StatusCode != http.StatusOK {
+		eo.check.IncrementCounter("collect_api_errors", cgm.Tags{
+			cgm.Tag{Category: "source", Value: release.NAME},
+			cgm.Tag{Category: "request", Value: "metrics"},
+			cgm.Tag{Category: "target", Value: "api-server"},
+			cgm.Tag{Category: "code", Value: fmt.Sprintf("%d", resp.StatusCode)},
+		})
+		data, err := ioutil.ReadAll(resp.Body)
+		if err != nil {
+			eo.log.Error().Err(err).Str("url", metricURL).Msg("reading response")
+			return err
+		}
+		eo.log.Warn().Str("status", resp.Status).RawJSON("response", data).Msg("error from API server")
+		return errors.New("error response from api server")
+	}
+
+	counts, err := objectCounts(resp.Body)
+	if err != nil {
+		return errors.Wrap(err, "parsing api-server metrics")
+	}
+
+	metrics := make(map[string]circonus.MetricSample)
+	var total uint64
+	for _, c := range counts {
+		streamTags := []string{
+			"source:etcd-objects",
+			"source_type:api-server",
+			"resource:" + c.resource,
+		}
+		_ = eo.check.QueueMetricSample(metrics, "etcd_objects", circonus.MetricTypeUint64, streamTags, []string{}, c.objects, eo.ts)
+		total += c.objects
+	}
+
+	streamTags := []string{
+		"source:etcd-objects",
+		"source_type:api-server",
+	}
+	_ = eo.check.QueueMetricSample(metrics, "etcd_objects_total", circonus.MetricTypeUint64, streamTags, []string{}, total, eo.ts)
+
+	return eo.check.SubmitQueue(ctx, metrics, eo.log.With().Str("type", "etcd_objects").Logger())
+}
+
+type objectCount struct {
+	resource string
+	objects  uint64
+}
+
+// objectCounts returns the number of objects stored for each resource type, from
+// api-server prometheus text metrics, sorted by resource
+func objectCounts(data io.Reader) ([]objectCount, error) {
+	var parser expfmt.TextParser
+	families, err := parser.TextToMetricFamilies(data)
+	if err != nil {
+		return nil, err
+	}
+
+	var family *dto.MetricFamily
+	for _, name := range objectFamilies {
+		if f, ok := families[name]; ok {
+			family = f
+			break
+		}
+	}
+	if family == nil {
+		return nil, nil
+	}
+
+	counts := make(map[string]uint64)
+	for _, m := range family.Metric {
+		resource := labelValue(m, "resource")
+		v := m.GetGauge().GetValue()
+		if resource == "" || v < 0 {
+			continue
+		}
+		counts[resource] += uint64(v)
+	}
+
+	resources := make([]string, 0, len(counts))
+	for resource := range counts {
+		resources = append(resources, resource)
+	}
+	sort.Strings(resources)
+
+	result := make([]objectCount, 0, len(resources))
+	for _, resource := range resources {
+		result = append(result, objectCount{resource: resource, objects: counts[resource]})
+	}
+	return result, nil
+}
+
+// labelValue returns the value of the named label, blank if not found
+func labelValue(m *dto.Metric, name string) string {
+	for _, l := range m.GetLabel() {
+		if l.GetName() == name {
+			return l.GetValue()
+		}
+	}
+	return ""
+}
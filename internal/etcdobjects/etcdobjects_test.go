@@ -0,0 +1,59 @@
+// Copyright © 2019 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+package etcdobjects
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestObjectCounts(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		want []objectCount
+	}{
+		{
+			name: "storage objects",
+			data: `# TYPE apiserver_storage_objects gauge
+apiserver_storage_objects{resource="pods"} 120
+apiserver_storage_objects{resource="events"} 4500
+apiserver_storage_objects{resource="widgets.example.com"} 3
+apiserver_storage_objects{resource="leases.coordination.k8s.io"} -1
+`,
+			want: []objectCount{
+				{resource: "events", objects: 4500},
+				{resource: "pods", objects: 120},
+				{resource: "widgets.example.com", objects: 3},
+			},
+		},
+		{
+			name: "etcd object counts",
+			data: `# TYPE etcd_object_counts gauge
+etcd_object_counts{resource="pods"} 10
+`,
+			want: []objectCount{{resource: "pods", objects: 10}},
+		},
+		{
+			name: "none",
+			data: "# TYPE apiserver_request_total counter\napiserver_request_total{resource=\"pods\"} 1\n",
+			want: nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := objectCounts(strings.NewReader(tt.data))
+			if err != nil {
+				t.Fatalf("unexpected error (%s)", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("objectCounts() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,277 @@
+// Copyright © 2019 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+// Package evictions is the pod eviction and preemption collector
+package evictions
+
+import (
+	"context"
+	"crypto/tls"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/circonus"
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/config"
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/k8s"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/cache"
+)
+
+// disruptionTarget is the pod condition set (kubernetes v1.26+) when a pod is
+// about to be terminated due to a disruption, the reason identifies the cause
+const disruptionTarget corev1.PodConditionType = "DisruptionTarget"
+
+// seenTTL is how long an evicted pod uid is remembered to de-duplicate signals
+const seenTTL = time.Hour
+
+var (
+	lowResourceRx = regexp.MustCompile(`low on resource: ([A-Za-z0-9.-]+)`)
+	onNodeRx      = regexp.MustCompile(`on node "?([^\s"]+)"?`)
+)
+
+type Evictions struct {
+	config *config.Cluster
+	check  *circonus.Check
+	log    zerolog.Logger
+	start  time.Time // events before start are not counted
+	seen   map[types.UID]time.Time
+	events *circonus.EventMetrics
+	sync.Mutex
+}
+
+// NOTES:
+// Pods and pod events are watched, an eviction is counted once per pod from the first signal
+// received: the DisruptionTarget pod condition (v1.26+, api evictions, preemption, kubelet
+// node pressure, taint manager), a pod failed with status reason Evicted (kubelet node pressure),
+// or an Evicted/Preempted pod event. Counts are tagged with the namespace, node, and reason.
+
+func New(cfg *config.Cluster, parentLog zerolog.Logger, check *circonus.Check) (*Evictions, error) {
+	if cfg == nil {
+		return nil, errors.New("invalid cluster config (nil)")
+	}
+	if check == nil {
+		return nil, errors.New("invalid check (nil)")
+	}
+
+	return &Evictions{
+		config: cfg,
+		check:  check,
+		log:    parentLog.With().Str("collector", "evictions").Logger(),
+		start:  time.Now(),
+		seen:   make(map[types.UID]time.Time),
+		events: circonus.NewEventMetrics(),
+	}, nil
+}
+
+func (e *Evictions) ID() string {
+	return "evictions"
+}
+
+// Start watching pods and pod events, does not return until ctx is done
+func (e *Evictions) Start(ctx context.Context, _ *tls.Config) {
+	e.log.Info().Msg("starting watcher")
+
+	cfg, err := k8s.RESTConfig(e.config)
+	if err != nil {
+		e.log.Error().Err(err).Msg("unable to start eviction watcher")
+		return
+	}
+
+	clientset, err := kubernetes.NewForConfig(cfg)
+	if err != nil {
+		e.log.Error().Err(err).Msg("initializing client set")
+		return
+	}
+
+	podFactory := informers.NewSharedInformerFactory(clientset, 0)
+	podInformer := podFactory.Core().V1().Pods().Informer()
+	eventFactory := informers.NewSharedInformerFactoryWithOptions(clientset, 0,
+		informers.WithTweakListOptions(func(opts *metav1.ListOptions) {
+			opts.FieldSelector = "involvedObject.kind=Pod"
+		}))
+	eventInformer := eventFactory.Core().V1().Events().Informer()
+	stopper := make(chan struct{})
+	defer close(stopper)
+	defer runtime.HandleCrash()
+
+	podInformer.AddEventHandler(cache.ResourceEventHandlerFuncs{
+		UpdateFunc: func(oldObj interface{}, newObj interface{}) {
+			oldPod := oldObj.(*corev1.Pod)
+			newPod := newObj.(*corev1.Pod)
+			if !e.check.AllowNamespace(newPod.Namespace) {
+				return
+			}
+			if reason, resource, ok := podEviction(newPod); ok {
+				if _, wasEvicted := podEvictionReason(oldPod); !wasEvicted {
+					e.record(newPod.UID, newPod.Namespace, newPod.Spec.NodeName, reason, resource)
+				}
+			}
+		},
+	})
+
+	eventInformer.AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc: func(obj interface{}) {
+			ev := obj.(*corev1.Event)
+			if eventTime(ev).Before(e.start) || !e.check.AllowNamespace(ev.InvolvedObject.Namespace) {
+				return
+			}
+			if reason, node, resource, ok := eventEviction(ev); ok {
+				e.record(ev.InvolvedObject.UID, ev.InvolvedObject.Namespace, node, reason, resource)
+			}
+		},
+	})
+
+	go podInformer.Run(stopper)
+	go eventInformer.Run(stopper)
+
+	if !cache.WaitForCacheSync(stopper, podInformer.HasSynced, eventInformer.HasSynced) {
+		e.log.Warn().Msg("timed out waiting for cache to sync")
+		return
+	}
+
+	<-ctx.Done()
+	e.log.Debug().Msg("closing eviction watcher")
+}
+
+// Collect queues the evictions recorded since the previous collection and expires remembered evicted pods
+func (e *Evictions) Collect(ctx context.Context, _ *tls.Config, ts *time.Time) {
+	e.Lock()
+	for uid, t := range e.seen {
+		if time.Since(t) > seenTTL {
+			delete(e.seen, uid)
+		}
+	}
+	e.Unlock()
+
+	metrics := make(map[string]circonus.MetricSample)
+	e.events.Queue(e.check, metrics, ts)
+	if len(metrics) == 0 {
+		return
+	}
+	if err := e.check.SubmitQueue(ctx, metrics, e.log.With().Str("type", "evictions").Logger()); err != nil {
+		e.log.Warn().Err(err).Msg("submitting metrics")
+	}
+}
+
+// record counts an eviction, once per pod
+func (e *Evictions) record(uid types.UID, namespace, node, reason, resource string) {
+	e.Lock()
+	if _, found := e.seen[uid]; found {
+		e.Unlock()
+		return
+	}
+	e.seen[uid] = time.Now()
+	e.Unlock()
+
+	if node == "" {
+		node = "unknown"
+	}
+	streamTags := []string{
+		"source:evictions",
+		"namespace:" + namespace,
+		"node:" + node,
+		"reason:" + reason,
+	}
+	if resource != "" {
+		streamTags = append(streamTags, "resource:"+resource)
+	}
+	e.events.Increment("pod_evictions", streamTags, 1)
+}
+
+// podEviction returns the eviction reason and (for node pressure) the resource of an evicted pod
+func podEviction(pod *corev1.Pod) (string, string, bool) {
+	reason, ok := podEvictionReason(pod)
+	if !ok {
+		return "", "", false
+	}
+	resource := ""
+	if reason == "node_pressure" {
+		resource = lowResource(pod.Status.Message)
+		if resource == "" {
+			for _, cond := range pod.Status.Conditions {
+				if cond.Type == disruptionTarget {
+					resource = lowResource(cond.Message)
+				}
+			}
+		}
+	}
+	return reason, resource, true
+}
+
+// podEvictionReason returns the eviction reason of a pod, false if the pod has not been evicted
+func podEvictionReason(pod *corev1.Pod) (string, bool) {
+	for _, cond := range pod.Status.Conditions {
+		if cond.Type == disruptionTarget && cond.Status == corev1.ConditionTrue {
+			return disruptionReason(cond.Reason), true
+		}
+	}
+	if pod.Status.Phase == corev1.PodFailed && pod.Status.Reason == "Evicted" {
+		return "node_pressure", true
+	}
+	return "", false
+}
+
+// disruptionReason maps DisruptionTarget condition reasons to eviction reasons
+func disruptionReason(reason string) string {
+	switch reason {
+	case "EvictionByEvictionAPI":
+		return "api"
+	case "PreemptionByScheduler", "PreemptionByKubeScheduler":
+		return "preemption"
+	case "TerminationByKubelet":
+		return "node_pressure"
+	case "DeletionByTaintManager":
+		return "taint_manager"
+	case "DeletionByPodGC":
+		return "pod_gc"
+	default:
+		return "other"
+	}
+}
+
+// eventEviction returns the eviction reason, node, and resource of an Evicted (kubelet)
+// or Preempted (scheduler) pod event
+func eventEviction(ev *corev1.Event) (string, string, string, bool) {
+	switch ev.Reason {
+	case "Evicted":
+		return "node_pressure", ev.Source.Host, lowResource(ev.Message), true
+	case "Preempted":
+		node := ev.Source.Host
+		if m := onNodeRx.FindStringSubmatch(ev.Message); m != nil {
+			node = m[1]
+		}
+		return "preemption", node, "", true
+	}
+	return "", "", "", false
+}
+
+// lowResource returns the resource from a kubelet eviction message
+// (e.g. "The node was low on resource: memory. ...")
+func lowResource(message string) string {
+	if m := lowResourceRx.FindStringSubmatch(message); m != nil {
+		return strings.TrimSuffix(m[1], ".") // end of the sentence, not the resource name
+	}
+	return ""
+}
+
+// eventTime returns the last time the event occurred
+func eventTime(ev *corev1.Event) time.Time {
+	if !ev.LastTimestamp.IsZero() {
+		return ev.LastTimestamp.Time
+	}
+	if !ev.EventTime.IsZero() {
+		return ev.EventTime.Time
+	}
+	return ev.CreationTimestamp.Time
+}
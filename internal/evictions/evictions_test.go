@@ -0,0 +1,109 @@
+// Copyright © 2019 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+package evictions
+
+import (
+	"os"
+	"testing"
+
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/circonus"
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/config"
+	"github.com/rs/zerolog"
+	corev1 "k8s.io/api/core/v1"
+)
+
+func TestPodEviction(t *testing.T) {
+	tests := []struct {
+		name     string
+		status   corev1.PodStatus
+		reason   string
+		resource string
+		ok       bool
+	}{
+		{"running", corev1.PodStatus{Phase: corev1.PodRunning}, "", "", false},
+		{"kubelet evicted", corev1.PodStatus{Phase: corev1.PodFailed, Reason: "Evicted", Message: "The node was low on resource: ephemeral-storage. "}, "node_pressure", "ephemeral-storage", true},
+		{"api eviction", corev1.PodStatus{Phase: corev1.PodRunning, Conditions: []corev1.PodCondition{
+			{Type: disruptionTarget, Status: corev1.ConditionTrue, Reason: "EvictionByEvictionAPI"},
+		}}, "api", "", true},
+		{"preempted", corev1.PodStatus{Phase: corev1.PodRunning, Conditions: []corev1.PodCondition{
+			{Type: disruptionTarget, Status: corev1.ConditionTrue, Reason: "PreemptionByScheduler"},
+		}}, "preemption", "", true},
+		{"kubelet condition", corev1.PodStatus{Phase: corev1.PodRunning, Conditions: []corev1.PodCondition{
+			{Type: disruptionTarget, Status: corev1.ConditionTrue, Reason: "TerminationByKubelet", Message: "The node was low on resource: memory."},
+		}}, "node_pressure", "memory", true},
+		{"condition false", corev1.PodStatus{Phase: corev1.PodRunning, Conditions: []corev1.PodCondition{
+			{Type: disruptionTarget, Status: corev1.ConditionFalse, Reason: "EvictionByEvictionAPI"},
+		}}, "", "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reason, resource, ok := podEviction(&corev1.Pod{Status: tt.status})
+			if reason != tt.reason || resource != tt.resource || ok != tt.ok {
+				t.Errorf("podEviction() = %q, %q, %v, want %q, %q, %v", reason, resource, ok, tt.reason, tt.resource, tt.ok)
+			}
+		})
+	}
+}
+
+func TestEventEviction(t *testing.T) {
+	tests := []struct {
+		name     string
+		event    corev1.Event
+		reason   string
+		node     string
+		resource string
+		ok       bool
+	}{
+		{"evicted", corev1.Event{Reason: "Evicted", Message: "The node was low on resource: memory. Container app was using 1Gi", Source: corev1.EventSource{Host: "node1"}}, "node_pressure", "node1", "memory", true},
+		{"preempted", corev1.Event{Reason: "Preempted", Message: "Preempted by default/critical on node node2"}, "preemption", "node2", "", true},
+		{"other", corev1.Event{Reason: "Killing"}, "", "", "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reason, node, resource, ok := eventEviction(&tt.event)
+			if reason != tt.reason || node != tt.node || resource != tt.resource || ok != tt.ok {
+				t.Errorf("eventEviction() = %q, %q, %q, %v", reason, node, resource, ok)
+			}
+		})
+	}
+}
+
+func TestRecord(t *testing.T) {
+	check, err := circonus.NewCheck(zerolog.Nop(), &config.Circonus{DryRun: true, DryRunOutput: os.DevNull, DefaultStreamtags: "cluster:test", SubmitBackoffMin: "1s", SubmitBackoffMax: "1s"})
+	if err != nil {
+		t.Fatalf("unexpected error (%s)", err)
+	}
+	e, err := New(&config.Cluster{}, zerolog.Nop(), check)
+	if err != nil {
+		t.Fatalf("unexpected error (%s)", err)
+	}
+
+	e.record("uid1", "default", "node1", "node_pressure", "memory")
+	e.record("uid1", "default", "node1", "api", "") // same pod, counted once
+	e.record("uid2", "default", "", "preemption", "")
+	e.record("uid3", "kube-system", "", "preemption", "")
+
+	metrics := make(map[string]circonus.MetricSample)
+	e.events.Queue(check, metrics, nil)
+
+	want := map[string]uint64{
+		"pod_evictions|ST[cluster:test,namespace:default,node:node1,reason:node_pressure,resource:memory,source:evictions]": 1,
+		"pod_evictions|ST[cluster:test,namespace:default,node:unknown,reason:preemption,source:evictions]":                  1,
+		"pod_evictions|ST[cluster:test,namespace:kube-system,node:unknown,reason:preemption,source:evictions]":              1,
+	}
+	if len(metrics) != len(want) {
+		t.Fatalf("expected %d metrics, got %v", len(want), metrics)
+	}
+	for name, value := range want {
+		ms, ok := metrics[name]
+		if !ok {
+			t.Fatalf("expected %s, got %v", name, metrics)
+		}
+		if ms.Value != value {
+			t.Fatalf("%s: expected %v, got %v", name, value, ms.Value)
+		}
+	}
+}
@@ -0,0 +1,291 @@
+// Copyright © 2019 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+// Package flux is the flux (gitops toolkit) reconciliation status collector
+package flux
+
+import (
+	"context"
+	"crypto/tls"
+	"sync"
+	"time"
+
+	cgm "github.com/circonus-labs/circonus-gometrics/v3"
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/circonus"
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/config"
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/config/defaults"
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/k8s"
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/release"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/discovery"
+	"k8s.io/client-go/dynamic"
+)
+
+type fluxResource struct {
+	group    string
+	resource string
+	kind     string
+}
+
+// the flux custom resources collected, the api versions differ between flux
+// releases so the preferred version of each group is used
+var fluxResources = []fluxResource{
+	{group: "kustomize.toolkit.fluxcd.io", resource: "kustomizations", kind: "Kustomization"},
+	{group: "helm.toolkit.fluxcd.io", resource: "helmreleases", kind: "HelmRelease"},
+}
+
+type Flux struct {
+	config       *config.Cluster
+	check        *circonus.Check
+	log          zerolog.Logger
+	discovery    discovery.DiscoveryInterface
+	dynamic      dynamic.Interface
+	versions     map[string]string // preferred version by api group
+	running      bool
+	apiTimelimit time.Duration
+	sync.Mutex
+	ts *time.Time
+}
+
+// NOTES:
+// Kustomization and HelmRelease resources are listed, the Ready condition is emitted as a 0/1
+// gauge tagged with the condition reason (e.g. ReconciliationFailed, InstallFailed). Drift is
+// reported when the last attempted revision differs from the last applied revision (i.e. the
+// latest source revision failed to apply). API groups which are not installed are skipped.
+
+func New(cfg *config.Cluster, parentLog zerolog.Logger, check *circonus.Check) (*Flux, error) {
+	if cfg == nil {
+		return nil, errors.New("invalid cluster config (nil)")
+	}
+	if check == nil {
+		return nil, errors.New("invalid check (nil)")
+	}
+
+	fc := &Flux{
+		config:   cfg,
+		check:    check,
+		log:      parentLog.With().Str("collector", "flux").Logger(),
+		versions: make(map[string]string),
+	}
+
+	if cfg.APITimelimit != "" {
+		v, err := time.ParseDuration(cfg.APITimelimit)
+		if err != nil {
+			fc.log.Error().Err(err).Msg("parsing api timelimit, using default")
+		} else {
+			fc.apiTimelimit = v
+		}
+	}
+
+	if fc.apiTimelimit == time.Duration(0) {
+		v, err := time.ParseDuration(defaults.K8SAPITimelimit)
+		if err != nil {
+			fc.log.Fatal().Err(err).Msg("parsing DEFAULT api timelimit")
+		}
+		fc.apiTimelimit = v
+	}
+
+	restCfg, err := k8s.RESTConfig(cfg)
+	if err != nil {
+		return nil, errors.Wrap(err, "rest config")
+	}
+	restCfg.Timeout = fc.apiTimelimit
+	dc, err := discovery.NewDiscoveryClientForConfig(restCfg)
+	if err != nil {
+		return nil, errors.Wrap(err, "discovery client")
+	}
+	fc.discovery = dc
+	dyn, err := dynamic.NewForConfig(restCfg)
+	if err != nil {
+		return nil, errors.Wrap(err, "dynamic client")
+	}
+	fc.dynamic = dyn
+
+	return fc, nil
+}
+
+func (fc *Flux) ID() string {
+	return "flux"
+}
+
+// Collect flux reconciliation status
+func (fc *Flux) Collect(ctx context.Context, _ *tls.Config, ts *time.Time) {
+	fc.Lock()
+	if fc.running {
+		fc.log.Warn().Msg("already running")
+		fc.Unlock()
+		return
+	}
+	fc.running = true
+	fc.ts = ts
+	fc.Unlock()
+
+	defer func() {
+		if r := recover(); r != nil {
+			fc.log.Error().Interface("panic", r).Msg("recover")
+			fc.Lock()
+			fc.running = false
+			fc.Unlock()
+		}
+	}()
+
+	collectStart := time.Now()
+
+	if err := fc.loadVersions(); err != nil {
+		fc.log.Error().Err(err).Msg("flux api versions")
+	} else {
+		for _, res := range fluxResources {
+			if ctx.Err() != nil {
+				break
+			}
+			fc.resourceMetrics(ctx, res)
+		}
+	}
+
+	fc.check.AddHistSample("collect_latency", cgm.Tags{
+		cgm.Tag{Category: "source", Value: release.NAME},
+		cgm.Tag{Category: "op", Value: "collect_flux"},
+		cgm.Tag{Category: "units", Value: "milliseconds"},
+	}, float64(time.Since(collectStart).Milliseconds()))
+	fc.log.Debug().Str("duration", time.Since(collectStart).String()).Msg("flux collect end")
+	fc.Lock()
+	fc.running = false
+	fc.Unlock()
+}
+
+// resourceMetrics emits the ready, suspended, and drift status of each object and
+// the number of objects not ready for a flux resource
+func (fc *Flux) resourceMetrics(ctx context.Context, res fluxResource) {
+	version, ok := fc.versions[res.group]
+	if !ok {
+		fc.log.Debug().Str("group", res.group).Msg("api group not installed, skipping")
+		return
+	}
+
+	gvr := schema.GroupVersionResource{Group: res.group, Version: version, Resource: res.resource}
+	list, err := fc.dynamic.Resource(gvr).Namespace(fc.config.FluxNamespace).List(metav1.ListOptions{})
+	if err != nil {
+		fc.check.IncrementCounter("collect_api_errors", cgm.Tags{
+			cgm.Tag{Category: "source", Value: release.NAME},
+			cgm.Tag{Category: "request", Value: res.resource + "-list"},
+			cgm.Tag{Category: "target", Value: "api-server"},
+		})
+		fc.log.Error().Err(err).Str("resource", gvr.String()).Msg("listing flux resources")
+		return
+	}
+
+	metrics := make(map[string]circonus.MetricSample)
+	notReady := uint64(0)
+
+	for i := range list.Items {
+		obj := &list.Items[i]
+		st := reconcileStatus(obj)
+		streamTags := []string{
+			"source:flux",
+			"source_type:" + res.resource,
+			"kind:" + res.kind,
+			"namespace:" + obj.GetNamespace(),
+			"name:" + obj.GetName(),
+			"__rollup:false", // prevent high cardinality metrics from rolling up
+		}
+
+		ready := uint64(0)
+		if st.ready {
+			ready = 1
+		} else {
+			notReady++
+		}
+		readyTags := streamTags
+		if st.reason != "" {
+			readyTags = append(readyTags, "reason:"+st.reason)
+		}
+		suspended := uint64(0)
+		if st.suspended {
+			suspended = 1
+		}
+		drift := uint64(0)
+		if st.drift {
+			drift = 1
+		}
+
+		_ = fc.check.QueueMetricSample(metrics, "flux_ready", circonus.MetricTypeUint64, readyTags, []string{}, ready, fc.ts)
+		_ = fc.check.QueueMetricSample(metrics, "flux_suspended", circonus.MetricTypeUint64, streamTags, []string{}, suspended, fc.ts)
+		_ = fc.check.QueueMetricSample(metrics, "flux_revision_drift", circonus.MetricTypeUint64, streamTags, []string{}, drift, fc.ts)
+	}
+
+	streamTags := []string{
+		"source:flux",
+		"source_type:" + res.resource,
+		"kind:" + res.kind,
+	}
+	_ = fc.check.QueueMetricSample(metrics, "flux_resources", circonus.MetricTypeUint64, streamTags, []string{}, uint64(len(list.Items)), fc.ts)
+	_ = fc.check.QueueMetricSample(metrics, "flux_resources_not_ready", circonus.MetricTypeUint64, streamTags, []string{}, notReady, fc.ts)
+
+	if err := fc.check.SubmitQueue(ctx, metrics, fc.log.With().Str("type", res.resource).Logger()); err != nil {
+		fc.log.Warn().Err(err).Msg("submitting metrics")
+	}
+}
+
+// loadVersions finds the preferred api version of each installed flux api group
+func (fc *Flux) loadVersions() error {
+	fc.Lock()
+	defer fc.Unlock()
+	if len(fc.versions) > 0 {
+		return nil
+	}
+
+	groups, err := fc.discovery.ServerGroups()
+	if err != nil {
+		return errors.Wrap(err, "api groups")
+	}
+	for _, g := range groups.Groups {
+		for _, res := range fluxResources {
+			if g.Name == res.group {
+				fc.versions[res.group] = g.PreferredVersion.Version
+			}
+		}
+	}
+	if len(fc.versions) == 0 {
+		return errors.New("no flux api groups found")
+	}
+
+	return nil
+}
+
+type status struct {
+	ready     bool
+	reason    string // Ready condition reason
+	suspended bool
+	drift     bool // last attempted revision was not applied
+}
+
+// reconcileStatus returns the reconciliation status of a Kustomization or HelmRelease
+func reconcileStatus(obj *unstructured.Unstructured) status {
+	var st status
+
+	st.suspended, _, _ = unstructured.NestedBool(obj.Object, "spec", "suspend")
+
+	conditions, _, _ := unstructured.NestedSlice(obj.Object, "status", "conditions")
+	for _, c := range conditions {
+		cond, ok := c.(map[string]interface{})
+		if !ok || cond["type"] != "Ready" {
+			continue
+		}
+		st.ready = cond["status"] == "True"
+		if reason, ok := cond["reason"].(string); ok {
+			st.reason = reason
+		}
+	}
+
+	attempted, _, _ := unstructured.NestedString(obj.Object, "status", "lastAttemptedRevision")
+	applied, _, _ := unstructured.NestedString(obj.Object, "status", "lastAppliedRevision")
+	st.drift = attempted != "" && attempted != applied
+
+	return st
+}
@@ -0,0 +1,54 @@
+// Copyright © 2019 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+package flux
+
+import (
+	"testing"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func TestReconcileStatus(t *testing.T) {
+	tests := []struct {
+		name string
+		obj  map[string]interface{}
+		want status
+	}{
+		{"no status", map[string]interface{}{}, status{}},
+		{"ready", map[string]interface{}{
+			"status": map[string]interface{}{
+				"conditions": []interface{}{
+					map[string]interface{}{"type": "Ready", "status": "True", "reason": "ReconciliationSucceeded"},
+				},
+				"lastAttemptedRevision": "main/abc",
+				"lastAppliedRevision":   "main/abc",
+			},
+		}, status{ready: true, reason: "ReconciliationSucceeded"}},
+		{"failed drift", map[string]interface{}{
+			"status": map[string]interface{}{
+				"conditions": []interface{}{
+					map[string]interface{}{"type": "Healthy", "status": "True"},
+					map[string]interface{}{"type": "Ready", "status": "False", "reason": "ReconciliationFailed"},
+				},
+				"lastAttemptedRevision": "main/def",
+				"lastAppliedRevision":   "main/abc",
+			},
+		}, status{reason: "ReconciliationFailed", drift: true}},
+		{"suspended", map[string]interface{}{
+			"spec": map[string]interface{}{"suspend": true},
+		}, status{suspended: true}},
+	}
+
+	for _, tst := range tests {
+		tst := tst
+		t.Run(tst.name, func(t *testing.T) {
+			got := reconcileStatus(&unstructured.Unstructured{Object: tst.obj})
+			if got != tst.want {
+				t.Errorf("reconcileStatus() = %+v, want %+v", got, tst.want)
+			}
+		})
+	}
+}
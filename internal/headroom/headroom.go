@@ -0,0 +1,320 @@
+// Copyright © 2019 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+// Package headroom is the scheduling capacity headroom collector
+package headroom
+
+import (
+	"context"
+	"crypto/tls"
+	"sync"
+	"time"
+
+	cgm "github.com/circonus-labs/circonus-gometrics/v3"
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/circonus"
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/config"
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/config/defaults"
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/k8s"
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/release"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// nodePoolLabels are the well known node pool labels, checked in order when
+// a node pool label is not configured
+var nodePoolLabels = []string{
+	"eks.amazonaws.com/nodegroup",
+	"cloud.google.com/gke-nodepool",
+	"kubernetes.azure.com/agentpool",
+	"agentpool",
+	"karpenter.sh/nodepool",
+	"karpenter.sh/provisioner-name",
+}
+
+type Headroom struct {
+	config       *config.Cluster
+	check        *circonus.Check
+	log          zerolog.Logger
+	clientset    kubernetes.Interface
+	running      bool
+	apiTimelimit time.Duration
+	sync.Mutex
+	ts *time.Time
+}
+
+// NOTES:
+// Headroom is node allocatable minus the requests of the pods (not yet completed) scheduled on
+// the node, for cpu, memory, and pods. Only ready, schedulable (not cordoned) nodes count. It is
+// emitted per node pool and cluster wide, as an absolute value and as a percentage of allocatable.
+// Headroom is summed across nodes, a replica still has to fit on a single node.
+
+func New(cfg *config.Cluster, parentLog zerolog.Logger, check *circonus.Check) (*Headroom, error) {
+	if cfg == nil {
+		return nil, errors.New("invalid cluster config (nil)")
+	}
+	if check == nil {
+		return nil, errors.New("invalid check (nil)")
+	}
+
+	hr := &Headroom{
+		config: cfg,
+		check:  check,
+		log:    parentLog.With().Str("collector", "headroom").Logger(),
+	}
+
+	if cfg.APITimelimit != "" {
+		v, err := time.ParseDuration(cfg.APITimelimit)
+		if err != nil {
+			hr.log.Error().Err(err).Msg("parsing api timelimit, using default")
+		} else {
+			hr.apiTimelimit = v
+		}
+	}
+
+	if hr.apiTimelimit == time.Duration(0) {
+		v, err := time.ParseDuration(defaults.K8SAPITimelimit)
+		if err != nil {
+			hr.log.Fatal().Err(err).Msg("parsing DEFAULT api timelimit")
+		}
+		hr.apiTimelimit = v
+	}
+
+	restCfg, err := k8s.RESTConfig(cfg)
+	if err != nil {
+		return nil, errors.Wrap(err, "rest config")
+	}
+	restCfg.Timeout = hr.apiTimelimit
+	clientset, err := kubernetes.NewForConfig(restCfg)
+	if err != nil {
+		return nil, errors.Wrap(err, "clientset")
+	}
+	hr.clientset = clientset
+
+	return hr, nil
+}
+
+func (hr *Headroom) ID() string {
+	return "headroom"
+}
+
+// Collect capacity headroom
+func (hr *Headroom) Collect(ctx context.Context, _ *tls.Config, ts *time.Time) {
+	hr.Lock()
+	if hr.running {
+		hr.log.Warn().Msg("already running")
+		hr.Unlock()
+		return
+	}
+	hr.running = true
+	hr.ts = ts
+	hr.Unlock()
+
+	defer func() {
+		if r := recover(); r != nil {
+			hr.log.Error().Interface("panic", r).Msg("recover")
+			hr.Lock()
+			hr.running = false
+			hr.Unlock()
+		}
+	}()
+
+	collectStart := time.Now()
+
+	hr.headroomMetrics(ctx)
+
+	hr.check.AddHistSample("collect_latency", cgm.Tags{
+		cgm.Tag{Category: "source", Value: release.NAME},
+		cgm.Tag{Category: "op", Value: "collect_headroom"},
+		cgm.Tag{Category: "units", Value: "milliseconds"},
+	}, float64(time.Since(collectStart).Milliseconds()))
+	hr.log.Debug().Str("duration", time.Since(collectStart).String()).Msg("headroom collect end")
+	hr.Lock()
+	hr.running = false
+	hr.Unlock()
+}
+
+// capacity is cpu (cores), memory (bytes), and pods
+type capacity struct {
+	cpu    float64
+	memory float64
+	pods   float64
+}
+
+// headroomMetrics emits the allocatable minus requested capacity per node pool and cluster wide
+func (hr *Headroom) headroomMetrics(ctx context.Context) {
+	nodes, err := hr.clientset.CoreV1().Nodes().List(metav1.ListOptions{LabelSelector: hr.config.NodeSelector})
+	if err != nil {
+		hr.apiError("node-list")
+		hr.log.Error().Err(err).Msg("listing nodes")
+		return
+	}
+	pods, err := hr.clientset.CoreV1().Pods(metav1.NamespaceAll).List(metav1.ListOptions{})
+	if err != nil {
+		hr.apiError("pod-list")
+		hr.log.Error().Err(err).Msg("listing pods")
+		return
+	}
+
+	requested := make(map[string]capacity)
+	for i := range pods.Items {
+		pod := &pods.Items[i]
+		if pod.Spec.NodeName == "" || pod.Status.Phase == corev1.PodSucceeded || pod.Status.Phase == corev1.PodFailed {
+			continue
+		}
+		cpu, memory := podRequests(pod)
+		r := requested[pod.Spec.NodeName]
+		r.cpu += cpu
+		r.memory += memory
+		r.pods++
+		requested[pod.Spec.NodeName] = r
+	}
+
+	allocatable := make(map[string]capacity) // by pool, "" is cluster wide
+	free := make(map[string]capacity)
+	for i := range nodes.Items {
+		node := &nodes.Items[i]
+		if !schedulable(node) {
+			continue
+		}
+		a := capacity{
+			cpu:    float64(node.Status.Allocatable.Cpu().MilliValue()) / 1000,
+			memory: float64(node.Status.Allocatable.Memory().Value()),
+			pods:   float64(node.Status.Allocatable.Pods().Value()),
+		}
+		h := headroom(a, requested[node.Name])
+		for _, pool := range []string{"", nodePool(node, hr.config.HeadroomNodePoolLabel)} {
+			allocatable[pool] = add(allocatable[pool], a)
+			free[pool] = add(free[pool], h)
+		}
+	}
+
+	metrics := make(map[string]circonus.MetricSample)
+	for pool, a := range allocatable {
+		streamTags := []string{
+			"source:headroom",
+			"source_type:capacity",
+		}
+		if pool == "" {
+			streamTags = append(streamTags, "scope:cluster")
+		} else {
+			streamTags = append(streamTags, "scope:node_pool", "node_pool:"+pool)
+		}
+		h := free[pool]
+		for _, rv := range []struct {
+			resource    string
+			units       string
+			headroom    float64
+			allocatable float64
+		}{
+			{"cpu", "cores", h.cpu, a.cpu},
+			{"memory", "bytes", h.memory, a.memory},
+			{"pods", "pods", h.pods, a.pods},
+		} {
+			tags := append(append([]string{}, streamTags...), "resource:"+rv.resource)
+			_ = hr.check.QueueMetricSample(metrics, "capacity_headroom", circonus.MetricTypeFloat64, append(tags, "units:"+rv.units), []string{}, rv.headroom, hr.ts)
+			_ = hr.check.QueueMetricSample(metrics, "capacity_headroom_percent", circonus.MetricTypeFloat64, append(tags, "units:percent"), []string{}, percent(rv.headroom, rv.allocatable), hr.ts)
+		}
+	}
+
+	if len(metrics) == 0 {
+		return
+	}
+	if err := hr.check.SubmitQueue(ctx, metrics, hr.log.With().Str("type", "headroom").Logger()); err != nil {
+		hr.log.Warn().Err(err).Msg("submitting metrics")
+	}
+}
+
+func (hr *Headroom) apiError(request string) {
+	hr.check.IncrementCounter("collect_api_errors", cgm.Tags{
+		cgm.Tag{Category: "source", Value: release.NAME},
+		cgm.Tag{Category: "request", Value: request},
+		cgm.Tag{Category: "target", Value: "api-server"},
+	})
+}
+
+// schedulable returns true if the node is ready and not cordoned
+func schedulable(node *corev1.Node) bool {
+	if node.Spec.Unschedulable {
+		return false
+	}
+	for _, cond := range node.Status.Conditions {
+		if cond.Type == corev1.NodeReady {
+			return cond.Status == corev1.ConditionTrue
+		}
+	}
+	return false
+}
+
+// nodePool returns the node pool of a node using the label (if set) or the first
+// well known node pool label found, "none" if the node is not in a pool
+func nodePool(node *corev1.Node, label string) string {
+	if label != "" {
+		if pool := node.Labels[label]; pool != "" {
+			return pool
+		}
+		return "none"
+	}
+	for _, l := range nodePoolLabels {
+		if pool := node.Labels[l]; pool != "" {
+			return pool
+		}
+	}
+	return "none"
+}
+
+// headroom returns allocatable minus requested, a node may be over committed (e.g. static
+// pods) so headroom is never negative
+func headroom(allocatable, requested capacity) capacity {
+	h := capacity{
+		cpu:    allocatable.cpu - requested.cpu,
+		memory: allocatable.memory - requested.memory,
+		pods:   allocatable.pods - requested.pods,
+	}
+	if h.cpu < 0 {
+		h.cpu = 0
+	}
+	if h.memory < 0 {
+		h.memory = 0
+	}
+	if h.pods < 0 {
+		h.pods = 0
+	}
+	return h
+}
+
+func add(a, b capacity) capacity {
+	return capacity{cpu: a.cpu + b.cpu, memory: a.memory + b.memory, pods: a.pods + b.pods}
+}
+
+func percent(v, total float64) float64 {
+	if total == 0 {
+		return 0
+	}
+	return v / total * 100
+}
+
+// podRequests returns the effective pod cpu (cores) and memory (bytes) requests, the larger of
+// the sum of the containers or the largest init container, plus the pod overhead
+func podRequests(pod *corev1.Pod) (float64, float64) {
+	var cpu, memory float64
+	for _, c := range pod.Spec.Containers {
+		cpu += float64(c.Resources.Requests.Cpu().MilliValue()) / 1000
+		memory += float64(c.Resources.Requests.Memory().Value())
+	}
+	for _, c := range pod.Spec.InitContainers {
+		if v := float64(c.Resources.Requests.Cpu().MilliValue()) / 1000; v > cpu {
+			cpu = v
+		}
+		if v := float64(c.Resources.Requests.Memory().Value()); v > memory {
+			memory = v
+		}
+	}
+	cpu += float64(pod.Spec.Overhead.Cpu().MilliValue()) / 1000
+	memory += float64(pod.Spec.Overhead.Memory().Value())
+	return cpu, memory
+}
@@ -0,0 +1,69 @@
+// Copyright © 2019 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+package headroom
+
+import (
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestSchedulable(t *testing.T) {
+	ready := []corev1.NodeCondition{{Type: corev1.NodeReady, Status: corev1.ConditionTrue}}
+	notReady := []corev1.NodeCondition{{Type: corev1.NodeReady, Status: corev1.ConditionFalse}}
+
+	tests := []struct {
+		name string
+		node *corev1.Node
+		want bool
+	}{
+		{"ready", &corev1.Node{Status: corev1.NodeStatus{Conditions: ready}}, true},
+		{"not ready", &corev1.Node{Status: corev1.NodeStatus{Conditions: notReady}}, false},
+		{"cordoned", &corev1.Node{Spec: corev1.NodeSpec{Unschedulable: true}, Status: corev1.NodeStatus{Conditions: ready}}, false},
+		{"no conditions", &corev1.Node{}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := schedulable(tt.node); got != tt.want {
+				t.Errorf("schedulable() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNodePool(t *testing.T) {
+	node := &corev1.Node{ObjectMeta: metav1.ObjectMeta{Labels: map[string]string{
+		"cloud.google.com/gke-nodepool": "default-pool",
+		"pool":                          "custom",
+	}}}
+
+	if p := nodePool(node, ""); p != "default-pool" {
+		t.Errorf("well known label = %s, want default-pool", p)
+	}
+	if p := nodePool(node, "pool"); p != "custom" {
+		t.Errorf("configured label = %s, want custom", p)
+	}
+	if p := nodePool(node, "missing"); p != "none" {
+		t.Errorf("missing label = %s, want none", p)
+	}
+	if p := nodePool(&corev1.Node{}, ""); p != "none" {
+		t.Errorf("no labels = %s, want none", p)
+	}
+}
+
+func TestHeadroom(t *testing.T) {
+	h := headroom(capacity{cpu: 4, memory: 1000, pods: 110}, capacity{cpu: 5, memory: 250, pods: 10})
+	if h.cpu != 0 || h.memory != 750 || h.pods != 100 {
+		t.Errorf("headroom() = %+v", h)
+	}
+	if p := percent(h.memory, 1000); p != 75 {
+		t.Errorf("percent() = %f, want 75", p)
+	}
+	if p := percent(1, 0); p != 0 {
+		t.Errorf("percent() of zero = %f, want 0", p)
+	}
+}
@@ -0,0 +1,317 @@
+// Copyright © 2019 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+// Package hpa is the horizontal pod autoscaler status collector
+package hpa
+
+import (
+	"context"
+	"crypto/tls"
+	"sync"
+	"time"
+
+	cgm "github.com/circonus-labs/circonus-gometrics/v3"
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/circonus"
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/config"
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/config/defaults"
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/k8s"
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/release"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog"
+	autoscalingv2 "k8s.io/api/autoscaling/v2beta2"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+type HPA struct {
+	config       *config.Cluster
+	check        *circonus.Check
+	log          zerolog.Logger
+	clientset    kubernetes.Interface
+	running      bool
+	apiTimelimit time.Duration
+	sync.Mutex
+	ts *time.Time
+}
+
+// NOTES:
+// HorizontalPodAutoscalers are listed (autoscaling/v2beta2) from the api-server, emitting current,
+// desired, min, and max replicas, whether the hpa is pinned at max replicas, the target vs current
+// value of each metric, and condition states (e.g. AbleToScale, ScalingActive, ScalingLimited).
+
+func New(cfg *config.Cluster, parentLog zerolog.Logger, check *circonus.Check) (*HPA, error) {
+	if cfg == nil {
+		return nil, errors.New("invalid cluster config (nil)")
+	}
+	if check == nil {
+		return nil, errors.New("invalid check (nil)")
+	}
+
+	h := &HPA{
+		config: cfg,
+		check:  check,
+		log:    parentLog.With().Str("collector", "hpa").Logger(),
+	}
+
+	if cfg.APITimelimit != "" {
+		v, err := time.ParseDuration(cfg.APITimelimit)
+		if err != nil {
+			h.log.Error().Err(err).Msg("parsing api timelimit, using default")
+		} else {
+			h.apiTimelimit = v
+		}
+	}
+
+	if h.apiTimelimit == time.Duration(0) {
+		v, err := time.ParseDuration(defaults.K8SAPITimelimit)
+		if err != nil {
+			h.log.Fatal().Err(err).Msg("parsing DEFAULT api timelimit")
+		}
+		h.apiTimelimit = v
+	}
+
+	restCfg, err := k8s.RESTConfig(cfg)
+	if err != nil {
+		return nil, errors.Wrap(err, "rest config")
+	}
+	restCfg.Timeout = h.apiTimelimit
+	clientset, err := kubernetes.NewForConfig(restCfg)
+	if err != nil {
+		return nil, errors.Wrap(err, "clientset")
+	}
+	h.clientset = clientset
+
+	return h, nil
+}
+
+func (h *HPA) ID() string {
+	return "hpa"
+}
+
+// Collect horizontal pod autoscaler status
+func (h *HPA) Collect(ctx context.Context, _ *tls.Config, ts *time.Time) {
+	h.Lock()
+	if h.running {
+		h.log.Warn().Msg("already running")
+		h.Unlock()
+		return
+	}
+	h.running = true
+	h.ts = ts
+	h.Unlock()
+
+	defer func() {
+		if r := recover(); r != nil {
+			h.log.Error().Interface("panic", r).Msg("recover")
+			h.Lock()
+			h.running = false
+			h.Unlock()
+		}
+	}()
+
+	collectStart := time.Now()
+
+	h.autoscalerMetrics(ctx)
+
+	h.check.AddHistSample("collect_latency", cgm.Tags{
+		cgm.Tag{Category: "source", Value: release.NAME},
+		cgm.Tag{Category: "op", Value: "collect_hpa"},
+		cgm.Tag{Category: "units", Value: "milliseconds"},
+	}, float64(time.Since(collectStart).Milliseconds()))
+	h.log.Debug().Str("duration", time.Since(collectStart).String()).Msg("hpa collect end")
+	h.Lock()
+	h.running = false
+	h.Unlock()
+}
+
+// metricValue is the target and current value of an hpa metric
+type metricValue struct {
+	sourceType string // Resource, Pods, Object, External
+	name       string
+	targetType string // Utilization, Value, AverageValue
+	target     float64
+	current    float64
+	hasCurrent bool
+}
+
+// autoscalerMetrics emits replica counts, metric target vs current values, and
+// condition states for each horizontal pod autoscaler
+func (h *HPA) autoscalerMetrics(ctx context.Context) {
+	hpas, err := h.clientset.AutoscalingV2beta2().HorizontalPodAutoscalers(metav1.NamespaceAll).List(metav1.ListOptions{})
+	if err != nil {
+		h.check.IncrementCounter("collect_api_errors", cgm.Tags{
+			cgm.Tag{Category: "source", Value: release.NAME},
+			cgm.Tag{Category: "request", Value: "hpa-list"},
+			cgm.Tag{Category: "target", Value: "api-server"},
+		})
+		h.log.Error().Err(err).Msg("listing horizontal pod autoscalers")
+		return
+	}
+
+	metrics := make(map[string]circonus.MetricSample)
+	for i := range hpas.Items {
+		hpa := &hpas.Items[i]
+		baseStreamTags := []string{
+			"source:hpa",
+			"source_type:autoscaling",
+			"namespace:" + hpa.Namespace,
+			"hpa:" + hpa.Name,
+			"target_kind:" + hpa.Spec.ScaleTargetRef.Kind,
+			"target_name:" + hpa.Spec.ScaleTargetRef.Name,
+			"__rollup:false", // prevent high cardinality metrics from rolling up
+		}
+
+		minReplicas := int32(1)
+		if hpa.Spec.MinReplicas != nil {
+			minReplicas = *hpa.Spec.MinReplicas
+		}
+		atMax := uint64(0)
+		if hpa.Status.CurrentReplicas >= hpa.Spec.MaxReplicas {
+			atMax = 1
+		}
+		_ = h.check.QueueMetricSample(metrics, "hpa_current_replicas", circonus.MetricTypeInt32, baseStreamTags, []string{}, hpa.Status.CurrentReplicas, h.ts)
+		_ = h.check.QueueMetricSample(metrics, "hpa_desired_replicas", circonus.MetricTypeInt32, baseStreamTags, []string{}, hpa.Status.DesiredReplicas, h.ts)
+		_ = h.check.QueueMetricSample(metrics, "hpa_min_replicas", circonus.MetricTypeInt32, baseStreamTags, []string{}, minReplicas, h.ts)
+		_ = h.check.QueueMetricSample(metrics, "hpa_max_replicas", circonus.MetricTypeInt32, baseStreamTags, []string{}, hpa.Spec.MaxReplicas, h.ts)
+		_ = h.check.QueueMetricSample(metrics, "hpa_at_max_replicas", circonus.MetricTypeUint64, baseStreamTags, []string{}, atMax, h.ts)
+
+		for _, mv := range metricValues(hpa) {
+			var streamTags []string
+			streamTags = append(streamTags, baseStreamTags...)
+			streamTags = append(streamTags, []string{
+				"metric_source:" + mv.sourceType,
+				"metric_name:" + mv.name,
+				"metric_target_type:" + mv.targetType,
+			}...)
+			_ = h.check.QueueMetricSample(metrics, "hpa_metric_target", circonus.MetricTypeFloat64, streamTags, []string{}, mv.target, h.ts)
+			if mv.hasCurrent {
+				_ = h.check.QueueMetricSample(metrics, "hpa_metric_current", circonus.MetricTypeFloat64, streamTags, []string{}, mv.current, h.ts)
+			}
+		}
+
+		for _, cond := range hpa.Status.Conditions {
+			var streamTags []string
+			streamTags = append(streamTags, baseStreamTags...)
+			streamTags = append(streamTags, []string{
+				"condition:" + string(cond.Type),
+				"reason:" + cond.Reason,
+			}...)
+			status := uint64(0)
+			if cond.Status == corev1.ConditionTrue {
+				status = 1
+			}
+			_ = h.check.QueueMetricSample(metrics, "hpa_condition", circonus.MetricTypeUint64, streamTags, []string{}, status, h.ts)
+		}
+	}
+
+	if len(metrics) == 0 {
+		return
+	}
+	if err := h.check.SubmitQueue(ctx, metrics, h.log.With().Str("type", "hpa").Logger()); err != nil {
+		h.log.Warn().Err(err).Msg("submitting metrics")
+	}
+}
+
+// metricValues returns the target and current (when reported in the status) value
+// of each metric in the hpa spec
+func metricValues(hpa *autoscalingv2.HorizontalPodAutoscaler) []metricValue {
+	current := make(map[string]autoscalingv2.MetricValueStatus)
+	for _, ms := range hpa.Status.CurrentMetrics {
+		var name string
+		var value autoscalingv2.MetricValueStatus
+		switch {
+		case ms.Resource != nil:
+			name, value = string(ms.Resource.Name), ms.Resource.Current
+		case ms.Pods != nil:
+			name, value = ms.Pods.Metric.Name, ms.Pods.Current
+		case ms.Object != nil:
+			name, value = ms.Object.Metric.Name, ms.Object.Current
+		case ms.External != nil:
+			name, value = ms.External.Metric.Name, ms.External.Current
+		default:
+			continue
+		}
+		current[string(ms.Type)+"/"+name] = value
+	}
+
+	var values []metricValue
+	for _, spec := range hpa.Spec.Metrics {
+		var name string
+		var target autoscalingv2.MetricTarget
+		switch {
+		case spec.Resource != nil:
+			name, target = string(spec.Resource.Name), spec.Resource.Target
+		case spec.Pods != nil:
+			name, target = spec.Pods.Metric.Name, spec.Pods.Target
+		case spec.Object != nil:
+			name, target = spec.Object.Metric.Name, spec.Object.Target
+		case spec.External != nil:
+			name, target = spec.External.Metric.Name, spec.External.Target
+		default:
+			continue
+		}
+
+		mv := metricValue{
+			sourceType: string(spec.Type),
+			name:       name,
+			targetType: string(target.Type),
+		}
+		var ok bool
+		if mv.target, ok = targetValue(target); !ok {
+			continue
+		}
+		if cur, found := current[mv.sourceType+"/"+name]; found {
+			mv.current, mv.hasCurrent = currentValue(cur, target.Type)
+		}
+		values = append(values, mv)
+	}
+
+	return values
+}
+
+// targetValue returns the metric target value for the target type
+func targetValue(target autoscalingv2.MetricTarget) (float64, bool) {
+	switch target.Type {
+	case autoscalingv2.UtilizationMetricType:
+		if target.AverageUtilization != nil {
+			return float64(*target.AverageUtilization), true
+		}
+	case autoscalingv2.AverageValueMetricType:
+		if target.AverageValue != nil {
+			return quantityValue(target.AverageValue), true
+		}
+	case autoscalingv2.ValueMetricType:
+		if target.Value != nil {
+			return quantityValue(target.Value), true
+		}
+	}
+	return 0, false
+}
+
+// currentValue returns the current metric value comparable to the target type
+func currentValue(cur autoscalingv2.MetricValueStatus, targetType autoscalingv2.MetricTargetType) (float64, bool) {
+	switch targetType {
+	case autoscalingv2.UtilizationMetricType:
+		if cur.AverageUtilization != nil {
+			return float64(*cur.AverageUtilization), true
+		}
+	case autoscalingv2.AverageValueMetricType:
+		if cur.AverageValue != nil {
+			return quantityValue(cur.AverageValue), true
+		}
+	case autoscalingv2.ValueMetricType:
+		if cur.Value != nil {
+			return quantityValue(cur.Value), true
+		}
+	}
+	return 0, false
+}
+
+// quantityValue returns a quantity as a float, preserving milli units (e.g. 500m cpu = 0.5)
+func quantityValue(q *resource.Quantity) float64 {
+	return float64(q.MilliValue()) / 1000
+}
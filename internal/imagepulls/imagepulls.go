@@ -0,0 +1,217 @@
+// Copyright © 2019 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+// Package imagepulls is the image pull failure and duration collector
+package imagepulls
+
+import (
+	"context"
+	"crypto/tls"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/circonus"
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/config"
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/k8s"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/cache"
+)
+
+var (
+	pulledRx  = regexp.MustCompile(`^Successfully pulled image "([^"]+)" in ([^ ]+)`)
+	failedRx  = regexp.MustCompile(`^Failed to pull image "([^"]+)"`)
+	backOffRx = regexp.MustCompile(`^Back-off pulling image "([^"]+)"`)
+)
+
+type ImagePulls struct {
+	config *config.Cluster
+	check  *circonus.Check
+	log    zerolog.Logger
+	start  time.Time // events before start are not counted
+	events *circonus.EventMetrics
+}
+
+// NOTES:
+// Pod events are watched for image pulls (Pulled), pull failures (Failed), and pull back-offs
+// (BackOff), counts and durations are tagged with the image registry. The pull duration is
+// only included in Pulled event messages by kubelet v1.19+. Pull operation latency and errors
+// as seen by the kubelet are collected with the kubelet operational metrics
+// (kubelet_runtime_operations_*{operation_type="pull_image"}).
+
+func New(cfg *config.Cluster, parentLog zerolog.Logger, check *circonus.Check) (*ImagePulls, error) {
+	if cfg == nil {
+		return nil, errors.New("invalid cluster config (nil)")
+	}
+	if check == nil {
+		return nil, errors.New("invalid check (nil)")
+	}
+
+	return &ImagePulls{
+		config: cfg,
+		check:  check,
+		log:    parentLog.With().Str("collector", "image-pulls").Logger(),
+		start:  time.Now(),
+		events: circonus.NewEventMetrics(),
+	}, nil
+}
+
+func (ip *ImagePulls) ID() string {
+	return "image-pulls"
+}
+
+// Start watching pod events, does not return until ctx is done
+func (ip *ImagePulls) Start(ctx context.Context, _ *tls.Config) {
+	ip.log.Info().Msg("starting watcher")
+
+	cfg, err := k8s.RESTConfig(ip.config)
+	if err != nil {
+		ip.log.Error().Err(err).Msg("unable to start image pull watcher")
+		return
+	}
+
+	clientset, err := kubernetes.NewForConfig(cfg)
+	if err != nil {
+		ip.log.Error().Err(err).Msg("initializing client set")
+		return
+	}
+
+	factory := informers.NewSharedInformerFactoryWithOptions(clientset, 0,
+		informers.WithTweakListOptions(func(opts *metav1.ListOptions) {
+			opts.FieldSelector = "involvedObject.kind=Pod"
+		}))
+	informer := factory.Core().V1().Events().Informer()
+	stopper := make(chan struct{})
+	defer close(stopper)
+	defer runtime.HandleCrash()
+
+	informer.AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc: func(obj interface{}) {
+			ev := obj.(*corev1.Event)
+			if ip.eventTime(ev).Before(ip.start) {
+				return
+			}
+			ip.record(ev, 1)
+		},
+		UpdateFunc: func(oldObj interface{}, newObj interface{}) {
+			// repeated events (e.g. BackOff) are updated with an increased count
+			oldEv := oldObj.(*corev1.Event)
+			newEv := newObj.(*corev1.Event)
+			if newEv.Count > oldEv.Count {
+				ip.record(newEv, int(newEv.Count-oldEv.Count))
+			}
+		},
+	})
+
+	go informer.Run(stopper)
+
+	if !cache.WaitForCacheSync(stopper, informer.HasSynced) {
+		ip.log.Warn().Msg("timed out waiting for cache to sync")
+		return
+	}
+
+	<-ctx.Done()
+	ip.log.Debug().Msg("closing image pull watcher")
+}
+
+// Collect queues the image pull metrics of the events received since the previous collection
+func (ip *ImagePulls) Collect(ctx context.Context, _ *tls.Config, ts *time.Time) {
+	metrics := make(map[string]circonus.MetricSample)
+	ip.events.Queue(ip.check, metrics, ts)
+	if len(metrics) == 0 {
+		return
+	}
+	if err := ip.check.SubmitQueue(ctx, metrics, ip.log.With().Str("type", "image-pulls").Logger()); err != nil {
+		ip.log.Warn().Err(err).Msg("submitting metrics")
+	}
+}
+
+// record counts an image pull event (and adds the pull duration)
+func (ip *ImagePulls) record(ev *corev1.Event, count int) {
+	if !ip.check.AllowNamespace(ev.InvolvedObject.Namespace) {
+		return
+	}
+	pull, ok := parseEvent(ev.Reason, ev.Message)
+	if !ok {
+		return
+	}
+
+	streamTags := []string{
+		"source:image-pulls",
+		"registry:" + registry(pull.image),
+	}
+
+	if pull.result == "success" {
+		ip.events.Increment("image_pulls", streamTags, uint64(count))
+		if pull.duration > 0 {
+			ip.events.AddSample("image_pull_duration", append(streamTags, "units:seconds"), pull.duration.Seconds())
+		}
+		return
+	}
+
+	ip.events.Increment("image_pull_failures", append(streamTags, "reason:"+pull.result), uint64(count))
+}
+
+// eventTime returns the last time the event occurred
+func (ip *ImagePulls) eventTime(ev *corev1.Event) time.Time {
+	if !ev.LastTimestamp.IsZero() {
+		return ev.LastTimestamp.Time
+	}
+	if !ev.EventTime.IsZero() {
+		return ev.EventTime.Time
+	}
+	return ev.CreationTimestamp.Time
+}
+
+type imagePull struct {
+	image    string
+	result   string // success, failed, or backoff
+	duration time.Duration
+}
+
+// parseEvent returns the image pull details from a pod event reason and message
+func parseEvent(reason, message string) (imagePull, bool) {
+	switch reason {
+	case "Pulled":
+		m := pulledRx.FindStringSubmatch(message)
+		if m == nil {
+			return imagePull{}, false // e.g. image already present on machine
+		}
+		pull := imagePull{image: m[1], result: "success"}
+		if d, err := time.ParseDuration(m[2]); err == nil {
+			pull.duration = d
+		}
+		return pull, true
+	case "Failed":
+		if m := failedRx.FindStringSubmatch(message); m != nil {
+			return imagePull{image: m[1], result: "failed"}, true
+		}
+	case "BackOff":
+		if m := backOffRx.FindStringSubmatch(message); m != nil {
+			return imagePull{image: m[1], result: "backoff"}, true
+		}
+	}
+	return imagePull{}, false
+}
+
+// registry returns the registry host of an image reference, docker.io if the reference
+// does not include a registry (e.g. nginx:1.17 or library/nginx)
+func registry(image string) string {
+	i := strings.Index(image, "/")
+	if i == -1 {
+		return "docker.io"
+	}
+	host := image[:i]
+	if strings.ContainsAny(host, ".:") || host == "localhost" {
+		return host
+	}
+	return "docker.io"
+}
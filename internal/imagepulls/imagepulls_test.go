@@ -0,0 +1,106 @@
+// Copyright © 2019 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+package imagepulls
+
+import (
+	"os"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/circonus"
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/config"
+	"github.com/rs/zerolog"
+	corev1 "k8s.io/api/core/v1"
+)
+
+func TestParseEvent(t *testing.T) {
+	tests := []struct {
+		name    string
+		reason  string
+		message string
+		want    imagePull
+		ok      bool
+	}{
+		{"pulled", "Pulled", `Successfully pulled image "nginx:1.17" in 2.5s`, imagePull{image: "nginx:1.17", result: "success", duration: 2500 * time.Millisecond}, true},
+		{"pulled waiting", "Pulled", `Successfully pulled image "nginx" in 1.2s (3.4s including waiting)`, imagePull{image: "nginx", result: "success", duration: 1200 * time.Millisecond}, true},
+		{"present", "Pulled", `Container image "nginx" already present on machine`, imagePull{}, false},
+		{"failed", "Failed", `Failed to pull image "quay.io/foo/bar:1": rpc error: code = Unknown`, imagePull{image: "quay.io/foo/bar:1", result: "failed"}, true},
+		{"failed container", "Failed", `Error: ErrImagePull`, imagePull{}, false},
+		{"backoff", "BackOff", `Back-off pulling image "gcr.io/foo/bar"`, imagePull{image: "gcr.io/foo/bar", result: "backoff"}, true},
+		{"backoff restart", "BackOff", `Back-off restarting failed container`, imagePull{}, false},
+		{"other", "Scheduled", `Successfully assigned default/foo to node1`, imagePull{}, false},
+	}
+
+	for _, tst := range tests {
+		tst := tst
+		t.Run(tst.name, func(t *testing.T) {
+			got, ok := parseEvent(tst.reason, tst.message)
+			if ok != tst.ok || got != tst.want {
+				t.Errorf("parseEvent() = %+v, %t, want %+v, %t", got, ok, tst.want, tst.ok)
+			}
+		})
+	}
+}
+
+func TestRegistry(t *testing.T) {
+	tests := []struct {
+		image string
+		want  string
+	}{
+		{"nginx", "docker.io"},
+		{"library/nginx:1.17", "docker.io"},
+		{"quay.io/foo/bar:1", "quay.io"},
+		{"localhost/foo", "localhost"},
+		{"registry.local:5000/foo", "registry.local:5000"},
+	}
+
+	for _, tst := range tests {
+		tst := tst
+		t.Run(tst.image, func(t *testing.T) {
+			if got := registry(tst.image); got != tst.want {
+				t.Errorf("registry() = %s, want %s", got, tst.want)
+			}
+		})
+	}
+}
+
+func TestRecord(t *testing.T) {
+	check, err := circonus.NewCheck(zerolog.Nop(), &config.Circonus{DryRun: true, DryRunOutput: os.DevNull, DefaultStreamtags: "cluster:test", SubmitBackoffMin: "1s", SubmitBackoffMax: "1s"})
+	if err != nil {
+		t.Fatalf("unexpected error (%s)", err)
+	}
+	ip, err := New(&config.Cluster{}, zerolog.Nop(), check)
+	if err != nil {
+		t.Fatalf("unexpected error (%s)", err)
+	}
+
+	ip.record(&corev1.Event{Reason: "Pulled", Message: `Successfully pulled image "nginx" in 1.05s`}, 1)
+	ip.record(&corev1.Event{Reason: "Failed", Message: `Failed to pull image "quay.io/foo/bar:1": rpc error`}, 1)
+	ip.record(&corev1.Event{Reason: "BackOff", Message: `Back-off pulling image "quay.io/foo/bar:1"`}, 3)
+
+	metrics := make(map[string]circonus.MetricSample)
+	ip.events.Queue(check, metrics, nil)
+
+	want := map[string]interface{}{
+		"image_pulls|ST[cluster:test,registry:docker.io,source:image-pulls]":                       uint64(1),
+		"image_pull_failures|ST[cluster:test,reason:failed,registry:quay.io,source:image-pulls]":   uint64(1),
+		"image_pull_failures|ST[cluster:test,reason:backoff,registry:quay.io,source:image-pulls]":  uint64(3),
+		"image_pull_duration|ST[cluster:test,registry:docker.io,source:image-pulls,units:seconds]": []string{"H[1.05e+00]=1"},
+	}
+	if len(metrics) != len(want) {
+		t.Fatalf("expected %d metrics, got %v", len(want), metrics)
+	}
+	for name, value := range want {
+		ms, ok := metrics[name]
+		if !ok {
+			t.Fatalf("expected %s, got %v", name, metrics)
+		}
+		if !reflect.DeepEqual(ms.Value, value) {
+			t.Fatalf("%s: expected %v, got %v", name, value, ms.Value)
+		}
+	}
+}
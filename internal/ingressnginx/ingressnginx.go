@@ -0,0 +1,130 @@
+// Copyright © 2019 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+// Package ingressnginx is the ingress-nginx controller metrics collector
+package ingressnginx
+
+import (
+	"context"
+	"crypto/tls"
+	"regexp"
+	"sync"
+	"time"
+
+	cgm "github.com/circonus-labs/circonus-gometrics/v3"
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/circonus"
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/config"
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/config/defaults"
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/release"
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/scrape"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog"
+)
+
+// only forward the controller request (rate, status), latency (request and upstream), and
+// connection metric families. The request metrics carry ingress, namespace, and status
+// labels which become stream tags.
+var familyFilter = regexp.MustCompile(`^nginx_ingress_controller_(requests|request_duration_seconds|response_duration_seconds|ingress_upstream_latency_seconds|nginx_process_connections|config_last_reload_successful)$`)
+
+type IngressNginx struct {
+	config       *config.Cluster
+	check        *circonus.Check
+	log          zerolog.Logger
+	running      bool
+	apiTimelimit time.Duration
+	sync.Mutex
+	ts *time.Time
+}
+
+// NOTES:
+// ingress-nginx controller pods (labeled app.kubernetes.io/name=ingress-nginx by the standard
+// deployments) expose metrics on 10254. Metrics are fetched through the api-server pod proxy.
+
+func New(cfg *config.Cluster, parentLog zerolog.Logger, check *circonus.Check) (*IngressNginx, error) {
+	if cfg == nil {
+		return nil, errors.New("invalid cluster config (nil)")
+	}
+	if check == nil {
+		return nil, errors.New("invalid check (nil)")
+	}
+
+	in := &IngressNginx{
+		config: cfg,
+		check:  check,
+		log:    parentLog.With().Str("collector", "ingress-nginx").Logger(),
+	}
+
+	if cfg.APITimelimit != "" {
+		v, err := time.ParseDuration(cfg.APITimelimit)
+		if err != nil {
+			in.log.Error().Err(err).Msg("parsing api timelimit, using default")
+		} else {
+			in.apiTimelimit = v
+		}
+	}
+
+	if in.apiTimelimit == time.Duration(0) {
+		v, err := time.ParseDuration(defaults.K8SAPITimelimit)
+		if err != nil {
+			in.log.Fatal().Err(err).Msg("parsing DEFAULT api timelimit")
+		}
+		in.apiTimelimit = v
+	}
+
+	return in, nil
+}
+
+func (in *IngressNginx) ID() string {
+	return "ingress-nginx"
+}
+
+// Collect metrics from ingress-nginx pods
+func (in *IngressNginx) Collect(ctx context.Context, tlsConfig *tls.Config, ts *time.Time) {
+	in.Lock()
+	if in.running {
+		in.log.Warn().Msg("already running")
+		in.Unlock()
+		return
+	}
+	in.running = true
+	in.ts = ts
+	in.Unlock()
+
+	defer func() {
+		if r := recover(); r != nil {
+			in.log.Error().Interface("panic", r).Msg("recover")
+			in.Lock()
+			in.running = false
+			in.Unlock()
+		}
+	}()
+
+	collectStart := time.Now()
+
+	in.podMetrics(ctx, tlsConfig)
+
+	in.check.AddHistSample("collect_latency", cgm.Tags{
+		cgm.Tag{Category: "source", Value: release.NAME},
+		cgm.Tag{Category: "op", Value: "collect_ingress-nginx"},
+		cgm.Tag{Category: "units", Value: "milliseconds"},
+	}, float64(time.Since(collectStart).Milliseconds()))
+	in.log.Debug().Str("duration", time.Since(collectStart).String()).Msg("ingress-nginx collect end")
+	in.Lock()
+	in.running = false
+	in.Unlock()
+}
+
+// podMetrics collects metrics from each ingress-nginx pod via the api-server proxy
+func (in *IngressNginx) podMetrics(ctx context.Context, tlsConfig *tls.Config) {
+	scrape.PodMetrics(ctx, in.check, in.log, in.config, tlsConfig, in.apiTimelimit, scrape.Component{
+		Name:         "ingress-nginx",
+		Namespaces:   []string{in.config.IngressNginxNamespace},
+		Selector:     in.config.IngressNginxSelector,
+		Port:         in.config.IngressNginxPort,
+		FamilyFilter: familyFilter,
+		PodTag:       true,
+		Workers:      int(in.config.NodePoolSize), // controllers may run as a daemonset, same number of workers as node collection
+	}, in.ts)
+}
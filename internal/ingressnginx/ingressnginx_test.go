@@ -0,0 +1,65 @@
+// Copyright © 2019 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+package ingressnginx
+
+import (
+	"context"
+	"crypto/tls"
+	"testing"
+
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/config"
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/scrape/scrapetest"
+	"github.com/rs/zerolog"
+)
+
+func TestFamilyFilter(t *testing.T) {
+	tests := []struct {
+		family string
+		want   bool
+	}{
+		{"nginx_ingress_controller_requests", true},
+		{"nginx_ingress_controller_request_duration_seconds", true},
+		{"nginx_ingress_controller_ingress_upstream_latency_seconds", true},
+		{"nginx_ingress_controller_config_last_reload_successful", true},
+		{"nginx_ingress_controller_bytes_sent", false},
+		{"nginx_ingress_controller_requests_total", false},
+		{"go_goroutines", false},
+	}
+
+	for _, tt := range tests {
+		if got := familyFilter.MatchString(tt.family); got != tt.want {
+			t.Errorf("%s: expected %v, got %v", tt.family, tt.want, got)
+		}
+	}
+}
+
+func TestCollect(t *testing.T) {
+	ts := scrapetest.NewServer(map[string]string{
+		"/api/v1/namespaces/ingress-nginx/pods": `{"items":[{"metadata":{"name":"ingress-nginx-controller-abcde","namespace":"ingress-nginx"},"spec":{"nodeName":"worker1"},"status":{"phase":"Running"}}]}`,
+		"/api/v1/namespaces/ingress-nginx/pods/ingress-nginx-controller-abcde:10254/proxy/metrics": `# TYPE nginx_ingress_controller_requests counter
+nginx_ingress_controller_requests{ingress="web",namespace="app",status="503"} 7
+`,
+	})
+	defer ts.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	check, rec := scrapetest.NewCheck(ctx, t)
+
+	in, err := New(&config.Cluster{
+		URL:                   ts.URL,
+		IngressNginxNamespace: "ingress-nginx",
+		IngressNginxPort:      "10254",
+	}, zerolog.Nop(), check)
+	if err != nil {
+		t.Fatalf("unexpected error (%s)", err)
+	}
+	in.Collect(ctx, &tls.Config{}, nil)
+
+	// the ingress, namespace, and status request labels are stream tags
+	scrapetest.ExpectTags(t, rec, "nginx_ingress_controller_requests",
+		"source:ingress-nginx", "pod:ingress-nginx-controller-abcde", "ingress:web", "namespace:app", "status:503")
+}
@@ -0,0 +1,134 @@
+// Copyright © 2019 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+// Package istio is the istio-proxy (envoy) sidecar metrics collector
+package istio
+
+import (
+	"context"
+	"crypto/tls"
+	"regexp"
+	"sync"
+	"time"
+
+	cgm "github.com/circonus-labs/circonus-gometrics/v3"
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/circonus"
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/config"
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/config/defaults"
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/release"
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/scrape"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog"
+)
+
+// only forward the istio standard metrics (requests, duration, tcp connections) and the key
+// envoy upstream connection and request (retries, timeouts, overflow) metric families
+var familyFilter = regexp.MustCompile(`^(istio_(requests_total|request_duration_milliseconds|tcp_connections_(opened|closed)_total)|envoy_cluster_upstream_(cx_(total|active|connect_fail|connect_timeout)|rq_(total|retry|retry_success|timeout|pending_overflow)))$`)
+
+type Istio struct {
+	config       *config.Cluster
+	check        *circonus.Check
+	log          zerolog.Logger
+	running      bool
+	apiTimelimit time.Duration
+	sync.Mutex
+	ts *time.Time
+}
+
+// NOTES:
+// Injected pods are labeled security.istio.io/tlsMode=istio, the istio-proxy sidecar serves the
+// merged istio and envoy metrics on 15090 /stats/prometheus. Sidecars are scraped directly (pod ip)
+// rather than through the api-server pod proxy, there can be a large number of them.
+
+func New(cfg *config.Cluster, parentLog zerolog.Logger, check *circonus.Check) (*Istio, error) {
+	if cfg == nil {
+		return nil, errors.New("invalid cluster config (nil)")
+	}
+	if check == nil {
+		return nil, errors.New("invalid check (nil)")
+	}
+
+	is := &Istio{
+		config: cfg,
+		check:  check,
+		log:    parentLog.With().Str("collector", "istio").Logger(),
+	}
+
+	if cfg.APITimelimit != "" {
+		v, err := time.ParseDuration(cfg.APITimelimit)
+		if err != nil {
+			is.log.Error().Err(err).Msg("parsing api timelimit, using default")
+		} else {
+			is.apiTimelimit = v
+		}
+	}
+
+	if is.apiTimelimit == time.Duration(0) {
+		v, err := time.ParseDuration(defaults.K8SAPITimelimit)
+		if err != nil {
+			is.log.Fatal().Err(err).Msg("parsing DEFAULT api timelimit")
+		}
+		is.apiTimelimit = v
+	}
+
+	return is, nil
+}
+
+func (is *Istio) ID() string {
+	return "istio"
+}
+
+// Collect metrics from istio-proxy sidecars
+func (is *Istio) Collect(ctx context.Context, tlsConfig *tls.Config, ts *time.Time) {
+	is.Lock()
+	if is.running {
+		is.log.Warn().Msg("already running")
+		is.Unlock()
+		return
+	}
+	is.running = true
+	is.ts = ts
+	is.Unlock()
+
+	defer func() {
+		if r := recover(); r != nil {
+			is.log.Error().Interface("panic", r).Msg("recover")
+			is.Lock()
+			is.running = false
+			is.Unlock()
+		}
+	}()
+
+	collectStart := time.Now()
+
+	is.podMetrics(ctx, tlsConfig)
+
+	is.check.AddHistSample("collect_latency", cgm.Tags{
+		cgm.Tag{Category: "source", Value: release.NAME},
+		cgm.Tag{Category: "op", Value: "collect_istio"},
+		cgm.Tag{Category: "units", Value: "milliseconds"},
+	}, float64(time.Since(collectStart).Milliseconds()))
+	is.log.Debug().Str("duration", time.Since(collectStart).String()).Msg("istio collect end")
+	is.Lock()
+	is.running = false
+	is.Unlock()
+}
+
+// podMetrics collects metrics from each istio-proxy sidecar in the selected namespaces
+func (is *Istio) podMetrics(ctx context.Context, tlsConfig *tls.Config) {
+	scrape.PodMetrics(ctx, is.check, is.log, is.config, tlsConfig, is.apiTimelimit, scrape.Component{
+		Name:         "istio-proxy",
+		Source:       "istio",
+		Namespaces:   scrape.Namespaces(is.config.IstioNamespaces),
+		Selector:     is.config.IstioSelector,
+		Port:         is.config.IstioPort,
+		Path:         "/stats/prometheus",
+		FamilyFilter: familyFilter,
+		Direct:       true,
+		PodTag:       true,
+		NamespaceTag: true,
+		Workers:      int(is.config.NodePoolSize),
+	}, is.ts)
+}
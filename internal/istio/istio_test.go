@@ -0,0 +1,66 @@
+// Copyright © 2019 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+package istio
+
+import (
+	"context"
+	"crypto/tls"
+	"testing"
+
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/config"
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/scrape/scrapetest"
+	"github.com/rs/zerolog"
+)
+
+func TestFamilyFilter(t *testing.T) {
+	tests := []struct {
+		family string
+		want   bool
+	}{
+		{"istio_requests_total", true},
+		{"istio_request_duration_milliseconds", true},
+		{"istio_tcp_connections_opened_total", true},
+		{"envoy_cluster_upstream_rq_retry", true},
+		{"envoy_cluster_upstream_cx_active", true},
+		{"istio_request_bytes", false},
+		{"envoy_server_memory_allocated", false},
+	}
+
+	for _, tt := range tests {
+		if got := familyFilter.MatchString(tt.family); got != tt.want {
+			t.Errorf("%s: expected %v, got %v", tt.family, tt.want, got)
+		}
+	}
+}
+
+func TestCollect(t *testing.T) {
+	ts := scrapetest.NewServer(map[string]string{
+		"/api/v1/namespaces/app/pods": `{"items":[{"metadata":{"name":"web-1","namespace":"app"},"spec":{"nodeName":"worker1"},"status":{"phase":"Running","podIP":"127.0.0.1"}}]}`,
+		"/stats/prometheus": `# TYPE istio_requests_total counter
+istio_requests_total{response_code="200",destination_service_name="web"} 12
+`,
+	})
+	defer ts.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	check, rec := scrapetest.NewCheck(ctx, t)
+
+	// sidecars are scraped directly on the pod ip, a namespace which cannot be listed
+	// (missing) does not prevent collecting the others
+	is, err := New(&config.Cluster{
+		URL:             ts.URL,
+		IstioNamespaces: "missing, app",
+		IstioPort:       scrapetest.Port(t, ts),
+	}, zerolog.Nop(), check)
+	if err != nil {
+		t.Fatalf("unexpected error (%s)", err)
+	}
+	is.Collect(ctx, &tls.Config{}, nil)
+
+	scrapetest.ExpectTags(t, rec, "istio_requests_total",
+		"source:istio", "namespace:app", "pod:web-1", "node:worker1", "response_code:200")
+}
@@ -0,0 +1,282 @@
+// Copyright © 2019 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+// Package jobs is the job and cron job health collector
+package jobs
+
+import (
+	"context"
+	"crypto/tls"
+	"strings"
+	"sync"
+	"time"
+
+	cgm "github.com/circonus-labs/circonus-gometrics/v3"
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/circonus"
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/config"
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/config/defaults"
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/k8s"
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/release"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+type Jobs struct {
+	config       *config.Cluster
+	check        *circonus.Check
+	log          zerolog.Logger
+	clientset    kubernetes.Interface
+	running      bool
+	apiTimelimit time.Duration
+	sync.Mutex
+	ts *time.Time
+}
+
+// NOTES:
+// Jobs (batch/v1) and CronJobs (batch/v1beta1) are listed from the api-server. The cron job
+// status does not include the last successful time, it is derived from the completion time of
+// the most recent completed job controlled by the cron job (only retained jobs are considered,
+// see successfulJobsHistoryLimit).
+
+func New(cfg *config.Cluster, parentLog zerolog.Logger, check *circonus.Check) (*Jobs, error) {
+	if cfg == nil {
+		return nil, errors.New("invalid cluster config (nil)")
+	}
+	if check == nil {
+		return nil, errors.New("invalid check (nil)")
+	}
+
+	j := &Jobs{
+		config: cfg,
+		check:  check,
+		log:    parentLog.With().Str("collector", "jobs").Logger(),
+	}
+
+	if cfg.APITimelimit != "" {
+		v, err := time.ParseDuration(cfg.APITimelimit)
+		if err != nil {
+			j.log.Error().Err(err).Msg("parsing api timelimit, using default")
+		} else {
+			j.apiTimelimit = v
+		}
+	}
+
+	if j.apiTimelimit == time.Duration(0) {
+		v, err := time.ParseDuration(defaults.K8SAPITimelimit)
+		if err != nil {
+			j.log.Fatal().Err(err).Msg("parsing DEFAULT api timelimit")
+		}
+		j.apiTimelimit = v
+	}
+
+	restCfg, err := k8s.RESTConfig(cfg)
+	if err != nil {
+		return nil, errors.Wrap(err, "rest config")
+	}
+	restCfg.Timeout = j.apiTimelimit
+	clientset, err := kubernetes.NewForConfig(restCfg)
+	if err != nil {
+		return nil, errors.Wrap(err, "clientset")
+	}
+	j.clientset = clientset
+
+	return j, nil
+}
+
+func (j *Jobs) ID() string {
+	return "jobs"
+}
+
+// Collect job and cron job health
+func (j *Jobs) Collect(ctx context.Context, _ *tls.Config, ts *time.Time) {
+	j.Lock()
+	if j.running {
+		j.log.Warn().Msg("already running")
+		j.Unlock()
+		return
+	}
+	j.running = true
+	j.ts = ts
+	j.Unlock()
+
+	defer func() {
+		if r := recover(); r != nil {
+			j.log.Error().Interface("panic", r).Msg("recover")
+			j.Lock()
+			j.running = false
+			j.Unlock()
+		}
+	}()
+
+	collectStart := time.Now()
+
+	j.jobMetrics(ctx)
+
+	j.check.AddHistSample("collect_latency", cgm.Tags{
+		cgm.Tag{Category: "source", Value: release.NAME},
+		cgm.Tag{Category: "op", Value: "collect_jobs"},
+		cgm.Tag{Category: "units", Value: "milliseconds"},
+	}, float64(time.Since(collectStart).Milliseconds()))
+	j.log.Debug().Str("duration", time.Since(collectStart).String()).Msg("jobs collect end")
+	j.Lock()
+	j.running = false
+	j.Unlock()
+}
+
+// jobMetrics emits job completion/failure counts per namespace, the duration of active jobs,
+// and the age of the last schedule and last successful run for each cron job
+func (j *Jobs) jobMetrics(ctx context.Context) {
+	jobs, err := j.clientset.BatchV1().Jobs(metav1.NamespaceAll).List(metav1.ListOptions{})
+	if err != nil {
+		j.apiError("job-list")
+		j.log.Error().Err(err).Msg("listing jobs")
+		return
+	}
+	cronJobs, err := j.clientset.BatchV1beta1().CronJobs(metav1.NamespaceAll).List(metav1.ListOptions{})
+	if err != nil {
+		j.apiError("cronjob-list")
+		j.log.Error().Err(err).Msg("listing cron jobs")
+		// continue, job metrics are still valid
+	}
+
+	now := time.Now()
+	metrics := make(map[string]circonus.MetricSample)
+
+	type counts struct {
+		active, succeeded, failed uint64
+	}
+	namespaces := make(map[string]*counts)
+	activeDurations := make(map[string]float64) // key namespace/owner_kind/name
+	lastSuccess := make(map[string]time.Time)   // key namespace/cronjob
+
+	for i := range jobs.Items {
+		job := &jobs.Items[i]
+		nc, ok := namespaces[job.Namespace]
+		if !ok {
+			nc = &counts{}
+			namespaces[job.Namespace] = nc
+		}
+
+		kind, name := jobOwner(job)
+		complete, failed := jobFinished(job)
+		switch {
+		case complete:
+			nc.succeeded++
+			if kind == "CronJob" && job.Status.CompletionTime != nil {
+				key := job.Namespace + "/" + name
+				if job.Status.CompletionTime.Time.After(lastSuccess[key]) {
+					lastSuccess[key] = job.Status.CompletionTime.Time
+				}
+			}
+		case failed:
+			nc.failed++
+		case job.Status.Active > 0:
+			nc.active++
+			if job.Status.StartTime != nil {
+				// jobs created by a cron job are tagged with the cron job name (stable across runs)
+				key := job.Namespace + "/" + kind + "/" + name
+				if d := now.Sub(job.Status.StartTime.Time).Seconds(); d > activeDurations[key] {
+					activeDurations[key] = d
+				}
+			}
+		}
+	}
+
+	for ns, nc := range namespaces {
+		streamTags := []string{
+			"source:jobs",
+			"source_type:batch",
+			"namespace:" + ns,
+		}
+		_ = j.check.QueueMetricSample(metrics, "jobs_active", circonus.MetricTypeUint64, streamTags, []string{}, nc.active, j.ts)
+		_ = j.check.QueueMetricSample(metrics, "jobs_succeeded", circonus.MetricTypeUint64, streamTags, []string{}, nc.succeeded, j.ts)
+		_ = j.check.QueueMetricSample(metrics, "jobs_failed", circonus.MetricTypeUint64, streamTags, []string{}, nc.failed, j.ts)
+	}
+
+	for key, secs := range activeDurations {
+		parts := strings.SplitN(key, "/", 3)
+		streamTags := []string{
+			"source:jobs",
+			"source_type:batch",
+			"namespace:" + parts[0],
+			"owner_kind:" + parts[1],
+			"job:" + parts[2],
+			"units:seconds",
+			"__rollup:false", // prevent high cardinality metrics from rolling up
+		}
+		_ = j.check.QueueMetricSample(metrics, "job_active_duration", circonus.MetricTypeFloat64, streamTags, []string{}, secs, j.ts)
+	}
+
+	if cronJobs != nil {
+		for i := range cronJobs.Items {
+			cj := &cronJobs.Items[i]
+			streamTags := []string{
+				"source:jobs",
+				"source_type:batch",
+				"namespace:" + cj.Namespace,
+				"cronjob:" + cj.Name,
+				"__rollup:false", // prevent high cardinality metrics from rolling up
+			}
+			suspended := uint64(0)
+			if cj.Spec.Suspend != nil && *cj.Spec.Suspend {
+				suspended = 1
+			}
+			_ = j.check.QueueMetricSample(metrics, "cronjob_active", circonus.MetricTypeUint64, streamTags, []string{}, uint64(len(cj.Status.Active)), j.ts)
+			_ = j.check.QueueMetricSample(metrics, "cronjob_suspended", circonus.MetricTypeUint64, streamTags, []string{}, suspended, j.ts)
+			if cj.Status.LastScheduleTime != nil {
+				_ = j.check.QueueMetricSample(metrics, "cronjob_last_schedule_age", circonus.MetricTypeFloat64, append(streamTags, "units:seconds"), []string{}, now.Sub(cj.Status.LastScheduleTime.Time).Seconds(), j.ts)
+			}
+			if last, ok := lastSuccess[cj.Namespace+"/"+cj.Name]; ok {
+				_ = j.check.QueueMetricSample(metrics, "cronjob_last_successful_age", circonus.MetricTypeFloat64, append(streamTags, "units:seconds"), []string{}, now.Sub(last).Seconds(), j.ts)
+			}
+		}
+	}
+
+	if len(metrics) == 0 {
+		return
+	}
+	if err := j.check.SubmitQueue(ctx, metrics, j.log.With().Str("type", "jobs").Logger()); err != nil {
+		j.log.Warn().Err(err).Msg("submitting metrics")
+	}
+}
+
+func (j *Jobs) apiError(request string) {
+	j.check.IncrementCounter("collect_api_errors", cgm.Tags{
+		cgm.Tag{Category: "source", Value: release.NAME},
+		cgm.Tag{Category: "request", Value: request},
+		cgm.Tag{Category: "target", Value: "api-server"},
+	})
+}
+
+// jobFinished returns whether the job has completed or failed, from the job conditions
+func jobFinished(job *batchv1.Job) (complete bool, failed bool) {
+	for _, cond := range job.Status.Conditions {
+		if cond.Status != corev1.ConditionTrue {
+			continue
+		}
+		switch cond.Type {
+		case batchv1.JobComplete:
+			complete = true
+		case batchv1.JobFailed:
+			failed = true
+		}
+	}
+	return complete, failed
+}
+
+// jobOwner returns the kind and name of the cron job controlling the job,
+// or Job and the job name if it is not controlled by a cron job
+func jobOwner(job *batchv1.Job) (string, string) {
+	for _, ref := range job.OwnerReferences {
+		if ref.Kind == "CronJob" && ref.Controller != nil && *ref.Controller {
+			return ref.Kind, ref.Name
+		}
+	}
+	return "Job", job.Name
+}
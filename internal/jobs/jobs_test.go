@@ -0,0 +1,65 @@
+// Copyright © 2019 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+package jobs
+
+import (
+	"testing"
+
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestJobFinished(t *testing.T) {
+	tests := []struct {
+		name     string
+		conds    []batchv1.JobCondition
+		complete bool
+		failed   bool
+	}{
+		{"running", nil, false, false},
+		{"complete", []batchv1.JobCondition{{Type: batchv1.JobComplete, Status: corev1.ConditionTrue}}, true, false},
+		{"failed", []batchv1.JobCondition{{Type: batchv1.JobFailed, Status: corev1.ConditionTrue}}, false, true},
+		{"not true", []batchv1.JobCondition{{Type: batchv1.JobFailed, Status: corev1.ConditionFalse}}, false, false},
+	}
+
+	for _, tst := range tests {
+		tst := tst
+		t.Run(tst.name, func(t *testing.T) {
+			job := &batchv1.Job{Status: batchv1.JobStatus{Conditions: tst.conds}}
+			complete, failed := jobFinished(job)
+			if complete != tst.complete || failed != tst.failed {
+				t.Errorf("jobFinished() = %t, %t, want %t, %t", complete, failed, tst.complete, tst.failed)
+			}
+		})
+	}
+}
+
+func TestJobOwner(t *testing.T) {
+	controller := true
+
+	tests := []struct {
+		name string
+		refs []metav1.OwnerReference
+		kind string
+		own  string
+	}{
+		{"no owner", nil, "Job", "backup-123"},
+		{"cronjob", []metav1.OwnerReference{{Kind: "CronJob", Name: "backup", Controller: &controller}}, "CronJob", "backup"},
+		{"not controller", []metav1.OwnerReference{{Kind: "CronJob", Name: "backup"}}, "Job", "backup-123"},
+	}
+
+	for _, tst := range tests {
+		tst := tst
+		t.Run(tst.name, func(t *testing.T) {
+			job := &batchv1.Job{ObjectMeta: metav1.ObjectMeta{Name: "backup-123", OwnerReferences: tst.refs}}
+			kind, name := jobOwner(job)
+			if kind != tst.kind || name != tst.own {
+				t.Errorf("jobOwner() = %s, %s, want %s, %s", kind, name, tst.kind, tst.own)
+			}
+		})
+	}
+}
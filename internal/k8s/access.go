@@ -0,0 +1,86 @@
+// Copyright © 2019 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+package k8s
+
+import (
+	"strings"
+
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/config"
+	"github.com/pkg/errors"
+	authv1 "k8s.io/api/authorization/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// Access is an api permission, a resource (e.g. list pods, get nodes/proxy) or a
+// non-resource path (e.g. get /metrics)
+type Access struct {
+	Verb        string
+	Group       string
+	Resource    string
+	Subresource string
+	Path        string
+}
+
+// String returns the access in rbac terms (e.g. list pods, get nodes/proxy)
+func (a Access) String() string {
+	if a.Path != "" {
+		return a.Verb + " " + a.Path
+	}
+	resource := a.Resource
+	if a.Subresource != "" {
+		resource += "/" + a.Subresource
+	}
+	if a.Group != "" {
+		resource += "." + a.Group
+	}
+	return a.Verb + " " + resource
+}
+
+// DeniedAccess returns the accesses the cluster's credentials are not allowed, using
+// self subject access reviews
+func DeniedAccess(cfg *config.Cluster, access []Access) ([]Access, error) {
+	restCfg, err := RESTConfig(cfg)
+	if err != nil {
+		return nil, errors.Wrap(err, "rest config")
+	}
+	clientset, err := kubernetes.NewForConfig(restCfg)
+	if err != nil {
+		return nil, errors.Wrap(err, "clientset")
+	}
+
+	var denied []Access
+	for _, a := range access {
+		review := &authv1.SelfSubjectAccessReview{}
+		if a.Path != "" {
+			review.Spec.NonResourceAttributes = &authv1.NonResourceAttributes{Verb: a.Verb, Path: a.Path}
+		} else {
+			review.Spec.ResourceAttributes = &authv1.ResourceAttributes{
+				Verb:        a.Verb,
+				Group:       a.Group,
+				Resource:    a.Resource,
+				Subresource: a.Subresource,
+			}
+		}
+		result, err := clientset.AuthorizationV1().SelfSubjectAccessReviews().Create(review)
+		if err != nil {
+			return nil, errors.Wrapf(err, "reviewing access (%s)", a)
+		}
+		if !result.Status.Allowed {
+			denied = append(denied, a)
+		}
+	}
+
+	return denied, nil
+}
+
+// AccessList returns the accesses as a comma separated list
+func AccessList(access []Access) string {
+	list := make([]string, len(access))
+	for i, a := range access {
+		list[i] = a.String()
+	}
+	return strings.Join(list, ", ")
+}
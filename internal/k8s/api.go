@@ -63,6 +63,8 @@ func NewAPIRequest(token string, reqURL string) (*http.Request, error) {
 	if err != nil {
 		return nil, errors.Wrap(err, "creating k8s api request")
 	}
-	req.Header.Add("Authorization", "Bearer "+token)
+	if token != "" { // e.g. kubeconfig client certificate
+		req.Header.Add("Authorization", "Bearer "+token)
+	}
 	return req, nil
 }
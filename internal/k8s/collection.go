@@ -0,0 +1,97 @@
+// Copyright © 2019 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+package k8s
+
+import (
+	"context"
+	"reflect"
+
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/config"
+	"github.com/rs/zerolog"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/dynamic/dynamicinformer"
+	"k8s.io/client-go/tools/cache"
+)
+
+// CirconusCollectionResource is the agent's collection settings custom resource
+// (see deploy/optional/circonuscollection.yaml)
+var CirconusCollectionResource = schema.GroupVersionResource{
+	Group:    "circonus.com",
+	Version:  "v1alpha1",
+	Resource: "circonuscollections",
+}
+
+// WatchCirconusCollection calls onChange with the spec of a CirconusCollection resource
+// (namespace/name) when it is created or its spec changes, and with a nil spec when it
+// is deleted, it does not return until ctx is done
+func WatchCirconusCollection(ctx context.Context, cfg *config.Cluster, ref string, onChange func(map[string]interface{}), logger zerolog.Logger) {
+	namespace, name, err := config.ParseObjectRef(ref)
+	if err != nil {
+		logger.Error().Err(err).Msg("unable to watch collection resource")
+		return
+	}
+	restCfg, err := RESTConfig(cfg)
+	if err != nil {
+		logger.Error().Err(err).Msg("unable to watch collection resource")
+		return
+	}
+	client, err := dynamic.NewForConfig(restCfg)
+	if err != nil {
+		logger.Error().Err(err).Msg("unable to watch collection resource")
+		return
+	}
+
+	factory := dynamicinformer.NewFilteredDynamicSharedInformerFactory(client, 0, namespace, func(opts *metav1.ListOptions) {
+		opts.FieldSelector = "metadata.name=" + name
+	})
+	informer := factory.ForResource(CirconusCollectionResource).Informer()
+	stopper := make(chan struct{})
+	defer close(stopper)
+	defer runtime.HandleCrash()
+
+	var current map[string]interface{}
+	update := func(obj interface{}) {
+		u, ok := obj.(*unstructured.Unstructured)
+		if !ok {
+			return
+		}
+		spec, _, err := unstructured.NestedMap(u.Object, "spec")
+		if err != nil {
+			logger.Warn().Err(err).Msg("collection resource updated, invalid spec, ignoring")
+			return
+		}
+		if spec == nil {
+			spec = map[string]interface{}{}
+		}
+		if current != nil && reflect.DeepEqual(spec, current) {
+			return // e.g. status or metadata change
+		}
+		current = spec
+		onChange(spec)
+	}
+
+	informer.AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc:    update,
+		UpdateFunc: func(_, newObj interface{}) { update(newObj) },
+		DeleteFunc: func(_ interface{}) {
+			current = nil
+			onChange(nil)
+		},
+	})
+
+	go informer.Run(stopper)
+
+	if !cache.WaitForCacheSync(stopper, informer.HasSynced) {
+		logger.Warn().Msg("timed out waiting for cache to sync")
+		return
+	}
+
+	<-ctx.Done()
+}
@@ -0,0 +1,63 @@
+// Copyright © 2019 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+package k8s
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"io/ioutil"
+
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/config"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog"
+)
+
+// ConfigureCredentials resolves the cluster's api url and credentials (kubeconfig
+// context, bearer token, or bearer token file), returns the tls config for api requests
+func ConfigureCredentials(cfg *config.Cluster, logger zerolog.Logger) (*tls.Config, error) {
+	var tlsConfig *tls.Config
+	if cfg.Kubeconfig != "" {
+		tc, err := ResolveKubeconfig(cfg, logger)
+		if err != nil {
+			return nil, err
+		}
+		tlsConfig = tc
+		logger.Info().Str("kubeconfig", cfg.Kubeconfig).Str("context", cfg.KubeconfigContext).Str("api_url", cfg.URL).Msg("using kubeconfig")
+	} else if cfg.BearerToken == "" && cfg.BearerTokenFile == "" {
+		return nil, errors.New("invalid bearer credentials (empty)")
+	}
+
+	if cfg.BearerToken == "" && cfg.BearerTokenFile != "" {
+		// re-read as it is rotated (e.g. projected service account tokens)
+		src, err := NewFileTokenSource(cfg.BearerTokenFile, logger)
+		if err != nil {
+			return nil, err
+		}
+		cfg.TokenSource = src
+		cfg.BearerToken = src.Token()
+	}
+	if len(cfg.BearerToken) > 8 {
+		logger.Debug().Str("token", cfg.BearerToken[0:8]+"...").Msg("using bearer token")
+	}
+
+	if cfg.CAFile != "" {
+		cert, err := ioutil.ReadFile(cfg.CAFile)
+		if err != nil {
+			return nil, errors.Wrap(err, "configuring k8s api tls")
+		}
+		cp := x509.NewCertPool()
+		if !cp.AppendCertsFromPEM(cert) {
+			return nil, errors.New("unable to add k8s api CA Certificate to x509 cert pool")
+		}
+		tlsConfig = &tls.Config{
+			RootCAs: cp,
+			// InsecureSkipVerify: true,
+		}
+		logger.Debug().Str("cert", cfg.CAFile).Msg("adding CA cert to TLS config")
+	}
+
+	return tlsConfig, nil
+}
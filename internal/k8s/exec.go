@@ -0,0 +1,142 @@
+// Copyright © 2019 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+package k8s
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+)
+
+// NOTES:
+// A kubeconfig user with an exec credential plugin (e.g. aws-iam-authenticator,
+// gke-gcloud-auth-plugin) gets its api bearer token from the plugin, the same way as
+// kubectl. The plugin is run when the cluster is configured, and again when the token
+// is about to expire (execRefreshMargin before the expiration returned by the plugin).
+// Plugins returning a token without an expiration are run once. If a refresh fails the
+// current token is used (and the plugin run again on the next request). Plugins
+// returning client certificates rather than tokens are not supported.
+
+const (
+	execRefreshMargin = 1 * time.Minute
+	execTimeout       = 30 * time.Second
+)
+
+// execCredential is the output of an exec credential plugin
+type execCredential struct {
+	APIVersion string `json:"apiVersion"`
+	Kind       string `json:"kind"`
+	Status     *struct {
+		Token                 string     `json:"token"`
+		ExpirationTimestamp   *time.Time `json:"expirationTimestamp"`
+		ClientCertificateData string     `json:"clientCertificateData"`
+	} `json:"status"`
+}
+
+// execTokenSource provides the api bearer token from an exec credential plugin
+type execTokenSource struct {
+	exec   *clientcmdapi.ExecConfig
+	logger zerolog.Logger
+	token  string
+	expiry time.Time // zero, does not expire
+	sync.Mutex
+}
+
+// newExecTokenSource runs the exec credential plugin for the initial token
+func newExecTokenSource(execConfig *clientcmdapi.ExecConfig, logger zerolog.Logger) (*execTokenSource, error) {
+	s := &execTokenSource{
+		exec:   execConfig,
+		logger: logger.With().Str("exec_plugin", execConfig.Command).Logger(),
+	}
+	if err := s.refresh(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Token returns the current token, running the plugin again if it is about to expire
+func (s *execTokenSource) Token() string {
+	s.Lock()
+	defer s.Unlock()
+
+	if s.expiry.IsZero() || time.Now().Add(execRefreshMargin).Before(s.expiry) {
+		return s.token
+	}
+	if err := s.refresh(); err != nil {
+		s.logger.Error().Err(err).Time("expiry", s.expiry).Msg("refreshing token, using current token")
+	}
+	return s.token
+}
+
+// refresh runs the plugin and records the token and its expiration
+func (s *execTokenSource) refresh() error {
+	ctx, cancel := context.WithTimeout(context.Background(), execTimeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, s.exec.Command, s.exec.Args...) //nolint:gosec
+	cmd.Env = os.Environ()
+	for _, env := range s.exec.Env {
+		cmd.Env = append(cmd.Env, env.Name+"="+env.Value)
+	}
+	cmd.Env = append(cmd.Env, fmt.Sprintf(`KUBERNETES_EXEC_INFO={"apiVersion":%q,"kind":"ExecCredential","spec":{"interactive":false}}`, s.exec.APIVersion))
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+
+	out, err := cmd.Output()
+	if err != nil {
+		return errors.Wrapf(err, "running exec credential plugin %s (%s)", s.exec.Command, strings.TrimSpace(stderr.String()))
+	}
+
+	token, expiry, err := parseExecCredential(out, s.exec.APIVersion)
+	if err != nil {
+		return errors.Wrapf(err, "exec credential plugin %s", s.exec.Command)
+	}
+
+	s.token = token
+	s.expiry = expiry
+	s.logger.Debug().Time("expiry", expiry).Msg("exec credential plugin token")
+	return nil
+}
+
+// parseExecCredential returns the token and expiration (zero if none) of exec
+// credential plugin output
+func parseExecCredential(data []byte, apiVersion string) (string, time.Time, error) {
+	var cred execCredential
+	if err := json.Unmarshal(data, &cred); err != nil {
+		return "", time.Time{}, errors.Wrap(err, "parsing output")
+	}
+	if cred.Kind != "ExecCredential" {
+		return "", time.Time{}, errors.Errorf("invalid output kind %q", cred.Kind)
+	}
+	if apiVersion != "" && cred.APIVersion != apiVersion {
+		return "", time.Time{}, errors.Errorf("invalid output apiVersion %q, expected %q", cred.APIVersion, apiVersion)
+	}
+	if cred.Status == nil {
+		return "", time.Time{}, errors.New("invalid output, no status")
+	}
+	if cred.Status.Token == "" {
+		if cred.Status.ClientCertificateData != "" {
+			return "", time.Time{}, errors.New("client certificate credentials are not supported")
+		}
+		return "", time.Time{}, errors.New("invalid output, no token")
+	}
+
+	var expiry time.Time
+	if cred.Status.ExpirationTimestamp != nil {
+		expiry = *cred.Status.ExpirationTimestamp
+	}
+	return cred.Status.Token, expiry, nil
+}
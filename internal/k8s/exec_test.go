@@ -0,0 +1,48 @@
+// Copyright © 2019 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+package k8s
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseExecCredential(t *testing.T) {
+	const apiVersion = "client.authentication.k8s.io/v1beta1"
+	expiry := time.Date(2020, 2, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name       string
+		data       string
+		wantToken  string
+		wantExpiry time.Time
+		shouldErr  bool
+	}{
+		{"token", `{"apiVersion":"client.authentication.k8s.io/v1beta1","kind":"ExecCredential","status":{"token":"abc"}}`, "abc", time.Time{}, false},
+		{"expiring token", `{"apiVersion":"client.authentication.k8s.io/v1beta1","kind":"ExecCredential","status":{"token":"abc","expirationTimestamp":"2020-02-01T12:00:00Z"}}`, "abc", expiry, false},
+		{"invalid json", `{`, "", time.Time{}, true},
+		{"invalid kind", `{"apiVersion":"client.authentication.k8s.io/v1beta1","kind":"Token","status":{"token":"abc"}}`, "", time.Time{}, true},
+		{"invalid api version", `{"apiVersion":"client.authentication.k8s.io/v1alpha1","kind":"ExecCredential","status":{"token":"abc"}}`, "", time.Time{}, true},
+		{"no status", `{"apiVersion":"client.authentication.k8s.io/v1beta1","kind":"ExecCredential"}`, "", time.Time{}, true},
+		{"client certificate", `{"apiVersion":"client.authentication.k8s.io/v1beta1","kind":"ExecCredential","status":{"clientCertificateData":"..."}}`, "", time.Time{}, true},
+	}
+
+	for _, tt := range tests {
+		token, exp, err := parseExecCredential([]byte(tt.data), apiVersion)
+		if tt.shouldErr {
+			if err == nil {
+				t.Fatalf("%s: expected error", tt.name)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("%s: unexpected error (%s)", tt.name, err)
+		}
+		if token != tt.wantToken || !exp.Equal(tt.wantExpiry) {
+			t.Fatalf("%s: expected %q %s, got %q %s", tt.name, tt.wantToken, tt.wantExpiry, token, exp)
+		}
+	}
+}
@@ -0,0 +1,79 @@
+// Copyright © 2019 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+package k8s
+
+import (
+	"crypto/tls"
+	"strings"
+
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/config"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+)
+
+// NOTES:
+// With --k8s-kubeconfig the agent runs outside of the cluster (e.g. in dev against
+// kind or minikube), the api url, ca, and credentials of a kubeconfig context (the
+// current context unless --k8s-kubeconfig-context is set) are used rather than the
+// api url, ca file, and bearer token settings. Bearer token (token or tokenFile) and
+// client certificate credentials are supported, as are exec credential plugins (see
+// exec.go), basic auth and auth provider plugins are not.
+
+// kubeconfigREST returns the client-go rest config of the cluster's kubeconfig context
+func kubeconfigREST(cfg *config.Cluster) (*rest.Config, error) {
+	rules := &clientcmd.ClientConfigLoadingRules{ExplicitPath: cfg.Kubeconfig}
+	overrides := &clientcmd.ConfigOverrides{CurrentContext: cfg.KubeconfigContext}
+	c, err := clientcmd.NewNonInteractiveDeferredLoadingClientConfig(rules, overrides).ClientConfig()
+	if err != nil {
+		return nil, errors.Wrapf(err, "loading kubeconfig %s", cfg.Kubeconfig)
+	}
+	return c, nil
+}
+
+// ResolveKubeconfig sets the cluster's api url and bearer token (or exec credential
+// plugin token source) from its kubeconfig context, returns the tls config (ca, client
+// certificate) for api requests
+func ResolveKubeconfig(cfg *config.Cluster, logger zerolog.Logger) (*tls.Config, error) {
+	c, err := kubeconfigREST(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	if c.Username != "" || c.Password != "" {
+		return nil, errors.Errorf("kubeconfig %s, basic auth is not supported", cfg.Kubeconfig)
+	}
+	if c.AuthProvider != nil {
+		return nil, errors.Errorf("kubeconfig %s, auth provider %s is not supported", cfg.Kubeconfig, c.AuthProvider.Name)
+	}
+
+	tlsConfig, err := rest.TLSConfigFor(c)
+	if err != nil {
+		return nil, errors.Wrapf(err, "kubeconfig %s tls config", cfg.Kubeconfig)
+	}
+
+	host := strings.TrimSuffix(c.Host, "/")
+	if !strings.Contains(host, "://") {
+		host = "https://" + host
+	}
+	cfg.URL = host
+	cfg.CAFile = "" // in the tls config
+	cfg.BearerToken = c.BearerToken
+	cfg.BearerTokenFile = c.BearerTokenFile
+
+	if c.ExecProvider != nil {
+		src, err := newExecTokenSource(c.ExecProvider, logger)
+		if err != nil {
+			return nil, errors.Wrapf(err, "kubeconfig %s", cfg.Kubeconfig)
+		}
+		cfg.BearerToken = ""
+		cfg.BearerTokenFile = ""
+		cfg.TokenSource = src
+	}
+
+	return tlsConfig, nil
+}
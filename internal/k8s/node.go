@@ -37,11 +37,14 @@ type NodeSizes struct {
 type NodeCondition struct {
 	Type    string `json:"type"`
 	Status  string `json:"status"`
+	Reason  string `json:"reason"`
 	Message string `json:"message"`
 }
 
 type NodeInfo struct {
-	KernelVersion  string `json:"kernelVersion"`
-	OSImage        string `json:"osImage"`
-	KubeletVersion string `json:"kubeletVersion"`
+	KernelVersion           string `json:"kernelVersion"`
+	OSImage                 string `json:"osImage"`
+	OperatingSystem         string `json:"operatingSystem"`
+	KubeletVersion          string `json:"kubeletVersion"`
+	ContainerRuntimeVersion string `json:"containerRuntimeVersion"`
 }
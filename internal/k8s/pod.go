@@ -11,15 +11,19 @@ type PodList struct {
 type Pod struct {
 	Metadata PodMetadata `json:"metadata"`
 	Spec     PodSpec     `json:"spec"`
+	Status   PodStatus   `json:"status"`
 }
 type PodMetadata struct {
-	Name      string `json:"name"`
-	Namespace string `json:"namespace"`
-	SelfLink  string `json:"selfLink"`
+	Name        string            `json:"name"`
+	Namespace   string            `json:"namespace"`
+	SelfLink    string            `json:"selfLink"`
+	Labels      map[string]string `json:"labels"`
+	Annotations map[string]string `json:"annotations"`
 }
 type PodSpec struct {
-	Status PodStatus `json:"status"`
+	NodeName string `json:"nodeName"`
 }
 type PodStatus struct {
+	Phase string `json:"phase"`
 	PodIP string `json:"podIP"`
 }
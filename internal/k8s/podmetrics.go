@@ -0,0 +1,50 @@
+// Copyright © 2019 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+package k8s
+
+import (
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// PodMetricsResource is the metrics.k8s.io pod metrics api served by metrics-server
+var PodMetricsResource = schema.GroupVersionResource{Group: "metrics.k8s.io", Version: "v1beta1", Resource: "pods"}
+
+// ContainerUsage returns the usage of each container, by container name, in a metrics.k8s.io PodMetrics
+func ContainerUsage(pm *unstructured.Unstructured) (map[string]corev1.ResourceList, error) {
+	containers, _, err := unstructured.NestedSlice(pm.Object, "containers")
+	if err != nil {
+		return nil, errors.Wrap(err, "containers")
+	}
+	usage := make(map[string]corev1.ResourceList, len(containers))
+	for _, c := range containers {
+		cm, ok := c.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		name, _, err := unstructured.NestedString(cm, "name")
+		if err != nil {
+			return nil, errors.Wrap(err, "container name")
+		}
+		u, _, err := unstructured.NestedStringMap(cm, "usage")
+		if err != nil {
+			return nil, errors.Wrap(err, "container usage")
+		}
+		list := corev1.ResourceList{}
+		for rn, value := range u {
+			q, err := resource.ParseQuantity(value)
+			if err != nil {
+				return nil, errors.Wrapf(err, "parsing %s usage (%s)", rn, value)
+			}
+			list[corev1.ResourceName(rn)] = q
+		}
+		usage[name] = list
+	}
+	return usage, nil
+}
@@ -0,0 +1,50 @@
+// Copyright © 2019 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+package k8s
+
+import (
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/config"
+	"github.com/pkg/errors"
+	"k8s.io/client-go/rest"
+)
+
+// RESTConfig returns the client-go rest config, the kubeconfig context if a kubeconfig
+// is configured, the in-cluster config when running in a cluster otherwise one created
+// from the cluster configuration
+func RESTConfig(cfg *config.Cluster) (*rest.Config, error) {
+	if cfg == nil {
+		return nil, errors.New("invalid cluster config (nil)")
+	}
+
+	if cfg.Kubeconfig != "" {
+		return kubeconfigREST(cfg)
+	}
+
+	c, err := rest.InClusterConfig()
+	if err == nil {
+		return c, nil // use in-cluster config
+	}
+	if err != rest.ErrNotInCluster {
+		return nil, errors.Wrap(err, "in-cluster config")
+	}
+
+	// not in cluster, use supplied customer config for cluster
+	c = &rest.Config{}
+	if cfg.BearerToken != "" {
+		c.BearerToken = cfg.BearerToken
+	}
+	if cfg.BearerTokenFile != "" && cfg.TokenSource != nil {
+		c.BearerTokenFile = cfg.BearerTokenFile // re-read by client-go as it is rotated
+	}
+	if cfg.URL != "" {
+		c.Host = cfg.URL
+	}
+	if cfg.CAFile != "" {
+		c.TLSClientConfig = rest.TLSClientConfig{CAFile: cfg.CAFile}
+	}
+
+	return c, nil
+}
@@ -0,0 +1,127 @@
+// Copyright © 2019 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+package k8s
+
+import (
+	"context"
+	"strings"
+
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/config"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/cache"
+)
+
+// SecretRefValue returns the value of a secret key reference (namespace/name/key)
+func SecretRefValue(cfg *config.Cluster, ref string) (string, error) {
+	namespace, name, key, err := config.ParseSecretRef(ref)
+	if err != nil {
+		return "", err
+	}
+	clientset, err := secretClientset(cfg)
+	if err != nil {
+		return "", err
+	}
+	return SecretValue(clientset, namespace, name, key)
+}
+
+// WatchSecretRef calls onChange with the value of a secret key reference (namespace/name/key)
+// when it changes, it does not return until ctx is done
+func WatchSecretRef(ctx context.Context, cfg *config.Cluster, ref, current string, onChange func(string), logger zerolog.Logger) {
+	namespace, name, key, err := config.ParseSecretRef(ref)
+	if err != nil {
+		logger.Error().Err(err).Msg("unable to watch secret")
+		return
+	}
+	clientset, err := secretClientset(cfg)
+	if err != nil {
+		logger.Error().Err(err).Msg("unable to watch secret")
+		return
+	}
+	WatchSecret(ctx, clientset, namespace, name, key, current, onChange, logger)
+}
+
+// secretClientset returns a clientset for reading secrets
+func secretClientset(cfg *config.Cluster) (kubernetes.Interface, error) {
+	restCfg, err := RESTConfig(cfg)
+	if err != nil {
+		return nil, errors.Wrap(err, "rest config")
+	}
+	clientset, err := kubernetes.NewForConfig(restCfg)
+	if err != nil {
+		return nil, errors.Wrap(err, "clientset")
+	}
+	return clientset, nil
+}
+
+// SecretValue returns the value of a key in a secret
+func SecretValue(clientset kubernetes.Interface, namespace, name, key string) (string, error) {
+	secret, err := clientset.CoreV1().Secrets(namespace).Get(name, metav1.GetOptions{})
+	if err != nil {
+		return "", errors.Wrapf(err, "fetching secret %s/%s", namespace, name)
+	}
+	return secretKeyValue(secret, key)
+}
+
+// WatchSecret calls onChange with the value of a key in a secret when it changes
+// (e.g. the secret was rotated), it does not return until ctx is done
+func WatchSecret(ctx context.Context, clientset kubernetes.Interface, namespace, name, key, current string, onChange func(string), logger zerolog.Logger) {
+	factory := informers.NewSharedInformerFactoryWithOptions(clientset, 0,
+		informers.WithNamespace(namespace),
+		informers.WithTweakListOptions(func(opts *metav1.ListOptions) {
+			opts.FieldSelector = "metadata.name=" + name
+		}))
+	informer := factory.Core().V1().Secrets().Informer()
+	stopper := make(chan struct{})
+	defer close(stopper)
+	defer runtime.HandleCrash()
+
+	update := func(obj interface{}) {
+		secret, ok := obj.(*corev1.Secret)
+		if !ok {
+			return
+		}
+		v, err := secretKeyValue(secret, key)
+		if err != nil {
+			logger.Warn().Err(err).Msg("secret updated, ignoring")
+			return
+		}
+		if v == current {
+			return
+		}
+		current = v
+		onChange(v)
+	}
+
+	informer.AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc:    update,
+		UpdateFunc: func(_, newObj interface{}) { update(newObj) },
+	})
+
+	go informer.Run(stopper)
+
+	if !cache.WaitForCacheSync(stopper, informer.HasSynced) {
+		logger.Warn().Msg("timed out waiting for cache to sync")
+		return
+	}
+
+	<-ctx.Done()
+}
+
+// secretKeyValue returns the value of a key in a secret, surrounding whitespace
+// (e.g. a trailing newline) is removed
+func secretKeyValue(secret *corev1.Secret, key string) (string, error) {
+	v := strings.TrimSpace(string(secret.Data[key]))
+	if v == "" {
+		return "", errors.Errorf("secret %s/%s, key %s not found or empty", secret.Namespace, secret.Name, key)
+	}
+	return v, nil
+}
@@ -0,0 +1,43 @@
+// Copyright © 2019 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+package k8s
+
+import (
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestSecretKeyValue(t *testing.T) {
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "cka-secrets-v1"},
+		Data: map[string][]byte{
+			"circonus-api-key": []byte("0123-abcd\n"),
+			"empty":            []byte(""),
+		},
+	}
+
+	tests := []struct {
+		key     string
+		want    string
+		wantErr bool
+	}{
+		{"circonus-api-key", "0123-abcd", false},
+		{"empty", "", true},
+		{"missing", "", true},
+	}
+
+	for _, tt := range tests {
+		got, err := secretKeyValue(secret, tt.key)
+		if (err != nil) != tt.wantErr {
+			t.Fatalf("%s: unexpected error (%v)", tt.key, err)
+		}
+		if got != tt.want {
+			t.Fatalf("%s: expected %q, got %q", tt.key, tt.want, got)
+		}
+	}
+}
@@ -0,0 +1,43 @@
+// Copyright © 2019 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+package k8s
+
+import (
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/config"
+	"github.com/pkg/errors"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+// NOTES:
+// --k8s-node-selector and --k8s-pod-selector are label selectors (e.g. team=payments,
+// or team in (payments,billing)) scoping collection to matching objects in a shared
+// cluster. Collectors listing nodes or pods (and pod metrics) pass the selector to the
+// api server, the node collector matches the pods running on each node against the pod
+// selector (stats summary) and drops /metrics/cadvisor samples of pods on the node not
+// matching it. Headroom is computed from all pods on the selected nodes, since pods of
+// other teams use the capacity as well. Collectors scraping system components (e.g.
+// kube-state-metrics, dns) and event watchers (events do not carry the object's labels)
+// are not scoped, use the namespace lists for those.
+
+// ValidateSelectors verifies the node and pod label selectors parse
+func ValidateSelectors(cfg *config.Cluster) error {
+	if _, err := labels.Parse(cfg.NodeSelector); err != nil {
+		return errors.Wrap(err, "invalid node selector")
+	}
+	if _, err := labels.Parse(cfg.PodSelector); err != nil {
+		return errors.Wrap(err, "invalid pod selector")
+	}
+	return nil
+}
+
+// PodSelector returns the pod label selector, everything if no selector is configured
+func PodSelector(cfg *config.Cluster) (labels.Selector, error) {
+	sel, err := labels.Parse(cfg.PodSelector)
+	if err != nil {
+		return nil, errors.Wrap(err, "invalid pod selector")
+	}
+	return sel, nil
+}
@@ -0,0 +1,57 @@
+// Copyright © 2019 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+package k8s
+
+import (
+	"testing"
+
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/config"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+func TestValidateSelectors(t *testing.T) {
+	tests := []struct {
+		name      string
+		cfg       config.Cluster
+		shouldErr bool
+	}{
+		{"none", config.Cluster{}, false},
+		{"valid", config.Cluster{NodeSelector: "pool=general", PodSelector: "team in (payments,billing),tier!=test"}, false},
+		{"invalid node selector", config.Cluster{NodeSelector: "=general"}, true},
+		{"invalid pod selector", config.Cluster{PodSelector: "team in (payments"}, true},
+	}
+
+	for _, tt := range tests {
+		err := ValidateSelectors(&tt.cfg)
+		if tt.shouldErr && err == nil {
+			t.Fatalf("%s: expected error", tt.name)
+		}
+		if !tt.shouldErr && err != nil {
+			t.Fatalf("%s: unexpected error (%s)", tt.name, err)
+		}
+	}
+}
+
+func TestPodSelector(t *testing.T) {
+	sel, err := PodSelector(&config.Cluster{})
+	if err != nil {
+		t.Fatalf("unexpected error (%s)", err)
+	}
+	if !sel.Matches(labels.Set{"team": "search"}) {
+		t.Fatal("expected blank selector to match all pods")
+	}
+
+	sel, err = PodSelector(&config.Cluster{PodSelector: "team in (payments,billing)"})
+	if err != nil {
+		t.Fatalf("unexpected error (%s)", err)
+	}
+	if !sel.Matches(labels.Set{"team": "billing", "app": "api"}) {
+		t.Fatal("expected matching pod")
+	}
+	if sel.Matches(labels.Set{"team": "search"}) {
+		t.Fatal("expected pod of another team to not match")
+	}
+}
@@ -13,9 +13,11 @@ type Service struct {
 	Spec     ServiceSpec     `json:"spec"`
 }
 type ServiceMetadata struct {
-	Name      string `json:"name"`
-	Namespace string `json:"namespace"`
-	SelfLink  string `json:"selfLink"`
+	Name        string            `json:"name"`
+	Namespace   string            `json:"namespace"`
+	SelfLink    string            `json:"selfLink"`
+	Labels      map[string]string `json:"labels"`
+	Annotations map[string]string `json:"annotations"`
 }
 type ServiceSpec struct {
 	Ports    []ServicePort     `json:"ports"`
@@ -0,0 +1,79 @@
+// Copyright © 2019 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+package k8s
+
+import (
+	"io/ioutil"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog"
+)
+
+// NOTES:
+// Projected (bound) service account tokens expire and are rotated by the kubelet
+// (hourly by default, at 80% of their ttl), so the bearer token file is not read only
+// once. Each api request checks whether the token was read more than tokenFileRefresh
+// ago and re-reads the file if so (the same period client-go uses for the in-cluster
+// token). If the file can not be read, or is empty, the current token is used and the
+// file is read again on the next request.
+
+const tokenFileRefresh = 1 * time.Minute
+
+// FileTokenSource provides the api bearer token from a token file, re-read periodically
+type FileTokenSource struct {
+	file   string
+	logger zerolog.Logger
+	token  string
+	readAt time.Time
+	sync.Mutex
+}
+
+// NewFileTokenSource reads the initial token from the token file
+func NewFileTokenSource(file string, logger zerolog.Logger) (*FileTokenSource, error) {
+	s := &FileTokenSource{
+		file:   file,
+		logger: logger.With().Str("token_file", file).Logger(),
+	}
+	if err := s.read(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Token returns the current token, re-reading the token file if it is stale
+func (s *FileTokenSource) Token() string {
+	s.Lock()
+	defer s.Unlock()
+
+	if time.Since(s.readAt) < tokenFileRefresh {
+		return s.token
+	}
+	if err := s.read(); err != nil {
+		s.logger.Warn().Err(err).Msg("re-reading bearer token file, using current token")
+	}
+	return s.token
+}
+
+// read reads the token file
+func (s *FileTokenSource) read() error {
+	data, err := ioutil.ReadFile(s.file)
+	if err != nil {
+		return errors.Wrap(err, "bearer token file")
+	}
+	token := strings.TrimSpace(string(data))
+	if token == "" {
+		return errors.Errorf("bearer token file %s is empty", s.file)
+	}
+	if s.token != "" && token != s.token {
+		s.logger.Debug().Msg("bearer token rotated")
+	}
+	s.token = token
+	s.readAt = time.Now()
+	return nil
+}
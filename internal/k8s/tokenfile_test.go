@@ -0,0 +1,60 @@
+// Copyright © 2019 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+package k8s
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+func TestFileTokenSource(t *testing.T) {
+	dir, err := ioutil.TempDir("", "cka-token")
+	if err != nil {
+		t.Fatalf("unexpected error (%s)", err)
+	}
+	defer os.RemoveAll(dir)
+
+	file := filepath.Join(dir, "token")
+	if _, err := NewFileTokenSource(file, zerolog.Nop()); err == nil {
+		t.Fatal("expected error, missing token file")
+	}
+
+	if err := ioutil.WriteFile(file, []byte("first\n"), 0600); err != nil {
+		t.Fatalf("unexpected error (%s)", err)
+	}
+	src, err := NewFileTokenSource(file, zerolog.Nop())
+	if err != nil {
+		t.Fatalf("unexpected error (%s)", err)
+	}
+	if got := src.Token(); got != "first" {
+		t.Fatalf("expected first, got %q", got)
+	}
+
+	if err := ioutil.WriteFile(file, []byte("second"), 0600); err != nil {
+		t.Fatalf("unexpected error (%s)", err)
+	}
+	if got := src.Token(); got != "first" {
+		t.Fatalf("expected current token before refresh, got %q", got)
+	}
+
+	src.readAt = time.Now().Add(-tokenFileRefresh)
+	if got := src.Token(); got != "second" {
+		t.Fatalf("expected rotated token, got %q", got)
+	}
+
+	if err := os.Remove(file); err != nil {
+		t.Fatalf("unexpected error (%s)", err)
+	}
+	src.readAt = time.Now().Add(-tokenFileRefresh)
+	if got := src.Token(); got != "second" {
+		t.Fatalf("expected current token when file is missing, got %q", got)
+	}
+}
@@ -0,0 +1,130 @@
+// Copyright © 2019 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+// Package karpenter is the karpenter controller metrics collector
+package karpenter
+
+import (
+	"context"
+	"crypto/tls"
+	"regexp"
+	"sync"
+	"time"
+
+	cgm "github.com/circonus-labs/circonus-gometrics/v3"
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/circonus"
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/config"
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/config/defaults"
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/release"
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/scrape"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog"
+)
+
+// only forward provisioner/nodepool capacity (limits and usage), node churn (created,
+// launched, terminated), and disruption metric families, metric names changed with the
+// v1beta1 apis (provisioner->nodepool, nodes->nodeclaims) so both are included
+var familyFilter = regexp.MustCompile(`^karpenter_(provisioner_(limit|usage|usage_pct)|nodepool_(limit|usage)|nodes_(created|terminated)_total|nodeclaims_(created|launched|terminated|disrupted)_total|disruption_(actions_performed_total|eligible_nodes|nodes_disrupted_total)|interruption_received_messages_total)$`)
+
+type Karpenter struct {
+	config       *config.Cluster
+	check        *circonus.Check
+	log          zerolog.Logger
+	running      bool
+	apiTimelimit time.Duration
+	sync.Mutex
+	ts *time.Time
+}
+
+// NOTES:
+// the karpenter controller serves metrics on 8000 /metrics (8080 prior to v0.32), only the
+// elected leader provisions and disrupts nodes so standby replicas report idle values.
+
+func New(cfg *config.Cluster, parentLog zerolog.Logger, check *circonus.Check) (*Karpenter, error) {
+	if cfg == nil {
+		return nil, errors.New("invalid cluster config (nil)")
+	}
+	if check == nil {
+		return nil, errors.New("invalid check (nil)")
+	}
+
+	kc := &Karpenter{
+		config: cfg,
+		check:  check,
+		log:    parentLog.With().Str("collector", "karpenter").Logger(),
+	}
+
+	if cfg.APITimelimit != "" {
+		v, err := time.ParseDuration(cfg.APITimelimit)
+		if err != nil {
+			kc.log.Error().Err(err).Msg("parsing api timelimit, using default")
+		} else {
+			kc.apiTimelimit = v
+		}
+	}
+
+	if kc.apiTimelimit == time.Duration(0) {
+		v, err := time.ParseDuration(defaults.K8SAPITimelimit)
+		if err != nil {
+			kc.log.Fatal().Err(err).Msg("parsing DEFAULT api timelimit")
+		}
+		kc.apiTimelimit = v
+	}
+
+	return kc, nil
+}
+
+func (kc *Karpenter) ID() string {
+	return "karpenter"
+}
+
+// Collect metrics from karpenter pods
+func (kc *Karpenter) Collect(ctx context.Context, tlsConfig *tls.Config, ts *time.Time) {
+	kc.Lock()
+	if kc.running {
+		kc.log.Warn().Msg("already running")
+		kc.Unlock()
+		return
+	}
+	kc.running = true
+	kc.ts = ts
+	kc.Unlock()
+
+	defer func() {
+		if r := recover(); r != nil {
+			kc.log.Error().Interface("panic", r).Msg("recover")
+			kc.Lock()
+			kc.running = false
+			kc.Unlock()
+		}
+	}()
+
+	collectStart := time.Now()
+
+	kc.podMetrics(ctx, tlsConfig)
+
+	kc.check.AddHistSample("collect_latency", cgm.Tags{
+		cgm.Tag{Category: "source", Value: release.NAME},
+		cgm.Tag{Category: "op", Value: "collect_karpenter"},
+		cgm.Tag{Category: "units", Value: "milliseconds"},
+	}, float64(time.Since(collectStart).Milliseconds()))
+	kc.log.Debug().Str("duration", time.Since(collectStart).String()).Msg("karpenter collect end")
+	kc.Lock()
+	kc.running = false
+	kc.Unlock()
+}
+
+// podMetrics collects metrics from each karpenter pod via the api-server proxy
+func (kc *Karpenter) podMetrics(ctx context.Context, tlsConfig *tls.Config) {
+	scrape.PodMetrics(ctx, kc.check, kc.log, kc.config, tlsConfig, kc.apiTimelimit, scrape.Component{
+		Name:         "karpenter",
+		Namespaces:   []string{kc.config.KarpenterNamespace},
+		Selector:     kc.config.KarpenterSelector,
+		Port:         kc.config.KarpenterPort,
+		FamilyFilter: familyFilter,
+		PodTag:       true,
+		Workers:      int(kc.config.NodePoolSize),
+	}, kc.ts)
+}
@@ -0,0 +1,34 @@
+// Copyright © 2019 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+package karpenter
+
+import "testing"
+
+func TestFamilyFilter(t *testing.T) {
+	// provisioner_ (v1alpha5) and nodepool_ (v1beta1+) names are both collected
+	tests := []struct {
+		family string
+		want   bool
+	}{
+		{"karpenter_provisioner_limit", true},
+		{"karpenter_provisioner_usage", true},
+		{"karpenter_provisioner_usage_pct", true},
+		{"karpenter_nodepool_limit", true},
+		{"karpenter_nodepool_usage", true},
+		{"karpenter_nodes_created_total", true},
+		{"karpenter_nodeclaims_disrupted_total", true},
+		{"karpenter_disruption_eligible_nodes", true},
+		{"karpenter_interruption_received_messages_total", true},
+		{"karpenter_nodepool_usage_pct", false},
+		{"karpenter_cloudprovider_duration_seconds", false},
+	}
+
+	for _, tt := range tests {
+		if got := familyFilter.MatchString(tt.family); got != tt.want {
+			t.Errorf("%s: expected %v, got %v", tt.family, tt.want, got)
+		}
+	}
+}
@@ -0,0 +1,129 @@
+// Copyright © 2019 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+// Package kcm is the kube-controller-manager metrics collector
+package kcm
+
+import (
+	"context"
+	"crypto/tls"
+	"regexp"
+	"sync"
+	"time"
+
+	cgm "github.com/circonus-labs/circonus-gometrics/v3"
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/circonus"
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/config"
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/config/defaults"
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/release"
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/scrape"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog"
+)
+
+// only forward controller workqueue metric families (e.g. depth, queue and
+// work durations, retries), not the go runtime/process metrics
+var familyFilter = regexp.MustCompile(`^workqueue_`)
+
+type KCM struct {
+	config       *config.Cluster
+	check        *circonus.Check
+	log          zerolog.Logger
+	running      bool
+	apiTimelimit time.Duration
+	sync.Mutex
+	ts *time.Time
+}
+
+// NOTES:
+// kube-controller-manager normally runs as a static pod (kubeadm labels it component=kube-controller-manager)
+// in kube-system with hostNetwork, metrics are on 10252 (http) or 10257 (https, needs authn/z).
+// Metrics are fetched through the api-server pod proxy.
+
+func New(cfg *config.Cluster, parentLog zerolog.Logger, check *circonus.Check) (*KCM, error) {
+	if cfg == nil {
+		return nil, errors.New("invalid cluster config (nil)")
+	}
+	if check == nil {
+		return nil, errors.New("invalid check (nil)")
+	}
+
+	k := &KCM{
+		config: cfg,
+		check:  check,
+		log:    parentLog.With().Str("collector", "kube-controller-manager").Logger(),
+	}
+
+	if cfg.APITimelimit != "" {
+		v, err := time.ParseDuration(cfg.APITimelimit)
+		if err != nil {
+			k.log.Error().Err(err).Msg("parsing api timelimit, using default")
+		} else {
+			k.apiTimelimit = v
+		}
+	}
+
+	if k.apiTimelimit == time.Duration(0) {
+		v, err := time.ParseDuration(defaults.K8SAPITimelimit)
+		if err != nil {
+			k.log.Fatal().Err(err).Msg("parsing DEFAULT api timelimit")
+		}
+		k.apiTimelimit = v
+	}
+
+	return k, nil
+}
+
+func (k *KCM) ID() string {
+	return "kube-controller-manager"
+}
+
+// Collect metrics from kube-controller-manager(s)
+func (k *KCM) Collect(ctx context.Context, tlsConfig *tls.Config, ts *time.Time) {
+	k.Lock()
+	if k.running {
+		k.log.Warn().Msg("already running")
+		k.Unlock()
+		return
+	}
+	k.running = true
+	k.ts = ts
+	k.Unlock()
+
+	defer func() {
+		if r := recover(); r != nil {
+			k.log.Error().Interface("panic", r).Msg("recover")
+			k.Lock()
+			k.running = false
+			k.Unlock()
+		}
+	}()
+
+	collectStart := time.Now()
+
+	k.podMetrics(ctx, tlsConfig)
+
+	k.check.AddHistSample("collect_latency", cgm.Tags{
+		cgm.Tag{Category: "source", Value: release.NAME},
+		cgm.Tag{Category: "op", Value: "collect_kube-controller-manager"},
+		cgm.Tag{Category: "units", Value: "milliseconds"},
+	}, float64(time.Since(collectStart).Milliseconds()))
+	k.log.Debug().Str("duration", time.Since(collectStart).String()).Msg("kube-controller-manager collect end")
+	k.Lock()
+	k.running = false
+	k.Unlock()
+}
+
+// podMetrics collects metrics from each controller-manager pod via the api-server proxy
+func (k *KCM) podMetrics(ctx context.Context, tlsConfig *tls.Config) {
+	scrape.PodMetrics(ctx, k.check, k.log, k.config, tlsConfig, k.apiTimelimit, scrape.Component{
+		Name:         "kube-controller-manager",
+		Namespaces:   []string{k.config.KubeControllerManagerNamespace},
+		Selector:     k.config.KubeControllerManagerSelector,
+		Port:         k.config.KubeControllerManagerPort,
+		FamilyFilter: familyFilter,
+		PodTag:       true,
+	}, k.ts)
+}
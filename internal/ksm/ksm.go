@@ -220,7 +220,7 @@ func (ksm *KSM) getServiceDefinition(tlsConfig *tls.Config) (*k8s.Service, error
 
 	reqURL := u.String()
 	ksm.log.Debug().Str("url", reqURL).Msg("service")
-	req, err := k8s.NewAPIRequest(ksm.config.BearerToken, reqURL)
+	req, err := k8s.NewAPIRequest(ksm.config.Token(), reqURL)
 	if err != nil {
 		return nil, errors.Wrap(err, "service definition req")
 	}
@@ -276,7 +276,7 @@ func (ksm *KSM) metrics(ctx context.Context, tlsConfig *tls.Config, metricURL st
 	defer client.CloseIdleConnections()
 
 	ksm.log.Debug().Str("url", metricURL).Msg("metrics")
-	req, err := k8s.NewAPIRequest(ksm.config.BearerToken, metricURL)
+	req, err := k8s.NewAPIRequest(ksm.config.Token(), metricURL)
 	if err != nil {
 		return errors.Wrap(err, "/metrics req")
 	}
@@ -340,7 +340,7 @@ func (ksm *KSM) telemetry(ctx context.Context, tlsConfig *tls.Config, telemetryU
 	defer client.CloseIdleConnections()
 
 	ksm.log.Debug().Str("url", telemetryURL).Msg("telemetry")
-	req, err := k8s.NewAPIRequest(ksm.config.BearerToken, telemetryURL)
+	req, err := k8s.NewAPIRequest(ksm.config.Token(), telemetryURL)
 	if err != nil {
 		return errors.Wrap(err, "/telemetry req")
 	}
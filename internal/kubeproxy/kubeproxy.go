@@ -0,0 +1,129 @@
+// Copyright © 2019 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+// Package kubeproxy is the kube-proxy metrics collector
+package kubeproxy
+
+import (
+	"context"
+	"crypto/tls"
+	"regexp"
+	"sync"
+	"time"
+
+	cgm "github.com/circonus-labs/circonus-gometrics/v3"
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/circonus"
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/config"
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/config/defaults"
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/release"
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/scrape"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog"
+)
+
+// only forward kube-proxy metric families (e.g. rule sync latency, iptables
+// restore failures, network programming latency), not the go runtime/process metrics
+var familyFilter = regexp.MustCompile(`^kubeproxy_`)
+
+type KubeProxy struct {
+	config       *config.Cluster
+	check        *circonus.Check
+	log          zerolog.Logger
+	running      bool
+	apiTimelimit time.Duration
+	sync.Mutex
+	ts *time.Time
+}
+
+// NOTES:
+// kube-proxy normally runs as a daemonset (kubeadm labels pods k8s-app=kube-proxy) in kube-system
+// with hostNetwork, metrics are on 10249. Note, by default metricsBindAddress is 127.0.0.1:10249,
+// it must be set to 0.0.0.0:10249 for the metrics to be reachable through the api-server pod proxy.
+
+func New(cfg *config.Cluster, parentLog zerolog.Logger, check *circonus.Check) (*KubeProxy, error) {
+	if cfg == nil {
+		return nil, errors.New("invalid cluster config (nil)")
+	}
+	if check == nil {
+		return nil, errors.New("invalid check (nil)")
+	}
+
+	kp := &KubeProxy{
+		config: cfg,
+		check:  check,
+		log:    parentLog.With().Str("collector", "kube-proxy").Logger(),
+	}
+
+	if cfg.APITimelimit != "" {
+		v, err := time.ParseDuration(cfg.APITimelimit)
+		if err != nil {
+			kp.log.Error().Err(err).Msg("parsing api timelimit, using default")
+		} else {
+			kp.apiTimelimit = v
+		}
+	}
+
+	if kp.apiTimelimit == time.Duration(0) {
+		v, err := time.ParseDuration(defaults.K8SAPITimelimit)
+		if err != nil {
+			kp.log.Fatal().Err(err).Msg("parsing DEFAULT api timelimit")
+		}
+		kp.apiTimelimit = v
+	}
+
+	return kp, nil
+}
+
+func (kp *KubeProxy) ID() string {
+	return "kube-proxy"
+}
+
+// Collect metrics from kube-proxy(s)
+func (kp *KubeProxy) Collect(ctx context.Context, tlsConfig *tls.Config, ts *time.Time) {
+	kp.Lock()
+	if kp.running {
+		kp.log.Warn().Msg("already running")
+		kp.Unlock()
+		return
+	}
+	kp.running = true
+	kp.ts = ts
+	kp.Unlock()
+
+	defer func() {
+		if r := recover(); r != nil {
+			kp.log.Error().Interface("panic", r).Msg("recover")
+			kp.Lock()
+			kp.running = false
+			kp.Unlock()
+		}
+	}()
+
+	collectStart := time.Now()
+
+	kp.podMetrics(ctx, tlsConfig)
+
+	kp.check.AddHistSample("collect_latency", cgm.Tags{
+		cgm.Tag{Category: "source", Value: release.NAME},
+		cgm.Tag{Category: "op", Value: "collect_kube-proxy"},
+		cgm.Tag{Category: "units", Value: "milliseconds"},
+	}, float64(time.Since(collectStart).Milliseconds()))
+	kp.log.Debug().Str("duration", time.Since(collectStart).String()).Msg("kube-proxy collect end")
+	kp.Lock()
+	kp.running = false
+	kp.Unlock()
+}
+
+// podMetrics collects metrics from each kube-proxy pod via the api-server proxy
+func (kp *KubeProxy) podMetrics(ctx context.Context, tlsConfig *tls.Config) {
+	scrape.PodMetrics(ctx, kp.check, kp.log, kp.config, tlsConfig, kp.apiTimelimit, scrape.Component{
+		Name:         "kube-proxy",
+		Namespaces:   []string{kp.config.KubeProxyNamespace},
+		Selector:     kp.config.KubeProxySelector,
+		Port:         kp.config.KubeProxyPort,
+		FamilyFilter: familyFilter,
+		Workers:      int(kp.config.NodePoolSize), // one kube-proxy per node, same number of workers as node collection
+	}, kp.ts)
+}
@@ -0,0 +1,26 @@
+// Copyright © 2019 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+package kubeproxy
+
+import "testing"
+
+func TestFamilyFilter(t *testing.T) {
+	tests := []struct {
+		family string
+		want   bool
+	}{
+		{"kubeproxy_sync_proxy_rules_last_timestamp_seconds", true},
+		{"kubeproxy_network_programming_duration_seconds", true},
+		{"go_goroutines", false},
+		{"rest_client_requests_total", false},
+	}
+
+	for _, tt := range tests {
+		if got := familyFilter.MatchString(tt.family); got != tt.want {
+			t.Errorf("%s: expected %v, got %v", tt.family, tt.want, got)
+		}
+	}
+}
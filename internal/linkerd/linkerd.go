@@ -0,0 +1,134 @@
+// Copyright © 2019 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+// Package linkerd is the linkerd-proxy sidecar metrics collector
+package linkerd
+
+import (
+	"context"
+	"crypto/tls"
+	"regexp"
+	"sync"
+	"time"
+
+	cgm "github.com/circonus-labs/circonus-gometrics/v3"
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/circonus"
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/config"
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/config/defaults"
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/release"
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/scrape"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog"
+)
+
+// only forward the linkerd-proxy golden signal metric families, request/response
+// totals (success rate is derived from the response_total classification label),
+// response latency histograms, and tcp connection counts
+var familyFilter = regexp.MustCompile(`^(request_total|response_total|response_latency_ms|tcp_open_total|tcp_close_total|tcp_open_connections)$`)
+
+type Linkerd struct {
+	config       *config.Cluster
+	check        *circonus.Check
+	log          zerolog.Logger
+	running      bool
+	apiTimelimit time.Duration
+	sync.Mutex
+	ts *time.Time
+}
+
+// NOTES:
+// Meshed pods are labeled linkerd.io/control-plane-ns, the linkerd-proxy sidecar serves its
+// metrics on the admin port 4191 /metrics. Sidecars are scraped directly (pod ip) rather than
+// through the api-server pod proxy, there can be a large number of them.
+
+func New(cfg *config.Cluster, parentLog zerolog.Logger, check *circonus.Check) (*Linkerd, error) {
+	if cfg == nil {
+		return nil, errors.New("invalid cluster config (nil)")
+	}
+	if check == nil {
+		return nil, errors.New("invalid check (nil)")
+	}
+
+	ld := &Linkerd{
+		config: cfg,
+		check:  check,
+		log:    parentLog.With().Str("collector", "linkerd").Logger(),
+	}
+
+	if cfg.APITimelimit != "" {
+		v, err := time.ParseDuration(cfg.APITimelimit)
+		if err != nil {
+			ld.log.Error().Err(err).Msg("parsing api timelimit, using default")
+		} else {
+			ld.apiTimelimit = v
+		}
+	}
+
+	if ld.apiTimelimit == time.Duration(0) {
+		v, err := time.ParseDuration(defaults.K8SAPITimelimit)
+		if err != nil {
+			ld.log.Fatal().Err(err).Msg("parsing DEFAULT api timelimit")
+		}
+		ld.apiTimelimit = v
+	}
+
+	return ld, nil
+}
+
+func (ld *Linkerd) ID() string {
+	return "linkerd"
+}
+
+// Collect metrics from linkerd-proxy sidecars
+func (ld *Linkerd) Collect(ctx context.Context, tlsConfig *tls.Config, ts *time.Time) {
+	ld.Lock()
+	if ld.running {
+		ld.log.Warn().Msg("already running")
+		ld.Unlock()
+		return
+	}
+	ld.running = true
+	ld.ts = ts
+	ld.Unlock()
+
+	defer func() {
+		if r := recover(); r != nil {
+			ld.log.Error().Interface("panic", r).Msg("recover")
+			ld.Lock()
+			ld.running = false
+			ld.Unlock()
+		}
+	}()
+
+	collectStart := time.Now()
+
+	ld.podMetrics(ctx, tlsConfig)
+
+	ld.check.AddHistSample("collect_latency", cgm.Tags{
+		cgm.Tag{Category: "source", Value: release.NAME},
+		cgm.Tag{Category: "op", Value: "collect_linkerd"},
+		cgm.Tag{Category: "units", Value: "milliseconds"},
+	}, float64(time.Since(collectStart).Milliseconds()))
+	ld.log.Debug().Str("duration", time.Since(collectStart).String()).Msg("linkerd collect end")
+	ld.Lock()
+	ld.running = false
+	ld.Unlock()
+}
+
+// podMetrics collects metrics from each linkerd-proxy sidecar in the selected namespaces
+func (ld *Linkerd) podMetrics(ctx context.Context, tlsConfig *tls.Config) {
+	scrape.PodMetrics(ctx, ld.check, ld.log, ld.config, tlsConfig, ld.apiTimelimit, scrape.Component{
+		Name:         "linkerd-proxy",
+		Source:       "linkerd",
+		Namespaces:   scrape.Namespaces(ld.config.LinkerdNamespaces),
+		Selector:     ld.config.LinkerdSelector,
+		Port:         ld.config.LinkerdPort,
+		FamilyFilter: familyFilter,
+		Direct:       true,
+		PodTag:       true,
+		NamespaceTag: true,
+		Workers:      int(ld.config.NodePoolSize),
+	}, ld.ts)
+}
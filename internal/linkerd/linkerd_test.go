@@ -0,0 +1,66 @@
+// Copyright © 2019 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+package linkerd
+
+import (
+	"context"
+	"crypto/tls"
+	"testing"
+
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/config"
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/scrape/scrapetest"
+	"github.com/rs/zerolog"
+)
+
+func TestFamilyFilter(t *testing.T) {
+	tests := []struct {
+		family string
+		want   bool
+	}{
+		{"request_total", true},
+		{"response_total", true},
+		{"response_latency_ms", true},
+		{"tcp_open_total", true},
+		{"tcp_open_connections", true},
+		{"tcp_read_bytes_total", false},
+		{"process_cpu_seconds_total", false},
+	}
+
+	for _, tt := range tests {
+		if got := familyFilter.MatchString(tt.family); got != tt.want {
+			t.Errorf("%s: expected %v, got %v", tt.family, tt.want, got)
+		}
+	}
+}
+
+func TestCollect(t *testing.T) {
+	ts := scrapetest.NewServer(map[string]string{
+		"/api/v1/namespaces/mesh/pods": `{"items":[{"metadata":{"name":"web-abcde","namespace":"mesh"},"spec":{"nodeName":"worker1"},"status":{"phase":"Running","podIP":"127.0.0.1"}}]}`,
+		"/metrics": `# TYPE response_total counter
+response_total{direction="inbound",classification="failure",status_code="503"} 2
+`,
+	})
+	defer ts.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	check, rec := scrapetest.NewCheck(ctx, t)
+
+	ld, err := New(&config.Cluster{
+		URL:               ts.URL,
+		LinkerdNamespaces: "mesh",
+		LinkerdSelector:   "linkerd.io/control-plane-ns",
+		LinkerdPort:       scrapetest.Port(t, ts),
+	}, zerolog.Nop(), check)
+	if err != nil {
+		t.Fatalf("unexpected error (%s)", err)
+	}
+	ld.Collect(ctx, &tls.Config{}, nil)
+
+	// the success/failure classification of responses is kept as a stream tag
+	scrapetest.ExpectTags(t, rec, "response_total",
+		"source:linkerd", "namespace:mesh", "pod:web-abcde", "node:worker1", "classification:failure")
+}
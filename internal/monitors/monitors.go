@@ -0,0 +1,307 @@
+// Copyright © 2019 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+// Package monitors is the collector for prometheus-operator ServiceMonitor
+// and PodMonitor resources, the monitors are watched and translated into
+// scrape targets
+package monitors
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"sync"
+	"time"
+
+	cgm "github.com/circonus-labs/circonus-gometrics/v3"
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/circonus"
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/config"
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/config/defaults"
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/k8s"
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/release"
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/scrape"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/dynamic/dynamicinformer"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/cache"
+)
+
+const monitoringGroupVersion = "monitoring.coreos.com/v1"
+
+var (
+	serviceMonitorGVR = schema.GroupVersionResource{Group: "monitoring.coreos.com", Version: "v1", Resource: "servicemonitors"}
+	podMonitorGVR     = schema.GroupVersionResource{Group: "monitoring.coreos.com", Version: "v1", Resource: "podmonitors"}
+)
+
+type Monitors struct {
+	config       *config.Cluster
+	check        *circonus.Check
+	log          zerolog.Logger
+	clientset    kubernetes.Interface
+	smLister     cache.GenericLister
+	pmLister     cache.GenericLister
+	running      bool
+	apiTimelimit time.Duration
+	sync.Mutex
+	ts *time.Time
+}
+
+// NOTES:
+// Only the target selection parts of the monitor endpoints are used (port, targetPort,
+// path, scheme), scrape intervals are the cluster collection interval and endpoint
+// authentication (bearer token, basic auth, tls config) and relabeling are not supported.
+// https endpoints are scraped without verifying the target certificate.
+
+func New(cfg *config.Cluster, parentLog zerolog.Logger, check *circonus.Check) (*Monitors, error) {
+	if cfg == nil {
+		return nil, errors.New("invalid cluster config (nil)")
+	}
+	if check == nil {
+		return nil, errors.New("invalid check (nil)")
+	}
+
+	m := &Monitors{
+		config: cfg,
+		check:  check,
+		log:    parentLog.With().Str("collector", "monitors").Logger(),
+	}
+
+	if cfg.APITimelimit != "" {
+		v, err := time.ParseDuration(cfg.APITimelimit)
+		if err != nil {
+			m.log.Error().Err(err).Msg("parsing api timelimit, using default")
+		} else {
+			m.apiTimelimit = v
+		}
+	}
+
+	if m.apiTimelimit == time.Duration(0) {
+		v, err := time.ParseDuration(defaults.K8SAPITimelimit)
+		if err != nil {
+			m.log.Fatal().Err(err).Msg("parsing DEFAULT api timelimit")
+		}
+		m.apiTimelimit = v
+	}
+
+	return m, nil
+}
+
+func (m *Monitors) ID() string {
+	return "monitors"
+}
+
+// Start watching ServiceMonitor and PodMonitor resources, does not return until ctx is done
+func (m *Monitors) Start(ctx context.Context, _ *tls.Config) {
+	m.log.Info().Msg("starting watcher")
+
+	cfg, err := k8s.RESTConfig(m.config)
+	if err != nil {
+		m.log.Error().Err(err).Msg("unable to start monitor watcher")
+		return
+	}
+
+	clientset, err := kubernetes.NewForConfig(cfg)
+	if err != nil {
+		m.log.Error().Err(err).Msg("initializing client set")
+		return
+	}
+
+	// the informers retry (forever) if the resources do not exist, verify the CRDs are installed
+	if _, err := clientset.Discovery().ServerResourcesForGroupVersion(monitoringGroupVersion); err != nil {
+		m.log.Error().Err(err).Str("group_version", monitoringGroupVersion).Msg("prometheus-operator CRDs not found, not watching monitors")
+		return
+	}
+
+	dc, err := dynamic.NewForConfig(cfg)
+	if err != nil {
+		m.log.Error().Err(err).Msg("initializing dynamic client")
+		return
+	}
+
+	factory := dynamicinformer.NewFilteredDynamicSharedInformerFactory(dc, 0, m.config.PromMonitorsNamespace, nil)
+	sm := factory.ForResource(serviceMonitorGVR)
+	pm := factory.ForResource(podMonitorGVR)
+	stopper := make(chan struct{})
+	defer close(stopper)
+	defer runtime.HandleCrash()
+
+	factory.Start(stopper)
+	for gvr, synced := range factory.WaitForCacheSync(stopper) {
+		if !synced {
+			m.log.Warn().Str("resource", gvr.Resource).Msg("timed out waiting for cache to sync")
+			return
+		}
+	}
+
+	m.Lock()
+	m.clientset = clientset
+	m.smLister = sm.Lister()
+	m.pmLister = pm.Lister()
+	m.Unlock()
+
+	<-ctx.Done()
+	m.log.Debug().Msg("closing monitor watcher")
+}
+
+// Collect metrics from the ServiceMonitor and PodMonitor targets
+func (m *Monitors) Collect(ctx context.Context, tlsConfig *tls.Config, ts *time.Time) {
+	m.Lock()
+	if m.running {
+		m.log.Warn().Msg("already running")
+		m.Unlock()
+		return
+	}
+	if m.smLister == nil || m.pmLister == nil {
+		m.log.Warn().Msg("monitor watcher not ready, skipping")
+		m.Unlock()
+		return
+	}
+	m.running = true
+	m.ts = ts
+	m.Unlock()
+
+	defer func() {
+		if r := recover(); r != nil {
+			m.log.Error().Interface("panic", r).Msg("recover")
+			m.Lock()
+			m.running = false
+			m.Unlock()
+		}
+	}()
+
+	collectStart := time.Now()
+
+	var targets []scrape.Target
+	targets = append(targets, m.serviceMonitorTargets()...)
+	targets = append(targets, m.podMonitorTargets()...)
+
+	if len(targets) > 0 {
+		scrape.MetricsPool(ctx, m.check, m.log, tlsConfig, m.apiTimelimit, targets, int(m.config.NodePoolSize), m.ts)
+	}
+
+	m.check.AddHistSample("collect_latency", cgm.Tags{
+		cgm.Tag{Category: "source", Value: release.NAME},
+		cgm.Tag{Category: "op", Value: "collect_monitors"},
+		cgm.Tag{Category: "units", Value: "milliseconds"},
+	}, float64(time.Since(collectStart).Milliseconds()))
+	m.log.Debug().Int("targets", len(targets)).Str("duration", time.Since(collectStart).String()).Msg("monitors collect end")
+	m.Lock()
+	m.running = false
+	m.Unlock()
+}
+
+// serviceMonitorTargets translates the ServiceMonitors into targets for the endpoints of the selected services
+func (m *Monitors) serviceMonitorTargets() []scrape.Target {
+	objs, err := m.smLister.List(labels.Everything())
+	if err != nil {
+		m.log.Error().Err(err).Msg("listing service monitors")
+		return nil
+	}
+
+	var targets []scrape.Target
+	for _, obj := range objs {
+		var sm serviceMonitor
+		if err := decode(obj, &sm); err != nil {
+			m.log.Warn().Err(err).Msg("decoding service monitor")
+			continue
+		}
+		sel, err := metav1.LabelSelectorAsSelector(&sm.Spec.Selector)
+		if err != nil {
+			m.log.Warn().Err(err).Str("service_monitor", sm.Metadata.Name).Msg("invalid selector")
+			continue
+		}
+		for _, ns := range sm.Spec.NamespaceSelector.namespaces(sm.Metadata.Namespace) {
+			svcs, err := m.clientset.CoreV1().Services(ns).List(metav1.ListOptions{LabelSelector: sel.String()})
+			if err != nil {
+				m.apiError("service-list", err)
+				continue
+			}
+			for i := range svcs.Items {
+				svc := &svcs.Items[i]
+				eps, err := m.clientset.CoreV1().Endpoints(svc.Namespace).Get(svc.Name, metav1.GetOptions{})
+				if err != nil {
+					m.apiError("endpoints", err)
+					continue
+				}
+				for _, ep := range sm.Spec.Endpoints {
+					targets = append(targets, endpointsTargets(sm.Metadata.Name, svc, eps, ep)...)
+				}
+			}
+		}
+	}
+
+	return targets
+}
+
+// podMonitorTargets translates the PodMonitors into targets for the selected pods
+func (m *Monitors) podMonitorTargets() []scrape.Target {
+	objs, err := m.pmLister.List(labels.Everything())
+	if err != nil {
+		m.log.Error().Err(err).Msg("listing pod monitors")
+		return nil
+	}
+
+	var targets []scrape.Target
+	for _, obj := range objs {
+		var pm podMonitor
+		if err := decode(obj, &pm); err != nil {
+			m.log.Warn().Err(err).Msg("decoding pod monitor")
+			continue
+		}
+		sel, err := metav1.LabelSelectorAsSelector(&pm.Spec.Selector)
+		if err != nil {
+			m.log.Warn().Err(err).Str("pod_monitor", pm.Metadata.Name).Msg("invalid selector")
+			continue
+		}
+		for _, ns := range pm.Spec.NamespaceSelector.namespaces(pm.Metadata.Namespace) {
+			pods, err := m.clientset.CoreV1().Pods(ns).List(metav1.ListOptions{
+				LabelSelector: sel.String(),
+				FieldSelector: "status.phase=Running",
+			})
+			if err != nil {
+				m.apiError("pod-list", err)
+				continue
+			}
+			for i := range pods.Items {
+				for _, ep := range pm.Spec.PodMetricsEndpoints {
+					if target, ok := podTarget(pm.Metadata.Name, &pods.Items[i], ep); ok {
+						targets = append(targets, target)
+					}
+				}
+			}
+		}
+	}
+
+	return targets
+}
+
+func (m *Monitors) apiError(request string, err error) {
+	m.check.IncrementCounter("collect_api_errors", cgm.Tags{
+		cgm.Tag{Category: "source", Value: release.NAME},
+		cgm.Tag{Category: "request", Value: request},
+		cgm.Tag{Category: "target", Value: "api-server"},
+	})
+	m.log.Error().Err(err).Str("request", request).Msg("api request")
+}
+
+// decode converts a watched (unstructured) monitor resource into v
+func decode(obj interface{}, v interface{}) error {
+	u, ok := obj.(*unstructured.Unstructured)
+	if !ok {
+		return errors.Errorf("unexpected object type (%T)", obj)
+	}
+	data, err := json.Marshal(u.Object)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
@@ -0,0 +1,126 @@
+// Copyright © 2019 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+package monitors
+
+import (
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+func TestNamespaces(t *testing.T) {
+	tests := []struct {
+		name string
+		sel  namespaceSelector
+		want []string
+	}{
+		{"default", namespaceSelector{}, []string{"mon"}},
+		{"any", namespaceSelector{Any: true}, []string{""}},
+		{"match", namespaceSelector{MatchNames: []string{"a", "b"}}, []string{"a", "b"}},
+	}
+
+	for _, tst := range tests {
+		tst := tst
+		t.Run(tst.name, func(t *testing.T) {
+			got := tst.sel.namespaces("mon")
+			if len(got) != len(tst.want) {
+				t.Fatalf("namespaces() = %v, want %v", got, tst.want)
+			}
+			for i := range got {
+				if got[i] != tst.want[i] {
+					t.Fatalf("namespaces() = %v, want %v", got, tst.want)
+				}
+			}
+		})
+	}
+}
+
+func TestEndpointsTargets(t *testing.T) {
+	svc := &corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{Name: "app", Namespace: "ns"},
+		Spec: corev1.ServiceSpec{
+			Ports: []corev1.ServicePort{{Name: "web", TargetPort: intstr.FromString("http-metrics")}},
+		},
+	}
+	eps := &corev1.Endpoints{
+		Subsets: []corev1.EndpointSubset{{
+			Addresses: []corev1.EndpointAddress{
+				{IP: "10.0.0.1", TargetRef: &corev1.ObjectReference{Kind: "Pod", Name: "app-1"}},
+				{IP: "10.0.0.2"},
+			},
+			Ports: []corev1.EndpointPort{{Name: "web", Port: 8080}},
+		}},
+	}
+	named := intstr.FromString("http-metrics")
+	number := intstr.FromInt(9090)
+
+	tests := []struct {
+		name string
+		ep   endpoint
+		want []string
+	}{
+		{"port name", endpoint{Port: "web"}, []string{"http://10.0.0.1:8080/metrics", "http://10.0.0.2:8080/metrics"}},
+		{"unknown port", endpoint{Port: "foo"}, nil},
+		{"target port name", endpoint{TargetPort: &named, Path: "stats"}, []string{"http://10.0.0.1:8080/stats", "http://10.0.0.2:8080/stats"}},
+		{"target port number", endpoint{TargetPort: &number, Scheme: "https"}, []string{"https://10.0.0.1:9090/metrics", "https://10.0.0.2:9090/metrics"}},
+	}
+
+	for _, tst := range tests {
+		tst := tst
+		t.Run(tst.name, func(t *testing.T) {
+			targets := endpointsTargets("mon", svc, eps, tst.ep)
+			if len(targets) != len(tst.want) {
+				t.Fatalf("endpointsTargets() returned %d targets, want %d", len(targets), len(tst.want))
+			}
+			for i, target := range targets {
+				if target.URL != tst.want[i] {
+					t.Errorf("endpointsTargets() url = %s, want %s", target.URL, tst.want[i])
+				}
+				if (tst.ep.Scheme == "https") != (target.TLSConfig != nil) {
+					t.Errorf("endpointsTargets() unexpected tls config for scheme %q", tst.ep.Scheme)
+				}
+			}
+		})
+	}
+}
+
+func TestPodTarget(t *testing.T) {
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "app-1", Namespace: "ns"},
+		Spec: corev1.PodSpec{
+			Containers: []corev1.Container{{Ports: []corev1.ContainerPort{{Name: "metrics", ContainerPort: 9102}}}},
+		},
+		Status: corev1.PodStatus{PodIP: "10.0.0.1"},
+	}
+	number := intstr.FromInt(8080)
+
+	tests := []struct {
+		name string
+		ep   endpoint
+		want string
+		ok   bool
+	}{
+		{"port name", endpoint{Port: "metrics"}, "http://10.0.0.1:9102/metrics", true},
+		{"unknown port", endpoint{Port: "foo"}, "", false},
+		{"target port number", endpoint{TargetPort: &number, Path: "/m"}, "http://10.0.0.1:8080/m", true},
+		{"no port", endpoint{}, "", false},
+	}
+
+	for _, tst := range tests {
+		tst := tst
+		t.Run(tst.name, func(t *testing.T) {
+			target, ok := podTarget("mon", pod, tst.ep)
+			if ok != tst.ok {
+				t.Fatalf("podTarget() ok = %t, want %t", ok, tst.ok)
+			}
+			if target.URL != tst.want {
+				t.Errorf("podTarget() url = %s, want %s", target.URL, tst.want)
+			}
+		})
+	}
+}
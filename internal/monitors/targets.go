@@ -0,0 +1,192 @@
+// Copyright © 2019 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+package monitors
+
+import (
+	"crypto/tls"
+	"net"
+	"strconv"
+	"strings"
+
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/scrape"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+// the subset of the prometheus-operator monitoring.coreos.com/v1 types used to select targets
+
+type serviceMonitor struct {
+	Metadata metav1.ObjectMeta  `json:"metadata"`
+	Spec     serviceMonitorSpec `json:"spec"`
+}
+type serviceMonitorSpec struct {
+	Selector          metav1.LabelSelector `json:"selector"`
+	NamespaceSelector namespaceSelector    `json:"namespaceSelector"`
+	Endpoints         []endpoint           `json:"endpoints"`
+}
+type podMonitor struct {
+	Metadata metav1.ObjectMeta `json:"metadata"`
+	Spec     podMonitorSpec    `json:"spec"`
+}
+type podMonitorSpec struct {
+	Selector            metav1.LabelSelector `json:"selector"`
+	NamespaceSelector   namespaceSelector    `json:"namespaceSelector"`
+	PodMetricsEndpoints []endpoint           `json:"podMetricsEndpoints"`
+}
+type namespaceSelector struct {
+	Any        bool     `json:"any"`
+	MatchNames []string `json:"matchNames"`
+}
+type endpoint struct {
+	Port       string              `json:"port"`
+	TargetPort *intstr.IntOrString `json:"targetPort"`
+	Path       string              `json:"path"`
+	Scheme     string              `json:"scheme"`
+}
+
+// namespaces returns the namespaces selected, the monitor's own namespace if none are specified
+func (ns namespaceSelector) namespaces(monitorNamespace string) []string {
+	if ns.Any {
+		return []string{metav1.NamespaceAll}
+	}
+	if len(ns.MatchNames) > 0 {
+		return ns.MatchNames
+	}
+	return []string{monitorNamespace}
+}
+
+// url returns the target url for an address and port
+func (ep endpoint) url(addr string, port int32) string {
+	scheme := "http"
+	if ep.Scheme != "" {
+		scheme = strings.ToLower(ep.Scheme)
+	}
+	path := ep.Path
+	if path == "" {
+		path = "/metrics"
+	}
+	if !strings.HasPrefix(path, "/") {
+		path = "/" + path
+	}
+	return scheme + "://" + net.JoinHostPort(addr, strconv.Itoa(int(port))) + path
+}
+
+// tlsConfig returns the tls config for the endpoint target, nil for http
+func (ep endpoint) tlsConfig() *tls.Config {
+	if strings.ToLower(ep.Scheme) != "https" {
+		return nil
+	}
+	return &tls.Config{InsecureSkipVerify: true} //nolint:gosec
+}
+
+// endpointsTargets returns a target for each address of the service endpoints matching the ServiceMonitor endpoint port
+func endpointsTargets(monitor string, svc *corev1.Service, eps *corev1.Endpoints, ep endpoint) []scrape.Target {
+	var targets []scrape.Target
+	for _, subset := range eps.Subsets {
+		port, ok := endpointPort(svc, subset.Ports, ep)
+		if !ok {
+			continue
+		}
+		for _, addr := range subset.Addresses {
+			streamTags := []string{
+				"source:monitors",
+				"source_type:metrics",
+				"service_monitor:" + monitor,
+				"namespace:" + svc.Namespace,
+				"service:" + svc.Name,
+			}
+			if addr.TargetRef != nil && addr.TargetRef.Kind == "Pod" {
+				streamTags = append(streamTags, "pod:"+addr.TargetRef.Name)
+			}
+			streamTags = append(streamTags, "__rollup:false") // prevent high cardinality metrics from rolling up
+			targets = append(targets, scrape.Target{
+				URL:        ep.url(addr.IP, port),
+				Name:       "service-monitor",
+				TLSConfig:  ep.tlsConfig(),
+				StreamTags: streamTags,
+			})
+		}
+	}
+	return targets
+}
+
+// endpointPort returns the endpoints port number for the ServiceMonitor endpoint, port is the
+// name of the service port, targetPort is the endpoints (pod) port number or name
+func endpointPort(svc *corev1.Service, ports []corev1.EndpointPort, ep endpoint) (int32, bool) {
+	if ep.Port != "" {
+		for _, p := range ports {
+			if p.Name == ep.Port {
+				return p.Port, true
+			}
+		}
+		return 0, false
+	}
+	if ep.TargetPort != nil {
+		if ep.TargetPort.Type == intstr.Int {
+			return ep.TargetPort.IntVal, true
+		}
+		// a named target port, use the endpoints port for the service port targeting that name
+		for _, sp := range svc.Spec.Ports {
+			if sp.TargetPort.Type != intstr.String || sp.TargetPort.StrVal != ep.TargetPort.StrVal {
+				continue
+			}
+			for _, p := range ports {
+				if p.Name == sp.Name {
+					return p.Port, true
+				}
+			}
+		}
+	}
+	return 0, false
+}
+
+// podTarget returns the target for a pod matching the PodMonitor endpoint port
+func podTarget(monitor string, pod *corev1.Pod, ep endpoint) (scrape.Target, bool) {
+	if pod.Status.PodIP == "" {
+		return scrape.Target{}, false
+	}
+	port, ok := containerPort(pod, ep)
+	if !ok {
+		return scrape.Target{}, false
+	}
+	return scrape.Target{
+		URL:       ep.url(pod.Status.PodIP, port),
+		Name:      "pod-monitor",
+		TLSConfig: ep.tlsConfig(),
+		StreamTags: []string{
+			"source:monitors",
+			"source_type:metrics",
+			"pod_monitor:" + monitor,
+			"namespace:" + pod.Namespace,
+			"pod:" + pod.Name,
+			"__rollup:false", // prevent high cardinality metrics from rolling up
+		},
+	}, true
+}
+
+// containerPort returns the pod port number for the PodMonitor endpoint, port is the
+// name of a container port, targetPort is a container port number or name
+func containerPort(pod *corev1.Pod, ep endpoint) (int32, bool) {
+	name := ep.Port
+	if name == "" && ep.TargetPort != nil {
+		if ep.TargetPort.Type == intstr.Int {
+			return ep.TargetPort.IntVal, true
+		}
+		name = ep.TargetPort.StrVal
+	}
+	if name == "" {
+		return 0, false
+	}
+	for _, c := range pod.Spec.Containers {
+		for _, p := range c.Ports {
+			if p.Name == name {
+				return p.ContainerPort, true
+			}
+		}
+	}
+	return 0, false
+}
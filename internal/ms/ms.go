@@ -112,7 +112,7 @@ func (ms *MS) Collect(ctx context.Context, tlsConfig *tls.Config, ts *time.Time)
 	}
 	defer client.CloseIdleConnections()
 
-	req, err := k8s.NewAPIRequest(ms.config.BearerToken, metricsURL)
+	req, err := k8s.NewAPIRequest(ms.config.Token(), metricsURL)
 	if err != nil {
 		ms.log.Error().Err(err).Str("url", metricsURL).Msg("metrics req")
 		ms.Lock()
@@ -0,0 +1,129 @@
+// Copyright © 2019 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+// Package nodelocaldns is the NodeLocal DNSCache metrics collector
+package nodelocaldns
+
+import (
+	"context"
+	"crypto/tls"
+	"regexp"
+	"sync"
+	"time"
+
+	cgm "github.com/circonus-labs/circonus-gometrics/v3"
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/circonus"
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/config"
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/config/defaults"
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/release"
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/scrape"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog"
+)
+
+// only forward the coredns plugin metric families (e.g. cache hits/misses, request
+// duration, forward failures) and node cache setup errors, not the go runtime/process metrics
+var familyFilter = regexp.MustCompile(`^(coredns|nodecache)_`)
+
+type NodeLocalDNS struct {
+	config       *config.Cluster
+	check        *circonus.Check
+	log          zerolog.Logger
+	running      bool
+	apiTimelimit time.Duration
+	sync.Mutex
+	ts *time.Time
+}
+
+// NOTES:
+// NodeLocal DNSCache runs as a daemonset (pods labeled k8s-app=node-local-dns) in kube-system
+// with hostNetwork, it is coredns based and the metrics are on 9253 (the prometheus plugin port
+// in the node-local-dns Corefile).
+
+func New(cfg *config.Cluster, parentLog zerolog.Logger, check *circonus.Check) (*NodeLocalDNS, error) {
+	if cfg == nil {
+		return nil, errors.New("invalid cluster config (nil)")
+	}
+	if check == nil {
+		return nil, errors.New("invalid check (nil)")
+	}
+
+	nld := &NodeLocalDNS{
+		config: cfg,
+		check:  check,
+		log:    parentLog.With().Str("collector", "node-local-dns").Logger(),
+	}
+
+	if cfg.APITimelimit != "" {
+		v, err := time.ParseDuration(cfg.APITimelimit)
+		if err != nil {
+			nld.log.Error().Err(err).Msg("parsing api timelimit, using default")
+		} else {
+			nld.apiTimelimit = v
+		}
+	}
+
+	if nld.apiTimelimit == time.Duration(0) {
+		v, err := time.ParseDuration(defaults.K8SAPITimelimit)
+		if err != nil {
+			nld.log.Fatal().Err(err).Msg("parsing DEFAULT api timelimit")
+		}
+		nld.apiTimelimit = v
+	}
+
+	return nld, nil
+}
+
+func (nld *NodeLocalDNS) ID() string {
+	return "node-local-dns"
+}
+
+// Collect metrics from node-local-dns instances
+func (nld *NodeLocalDNS) Collect(ctx context.Context, tlsConfig *tls.Config, ts *time.Time) {
+	nld.Lock()
+	if nld.running {
+		nld.log.Warn().Msg("already running")
+		nld.Unlock()
+		return
+	}
+	nld.running = true
+	nld.ts = ts
+	nld.Unlock()
+
+	defer func() {
+		if r := recover(); r != nil {
+			nld.log.Error().Interface("panic", r).Msg("recover")
+			nld.Lock()
+			nld.running = false
+			nld.Unlock()
+		}
+	}()
+
+	collectStart := time.Now()
+
+	nld.podMetrics(ctx, tlsConfig)
+
+	nld.check.AddHistSample("collect_latency", cgm.Tags{
+		cgm.Tag{Category: "source", Value: release.NAME},
+		cgm.Tag{Category: "op", Value: "collect_node-local-dns"},
+		cgm.Tag{Category: "units", Value: "milliseconds"},
+	}, float64(time.Since(collectStart).Milliseconds()))
+	nld.log.Debug().Str("duration", time.Since(collectStart).String()).Msg("node-local-dns collect end")
+	nld.Lock()
+	nld.running = false
+	nld.Unlock()
+}
+
+// podMetrics collects metrics from each node-local-dns pod via the api-server proxy
+func (nld *NodeLocalDNS) podMetrics(ctx context.Context, tlsConfig *tls.Config) {
+	scrape.PodMetrics(ctx, nld.check, nld.log, nld.config, tlsConfig, nld.apiTimelimit, scrape.Component{
+		Name:         "node-local-dns",
+		Namespaces:   []string{nld.config.NodeLocalDNSNamespace},
+		Selector:     nld.config.NodeLocalDNSSelector,
+		Port:         nld.config.NodeLocalDNSPort,
+		FamilyFilter: familyFilter,
+		Workers:      int(nld.config.NodePoolSize), // one node-local-dns per node, same number of workers as node collection
+	}, nld.ts)
+}
@@ -0,0 +1,27 @@
+// Copyright © 2019 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+package nodelocaldns
+
+import "testing"
+
+func TestFamilyFilter(t *testing.T) {
+	tests := []struct {
+		family string
+		want   bool
+	}{
+		{"coredns_cache_entries", true},
+		{"coredns_forward_requests_total", true},
+		{"nodecache_setup_errors_total", true},
+		{"go_goroutines", false},
+		{"process_open_fds", false},
+	}
+
+	for _, tt := range tests {
+		if got := familyFilter.MatchString(tt.family); got != tt.want {
+			t.Errorf("%s: expected %v, got %v", tt.family, tt.want, got)
+		}
+	}
+}
@@ -13,6 +13,8 @@ import (
 	"fmt"
 	"io/ioutil"
 	"net/http"
+	"net/url"
+	"regexp"
 	"strconv"
 	"sync"
 	"time"
@@ -27,21 +29,42 @@ import (
 	"github.com/rs/zerolog"
 
 	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/apimachinery/pkg/labels"
 )
 
+// kubeletOperationalFamilies are the kubelet /metrics families describing kubelet
+// health, e.g. pod lifecycle event generator relist latency, container runtime
+// operation errors/latency, and pod start duration
+var kubeletOperationalFamilies = regexp.MustCompile(`^(kubelet_(pleg_relist_(duration|interval)_seconds|runtime_operations_(total|errors_total|duration_seconds)|pod_start_duration_seconds|pod_worker_duration_seconds|pod_worker_start_duration_seconds))$`)
+
+// standardNodeConditions are the conditions set by the kubelet, any other conditions are set by
+// node-problem-detector or similar (e.g. KernelDeadlock, ReadonlyFilesystem, FrequentKubeletRestart)
+var standardNodeConditions = map[string]bool{
+	"Ready":              true,
+	"MemoryPressure":     true,
+	"DiskPressure":       true,
+	"PIDPressure":        true,
+	"NetworkUnavailable": true,
+}
+
 type Collector struct {
-	cfg          *config.Cluster
-	tlsConfig    *tls.Config
-	ctx          context.Context
-	check        *circonus.Check
-	node         *k8s.Node
-	baseLogger   zerolog.Logger
-	log          zerolog.Logger
-	ts           *time.Time
-	apiTimelimit time.Duration
-}
-
-func New(cfg *config.Cluster, node *k8s.Node, logger zerolog.Logger, check *circonus.Check, apiTimeout time.Duration) (*Collector, error) {
+	cfg            *config.Cluster
+	tlsConfig      *tls.Config
+	ctx            context.Context
+	check          *circonus.Check
+	node           *k8s.Node
+	cadvisorFilter *regexp.Regexp
+	podSelector    labels.Selector
+	baseLogger     zerolog.Logger
+	log            zerolog.Logger
+	ts             *time.Time
+	apiTimelimit   time.Duration
+	windows        bool // windows nodes do not report some kubelet stats (e.g. inodes, rlimit)
+}
+
+// New creates a collector for a node, cadvisorFilter restricts the /metrics/cadvisor
+// metric families forwarded (nil for all), podSelector the pods collected (nil for all)
+func New(cfg *config.Cluster, node *k8s.Node, logger zerolog.Logger, check *circonus.Check, apiTimeout time.Duration, cadvisorFilter *regexp.Regexp, podSelector labels.Selector) (*Collector, error) {
 	if cfg == nil {
 		return nil, errors.New("invalid cluster config (nil)")
 	}
@@ -53,14 +76,25 @@ func New(cfg *config.Cluster, node *k8s.Node, logger zerolog.Logger, check *circ
 	}
 
 	return &Collector{
-		cfg:          cfg,
-		check:        check,
-		node:         node,
-		apiTimelimit: apiTimeout,
-		baseLogger:   logger.With().Str("node", node.Metadata.Name).Logger(),
+		cfg:            cfg,
+		check:          check,
+		node:           node,
+		cadvisorFilter: cadvisorFilter,
+		podSelector:    podSelector,
+		apiTimelimit:   apiTimeout,
+		baseLogger:     logger.With().Str("node", node.Metadata.Name).Logger(),
+		windows:        isWindows(node),
 	}, nil
 }
 
+// isWindows returns whether the node is running windows, from the node info or os label
+func isWindows(node *k8s.Node) bool {
+	if node.Status.NodeInfo.OperatingSystem != "" {
+		return node.Status.NodeInfo.OperatingSystem == "windows"
+	}
+	return node.Metadata.Labels["kubernetes.io/os"] == "windows"
+}
+
 func (nc *Collector) Collect(ctx context.Context, workerID int, tlsConfig *tls.Config, ts *time.Time) {
 	nc.ctx = ctx
 	nc.tlsConfig = tlsConfig
@@ -86,14 +120,22 @@ func (nc *Collector) Collect(ctx context.Context, workerID int, tlsConfig *tls.C
 			wg.Done()
 		}()
 	}
-	if nc.cfg.EnableNodeMetrics {
+	if nc.cfg.EnableNodeMetrics || nc.cfg.EnableKubeletOperationalMetrics {
 		wg.Add(1)
 		go func() {
 			nc.nmetrics(baseStreamTags, baseMeasurementTags) // from /metrics
 			wg.Done()
 		}()
 	}
-	if nc.cfg.EnableCadvisorMetrics {
+	if nc.cfg.EnableNodeNetworkSaturation && !nc.windows {
+		wg.Add(1)
+		go func() {
+			nc.netSaturation(baseStreamTags, baseMeasurementTags) // from /metrics/cadvisor (root cgroup)
+			wg.Done()
+		}()
+	}
+	if nc.cfg.EnableCadvisorMetrics && !nc.windows {
+		// the windows kubelet does not serve /metrics/cadvisor
 		wg.Add(1)
 		go func() {
 			nc.cadvisor(baseStreamTags, baseMeasurementTags) // from /metrics/cadvisor
@@ -160,6 +202,34 @@ func (nc *Collector) meta(parentStreamTags []string, parentMeasurementTags []str
 		}
 	}
 
+	if nc.cfg.EnableNodeProblemConditions { // node-problem-detector (custom) conditions
+		for _, cond := range nc.node.Status.Conditions {
+			if nc.done() {
+				break
+			}
+			if standardNodeConditions[cond.Type] {
+				continue
+			}
+			var streamTags []string
+			streamTags = append(streamTags, parentStreamTags...)
+			streamTags = append(streamTags, []string{
+				"condition:" + cond.Type,
+				"reason:" + cond.Reason,
+			}...)
+			status := uint64(0)
+			if cond.Status == "True" {
+				status = 1
+			}
+			_ = nc.check.QueueMetricSample(
+				metrics,
+				"node_condition",
+				circonus.MetricTypeUint64,
+				streamTags, parentMeasurementTags,
+				status,
+				nc.ts)
+		}
+	}
+
 	{ // capacity and allocatable
 		var streamTags []string
 		streamTags = append(streamTags, parentStreamTags...)
@@ -300,9 +370,14 @@ type container struct {
 	Logs   fs     `json:"logs"`
 }
 type volume struct {
-	Name string `json:"name"`
+	Name   string  `json:"name"`
+	PVCRef *pvcRef `json:"pvcRef,omitempty"`
 	fs
 }
+type pvcRef struct {
+	Name      string `json:"name"`
+	Namespace string `json:"namespace"`
+}
 
 // summary emits node summary stats
 func (nc *Collector) summary(parentStreamTags []string, parentMeasurementTags []string) {
@@ -318,7 +393,7 @@ func (nc *Collector) summary(parentStreamTags []string, parentMeasurementTags []
 	defer client.CloseIdleConnections()
 
 	reqURL := nc.cfg.URL + nc.node.Metadata.SelfLink + "/proxy/stats/summary"
-	req, err := k8s.NewAPIRequest(nc.cfg.BearerToken, reqURL)
+	req, err := k8s.NewAPIRequest(nc.cfg.Token(), reqURL)
 	if err != nil {
 		nc.log.Error().Err(err).Msg("abandoning collection")
 		return
@@ -375,6 +450,7 @@ func (nc *Collector) summary(parentStreamTags []string, parentMeasurementTags []
 	nc.summaryNode(&stats.Node, parentStreamTags, parentMeasurementTags)
 	nc.summarySystemContainers(&stats.Node, parentStreamTags, parentMeasurementTags)
 	nc.summaryPods(&stats, parentStreamTags, parentMeasurementTags)
+	nc.summaryVolumes(&stats, parentStreamTags, parentMeasurementTags)
 }
 
 func (nc *Collector) summaryNode(node *statsSummaryNode, parentStreamTags []string, parentMeasurementTags []string) {
@@ -389,7 +465,9 @@ func (nc *Collector) summaryNode(node *statsSummaryNode, parentStreamTags []stri
 	nc.queueNetwork(metrics, &node.Network, parentStreamTags, parentMeasurementTags)
 	nc.queueFS(metrics, &node.FS, parentStreamTags, parentMeasurementTags)
 	nc.queueRuntimeImageFS(metrics, &node.Runtime.ImageFs, parentStreamTags, parentMeasurementTags)
-	nc.queueRlimit(metrics, &node.Rlimit, parentStreamTags, parentMeasurementTags)
+	if !nc.windows {
+		nc.queueRlimit(metrics, &node.Rlimit, parentStreamTags, parentMeasurementTags)
+	}
 
 	if len(metrics) == 0 {
 		nc.log.Warn().Msg("no telemetry to submit")
@@ -408,6 +486,9 @@ func (nc *Collector) summarySystemContainers(node *statsSummaryNode, parentStrea
 		return
 	}
 	if len(node.SystemContainers) == 0 {
+		if nc.windows {
+			return // not reported by the windows kubelet
+		}
 		nc.log.Error().Msg("invalid system containers (none)")
 		return
 	}
@@ -514,6 +595,54 @@ func (nc *Collector) summaryPods(stats *statsSummary, parentStreamTags []string,
 	}
 }
 
+// summaryVolumes emits usage for persistent volume claims mounted by pods on the node,
+// independent of pod metrics (a claim mounted by multiple pods is only emitted once)
+func (nc *Collector) summaryVolumes(stats *statsSummary, parentStreamTags []string, parentMeasurementTags []string) {
+	if nc.done() {
+		return
+	}
+	if !nc.cfg.EnableVolumeStats {
+		return
+	}
+
+	metrics := make(map[string]circonus.MetricSample)
+	seen := make(map[string]bool)
+
+	for _, pod := range stats.Pods {
+		if nc.done() {
+			break
+		}
+		for _, volume := range pod.Volumes {
+			if volume.PVCRef == nil {
+				continue
+			}
+			claim := volume.PVCRef.Namespace + "/" + volume.PVCRef.Name
+			if seen[claim] {
+				continue
+			}
+			seen[claim] = true
+
+			var streamTags []string
+			streamTags = append(streamTags, parentStreamTags...)
+			streamTags = append(streamTags, []string{
+				"persistentvolumeclaim:" + volume.PVCRef.Name,
+				"namespace:" + volume.PVCRef.Namespace,
+				"__rollup:false", // prevent high cardinality metrics from rolling up
+			}...)
+			volume := volume
+			nc.queuePVC(metrics, &volume.fs, streamTags, parentMeasurementTags)
+		}
+	}
+
+	if len(metrics) == 0 {
+		nc.log.Debug().Msg("no persistent volume claims")
+		return
+	}
+	if err := nc.check.SubmitQueue(nc.ctx, metrics, nc.log.With().Str("type", "volumes").Logger()); err != nil {
+		nc.log.Warn().Err(err).Msg("submitting metrics")
+	}
+}
+
 // nmetrics emits metrics from the node /metrics endpoint
 func (nc *Collector) nmetrics(parentStreamTags []string, parentMeasurementTags []string) {
 	if nc.done() {
@@ -528,7 +657,7 @@ func (nc *Collector) nmetrics(parentStreamTags []string, parentMeasurementTags [
 	defer client.CloseIdleConnections()
 
 	reqURL := nc.cfg.URL + nc.node.Metadata.SelfLink + "/proxy/metrics"
-	req, err := k8s.NewAPIRequest(nc.cfg.BearerToken, reqURL)
+	req, err := k8s.NewAPIRequest(nc.cfg.Token(), reqURL)
 	if err != nil {
 		nc.log.Error().Err(err).Msg("abandoning /metrics collection")
 		return
@@ -575,6 +704,14 @@ func (nc *Collector) nmetrics(parentStreamTags []string, parentMeasurementTags [
 		return
 	}
 
+	if !nc.cfg.EnableNodeMetrics {
+		// only the kubelet operational metrics
+		if err := promtext.QueueFilteredMetrics(nc.ctx, nc.check, nc.log, resp.Body, kubeletOperationalFamilies, parentStreamTags, parentMeasurementTags, nil); err != nil {
+			nc.log.Error().Err(err).Msg("parsing node metrics")
+		}
+		return
+	}
+
 	if err := promtext.QueueMetrics(nc.ctx, nc.check, nc.log, resp.Body, parentStreamTags, parentMeasurementTags, nil); err != nil {
 		nc.log.Error().Err(err).Msg("parsing node metrics")
 	}
@@ -594,7 +731,7 @@ func (nc *Collector) cadvisor(parentStreamTags []string, parentMeasurementTags [
 	defer client.CloseIdleConnections()
 
 	reqURL := nc.cfg.URL + nc.node.Metadata.SelfLink + "/proxy/metrics/cadvisor"
-	req, err := k8s.NewAPIRequest(nc.cfg.BearerToken, reqURL)
+	req, err := k8s.NewAPIRequest(nc.cfg.Token(), reqURL)
 	if err != nil {
 		nc.log.Error().Err(err).Msg("abandoning /metrics/cadvisor collection")
 		return
@@ -613,7 +750,7 @@ func (nc *Collector) cadvisor(parentStreamTags []string, parentMeasurementTags [
 		return
 	}
 	nc.check.AddHistSample("collect_latency", cgm.Tags{
-		cgm.Tag{Category: "request", Value: "metrics/cadvsior"},
+		cgm.Tag{Category: "request", Value: "metrics/cadvisor"},
 		cgm.Tag{Category: "proxy", Value: "api-server"},
 		cgm.Tag{Category: "target", Value: "kubelet"},
 		cgm.Tag{Category: "units", Value: "milliseconds"},
@@ -644,11 +781,110 @@ func (nc *Collector) cadvisor(parentStreamTags []string, parentMeasurementTags [
 	streamTags := []string{"__rollup:false"} // prevent high cardinality metrics from rolling up
 	streamTags = append(streamTags, parentStreamTags...)
 
+	if nc.podSelector != nil && !nc.podSelector.Empty() {
+		// the kubelet reports the containers of every pod on the node, only forward
+		// samples of pods matching the pod selector (and samples not of a pod)
+		selected, err := nc.selectedPods()
+		if err != nil {
+			nc.log.Error().Err(err).Msg("abandoning /metrics/cadvisor collection, listing pods matching pod selector")
+			return
+		}
+		if err := promtext.QueueSelectedMetrics(nc.ctx, nc.check, nc.log, resp.Body, nc.cadvisorFilter, cadvisorPodFilter(selected), streamTags, parentMeasurementTags, nil); err != nil {
+			nc.log.Error().Err(err).Msg("parsing node metrics/cadvisor")
+		}
+		return
+	}
+
+	if nc.cadvisorFilter != nil {
+		if err := promtext.QueueFilteredMetrics(nc.ctx, nc.check, nc.log, resp.Body, nc.cadvisorFilter, streamTags, parentMeasurementTags, nil); err != nil {
+			nc.log.Error().Err(err).Msg("parsing node metrics/cadvisor")
+		}
+		return
+	}
+
 	if err := promtext.QueueMetrics(nc.ctx, nc.check, nc.log, resp.Body, streamTags, parentMeasurementTags, nil); err != nil {
 		nc.log.Error().Err(err).Msg("parsing node metrics/cadvisor")
 	}
 }
 
+// cadvisorPodFilter returns a sample filter accepting samples of the selected pods
+// (namespace/name) and samples not of a pod (e.g. the root cgroup), older kubelets
+// label cadvisor samples with pod_name rather than pod
+func cadvisorPodFilter(selected map[string]bool) promtext.SampleFilter {
+	return func(labels map[string]string) bool {
+		pod := labels["pod"]
+		if pod == "" {
+			pod = labels["pod_name"]
+		}
+		if pod == "" {
+			return true
+		}
+		return selected[labels["namespace"]+"/"+pod]
+	}
+}
+
+// selectedPods returns the pods on the node matching the pod selector (namespace/name)
+func (nc *Collector) selectedPods() (map[string]bool, error) {
+	client, err := k8s.NewAPIClient(nc.tlsConfig, nc.apiTimelimit)
+	if err != nil {
+		return nil, err
+	}
+	defer client.CloseIdleConnections()
+
+	q := url.Values{}
+	q.Set("fieldSelector", "spec.nodeName="+nc.node.Metadata.Name)
+	q.Set("labelSelector", nc.podSelector.String())
+	reqURL := nc.cfg.URL + "/api/v1/pods?" + q.Encode()
+	req, err := k8s.NewAPIRequest(nc.cfg.Token(), reqURL)
+	if err != nil {
+		return nil, err
+	}
+
+	start := time.Now()
+	resp, err := client.Do(req)
+	if err != nil {
+		nc.check.IncrementCounter("collect_api_errors", cgm.Tags{
+			cgm.Tag{Category: "source", Value: release.NAME},
+			cgm.Tag{Category: "request", Value: "pod-list"},
+			cgm.Tag{Category: "target", Value: "api-server"},
+		})
+		return nil, err
+	}
+	defer resp.Body.Close()
+	nc.check.AddHistSample("collect_latency", cgm.Tags{
+		cgm.Tag{Category: "request", Value: "pod-list"},
+		cgm.Tag{Category: "target", Value: "api-server"},
+		cgm.Tag{Category: "source", Value: release.NAME},
+		cgm.Tag{Category: "units", Value: "milliseconds"},
+	}, float64(time.Since(start).Milliseconds()))
+
+	if resp.StatusCode != http.StatusOK {
+		nc.check.IncrementCounter("collect_api_errors", cgm.Tags{
+			cgm.Tag{Category: "source", Value: release.NAME},
+			cgm.Tag{Category: "request", Value: "pod-list"},
+			cgm.Tag{Category: "target", Value: "api-server"},
+			cgm.Tag{Category: "code", Value: fmt.Sprintf("%d", resp.StatusCode)},
+		})
+		data, err := ioutil.ReadAll(resp.Body)
+		if err != nil {
+			return nil, err
+		}
+		return nil, errors.Errorf("error from api %s (%s)", resp.Status, string(data))
+	}
+
+	var pods k8s.PodList
+	if err := json.NewDecoder(resp.Body).Decode(&pods); err != nil {
+		return nil, err
+	}
+
+	selected := make(map[string]bool, len(pods.Items))
+	for _, pod := range pods.Items {
+		selected[pod.Metadata.Namespace+"/"+pod.Metadata.Name] = true
+	}
+
+	return selected, nil
+}
+
 type podSpec struct {
 	Metadata podMeta `json:"metadata"`
 }
@@ -667,7 +903,7 @@ func (nc *Collector) getPodLabels(ns string, name string) (bool, []string, error
 	defer client.CloseIdleConnections()
 
 	reqURL := nc.cfg.URL + "/api/v1/namespaces/" + ns + "/pods/" + name
-	req, err := k8s.NewAPIRequest(nc.cfg.BearerToken, reqURL)
+	req, err := k8s.NewAPIRequest(nc.cfg.Token(), reqURL)
 	if err != nil {
 		return collect, tags, err
 	}
@@ -722,6 +958,9 @@ func (nc *Collector) getPodLabels(ns string, name string) (bool, []string, error
 			}
 		}
 	}
+	if nc.podSelector != nil && !nc.podSelector.Matches(labels.Set(ps.Metadata.Labels)) {
+		collect = false
+	}
 
 	for k, v := range ps.Metadata.Labels {
 		tags = append(tags, k+":"+v)
@@ -39,6 +39,10 @@ func (nc *Collector) queueMemory(dest map[string]circonus.MetricSample, stats *m
 		}
 		_ = nc.check.QueueMetricSample(dest, used, circonus.MetricTypeUint64, streamTags, parentMeasurementTags, stats.UsageBytes, nc.ts)
 		_ = nc.check.QueueMetricSample(dest, workingSet, circonus.MetricTypeUint64, streamTags, parentMeasurementTags, stats.WorkingSetBytes, nc.ts)
+		if nc.windows {
+			// windows nodes don't have rss or page faults
+			return
+		}
 		_ = nc.check.QueueMetricSample(dest, rss, circonus.MetricTypeUint64, streamTags, parentMeasurementTags, stats.RSSBytes, nc.ts)
 	}
 	{ // units:faults
@@ -100,7 +104,7 @@ func (nc *Collector) queueBaseFS(dest map[string]circonus.MetricSample, stats *f
 		_ = nc.check.QueueMetricSample(dest, free, circonus.MetricTypeUint64, streamTags, parentMeasurementTags, stats.AvailableBytes, nc.ts)
 		_ = nc.check.QueueMetricSample(dest, used, circonus.MetricTypeUint64, streamTags, parentMeasurementTags, stats.UsedBytes, nc.ts)
 	}
-	{ // units:inodes
+	if !nc.windows { // units:inodes (windows filesystems don't have inodes)
 		var streamTags []string
 		streamTags = append(streamTags, parentStreamTags...)
 		streamTags = append(streamTags, "units:inodes")
@@ -169,6 +173,26 @@ func (nc *Collector) queueVolume(dest map[string]circonus.MetricSample, stats *v
 	nc.queueBaseFS(dest, &stats.fs, baseStreamTags, parentMeasurementTags)
 }
 
+func (nc *Collector) queuePVC(dest map[string]circonus.MetricSample, stats *fs, parentStreamTags []string, parentMeasurementTags []string) {
+	var baseStreamTags []string
+	if len(parentStreamTags) > 0 {
+		baseStreamTags = make([]string, len(parentStreamTags))
+		copy(baseStreamTags, parentStreamTags)
+	}
+	baseStreamTags = append(baseStreamTags, "resource:pvc")
+	nc.queueBaseFS(dest, stats, baseStreamTags, parentMeasurementTags)
+
+	{ // units:percent (inodes)
+		if stats.InodesUsed > 0 && stats.Inodes > 0 {
+			var streamTags []string
+			streamTags = append(streamTags, baseStreamTags...)
+			streamTags = append(streamTags, "units:percent")
+			usedPct := ((float64(stats.InodesUsed) / float64(stats.Inodes)) * 100)
+			_ = nc.check.QueueMetricSample(dest, "inodes_used", circonus.MetricTypeFloat64, streamTags, parentMeasurementTags, usedPct, nc.ts)
+		}
+	}
+}
+
 func (nc *Collector) queueRlimit(dest map[string]circonus.MetricSample, stats *rlimit, parentStreamTags []string, parentMeasurementTags []string) {
 	maxPID := "maxPID"
 	curProc := "curProc"
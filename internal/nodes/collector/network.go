@@ -0,0 +1,208 @@
+// Copyright © 2019 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+package collector
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"time"
+
+	cgm "github.com/circonus-labs/circonus-gometrics/v3"
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/circonus"
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/k8s"
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/release"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/prometheus/common/expfmt"
+)
+
+// rootCgroup is the cadvisor id of the node level (root cgroup) stats
+const rootCgroup = "/"
+
+type interfaceSaturation struct {
+	rxPackets, txPackets uint64
+	rxDropped, txDropped uint64
+}
+
+type networkSaturation struct {
+	interfaces map[string]*interfaceSaturation
+	sockets    uint64
+	hasSockets bool
+}
+
+// netSaturation emits node level network packets and drops per interface and
+// the open socket count, from the root cgroup stats in the node /metrics/cadvisor endpoint
+func (nc *Collector) netSaturation(parentStreamTags []string, parentMeasurementTags []string) {
+	if nc.done() {
+		return
+	}
+
+	client, err := k8s.NewAPIClient(nc.tlsConfig, nc.apiTimelimit)
+	if err != nil {
+		nc.log.Error().Err(err).Msg("abandoning network saturation collection")
+		return
+	}
+	defer client.CloseIdleConnections()
+
+	reqURL := nc.cfg.URL + nc.node.Metadata.SelfLink + "/proxy/metrics/cadvisor"
+	req, err := k8s.NewAPIRequest(nc.cfg.Token(), reqURL)
+	if err != nil {
+		nc.log.Error().Err(err).Msg("abandoning network saturation collection")
+		return
+	}
+
+	start := time.Now()
+	resp, err := client.Do(req)
+	if err != nil {
+		nc.check.IncrementCounter("collect_api_errors", cgm.Tags{
+			cgm.Tag{Category: "source", Value: release.NAME},
+			cgm.Tag{Category: "request", Value: "metrics/cadvisor"},
+			cgm.Tag{Category: "proxy", Value: "api-server"},
+			cgm.Tag{Category: "target", Value: "kubelet"},
+		})
+		nc.log.Error().Err(err).Str("url", reqURL).Msg("node network saturation")
+		return
+	}
+	nc.check.AddHistSample("collect_latency", cgm.Tags{
+		cgm.Tag{Category: "request", Value: "metrics/cadvisor"},
+		cgm.Tag{Category: "proxy", Value: "api-server"},
+		cgm.Tag{Category: "target", Value: "kubelet"},
+		cgm.Tag{Category: "units", Value: "milliseconds"},
+	}, float64(time.Since(start).Milliseconds()))
+
+	defer resp.Body.Close()
+	if nc.done() {
+		return
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		nc.check.IncrementCounter("collect_api_errors", cgm.Tags{
+			cgm.Tag{Category: "source", Value: release.NAME},
+			cgm.Tag{Category: "request", Value: "metrics/cadvisor"},
+			cgm.Tag{Category: "proxy", Value: "api-server"},
+			cgm.Tag{Category: "target", Value: "kubelet"},
+			cgm.Tag{Category: "code", Value: fmt.Sprintf("%d", resp.StatusCode)},
+		})
+		data, err := ioutil.ReadAll(resp.Body)
+		if err != nil {
+			nc.log.Error().Err(err).Str("url", reqURL).Msg("reading response")
+			return
+		}
+		nc.log.Warn().Str("url", reqURL).Str("status", resp.Status).RawJSON("response", data).Msg("error from API server")
+		return
+	}
+
+	stats, err := parseNetworkSaturation(resp.Body)
+	if err != nil {
+		nc.log.Error().Err(err).Msg("parsing node network saturation")
+		return
+	}
+
+	metrics := make(map[string]circonus.MetricSample)
+	for name, iface := range stats.interfaces {
+		baseStreamTags := make([]string, 0, len(parentStreamTags)+3)
+		baseStreamTags = append(baseStreamTags, parentStreamTags...)
+		baseStreamTags = append(baseStreamTags, "resource:network", "interface:"+name)
+		{ // units:packets
+			streamTags := append(append([]string{}, baseStreamTags...), "units:packets")
+			_ = nc.check.QueueMetricSample(metrics, "rx", circonus.MetricTypeUint64, streamTags, parentMeasurementTags, iface.rxPackets, nc.ts)
+			_ = nc.check.QueueMetricSample(metrics, "tx", circonus.MetricTypeUint64, streamTags, parentMeasurementTags, iface.txPackets, nc.ts)
+		}
+		{ // units:drops
+			streamTags := append(append([]string{}, baseStreamTags...), "units:drops")
+			_ = nc.check.QueueMetricSample(metrics, "rx", circonus.MetricTypeUint64, streamTags, parentMeasurementTags, iface.rxDropped, nc.ts)
+			_ = nc.check.QueueMetricSample(metrics, "tx", circonus.MetricTypeUint64, streamTags, parentMeasurementTags, iface.txDropped, nc.ts)
+		}
+	}
+	if stats.hasSockets {
+		var streamTags []string
+		streamTags = append(streamTags, parentStreamTags...)
+		streamTags = append(streamTags, "resource:network", "units:sockets")
+		_ = nc.check.QueueMetricSample(metrics, "sockets", circonus.MetricTypeUint64, streamTags, parentMeasurementTags, stats.sockets, nc.ts)
+	}
+
+	if len(metrics) == 0 {
+		nc.log.Warn().Msg("no network saturation telemetry to submit")
+		return
+	}
+	if err := nc.check.SubmitQueue(nc.ctx, metrics, nc.log.With().Str("type", "network_saturation").Logger()); err != nil {
+		nc.log.Warn().Err(err).Msg("submitting metrics")
+	}
+}
+
+// parseNetworkSaturation extracts the root cgroup network packet/drop counters and socket count
+// from cadvisor prometheus text metrics (network errors are emitted from /stats/summary)
+func parseNetworkSaturation(data io.Reader) (*networkSaturation, error) {
+	var parser expfmt.TextParser
+	families, err := parser.TextToMetricFamilies(data)
+	if err != nil {
+		return nil, err
+	}
+
+	stats := &networkSaturation{interfaces: make(map[string]*interfaceSaturation)}
+
+	counters := map[string]func(*interfaceSaturation, uint64){
+		"container_network_receive_packets_total":          func(i *interfaceSaturation, v uint64) { i.rxPackets = v },
+		"container_network_transmit_packets_total":         func(i *interfaceSaturation, v uint64) { i.txPackets = v },
+		"container_network_receive_packets_dropped_total":  func(i *interfaceSaturation, v uint64) { i.rxDropped = v },
+		"container_network_transmit_packets_dropped_total": func(i *interfaceSaturation, v uint64) { i.txDropped = v },
+	}
+
+	for name, set := range counters {
+		mf, ok := families[name]
+		if !ok {
+			continue
+		}
+		for _, m := range mf.Metric {
+			if labelValue(m, "id") != rootCgroup {
+				continue
+			}
+			iface := labelValue(m, "interface")
+			if iface == "" {
+				continue
+			}
+			is, ok := stats.interfaces[iface]
+			if !ok {
+				is = &interfaceSaturation{}
+				stats.interfaces[iface] = is
+			}
+			set(is, uint64(metricValue(m)))
+		}
+	}
+
+	if mf, ok := families["container_sockets"]; ok {
+		for _, m := range mf.Metric {
+			if labelValue(m, "id") == rootCgroup {
+				stats.sockets = uint64(metricValue(m))
+				stats.hasSockets = true
+			}
+		}
+	}
+
+	return stats, nil
+}
+
+// labelValue returns the value of the named label, blank if not found
+func labelValue(m *dto.Metric, name string) string {
+	for _, l := range m.GetLabel() {
+		if l.GetName() == name {
+			return l.GetValue()
+		}
+	}
+	return ""
+}
+
+// metricValue returns the counter or gauge value of a metric
+func metricValue(m *dto.Metric) float64 {
+	if m.Counter != nil {
+		return m.Counter.GetValue()
+	}
+	if m.Gauge != nil {
+		return m.Gauge.GetValue()
+	}
+	return m.GetUntyped().GetValue()
+}
@@ -0,0 +1,69 @@
+// Copyright © 2019 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+package collector
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseNetworkSaturation(t *testing.T) {
+	data := `# TYPE container_network_receive_packets_total counter
+container_network_receive_packets_total{container="",id="/",interface="eth0",name="",namespace="",pod=""} 1000 1588000000000
+container_network_receive_packets_total{container="",id="/kubepods/pod1",interface="eth0",name="",namespace="default",pod="foo"} 10 1588000000000
+# TYPE container_network_receive_packets_dropped_total counter
+container_network_receive_packets_dropped_total{container="",id="/",interface="eth0",name="",namespace="",pod=""} 5 1588000000000
+container_network_receive_packets_dropped_total{container="",id="/",interface="eth1",name="",namespace="",pod=""} 7 1588000000000
+# TYPE container_network_transmit_packets_dropped_total counter
+container_network_transmit_packets_dropped_total{container="",id="/",interface="eth0",name="",namespace="",pod=""} 3 1588000000000
+# TYPE container_sockets gauge
+container_sockets{container="",id="/",image="",name="",namespace="",pod=""} 42 1588000000000
+`
+
+	stats, err := parseNetworkSaturation(strings.NewReader(data))
+	if err != nil {
+		t.Fatalf("unexpected error (%s)", err)
+	}
+
+	if len(stats.interfaces) != 2 {
+		t.Fatalf("interfaces = %d, want 2", len(stats.interfaces))
+	}
+	eth0 := stats.interfaces["eth0"]
+	if eth0 == nil {
+		t.Fatal("expected eth0")
+	}
+	if eth0.rxPackets != 1000 || eth0.rxDropped != 5 || eth0.txDropped != 3 {
+		t.Errorf("eth0 = %+v, want rxPackets 1000, rxDropped 5, txDropped 3", *eth0)
+	}
+	if eth1 := stats.interfaces["eth1"]; eth1 == nil || eth1.rxDropped != 7 {
+		t.Errorf("eth1 = %+v, want rxDropped 7", eth1)
+	}
+	if !stats.hasSockets || stats.sockets != 42 {
+		t.Errorf("sockets = %d (%t), want 42", stats.sockets, stats.hasSockets)
+	}
+}
+
+func TestCadvisorPodFilter(t *testing.T) {
+	filter := cadvisorPodFilter(map[string]bool{"team-a/web": true})
+
+	tests := []struct {
+		name   string
+		labels map[string]string
+		want   bool
+	}{
+		{"root cgroup", map[string]string{"id": "/", "namespace": "", "pod": ""}, true},
+		{"selected pod", map[string]string{"namespace": "team-a", "pod": "web"}, true},
+		{"other pod", map[string]string{"namespace": "team-b", "pod": "web"}, false},
+		{"selected pod_name", map[string]string{"namespace": "team-a", "pod_name": "web"}, true},
+		{"other pod_name", map[string]string{"namespace": "team-a", "pod_name": "db"}, false},
+	}
+
+	for _, tt := range tests {
+		if got := filter(tt.labels); got != tt.want {
+			t.Errorf("%s: expected %v, got %v", tt.name, tt.want, got)
+		}
+	}
+}
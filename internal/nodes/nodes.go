@@ -14,6 +14,7 @@ import (
 	"io/ioutil"
 	"net/http"
 	"net/url"
+	"regexp"
 	"sync"
 	"time"
 
@@ -26,14 +27,17 @@ import (
 	"github.com/circonus-labs/circonus-kubernetes-agent/internal/release"
 	"github.com/pkg/errors"
 	"github.com/rs/zerolog"
+	"k8s.io/apimachinery/pkg/labels"
 )
 
 type Nodes struct {
-	check        *circonus.Check
-	config       *config.Cluster
-	log          zerolog.Logger
-	running      bool
-	apiTimelimit time.Duration
+	check          *circonus.Check
+	config         *config.Cluster
+	log            zerolog.Logger
+	cadvisorFilter *regexp.Regexp
+	podSelector    labels.Selector
+	running        bool
+	apiTimelimit   time.Duration
 	sync.Mutex
 }
 
@@ -68,6 +72,20 @@ func New(cfg *config.Cluster, parentLog zerolog.Logger, check *circonus.Check) (
 		nodes.apiTimelimit = v
 	}
 
+	if cfg.EnableCadvisorMetrics && cfg.CadvisorMetricsFilter != "" {
+		rx, err := regexp.Compile(cfg.CadvisorMetricsFilter)
+		if err != nil {
+			return nil, errors.Wrap(err, "compiling cadvisor metrics filter")
+		}
+		nodes.cadvisorFilter = rx
+	}
+
+	sel, err := k8s.PodSelector(cfg)
+	if err != nil {
+		return nil, err
+	}
+	nodes.podSelector = sel
+
 	return nodes, nil
 }
 
@@ -105,6 +123,10 @@ func (n *Nodes) Collect(ctx context.Context, tlsConfig *tls.Config, ts *time.Tim
 		return
 	}
 
+	if n.config.EnableNodeVersions {
+		n.versions(ctx, nodes, ts) // all nodes, including not ready nodes being upgraded
+	}
+
 	maxCollectors := int(n.config.NodePoolSize)
 	nodeQueue := make(chan *collector.Collector)
 	var wg sync.WaitGroup
@@ -139,7 +161,7 @@ func (n *Nodes) Collect(ctx context.Context, tlsConfig *tls.Config, ts *time.Tim
 				continue
 			}
 			if cond.Status == "True" {
-				nc, err := collector.New(n.config, &node, n.log, n.check, n.apiTimelimit)
+				nc, err := collector.New(n.config, &node, n.log, n.check, n.apiTimelimit, n.cadvisorFilter, n.podSelector)
 				if err != nil {
 					n.log.Error().Err(err).Str("node", node.Metadata.Name).Msg("skipping...")
 					break
@@ -191,7 +213,7 @@ func (n *Nodes) nodeList(tlsConfig *tls.Config) (*k8s.NodeList, error) {
 	defer client.CloseIdleConnections()
 
 	reqURL := u.String()
-	req, err := k8s.NewAPIRequest(n.config.BearerToken, reqURL)
+	req, err := k8s.NewAPIRequest(n.config.Token(), reqURL)
 	if err != nil {
 		return nil, errors.Wrap(err, "node list req")
 	}
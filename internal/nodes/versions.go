@@ -0,0 +1,78 @@
+// Copyright © 2019 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+package nodes
+
+import (
+	"context"
+	"time"
+
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/circonus"
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/k8s"
+)
+
+// versionComponents are the node info versions tracked, the per node metric name,
+// the component tag used for node counts, and the node info value
+var versionComponents = []struct {
+	name      string
+	component string
+	version   func(k8s.NodeInfo) string
+}{
+	{"kubelet_version", "kubelet", func(ni k8s.NodeInfo) string { return ni.KubeletVersion }},
+	{"container_runtime_version", "container_runtime", func(ni k8s.NodeInfo) string { return ni.ContainerRuntimeVersion }},
+	{"kernel_version", "kernel", func(ni k8s.NodeInfo) string { return ni.KernelVersion }},
+	{"os_image", "os_image", func(ni k8s.NodeInfo) string { return ni.OSImage }},
+}
+
+// versions emits per node text metrics for each version and counts of nodes per version,
+// so version skew during upgrades can be tracked
+func (n *Nodes) versions(ctx context.Context, nodes *k8s.NodeList, ts *time.Time) {
+	metrics := make(map[string]circonus.MetricSample)
+
+	for _, node := range nodes.Items {
+		streamTags := []string{"source:kubelet", "node:" + node.Metadata.Name}
+		for _, vc := range versionComponents {
+			if v := vc.version(node.Status.NodeInfo); v != "" {
+				_ = n.check.QueueMetricSample(metrics, vc.name, circonus.MetricTypeString, streamTags, []string{}, v, ts)
+			}
+		}
+	}
+
+	for component, counts := range versionCounts(nodes.Items) {
+		for version, count := range counts {
+			streamTags := []string{
+				"source:kubelet",
+				"component:" + component,
+				"version:" + version,
+			}
+			_ = n.check.QueueMetricSample(metrics, "node_versions", circonus.MetricTypeUint64, streamTags, []string{}, count, ts)
+		}
+	}
+
+	if len(metrics) == 0 {
+		return
+	}
+	if err := n.check.SubmitQueue(ctx, metrics, n.log.With().Str("type", "node-versions").Logger()); err != nil {
+		n.log.Warn().Err(err).Msg("submitting metrics")
+	}
+}
+
+// versionCounts returns the number of nodes per version of each component (e.g. kubelet)
+func versionCounts(nodes []k8s.Node) map[string]map[string]uint64 {
+	counts := make(map[string]map[string]uint64)
+	for _, node := range nodes {
+		for _, vc := range versionComponents {
+			v := vc.version(node.Status.NodeInfo)
+			if v == "" {
+				continue
+			}
+			if counts[vc.component] == nil {
+				counts[vc.component] = make(map[string]uint64)
+			}
+			counts[vc.component][v]++
+		}
+	}
+	return counts
+}
@@ -0,0 +1,39 @@
+// Copyright © 2019 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+package nodes
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/k8s"
+)
+
+func TestVersionCounts(t *testing.T) {
+	node := func(kubelet, runtime string) k8s.Node {
+		return k8s.Node{Status: k8s.NodeStatus{NodeInfo: k8s.NodeInfo{
+			KubeletVersion:          kubelet,
+			ContainerRuntimeVersion: runtime,
+			KernelVersion:           "5.10.0",
+			OSImage:                 "Ubuntu 22.04.3 LTS",
+		}}}
+	}
+	nodes := []k8s.Node{
+		node("v1.27.4", "containerd://1.6.20"),
+		node("v1.27.4", "containerd://1.6.20"),
+		node("v1.28.1", "containerd://1.7.2"),
+		node("v1.28.1", ""),
+	}
+	want := map[string]map[string]uint64{
+		"kubelet":           {"v1.27.4": 2, "v1.28.1": 2},
+		"container_runtime": {"containerd://1.6.20": 2, "containerd://1.7.2": 1},
+		"kernel":            {"5.10.0": 4},
+		"os_image":          {"Ubuntu 22.04.3 LTS": 4},
+	}
+	if got := versionCounts(nodes); !reflect.DeepEqual(got, want) {
+		t.Errorf("versionCounts() = %v, want %v", got, want)
+	}
+}
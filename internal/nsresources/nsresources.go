@@ -0,0 +1,319 @@
+// Copyright © 2019 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+// Package nsresources is the namespace resource aggregation collector
+package nsresources
+
+import (
+	"context"
+	"crypto/tls"
+	"sync"
+	"time"
+
+	cgm "github.com/circonus-labs/circonus-gometrics/v3"
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/circonus"
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/config"
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/config/defaults"
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/k8s"
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/release"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/kubernetes"
+)
+
+var podMetricsResource = schema.GroupVersionResource{Group: "metrics.k8s.io", Version: "v1beta1", Resource: "pods"}
+
+type NSResources struct {
+	config       *config.Cluster
+	check        *circonus.Check
+	log          zerolog.Logger
+	clientset    kubernetes.Interface
+	dynamic      dynamic.Interface
+	running      bool
+	apiTimelimit time.Duration
+	sync.Mutex
+	ts *time.Time
+}
+
+// NOTES:
+// Pod cpu/memory requests and limits (of pods not yet completed) and live usage are summed per
+// namespace, so chargeback dashboards do not need per pod streams. The metrics-server collector
+// (internal/ms) forwards the metrics-server's own /metrics, live usage is read from the
+// metrics.k8s.io pod metrics api served by metrics-server. If metrics-server is not installed
+// only requests and limits are emitted.
+
+func New(cfg *config.Cluster, parentLog zerolog.Logger, check *circonus.Check) (*NSResources, error) {
+	if cfg == nil {
+		return nil, errors.New("invalid cluster config (nil)")
+	}
+	if check == nil {
+		return nil, errors.New("invalid check (nil)")
+	}
+
+	nr := &NSResources{
+		config: cfg,
+		check:  check,
+		log:    parentLog.With().Str("collector", "namespace-resources").Logger(),
+	}
+
+	if cfg.APITimelimit != "" {
+		v, err := time.ParseDuration(cfg.APITimelimit)
+		if err != nil {
+			nr.log.Error().Err(err).Msg("parsing api timelimit, using default")
+		} else {
+			nr.apiTimelimit = v
+		}
+	}
+
+	if nr.apiTimelimit == time.Duration(0) {
+		v, err := time.ParseDuration(defaults.K8SAPITimelimit)
+		if err != nil {
+			nr.log.Fatal().Err(err).Msg("parsing DEFAULT api timelimit")
+		}
+		nr.apiTimelimit = v
+	}
+
+	restCfg, err := k8s.RESTConfig(cfg)
+	if err != nil {
+		return nil, errors.Wrap(err, "rest config")
+	}
+	restCfg.Timeout = nr.apiTimelimit
+	clientset, err := kubernetes.NewForConfig(restCfg)
+	if err != nil {
+		return nil, errors.Wrap(err, "clientset")
+	}
+	nr.clientset = clientset
+	dc, err := dynamic.NewForConfig(restCfg)
+	if err != nil {
+		return nil, errors.Wrap(err, "dynamic client")
+	}
+	nr.dynamic = dc
+
+	return nr, nil
+}
+
+func (nr *NSResources) ID() string {
+	return "namespace-resources"
+}
+
+// Collect per namespace resource requests, limits, and usage
+func (nr *NSResources) Collect(ctx context.Context, _ *tls.Config, ts *time.Time) {
+	nr.Lock()
+	if nr.running {
+		nr.log.Warn().Msg("already running")
+		nr.Unlock()
+		return
+	}
+	nr.running = true
+	nr.ts = ts
+	nr.Unlock()
+
+	defer func() {
+		if r := recover(); r != nil {
+			nr.log.Error().Interface("panic", r).Msg("recover")
+			nr.Lock()
+			nr.running = false
+			nr.Unlock()
+		}
+	}()
+
+	collectStart := time.Now()
+
+	nr.namespaceMetrics(ctx)
+
+	nr.check.AddHistSample("collect_latency", cgm.Tags{
+		cgm.Tag{Category: "source", Value: release.NAME},
+		cgm.Tag{Category: "op", Value: "collect_namespace-resources"},
+		cgm.Tag{Category: "units", Value: "milliseconds"},
+	}, float64(time.Since(collectStart).Milliseconds()))
+	nr.log.Debug().Str("duration", time.Since(collectStart).String()).Msg("namespace-resources collect end")
+	nr.Lock()
+	nr.running = false
+	nr.Unlock()
+}
+
+// resources is cpu (cores) and memory (bytes)
+type resources struct {
+	cpu    float64
+	memory float64
+}
+
+func (r *resources) add(list corev1.ResourceList) {
+	if q, ok := list[corev1.ResourceCPU]; ok {
+		r.cpu += float64(q.MilliValue()) / 1000
+	}
+	if q, ok := list[corev1.ResourceMemory]; ok {
+		r.memory += float64(q.Value())
+	}
+}
+
+// namespaceTotals is the resource roll-up of a namespace
+type namespaceTotals struct {
+	pods     uint64
+	requests resources
+	limits   resources
+	usage    resources
+	hasUsage bool
+}
+
+// namespaceMetrics emits the per namespace pod count, requests, limits, and usage
+func (nr *NSResources) namespaceMetrics(ctx context.Context) {
+	pods, err := nr.clientset.CoreV1().Pods(metav1.NamespaceAll).List(metav1.ListOptions{LabelSelector: nr.config.PodSelector})
+	if err != nil {
+		nr.apiError("pod-list")
+		nr.log.Error().Err(err).Msg("listing pods")
+		return
+	}
+
+	namespaces := make(map[string]*namespaceTotals)
+	for i := range pods.Items {
+		pod := &pods.Items[i]
+		if pod.Status.Phase == corev1.PodSucceeded || pod.Status.Phase == corev1.PodFailed {
+			continue // completed pods do not hold resources
+		}
+		totals := totalsFor(namespaces, pod.Namespace)
+		totals.pods++
+		requests, limits := podResources(pod)
+		totals.requests.cpu += requests.cpu
+		totals.requests.memory += requests.memory
+		totals.limits.cpu += limits.cpu
+		totals.limits.memory += limits.memory
+	}
+
+	podMetrics, err := nr.dynamic.Resource(podMetricsResource).Namespace(metav1.NamespaceAll).List(metav1.ListOptions{LabelSelector: nr.config.PodSelector})
+	if err != nil {
+		nr.apiError("pod-metrics-list")
+		nr.log.Warn().Err(err).Msg("listing pod metrics, usage not available (is metrics-server installed?)")
+	} else {
+		for i := range podMetrics.Items {
+			usage, err := podUsage(&podMetrics.Items[i])
+			if err != nil {
+				nr.log.Warn().Err(err).Str("namespace", podMetrics.Items[i].GetNamespace()).Str("pod", podMetrics.Items[i].GetName()).Msg("pod usage")
+				continue
+			}
+			totals := totalsFor(namespaces, podMetrics.Items[i].GetNamespace())
+			totals.usage.cpu += usage.cpu
+			totals.usage.memory += usage.memory
+			totals.hasUsage = true
+		}
+	}
+
+	metrics := make(map[string]circonus.MetricSample)
+	for ns, totals := range namespaces {
+		baseStreamTags := []string{
+			"source:namespace-resources",
+			"source_type:namespaces",
+			"namespace:" + ns,
+		}
+		_ = nr.check.QueueMetricSample(metrics, "namespace_pods", circonus.MetricTypeUint64, baseStreamTags, []string{}, totals.pods, nr.ts)
+		for _, rv := range []struct {
+			name  string
+			value resources
+			ok    bool
+		}{
+			{"namespace_requests", totals.requests, true},
+			{"namespace_limits", totals.limits, true},
+			{"namespace_usage", totals.usage, totals.hasUsage},
+		} {
+			if !rv.ok {
+				continue
+			}
+			cpuTags := append(append([]string{}, baseStreamTags...), "resource:cpu", "units:cores")
+			memTags := append(append([]string{}, baseStreamTags...), "resource:memory", "units:bytes")
+			_ = nr.check.QueueMetricSample(metrics, rv.name, circonus.MetricTypeFloat64, cpuTags, []string{}, rv.value.cpu, nr.ts)
+			_ = nr.check.QueueMetricSample(metrics, rv.name, circonus.MetricTypeFloat64, memTags, []string{}, rv.value.memory, nr.ts)
+		}
+	}
+
+	if len(metrics) == 0 {
+		return
+	}
+	if err := nr.check.SubmitQueue(ctx, metrics, nr.log.With().Str("type", "namespace-resources").Logger()); err != nil {
+		nr.log.Warn().Err(err).Msg("submitting metrics")
+	}
+}
+
+func (nr *NSResources) apiError(request string) {
+	nr.check.IncrementCounter("collect_api_errors", cgm.Tags{
+		cgm.Tag{Category: "source", Value: release.NAME},
+		cgm.Tag{Category: "request", Value: request},
+		cgm.Tag{Category: "target", Value: "api-server"},
+	})
+}
+
+// totalsFor returns the totals of a namespace, adding them if needed
+func totalsFor(namespaces map[string]*namespaceTotals, ns string) *namespaceTotals {
+	totals, ok := namespaces[ns]
+	if !ok {
+		totals = &namespaceTotals{}
+		namespaces[ns] = totals
+	}
+	return totals
+}
+
+// podResources returns the effective pod requests and limits, the larger of the sum of the
+// containers or the largest init container (which run one at a time), plus the pod overhead
+func podResources(pod *corev1.Pod) (resources, resources) {
+	var requests, limits resources
+	for _, c := range pod.Spec.Containers {
+		requests.add(c.Resources.Requests)
+		limits.add(c.Resources.Limits)
+	}
+	for _, c := range pod.Spec.InitContainers {
+		var initRequests, initLimits resources
+		initRequests.add(c.Resources.Requests)
+		initLimits.add(c.Resources.Limits)
+		requests = maxResources(requests, initRequests)
+		limits = maxResources(limits, initLimits)
+	}
+	requests.add(pod.Spec.Overhead)
+	limits.add(pod.Spec.Overhead)
+	return requests, limits
+}
+
+func maxResources(a, b resources) resources {
+	if b.cpu > a.cpu {
+		a.cpu = b.cpu
+	}
+	if b.memory > a.memory {
+		a.memory = b.memory
+	}
+	return a
+}
+
+// podUsage returns the sum of the container usage in a metrics.k8s.io PodMetrics
+func podUsage(pm *unstructured.Unstructured) (resources, error) {
+	var usage resources
+	containers, _, err := unstructured.NestedSlice(pm.Object, "containers")
+	if err != nil {
+		return usage, errors.Wrap(err, "containers")
+	}
+	for _, c := range containers {
+		cm, ok := c.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		u, _, err := unstructured.NestedStringMap(cm, "usage")
+		if err != nil {
+			return usage, errors.Wrap(err, "container usage")
+		}
+		list := corev1.ResourceList{}
+		for name, value := range u {
+			q, err := resource.ParseQuantity(value)
+			if err != nil {
+				return usage, errors.Wrapf(err, "parsing %s usage (%s)", name, value)
+			}
+			list[corev1.ResourceName(name)] = q
+		}
+		usage.add(list)
+	}
+	return usage, nil
+}
@@ -0,0 +1,77 @@
+// Copyright © 2019 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+package nsresources
+
+import (
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func resourceList(cpu, memory string) corev1.ResourceList {
+	return corev1.ResourceList{
+		corev1.ResourceCPU:    resource.MustParse(cpu),
+		corev1.ResourceMemory: resource.MustParse(memory),
+	}
+}
+
+func TestPodResources(t *testing.T) {
+	pod := &corev1.Pod{
+		Spec: corev1.PodSpec{
+			Containers: []corev1.Container{
+				{Resources: corev1.ResourceRequirements{Requests: resourceList("250m", "64Mi"), Limits: resourceList("500m", "128Mi")}},
+				{Resources: corev1.ResourceRequirements{Requests: resourceList("250m", "64Mi")}},
+			},
+			InitContainers: []corev1.Container{
+				{Resources: corev1.ResourceRequirements{Requests: resourceList("1", "32Mi")}},
+			},
+			Overhead: resourceList("100m", "1Mi"),
+		},
+	}
+
+	requests, limits := podResources(pod)
+	if requests.cpu != 1.1 {
+		t.Errorf("requests cpu = %f, want 1.1 (init container + overhead)", requests.cpu)
+	}
+	if requests.memory != 129*1024*1024 {
+		t.Errorf("requests memory = %f, want %d", requests.memory, 129*1024*1024)
+	}
+	if limits.cpu != 0.6 {
+		t.Errorf("limits cpu = %f, want 0.6", limits.cpu)
+	}
+	if limits.memory != 129*1024*1024 {
+		t.Errorf("limits memory = %f, want %d", limits.memory, 129*1024*1024)
+	}
+}
+
+func TestPodUsage(t *testing.T) {
+	pm := &unstructured.Unstructured{Object: map[string]interface{}{
+		"containers": []interface{}{
+			map[string]interface{}{"name": "a", "usage": map[string]interface{}{"cpu": "500m", "memory": "10Mi"}},
+			map[string]interface{}{"name": "b", "usage": map[string]interface{}{"cpu": "250000000n", "memory": "1024Ki"}},
+		},
+	}}
+
+	usage, err := podUsage(pm)
+	if err != nil {
+		t.Fatalf("unexpected error (%s)", err)
+	}
+	if usage.cpu != 0.75 {
+		t.Errorf("usage cpu = %f, want 0.75", usage.cpu)
+	}
+	if usage.memory != 11*1024*1024 {
+		t.Errorf("usage memory = %f, want %d", usage.memory, 11*1024*1024)
+	}
+
+	pm.Object["containers"] = []interface{}{
+		map[string]interface{}{"name": "a", "usage": map[string]interface{}{"cpu": "bad"}},
+	}
+	if _, err := podUsage(pm); err == nil {
+		t.Error("expected error for invalid quantity")
+	}
+}
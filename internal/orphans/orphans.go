@@ -0,0 +1,269 @@
+// Copyright © 2019 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+// Package orphans is the orphaned and lingering resource (cleanup candidate) collector
+package orphans
+
+import (
+	"context"
+	"crypto/tls"
+	"sync"
+	"time"
+
+	cgm "github.com/circonus-labs/circonus-gometrics/v3"
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/circonus"
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/config"
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/config/defaults"
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/k8s"
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/release"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// mirrorPodAnnotation is set on the api-server mirror of a static pod, static
+// pods are managed by the kubelet and have no owner
+const mirrorPodAnnotation = "kubernetes.io/config.mirror"
+
+type Orphans struct {
+	config       *config.Cluster
+	check        *circonus.Check
+	log          zerolog.Logger
+	clientset    *kubernetes.Clientset
+	threshold    time.Duration
+	running      bool
+	apiTimelimit time.Duration
+	sync.Mutex
+	ts *time.Time
+}
+
+// NOTES:
+// Cleanup candidates are counted per namespace:
+//   orphaned_pods - pods without owner references (excluding static pods), not recreated if deleted or evicted
+//   lingering_pods - evicted and completed pods finished longer than the threshold ago
+//   empty_replicasets - replicasets with zero desired and current replicas (old deployment
+//                       revisions, retained up to the deployment revisionHistoryLimit)
+
+func New(cfg *config.Cluster, parentLog zerolog.Logger, check *circonus.Check) (*Orphans, error) {
+	if cfg == nil {
+		return nil, errors.New("invalid cluster config (nil)")
+	}
+	if check == nil {
+		return nil, errors.New("invalid check (nil)")
+	}
+
+	o := &Orphans{
+		config: cfg,
+		check:  check,
+		log:    parentLog.With().Str("collector", "orphans").Logger(),
+	}
+
+	threshold, err := time.ParseDuration(cfg.OrphansLingerThreshold)
+	if err != nil {
+		return nil, errors.Wrap(err, "parsing linger threshold")
+	}
+	o.threshold = threshold
+
+	if cfg.APITimelimit != "" {
+		v, err := time.ParseDuration(cfg.APITimelimit)
+		if err != nil {
+			o.log.Error().Err(err).Msg("parsing api timelimit, using default")
+		} else {
+			o.apiTimelimit = v
+		}
+	}
+
+	if o.apiTimelimit == time.Duration(0) {
+		v, err := time.ParseDuration(defaults.K8SAPITimelimit)
+		if err != nil {
+			o.log.Fatal().Err(err).Msg("parsing DEFAULT api timelimit")
+		}
+		o.apiTimelimit = v
+	}
+
+	restCfg, err := k8s.RESTConfig(cfg)
+	if err != nil {
+		return nil, errors.Wrap(err, "rest config")
+	}
+	restCfg.Timeout = o.apiTimelimit
+	clientset, err := kubernetes.NewForConfig(restCfg)
+	if err != nil {
+		return nil, errors.Wrap(err, "clientset")
+	}
+	o.clientset = clientset
+
+	return o, nil
+}
+
+func (o *Orphans) ID() string {
+	return "orphans"
+}
+
+// Collect orphaned and lingering resource counts
+func (o *Orphans) Collect(ctx context.Context, _ *tls.Config, ts *time.Time) {
+	o.Lock()
+	if o.running {
+		o.log.Warn().Msg("already running")
+		o.Unlock()
+		return
+	}
+	o.running = true
+	o.ts = ts
+	o.Unlock()
+
+	defer func() {
+		if r := recover(); r != nil {
+			o.log.Error().Interface("panic", r).Msg("recover")
+			o.Lock()
+			o.running = false
+			o.Unlock()
+		}
+	}()
+
+	collectStart := time.Now()
+
+	o.sweep(ctx)
+
+	o.check.AddHistSample("collect_latency", cgm.Tags{
+		cgm.Tag{Category: "source", Value: release.NAME},
+		cgm.Tag{Category: "op", Value: "collect_orphans"},
+		cgm.Tag{Category: "units", Value: "milliseconds"},
+	}, float64(time.Since(collectStart).Milliseconds()))
+	o.log.Debug().Str("duration", time.Since(collectStart).String()).Msg("orphans collect end")
+	o.Lock()
+	o.running = false
+	o.Unlock()
+}
+
+// namespaceCounts are the cleanup candidates in a namespace
+type namespaceCounts struct {
+	orphanedPods     uint64
+	evictedPods      uint64
+	completedPods    uint64
+	emptyReplicaSets uint64
+}
+
+// sweep counts the cleanup candidates in each namespace
+func (o *Orphans) sweep(ctx context.Context) {
+	pods, err := o.clientset.CoreV1().Pods(metav1.NamespaceAll).List(metav1.ListOptions{LabelSelector: o.config.PodSelector})
+	if err != nil {
+		o.apiError("pod-list")
+		o.log.Error().Err(err).Msg("listing pods")
+		return
+	}
+
+	rsets, err := o.clientset.AppsV1().ReplicaSets(metav1.NamespaceAll).List(metav1.ListOptions{})
+	if err != nil {
+		o.apiError("replicaset-list")
+		o.log.Error().Err(err).Msg("listing replicasets")
+		return
+	}
+
+	namespaces := make(map[string]*namespaceCounts)
+	counts := func(ns string) *namespaceCounts {
+		nc, ok := namespaces[ns]
+		if !ok {
+			nc = &namespaceCounts{}
+			namespaces[ns] = nc
+		}
+		return nc
+	}
+
+	now := time.Now()
+	for i := range pods.Items {
+		pod := &pods.Items[i]
+		if orphaned(pod) {
+			counts(pod.Namespace).orphanedPods++
+		}
+		switch lingering(pod, now, o.threshold) {
+		case "evicted":
+			counts(pod.Namespace).evictedPods++
+		case "completed":
+			counts(pod.Namespace).completedPods++
+		}
+	}
+
+	for _, rs := range rsets.Items {
+		if rs.Spec.Replicas != nil && *rs.Spec.Replicas == 0 && rs.Status.Replicas == 0 {
+			counts(rs.Namespace).emptyReplicaSets++
+		}
+	}
+
+	metrics := make(map[string]circonus.MetricSample)
+	for ns, nc := range namespaces {
+		streamTags := []string{
+			"source:orphans",
+			"namespace:" + ns,
+		}
+		_ = o.check.QueueMetricSample(metrics, "orphaned_pods", circonus.MetricTypeUint64, streamTags, []string{}, nc.orphanedPods, o.ts)
+		_ = o.check.QueueMetricSample(metrics, "lingering_pods", circonus.MetricTypeUint64, append(streamTags, "reason:evicted"), []string{}, nc.evictedPods, o.ts)
+		_ = o.check.QueueMetricSample(metrics, "lingering_pods", circonus.MetricTypeUint64, append(streamTags, "reason:completed"), []string{}, nc.completedPods, o.ts)
+		_ = o.check.QueueMetricSample(metrics, "empty_replicasets", circonus.MetricTypeUint64, streamTags, []string{}, nc.emptyReplicaSets, o.ts)
+	}
+
+	if len(metrics) == 0 {
+		return
+	}
+	if err := o.check.SubmitQueue(ctx, metrics, o.log.With().Str("type", "orphans").Logger()); err != nil {
+		o.log.Warn().Err(err).Msg("submitting metrics")
+	}
+}
+
+func (o *Orphans) apiError(request string) {
+	o.check.IncrementCounter("collect_api_errors", cgm.Tags{
+		cgm.Tag{Category: "source", Value: release.NAME},
+		cgm.Tag{Category: "request", Value: request},
+		cgm.Tag{Category: "target", Value: "api-server"},
+	})
+}
+
+// orphaned returns true if the pod has no owner (bare pods, excluding static pods)
+func orphaned(pod *corev1.Pod) bool {
+	if len(pod.OwnerReferences) > 0 {
+		return false
+	}
+	if _, ok := pod.Annotations[mirrorPodAnnotation]; ok {
+		return false
+	}
+	return pod.DeletionTimestamp == nil
+}
+
+// lingering returns evicted or completed if the pod finished longer than threshold ago,
+// blank if the pod is not a cleanup candidate
+func lingering(pod *corev1.Pod, now time.Time, threshold time.Duration) string {
+	reason := ""
+	switch {
+	case pod.Status.Phase == corev1.PodFailed && pod.Status.Reason == "Evicted":
+		reason = "evicted"
+	case pod.Status.Phase == corev1.PodSucceeded:
+		reason = "completed"
+	default:
+		return ""
+	}
+	if now.Sub(finishedAt(pod)) < threshold {
+		return ""
+	}
+	return reason
+}
+
+// finishedAt returns the time the last container terminated, the pod start
+// (or creation) time if no container termination time is available
+func finishedAt(pod *corev1.Pod) time.Time {
+	var finished time.Time
+	for _, cs := range pod.Status.ContainerStatuses {
+		if cs.State.Terminated != nil && cs.State.Terminated.FinishedAt.Time.After(finished) {
+			finished = cs.State.Terminated.FinishedAt.Time
+		}
+	}
+	if !finished.IsZero() {
+		return finished
+	}
+	if pod.Status.StartTime != nil {
+		return pod.Status.StartTime.Time
+	}
+	return pod.CreationTimestamp.Time
+}
@@ -0,0 +1,60 @@
+// Copyright © 2019 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+package orphans
+
+import (
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestOrphaned(t *testing.T) {
+	tests := []struct {
+		name string
+		meta metav1.ObjectMeta
+		want bool
+	}{
+		{"bare", metav1.ObjectMeta{Name: "debug"}, true},
+		{"owned", metav1.ObjectMeta{OwnerReferences: []metav1.OwnerReference{{Kind: "ReplicaSet", Name: "web-5d8f"}}}, false},
+		{"static", metav1.ObjectMeta{Annotations: map[string]string{mirrorPodAnnotation: "abc"}}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := orphaned(&corev1.Pod{ObjectMeta: tt.meta}); got != tt.want {
+				t.Errorf("orphaned() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLingering(t *testing.T) {
+	now := time.Now()
+	terminated := func(ago time.Duration) []corev1.ContainerStatus {
+		return []corev1.ContainerStatus{{State: corev1.ContainerState{
+			Terminated: &corev1.ContainerStateTerminated{FinishedAt: metav1.NewTime(now.Add(-ago))},
+		}}}
+	}
+	tests := []struct {
+		name   string
+		status corev1.PodStatus
+		want   string
+	}{
+		{"running", corev1.PodStatus{Phase: corev1.PodRunning}, ""},
+		{"completed old", corev1.PodStatus{Phase: corev1.PodSucceeded, ContainerStatuses: terminated(48 * time.Hour)}, "completed"},
+		{"completed recent", corev1.PodStatus{Phase: corev1.PodSucceeded, ContainerStatuses: terminated(time.Hour)}, ""},
+		{"evicted old", corev1.PodStatus{Phase: corev1.PodFailed, Reason: "Evicted", StartTime: &metav1.Time{Time: now.Add(-72 * time.Hour)}}, "evicted"},
+		{"failed old", corev1.PodStatus{Phase: corev1.PodFailed, ContainerStatuses: terminated(48 * time.Hour)}, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := lingering(&corev1.Pod{Status: tt.status}, now, 24*time.Hour); got != tt.want {
+				t.Errorf("lingering() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
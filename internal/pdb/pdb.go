@@ -0,0 +1,202 @@
+// Copyright © 2019 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+// Package pdb is the pod disruption budget status collector
+package pdb
+
+import (
+	"context"
+	"crypto/tls"
+	"sync"
+	"time"
+
+	cgm "github.com/circonus-labs/circonus-gometrics/v3"
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/circonus"
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/config"
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/config/defaults"
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/k8s"
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/release"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog"
+	policyv1beta1 "k8s.io/api/policy/v1beta1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+type PDB struct {
+	config       *config.Cluster
+	check        *circonus.Check
+	log          zerolog.Logger
+	clientset    kubernetes.Interface
+	running      bool
+	apiTimelimit time.Duration
+	sync.Mutex
+	ts *time.Time
+}
+
+// NOTES:
+// PodDisruptionBudgets are listed (policy/v1beta1) from the api-server, emitting current and
+// desired healthy pods, expected pods, disruptions allowed, and whether the budget currently
+// blocks voluntary disruptions (e.g. node drains), per budget and per namespace.
+
+func New(cfg *config.Cluster, parentLog zerolog.Logger, check *circonus.Check) (*PDB, error) {
+	if cfg == nil {
+		return nil, errors.New("invalid cluster config (nil)")
+	}
+	if check == nil {
+		return nil, errors.New("invalid check (nil)")
+	}
+
+	p := &PDB{
+		config: cfg,
+		check:  check,
+		log:    parentLog.With().Str("collector", "pdb").Logger(),
+	}
+
+	if cfg.APITimelimit != "" {
+		v, err := time.ParseDuration(cfg.APITimelimit)
+		if err != nil {
+			p.log.Error().Err(err).Msg("parsing api timelimit, using default")
+		} else {
+			p.apiTimelimit = v
+		}
+	}
+
+	if p.apiTimelimit == time.Duration(0) {
+		v, err := time.ParseDuration(defaults.K8SAPITimelimit)
+		if err != nil {
+			p.log.Fatal().Err(err).Msg("parsing DEFAULT api timelimit")
+		}
+		p.apiTimelimit = v
+	}
+
+	restCfg, err := k8s.RESTConfig(cfg)
+	if err != nil {
+		return nil, errors.Wrap(err, "rest config")
+	}
+	restCfg.Timeout = p.apiTimelimit
+	clientset, err := kubernetes.NewForConfig(restCfg)
+	if err != nil {
+		return nil, errors.Wrap(err, "clientset")
+	}
+	p.clientset = clientset
+
+	return p, nil
+}
+
+func (p *PDB) ID() string {
+	return "pdb"
+}
+
+// Collect pod disruption budget status
+func (p *PDB) Collect(ctx context.Context, _ *tls.Config, ts *time.Time) {
+	p.Lock()
+	if p.running {
+		p.log.Warn().Msg("already running")
+		p.Unlock()
+		return
+	}
+	p.running = true
+	p.ts = ts
+	p.Unlock()
+
+	defer func() {
+		if r := recover(); r != nil {
+			p.log.Error().Interface("panic", r).Msg("recover")
+			p.Lock()
+			p.running = false
+			p.Unlock()
+		}
+	}()
+
+	collectStart := time.Now()
+
+	p.budgetMetrics(ctx)
+
+	p.check.AddHistSample("collect_latency", cgm.Tags{
+		cgm.Tag{Category: "source", Value: release.NAME},
+		cgm.Tag{Category: "op", Value: "collect_pdb"},
+		cgm.Tag{Category: "units", Value: "milliseconds"},
+	}, float64(time.Since(collectStart).Milliseconds()))
+	p.log.Debug().Str("duration", time.Since(collectStart).String()).Msg("pdb collect end")
+	p.Lock()
+	p.running = false
+	p.Unlock()
+}
+
+// namespaceBudgets is the pod disruption budget roll-up of a namespace
+type namespaceBudgets struct {
+	budgets uint64
+	blocked uint64
+}
+
+// budgetMetrics emits the status of each pod disruption budget and per namespace
+// counts of budgets and budgets blocking disruptions
+func (p *PDB) budgetMetrics(ctx context.Context) {
+	pdbs, err := p.clientset.PolicyV1beta1().PodDisruptionBudgets(metav1.NamespaceAll).List(metav1.ListOptions{})
+	if err != nil {
+		p.check.IncrementCounter("collect_api_errors", cgm.Tags{
+			cgm.Tag{Category: "source", Value: release.NAME},
+			cgm.Tag{Category: "request", Value: "pdb-list"},
+			cgm.Tag{Category: "target", Value: "api-server"},
+		})
+		p.log.Error().Err(err).Msg("listing pod disruption budgets")
+		return
+	}
+
+	metrics := make(map[string]circonus.MetricSample)
+	namespaces := make(map[string]*namespaceBudgets)
+	for i := range pdbs.Items {
+		pdb := &pdbs.Items[i]
+		streamTags := []string{
+			"source:pdb",
+			"source_type:policy",
+			"namespace:" + pdb.Namespace,
+			"pdb:" + pdb.Name,
+			"__rollup:false", // prevent high cardinality metrics from rolling up
+		}
+
+		b := blocked(pdb)
+		_ = p.check.QueueMetricSample(metrics, "pdb_current_healthy", circonus.MetricTypeInt32, streamTags, []string{}, pdb.Status.CurrentHealthy, p.ts)
+		_ = p.check.QueueMetricSample(metrics, "pdb_desired_healthy", circonus.MetricTypeInt32, streamTags, []string{}, pdb.Status.DesiredHealthy, p.ts)
+		_ = p.check.QueueMetricSample(metrics, "pdb_expected_pods", circonus.MetricTypeInt32, streamTags, []string{}, pdb.Status.ExpectedPods, p.ts)
+		_ = p.check.QueueMetricSample(metrics, "pdb_disruptions_allowed", circonus.MetricTypeInt32, streamTags, []string{}, pdb.Status.PodDisruptionsAllowed, p.ts)
+		_ = p.check.QueueMetricSample(metrics, "pdb_blocked", circonus.MetricTypeUint64, streamTags, []string{}, b, p.ts)
+
+		nb, ok := namespaces[pdb.Namespace]
+		if !ok {
+			nb = &namespaceBudgets{}
+			namespaces[pdb.Namespace] = nb
+		}
+		nb.budgets++
+		nb.blocked += b
+	}
+
+	for ns, nb := range namespaces {
+		streamTags := []string{
+			"source:pdb",
+			"source_type:policy",
+			"namespace:" + ns,
+		}
+		_ = p.check.QueueMetricSample(metrics, "pdb_budgets", circonus.MetricTypeUint64, streamTags, []string{}, nb.budgets, p.ts)
+		_ = p.check.QueueMetricSample(metrics, "pdb_budgets_blocked", circonus.MetricTypeUint64, streamTags, []string{}, nb.blocked, p.ts)
+	}
+
+	if len(metrics) == 0 {
+		return
+	}
+	if err := p.check.SubmitQueue(ctx, metrics, p.log.With().Str("type", "pdb").Logger()); err != nil {
+		p.log.Warn().Err(err).Msg("submitting metrics")
+	}
+}
+
+// blocked returns 1 if the budget currently allows no voluntary disruptions of the pods it
+// covers (budgets matching no pods do not block anything)
+func blocked(pdb *policyv1beta1.PodDisruptionBudget) uint64 {
+	if pdb.Status.ExpectedPods > 0 && pdb.Status.PodDisruptionsAllowed == 0 {
+		return 1
+	}
+	return 0
+}
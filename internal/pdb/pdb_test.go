@@ -0,0 +1,38 @@
+// Copyright © 2019 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+package pdb
+
+import (
+	"testing"
+
+	policyv1beta1 "k8s.io/api/policy/v1beta1"
+)
+
+func TestBlocked(t *testing.T) {
+	tests := []struct {
+		name     string
+		expected int32
+		allowed  int32
+		want     uint64
+	}{
+		{"no pods", 0, 0, 0},
+		{"disruptions allowed", 3, 1, 0},
+		{"blocked", 3, 0, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pdb := &policyv1beta1.PodDisruptionBudget{
+				Status: policyv1beta1.PodDisruptionBudgetStatus{
+					ExpectedPods:          tt.expected,
+					PodDisruptionsAllowed: tt.allowed,
+				},
+			}
+			if got := blocked(pdb); got != tt.want {
+				t.Errorf("blocked() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
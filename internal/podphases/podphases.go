@@ -0,0 +1,243 @@
+// Copyright © 2019 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+// Package podphases is the pod phase duration collector
+package podphases
+
+import (
+	"context"
+	"crypto/tls"
+	"sync"
+	"time"
+
+	cgm "github.com/circonus-labs/circonus-gometrics/v3"
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/circonus"
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/config"
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/config/defaults"
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/k8s"
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/release"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes"
+)
+
+type PodPhases struct {
+	config       *config.Cluster
+	check        *circonus.Check
+	log          zerolog.Logger
+	clientset    kubernetes.Interface
+	stages       map[types.UID]int // stages recorded for each pod
+	start        time.Time         // transitions before start are not recorded
+	running      bool
+	apiTimelimit time.Duration
+	sync.Mutex
+	ts *time.Time
+}
+
+// NOTES:
+// Pods are listed from the api-server each collection, the time from creation until scheduled
+// (Pending) and from scheduled until all containers are running (ContainerCreating, including
+// image pulls and init containers) are queued as per namespace histograms. Each pod is recorded
+// once per stage, transitions before the agent started are not recorded.
+
+func New(cfg *config.Cluster, parentLog zerolog.Logger, check *circonus.Check) (*PodPhases, error) {
+	if cfg == nil {
+		return nil, errors.New("invalid cluster config (nil)")
+	}
+	if check == nil {
+		return nil, errors.New("invalid check (nil)")
+	}
+
+	pp := &PodPhases{
+		config: cfg,
+		check:  check,
+		log:    parentLog.With().Str("collector", "pod-phases").Logger(),
+		stages: make(map[types.UID]int),
+		start:  time.Now(),
+	}
+
+	if cfg.APITimelimit != "" {
+		v, err := time.ParseDuration(cfg.APITimelimit)
+		if err != nil {
+			pp.log.Error().Err(err).Msg("parsing api timelimit, using default")
+		} else {
+			pp.apiTimelimit = v
+		}
+	}
+
+	if pp.apiTimelimit == time.Duration(0) {
+		v, err := time.ParseDuration(defaults.K8SAPITimelimit)
+		if err != nil {
+			pp.log.Fatal().Err(err).Msg("parsing DEFAULT api timelimit")
+		}
+		pp.apiTimelimit = v
+	}
+
+	restCfg, err := k8s.RESTConfig(cfg)
+	if err != nil {
+		return nil, errors.Wrap(err, "rest config")
+	}
+	restCfg.Timeout = pp.apiTimelimit
+	clientset, err := kubernetes.NewForConfig(restCfg)
+	if err != nil {
+		return nil, errors.Wrap(err, "clientset")
+	}
+	pp.clientset = clientset
+
+	return pp, nil
+}
+
+func (pp *PodPhases) ID() string {
+	return "pod-phases"
+}
+
+// Collect pod phase durations
+func (pp *PodPhases) Collect(ctx context.Context, _ *tls.Config, ts *time.Time) {
+	pp.Lock()
+	if pp.running {
+		pp.log.Warn().Msg("already running")
+		pp.Unlock()
+		return
+	}
+	pp.running = true
+	pp.ts = ts
+	pp.Unlock()
+
+	defer func() {
+		if r := recover(); r != nil {
+			pp.log.Error().Interface("panic", r).Msg("recover")
+			pp.Lock()
+			pp.running = false
+			pp.Unlock()
+		}
+	}()
+
+	collectStart := time.Now()
+
+	pp.phaseMetrics(ctx)
+
+	pp.check.AddHistSample("collect_latency", cgm.Tags{
+		cgm.Tag{Category: "source", Value: release.NAME},
+		cgm.Tag{Category: "op", Value: "collect_pod-phases"},
+		cgm.Tag{Category: "units", Value: "milliseconds"},
+	}, float64(time.Since(collectStart).Milliseconds()))
+	pp.log.Debug().Str("duration", time.Since(collectStart).String()).Msg("pod-phases collect end")
+	pp.Lock()
+	pp.running = false
+	pp.Unlock()
+}
+
+const (
+	stageScheduled = 1 << iota
+	stageStarted
+)
+
+// phaseMetrics queues histograms of the time pods spend pending scheduling and creating
+// containers
+func (pp *PodPhases) phaseMetrics(ctx context.Context) {
+	pods, err := pp.clientset.CoreV1().Pods(metav1.NamespaceAll).List(metav1.ListOptions{LabelSelector: pp.config.PodSelector})
+	if err != nil {
+		pp.check.IncrementCounter("collect_api_errors", cgm.Tags{
+			cgm.Tag{Category: "source", Value: release.NAME},
+			cgm.Tag{Category: "request", Value: "pod-list"},
+			cgm.Tag{Category: "target", Value: "api-server"},
+		})
+		pp.log.Error().Err(err).Msg("listing pods")
+		return
+	}
+
+	samples := pp.phaseSamples(ctx, pods.Items)
+
+	metrics := make(map[string]circonus.MetricSample)
+	for metricName, namespaces := range samples {
+		for ns, values := range namespaces {
+			streamTags := []string{
+				"source:pod-phases",
+				"namespace:" + ns,
+				"units:seconds",
+			}
+			_ = pp.check.QueueMetricSample(metrics, metricName, circonus.MetricTypeHistogram, streamTags, []string{}, circonus.HistogramBins(values), pp.ts)
+		}
+	}
+	if len(metrics) == 0 {
+		return
+	}
+	if err := pp.check.SubmitQueue(ctx, metrics, pp.log.With().Str("type", "pod-phases").Logger()); err != nil {
+		pp.log.Warn().Err(err).Msg("submitting metrics")
+	}
+}
+
+// phaseSamples returns the pending and container creating durations, by metric and namespace,
+// of the pods not yet recorded, each pod is recorded once per stage
+func (pp *PodPhases) phaseSamples(ctx context.Context, pods []corev1.Pod) map[string]map[string][]float64 {
+	samples := map[string]map[string][]float64{
+		"pod_pending_duration":            {},
+		"pod_container_creating_duration": {},
+	}
+	stages := make(map[types.UID]int)
+	for i := range pods {
+		if ctx.Err() != nil {
+			return nil
+		}
+		pod := &pods[i]
+		if !pp.check.AllowNamespace(pod.Namespace) {
+			continue
+		}
+		recorded := pp.stages[pod.UID]
+
+		scheduled, ok := scheduledTime(pod)
+		if ok && recorded&stageScheduled == 0 {
+			recorded |= stageScheduled
+			if scheduled.After(pp.start) {
+				samples["pod_pending_duration"][pod.Namespace] = append(samples["pod_pending_duration"][pod.Namespace], scheduled.Sub(pod.CreationTimestamp.Time).Seconds())
+			}
+		}
+
+		if started, ok := startedTime(pod); ok && !scheduled.IsZero() && recorded&stageStarted == 0 {
+			recorded |= stageStarted
+			if started.After(pp.start) && !started.Before(scheduled) {
+				samples["pod_container_creating_duration"][pod.Namespace] = append(samples["pod_container_creating_duration"][pod.Namespace], started.Sub(scheduled).Seconds())
+			}
+		}
+
+		stages[pod.UID] = recorded
+	}
+
+	// replace the previous stages, pods no longer present are dropped
+	pp.stages = stages
+
+	return samples
+}
+
+// scheduledTime returns when the pod was scheduled to a node
+func scheduledTime(pod *corev1.Pod) (time.Time, bool) {
+	for _, cond := range pod.Status.Conditions {
+		if cond.Type == corev1.PodScheduled && cond.Status == corev1.ConditionTrue {
+			return cond.LastTransitionTime.Time, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// startedTime returns when the last container of the pod started running, containers
+// which have restarted no longer reflect the initial start so the pod is not included
+func startedTime(pod *corev1.Pod) (time.Time, bool) {
+	if len(pod.Status.ContainerStatuses) == 0 {
+		return time.Time{}, false
+	}
+	var started time.Time
+	for _, cs := range pod.Status.ContainerStatuses {
+		if cs.RestartCount > 0 || cs.State.Running == nil {
+			return time.Time{}, false
+		}
+		if cs.State.Running.StartedAt.Time.After(started) {
+			started = cs.State.Running.StartedAt.Time
+		}
+	}
+	return started, true
+}
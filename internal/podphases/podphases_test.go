@@ -0,0 +1,114 @@
+// Copyright © 2019 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+package podphases
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/circonus"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+func TestScheduledTime(t *testing.T) {
+	ts := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	pod := &corev1.Pod{}
+	if _, ok := scheduledTime(pod); ok {
+		t.Error("expected not scheduled")
+	}
+
+	pod.Status.Conditions = []corev1.PodCondition{
+		{Type: corev1.PodScheduled, Status: corev1.ConditionTrue, LastTransitionTime: metav1.NewTime(ts)},
+	}
+	if got, ok := scheduledTime(pod); !ok || !got.Equal(ts) {
+		t.Errorf("scheduledTime() = %s, %t, want %s, true", got, ok, ts)
+	}
+}
+
+func TestStartedTime(t *testing.T) {
+	t1 := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	t2 := t1.Add(10 * time.Second)
+
+	running := func(ts time.Time, restarts int32) corev1.ContainerStatus {
+		return corev1.ContainerStatus{
+			RestartCount: restarts,
+			State:        corev1.ContainerState{Running: &corev1.ContainerStateRunning{StartedAt: metav1.NewTime(ts)}},
+		}
+	}
+
+	tests := []struct {
+		name     string
+		statuses []corev1.ContainerStatus
+		want     time.Time
+		ok       bool
+	}{
+		{"no containers", nil, time.Time{}, false},
+		{"waiting", []corev1.ContainerStatus{running(t1, 0), {State: corev1.ContainerState{Waiting: &corev1.ContainerStateWaiting{}}}}, time.Time{}, false},
+		{"restarted", []corev1.ContainerStatus{running(t1, 1)}, time.Time{}, false},
+		{"last container", []corev1.ContainerStatus{running(t2, 0), running(t1, 0)}, t2, true},
+	}
+
+	for _, tst := range tests {
+		tst := tst
+		t.Run(tst.name, func(t *testing.T) {
+			pod := &corev1.Pod{Status: corev1.PodStatus{ContainerStatuses: tst.statuses}}
+			got, ok := startedTime(pod)
+			if ok != tst.ok || !got.Equal(tst.want) {
+				t.Errorf("startedTime() = %s, %t, want %s, %t", got, ok, tst.want, tst.ok)
+			}
+		})
+	}
+}
+
+func TestPhaseSamples(t *testing.T) {
+	start := time.Date(2020, 1, 2, 3, 0, 0, 0, time.UTC)
+	created := start.Add(time.Minute)
+
+	pod := corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{UID: types.UID("1"), Namespace: "default", CreationTimestamp: metav1.NewTime(created)},
+		Status: corev1.PodStatus{
+			Conditions: []corev1.PodCondition{
+				{Type: corev1.PodScheduled, Status: corev1.ConditionTrue, LastTransitionTime: metav1.NewTime(created.Add(2 * time.Second))},
+			},
+		},
+	}
+	old := corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{UID: types.UID("2"), Namespace: "default", CreationTimestamp: metav1.NewTime(start.Add(-time.Hour))},
+		Status:     pod.Status,
+	}
+	old.Status.Conditions = []corev1.PodCondition{
+		{Type: corev1.PodScheduled, Status: corev1.ConditionTrue, LastTransitionTime: metav1.NewTime(start.Add(-time.Minute))},
+	}
+
+	pp := &PodPhases{check: &circonus.Check{}, stages: make(map[types.UID]int), start: start}
+
+	got := pp.phaseSamples(context.Background(), []corev1.Pod{pod, old})
+	want := map[string]map[string][]float64{
+		"pod_pending_duration":            {"default": {2}},
+		"pod_container_creating_duration": {},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("expected %v, got %v", want, got)
+	}
+
+	// containers running, pending is not recorded again
+	pod.Status.ContainerStatuses = []corev1.ContainerStatus{
+		{State: corev1.ContainerState{Running: &corev1.ContainerStateRunning{StartedAt: metav1.NewTime(created.Add(7 * time.Second))}}},
+	}
+	got = pp.phaseSamples(context.Background(), []corev1.Pod{pod, old})
+	want = map[string]map[string][]float64{
+		"pod_pending_duration":            {},
+		"pod_container_creating_duration": {"default": {5}},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("expected %v, got %v", want, got)
+	}
+}
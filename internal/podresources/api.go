@@ -0,0 +1,81 @@
+// Copyright © 2019 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+package podresources
+
+import (
+	"context"
+
+	"github.com/golang/protobuf/proto"
+	"google.golang.org/grpc"
+)
+
+// The kubelet pod resources api (k8s.io/kubelet/pkg/apis/podresources/v1) is only published
+// with kubernetes v0.19+ modules, the subset of the v1 messages used is defined here so the
+// agent can stay on the current client-go. Field numbers must match the kubelet api.proto.
+
+const listMethod = "/v1.PodResourcesLister/List"
+
+type listPodResourcesRequest struct{}
+
+func (m *listPodResourcesRequest) Reset()         { *m = listPodResourcesRequest{} }
+func (m *listPodResourcesRequest) String() string { return proto.CompactTextString(m) }
+func (*listPodResourcesRequest) ProtoMessage()    {}
+
+type listPodResourcesResponse struct {
+	PodResources []*podResources `protobuf:"bytes,1,rep,name=pod_resources,json=podResources,proto3"`
+}
+
+func (m *listPodResourcesResponse) Reset()         { *m = listPodResourcesResponse{} }
+func (m *listPodResourcesResponse) String() string { return proto.CompactTextString(m) }
+func (*listPodResourcesResponse) ProtoMessage()    {}
+
+type podResources struct {
+	Name       string                `protobuf:"bytes,1,opt,name=name,proto3"`
+	Namespace  string                `protobuf:"bytes,2,opt,name=namespace,proto3"`
+	Containers []*containerResources `protobuf:"bytes,3,rep,name=containers,proto3"`
+}
+
+func (m *podResources) Reset()         { *m = podResources{} }
+func (m *podResources) String() string { return proto.CompactTextString(m) }
+func (*podResources) ProtoMessage()    {}
+
+type containerResources struct {
+	Name    string              `protobuf:"bytes,1,opt,name=name,proto3"`
+	Devices []*containerDevices `protobuf:"bytes,2,rep,name=devices,proto3"`
+	CPUIds  []int64             `protobuf:"varint,3,rep,packed,name=cpu_ids,json=cpuIds,proto3"`
+	Memory  []*containerMemory  `protobuf:"bytes,4,rep,name=memory,proto3"`
+}
+
+func (m *containerResources) Reset()         { *m = containerResources{} }
+func (m *containerResources) String() string { return proto.CompactTextString(m) }
+func (*containerResources) ProtoMessage()    {}
+
+type containerDevices struct {
+	ResourceName string   `protobuf:"bytes,1,opt,name=resource_name,json=resourceName,proto3"`
+	DeviceIds    []string `protobuf:"bytes,2,rep,name=device_ids,json=deviceIds,proto3"`
+}
+
+func (m *containerDevices) Reset()         { *m = containerDevices{} }
+func (m *containerDevices) String() string { return proto.CompactTextString(m) }
+func (*containerDevices) ProtoMessage()    {}
+
+type containerMemory struct {
+	MemoryType string `protobuf:"bytes,1,opt,name=memory_type,json=memoryType,proto3"`
+	Size       uint64 `protobuf:"varint,2,opt,name=size,proto3"`
+}
+
+func (m *containerMemory) Reset()         { *m = containerMemory{} }
+func (m *containerMemory) String() string { return proto.CompactTextString(m) }
+func (*containerMemory) ProtoMessage()    {}
+
+// list returns the resources allocated to the pods on the node
+func list(ctx context.Context, conn *grpc.ClientConn) (*listPodResourcesResponse, error) {
+	resp := &listPodResourcesResponse{}
+	if err := conn.Invoke(ctx, listMethod, &listPodResourcesRequest{}, resp); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
@@ -0,0 +1,247 @@
+// Copyright © 2019 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+// Package podresources is the kubelet pod resources (device allocation) collector
+package podresources
+
+import (
+	"context"
+	"crypto/tls"
+	"net"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	cgm "github.com/circonus-labs/circonus-gometrics/v3"
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/circonus"
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/config"
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/config/defaults"
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/release"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog"
+	"google.golang.org/grpc"
+)
+
+type PodResources struct {
+	config       *config.Cluster
+	check        *circonus.Check
+	log          zerolog.Logger
+	socket       string
+	node         string
+	running      bool
+	apiTimelimit time.Duration
+	sync.Mutex
+	ts *time.Time
+}
+
+// NOTES:
+// The pod resources collector runs in the agent daemonset (see deploy/optional/daemonset.yaml),
+// each agent pod mounts the kubelet pod-resources socket and lists the devices (device plugins,
+// e.g. nvidia.com/gpu, sr-iov nics), exclusive cpus, and memory/hugepages (kubernetes v1.21+)
+// allocated to each container on the node. Requires the kubelet v1 pod resources api (v1.20+).
+
+func New(cfg *config.Cluster, parentLog zerolog.Logger, check *circonus.Check) (*PodResources, error) {
+	if cfg == nil {
+		return nil, errors.New("invalid cluster config (nil)")
+	}
+	if check == nil {
+		return nil, errors.New("invalid check (nil)")
+	}
+	if cfg.PodResourcesSocket == "" {
+		return nil, errors.New("invalid pod resources socket (empty)")
+	}
+	if cfg.NodeName == "" {
+		return nil, errors.New("invalid node name (empty)")
+	}
+
+	pr := &PodResources{
+		config: cfg,
+		check:  check,
+		socket: strings.TrimPrefix(cfg.PodResourcesSocket, "unix://"),
+		node:   cfg.NodeName,
+		log:    parentLog.With().Str("collector", "pod-resources").Logger(),
+	}
+
+	if cfg.APITimelimit != "" {
+		v, err := time.ParseDuration(cfg.APITimelimit)
+		if err != nil {
+			pr.log.Error().Err(err).Msg("parsing api timelimit, using default")
+		} else {
+			pr.apiTimelimit = v
+		}
+	}
+
+	if pr.apiTimelimit == time.Duration(0) {
+		v, err := time.ParseDuration(defaults.K8SAPITimelimit)
+		if err != nil {
+			pr.log.Fatal().Err(err).Msg("parsing DEFAULT api timelimit")
+		}
+		pr.apiTimelimit = v
+	}
+
+	return pr, nil
+}
+
+func (pr *PodResources) ID() string {
+	return "pod-resources"
+}
+
+// Collect pod resource allocations from the kubelet
+func (pr *PodResources) Collect(ctx context.Context, _ *tls.Config, ts *time.Time) {
+	pr.Lock()
+	if pr.running {
+		pr.log.Warn().Msg("already running")
+		pr.Unlock()
+		return
+	}
+	pr.running = true
+	pr.ts = ts
+	pr.Unlock()
+
+	defer func() {
+		if r := recover(); r != nil {
+			pr.log.Error().Interface("panic", r).Msg("recover")
+			pr.Lock()
+			pr.running = false
+			pr.Unlock()
+		}
+	}()
+
+	collectStart := time.Now()
+
+	if err := pr.allocationMetrics(ctx); err != nil {
+		pr.log.Error().Err(err).Str("socket", pr.socket).Msg("pod resources")
+	}
+
+	pr.check.AddHistSample("collect_latency", cgm.Tags{
+		cgm.Tag{Category: "source", Value: release.NAME},
+		cgm.Tag{Category: "op", Value: "collect_pod-resources"},
+		cgm.Tag{Category: "units", Value: "milliseconds"},
+	}, float64(time.Since(collectStart).Milliseconds()))
+	pr.log.Debug().Str("duration", time.Since(collectStart).String()).Msg("pod-resources collect end")
+	pr.Lock()
+	pr.running = false
+	pr.Unlock()
+}
+
+// allocationMetrics emits the devices, exclusive cpus, and memory allocated to each container
+func (pr *PodResources) allocationMetrics(ctx context.Context) error {
+	reqCtx, cancel := context.WithTimeout(ctx, pr.apiTimelimit)
+	defer cancel()
+
+	conn, err := grpc.DialContext(reqCtx, pr.socket,
+		grpc.WithInsecure(),
+		grpc.WithBlock(),
+		grpc.WithContextDialer(func(ctx context.Context, addr string) (net.Conn, error) {
+			var d net.Dialer
+			return d.DialContext(ctx, "unix", addr)
+		}))
+	if err != nil {
+		pr.apiError("dial")
+		return errors.Wrap(err, "connecting to kubelet")
+	}
+	defer conn.Close()
+
+	start := time.Now()
+	resp, err := list(reqCtx, conn)
+	if err != nil {
+		pr.apiError("pod-resources-list")
+		return errors.Wrap(err, "listing pod resources")
+	}
+	pr.check.AddHistSample("collect_latency", cgm.Tags{
+		cgm.Tag{Category: "source", Value: release.NAME},
+		cgm.Tag{Category: "request", Value: "pod-resources-list"},
+		cgm.Tag{Category: "target", Value: "kubelet"},
+		cgm.Tag{Category: "units", Value: "milliseconds"},
+	}, float64(time.Since(start).Milliseconds()))
+
+	metrics := make(map[string]circonus.MetricSample)
+	for _, a := range allocations(resp) {
+		streamTags := []string{
+			"source:pod-resources",
+			"source_type:allocations",
+			"node:" + pr.node,
+			"namespace:" + a.namespace,
+			"pod:" + a.pod,
+			"container_name:" + a.container,
+			"resource:" + a.resource,
+			"__rollup:false", // prevent high cardinality metrics from rolling up
+		}
+		switch a.kind {
+		case "device":
+			_ = pr.check.QueueMetricSample(metrics, "pod_resources_devices", circonus.MetricTypeUint64, append(streamTags, "units:devices"), []string{}, a.value, pr.ts)
+		case "cpu":
+			_ = pr.check.QueueMetricSample(metrics, "pod_resources_cpus", circonus.MetricTypeUint64, append(streamTags, "units:cpus"), []string{}, a.value, pr.ts)
+		case "memory":
+			_ = pr.check.QueueMetricSample(metrics, "pod_resources_memory", circonus.MetricTypeUint64, append(streamTags, "units:bytes"), []string{}, a.value, pr.ts)
+		}
+	}
+
+	if len(metrics) == 0 {
+		return nil
+	}
+	return pr.check.SubmitQueue(ctx, metrics, pr.log.With().Str("type", "pod-resources").Logger())
+}
+
+func (pr *PodResources) apiError(request string) {
+	pr.check.IncrementCounter("collect_api_errors", cgm.Tags{
+		cgm.Tag{Category: "source", Value: release.NAME},
+		cgm.Tag{Category: "request", Value: request},
+		cgm.Tag{Category: "target", Value: "kubelet"},
+	})
+}
+
+// allocation is an amount of a resource allocated to a container
+type allocation struct {
+	namespace string
+	pod       string
+	container string
+	kind      string // device, cpu, memory
+	resource  string // e.g. nvidia.com/gpu, cpu, hugepages-1Gi
+	value     uint64 // devices, cpus, or bytes
+}
+
+// allocations returns the per container resource allocations, device ids of the same
+// resource are counted, sorted by namespace/pod/container/resource
+func allocations(resp *listPodResourcesResponse) []allocation {
+	var result []allocation
+	for _, pod := range resp.PodResources {
+		for _, c := range pod.Containers {
+			devices := make(map[string]uint64)
+			for _, d := range c.Devices {
+				devices[d.ResourceName] += uint64(len(d.DeviceIds))
+			}
+			for name, count := range devices {
+				result = append(result, allocation{pod.Namespace, pod.Name, c.Name, "device", name, count})
+			}
+			if len(c.CPUIds) > 0 {
+				result = append(result, allocation{pod.Namespace, pod.Name, c.Name, "cpu", "cpu", uint64(len(c.CPUIds))})
+			}
+			memory := make(map[string]uint64)
+			for _, m := range c.Memory {
+				memory[m.MemoryType] += m.Size
+			}
+			for name, size := range memory {
+				result = append(result, allocation{pod.Namespace, pod.Name, c.Name, "memory", name, size})
+			}
+		}
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		a, b := result[i], result[j]
+		if a.namespace != b.namespace {
+			return a.namespace < b.namespace
+		}
+		if a.pod != b.pod {
+			return a.pod < b.pod
+		}
+		if a.container != b.container {
+			return a.container < b.container
+		}
+		return a.resource < b.resource
+	})
+	return result
+}
@@ -0,0 +1,82 @@
+// Copyright © 2019 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+package podresources
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/golang/protobuf/proto"
+)
+
+func TestAllocations(t *testing.T) {
+	resp := &listPodResourcesResponse{
+		PodResources: []*podResources{
+			{
+				Name:      "trainer",
+				Namespace: "ml",
+				Containers: []*containerResources{
+					{
+						Name: "cuda",
+						Devices: []*containerDevices{
+							{ResourceName: "nvidia.com/gpu", DeviceIds: []string{"GPU-0"}},
+							{ResourceName: "nvidia.com/gpu", DeviceIds: []string{"GPU-1"}},
+						},
+						CPUIds: []int64{2, 3},
+						Memory: []*containerMemory{
+							{MemoryType: "hugepages-1Gi", Size: 2 << 30},
+						},
+					},
+				},
+			},
+			{
+				Name:       "web",
+				Namespace:  "default",
+				Containers: []*containerResources{{Name: "nginx"}},
+			},
+		},
+	}
+
+	want := []allocation{
+		{"ml", "trainer", "cuda", "cpu", "cpu", 2},
+		{"ml", "trainer", "cuda", "memory", "hugepages-1Gi", 2 << 30},
+		{"ml", "trainer", "cuda", "device", "nvidia.com/gpu", 2},
+	}
+	if got := allocations(resp); !reflect.DeepEqual(got, want) {
+		t.Errorf("allocations() = %+v, want %+v", got, want)
+	}
+}
+
+func TestMessageRoundTrip(t *testing.T) {
+	in := &listPodResourcesResponse{
+		PodResources: []*podResources{
+			{
+				Name:      "pod",
+				Namespace: "ns",
+				Containers: []*containerResources{
+					{
+						Name:    "c",
+						Devices: []*containerDevices{{ResourceName: "intel.com/sriov", DeviceIds: []string{"0000:03:02.0"}}},
+						CPUIds:  []int64{1},
+						Memory:  []*containerMemory{{MemoryType: "memory", Size: 1024}},
+					},
+				},
+			},
+		},
+	}
+
+	data, err := proto.Marshal(in)
+	if err != nil {
+		t.Fatalf("marshal (%s)", err)
+	}
+	out := &listPodResourcesResponse{}
+	if err := proto.Unmarshal(data, out); err != nil {
+		t.Fatalf("unmarshal (%s)", err)
+	}
+	if !reflect.DeepEqual(in, out) {
+		t.Errorf("round trip = %+v, want %+v", out, in)
+	}
+}
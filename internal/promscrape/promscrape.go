@@ -0,0 +1,226 @@
+// Copyright © 2019 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+// Package promscrape is the collector for pods and services annotated
+// for prometheus scraping (prometheus.io/scrape=true)
+package promscrape
+
+import (
+	"context"
+	"crypto/tls"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	cgm "github.com/circonus-labs/circonus-gometrics/v3"
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/circonus"
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/config"
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/config/defaults"
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/k8s"
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/release"
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/scrape"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog"
+)
+
+const (
+	annotationScrape = "prometheus.io/scrape"
+	annotationPort   = "prometheus.io/port"
+	annotationPath   = "prometheus.io/path"
+	annotationScheme = "prometheus.io/scheme"
+	defaultPath      = "/metrics"
+)
+
+type PromScrape struct {
+	config       *config.Cluster
+	check        *circonus.Check
+	log          zerolog.Logger
+	running      bool
+	apiTimelimit time.Duration
+	sync.Mutex
+	ts *time.Time
+}
+
+// NOTES:
+// Annotated pods are scraped directly (pod ip), the agent does not send its service account
+// token to application endpoints. Annotated services are scraped through the api-server
+// service proxy (one endpoint per service). A port annotation is required, there is no
+// fallback to the declared container ports.
+
+func New(cfg *config.Cluster, parentLog zerolog.Logger, check *circonus.Check) (*PromScrape, error) {
+	if cfg == nil {
+		return nil, errors.New("invalid cluster config (nil)")
+	}
+	if check == nil {
+		return nil, errors.New("invalid check (nil)")
+	}
+
+	ps := &PromScrape{
+		config: cfg,
+		check:  check,
+		log:    parentLog.With().Str("collector", "promscrape").Logger(),
+	}
+
+	if cfg.APITimelimit != "" {
+		v, err := time.ParseDuration(cfg.APITimelimit)
+		if err != nil {
+			ps.log.Error().Err(err).Msg("parsing api timelimit, using default")
+		} else {
+			ps.apiTimelimit = v
+		}
+	}
+
+	if ps.apiTimelimit == time.Duration(0) {
+		v, err := time.ParseDuration(defaults.K8SAPITimelimit)
+		if err != nil {
+			ps.log.Fatal().Err(err).Msg("parsing DEFAULT api timelimit")
+		}
+		ps.apiTimelimit = v
+	}
+
+	return ps, nil
+}
+
+func (ps *PromScrape) ID() string {
+	return "promscrape"
+}
+
+// Collect metrics from annotated pods and services
+func (ps *PromScrape) Collect(ctx context.Context, tlsConfig *tls.Config, ts *time.Time) {
+	ps.Lock()
+	if ps.running {
+		ps.log.Warn().Msg("already running")
+		ps.Unlock()
+		return
+	}
+	ps.running = true
+	ps.ts = ts
+	ps.Unlock()
+
+	defer func() {
+		if r := recover(); r != nil {
+			ps.log.Error().Interface("panic", r).Msg("recover")
+			ps.Lock()
+			ps.running = false
+			ps.Unlock()
+		}
+	}()
+
+	collectStart := time.Now()
+
+	var targets []scrape.Target
+
+	pods, err := scrape.Pods(ps.check, ps.log, ps.config, tlsConfig, ps.apiTimelimit, ps.config.PromScrapeNamespace, "")
+	if err != nil {
+		ps.log.Error().Err(err).Msg("listing pods")
+	} else {
+		for _, pod := range pods {
+			if target, ok := podTarget(pod); ok {
+				targets = append(targets, target)
+			}
+		}
+	}
+
+	svcs, err := scrape.Services(ps.check, ps.log, ps.config, tlsConfig, ps.apiTimelimit, ps.config.PromScrapeNamespace, "")
+	if err != nil {
+		ps.log.Error().Err(err).Msg("listing services")
+	} else {
+		for _, svc := range svcs {
+			if target, ok := serviceTarget(ps.config.URL, ps.config.Token(), svc); ok {
+				targets = append(targets, target)
+			}
+		}
+	}
+
+	if len(targets) > 0 {
+		scrape.MetricsPool(ctx, ps.check, ps.log, tlsConfig, ps.apiTimelimit, targets, int(ps.config.NodePoolSize), ps.ts)
+	}
+
+	ps.check.AddHistSample("collect_latency", cgm.Tags{
+		cgm.Tag{Category: "source", Value: release.NAME},
+		cgm.Tag{Category: "op", Value: "collect_promscrape"},
+		cgm.Tag{Category: "units", Value: "milliseconds"},
+	}, float64(time.Since(collectStart).Milliseconds()))
+	ps.log.Debug().Int("targets", len(targets)).Str("duration", time.Since(collectStart).String()).Msg("promscrape collect end")
+	ps.Lock()
+	ps.running = false
+	ps.Unlock()
+}
+
+// podTarget returns a scrape target for a pod annotated with prometheus.io/scrape=true and prometheus.io/port
+func podTarget(pod *k8s.Pod) (scrape.Target, bool) {
+	port, path, https, ok := annotations(pod.Metadata.Annotations)
+	if !ok || pod.Status.PodIP == "" {
+		return scrape.Target{}, false
+	}
+
+	scheme := "http://"
+	var tlsConfig *tls.Config
+	if https {
+		scheme = "https://"
+		// pod serving certificates are rarely valid for the pod ip
+		tlsConfig = &tls.Config{InsecureSkipVerify: true} //nolint:gosec
+	}
+
+	return scrape.Target{
+		URL:       scheme + net.JoinHostPort(pod.Status.PodIP, port) + path,
+		Name:      "promscrape",
+		TLSConfig: tlsConfig,
+		StreamTags: []string{
+			"source:promscrape",
+			"source_type:metrics",
+			"namespace:" + pod.Metadata.Namespace,
+			"pod:" + pod.Metadata.Name,
+			"__rollup:false", // prevent high cardinality metrics from rolling up
+		},
+	}, true
+}
+
+// serviceTarget returns a scrape target (via the api-server proxy) for a service annotated
+// with prometheus.io/scrape=true and prometheus.io/port
+func serviceTarget(apiURL, bearerToken string, svc *k8s.Service) (scrape.Target, bool) {
+	port, path, https, ok := annotations(svc.Metadata.Annotations)
+	if !ok {
+		return scrape.Target{}, false
+	}
+	if https {
+		port = "https:" + port
+	}
+
+	return scrape.Target{
+		URL:         scrape.ServiceProxyURL(apiURL, svc, port, path),
+		BearerToken: bearerToken,
+		Name:        "promscrape",
+		Proxy:       "api-server",
+		StreamTags: []string{
+			"source:promscrape",
+			"source_type:metrics",
+			"namespace:" + svc.Metadata.Namespace,
+			"service:" + svc.Metadata.Name,
+			"__rollup:false", // prevent high cardinality metrics from rolling up
+		},
+	}, true
+}
+
+// annotations returns the port, path, and whether to use https from the prometheus.io annotations
+func annotations(a map[string]string) (port string, path string, https bool, ok bool) {
+	if strings.ToLower(a[annotationScrape]) != "true" {
+		return "", "", false, false
+	}
+	port = a[annotationPort]
+	if port == "" {
+		return "", "", false, false
+	}
+	path = a[annotationPath]
+	if path == "" {
+		path = defaultPath
+	}
+	if !strings.HasPrefix(path, "/") {
+		path = "/" + path
+	}
+	https = strings.ToLower(a[annotationScheme]) == "https"
+	return port, path, https, true
+}
@@ -0,0 +1,68 @@
+// Copyright © 2019 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+package promscrape
+
+import (
+	"testing"
+
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/k8s"
+)
+
+func TestPodTarget(t *testing.T) {
+	tests := []struct {
+		name        string
+		annotations map[string]string
+		podIP       string
+		want        string
+		ok          bool
+	}{
+		{"not annotated", nil, "10.0.0.1", "", false},
+		{"scrape false", map[string]string{"prometheus.io/scrape": "false", "prometheus.io/port": "8080"}, "10.0.0.1", "", false},
+		{"no port", map[string]string{"prometheus.io/scrape": "true"}, "10.0.0.1", "", false},
+		{"no ip", map[string]string{"prometheus.io/scrape": "true", "prometheus.io/port": "8080"}, "", "", false},
+		{"defaults", map[string]string{"prometheus.io/scrape": "true", "prometheus.io/port": "8080"}, "10.0.0.1", "http://10.0.0.1:8080/metrics", true},
+		{"path", map[string]string{"prometheus.io/scrape": "true", "prometheus.io/port": "8080", "prometheus.io/path": "stats"}, "10.0.0.1", "http://10.0.0.1:8080/stats", true},
+		{"https", map[string]string{"prometheus.io/scrape": "true", "prometheus.io/port": "8443", "prometheus.io/scheme": "https"}, "10.0.0.1", "https://10.0.0.1:8443/metrics", true},
+	}
+
+	for _, tst := range tests {
+		tst := tst
+		t.Run(tst.name, func(t *testing.T) {
+			pod := &k8s.Pod{
+				Metadata: k8s.PodMetadata{Name: "foo", Namespace: "bar", Annotations: tst.annotations},
+				Status:   k8s.PodStatus{PodIP: tst.podIP},
+			}
+			target, ok := podTarget(pod)
+			if ok != tst.ok {
+				t.Fatalf("podTarget() ok = %t, want %t", ok, tst.ok)
+			}
+			if target.URL != tst.want {
+				t.Errorf("podTarget() url = %s, want %s", target.URL, tst.want)
+			}
+			if ok && target.BearerToken != "" {
+				t.Error("podTarget() expected no bearer token")
+			}
+		})
+	}
+}
+
+func TestServiceTarget(t *testing.T) {
+	svc := &k8s.Service{
+		Metadata: k8s.ServiceMetadata{
+			Name:        "foo",
+			Namespace:   "bar",
+			Annotations: map[string]string{"prometheus.io/scrape": "true", "prometheus.io/port": "9102"},
+		},
+	}
+	target, ok := serviceTarget("https://kubernetes", "token", svc)
+	if !ok {
+		t.Fatal("serviceTarget() expected ok")
+	}
+	want := "https://kubernetes/api/v1/namespaces/bar/services/foo:9102/proxy/metrics"
+	if target.URL != want {
+		t.Errorf("serviceTarget() url = %s, want %s", target.URL, want)
+	}
+}
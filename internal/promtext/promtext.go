@@ -11,6 +11,7 @@ import (
 	"fmt"
 	"io"
 	"math"
+	"regexp"
 	"strings"
 	"time"
 
@@ -21,10 +22,10 @@ import (
 )
 
 const (
-	// NOTE: these will be enabled in a future release when we support cumulative histograms (type H)
-	//       flip emitHistogramBuckets to true and leave circCumulativeHistogram true. Once we're
-	//       happy with the support the gating flag logic can be removed and the code simplifed.
-	emitHistogramBuckets    = false
+	// NOTE: histogram buckets are submitted as circonus log-linear cumulative histograms (type H),
+	//       translated by circonus.LogLinearBins. Setting circCumulativeHistogram to false emits
+	//       each bucket as a separate sample tagged with its upper bound instead.
+	emitHistogramBuckets    = true
 	circCumulativeHistogram = true
 )
 
@@ -40,6 +41,56 @@ func QueueMetrics(
 	parentMeasurementTags []string,
 	ts *time.Time) error {
 
+	return queueMetrics(ctx, check, logger, data, nil, nil, parentStreamTags, parentMeasurementTags, ts)
+}
+
+// QueueFilteredMetrics is QueueMetrics restricted to the metric families
+// with names matching familyFilter. Used for endpoints (e.g. api-server)
+// which expose far more metrics than are useful to forward.
+func QueueFilteredMetrics(
+	ctx context.Context,
+	check *circonus.Check,
+	logger zerolog.Logger,
+	data io.Reader,
+	familyFilter *regexp.Regexp,
+	parentStreamTags []string,
+	parentMeasurementTags []string,
+	ts *time.Time) error {
+
+	return queueMetrics(ctx, check, logger, data, familyFilter, nil, parentStreamTags, parentMeasurementTags, ts)
+}
+
+// SampleFilter returns true if a sample, identified by its labels, should be queued
+type SampleFilter func(labels map[string]string) bool
+
+// QueueSelectedMetrics is QueueFilteredMetrics (familyFilter may be nil for all
+// families) restricted to the samples accepted by sampleFilter. Used for endpoints
+// (e.g. kubelet /metrics/cadvisor) reporting on objects outside the configured scope.
+func QueueSelectedMetrics(
+	ctx context.Context,
+	check *circonus.Check,
+	logger zerolog.Logger,
+	data io.Reader,
+	familyFilter *regexp.Regexp,
+	sampleFilter SampleFilter,
+	parentStreamTags []string,
+	parentMeasurementTags []string,
+	ts *time.Time) error {
+
+	return queueMetrics(ctx, check, logger, data, familyFilter, sampleFilter, parentStreamTags, parentMeasurementTags, ts)
+}
+
+func queueMetrics(
+	ctx context.Context,
+	check *circonus.Check,
+	logger zerolog.Logger,
+	data io.Reader,
+	familyFilter *regexp.Regexp,
+	sampleFilter SampleFilter,
+	parentStreamTags []string,
+	parentMeasurementTags []string,
+	ts *time.Time) error {
+
 	var baseStreamTags []string
 	if len(parentStreamTags) > 0 {
 		baseStreamTags = make([]string, len(parentStreamTags))
@@ -60,6 +111,9 @@ func QueueMetrics(
 		if done(ctx) {
 			return nil
 		}
+		if familyFilter != nil && !familyFilter.MatchString(mn) {
+			continue
+		}
 		for _, m := range mf.Metric {
 			if maxMetrics > 0 && len(metrics) >= maxMetrics {
 				if err := check.SubmitQueue(ctx, metrics, logger); err != nil {
@@ -70,21 +124,15 @@ func QueueMetrics(
 			if done(ctx) {
 				return nil
 			}
+			if sampleFilter != nil && !sampleFilter(labelMap(m)) {
+				continue
+			}
 			metricName := mn
 			streamTags := getLabels(m)
 			streamTags = append(streamTags, baseStreamTags...)
 			switch mf.GetType() {
 			case dto.MetricType_SUMMARY:
-				_ = check.QueueMetricSample(
-					metrics, metricName+"_count",
-					circonus.MetricTypeUint64,
-					streamTags, parentMeasurementTags,
-					m.GetSummary().GetSampleCount(), ts)
-				_ = check.QueueMetricSample(
-					metrics, metricName+"_sum",
-					circonus.MetricTypeFloat64,
-					streamTags, parentMeasurementTags,
-					m.GetSummary().GetSampleSum(), ts)
+				queueCountSum(check, metrics, metricName, streamTags, parentMeasurementTags, m.GetSummary().GetSampleCount(), m.GetSummary().GetSampleSum(), ts)
 				for qn, qv := range getQuantiles(m) {
 					var qtags []string
 					qtags = append(qtags, streamTags...)
@@ -96,21 +144,12 @@ func QueueMetrics(
 						qv, ts)
 				}
 			case dto.MetricType_HISTOGRAM:
-				_ = check.QueueMetricSample(
-					metrics, metricName+"_count",
-					circonus.MetricTypeUint64,
-					streamTags, parentMeasurementTags,
-					m.GetHistogram().GetSampleCount(), ts)
-				_ = check.QueueMetricSample(
-					metrics, metricName+"_sum",
-					circonus.MetricTypeFloat64,
-					streamTags, parentMeasurementTags,
-					m.GetHistogram().GetSampleSum(), ts)
+				queueCountSum(check, metrics, metricName, streamTags, parentMeasurementTags, m.GetHistogram().GetSampleCount(), m.GetHistogram().GetSampleSum(), ts)
 				if emitHistogramBuckets {
 					if circCumulativeHistogram {
 						var htags []string
 						htags = append(htags, streamTags...)
-						histo := promHistoBucketsToCircHisto(m)
+						histo := circonus.LogLinearBins(getHistogramBuckets(m))
 						if len(histo) > 0 {
 							_ = check.QueueMetricSample(
 								metrics, metricName,
@@ -143,7 +182,7 @@ func QueueMetrics(
 					}
 				case m.Counter != nil:
 					if m.GetCounter().Value != nil {
-						_ = check.QueueMetricSample(
+						_ = check.QueueCounterSample(
 							metrics, metricName,
 							circonus.MetricTypeFloat64,
 							streamTags, parentMeasurementTags,
@@ -180,6 +219,30 @@ func QueueMetrics(
 	return nil
 }
 
+// queueCountSum queues the _count and _sum of a summary or histogram, both are
+// cumulative and go through counter deltas like counters
+func queueCountSum(
+	check *circonus.Check,
+	metrics map[string]circonus.MetricSample,
+	metricName string,
+	streamTags []string,
+	measurementTags []string,
+	count uint64,
+	sum float64,
+	ts *time.Time) {
+
+	_ = check.QueueCounterSample(
+		metrics, metricName+"_count",
+		circonus.MetricTypeUint64,
+		streamTags, measurementTags,
+		count, ts)
+	_ = check.QueueCounterSample(
+		metrics, metricName+"_sum",
+		circonus.MetricTypeFloat64,
+		streamTags, measurementTags,
+		sum, ts)
+}
+
 func getLabels(m *dto.Metric) []string {
 	labels := []string{}
 
@@ -197,6 +260,16 @@ func getLabels(m *dto.Metric) []string {
 	return labels
 }
 
+func labelMap(m *dto.Metric) map[string]string {
+	labels := make(map[string]string, len(m.Label))
+
+	for _, label := range m.Label {
+		labels[label.GetName()] = label.GetValue()
+	}
+
+	return labels
+}
+
 func getQuantiles(m *dto.Metric) map[string]float64 {
 	ret := make(map[string]float64)
 	for _, q := range m.GetSummary().Quantile {
@@ -217,30 +290,19 @@ func getBuckets(m *dto.Metric) map[string]uint64 {
 	return ret
 }
 
-func promHistoBucketsToCircHisto(m *dto.Metric) []string {
-	const reducer = 0.999
-	var ret []string
-	tot := m.GetHistogram().GetSampleCount()
-	n := uint64(0)
+// getHistogramBuckets returns the cumulative buckets of a histogram
+func getHistogramBuckets(m *dto.Metric) []circonus.HistogramBucket {
+	buckets := make([]circonus.HistogramBucket, 0, len(m.GetHistogram().Bucket))
 	for _, b := range m.GetHistogram().Bucket {
-		if b.CumulativeCount != nil {
-			v := *b.CumulativeCount - n
-			if v > 0 {
-				upperBound := *b.UpperBound
-				if upperBound == math.Inf(+1) {
-					upperBound = 10e+127
-				} else {
-					upperBound *= reducer
-				}
-				ret = append(ret, fmt.Sprintf("H[%e]=%d", upperBound, v))
-				if *b.CumulativeCount == tot {
-					break
-				}
-				n += v
-			}
+		if b.CumulativeCount != nil && b.UpperBound != nil {
+			buckets = append(buckets, circonus.HistogramBucket{UpperBound: *b.UpperBound, CumulativeCount: *b.CumulativeCount})
 		}
 	}
-	return ret
+	// samples above the largest bucket upper bound, when there is no +Inf bucket
+	if n := len(buckets); n > 0 && !math.IsInf(buckets[n-1].UpperBound, +1) && m.GetHistogram().GetSampleCount() > buckets[n-1].CumulativeCount {
+		buckets = append(buckets, circonus.HistogramBucket{UpperBound: math.Inf(+1), CumulativeCount: m.GetHistogram().GetSampleCount()})
+	}
+	return buckets
 }
 
 func done(ctx context.Context) bool {
@@ -0,0 +1,81 @@
+// Copyright © 2019 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+package promtext
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/circonus"
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/config"
+	"github.com/rs/zerolog"
+)
+
+// recorder keeps the last value recorded for each metric, counters are recorded
+// as cumulative values with counter deltas enabled
+type recorder struct {
+	values map[string]interface{}
+	sync.Mutex
+}
+
+func (r *recorder) Record(metricName string, streamTags []string, value interface{}) {
+	r.Lock()
+	r.values[metricName] = value
+	r.Unlock()
+}
+
+func TestQueueMetricsCounterDeltas(t *testing.T) {
+	check, err := circonus.NewCheck(zerolog.Nop(), &config.Circonus{
+		DryRun:           true,
+		DryRunOutput:     os.DevNull,
+		CounterDeltas:    true,
+		SubmitBackoffMin: "1s",
+		SubmitBackoffMax: "1s",
+	})
+	if err != nil {
+		t.Fatalf("unexpected error (%s)", err)
+	}
+	r := &recorder{}
+	check.AddRecorder(r)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go check.Submitter(ctx)
+
+	tests := []struct {
+		name  string
+		count uint64
+		sum   float64
+		want  map[string]interface{}
+	}{
+		{"first", 10, 5, map[string]interface{}{"request_seconds_count": uint64(10), "request_seconds_sum": 5.0, "requests_total": 10.0}},
+		{"delta", 15, 7.5, map[string]interface{}{"request_seconds_count": uint64(15), "request_seconds_sum": 7.5, "requests_total": 15.0}},
+		{"reset", 3, 1.5, map[string]interface{}{"request_seconds_count": uint64(3), "request_seconds_sum": 1.5, "requests_total": 3.0}},
+	}
+
+	for _, tt := range tests {
+		r.values = make(map[string]interface{})
+		data := fmt.Sprintf(`# TYPE request_seconds histogram
+request_seconds_bucket{le="1"} %[1]d
+request_seconds_bucket{le="+Inf"} %[1]d
+request_seconds_sum %[2]g
+request_seconds_count %[1]d
+# TYPE requests_total counter
+requests_total %[1]d
+`, tt.count, tt.sum)
+		if err := QueueMetrics(ctx, check, zerolog.Nop(), strings.NewReader(data), nil, nil, nil); err != nil {
+			t.Fatalf("%s: unexpected error (%s)", tt.name, err)
+		}
+		if !reflect.DeepEqual(r.values, tt.want) {
+			t.Fatalf("%s: expected %v, got %v", tt.name, tt.want, r.values)
+		}
+	}
+}
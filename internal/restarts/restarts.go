@@ -0,0 +1,214 @@
+// Copyright © 2019 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+// Package restarts is the container restart and OOMKill tracking collector
+package restarts
+
+import (
+	"context"
+	"crypto/tls"
+	"strconv"
+	"sync"
+	"time"
+
+	cgm "github.com/circonus-labs/circonus-gometrics/v3"
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/circonus"
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/config"
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/config/defaults"
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/k8s"
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/release"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+type Restarts struct {
+	config       *config.Cluster
+	check        *circonus.Check
+	log          zerolog.Logger
+	clientset    kubernetes.Interface
+	restarts     map[string]int32 // restart count by pod uid/container from the previous collection
+	collected    bool             // the first collection (the baseline) is done
+	running      bool
+	apiTimelimit time.Duration
+	sync.Mutex
+	ts *time.Time
+}
+
+// NOTES:
+// Pods are listed from the api-server each collection and the container restart counts are
+// compared to the previous collection, the number of restarts since the previous collection
+// is emitted so crashloops and OOM kills are directly graphable. The first collection only
+// establishes the baseline, containers first seen after it (e.g. new pods) count all of their
+// restarts so a container crashlooping before the next collection is not missed.
+
+func New(cfg *config.Cluster, parentLog zerolog.Logger, check *circonus.Check) (*Restarts, error) {
+	if cfg == nil {
+		return nil, errors.New("invalid cluster config (nil)")
+	}
+	if check == nil {
+		return nil, errors.New("invalid check (nil)")
+	}
+
+	rs := &Restarts{
+		config:   cfg,
+		check:    check,
+		log:      parentLog.With().Str("collector", "container-restarts").Logger(),
+		restarts: make(map[string]int32),
+	}
+
+	if cfg.APITimelimit != "" {
+		v, err := time.ParseDuration(cfg.APITimelimit)
+		if err != nil {
+			rs.log.Error().Err(err).Msg("parsing api timelimit, using default")
+		} else {
+			rs.apiTimelimit = v
+		}
+	}
+
+	if rs.apiTimelimit == time.Duration(0) {
+		v, err := time.ParseDuration(defaults.K8SAPITimelimit)
+		if err != nil {
+			rs.log.Fatal().Err(err).Msg("parsing DEFAULT api timelimit")
+		}
+		rs.apiTimelimit = v
+	}
+
+	restCfg, err := k8s.RESTConfig(cfg)
+	if err != nil {
+		return nil, errors.Wrap(err, "rest config")
+	}
+	restCfg.Timeout = rs.apiTimelimit
+	clientset, err := kubernetes.NewForConfig(restCfg)
+	if err != nil {
+		return nil, errors.Wrap(err, "clientset")
+	}
+	rs.clientset = clientset
+
+	return rs, nil
+}
+
+func (rs *Restarts) ID() string {
+	return "container-restarts"
+}
+
+// Collect container restarts
+func (rs *Restarts) Collect(ctx context.Context, _ *tls.Config, ts *time.Time) {
+	rs.Lock()
+	if rs.running {
+		rs.log.Warn().Msg("already running")
+		rs.Unlock()
+		return
+	}
+	rs.running = true
+	rs.ts = ts
+	rs.Unlock()
+
+	defer func() {
+		if r := recover(); r != nil {
+			rs.log.Error().Interface("panic", r).Msg("recover")
+			rs.Lock()
+			rs.running = false
+			rs.Unlock()
+		}
+	}()
+
+	collectStart := time.Now()
+
+	rs.containerMetrics(ctx)
+
+	rs.check.AddHistSample("collect_latency", cgm.Tags{
+		cgm.Tag{Category: "source", Value: release.NAME},
+		cgm.Tag{Category: "op", Value: "collect_container-restarts"},
+		cgm.Tag{Category: "units", Value: "milliseconds"},
+	}, float64(time.Since(collectStart).Milliseconds()))
+	rs.log.Debug().Str("duration", time.Since(collectStart).String()).Msg("container-restarts collect end")
+	rs.Lock()
+	rs.running = false
+	rs.Unlock()
+}
+
+// containerMetrics emits the container restarts since the previous collection, and the
+// termination reason (e.g. OOMKilled, Error) and exit code of the restarted containers
+func (rs *Restarts) containerMetrics(ctx context.Context) {
+	pods, err := rs.clientset.CoreV1().Pods(metav1.NamespaceAll).List(metav1.ListOptions{LabelSelector: rs.config.PodSelector})
+	if err != nil {
+		rs.check.IncrementCounter("collect_api_errors", cgm.Tags{
+			cgm.Tag{Category: "source", Value: release.NAME},
+			cgm.Tag{Category: "request", Value: "pod-list"},
+			cgm.Tag{Category: "target", Value: "api-server"},
+		})
+		rs.log.Error().Err(err).Msg("listing pods")
+		return
+	}
+
+	metrics := make(map[string]circonus.MetricSample)
+	current := make(map[string]int32)
+	for i := range pods.Items {
+		pod := &pods.Items[i]
+		statuses := append(append([]corev1.ContainerStatus{}, pod.Status.InitContainerStatuses...), pod.Status.ContainerStatuses...)
+		for _, cs := range statuses {
+			key := string(pod.UID) + "/" + cs.Name
+			current[key] = cs.RestartCount
+			prev, seen := rs.restarts[key]
+			delta := restartDelta(prev, cs.RestartCount, seen, !rs.collected)
+			if delta == 0 {
+				continue
+			}
+
+			streamTags := []string{
+				"source:restarts",
+				"source_type:containers",
+				"namespace:" + pod.Namespace,
+				"pod:" + pod.Name,
+				"container_name:" + cs.Name,
+				"node:" + pod.Spec.NodeName,
+				"__rollup:false", // prevent high cardinality metrics from rolling up
+			}
+			_ = rs.check.QueueMetricSample(metrics, "container_restarts", circonus.MetricTypeInt32, streamTags, []string{}, delta, rs.ts)
+
+			if term := cs.LastTerminationState.Terminated; term != nil {
+				var termTags []string
+				termTags = append(termTags, streamTags...)
+				termTags = append(termTags, []string{
+					"reason:" + term.Reason,
+					"exit_code:" + strconv.Itoa(int(term.ExitCode)),
+				}...)
+				_ = rs.check.QueueMetricSample(metrics, "container_terminations", circonus.MetricTypeInt32, termTags, []string{}, delta, rs.ts)
+				if term.Reason == "OOMKilled" {
+					_ = rs.check.QueueMetricSample(metrics, "container_oom_kills", circonus.MetricTypeInt32, streamTags, []string{}, delta, rs.ts)
+				}
+			}
+		}
+	}
+
+	// replace the previous counts, containers no longer present are dropped
+	rs.restarts = current
+	rs.collected = true
+
+	if len(metrics) == 0 {
+		return
+	}
+	if err := rs.check.SubmitQueue(ctx, metrics, rs.log.With().Str("type", "restarts").Logger()); err != nil {
+		rs.log.Warn().Err(err).Msg("submitting metrics")
+	}
+}
+
+// restartDelta returns the number of restarts since the previous collection, containers
+// not seen previously count from 0, the first collection (baseline) returns 0
+func restartDelta(prev, cur int32, seen, baseline bool) int32 {
+	if baseline {
+		return 0
+	}
+	if !seen {
+		prev = 0
+	}
+	if cur < prev {
+		return 0
+	}
+	return cur - prev
+}
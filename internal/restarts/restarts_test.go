@@ -0,0 +1,34 @@
+// Copyright © 2019 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+package restarts
+
+import "testing"
+
+func TestRestartDelta(t *testing.T) {
+	tests := []struct {
+		name     string
+		prev     int32
+		cur      int32
+		seen     bool
+		baseline bool
+		want     int32
+	}{
+		{"baseline", 0, 5, false, true, 0},
+		{"new container", 0, 2, false, false, 2},
+		{"no change", 5, 5, true, false, 0},
+		{"restarted", 5, 7, true, false, 2},
+		{"reset", 5, 1, true, false, 0},
+	}
+
+	for _, tst := range tests {
+		tst := tst
+		t.Run(tst.name, func(t *testing.T) {
+			if got := restartDelta(tst.prev, tst.cur, tst.seen, tst.baseline); got != tst.want {
+				t.Errorf("restartDelta() = %d, want %d", got, tst.want)
+			}
+		})
+	}
+}
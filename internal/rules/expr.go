@@ -0,0 +1,363 @@
+// Copyright © 2019 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+package rules
+
+import (
+	"math"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/pkg/errors"
+)
+
+// Expressions are arithmetic (+ - * / and parentheses) over numbers and aggregations
+// of the samples matching a selector, e.g.
+//
+//   sum(namespace_usage{resource:cpu}) / sum(namespace_requests{resource:cpu}) * 100
+//
+// A selector is a metric name and, optionally, stream tags which must all be present
+// on a sample for it to match, a tag value ending in * matches any value with the
+// prefix (e.g. code:5*). Aggregations are sum, avg, min, max, and count.
+
+// Expression is a parsed rule expression
+type Expression struct {
+	root    node
+	metrics map[string]bool
+}
+
+// ParseExpression parses a rule expression
+func ParseExpression(expr string) (*Expression, error) {
+	root, metrics, err := parse(expr)
+	if err != nil {
+		return nil, err
+	}
+	return &Expression{root: root, metrics: metrics}, nil
+}
+
+// Metrics returns the metric names referenced by the expression
+func (e *Expression) Metrics() []string {
+	names := make([]string, 0, len(e.metrics))
+	for name := range e.metrics {
+		names = append(names, name)
+	}
+	return names
+}
+
+// Eval evaluates the expression over the samples
+func (e *Expression) Eval(samples Samples) (float64, error) {
+	return e.root.eval(samples)
+}
+
+// Samples are the numeric samples queued for each metric, by metric name
+type Samples map[string][]sample
+
+// Add a sample, non-numeric values are ignored
+func (s Samples) Add(metricName string, streamTags []string, value interface{}) {
+	v, ok := toFloat(value)
+	if !ok {
+		return
+	}
+	tags := make(map[string]bool, len(streamTags))
+	for _, tag := range streamTags {
+		tags[tag] = true
+	}
+	s[metricName] = append(s[metricName], sample{tags: tags, value: v})
+}
+
+// sample is the value of a queued metric sample, with its stream tags
+type sample struct {
+	tags  map[string]bool
+	value float64
+}
+
+// matches returns true if the sample has all of the tags
+func (s sample) matches(tags []string) bool {
+	for _, tag := range tags {
+		if !strings.HasSuffix(tag, "*") {
+			if !s.tags[tag] {
+				return false
+			}
+			continue
+		}
+		prefix := strings.TrimSuffix(tag, "*")
+		found := false
+		for st := range s.tags {
+			if strings.HasPrefix(st, prefix) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// node is an evaluable expression node
+type node interface {
+	eval(samples Samples) (float64, error)
+}
+
+type number float64
+
+func (n number) eval(Samples) (float64, error) {
+	return float64(n), nil
+}
+
+type binary struct {
+	op          byte
+	left, right node
+}
+
+func (b *binary) eval(samples Samples) (float64, error) {
+	l, err := b.left.eval(samples)
+	if err != nil {
+		return 0, err
+	}
+	r, err := b.right.eval(samples)
+	if err != nil {
+		return 0, err
+	}
+	switch b.op {
+	case '+':
+		return l + r, nil
+	case '-':
+		return l - r, nil
+	case '*':
+		return l * r, nil
+	case '/':
+		if r == 0 {
+			return 0, errors.New("division by zero")
+		}
+		return l / r, nil
+	}
+	return 0, errors.Errorf("unknown operator (%c)", b.op)
+}
+
+type aggregation struct {
+	fn     string
+	metric string
+	tags   []string
+}
+
+func (a *aggregation) eval(samples Samples) (float64, error) {
+	var values []float64
+	for _, s := range samples[a.metric] {
+		if s.matches(a.tags) {
+			values = append(values, s.value)
+		}
+	}
+
+	switch a.fn {
+	case "count":
+		return float64(len(values)), nil
+	case "sum":
+		sum := 0.0
+		for _, v := range values {
+			sum += v
+		}
+		return sum, nil
+	}
+
+	if len(values) == 0 {
+		return 0, errors.Errorf("no samples for %s(%s)", a.fn, a.metric)
+	}
+	switch a.fn {
+	case "avg":
+		sum := 0.0
+		for _, v := range values {
+			sum += v
+		}
+		return sum / float64(len(values)), nil
+	case "min":
+		min := math.Inf(1)
+		for _, v := range values {
+			min = math.Min(min, v)
+		}
+		return min, nil
+	case "max":
+		max := math.Inf(-1)
+		for _, v := range values {
+			max = math.Max(max, v)
+		}
+		return max, nil
+	}
+	return 0, errors.Errorf("unknown aggregation (%s)", a.fn)
+}
+
+var aggregations = map[string]bool{"sum": true, "avg": true, "min": true, "max": true, "count": true}
+
+// parser is a recursive descent parser for rule expressions
+type parser struct {
+	input   string
+	pos     int
+	metrics map[string]bool // metric names referenced by the expression
+}
+
+// parse returns the expression tree and the metric names it references
+func parse(expr string) (node, map[string]bool, error) {
+	p := &parser{input: expr, metrics: make(map[string]bool)}
+	n, err := p.expr()
+	if err != nil {
+		return nil, nil, err
+	}
+	p.skipSpace()
+	if p.pos < len(p.input) {
+		return nil, nil, errors.Errorf("unexpected %q at %d", p.input[p.pos:], p.pos)
+	}
+	return n, p.metrics, nil
+}
+
+func (p *parser) skipSpace() {
+	for p.pos < len(p.input) && unicode.IsSpace(rune(p.input[p.pos])) {
+		p.pos++
+	}
+}
+
+// peek returns the next non-space character, 0 at the end of the input
+func (p *parser) peek() byte {
+	p.skipSpace()
+	if p.pos >= len(p.input) {
+		return 0
+	}
+	return p.input[p.pos]
+}
+
+func (p *parser) expect(c byte) error {
+	if p.peek() != c {
+		return errors.Errorf("expected %q at %d", c, p.pos)
+	}
+	p.pos++
+	return nil
+}
+
+// expr := term (('+'|'-') term)*
+func (p *parser) expr() (node, error) {
+	left, err := p.term()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		op := p.peek()
+		if op != '+' && op != '-' {
+			return left, nil
+		}
+		p.pos++
+		right, err := p.term()
+		if err != nil {
+			return nil, err
+		}
+		left = &binary{op: op, left: left, right: right}
+	}
+}
+
+// term := factor (('*'|'/') factor)*
+func (p *parser) term() (node, error) {
+	left, err := p.factor()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		op := p.peek()
+		if op != '*' && op != '/' {
+			return left, nil
+		}
+		p.pos++
+		right, err := p.factor()
+		if err != nil {
+			return nil, err
+		}
+		left = &binary{op: op, left: left, right: right}
+	}
+}
+
+// factor := number | '-' factor | '(' expr ')' | aggregation '(' selector ')'
+func (p *parser) factor() (node, error) {
+	c := p.peek()
+	switch {
+	case c == 0:
+		return nil, errors.New("unexpected end of expression")
+	case c == '-':
+		p.pos++
+		n, err := p.factor()
+		if err != nil {
+			return nil, err
+		}
+		return &binary{op: '-', left: number(0), right: n}, nil
+	case c == '(':
+		p.pos++
+		n, err := p.expr()
+		if err != nil {
+			return nil, err
+		}
+		if err := p.expect(')'); err != nil {
+			return nil, err
+		}
+		return n, nil
+	case c == '.' || (c >= '0' && c <= '9'):
+		start := p.pos
+		for p.pos < len(p.input) && (p.input[p.pos] == '.' || (p.input[p.pos] >= '0' && p.input[p.pos] <= '9')) {
+			p.pos++
+		}
+		v, err := strconv.ParseFloat(p.input[start:p.pos], 64)
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid number at %d", start)
+		}
+		return number(v), nil
+	}
+
+	fn := p.ident()
+	if !aggregations[fn] {
+		return nil, errors.Errorf("unknown aggregation %q at %d", fn, p.pos)
+	}
+	if err := p.expect('('); err != nil {
+		return nil, err
+	}
+	metric := p.ident()
+	if metric == "" {
+		return nil, errors.Errorf("expected metric name at %d", p.pos)
+	}
+	agg := &aggregation{fn: fn, metric: metric}
+	if p.peek() == '{' {
+		p.pos++
+		end := strings.IndexByte(p.input[p.pos:], '}')
+		if end == -1 {
+			return nil, errors.Errorf("unterminated selector at %d", p.pos)
+		}
+		for _, tag := range strings.Split(p.input[p.pos:p.pos+end], ",") {
+			tag = strings.TrimSpace(tag)
+			if tag == "" {
+				continue
+			}
+			if !strings.Contains(tag, ":") {
+				return nil, errors.Errorf("invalid tag %q, expected category:value", tag)
+			}
+			agg.tags = append(agg.tags, tag)
+		}
+		p.pos += end + 1
+	}
+	if err := p.expect(')'); err != nil {
+		return nil, err
+	}
+	p.metrics[metric] = true
+	return agg, nil
+}
+
+// ident returns the next metric name or aggregation function name
+func (p *parser) ident() string {
+	p.skipSpace()
+	start := p.pos
+	for p.pos < len(p.input) {
+		c := rune(p.input[p.pos])
+		if !unicode.IsLetter(c) && !unicode.IsDigit(c) && c != '_' && c != '.' && c != ':' {
+			break
+		}
+		p.pos++
+	}
+	return p.input[start:p.pos]
+}
@@ -0,0 +1,180 @@
+// Copyright © 2019 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+// Package rules is the recording rule engine, deriving metrics from the
+// samples collected during an interval
+package rules
+
+import (
+	"context"
+	"io/ioutil"
+	"sync"
+	"time"
+
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/circonus"
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/config"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog"
+	yaml "gopkg.in/yaml.v2"
+)
+
+// Example rules file:
+//
+//   rules:
+//     - name: cluster_cpu_request_utilization
+//       expr: sum(namespace_usage{resource:cpu}) / sum(namespace_requests{resource:cpu})
+//       tags:
+//         - units:ratio
+//     - name: cluster_ready_nodes_pct
+//       expr: sum(Ready) / count(Ready) * 100
+//
+// Rules are evaluated once per interval, after all collectors have finished, over the
+// metric samples queued during the interval. Only samples of collected (enabled) metrics
+// are available, regardless of the check metric filters.
+
+type ruleFile struct {
+	Rules []ruleConfig `yaml:"rules"`
+}
+
+type ruleConfig struct {
+	Name string   `yaml:"name"`
+	Expr string   `yaml:"expr"`
+	Tags []string `yaml:"tags"`
+}
+
+type rule struct {
+	name string
+	expr *Expression
+	tags []string
+}
+
+type Rules struct {
+	check   *circonus.Check
+	log     zerolog.Logger
+	rules   []rule
+	metrics map[string]bool // metric names referenced by the rules
+	samples Samples         // samples queued during the current interval
+	sync.Mutex
+}
+
+func New(cfg *config.Cluster, parentLog zerolog.Logger, check *circonus.Check) (*Rules, error) {
+	if cfg == nil {
+		return nil, errors.New("invalid cluster config (nil)")
+	}
+	if check == nil {
+		return nil, errors.New("invalid check (nil)")
+	}
+	if cfg.RecordingRulesFile == "" {
+		return nil, errors.New("invalid recording rules file (empty)")
+	}
+
+	data, err := ioutil.ReadFile(cfg.RecordingRulesFile)
+	if err != nil {
+		return nil, errors.Wrap(err, "reading recording rules file")
+	}
+
+	r := &Rules{
+		check:   check,
+		log:     parentLog.With().Str("pkg", "rules").Logger(),
+		samples: make(Samples),
+	}
+	if err := r.load(data); err != nil {
+		return nil, err
+	}
+
+	r.log.Info().Int("rules", len(r.rules)).Msg("recording rules loaded")
+
+	return r, nil
+}
+
+// load parses and validates the rules
+func (r *Rules) load(data []byte) error {
+	var rf ruleFile
+	if err := yaml.Unmarshal(data, &rf); err != nil {
+		return errors.Wrap(err, "parsing recording rules")
+	}
+
+	r.metrics = make(map[string]bool)
+	names := make(map[string]bool)
+	for i, rc := range rf.Rules {
+		if rc.Name == "" {
+			return errors.Errorf("invalid recording rule %d, name is required", i)
+		}
+		if names[rc.Name] {
+			return errors.Errorf("invalid recording rule %s, duplicate name", rc.Name)
+		}
+		names[rc.Name] = true
+		expr, err := ParseExpression(rc.Expr)
+		if err != nil {
+			return errors.Wrapf(err, "invalid recording rule %s expression", rc.Name)
+		}
+		for _, m := range expr.Metrics() {
+			r.metrics[m] = true
+		}
+		r.rules = append(r.rules, rule{name: rc.Name, expr: expr, tags: rc.Tags})
+	}
+
+	return nil
+}
+
+// Record keeps the numeric samples of metrics referenced by the rules, called
+// by the check for each queued metric sample
+func (r *Rules) Record(metricName string, streamTags []string, value interface{}) {
+	if !r.metrics[metricName] {
+		return
+	}
+	r.Lock()
+	r.samples.Add(metricName, streamTags, value)
+	r.Unlock()
+}
+
+// Evaluate the rules over the samples recorded since the last evaluation and submit the results
+func (r *Rules) Evaluate(ctx context.Context, ts *time.Time) {
+	r.Lock()
+	samples := r.samples
+	r.samples = make(Samples)
+	r.Unlock()
+
+	metrics := make(map[string]circonus.MetricSample)
+	for _, rl := range r.rules {
+		v, err := rl.expr.Eval(samples)
+		if err != nil {
+			r.log.Debug().Err(err).Str("rule", rl.name).Msg("skipping rule")
+			continue
+		}
+		streamTags := append([]string{"source:recording-rules"}, rl.tags...)
+		_ = r.check.QueueMetricSample(metrics, rl.name, circonus.MetricTypeFloat64, streamTags, []string{}, v, ts)
+	}
+
+	if len(metrics) == 0 {
+		return
+	}
+	if err := r.check.SubmitQueue(ctx, metrics, r.log.With().Str("type", "recording-rules").Logger()); err != nil {
+		r.log.Warn().Err(err).Msg("submitting metrics")
+	}
+}
+
+// toFloat converts numeric sample values, false for any other value
+func toFloat(value interface{}) (float64, bool) {
+	switch v := value.(type) {
+	case float64:
+		return v, true
+	case float32:
+		return float64(v), true
+	case int:
+		return float64(v), true
+	case int32:
+		return float64(v), true
+	case int64:
+		return float64(v), true
+	case uint:
+		return float64(v), true
+	case uint32:
+		return float64(v), true
+	case uint64:
+		return float64(v), true
+	}
+	return 0, false
+}
@@ -0,0 +1,101 @@
+// Copyright © 2019 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+package rules
+
+import (
+	"math"
+	"testing"
+)
+
+func TestEval(t *testing.T) {
+	r := &Rules{samples: make(Samples)}
+	r.metrics = map[string]bool{"namespace_usage": true, "namespace_requests": true, "Ready": true}
+	r.Record("namespace_usage", []string{"namespace:a", "resource:cpu"}, 0.5)
+	r.Record("namespace_usage", []string{"namespace:b", "resource:cpu"}, float64(1))
+	r.Record("namespace_usage", []string{"namespace:a", "resource:memory"}, float64(1024))
+	r.Record("namespace_requests", []string{"namespace:a", "resource:cpu"}, float64(1))
+	r.Record("namespace_requests", []string{"namespace:b", "resource:cpu"}, float64(2))
+	r.Record("Ready", []string{"node:a"}, uint64(1))
+	r.Record("Ready", []string{"node:b"}, uint64(0))
+	r.Record("Ready", []string{"node:c"}, "text ignored")
+	r.Record("other", []string{}, float64(1))
+
+	tests := []struct {
+		expr    string
+		want    float64
+		wantErr bool
+	}{
+		{"sum(namespace_usage{resource:cpu}) / sum(namespace_requests{resource:cpu})", 0.5, false},
+		{"sum(Ready) / count(Ready) * 100", 50, false},
+		{"max(namespace_usage{resource:cpu, namespace:a}) + 1", 1.5, false},
+		{"-(min(namespace_requests) - avg(namespace_requests))", 0.5, false},
+		{"(2 + 3) * 4 - 10 / 4", 17.5, false},
+		{"sum(namespace_usage{resource:gpu})", 0, false},
+		{"count(namespace_usage{resource:c*})", 2, false},
+		{"avg(namespace_usage{resource:gpu})", 0, true},
+		{"1 / sum(namespace_usage{resource:gpu})", 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			n, _, err := parse(tt.expr)
+			if err != nil {
+				t.Fatalf("unexpected parse error (%s)", err)
+			}
+			got, err := n.eval(r.samples)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("expected error, got %v", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error (%s)", err)
+			}
+			if math.Abs(got-tt.want) > 1e-9 {
+				t.Errorf("eval() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []string{
+		"",
+		"sum(namespace_usage",
+		"rate(namespace_usage)",
+		"sum(namespace_usage{resource}) ",
+		"sum(namespace_usage{resource:cpu)",
+		"1 +",
+		"1 2",
+	}
+	for _, expr := range tests {
+		t.Run(expr, func(t *testing.T) {
+			if _, _, err := parse(expr); err == nil {
+				t.Errorf("expected error for %q", expr)
+			}
+		})
+	}
+}
+
+func TestLoad(t *testing.T) {
+	r := &Rules{}
+	err := r.load([]byte(`
+rules:
+  - name: cluster_cpu_request_utilization
+    expr: sum(namespace_usage{resource:cpu}) / sum(namespace_requests{resource:cpu})
+    tags: ["units:ratio"]
+`))
+	if err != nil {
+		t.Fatalf("unexpected error (%s)", err)
+	}
+	if len(r.rules) != 1 || !r.metrics["namespace_usage"] || !r.metrics["namespace_requests"] {
+		t.Errorf("unexpected rules %+v metrics %v", r.rules, r.metrics)
+	}
+
+	if err := (&Rules{}).load([]byte("rules:\n  - name: a\n    expr: sum(x)\n  - name: a\n    expr: sum(y)\n")); err == nil {
+		t.Error("expected duplicate name error")
+	}
+}
@@ -0,0 +1,301 @@
+// Copyright © 2019 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+// Package schedfailures is the pending pod scheduling failure collector
+package schedfailures
+
+import (
+	"context"
+	"crypto/tls"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	cgm "github.com/circonus-labs/circonus-gometrics/v3"
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/circonus"
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/config"
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/config/defaults"
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/k8s"
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/release"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+type SchedFailures struct {
+	config       *config.Cluster
+	check        *circonus.Check
+	log          zerolog.Logger
+	clientset    kubernetes.Interface
+	lastCollect  time.Time
+	running      bool
+	apiTimelimit time.Duration
+	sync.Mutex
+	ts *time.Time
+}
+
+// NOTES:
+// Pending pods are listed and the scheduler message of the PodScheduled condition (e.g. "0/5 nodes
+// are available: 3 Insufficient cpu, 2 node(s) had taint {...}, that the pod didn't tolerate.") is
+// parsed into failure reason buckets, counting the pending pods failing for each reason (a pod can
+// fail for several reasons on different nodes). FailedScheduling events seen since the previous
+// collection are bucketed the same way.
+
+func New(cfg *config.Cluster, parentLog zerolog.Logger, check *circonus.Check) (*SchedFailures, error) {
+	if cfg == nil {
+		return nil, errors.New("invalid cluster config (nil)")
+	}
+	if check == nil {
+		return nil, errors.New("invalid check (nil)")
+	}
+
+	sf := &SchedFailures{
+		config:      cfg,
+		check:       check,
+		lastCollect: time.Now(),
+		log:         parentLog.With().Str("collector", "scheduling-failures").Logger(),
+	}
+
+	if cfg.APITimelimit != "" {
+		v, err := time.ParseDuration(cfg.APITimelimit)
+		if err != nil {
+			sf.log.Error().Err(err).Msg("parsing api timelimit, using default")
+		} else {
+			sf.apiTimelimit = v
+		}
+	}
+
+	if sf.apiTimelimit == time.Duration(0) {
+		v, err := time.ParseDuration(defaults.K8SAPITimelimit)
+		if err != nil {
+			sf.log.Fatal().Err(err).Msg("parsing DEFAULT api timelimit")
+		}
+		sf.apiTimelimit = v
+	}
+
+	restCfg, err := k8s.RESTConfig(cfg)
+	if err != nil {
+		return nil, errors.Wrap(err, "rest config")
+	}
+	restCfg.Timeout = sf.apiTimelimit
+	clientset, err := kubernetes.NewForConfig(restCfg)
+	if err != nil {
+		return nil, errors.Wrap(err, "clientset")
+	}
+	sf.clientset = clientset
+
+	return sf, nil
+}
+
+func (sf *SchedFailures) ID() string {
+	return "scheduling-failures"
+}
+
+// Collect pending pod scheduling failures
+func (sf *SchedFailures) Collect(ctx context.Context, _ *tls.Config, ts *time.Time) {
+	sf.Lock()
+	if sf.running {
+		sf.log.Warn().Msg("already running")
+		sf.Unlock()
+		return
+	}
+	sf.running = true
+	sf.ts = ts
+	since := sf.lastCollect
+	sf.Unlock()
+
+	defer func() {
+		if r := recover(); r != nil {
+			sf.log.Error().Interface("panic", r).Msg("recover")
+			sf.Lock()
+			sf.running = false
+			sf.Unlock()
+		}
+	}()
+
+	collectStart := time.Now()
+
+	metrics := make(map[string]circonus.MetricSample)
+	sf.pendingMetrics(metrics)
+	sf.eventMetrics(metrics, since)
+	if len(metrics) > 0 {
+		if err := sf.check.SubmitQueue(ctx, metrics, sf.log.With().Str("type", "scheduling-failures").Logger()); err != nil {
+			sf.log.Warn().Err(err).Msg("submitting metrics")
+		}
+	}
+
+	sf.check.AddHistSample("collect_latency", cgm.Tags{
+		cgm.Tag{Category: "source", Value: release.NAME},
+		cgm.Tag{Category: "op", Value: "collect_scheduling-failures"},
+		cgm.Tag{Category: "units", Value: "milliseconds"},
+	}, float64(time.Since(collectStart).Milliseconds()))
+	sf.log.Debug().Str("duration", time.Since(collectStart).String()).Msg("scheduling-failures collect end")
+	sf.Lock()
+	sf.lastCollect = collectStart
+	sf.running = false
+	sf.Unlock()
+}
+
+// pendingMetrics queues the number of pending and unschedulable pods, and the number
+// of unschedulable pods failing for each reason
+func (sf *SchedFailures) pendingMetrics(metrics map[string]circonus.MetricSample) {
+	pods, err := sf.clientset.CoreV1().Pods(metav1.NamespaceAll).List(metav1.ListOptions{
+		FieldSelector: "status.phase=" + string(corev1.PodPending),
+		LabelSelector: sf.config.PodSelector,
+	})
+	if err != nil {
+		sf.apiError("pod-list")
+		sf.log.Error().Err(err).Msg("listing pending pods")
+		return
+	}
+
+	var unschedulable uint64
+	reasons := make(map[string]uint64)
+	for i := range pods.Items {
+		if !sf.check.AllowNamespace(pods.Items[i].Namespace) {
+			continue
+		}
+		cond := scheduledCondition(&pods.Items[i])
+		if cond == nil || cond.Status != corev1.ConditionFalse || cond.Reason != corev1.PodReasonUnschedulable {
+			continue
+		}
+		unschedulable++
+		for reason := range failureReasons(cond.Message) {
+			reasons[reason]++
+		}
+	}
+
+	streamTags := []string{
+		"source:scheduling-failures",
+		"source_type:scheduler",
+	}
+	_ = sf.check.QueueMetricSample(metrics, "pending_pods", circonus.MetricTypeUint64, streamTags, []string{}, uint64(len(pods.Items)), sf.ts)
+	_ = sf.check.QueueMetricSample(metrics, "pending_pods_unschedulable", circonus.MetricTypeUint64, streamTags, []string{}, unschedulable, sf.ts)
+	for reason, count := range reasons {
+		_ = sf.check.QueueMetricSample(metrics, "pending_pods_unschedulable", circonus.MetricTypeUint64, append(streamTags, "reason:"+reason), []string{}, count, sf.ts)
+	}
+}
+
+// eventMetrics queues the number of FailedScheduling events, by reason, seen since the last collection
+func (sf *SchedFailures) eventMetrics(metrics map[string]circonus.MetricSample, since time.Time) {
+	events, err := sf.clientset.CoreV1().Events(metav1.NamespaceAll).List(metav1.ListOptions{
+		FieldSelector: "reason=FailedScheduling",
+	})
+	if err != nil {
+		sf.apiError("event-list")
+		sf.log.Error().Err(err).Msg("listing FailedScheduling events")
+		return
+	}
+
+	reasons := make(map[string]uint64)
+	for i := range events.Items {
+		event := &events.Items[i]
+		if !sf.check.AllowNamespace(event.InvolvedObject.Namespace) {
+			continue
+		}
+		last := event.LastTimestamp.Time
+		if last.IsZero() {
+			last = event.EventTime.Time
+		}
+		if !last.After(since) {
+			continue
+		}
+		for reason := range failureReasons(event.Message) {
+			reasons[reason]++
+		}
+	}
+
+	streamTags := []string{
+		"source:scheduling-failures",
+		"source_type:events",
+	}
+	for reason, count := range reasons {
+		_ = sf.check.QueueMetricSample(metrics, "scheduling_failure_events", circonus.MetricTypeUint64, append(streamTags, "reason:"+reason), []string{}, count, sf.ts)
+	}
+}
+
+func (sf *SchedFailures) apiError(request string) {
+	sf.check.IncrementCounter("collect_api_errors", cgm.Tags{
+		cgm.Tag{Category: "source", Value: release.NAME},
+		cgm.Tag{Category: "request", Value: request},
+		cgm.Tag{Category: "target", Value: "api-server"},
+	})
+}
+
+// scheduledCondition returns the pod PodScheduled condition, nil if not set
+func scheduledCondition(pod *corev1.Pod) *corev1.PodCondition {
+	for i := range pod.Status.Conditions {
+		if pod.Status.Conditions[i].Type == corev1.PodScheduled {
+			return &pod.Status.Conditions[i]
+		}
+	}
+	return nil
+}
+
+// reasonBuckets map scheduler predicate messages to failure reasons, checked in order
+var reasonBuckets = []struct {
+	match  string
+	reason string
+}{
+	{"insufficient cpu", "insufficient_cpu"},
+	{"insufficient memory", "insufficient_memory"},
+	{"insufficient ephemeral-storage", "insufficient_ephemeral_storage"},
+	{"insufficient pods", "insufficient_pods"},
+	{"too many pods", "insufficient_pods"},
+	{"insufficient ", "insufficient_other"}, // extended resources (e.g. nvidia.com/gpu)
+	{"taint", "taint"},
+	{"volume node affinity conflict", "volume_affinity"},
+	{"persistentvolume", "volume_binding"},
+	{"volume", "volume_binding"},
+	{"pod affinity", "pod_affinity"},
+	{"pod anti-affinity", "pod_affinity"},
+	{"affinity/selector", "node_affinity"},
+	{"node affinity", "node_affinity"},
+	{"node selector", "node_affinity"},
+	{"topology spread", "topology_spread"},
+	{"free ports", "ports"},
+	{"unschedulable", "node_unschedulable"},
+	{"not ready", "node_not_ready"},
+}
+
+var countPrefix = regexp.MustCompile(`^\d+\s+`)
+
+// failureReasons returns the failure reasons in a scheduler FailedScheduling message, e.g.
+// "0/3 nodes are available: 1 Insufficient cpu, 2 node(s) had taint {...}, that the pod didn't tolerate."
+func failureReasons(message string) map[string]bool {
+	reasons := make(map[string]bool)
+
+	msg := message
+	if i := strings.Index(msg, "available:"); i >= 0 {
+		msg = msg[i+len("available:"):]
+	}
+	if i := strings.Index(msg, "preemption:"); i >= 0 {
+		msg = msg[:i] // newer schedulers append the preemption result
+	}
+	msg = strings.TrimSpace(strings.TrimSuffix(strings.TrimSpace(msg), "."))
+	if msg == "" {
+		return reasons
+	}
+
+	for _, part := range strings.Split(msg, ", ") {
+		part = strings.ToLower(countPrefix.ReplaceAllString(strings.TrimSpace(part), ""))
+		if part == "" || strings.HasPrefix(part, "that the pod") {
+			continue // continuation of a taint message
+		}
+		reason := "other"
+		for _, b := range reasonBuckets {
+			if strings.Contains(part, b.match) {
+				reason = b.reason
+				break
+			}
+		}
+		reasons[reason] = true
+	}
+
+	return reasons
+}
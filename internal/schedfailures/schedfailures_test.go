@@ -0,0 +1,62 @@
+// Copyright © 2019 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+package schedfailures
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestFailureReasons(t *testing.T) {
+	tests := []struct {
+		name    string
+		message string
+		want    map[string]bool
+	}{
+		{
+			"resources and taint",
+			"0/5 nodes are available: 3 Insufficient cpu, 1 Insufficient memory, 2 node(s) had taint {node-role.kubernetes.io/master: }, that the pod didn't tolerate.",
+			map[string]bool{"insufficient_cpu": true, "insufficient_memory": true, "taint": true},
+		},
+		{
+			"volume affinity",
+			"0/3 nodes are available: 3 node(s) had volume node affinity conflict.",
+			map[string]bool{"volume_affinity": true},
+		},
+		{
+			"unbound pvc",
+			"pod has unbound immediate PersistentVolumeClaims",
+			map[string]bool{"volume_binding": true},
+		},
+		{
+			"gpu and selector with preemption",
+			"0/4 nodes are available: 2 Insufficient nvidia.com/gpu, 2 node(s) didn't match Pod's node affinity/selector. preemption: 0/4 nodes are available: 4 No preemption victims found for incoming pod.",
+			map[string]bool{"insufficient_other": true, "node_affinity": true},
+		},
+		{
+			"anti-affinity and unschedulable",
+			"0/3 nodes are available: 1 node(s) were unschedulable, 2 node(s) didn't match pod anti-affinity rules.",
+			map[string]bool{"node_unschedulable": true, "pod_affinity": true},
+		},
+		{
+			"unknown",
+			"0/1 nodes are available: 1 something new.",
+			map[string]bool{"other": true},
+		},
+		{
+			"empty",
+			"",
+			map[string]bool{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := failureReasons(tt.message); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("failureReasons() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,146 @@
+// Copyright © 2019 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+// Package scheduler is the kube-scheduler metrics collector
+package scheduler
+
+import (
+	"context"
+	"crypto/tls"
+	"regexp"
+	"sync"
+	"time"
+
+	cgm "github.com/circonus-labs/circonus-gometrics/v3"
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/circonus"
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/config"
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/config/defaults"
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/release"
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/scrape"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog"
+)
+
+// only forward scheduler specific metric families (e.g. scheduling latency,
+// pending pods, preemption attempts), not the go runtime/process metrics
+var familyFilter = regexp.MustCompile(`^scheduler_`)
+
+type Scheduler struct {
+	config       *config.Cluster
+	check        *circonus.Check
+	log          zerolog.Logger
+	running      bool
+	apiTimelimit time.Duration
+	sync.Mutex
+	ts *time.Time
+}
+
+// NOTES:
+// kube-scheduler normally runs as a static pod (kubeadm labels it component=kube-scheduler)
+// in kube-system with hostNetwork, metrics are on 10251 (http) or 10259 (https, needs authn/z).
+// Metrics are fetched through the api-server pod proxy unless an explicit endpoint is configured.
+
+func New(cfg *config.Cluster, parentLog zerolog.Logger, check *circonus.Check) (*Scheduler, error) {
+	if cfg == nil {
+		return nil, errors.New("invalid cluster config (nil)")
+	}
+	if check == nil {
+		return nil, errors.New("invalid check (nil)")
+	}
+
+	s := &Scheduler{
+		config: cfg,
+		check:  check,
+		log:    parentLog.With().Str("collector", "kube-scheduler").Logger(),
+	}
+
+	if cfg.APITimelimit != "" {
+		v, err := time.ParseDuration(cfg.APITimelimit)
+		if err != nil {
+			s.log.Error().Err(err).Msg("parsing api timelimit, using default")
+		} else {
+			s.apiTimelimit = v
+		}
+	}
+
+	if s.apiTimelimit == time.Duration(0) {
+		v, err := time.ParseDuration(defaults.K8SAPITimelimit)
+		if err != nil {
+			s.log.Fatal().Err(err).Msg("parsing DEFAULT api timelimit")
+		}
+		s.apiTimelimit = v
+	}
+
+	return s, nil
+}
+
+func (s *Scheduler) ID() string {
+	return "kube-scheduler"
+}
+
+// Collect metrics from kube-scheduler(s)
+func (s *Scheduler) Collect(ctx context.Context, tlsConfig *tls.Config, ts *time.Time) {
+	s.Lock()
+	if s.running {
+		s.log.Warn().Msg("already running")
+		s.Unlock()
+		return
+	}
+	s.running = true
+	s.ts = ts
+	s.Unlock()
+
+	defer func() {
+		if r := recover(); r != nil {
+			s.log.Error().Interface("panic", r).Msg("recover")
+			s.Lock()
+			s.running = false
+			s.Unlock()
+		}
+	}()
+
+	collectStart := time.Now()
+
+	if s.config.KubeSchedulerEndpoint != "" {
+		target := scrape.Target{
+			URL:          s.config.KubeSchedulerEndpoint,
+			BearerToken:  s.config.Token(),
+			Name:         "kube-scheduler",
+			FamilyFilter: familyFilter,
+			StreamTags: []string{
+				"source:kube-scheduler",
+				"source_type:metrics",
+				"__rollup:false", // prevent high cardinality metrics from rolling up
+			},
+		}
+		if err := scrape.Metrics(ctx, s.check, s.log, tlsConfig, s.apiTimelimit, target, s.ts); err != nil {
+			s.log.Error().Err(err).Str("url", target.URL).Msg("kube-scheduler metrics")
+		}
+	} else {
+		s.podMetrics(ctx, tlsConfig)
+	}
+
+	s.check.AddHistSample("collect_latency", cgm.Tags{
+		cgm.Tag{Category: "source", Value: release.NAME},
+		cgm.Tag{Category: "op", Value: "collect_kube-scheduler"},
+		cgm.Tag{Category: "units", Value: "milliseconds"},
+	}, float64(time.Since(collectStart).Milliseconds()))
+	s.log.Debug().Str("duration", time.Since(collectStart).String()).Msg("kube-scheduler collect end")
+	s.Lock()
+	s.running = false
+	s.Unlock()
+}
+
+// podMetrics collects metrics from each scheduler pod via the api-server proxy
+func (s *Scheduler) podMetrics(ctx context.Context, tlsConfig *tls.Config) {
+	scrape.PodMetrics(ctx, s.check, s.log, s.config, tlsConfig, s.apiTimelimit, scrape.Component{
+		Name:         "kube-scheduler",
+		Namespaces:   []string{s.config.KubeSchedulerNamespace},
+		Selector:     s.config.KubeSchedulerSelector,
+		Port:         s.config.KubeSchedulerPort,
+		FamilyFilter: familyFilter,
+		PodTag:       true,
+	}, s.ts)
+}
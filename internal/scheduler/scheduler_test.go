@@ -0,0 +1,57 @@
+// Copyright © 2019 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+package scheduler
+
+import (
+	"context"
+	"crypto/tls"
+	"testing"
+
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/config"
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/scrape/scrapetest"
+	"github.com/rs/zerolog"
+)
+
+func TestFamilyFilter(t *testing.T) {
+	tests := []struct {
+		family string
+		want   bool
+	}{
+		{"scheduler_pending_pods", true},
+		{"scheduler_e2e_scheduling_duration_seconds", true},
+		{"go_goroutines", false},
+		{"process_cpu_seconds_total", false},
+	}
+
+	for _, tt := range tests {
+		if got := familyFilter.MatchString(tt.family); got != tt.want {
+			t.Errorf("%s: expected %v, got %v", tt.family, tt.want, got)
+		}
+	}
+}
+
+func TestCollectEndpoint(t *testing.T) {
+	ts := scrapetest.NewServer(map[string]string{
+		"/metrics": "# TYPE scheduler_pending_pods gauge\nscheduler_pending_pods{queue=\"active\"} 2\n",
+	})
+	defer ts.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	check, rec := scrapetest.NewCheck(ctx, t)
+
+	// an explicit endpoint is scraped instead of the scheduler pods
+	s, err := New(&config.Cluster{
+		URL:                   ts.URL,
+		KubeSchedulerEndpoint: ts.URL + "/metrics",
+	}, zerolog.Nop(), check)
+	if err != nil {
+		t.Fatalf("unexpected error (%s)", err)
+	}
+	s.Collect(ctx, &tls.Config{}, nil)
+
+	scrapetest.ExpectTags(t, rec, "scheduler_pending_pods", "source:kube-scheduler", "queue:active")
+}
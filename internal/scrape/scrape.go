@@ -0,0 +1,382 @@
+// Copyright © 2019 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+// Package scrape contains common methods for collectors which scrape
+// prometheus metrics endpoints, directly or via the api-server proxy
+package scrape
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	cgm "github.com/circonus-labs/circonus-gometrics/v3"
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/circonus"
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/config"
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/k8s"
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/promtext"
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/release"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog"
+)
+
+// Target defines a prometheus metrics endpoint
+type Target struct {
+	URL             string
+	BearerToken     string         // sent as authorization bearer token, blank for none
+	Name            string         // target name used in collect_* metric tags (e.g. kube-scheduler)
+	Proxy           string         // proxy used (e.g. api-server), blank for direct
+	FamilyFilter    *regexp.Regexp // only forward matching metric families, nil for all
+	TLSConfig       *tls.Config    // overrides the tls config passed to Metrics, nil for default
+	StreamTags      []string
+	MeasurementTags []string
+}
+
+// Metrics fetches prometheus metrics from the target and queues them for submission
+func Metrics(ctx context.Context, check *circonus.Check, logger zerolog.Logger, tlsConfig *tls.Config, timelimit time.Duration, target Target, ts *time.Time) error {
+	if check == nil {
+		return errors.New("invalid check (nil)")
+	}
+	if target.URL == "" {
+		return errors.New("invalid target url (empty)")
+	}
+
+	if target.TLSConfig != nil {
+		tlsConfig = target.TLSConfig
+	}
+
+	client, err := k8s.NewAPIClient(tlsConfig, timelimit)
+	if err != nil {
+		return errors.Wrap(err, "/metrics cli")
+	}
+	defer client.CloseIdleConnections()
+
+	logger.Debug().Str("url", target.URL).Msg("metrics")
+	var req *http.Request
+	if target.BearerToken != "" {
+		req, err = k8s.NewAPIRequest(target.BearerToken, target.URL)
+	} else {
+		req, err = http.NewRequest("GET", target.URL, nil)
+	}
+	if err != nil {
+		return errors.Wrap(err, "/metrics req")
+	}
+	req = req.WithContext(ctx)
+
+	tags := cgm.Tags{
+		cgm.Tag{Category: "source", Value: release.NAME},
+		cgm.Tag{Category: "request", Value: "metrics"},
+		cgm.Tag{Category: "target", Value: target.Name},
+	}
+	if target.Proxy != "" {
+		tags = append(tags, cgm.Tag{Category: "proxy", Value: target.Proxy})
+	}
+
+	start := time.Now()
+	resp, err := client.Do(req)
+	if err != nil {
+		check.IncrementCounter("collect_api_errors", append(cgm.Tags{}, tags...))
+		return err
+	}
+	defer resp.Body.Close()
+	check.AddHistSample("collect_latency", append(append(cgm.Tags{}, tags...), cgm.Tag{Category: "units", Value: "milliseconds"}), float64(time.Since(start).Milliseconds()))
+
+	if resp.StatusCode != http.StatusOK {
+		check.IncrementCounter("collect_api_errors", append(append(cgm.Tags{}, tags...), cgm.Tag{Category: "code", Value: fmt.Sprintf("%d", resp.StatusCode)}))
+		data, err := ioutil.ReadAll(resp.Body)
+		if err != nil {
+			logger.Error().Err(err).Str("url", target.URL).Msg("reading response")
+			return err
+		}
+		logger.Warn().Str("url", target.URL).Str("status", resp.Status).Str("response", string(data)).Msg("error from target")
+		return errors.Errorf("error response from %s (%s)", target.Name, resp.Status)
+	}
+
+	streamTags := target.StreamTags
+	measurementTags := target.MeasurementTags
+	if measurementTags == nil {
+		measurementTags = []string{}
+	}
+
+	if target.FamilyFilter != nil {
+		return promtext.QueueFilteredMetrics(ctx, check, logger, resp.Body, target.FamilyFilter, streamTags, measurementTags, ts)
+	}
+	return promtext.QueueMetrics(ctx, check, logger, resp.Body, streamTags, measurementTags, ts)
+}
+
+// Pods returns the running pods in a namespace (blank for all namespaces) matching the label selector
+func Pods(check *circonus.Check, logger zerolog.Logger, cfg *config.Cluster, tlsConfig *tls.Config, timelimit time.Duration, namespace, labelSelector string) ([]*k8s.Pod, error) {
+	reqPath := "/api/v1/pods"
+	if namespace != "" {
+		reqPath = "/api/v1/namespaces/" + namespace + "/pods"
+	}
+
+	q := url.Values{}
+	q.Set("fieldSelector", "status.phase=Running")
+	if labelSelector != "" {
+		q.Set("labelSelector", labelSelector)
+	}
+
+	var pods k8s.PodList
+	if err := list(check, logger, cfg, tlsConfig, timelimit, reqPath, q, "pod-list", &pods); err != nil {
+		return nil, err
+	}
+
+	return pods.Items, nil
+}
+
+// Services returns the services in a namespace (blank for all namespaces) matching the label selector
+func Services(check *circonus.Check, logger zerolog.Logger, cfg *config.Cluster, tlsConfig *tls.Config, timelimit time.Duration, namespace, labelSelector string) ([]*k8s.Service, error) {
+	reqPath := "/api/v1/services"
+	if namespace != "" {
+		reqPath = "/api/v1/namespaces/" + namespace + "/services"
+	}
+
+	q := url.Values{}
+	if labelSelector != "" {
+		q.Set("labelSelector", labelSelector)
+	}
+
+	var svcs k8s.ServiceList
+	if err := list(check, logger, cfg, tlsConfig, timelimit, reqPath, q, "service-list", &svcs); err != nil {
+		return nil, err
+	}
+
+	return svcs.Items, nil
+}
+
+// list decodes an api-server list request into v
+func list(check *circonus.Check, logger zerolog.Logger, cfg *config.Cluster, tlsConfig *tls.Config, timelimit time.Duration, reqPath string, query url.Values, request string, v interface{}) error {
+	if cfg == nil {
+		return errors.New("invalid cluster config (nil)")
+	}
+
+	u, err := url.Parse(cfg.URL + reqPath)
+	if err != nil {
+		return err
+	}
+	u.RawQuery = query.Encode()
+
+	client, err := k8s.NewAPIClient(tlsConfig, timelimit)
+	if err != nil {
+		return errors.Wrap(err, request+" cli")
+	}
+	defer client.CloseIdleConnections()
+
+	reqURL := u.String()
+	logger.Debug().Str("url", reqURL).Msg(request)
+	req, err := k8s.NewAPIRequest(cfg.Token(), reqURL)
+	if err != nil {
+		return errors.Wrap(err, request+" req")
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		check.IncrementCounter("collect_api_errors", cgm.Tags{
+			cgm.Tag{Category: "source", Value: release.NAME},
+			cgm.Tag{Category: "request", Value: request},
+			cgm.Tag{Category: "target", Value: "api-server"},
+		})
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		check.IncrementCounter("collect_api_errors", cgm.Tags{
+			cgm.Tag{Category: "source", Value: release.NAME},
+			cgm.Tag{Category: "request", Value: request},
+			cgm.Tag{Category: "target", Value: "api-server"},
+			cgm.Tag{Category: "code", Value: fmt.Sprintf("%d", resp.StatusCode)},
+		})
+		data, err := ioutil.ReadAll(resp.Body)
+		if err != nil {
+			logger.Error().Err(err).Str("url", reqURL).Msg("reading response")
+			return err
+		}
+		logger.Warn().Str("url", reqURL).Str("status", resp.Status).RawJSON("response", data).Msg("error from API server")
+		return errors.Errorf("error from api %s", resp.Status)
+	}
+
+	return json.NewDecoder(resp.Body).Decode(v)
+}
+
+// PodProxyURL returns the api-server proxy url for a path on a pod port.
+// If the port is prefixed with 'https:' the proxied request will use https.
+// See: https://kubernetes.io/docs/tasks/access-application-cluster/access-cluster/#manually-constructing-apiserver-proxy-urls
+func PodProxyURL(apiURL string, pod *k8s.Pod, port, path string) string {
+	scheme := ""
+	if strings.HasPrefix(port, "https:") {
+		scheme = "https:"
+		port = strings.TrimPrefix(port, "https:")
+	}
+	if !strings.HasPrefix(path, "/") {
+		path = "/" + path
+	}
+	return apiURL + "/api/v1/namespaces/" + pod.Metadata.Namespace + "/pods/" + scheme + pod.Metadata.Name + ":" + port + "/proxy" + path
+}
+
+// ServiceProxyURL returns the api-server proxy url for a path on a service port.
+// If the port is prefixed with 'https:' the proxied request will use https.
+func ServiceProxyURL(apiURL string, svc *k8s.Service, port, path string) string {
+	scheme := ""
+	if strings.HasPrefix(port, "https:") {
+		scheme = "https:"
+		port = strings.TrimPrefix(port, "https:")
+	}
+	if !strings.HasPrefix(path, "/") {
+		path = "/" + path
+	}
+	return apiURL + "/api/v1/namespaces/" + svc.Metadata.Namespace + "/services/" + scheme + svc.Metadata.Name + ":" + port + "/proxy" + path
+}
+
+// MetricsPool fetches metrics from the targets using a pool of workers (e.g. one target per node
+// for daemonsets), errors are logged per target
+func MetricsPool(ctx context.Context, check *circonus.Check, logger zerolog.Logger, tlsConfig *tls.Config, timelimit time.Duration, targets []Target, workers int, ts *time.Time) {
+	if workers < 1 {
+		workers = 1
+	}
+	if workers > len(targets) {
+		workers = len(targets)
+	}
+
+	targetQueue := make(chan Target)
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for target := range targetQueue {
+				if err := Metrics(ctx, check, logger, tlsConfig, timelimit, target, ts); err != nil {
+					logger.Error().Err(err).Str("url", target.URL).Msg(target.Name + " metrics")
+				}
+			}
+		}()
+	}
+
+	for _, target := range targets {
+		targetQueue <- target
+	}
+	close(targetQueue)
+	wg.Wait()
+}
+
+// Component defines the pods of a component (e.g. kube-scheduler, a daemonset) to scrape
+type Component struct {
+	Name         string         // target name used in collect_* metric tags and log messages (e.g. kube-scheduler)
+	Source       string         // source stream tag, blank to use the name
+	Namespaces   []string       // namespaces of the pods, blank for all namespaces
+	Selector     string         // pod label selector
+	Port         string         // metrics port, prefixed with 'https:' to use https through the api-server proxy
+	Path         string         // metrics path, blank for /metrics
+	FamilyFilter *regexp.Regexp // only forward matching metric families, nil for all
+	Direct       bool           // scrape the pod ip directly rather than through the api-server pod proxy
+	PodTag       bool           // tag metrics with the pod name (e.g. not for one pod per node daemonsets)
+	NamespaceTag bool           // tag metrics with the pod namespace
+	Workers      int            // number of pods scraped concurrently, less than 1 for all
+}
+
+// PodMetrics lists the running pods of the component and queues the metrics of each pod, tagged
+// with the component source and the node (and the pod and namespace if configured)
+func PodMetrics(ctx context.Context, check *circonus.Check, logger zerolog.Logger, cfg *config.Cluster, tlsConfig *tls.Config, timelimit time.Duration, c Component, ts *time.Time) {
+	namespaces := c.Namespaces
+	if len(namespaces) == 0 {
+		namespaces = []string{""}
+	}
+
+	var targets []Target
+	for _, ns := range namespaces {
+		pods, err := Pods(check, logger, cfg, tlsConfig, timelimit, ns, c.Selector)
+		if err != nil {
+			logger.Error().Err(err).Str("namespace", ns).Msg("listing " + c.Name + " pods")
+			continue
+		}
+		for _, pod := range pods {
+			target, ok := c.target(cfg, pod)
+			if ok {
+				targets = append(targets, target)
+			}
+		}
+	}
+
+	if len(targets) == 0 {
+		logger.Warn().Str("selector", c.Selector).Msg("no " + c.Name + " pods found")
+		return
+	}
+
+	workers := c.Workers
+	if workers < 1 {
+		workers = len(targets)
+	}
+	MetricsPool(ctx, check, logger, tlsConfig, timelimit, targets, workers, ts)
+}
+
+// target returns the scrape target of a pod, false if the pod cannot be scraped directly (no pod ip yet)
+func (c Component) target(cfg *config.Cluster, pod *k8s.Pod) (Target, bool) {
+	source := c.Source
+	if source == "" {
+		source = c.Name
+	}
+	path := c.Path
+	if path == "" {
+		path = "/metrics"
+	}
+
+	streamTags := []string{
+		"source:" + source,
+		"source_type:metrics",
+	}
+	if c.NamespaceTag {
+		streamTags = append(streamTags, "namespace:"+pod.Metadata.Namespace)
+	}
+	if c.PodTag {
+		streamTags = append(streamTags, "pod:"+pod.Metadata.Name)
+	}
+	streamTags = append(streamTags,
+		"node:"+pod.Spec.NodeName,
+		"__rollup:false", // prevent high cardinality metrics from rolling up
+	)
+
+	target := Target{
+		Name:         c.Name,
+		FamilyFilter: c.FamilyFilter,
+		StreamTags:   streamTags,
+	}
+	if c.Direct {
+		if pod.Status.PodIP == "" {
+			return target, false
+		}
+		target.URL = "http://" + net.JoinHostPort(pod.Status.PodIP, c.Port) + path
+	} else {
+		target.URL = PodProxyURL(cfg.URL, pod, c.Port, path)
+		target.BearerToken = cfg.Token()
+		target.Proxy = "api-server"
+	}
+
+	return target, true
+}
+
+// Namespaces returns the list of namespaces from a comma separated list, blank for all namespaces
+func Namespaces(list string) []string {
+	var nsl []string
+	for _, ns := range strings.Split(list, ",") {
+		ns = strings.TrimSpace(ns)
+		if ns != "" {
+			nsl = append(nsl, ns)
+		}
+	}
+	if len(nsl) == 0 {
+		return []string{""}
+	}
+	return nsl
+}
@@ -0,0 +1,121 @@
+// Copyright © 2019 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+package scrape
+
+import (
+	"context"
+	"crypto/tls"
+	"reflect"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/config"
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/k8s"
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/scrape/scrapetest"
+	"github.com/rs/zerolog"
+)
+
+func TestPodProxyURL(t *testing.T) {
+	pod := &k8s.Pod{Metadata: k8s.PodMetadata{Name: "foo", Namespace: "bar"}}
+
+	tests := []struct {
+		name string
+		port string
+		path string
+		want string
+	}{
+		{"http", "10251", "/metrics", "https://kubernetes/api/v1/namespaces/bar/pods/foo:10251/proxy/metrics"},
+		{"https", "https:10259", "/metrics", "https://kubernetes/api/v1/namespaces/bar/pods/https:foo:10259/proxy/metrics"},
+		{"no slash", "9153", "metrics", "https://kubernetes/api/v1/namespaces/bar/pods/foo:9153/proxy/metrics"},
+	}
+
+	for _, tst := range tests {
+		tst := tst
+		t.Run(tst.name, func(t *testing.T) {
+			if got := PodProxyURL("https://kubernetes", pod, tst.port, tst.path); got != tst.want {
+				t.Errorf("PodProxyURL() = %s, want %s", got, tst.want)
+			}
+		})
+	}
+}
+
+func TestNamespaces(t *testing.T) {
+	tests := []struct {
+		name string
+		list string
+		want []string
+	}{
+		{"blank", "", []string{""}},
+		{"spaces", " , ", []string{""}},
+		{"one", "default", []string{"default"}},
+		{"multiple", "foo, bar,baz", []string{"foo", "bar", "baz"}},
+	}
+
+	for _, tst := range tests {
+		tst := tst
+		t.Run(tst.name, func(t *testing.T) {
+			if got := Namespaces(tst.list); !reflect.DeepEqual(got, tst.want) {
+				t.Errorf("Namespaces() = %v, want %v", got, tst.want)
+			}
+		})
+	}
+}
+
+func TestPodMetrics(t *testing.T) {
+	metrics := "# TYPE foo_total counter\nfoo_total{code=\"200\"} 3\n# TYPE go_goroutines gauge\ngo_goroutines 100\n"
+	ts := scrapetest.NewServer(map[string]string{
+		"/api/v1/namespaces/kube-system/pods":                             `{"items":[{"metadata":{"name":"foo-cp1","namespace":"kube-system"},"spec":{"nodeName":"cp1"},"status":{"phase":"Running","podIP":"127.0.0.1"}}]}`,
+		"/api/v1/namespaces/kube-system/pods/foo-cp1:10251/proxy/metrics": metrics,
+		"/api/v1/namespaces/app/pods":                                     `{"items":[{"metadata":{"name":"bar-1","namespace":"app"},"spec":{"nodeName":"n1"},"status":{"phase":"Running","podIP":"127.0.0.1"}},{"metadata":{"name":"bar-2","namespace":"app"},"spec":{"nodeName":"n2"},"status":{"phase":"Running"}}]}`,
+		"/stats/prometheus":                                               metrics,
+	})
+	defer ts.Close()
+	port := scrapetest.Port(t, ts)
+
+	cfg := &config.Cluster{URL: ts.URL}
+	filter := regexp.MustCompile(`^foo_`)
+
+	t.Run("api-server proxy", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		check, rec := scrapetest.NewCheck(ctx, t)
+
+		PodMetrics(ctx, check, zerolog.Nop(), cfg, &tls.Config{}, time.Second, Component{
+			Name:         "foo",
+			Namespaces:   []string{"kube-system"},
+			Port:         "10251",
+			FamilyFilter: filter,
+			PodTag:       true,
+		}, nil)
+
+		scrapetest.ExpectTags(t, rec, "foo_total", "source:foo", "source_type:metrics", "pod:foo-cp1", "node:cp1", "code:200")
+		scrapetest.ExpectFiltered(t, rec, "go_goroutines")
+	})
+
+	t.Run("direct", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		check, rec := scrapetest.NewCheck(ctx, t)
+
+		// bar-2 has no pod ip yet and is skipped
+		PodMetrics(ctx, check, zerolog.Nop(), cfg, &tls.Config{}, time.Second, Component{
+			Name:         "bar-proxy",
+			Source:       "bar",
+			Namespaces:   []string{"app"},
+			Port:         port,
+			Path:         "/stats/prometheus",
+			FamilyFilter: filter,
+			Direct:       true,
+			NamespaceTag: true,
+		}, nil)
+
+		scrapetest.ExpectTags(t, rec, "foo_total", "source:bar", "namespace:app", "node:n1")
+		if tags, _ := rec.Tags("foo_total"); scrapetest.HasTag(tags, "pod:bar-1") {
+			t.Errorf("expected no pod tag, got %v", tags)
+		}
+	})
+}
@@ -0,0 +1,125 @@
+// Copyright © 2019 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+// Package scrapetest provides the fixtures for testing collectors which scrape
+// prometheus metrics endpoints
+package scrapetest
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"sync"
+	"testing"
+
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/circonus"
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/config"
+	"github.com/rs/zerolog"
+)
+
+// Recorder keeps the stream tags of each metric queued
+type Recorder struct {
+	metrics map[string][]string
+	sync.Mutex
+}
+
+// Record implements circonus.Recorder
+func (r *Recorder) Record(metricName string, streamTags []string, value interface{}) {
+	r.Lock()
+	r.metrics[metricName] = streamTags
+	r.Unlock()
+}
+
+// Tags returns the stream tags of a metric, false if the metric was not queued
+func (r *Recorder) Tags(metricName string) ([]string, bool) {
+	r.Lock()
+	defer r.Unlock()
+	tags, ok := r.metrics[metricName]
+	return tags, ok
+}
+
+// Len returns the number of metrics queued
+func (r *Recorder) Len() int {
+	r.Lock()
+	defer r.Unlock()
+	return len(r.metrics)
+}
+
+// NewCheck returns a dry run check with a recorder, the check's submitter runs until ctx is done
+func NewCheck(ctx context.Context, t testing.TB) (*circonus.Check, *Recorder) {
+	check, err := circonus.NewCheck(zerolog.Nop(), &config.Circonus{
+		DryRun:           true,
+		DryRunOutput:     os.DevNull,
+		SubmitBackoffMin: "1s",
+		SubmitBackoffMax: "1s",
+	})
+	if err != nil {
+		t.Fatalf("unexpected error (%s)", err)
+	}
+	rec := &Recorder{metrics: make(map[string][]string)}
+	check.AddRecorder(rec)
+	go check.Submitter(ctx)
+	return check, rec
+}
+
+// NewServer returns a server responding with the body for each path, other paths are not found
+func NewServer(responses map[string]string) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, ok := responses[r.URL.Path]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		_, _ = w.Write([]byte(body))
+	}))
+}
+
+// Port returns the port of a test server, for pods scraped directly on 127.0.0.1
+func Port(t testing.TB, srv *httptest.Server) string {
+	u, err := url.Parse(srv.URL)
+	if err != nil {
+		t.Fatalf("unexpected error (%s)", err)
+	}
+	_, port, err := net.SplitHostPort(u.Host)
+	if err != nil {
+		t.Fatalf("unexpected error (%s)", err)
+	}
+	return port
+}
+
+// ExpectTags fails the test if the metric was not queued with each of the stream tags
+func ExpectTags(t testing.TB, rec *Recorder, metricName string, tags ...string) {
+	t.Helper()
+	got, ok := rec.Tags(metricName)
+	if !ok {
+		t.Fatalf("expected %s, got %v", metricName, rec.metrics)
+	}
+	for _, tag := range tags {
+		if !HasTag(got, tag) {
+			t.Errorf("%s: expected tag %s, got %v", metricName, tag, got)
+		}
+	}
+}
+
+// ExpectFiltered fails the test if the metric was queued
+func ExpectFiltered(t testing.TB, rec *Recorder, metricName string) {
+	t.Helper()
+	if _, ok := rec.Tags(metricName); ok {
+		t.Errorf("expected %s to be filtered", metricName)
+	}
+}
+
+// HasTag returns true if the tag is in the list of tags
+func HasTag(tags []string, tag string) bool {
+	for _, t := range tags {
+		if t == tag {
+			return true
+		}
+	}
+	return false
+}
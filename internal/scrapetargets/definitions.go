@@ -0,0 +1,192 @@
+// Copyright © 2019 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+package scrapetargets
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"io/ioutil"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+	yaml "gopkg.in/yaml.v2"
+)
+
+// Example definitions file:
+//
+//   targets:
+//     - name: my-exporter
+//       url: "https://my-exporter.monitoring.svc:9100/metrics"
+//       bearer_token_file: "/var/run/secrets/my-exporter/token"
+//       tls:
+//         ca_file: "/var/run/secrets/my-exporter/ca.crt"
+//       metric_filter: "^myapp_"
+//       tags:
+//         team: payments
+//     - name: other-exporter
+//       service:
+//         namespace: monitoring
+//         name: other-exporter
+//         port: "9102"
+//         path: "/metrics"
+//
+// A target is either a url, scraped directly, or a service port, scraped through the
+// api-server service proxy (prefix the port with 'https:' for https).
+
+type definitions struct {
+	Targets []*targetDef `yaml:"targets"`
+}
+
+type targetDef struct {
+	Name            string            `yaml:"name"`
+	URL             string            `yaml:"url"`
+	Service         *serviceDef       `yaml:"service"`
+	BearerTokenFile string            `yaml:"bearer_token_file"` // url targets only, blank for none
+	TLS             *tlsDef           `yaml:"tls"`               // url targets only, default system roots
+	MetricFilter    string            `yaml:"metric_filter"`     // only forward matching metric families, blank for all
+	Tags            map[string]string `yaml:"tags"`
+	familyFilter    *regexp.Regexp
+	tlsConfig       *tls.Config
+	streamTags      []string
+}
+
+type serviceDef struct {
+	Namespace string `yaml:"namespace"`
+	Name      string `yaml:"name"`
+	Port      string `yaml:"port"`
+	Path      string `yaml:"path"` // default /metrics
+}
+
+type tlsDef struct {
+	CAFile             string `yaml:"ca_file"`
+	CertFile           string `yaml:"cert_file"`
+	KeyFile            string `yaml:"key_file"`
+	InsecureSkipVerify bool   `yaml:"insecure_skip_verify"`
+}
+
+// loadDefinitions reads and validates the scrape target definitions file
+func loadDefinitions(file string) ([]*targetDef, error) {
+	if file == "" {
+		return nil, errors.New("invalid scrape targets file (empty)")
+	}
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, errors.Wrap(err, "reading scrape targets file")
+	}
+	return parseDefinitions(data)
+}
+
+// parseDefinitions parses the scrape target definitions, compiles the metric filters,
+// and loads the tls configurations
+func parseDefinitions(data []byte) ([]*targetDef, error) {
+	var defs definitions
+	if err := yaml.Unmarshal(data, &defs); err != nil {
+		return nil, errors.Wrap(err, "parsing scrape targets")
+	}
+
+	names := make(map[string]bool)
+	for _, def := range defs.Targets {
+		if def.Name == "" {
+			return nil, errors.New("invalid scrape target, name is required")
+		}
+		if names[def.Name] {
+			return nil, errors.Errorf("invalid scrape target (%s), duplicate name", def.Name)
+		}
+		names[def.Name] = true
+
+		switch {
+		case def.URL != "" && def.Service != nil:
+			return nil, errors.Errorf("invalid scrape target (%s), url OR service, not both", def.Name)
+		case def.URL == "" && def.Service == nil:
+			return nil, errors.Errorf("invalid scrape target (%s), url or service is required", def.Name)
+		case def.Service != nil:
+			if def.Service.Namespace == "" || def.Service.Name == "" || def.Service.Port == "" {
+				return nil, errors.Errorf("invalid scrape target (%s), service namespace, name, and port are required", def.Name)
+			}
+			if def.Service.Path == "" {
+				def.Service.Path = "/metrics"
+			}
+		}
+
+		if def.MetricFilter != "" {
+			rx, err := regexp.Compile(def.MetricFilter)
+			if err != nil {
+				return nil, errors.Wrapf(err, "compiling metric filter for %s", def.Name)
+			}
+			def.familyFilter = rx
+		}
+
+		if def.TLS != nil {
+			tlsConfig, err := def.TLS.config()
+			if err != nil {
+				return nil, errors.Wrapf(err, "tls config for %s", def.Name)
+			}
+			def.tlsConfig = tlsConfig
+		} else if def.URL != "" {
+			def.tlsConfig = &tls.Config{} // system roots, not the cluster ca
+		}
+
+		def.streamTags = streamTags(def)
+	}
+
+	return defs.Targets, nil
+}
+
+// config builds the tls config for a url target
+func (t *tlsDef) config() (*tls.Config, error) {
+	tlsConfig := &tls.Config{
+		InsecureSkipVerify: t.InsecureSkipVerify, //nolint:gosec
+	}
+
+	if t.CAFile != "" {
+		cert, err := ioutil.ReadFile(t.CAFile)
+		if err != nil {
+			return nil, errors.Wrap(err, "reading ca file")
+		}
+		cp := x509.NewCertPool()
+		if !cp.AppendCertsFromPEM(cert) {
+			return nil, errors.New("unable to add CA Certificate to x509 cert pool")
+		}
+		tlsConfig.RootCAs = cp
+	}
+
+	if t.CertFile != "" || t.KeyFile != "" {
+		if t.CertFile == "" || t.KeyFile == "" {
+			return nil, errors.New("client cert AND key are required")
+		}
+		cert, err := tls.LoadX509KeyPair(t.CertFile, t.KeyFile)
+		if err != nil {
+			return nil, errors.Wrap(err, "loading client cert")
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+
+	return tlsConfig, nil
+}
+
+// streamTags returns the stream tags for a target, user supplied tags are sorted by name
+func streamTags(def *targetDef) []string {
+	tags := []string{
+		"source:scrape-targets",
+		"source_type:metrics",
+		"target:" + def.Name,
+	}
+	if def.Service != nil {
+		tags = append(tags, "namespace:"+def.Service.Namespace, "service:"+def.Service.Name)
+	}
+	userTags := make([]string, 0, len(def.Tags))
+	for k, v := range def.Tags {
+		k = strings.TrimSpace(k)
+		if k == "" {
+			continue
+		}
+		userTags = append(userTags, k+":"+strings.TrimSpace(v))
+	}
+	sort.Strings(userTags)
+	return append(tags, userTags...)
+}
@@ -0,0 +1,169 @@
+// Copyright © 2019 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+// Package scrapetargets is the collector for prometheus metrics endpoints
+// listed in a yaml definitions file
+package scrapetargets
+
+import (
+	"context"
+	"crypto/tls"
+	"io/ioutil"
+	"strings"
+	"sync"
+	"time"
+
+	cgm "github.com/circonus-labs/circonus-gometrics/v3"
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/circonus"
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/config"
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/config/defaults"
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/k8s"
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/release"
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/scrape"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog"
+)
+
+type ScrapeTargets struct {
+	config       *config.Cluster
+	check        *circonus.Check
+	log          zerolog.Logger
+	targets      []*targetDef
+	running      bool
+	apiTimelimit time.Duration
+	sync.Mutex
+	ts *time.Time
+}
+
+// NOTES:
+// Targets are defined in a yaml file (see definitions.go). Url targets are scraped directly, the
+// agent service account token is never sent to them (bearer_token_file is read each collection so
+// rotated tokens are picked up). Service targets are scraped through the api-server service proxy.
+
+func New(cfg *config.Cluster, parentLog zerolog.Logger, check *circonus.Check) (*ScrapeTargets, error) {
+	if cfg == nil {
+		return nil, errors.New("invalid cluster config (nil)")
+	}
+	if check == nil {
+		return nil, errors.New("invalid check (nil)")
+	}
+
+	st := &ScrapeTargets{
+		config: cfg,
+		check:  check,
+		log:    parentLog.With().Str("collector", "scrape-targets").Logger(),
+	}
+
+	if cfg.APITimelimit != "" {
+		v, err := time.ParseDuration(cfg.APITimelimit)
+		if err != nil {
+			st.log.Error().Err(err).Msg("parsing api timelimit, using default")
+		} else {
+			st.apiTimelimit = v
+		}
+	}
+
+	if st.apiTimelimit == time.Duration(0) {
+		v, err := time.ParseDuration(defaults.K8SAPITimelimit)
+		if err != nil {
+			st.log.Fatal().Err(err).Msg("parsing DEFAULT api timelimit")
+		}
+		st.apiTimelimit = v
+	}
+
+	targets, err := loadDefinitions(cfg.ScrapeTargetsFile)
+	if err != nil {
+		return nil, errors.Wrap(err, "loading scrape target definitions")
+	}
+	if len(targets) == 0 {
+		return nil, errors.Errorf("no scrape targets defined (%s)", cfg.ScrapeTargetsFile)
+	}
+	st.targets = targets
+
+	return st, nil
+}
+
+func (st *ScrapeTargets) ID() string {
+	return "scrape-targets"
+}
+
+// Collect metrics from the defined scrape targets
+func (st *ScrapeTargets) Collect(ctx context.Context, tlsConfig *tls.Config, ts *time.Time) {
+	st.Lock()
+	if st.running {
+		st.log.Warn().Msg("already running")
+		st.Unlock()
+		return
+	}
+	st.running = true
+	st.ts = ts
+	st.Unlock()
+
+	defer func() {
+		if r := recover(); r != nil {
+			st.log.Error().Interface("panic", r).Msg("recover")
+			st.Lock()
+			st.running = false
+			st.Unlock()
+		}
+	}()
+
+	collectStart := time.Now()
+
+	targets := make([]scrape.Target, 0, len(st.targets))
+	for _, def := range st.targets {
+		target, err := st.target(def)
+		if err != nil {
+			st.log.Error().Err(err).Str("target", def.Name).Msg("scrape target")
+			continue
+		}
+		targets = append(targets, target)
+	}
+
+	if len(targets) > 0 {
+		scrape.MetricsPool(ctx, st.check, st.log, tlsConfig, st.apiTimelimit, targets, int(st.config.NodePoolSize), st.ts)
+	}
+
+	st.check.AddHistSample("collect_latency", cgm.Tags{
+		cgm.Tag{Category: "source", Value: release.NAME},
+		cgm.Tag{Category: "op", Value: "collect_scrape-targets"},
+		cgm.Tag{Category: "units", Value: "milliseconds"},
+	}, float64(time.Since(collectStart).Milliseconds()))
+	st.log.Debug().Int("targets", len(targets)).Str("duration", time.Since(collectStart).String()).Msg("scrape-targets collect end")
+	st.Lock()
+	st.running = false
+	st.Unlock()
+}
+
+// target returns the scrape target for a definition
+func (st *ScrapeTargets) target(def *targetDef) (scrape.Target, error) {
+	if def.Service != nil {
+		svc := &k8s.Service{Metadata: k8s.ServiceMetadata{Namespace: def.Service.Namespace, Name: def.Service.Name}}
+		return scrape.Target{
+			URL:          scrape.ServiceProxyURL(st.config.URL, svc, def.Service.Port, def.Service.Path),
+			BearerToken:  st.config.Token(),
+			Name:         def.Name,
+			Proxy:        "api-server",
+			FamilyFilter: def.familyFilter,
+			StreamTags:   def.streamTags,
+		}, nil
+	}
+
+	target := scrape.Target{
+		URL:          def.URL,
+		Name:         def.Name,
+		FamilyFilter: def.familyFilter,
+		TLSConfig:    def.tlsConfig,
+		StreamTags:   def.streamTags,
+	}
+	if def.BearerTokenFile != "" {
+		token, err := ioutil.ReadFile(def.BearerTokenFile)
+		if err != nil {
+			return scrape.Target{}, errors.Wrap(err, "reading bearer token file")
+		}
+		target.BearerToken = strings.TrimSpace(string(token))
+	}
+	return target, nil
+}
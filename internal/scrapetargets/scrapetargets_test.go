@@ -0,0 +1,63 @@
+// Copyright © 2019 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+package scrapetargets
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseDefinitions(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		wantErr bool
+	}{
+		{"valid url", "targets:\n  - name: foo\n    url: http://foo.default.svc:9100/metrics\n", false},
+		{"valid service", "targets:\n  - name: foo\n    service:\n      namespace: default\n      name: foo\n      port: \"9100\"\n", false},
+		{"invalid yaml", "targets: [", true},
+		{"no name", "targets:\n  - url: http://foo/metrics\n", true},
+		{"duplicate name", "targets:\n  - name: foo\n    url: http://foo/metrics\n  - name: foo\n    url: http://bar/metrics\n", true},
+		{"no url or service", "targets:\n  - name: foo\n", true},
+		{"url and service", "targets:\n  - name: foo\n    url: http://foo/metrics\n    service:\n      namespace: default\n      name: foo\n      port: \"9100\"\n", true},
+		{"service no port", "targets:\n  - name: foo\n    service:\n      namespace: default\n      name: foo\n", true},
+		{"bad filter", "targets:\n  - name: foo\n    url: http://foo/metrics\n    metric_filter: \"^(foo\"\n", true},
+		{"cert no key", "targets:\n  - name: foo\n    url: https://foo/metrics\n    tls:\n      cert_file: /tmp/foo.crt\n", true},
+	}
+
+	for _, tst := range tests {
+		tst := tst
+		t.Run(tst.name, func(t *testing.T) {
+			_, err := parseDefinitions([]byte(tst.data))
+			if (err != nil) != tst.wantErr {
+				t.Errorf("parseDefinitions() error = %v, wantErr %t", err, tst.wantErr)
+			}
+		})
+	}
+}
+
+func TestStreamTags(t *testing.T) {
+	defs, err := parseDefinitions([]byte("targets:\n  - name: foo\n    service:\n      namespace: default\n      name: foo-svc\n      port: \"9100\"\n    tags:\n      team: payments\n      env: prod\n"))
+	if err != nil {
+		t.Fatalf("unexpected error (%s)", err)
+	}
+
+	want := []string{
+		"source:scrape-targets",
+		"source_type:metrics",
+		"target:foo",
+		"namespace:default",
+		"service:foo-svc",
+		"env:prod",
+		"team:payments",
+	}
+	if !reflect.DeepEqual(defs[0].streamTags, want) {
+		t.Errorf("streamTags = %v, want %v", defs[0].streamTags, want)
+	}
+	if defs[0].Service.Path != "/metrics" {
+		t.Errorf("service path = %s, want /metrics", defs[0].Service.Path)
+	}
+}
@@ -0,0 +1,336 @@
+// Copyright © 2019 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+// Package slo computes multi-window error budget burn rates for service
+// level objectives defined over the collected metrics
+package slo
+
+import (
+	"context"
+	"io/ioutil"
+	"sync"
+	"time"
+
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/circonus"
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/config"
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/rules"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog"
+	yaml "gopkg.in/yaml.v2"
+)
+
+// Example slos file:
+//
+//   slos:
+//     - name: apiserver-availability
+//       objective: 99.9
+//       bad: sum(apiserver_request_total{code:5*})
+//       total: sum(apiserver_request_total)
+//     - name: dns-availability
+//       objective: 99
+//       good: sum(coredns_dns_responses_total{rcode:NOERROR}) + sum(coredns_dns_responses_total{rcode:NXDOMAIN})
+//       total: sum(coredns_dns_responses_total)
+//       windows: [5m, 1h, 6h, 3d]
+//
+// The bad (or good) and total expressions use the recording rule expression syntax and
+// must evaluate to cumulative counts (e.g. prometheus counters), the counts for each
+// interval are the differences between evaluations. For each window the error ratio is
+// bad/total and the burn rate is the error ratio divided by the error budget (1-objective),
+// a burn rate of 1 consumes the budget exactly over the slo period.
+//
+// NOTES:
+// History is kept in memory, after the agent starts a window is only emitted once the
+// agent has been running for the length of the window. Prometheus histograms are
+// submitted as circonus histograms, not as individual bucket samples, so latency slos
+// need a counter of the requests within the latency threshold.
+
+// defaultWindows are the short/long window pairs of the common multi-window,
+// multi-burn-rate alerts (5m/1h and 30m/6h)
+var defaultWindows = []string{"5m", "30m", "1h", "6h"}
+
+type sloFile struct {
+	SLOs []sloConfig `yaml:"slos"`
+}
+
+type sloConfig struct {
+	Name      string   `yaml:"name"`
+	Objective float64  `yaml:"objective"` // percent
+	Bad       string   `yaml:"bad"`
+	Good      string   `yaml:"good"`
+	Total     string   `yaml:"total"`
+	Windows   []string `yaml:"windows"`
+	Tags      []string `yaml:"tags"`
+}
+
+// point is the bad and total counts of one interval
+type point struct {
+	ts    time.Time
+	bad   float64
+	total float64
+}
+
+type window struct {
+	name string
+	d    time.Duration
+}
+
+type slo struct {
+	name      string
+	objective float64 // ratio, e.g. 0.999
+	bad       *rules.Expression
+	good      *rules.Expression
+	total     *rules.Expression
+	windows   []window
+	tags      []string
+	started   time.Time // time of the first evaluation (baseline)
+	lastBad   float64
+	lastTotal float64
+	history   []point
+}
+
+type SLOs struct {
+	check   *circonus.Check
+	log     zerolog.Logger
+	slos    []*slo
+	metrics map[string]bool // metric names referenced by the slos
+	samples rules.Samples   // samples queued during the current interval
+	sync.Mutex
+}
+
+func New(cfg *config.Cluster, parentLog zerolog.Logger, check *circonus.Check) (*SLOs, error) {
+	if cfg == nil {
+		return nil, errors.New("invalid cluster config (nil)")
+	}
+	if check == nil {
+		return nil, errors.New("invalid check (nil)")
+	}
+	if cfg.SLOsFile == "" {
+		return nil, errors.New("invalid slos file (empty)")
+	}
+
+	data, err := ioutil.ReadFile(cfg.SLOsFile)
+	if err != nil {
+		return nil, errors.Wrap(err, "reading slos file")
+	}
+
+	s := &SLOs{
+		check:   check,
+		log:     parentLog.With().Str("pkg", "slo").Logger(),
+		samples: make(rules.Samples),
+	}
+	if err := s.load(data); err != nil {
+		return nil, err
+	}
+
+	s.log.Info().Int("slos", len(s.slos)).Msg("slos loaded")
+
+	return s, nil
+}
+
+// load parses and validates the slos
+func (s *SLOs) load(data []byte) error {
+	var sf sloFile
+	if err := yaml.Unmarshal(data, &sf); err != nil {
+		return errors.Wrap(err, "parsing slos")
+	}
+
+	s.metrics = make(map[string]bool)
+	names := make(map[string]bool)
+	for i, sc := range sf.SLOs {
+		if sc.Name == "" {
+			return errors.Errorf("invalid slo %d, name is required", i)
+		}
+		if names[sc.Name] {
+			return errors.Errorf("invalid slo %s, duplicate name", sc.Name)
+		}
+		names[sc.Name] = true
+		if sc.Objective <= 0 || sc.Objective >= 100 {
+			return errors.Errorf("invalid slo %s, objective must be between 0 and 100 (exclusive)", sc.Name)
+		}
+		if (sc.Bad == "") == (sc.Good == "") {
+			return errors.Errorf("invalid slo %s, one of bad or good is required", sc.Name)
+		}
+
+		o := &slo{
+			name:      sc.Name,
+			objective: sc.Objective / 100,
+			tags:      sc.Tags,
+		}
+
+		var err error
+		exprs := []struct {
+			name string
+			expr string
+			dst  **rules.Expression
+		}{
+			{"bad", sc.Bad, &o.bad},
+			{"good", sc.Good, &o.good},
+			{"total", sc.Total, &o.total},
+		}
+		for _, e := range exprs {
+			if e.expr == "" && e.name != "total" {
+				continue
+			}
+			if *e.dst, err = rules.ParseExpression(e.expr); err != nil {
+				return errors.Wrapf(err, "invalid slo %s %s expression", sc.Name, e.name)
+			}
+			for _, m := range (*e.dst).Metrics() {
+				s.metrics[m] = true
+			}
+		}
+
+		windows := sc.Windows
+		if len(windows) == 0 {
+			windows = defaultWindows
+		}
+		for _, w := range windows {
+			d, err := time.ParseDuration(w)
+			if err != nil {
+				return errors.Wrapf(err, "invalid slo %s window", sc.Name)
+			}
+			if d <= 0 {
+				return errors.Errorf("invalid slo %s window %s", sc.Name, w)
+			}
+			o.windows = append(o.windows, window{name: w, d: d})
+		}
+
+		s.slos = append(s.slos, o)
+	}
+
+	return nil
+}
+
+// Record keeps the numeric samples of metrics referenced by the slos, called
+// by the check for each queued metric sample
+func (s *SLOs) Record(metricName string, streamTags []string, value interface{}) {
+	if !s.metrics[metricName] {
+		return
+	}
+	s.Lock()
+	s.samples.Add(metricName, streamTags, value)
+	s.Unlock()
+}
+
+// Evaluate the slos over the samples recorded since the last evaluation and submit the burn rates
+func (s *SLOs) Evaluate(ctx context.Context, ts *time.Time) {
+	s.Lock()
+	samples := s.samples
+	s.samples = make(rules.Samples)
+	s.Unlock()
+
+	metrics := make(map[string]circonus.MetricSample)
+	for _, o := range s.slos {
+		if err := o.update(samples, *ts); err != nil {
+			s.log.Debug().Err(err).Str("slo", o.name).Msg("skipping slo")
+			continue
+		}
+		for _, w := range o.windows {
+			if ts.Sub(o.started) < w.d {
+				continue // not enough history for the window yet
+			}
+			bad, total := o.sums(*ts, w.d)
+			ratio, ok := errorRatio(bad, total)
+			if !ok {
+				continue
+			}
+			streamTags := append([]string{"source:slo", "slo:" + o.name, "window:" + w.name}, o.tags...)
+			_ = s.check.QueueMetricSample(metrics, "slo_error_ratio", circonus.MetricTypeFloat64, streamTags, []string{}, ratio, ts)
+			_ = s.check.QueueMetricSample(metrics, "slo_burn_rate", circonus.MetricTypeFloat64, streamTags, []string{}, burnRate(ratio, o.objective), ts)
+		}
+	}
+
+	if len(metrics) == 0 {
+		return
+	}
+	if err := s.check.SubmitQueue(ctx, metrics, s.log.With().Str("type", "slo").Logger()); err != nil {
+		s.log.Warn().Err(err).Msg("submitting metrics")
+	}
+}
+
+// update evaluates the slo counts and adds the interval's point to the history
+func (o *slo) update(samples rules.Samples, ts time.Time) error {
+	total, err := o.total.Eval(samples)
+	if err != nil {
+		return errors.Wrap(err, "total")
+	}
+	var bad float64
+	if o.bad != nil {
+		if bad, err = o.bad.Eval(samples); err != nil {
+			return errors.Wrap(err, "bad")
+		}
+	} else {
+		good, err := o.good.Eval(samples)
+		if err != nil {
+			return errors.Wrap(err, "good")
+		}
+		bad = total - good
+	}
+
+	if o.started.IsZero() {
+		o.started = ts
+	} else {
+		o.history = append(o.history, point{
+			ts:    ts,
+			bad:   delta(o.lastBad, bad),
+			total: delta(o.lastTotal, total),
+		})
+	}
+	o.lastBad = bad
+	o.lastTotal = total
+
+	// prune points older than the longest window
+	var longest time.Duration
+	for _, w := range o.windows {
+		if w.d > longest {
+			longest = w.d
+		}
+	}
+	i := 0
+	for i < len(o.history) && ts.Sub(o.history[i].ts) >= longest {
+		i++
+	}
+	o.history = o.history[i:]
+
+	return nil
+}
+
+// sums returns the bad and total counts of the points within the window ending at ts
+func (o *slo) sums(ts time.Time, d time.Duration) (float64, float64) {
+	var bad, total float64
+	for _, p := range o.history {
+		if ts.Sub(p.ts) < d {
+			bad += p.bad
+			total += p.total
+		}
+	}
+	return bad, total
+}
+
+// delta returns the increase of a cumulative count, after a reset (e.g. a
+// restarted process) the current value is the increase
+func delta(prev, cur float64) float64 {
+	if cur < prev {
+		return cur
+	}
+	return cur - prev
+}
+
+// errorRatio returns bad/total, false if there were no events
+func errorRatio(bad, total float64) (float64, bool) {
+	if total <= 0 {
+		return 0, false
+	}
+	ratio := bad / total
+	if ratio < 0 {
+		ratio = 0
+	}
+	return ratio, true
+}
+
+// burnRate returns the rate the error budget is being consumed, relative to the
+// rate which would exactly exhaust it over the slo period
+func burnRate(errorRatio, objective float64) float64 {
+	return errorRatio / (1 - objective)
+}
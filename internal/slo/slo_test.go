@@ -0,0 +1,108 @@
+// Copyright © 2019 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+package slo
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/rules"
+)
+
+func TestBurnRate(t *testing.T) {
+	tests := []struct {
+		bad, total, objective float64
+		want                  float64
+		wantOK                bool
+	}{
+		{1, 1000, 0.999, 1, true},
+		{14.4, 1000, 0.999, 14.4, true},
+		{0, 1000, 0.99, 0, true},
+		{0, 0, 0.99, 0, false},
+	}
+
+	for _, tt := range tests {
+		ratio, ok := errorRatio(tt.bad, tt.total)
+		if ok != tt.wantOK {
+			t.Fatalf("errorRatio(%v, %v) ok = %v, want %v", tt.bad, tt.total, ok, tt.wantOK)
+		}
+		if !ok {
+			continue
+		}
+		if got := burnRate(ratio, tt.objective); math.Abs(got-tt.want) > 1e-9 {
+			t.Errorf("burnRate(%v/%v, %v) = %v, want %v", tt.bad, tt.total, tt.objective, got, tt.want)
+		}
+	}
+}
+
+func TestDelta(t *testing.T) {
+	tests := []struct {
+		prev, cur, want float64
+	}{
+		{10, 15, 5},
+		{10, 10, 0},
+		{10, 3, 3}, // counter reset
+	}
+
+	for _, tt := range tests {
+		if got := delta(tt.prev, tt.cur); got != tt.want {
+			t.Errorf("delta(%v, %v) = %v, want %v", tt.prev, tt.cur, got, tt.want)
+		}
+	}
+}
+
+func TestWindows(t *testing.T) {
+	s := &SLOs{}
+	err := s.load([]byte(`
+slos:
+  - name: availability
+    objective: 99
+    bad: sum(requests{code:5*})
+    total: sum(requests)
+    windows: [2m, 4m]
+`))
+	if err != nil {
+		t.Fatalf("load: %s", err)
+	}
+	o := s.slos[0]
+
+	start := time.Now()
+	counts := []struct{ ok, errs float64 }{{100, 0}, {200, 0}, {300, 10}, {400, 20}, {500, 20}}
+	for i, c := range counts {
+		samples := make(rules.Samples)
+		samples.Add("requests", []string{"code:200"}, c.ok)
+		samples.Add("requests", []string{"code:503"}, c.errs)
+		if err := o.update(samples, start.Add(time.Duration(i)*time.Minute)); err != nil {
+			t.Fatalf("update: %s", err)
+		}
+	}
+
+	ts := start.Add(4 * time.Minute)
+	if bad, total := o.sums(ts, 2*time.Minute); bad != 10 || total != 210 {
+		t.Errorf("2m window = %v/%v, want 10/210", bad, total)
+	}
+	if bad, total := o.sums(ts, 4*time.Minute); bad != 20 || total != 420 {
+		t.Errorf("4m window = %v/%v, want 20/420", bad, total)
+	}
+}
+
+func TestLoadInvalid(t *testing.T) {
+	tests := []string{
+		"slos: [{name: a, objective: 100, bad: sum(x), total: sum(y)}]",
+		"slos: [{name: a, objective: 99, total: sum(y)}]",
+		"slos: [{name: a, objective: 99, bad: sum(x), good: sum(x), total: sum(y)}]",
+		"slos: [{name: a, objective: 99, bad: sum(x)}]",
+		"slos: [{name: a, objective: 99, bad: sum(x), total: sum(y), windows: [bogus]}]",
+	}
+
+	for _, tt := range tests {
+		s := &SLOs{}
+		if err := s.load([]byte(tt)); err == nil {
+			t.Errorf("load(%q) expected error", tt)
+		}
+	}
+}
@@ -0,0 +1,287 @@
+// Copyright © 2019 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+// Package spot is the spot/preemptible node interruption collector
+package spot
+
+import (
+	"context"
+	"crypto/tls"
+	"sync"
+	"time"
+
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/circonus"
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/config"
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/k8s"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/cache"
+)
+
+type signal struct {
+	provider string
+	name     string
+}
+
+// taintSignals are the node taints applied when an interruption notice is received
+var taintSignals = map[string]signal{
+	"aws-node-termination-handler/spot-itn":                  {"aws", "spot_itn"},
+	"aws-node-termination-handler/rebalance-recommendation":  {"aws", "rebalance_recommendation"},
+	"aws-node-termination-handler/scheduled-maintenance":     {"aws", "scheduled_event"},
+	"aws-node-termination-handler/asg-lifecycle-termination": {"aws", "asg_lifecycle"},
+	"cloud.google.com/impending-node-termination":            {"gcp", "preemption"},
+}
+
+// eventSignals are the node event reasons emitted when an interruption notice is received
+var eventSignals = map[string]signal{
+	"SpotInterruption":        {"aws", "spot_itn"},                 // aws-node-termination-handler
+	"RebalanceRecommendation": {"aws", "rebalance_recommendation"}, // aws-node-termination-handler
+	"ScheduledEvent":          {"aws", "scheduled_event"},          // aws-node-termination-handler
+	"ASGLifecycle":            {"aws", "asg_lifecycle"},            // aws-node-termination-handler
+	"SpotInterrupted":         {"aws", "spot_itn"},                 // karpenter
+}
+
+type Spot struct {
+	config      *config.Cluster
+	check       *circonus.Check
+	log         zerolog.Logger
+	start       time.Time                // events before start are not counted
+	interrupted map[string]*interruption // interrupted nodes, by name
+	events      *circonus.EventMetrics
+	sync.Mutex
+}
+
+type interruption struct {
+	start    time.Time
+	signal   signal          // first signal received, used for the drain duration
+	signals  map[string]bool // signals already counted
+	nodeTags []string
+}
+
+// NOTES:
+// Interruption notices are not visible to kubernetes directly, a handler running in the
+// cluster (e.g. aws-node-termination-handler, karpenter, or the gke node termination handler)
+// taints the node and/or emits a node event when a notice is received. Nodes and node events
+// are watched for the well-known taints and event reasons, each signal is counted once per
+// node. The drain duration is the time from the first signal until the node object is deleted.
+
+func New(cfg *config.Cluster, parentLog zerolog.Logger, check *circonus.Check) (*Spot, error) {
+	if cfg == nil {
+		return nil, errors.New("invalid cluster config (nil)")
+	}
+	if check == nil {
+		return nil, errors.New("invalid check (nil)")
+	}
+
+	return &Spot{
+		config:      cfg,
+		check:       check,
+		log:         parentLog.With().Str("collector", "spot-interruptions").Logger(),
+		start:       time.Now(),
+		interrupted: make(map[string]*interruption),
+		events:      circonus.NewEventMetrics(),
+	}, nil
+}
+
+func (s *Spot) ID() string {
+	return "spot-interruptions"
+}
+
+// Start watching nodes and node events, does not return until ctx is done
+func (s *Spot) Start(ctx context.Context, _ *tls.Config) {
+	s.log.Info().Msg("starting watcher")
+
+	cfg, err := k8s.RESTConfig(s.config)
+	if err != nil {
+		s.log.Error().Err(err).Msg("unable to start spot interruption watcher")
+		return
+	}
+
+	clientset, err := kubernetes.NewForConfig(cfg)
+	if err != nil {
+		s.log.Error().Err(err).Msg("initializing client set")
+		return
+	}
+
+	nodeFactory := informers.NewSharedInformerFactory(clientset, 0)
+	nodeInformer := nodeFactory.Core().V1().Nodes().Informer()
+	eventFactory := informers.NewSharedInformerFactoryWithOptions(clientset, 0,
+		informers.WithTweakListOptions(func(opts *metav1.ListOptions) {
+			opts.FieldSelector = "involvedObject.kind=Node"
+		}))
+	eventInformer := eventFactory.Core().V1().Events().Informer()
+	stopper := make(chan struct{})
+	defer close(stopper)
+	defer runtime.HandleCrash()
+
+	nodeInformer.AddEventHandler(cache.ResourceEventHandlerFuncs{
+		UpdateFunc: func(oldObj interface{}, newObj interface{}) {
+			oldNode := oldObj.(*corev1.Node)
+			newNode := newObj.(*corev1.Node)
+			for _, sig := range newSignals(oldNode.Spec.Taints, newNode.Spec.Taints) {
+				s.record(newNode.Name, nodeTags(newNode), sig)
+			}
+		},
+		DeleteFunc: func(obj interface{}) {
+			switch n := obj.(type) {
+			case *corev1.Node:
+				s.drained(n.Name)
+			case cache.DeletedFinalStateUnknown:
+				if node, ok := n.Obj.(*corev1.Node); ok {
+					s.drained(node.Name)
+				}
+			}
+		},
+	})
+
+	eventInformer.AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc: func(obj interface{}) {
+			ev := obj.(*corev1.Event)
+			if eventTime(ev).Before(s.start) {
+				return
+			}
+			sig, ok := eventSignals[ev.Reason]
+			if !ok {
+				return
+			}
+			var tags []string
+			if item, found, err := nodeInformer.GetStore().GetByKey(ev.InvolvedObject.Name); err == nil && found {
+				if node, ok := item.(*corev1.Node); ok {
+					tags = nodeTags(node)
+				}
+			}
+			s.record(ev.InvolvedObject.Name, tags, sig)
+		},
+	})
+
+	go nodeInformer.Run(stopper)
+	go eventInformer.Run(stopper)
+
+	if !cache.WaitForCacheSync(stopper, nodeInformer.HasSynced, eventInformer.HasSynced) {
+		s.log.Warn().Msg("timed out waiting for cache to sync")
+		return
+	}
+
+	<-ctx.Done()
+	s.log.Debug().Msg("closing spot interruption watcher")
+}
+
+// Collect queues the interruptions and drain durations recorded since the previous collection
+func (s *Spot) Collect(ctx context.Context, _ *tls.Config, ts *time.Time) {
+	metrics := make(map[string]circonus.MetricSample)
+	s.events.Queue(s.check, metrics, ts)
+	if len(metrics) == 0 {
+		return
+	}
+	if err := s.check.SubmitQueue(ctx, metrics, s.log.With().Str("type", "spot-interruptions").Logger()); err != nil {
+		s.log.Warn().Err(err).Msg("submitting metrics")
+	}
+}
+
+// record counts an interruption, once per node and signal
+func (s *Spot) record(nodeName string, tags []string, sig signal) {
+	s.Lock()
+	ni, found := s.interrupted[nodeName]
+	if !found {
+		ni = &interruption{
+			start:    time.Now(),
+			signal:   sig,
+			signals:  make(map[string]bool),
+			nodeTags: tags,
+		}
+		s.interrupted[nodeName] = ni
+	}
+	if ni.signals[sig.name] {
+		s.Unlock()
+		return
+	}
+	ni.signals[sig.name] = true
+	if len(ni.nodeTags) == 0 {
+		ni.nodeTags = tags
+	}
+	tags = ni.nodeTags
+	s.Unlock()
+
+	s.log.Info().Str("node", nodeName).Str("provider", sig.provider).Str("signal", sig.name).Msg("node interruption")
+	s.events.Increment("spot_interruptions", append(signalTags(sig), tags...), 1)
+}
+
+// drained records the drain duration of a deleted node which had received an interruption signal
+func (s *Spot) drained(nodeName string) {
+	s.Lock()
+	ni, found := s.interrupted[nodeName]
+	if found {
+		delete(s.interrupted, nodeName)
+	}
+	s.Unlock()
+	if !found {
+		return
+	}
+
+	tags := append(signalTags(ni.signal), ni.nodeTags...)
+	tags = append(tags, "units:seconds")
+	s.events.AddSample("spot_node_drain_duration", tags, time.Since(ni.start).Seconds())
+}
+
+func signalTags(sig signal) []string {
+	return []string{
+		"source:spot",
+		"provider:" + sig.provider,
+		"signal:" + sig.name,
+	}
+}
+
+// nodeTags returns the instance type and zone tags of a node (node names are not
+// used, spot nodes are short lived)
+func nodeTags(node *corev1.Node) []string {
+	tags := []string{}
+	for _, label := range []string{"node.kubernetes.io/instance-type", "beta.kubernetes.io/instance-type"} {
+		if v := node.Labels[label]; v != "" {
+			tags = append(tags, "instance_type:"+v)
+			break
+		}
+	}
+	for _, label := range []string{"topology.kubernetes.io/zone", "failure-domain.beta.kubernetes.io/zone"} {
+		if v := node.Labels[label]; v != "" {
+			tags = append(tags, "zone:"+v)
+			break
+		}
+	}
+	return tags
+}
+
+// newSignals returns the interruption signals for taints present in current but not in previous
+func newSignals(previous, current []corev1.Taint) []signal {
+	prev := make(map[string]bool, len(previous))
+	for _, t := range previous {
+		prev[t.Key] = true
+	}
+	var sigs []signal
+	for _, t := range current {
+		if prev[t.Key] {
+			continue
+		}
+		if sig, ok := taintSignals[t.Key]; ok {
+			sigs = append(sigs, sig)
+		}
+	}
+	return sigs
+}
+
+// eventTime returns the last time the event occurred
+func eventTime(ev *corev1.Event) time.Time {
+	if !ev.LastTimestamp.IsZero() {
+		return ev.LastTimestamp.Time
+	}
+	if !ev.EventTime.IsZero() {
+		return ev.EventTime.Time
+	}
+	return ev.CreationTimestamp.Time
+}
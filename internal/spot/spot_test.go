@@ -0,0 +1,78 @@
+// Copyright © 2019 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+package spot
+
+import (
+	"os"
+	"reflect"
+	"testing"
+
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/circonus"
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/config"
+	"github.com/rs/zerolog"
+	corev1 "k8s.io/api/core/v1"
+)
+
+func TestNewSignals(t *testing.T) {
+	itn := corev1.Taint{Key: "aws-node-termination-handler/spot-itn", Effect: corev1.TaintEffectNoSchedule}
+	gke := corev1.Taint{Key: "cloud.google.com/impending-node-termination", Effect: corev1.TaintEffectNoSchedule}
+	cordon := corev1.Taint{Key: "node.kubernetes.io/unschedulable", Effect: corev1.TaintEffectNoSchedule}
+
+	tests := []struct {
+		name     string
+		previous []corev1.Taint
+		current  []corev1.Taint
+		want     []signal
+	}{
+		{"none", nil, []corev1.Taint{cordon}, nil},
+		{"spot itn", []corev1.Taint{cordon}, []corev1.Taint{cordon, itn}, []signal{{"aws", "spot_itn"}}},
+		{"already tainted", []corev1.Taint{itn}, []corev1.Taint{itn}, nil},
+		{"gke", nil, []corev1.Taint{gke}, []signal{{"gcp", "preemption"}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := newSignals(tt.previous, tt.current); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("newSignals() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRecord(t *testing.T) {
+	check, err := circonus.NewCheck(zerolog.Nop(), &config.Circonus{DryRun: true, DryRunOutput: os.DevNull, DefaultStreamtags: "cluster:test", SubmitBackoffMin: "1s", SubmitBackoffMax: "1s"})
+	if err != nil {
+		t.Fatalf("unexpected error (%s)", err)
+	}
+	s, err := New(&config.Cluster{}, zerolog.Nop(), check)
+	if err != nil {
+		t.Fatalf("unexpected error (%s)", err)
+	}
+
+	sig := signal{"aws", "spot_itn"}
+	s.record("node1", []string{"instance_type:m5.large", "zone:us-east-1a"}, sig)
+	s.record("node1", nil, sig) // same node and signal, counted once
+	s.drained("node1")
+	s.drained("node2") // not interrupted
+
+	metrics := make(map[string]circonus.MetricSample)
+	s.events.Queue(check, metrics, nil)
+
+	if len(metrics) != 2 {
+		t.Fatalf("expected 2 metrics, got %v", metrics)
+	}
+	name := "spot_interruptions|ST[cluster:test,instance_type:m5.large,provider:aws,signal:spot_itn,source:spot,zone:us-east-1a]"
+	ms, ok := metrics[name]
+	if !ok {
+		t.Fatalf("expected %s, got %v", name, metrics)
+	}
+	if ms.Value != uint64(1) {
+		t.Fatalf("%s: expected 1, got %v", name, ms.Value)
+	}
+	name = "spot_node_drain_duration|ST[cluster:test,instance_type:m5.large,provider:aws,signal:spot_itn,source:spot,units:seconds,zone:us-east-1a]"
+	if ms, ok := metrics[name]; !ok || ms.Type != circonus.MetricTypeHistogram {
+		t.Fatalf("expected histogram %s, got %v", name, metrics)
+	}
+}
@@ -0,0 +1,238 @@
+// Copyright © 2019 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+// Package storage is the persistent volume and claim inventory collector
+package storage
+
+import (
+	"context"
+	"crypto/tls"
+	"sync"
+	"time"
+
+	cgm "github.com/circonus-labs/circonus-gometrics/v3"
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/circonus"
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/config"
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/config/defaults"
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/k8s"
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/release"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+type Storage struct {
+	config       *config.Cluster
+	check        *circonus.Check
+	log          zerolog.Logger
+	clientset    kubernetes.Interface
+	running      bool
+	apiTimelimit time.Duration
+	sync.Mutex
+	ts *time.Time
+}
+
+// NOTES:
+// Persistent volumes and claims are listed from the api-server, counts and capacity totals are
+// emitted by storage class and phase. This complements the kubelet-side volume stats
+// (per claim usage) with control-plane state.
+
+func New(cfg *config.Cluster, parentLog zerolog.Logger, check *circonus.Check) (*Storage, error) {
+	if cfg == nil {
+		return nil, errors.New("invalid cluster config (nil)")
+	}
+	if check == nil {
+		return nil, errors.New("invalid check (nil)")
+	}
+
+	s := &Storage{
+		config: cfg,
+		check:  check,
+		log:    parentLog.With().Str("collector", "storage").Logger(),
+	}
+
+	if cfg.APITimelimit != "" {
+		v, err := time.ParseDuration(cfg.APITimelimit)
+		if err != nil {
+			s.log.Error().Err(err).Msg("parsing api timelimit, using default")
+		} else {
+			s.apiTimelimit = v
+		}
+	}
+
+	if s.apiTimelimit == time.Duration(0) {
+		v, err := time.ParseDuration(defaults.K8SAPITimelimit)
+		if err != nil {
+			s.log.Fatal().Err(err).Msg("parsing DEFAULT api timelimit")
+		}
+		s.apiTimelimit = v
+	}
+
+	restCfg, err := k8s.RESTConfig(cfg)
+	if err != nil {
+		return nil, errors.Wrap(err, "rest config")
+	}
+	restCfg.Timeout = s.apiTimelimit
+	clientset, err := kubernetes.NewForConfig(restCfg)
+	if err != nil {
+		return nil, errors.Wrap(err, "clientset")
+	}
+	s.clientset = clientset
+
+	return s, nil
+}
+
+func (s *Storage) ID() string {
+	return "storage"
+}
+
+// Collect persistent volume and claim inventory
+func (s *Storage) Collect(ctx context.Context, _ *tls.Config, ts *time.Time) {
+	s.Lock()
+	if s.running {
+		s.log.Warn().Msg("already running")
+		s.Unlock()
+		return
+	}
+	s.running = true
+	s.ts = ts
+	s.Unlock()
+
+	defer func() {
+		if r := recover(); r != nil {
+			s.log.Error().Interface("panic", r).Msg("recover")
+			s.Lock()
+			s.running = false
+			s.Unlock()
+		}
+	}()
+
+	collectStart := time.Now()
+
+	s.volumeMetrics(ctx)
+	s.claimMetrics(ctx)
+
+	s.check.AddHistSample("collect_latency", cgm.Tags{
+		cgm.Tag{Category: "source", Value: release.NAME},
+		cgm.Tag{Category: "op", Value: "collect_storage"},
+		cgm.Tag{Category: "units", Value: "milliseconds"},
+	}, float64(time.Since(collectStart).Milliseconds()))
+	s.log.Debug().Str("duration", time.Since(collectStart).String()).Msg("storage collect end")
+	s.Lock()
+	s.running = false
+	s.Unlock()
+}
+
+// inventory is the aggregate for a storage class/phase (and namespace for claims)
+type inventory struct {
+	tags  []string
+	count uint64
+	bytes int64
+}
+
+// volumeMetrics emits persistent volume counts and capacity totals by storage class and phase
+func (s *Storage) volumeMetrics(ctx context.Context) {
+	pvs, err := s.clientset.CoreV1().PersistentVolumes().List(metav1.ListOptions{})
+	if err != nil {
+		s.apiError("persistentvolume-list")
+		s.log.Error().Err(err).Msg("listing persistent volumes")
+		return
+	}
+
+	totals := make(map[string]*inventory)
+	for i := range pvs.Items {
+		pv := &pvs.Items[i]
+		class := pv.Spec.StorageClassName
+		if class == "" {
+			class = "none"
+		}
+		phase := string(pv.Status.Phase)
+		key := class + "/" + phase
+		inv, ok := totals[key]
+		if !ok {
+			inv = &inventory{tags: []string{"storage_class:" + class, "phase:" + phase}}
+			totals[key] = inv
+		}
+		inv.count++
+		if q, ok := pv.Spec.Capacity[corev1.ResourceStorage]; ok {
+			inv.bytes += q.Value()
+		}
+	}
+
+	s.submit(ctx, totals, "persistentvolume", "capacity_bytes", "persistentvolumes")
+}
+
+// claimMetrics emits persistent volume claim counts and requested capacity totals by
+// namespace, storage class, and phase (Bound/Pending/Lost)
+func (s *Storage) claimMetrics(ctx context.Context) {
+	pvcs, err := s.clientset.CoreV1().PersistentVolumeClaims(metav1.NamespaceAll).List(metav1.ListOptions{})
+	if err != nil {
+		s.apiError("persistentvolumeclaim-list")
+		s.log.Error().Err(err).Msg("listing persistent volume claims")
+		return
+	}
+
+	totals := make(map[string]*inventory)
+	for i := range pvcs.Items {
+		pvc := &pvcs.Items[i]
+		class := claimStorageClass(pvc)
+		phase := string(pvc.Status.Phase)
+		key := pvc.Namespace + "/" + class + "/" + phase
+		inv, ok := totals[key]
+		if !ok {
+			inv = &inventory{tags: []string{"namespace:" + pvc.Namespace, "storage_class:" + class, "phase:" + phase}}
+			totals[key] = inv
+		}
+		inv.count++
+		if q, ok := pvc.Spec.Resources.Requests[corev1.ResourceStorage]; ok {
+			inv.bytes += q.Value()
+		}
+	}
+
+	s.submit(ctx, totals, "persistentvolumeclaim", "requested_bytes", "persistentvolumeclaims")
+}
+
+// submit queues the count and bytes for each inventory aggregate and submits them
+func (s *Storage) submit(ctx context.Context, totals map[string]*inventory, prefix, bytesName, logType string) {
+	if len(totals) == 0 {
+		return
+	}
+
+	metrics := make(map[string]circonus.MetricSample)
+	for _, inv := range totals {
+		streamTags := append([]string{
+			"source:storage",
+			"source_type:inventory",
+		}, inv.tags...)
+		_ = s.check.QueueMetricSample(metrics, prefix+"_count", circonus.MetricTypeUint64, streamTags, []string{}, inv.count, s.ts)
+		_ = s.check.QueueMetricSample(metrics, prefix+"_"+bytesName, circonus.MetricTypeInt64, append(streamTags, "units:bytes"), []string{}, inv.bytes, s.ts)
+	}
+
+	if err := s.check.SubmitQueue(ctx, metrics, s.log.With().Str("type", logType).Logger()); err != nil {
+		s.log.Warn().Err(err).Msg("submitting metrics")
+	}
+}
+
+func (s *Storage) apiError(request string) {
+	s.check.IncrementCounter("collect_api_errors", cgm.Tags{
+		cgm.Tag{Category: "source", Value: release.NAME},
+		cgm.Tag{Category: "request", Value: request},
+		cgm.Tag{Category: "target", Value: "api-server"},
+	})
+}
+
+// claimStorageClass returns the storage class of a claim, the deprecated beta
+// annotation is used if the spec does not have one, "none" if neither is set
+func claimStorageClass(pvc *corev1.PersistentVolumeClaim) string {
+	if pvc.Spec.StorageClassName != nil && *pvc.Spec.StorageClassName != "" {
+		return *pvc.Spec.StorageClassName
+	}
+	if class, ok := pvc.Annotations[corev1.BetaStorageClassAnnotation]; ok && class != "" {
+		return class
+	}
+	return "none"
+}
@@ -0,0 +1,44 @@
+// Copyright © 2019 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+package storage
+
+import (
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestClaimStorageClass(t *testing.T) {
+	fast := "fast"
+	empty := ""
+
+	tests := []struct {
+		name string
+		pvc  *corev1.PersistentVolumeClaim
+		want string
+	}{
+		{"none", &corev1.PersistentVolumeClaim{}, "none"},
+		{"empty spec", &corev1.PersistentVolumeClaim{Spec: corev1.PersistentVolumeClaimSpec{StorageClassName: &empty}}, "none"},
+		{"spec", &corev1.PersistentVolumeClaim{Spec: corev1.PersistentVolumeClaimSpec{StorageClassName: &fast}}, "fast"},
+		{"annotation", &corev1.PersistentVolumeClaim{
+			ObjectMeta: metav1.ObjectMeta{Annotations: map[string]string{corev1.BetaStorageClassAnnotation: "slow"}},
+		}, "slow"},
+		{"spec over annotation", &corev1.PersistentVolumeClaim{
+			ObjectMeta: metav1.ObjectMeta{Annotations: map[string]string{corev1.BetaStorageClassAnnotation: "slow"}},
+			Spec:       corev1.PersistentVolumeClaimSpec{StorageClassName: &fast},
+		}, "fast"},
+	}
+
+	for _, tst := range tests {
+		tst := tst
+		t.Run(tst.name, func(t *testing.T) {
+			if got := claimStorageClass(tst.pvc); got != tst.want {
+				t.Errorf("claimStorageClass() = %s, want %s", got, tst.want)
+			}
+		})
+	}
+}
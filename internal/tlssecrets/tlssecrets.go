@@ -0,0 +1,233 @@
+// Copyright © 2019 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+// Package tlssecrets is the tls secret certificate expiry collector
+package tlssecrets
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"sync"
+	"time"
+
+	cgm "github.com/circonus-labs/circonus-gometrics/v3"
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/circonus"
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/config"
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/config/defaults"
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/k8s"
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/release"
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/scrape"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+type TLSSecrets struct {
+	config       *config.Cluster
+	check        *circonus.Check
+	log          zerolog.Logger
+	clientset    kubernetes.Interface
+	running      bool
+	apiTimelimit time.Duration
+	sync.Mutex
+	ts *time.Time
+}
+
+// NOTES:
+// kubernetes.io/tls secrets are listed (optionally limited to a list of namespaces) and each
+// certificate in the tls.crt chain is parsed to emit the days until it expires, so certificates
+// not managed by cert-manager can be alerted on. Only the public certificate is read, the private
+// key (tls.key) is never inspected.
+
+func New(cfg *config.Cluster, parentLog zerolog.Logger, check *circonus.Check) (*TLSSecrets, error) {
+	if cfg == nil {
+		return nil, errors.New("invalid cluster config (nil)")
+	}
+	if check == nil {
+		return nil, errors.New("invalid check (nil)")
+	}
+
+	tc := &TLSSecrets{
+		config: cfg,
+		check:  check,
+		log:    parentLog.With().Str("collector", "tls-secrets").Logger(),
+	}
+
+	if cfg.APITimelimit != "" {
+		v, err := time.ParseDuration(cfg.APITimelimit)
+		if err != nil {
+			tc.log.Error().Err(err).Msg("parsing api timelimit, using default")
+		} else {
+			tc.apiTimelimit = v
+		}
+	}
+
+	if tc.apiTimelimit == time.Duration(0) {
+		v, err := time.ParseDuration(defaults.K8SAPITimelimit)
+		if err != nil {
+			tc.log.Fatal().Err(err).Msg("parsing DEFAULT api timelimit")
+		}
+		tc.apiTimelimit = v
+	}
+
+	restCfg, err := k8s.RESTConfig(cfg)
+	if err != nil {
+		return nil, errors.Wrap(err, "rest config")
+	}
+	restCfg.Timeout = tc.apiTimelimit
+	clientset, err := kubernetes.NewForConfig(restCfg)
+	if err != nil {
+		return nil, errors.Wrap(err, "clientset")
+	}
+	tc.clientset = clientset
+
+	return tc, nil
+}
+
+func (tc *TLSSecrets) ID() string {
+	return "tls-secrets"
+}
+
+// Collect certificate expiry from tls secrets
+func (tc *TLSSecrets) Collect(ctx context.Context, _ *tls.Config, ts *time.Time) {
+	tc.Lock()
+	if tc.running {
+		tc.log.Warn().Msg("already running")
+		tc.Unlock()
+		return
+	}
+	tc.running = true
+	tc.ts = ts
+	tc.Unlock()
+
+	defer func() {
+		if r := recover(); r != nil {
+			tc.log.Error().Interface("panic", r).Msg("recover")
+			tc.Lock()
+			tc.running = false
+			tc.Unlock()
+		}
+	}()
+
+	collectStart := time.Now()
+
+	for _, ns := range scrape.Namespaces(tc.config.TLSSecretsNamespaces) {
+		tc.secretMetrics(ctx, ns)
+	}
+
+	tc.check.AddHistSample("collect_latency", cgm.Tags{
+		cgm.Tag{Category: "source", Value: release.NAME},
+		cgm.Tag{Category: "op", Value: "collect_tls-secrets"},
+		cgm.Tag{Category: "units", Value: "milliseconds"},
+	}, float64(time.Since(collectStart).Milliseconds()))
+	tc.log.Debug().Str("duration", time.Since(collectStart).String()).Msg("tls-secrets collect end")
+	tc.Lock()
+	tc.running = false
+	tc.Unlock()
+}
+
+// secretMetrics emits the days until expiry of each certificate in the tls secrets of a
+// namespace (blank for all namespaces)
+func (tc *TLSSecrets) secretMetrics(ctx context.Context, namespace string) {
+	secrets, err := tc.clientset.CoreV1().Secrets(namespace).List(metav1.ListOptions{
+		FieldSelector: "type=" + string(corev1.SecretTypeTLS),
+	})
+	if err != nil {
+		tc.check.IncrementCounter("collect_api_errors", cgm.Tags{
+			cgm.Tag{Category: "source", Value: release.NAME},
+			cgm.Tag{Category: "request", Value: "secret-list"},
+			cgm.Tag{Category: "target", Value: "api-server"},
+		})
+		tc.log.Error().Err(err).Str("namespace", namespace).Msg("listing tls secrets")
+		return
+	}
+
+	metrics := make(map[string]circonus.MetricSample)
+	now := time.Now()
+	invalid := make(map[string]uint64)
+	for i := range secrets.Items {
+		secret := &secrets.Items[i]
+		certs, err := parseCertificates(secret.Data[corev1.TLSCertKey])
+		if err != nil {
+			tc.log.Warn().Err(err).Str("namespace", secret.Namespace).Str("secret", secret.Name).Msg("parsing tls.crt")
+			invalid[secret.Namespace]++
+			continue
+		}
+		for idx, cert := range certs {
+			position := "leaf"
+			if idx > 0 {
+				position = "chain"
+			}
+			streamTags := []string{
+				"source:tls-secrets",
+				"source_type:certificates",
+				"namespace:" + secret.Namespace,
+				"secret:" + secret.Name,
+				"common_name:" + cert.Subject.CommonName,
+				"issuer:" + cert.Issuer.CommonName,
+				"position:" + position,
+				"units:days",
+				"__rollup:false", // prevent high cardinality metrics from rolling up
+			}
+			_ = tc.check.QueueMetricSample(
+				metrics,
+				"tls_secret_expiry_days",
+				circonus.MetricTypeFloat64,
+				streamTags, []string{},
+				expiryDays(cert, now),
+				tc.ts)
+		}
+	}
+
+	for ns, count := range invalid {
+		streamTags := []string{
+			"source:tls-secrets",
+			"source_type:certificates",
+			"namespace:" + ns,
+		}
+		_ = tc.check.QueueMetricSample(metrics, "tls_secrets_invalid", circonus.MetricTypeUint64, streamTags, []string{}, count, tc.ts)
+	}
+
+	if len(metrics) == 0 {
+		return
+	}
+	if err := tc.check.SubmitQueue(ctx, metrics, tc.log.With().Str("type", "tls-secrets").Logger()); err != nil {
+		tc.log.Warn().Err(err).Msg("submitting metrics")
+	}
+}
+
+// parseCertificates returns the certificates in PEM encoded data (e.g. a leaf followed by its chain),
+// non-certificate blocks are ignored
+func parseCertificates(data []byte) ([]*x509.Certificate, error) {
+	var certs []*x509.Certificate
+	for {
+		var block *pem.Block
+		block, data = pem.Decode(data)
+		if block == nil {
+			break
+		}
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, errors.Wrap(err, "parsing certificate")
+		}
+		certs = append(certs, cert)
+	}
+	if len(certs) == 0 {
+		return nil, errors.New("no certificates found")
+	}
+	return certs, nil
+}
+
+// expiryDays returns the days until the certificate expires (negative if expired)
+func expiryDays(cert *x509.Certificate, now time.Time) float64 {
+	return cert.NotAfter.Sub(now).Hours() / 24
+}
@@ -0,0 +1,79 @@
+// Copyright © 2019 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+package tlssecrets
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"testing"
+	"time"
+)
+
+func testCert(t *testing.T, cn string, notAfter time.Time) []byte {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("generating key (%s)", err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: cn},
+		NotBefore:    notAfter.Add(-365 * 24 * time.Hour),
+		NotAfter:     notAfter,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("creating certificate (%s)", err)
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+}
+
+func TestParseCertificates(t *testing.T) {
+	now := time.Now().Truncate(time.Second)
+
+	data := append(testCert(t, "leaf.example.com", now.Add(48*time.Hour)), testCert(t, "intermediate", now.Add(-24*time.Hour))...)
+	data = append(data, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: []byte("ignored")})...)
+
+	certs, err := parseCertificates(data)
+	if err != nil {
+		t.Fatalf("unexpected error (%s)", err)
+	}
+	if len(certs) != 2 {
+		t.Fatalf("expected 2 certificates, got %d", len(certs))
+	}
+	if certs[0].Subject.CommonName != "leaf.example.com" {
+		t.Errorf("expected leaf first, got %s", certs[0].Subject.CommonName)
+	}
+	if d := expiryDays(certs[0], now); d != 2 {
+		t.Errorf("leaf expiryDays() = %f, want 2", d)
+	}
+	if d := expiryDays(certs[1], now); d != -1 {
+		t.Errorf("expired expiryDays() = %f, want -1", d)
+	}
+}
+
+func TestParseCertificatesInvalid(t *testing.T) {
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{"empty", nil},
+		{"not pem", []byte("not a certificate")},
+		{"bad der", pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: []byte("bad")})},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := parseCertificates(tt.data); err == nil {
+				t.Error("expected error")
+			}
+		})
+	}
+}
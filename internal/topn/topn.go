@@ -0,0 +1,243 @@
+// Copyright © 2019 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+// Package topn is the top-N (biggest consumers) pod and namespace usage collector
+package topn
+
+import (
+	"context"
+	"crypto/tls"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	cgm "github.com/circonus-labs/circonus-gometrics/v3"
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/circonus"
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/config"
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/config/defaults"
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/k8s"
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/release"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/dynamic"
+)
+
+type TopN struct {
+	config       *config.Cluster
+	check        *circonus.Check
+	log          zerolog.Logger
+	dynamic      dynamic.Interface
+	n            int
+	running      bool
+	apiTimelimit time.Duration
+	sync.Mutex
+	ts *time.Time
+}
+
+// NOTES:
+// Usage is read from the metrics.k8s.io pod metrics api (requires metrics-server). The streams
+// are per rank rather than per pod, so the number of streams is fixed (N per resource) regardless
+// of pod churn: a gauge with the usage and a text metric with the consumer (namespace/pod or namespace).
+
+func New(cfg *config.Cluster, parentLog zerolog.Logger, check *circonus.Check) (*TopN, error) {
+	if cfg == nil {
+		return nil, errors.New("invalid cluster config (nil)")
+	}
+	if check == nil {
+		return nil, errors.New("invalid check (nil)")
+	}
+	if cfg.TopN == 0 {
+		return nil, errors.New("invalid top n (0)")
+	}
+
+	t := &TopN{
+		config: cfg,
+		check:  check,
+		log:    parentLog.With().Str("collector", "top-n").Logger(),
+		n:      int(cfg.TopN),
+	}
+
+	if cfg.APITimelimit != "" {
+		v, err := time.ParseDuration(cfg.APITimelimit)
+		if err != nil {
+			t.log.Error().Err(err).Msg("parsing api timelimit, using default")
+		} else {
+			t.apiTimelimit = v
+		}
+	}
+
+	if t.apiTimelimit == time.Duration(0) {
+		v, err := time.ParseDuration(defaults.K8SAPITimelimit)
+		if err != nil {
+			t.log.Fatal().Err(err).Msg("parsing DEFAULT api timelimit")
+		}
+		t.apiTimelimit = v
+	}
+
+	restCfg, err := k8s.RESTConfig(cfg)
+	if err != nil {
+		return nil, errors.Wrap(err, "rest config")
+	}
+	restCfg.Timeout = t.apiTimelimit
+	dc, err := dynamic.NewForConfig(restCfg)
+	if err != nil {
+		return nil, errors.Wrap(err, "dynamic client")
+	}
+	t.dynamic = dc
+
+	return t, nil
+}
+
+func (t *TopN) ID() string {
+	return "top-n"
+}
+
+// Collect the top N pods and namespaces by cpu and memory usage
+func (t *TopN) Collect(ctx context.Context, _ *tls.Config, ts *time.Time) {
+	t.Lock()
+	if t.running {
+		t.log.Warn().Msg("already running")
+		t.Unlock()
+		return
+	}
+	t.running = true
+	t.ts = ts
+	t.Unlock()
+
+	defer func() {
+		if r := recover(); r != nil {
+			t.log.Error().Interface("panic", r).Msg("recover")
+			t.Lock()
+			t.running = false
+			t.Unlock()
+		}
+	}()
+
+	collectStart := time.Now()
+
+	t.topMetrics(ctx)
+
+	t.check.AddHistSample("collect_latency", cgm.Tags{
+		cgm.Tag{Category: "source", Value: release.NAME},
+		cgm.Tag{Category: "op", Value: "collect_top-n"},
+		cgm.Tag{Category: "units", Value: "milliseconds"},
+	}, float64(time.Since(collectStart).Milliseconds()))
+	t.log.Debug().Str("duration", time.Since(collectStart).String()).Msg("top-n collect end")
+	t.Lock()
+	t.running = false
+	t.Unlock()
+}
+
+// consumer is the cpu (cores) and memory (bytes) usage of a pod or namespace
+type consumer struct {
+	name   string
+	cpu    float64
+	memory float64
+}
+
+// topMetrics emits the top N pods and namespaces for each resource
+func (t *TopN) topMetrics(ctx context.Context) {
+	podMetrics, err := t.dynamic.Resource(k8s.PodMetricsResource).Namespace(metav1.NamespaceAll).List(metav1.ListOptions{LabelSelector: t.config.PodSelector})
+	if err != nil {
+		t.check.IncrementCounter("collect_api_errors", cgm.Tags{
+			cgm.Tag{Category: "source", Value: release.NAME},
+			cgm.Tag{Category: "request", Value: "pod-metrics-list"},
+			cgm.Tag{Category: "target", Value: "api-server"},
+		})
+		t.log.Error().Err(err).Msg("listing pod metrics (is metrics-server installed?)")
+		return
+	}
+
+	pods := make([]consumer, 0, len(podMetrics.Items))
+	namespaces := make(map[string]*consumer)
+	for i := range podMetrics.Items {
+		pm := &podMetrics.Items[i]
+		if !t.check.AllowNamespace(pm.GetNamespace()) {
+			continue
+		}
+		usage, err := k8s.ContainerUsage(pm)
+		if err != nil {
+			t.log.Warn().Err(err).Str("namespace", pm.GetNamespace()).Str("pod", pm.GetName()).Msg("pod usage")
+			continue
+		}
+		pod := consumer{name: pm.GetNamespace() + "/" + pm.GetName()}
+		for _, list := range usage {
+			if q, ok := list[corev1.ResourceCPU]; ok {
+				pod.cpu += float64(q.MilliValue()) / 1000
+			}
+			if q, ok := list[corev1.ResourceMemory]; ok {
+				pod.memory += float64(q.Value())
+			}
+		}
+		pods = append(pods, pod)
+
+		ns, ok := namespaces[pm.GetNamespace()]
+		if !ok {
+			ns = &consumer{name: pm.GetNamespace()}
+			namespaces[pm.GetNamespace()] = ns
+		}
+		ns.cpu += pod.cpu
+		ns.memory += pod.memory
+	}
+
+	nsl := make([]consumer, 0, len(namespaces))
+	for _, ns := range namespaces {
+		nsl = append(nsl, *ns)
+	}
+
+	metrics := make(map[string]circonus.MetricSample)
+	for _, res := range []struct {
+		name  string
+		units string
+		value func(consumer) float64
+	}{
+		{"cpu", "cores", func(c consumer) float64 { return c.cpu }},
+		{"memory", "bytes", func(c consumer) float64 { return c.memory }},
+	} {
+		for _, scope := range []struct {
+			name      string
+			consumers []consumer
+		}{
+			{"pod", pods},
+			{"namespace", nsl},
+		} {
+			for i, c := range top(scope.consumers, t.n, res.value) {
+				streamTags := []string{
+					"source:top-n",
+					"resource:" + res.name,
+					"rank:" + strconv.Itoa(i+1),
+				}
+				_ = t.check.QueueMetricSample(metrics, "top_"+scope.name, circonus.MetricTypeString, streamTags, []string{}, c.name, t.ts)
+				_ = t.check.QueueMetricSample(metrics, "top_"+scope.name+"_usage", circonus.MetricTypeFloat64, append(streamTags, "units:"+res.units), []string{}, res.value(c), t.ts)
+			}
+		}
+	}
+
+	if len(metrics) == 0 {
+		return
+	}
+	if err := t.check.SubmitQueue(ctx, metrics, t.log.With().Str("type", "top-n").Logger()); err != nil {
+		t.log.Warn().Err(err).Msg("submitting metrics")
+	}
+}
+
+// top returns the n consumers with the largest values, ties are ordered by name
+func top(consumers []consumer, n int, value func(consumer) float64) []consumer {
+	sorted := append([]consumer{}, consumers...)
+	sort.Slice(sorted, func(i, j int) bool {
+		vi, vj := value(sorted[i]), value(sorted[j])
+		if vi != vj {
+			return vi > vj
+		}
+		return sorted[i].name < sorted[j].name
+	})
+	if len(sorted) > n {
+		sorted = sorted[:n]
+	}
+	return sorted
+}
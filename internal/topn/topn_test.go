@@ -0,0 +1,44 @@
+// Copyright © 2019 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+package topn
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestTop(t *testing.T) {
+	consumers := []consumer{
+		{name: "a/web", cpu: 0.5, memory: 100},
+		{name: "b/db", cpu: 2, memory: 50},
+		{name: "c/cache", cpu: 0.5, memory: 300},
+		{name: "d/batch", cpu: 1, memory: 10},
+	}
+	cpu := func(c consumer) float64 { return c.cpu }
+	memory := func(c consumer) float64 { return c.memory }
+
+	tests := []struct {
+		name  string
+		n     int
+		value func(consumer) float64
+		want  []string
+	}{
+		{"cpu top 3", 3, cpu, []string{"b/db", "d/batch", "a/web"}},
+		{"memory top 2", 2, memory, []string{"c/cache", "a/web"}},
+		{"n larger than consumers", 10, cpu, []string{"b/db", "d/batch", "a/web", "c/cache"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			for _, c := range top(consumers, tt.n, tt.value) {
+				got = append(got, c.name)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("top() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
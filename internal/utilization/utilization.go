@@ -0,0 +1,232 @@
+// Copyright © 2019 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+// Package utilization is the container usage/request and usage/limit ratio collector
+package utilization
+
+import (
+	"context"
+	"crypto/tls"
+	"sync"
+	"time"
+
+	cgm "github.com/circonus-labs/circonus-gometrics/v3"
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/circonus"
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/config"
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/config/defaults"
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/k8s"
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/release"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/kubernetes"
+)
+
+// ratioResources are the resources ratios are computed for
+var ratioResources = []corev1.ResourceName{corev1.ResourceCPU, corev1.ResourceMemory}
+
+type Utilization struct {
+	config       *config.Cluster
+	check        *circonus.Check
+	log          zerolog.Logger
+	clientset    kubernetes.Interface
+	dynamic      dynamic.Interface
+	running      bool
+	apiTimelimit time.Duration
+	sync.Mutex
+	ts *time.Time
+}
+
+// NOTES:
+// Container usage from the metrics.k8s.io pod metrics api (requires metrics-server) is joined
+// with the container requests and limits from the pod spec. A ratio is only emitted when the
+// container has a request (or limit) for the resource. Memory usage is the working set, which
+// is what the kubelet evicts and the kernel oom kills on.
+
+func New(cfg *config.Cluster, parentLog zerolog.Logger, check *circonus.Check) (*Utilization, error) {
+	if cfg == nil {
+		return nil, errors.New("invalid cluster config (nil)")
+	}
+	if check == nil {
+		return nil, errors.New("invalid check (nil)")
+	}
+
+	u := &Utilization{
+		config: cfg,
+		check:  check,
+		log:    parentLog.With().Str("collector", "utilization").Logger(),
+	}
+
+	if cfg.APITimelimit != "" {
+		v, err := time.ParseDuration(cfg.APITimelimit)
+		if err != nil {
+			u.log.Error().Err(err).Msg("parsing api timelimit, using default")
+		} else {
+			u.apiTimelimit = v
+		}
+	}
+
+	if u.apiTimelimit == time.Duration(0) {
+		v, err := time.ParseDuration(defaults.K8SAPITimelimit)
+		if err != nil {
+			u.log.Fatal().Err(err).Msg("parsing DEFAULT api timelimit")
+		}
+		u.apiTimelimit = v
+	}
+
+	restCfg, err := k8s.RESTConfig(cfg)
+	if err != nil {
+		return nil, errors.Wrap(err, "rest config")
+	}
+	restCfg.Timeout = u.apiTimelimit
+	clientset, err := kubernetes.NewForConfig(restCfg)
+	if err != nil {
+		return nil, errors.Wrap(err, "clientset")
+	}
+	u.clientset = clientset
+	dc, err := dynamic.NewForConfig(restCfg)
+	if err != nil {
+		return nil, errors.Wrap(err, "dynamic client")
+	}
+	u.dynamic = dc
+
+	return u, nil
+}
+
+func (u *Utilization) ID() string {
+	return "utilization"
+}
+
+// Collect container utilization ratios
+func (u *Utilization) Collect(ctx context.Context, _ *tls.Config, ts *time.Time) {
+	u.Lock()
+	if u.running {
+		u.log.Warn().Msg("already running")
+		u.Unlock()
+		return
+	}
+	u.running = true
+	u.ts = ts
+	u.Unlock()
+
+	defer func() {
+		if r := recover(); r != nil {
+			u.log.Error().Interface("panic", r).Msg("recover")
+			u.Lock()
+			u.running = false
+			u.Unlock()
+		}
+	}()
+
+	collectStart := time.Now()
+
+	u.ratioMetrics(ctx)
+
+	u.check.AddHistSample("collect_latency", cgm.Tags{
+		cgm.Tag{Category: "source", Value: release.NAME},
+		cgm.Tag{Category: "op", Value: "collect_utilization"},
+		cgm.Tag{Category: "units", Value: "milliseconds"},
+	}, float64(time.Since(collectStart).Milliseconds()))
+	u.log.Debug().Str("duration", time.Since(collectStart).String()).Msg("utilization collect end")
+	u.Lock()
+	u.running = false
+	u.Unlock()
+}
+
+// ratioMetrics emits the usage/request and usage/limit ratios of each running container
+func (u *Utilization) ratioMetrics(ctx context.Context) {
+	pods, err := u.clientset.CoreV1().Pods(metav1.NamespaceAll).List(metav1.ListOptions{FieldSelector: "status.phase=Running", LabelSelector: u.config.PodSelector})
+	if err != nil {
+		u.apiError("pod-list")
+		u.log.Error().Err(err).Msg("listing pods")
+		return
+	}
+
+	podMetrics, err := u.dynamic.Resource(k8s.PodMetricsResource).Namespace(metav1.NamespaceAll).List(metav1.ListOptions{LabelSelector: u.config.PodSelector})
+	if err != nil {
+		u.apiError("pod-metrics-list")
+		u.log.Error().Err(err).Msg("listing pod metrics (is metrics-server installed?)")
+		return
+	}
+
+	usage := make(map[string]map[string]corev1.ResourceList, len(podMetrics.Items))
+	for i := range podMetrics.Items {
+		pm := &podMetrics.Items[i]
+		cu, err := k8s.ContainerUsage(pm)
+		if err != nil {
+			u.log.Warn().Err(err).Str("namespace", pm.GetNamespace()).Str("pod", pm.GetName()).Msg("pod usage")
+			continue
+		}
+		usage[pm.GetNamespace()+"/"+pm.GetName()] = cu
+	}
+
+	metrics := make(map[string]circonus.MetricSample)
+	for i := range pods.Items {
+		pod := &pods.Items[i]
+		containerUsage, ok := usage[pod.Namespace+"/"+pod.Name]
+		if !ok {
+			continue // not yet scraped by metrics-server
+		}
+		for _, c := range pod.Spec.Containers {
+			cu, ok := containerUsage[c.Name]
+			if !ok {
+				continue
+			}
+			baseStreamTags := []string{
+				"source:utilization",
+				"namespace:" + pod.Namespace,
+				"pod:" + pod.Name,
+				"container_name:" + c.Name,
+				"__rollup:false", // prevent high cardinality metrics from rolling up
+			}
+			for _, rr := range []struct {
+				name string
+				spec corev1.ResourceList
+			}{
+				{"container_request_utilization", c.Resources.Requests},
+				{"container_limit_utilization", c.Resources.Limits},
+			} {
+				for res, v := range ratios(cu, rr.spec) {
+					streamTags := append(append([]string{}, baseStreamTags...), "resource:"+string(res), "units:ratio")
+					_ = u.check.QueueMetricSample(metrics, rr.name, circonus.MetricTypeFloat64, streamTags, []string{}, v, u.ts)
+				}
+			}
+		}
+	}
+
+	if len(metrics) == 0 {
+		return
+	}
+	if err := u.check.SubmitQueue(ctx, metrics, u.log.With().Str("type", "utilization").Logger()); err != nil {
+		u.log.Warn().Err(err).Msg("submitting metrics")
+	}
+}
+
+func (u *Utilization) apiError(request string) {
+	u.check.IncrementCounter("collect_api_errors", cgm.Tags{
+		cgm.Tag{Category: "source", Value: release.NAME},
+		cgm.Tag{Category: "request", Value: request},
+		cgm.Tag{Category: "target", Value: "api-server"},
+	})
+}
+
+// ratios returns usage/spec for each resource with a non-zero spec (request or limit)
+func ratios(usage, spec corev1.ResourceList) map[corev1.ResourceName]float64 {
+	r := make(map[corev1.ResourceName]float64)
+	for _, res := range ratioResources {
+		sq, ok := spec[res]
+		if !ok || sq.IsZero() {
+			continue
+		}
+		uq, ok := usage[res]
+		if !ok {
+			continue
+		}
+		r[res] = float64(uq.MilliValue()) / float64(sq.MilliValue())
+	}
+	return r
+}
@@ -0,0 +1,45 @@
+// Copyright © 2019 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+package utilization
+
+import (
+	"reflect"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+func TestRatios(t *testing.T) {
+	usage := corev1.ResourceList{
+		corev1.ResourceCPU:    resource.MustParse("250m"),
+		corev1.ResourceMemory: resource.MustParse("768Mi"),
+	}
+	tests := []struct {
+		name string
+		spec corev1.ResourceList
+		want map[corev1.ResourceName]float64
+	}{
+		{"both", corev1.ResourceList{
+			corev1.ResourceCPU:    resource.MustParse("500m"),
+			corev1.ResourceMemory: resource.MustParse("512Mi"),
+		}, map[corev1.ResourceName]float64{corev1.ResourceCPU: 0.5, corev1.ResourceMemory: 1.5}},
+		{"cpu only", corev1.ResourceList{
+			corev1.ResourceCPU: resource.MustParse("1"),
+		}, map[corev1.ResourceName]float64{corev1.ResourceCPU: 0.25}},
+		{"zero request", corev1.ResourceList{
+			corev1.ResourceCPU: resource.MustParse("0"),
+		}, map[corev1.ResourceName]float64{}},
+		{"none", nil, map[corev1.ResourceName]float64{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ratios(usage, tt.spec); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ratios() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
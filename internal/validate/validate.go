@@ -0,0 +1,340 @@
+// Copyright © 2019 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+// Package validate verifies the configuration and the connectivity and permissions
+// it depends on, for use before starting the agent (e.g. init-container, CI)
+package validate
+
+import (
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/circonus"
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/config"
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/config/keys"
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/crstate"
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/k8s"
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/release"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog"
+	"github.com/spf13/viper"
+)
+
+// NOTES:
+// The validate command runs these steps, for each cluster:
+//
+//   config       the configuration parses and is valid
+//   credentials  the cluster's api credentials (kubeconfig, bearer token) can be read
+//   k8s api      the api server is reachable with the credentials (/version)
+//   permissions  the credentials are allowed the accesses the agent's core and enabled
+//                collectors need
+//   api token    the circonus api token is valid (skipped when forwarding metrics)
+//   broker       the broker the check submits to is reachable (skipped when the
+//                check does not exist yet and will be created)
+//
+// Steps depending on a failed step are skipped. Nothing is created or changed (e.g.
+// the check is not created when it does not exist).
+
+// step statuses
+const (
+	StatusOK   = "ok"
+	StatusFail = "fail"
+	StatusSkip = "skip"
+)
+
+// Step is the result of a validation step
+type Step struct {
+	Name    string `json:"name"`
+	Cluster string `json:"cluster,omitempty"`
+	Status  string `json:"status"`
+	Message string `json:"message,omitempty"`
+}
+
+// Report is the result of all validation steps
+type Report struct {
+	OK    bool   `json:"ok"`
+	Steps []Step `json:"steps"`
+}
+
+// coreAccess are the core accesses of the agent's cluster role
+var coreAccess = []k8s.Access{
+	{Verb: "list", Resource: "nodes"},
+	{Verb: "list", Resource: "pods"},
+	{Verb: "list", Resource: "services"},
+	{Verb: "list", Resource: "endpoints"},
+	{Verb: "list", Resource: "namespaces"},
+	{Verb: "get", Resource: "nodes", Subresource: "proxy"},
+	{Verb: "get", Resource: "services", Subresource: "proxy"},
+	{Verb: "get", Path: "/metrics"},
+}
+
+var (
+	eventsAccess     = []k8s.Access{{Verb: "list", Resource: "events"}, {Verb: "watch", Resource: "events"}}
+	podProxyAccess   = []k8s.Access{{Verb: "get", Resource: "pods", Subresource: "proxy"}}
+	podMetricsAccess = []k8s.Access{{Verb: "list", Group: "metrics.k8s.io", Resource: "pods"}}
+	secretsAccess    = []k8s.Access{{Verb: "list", Resource: "secrets"}}
+)
+
+// requiredAccess returns the core accesses and the accesses of the enabled collectors
+func requiredAccess(cfg *config.Cluster) ([]k8s.Access, error) {
+	var required []k8s.Access
+	seen := make(map[k8s.Access]bool)
+	add := func(enabled bool, access ...k8s.Access) {
+		if !enabled {
+			return
+		}
+		for _, a := range access {
+			if !seen[a] {
+				seen[a] = true
+				required = append(required, a)
+			}
+		}
+	}
+
+	add(true, coreAccess...)
+	add(cfg.EnableEvents || cfg.EnableImagePulls || cfg.EnableSchedulingFailures, eventsAccess...)
+
+	// collectors scraping pods through the api-server pod proxy
+	add(cfg.EnableKubeDNSMetrics || cfg.EnableKubeScheduler || cfg.EnableKubeControllerManager ||
+		cfg.EnableKubeProxy || cfg.EnableNodeLocalDNS || cfg.EnableIngressNginx || cfg.EnableDCGM ||
+		cfg.EnableClusterAutoscaler || cfg.EnableKarpenter, podProxyAccess...)
+
+	add(cfg.EnablePromMonitors,
+		k8s.Access{Verb: "list", Group: "monitoring.coreos.com", Resource: "servicemonitors"},
+		k8s.Access{Verb: "list", Group: "monitoring.coreos.com", Resource: "podmonitors"})
+	add(cfg.EnableCertManager, k8s.Access{Verb: "list", Group: "cert-manager.io", Resource: "certificates"})
+	add(cfg.EnableStorageInventory,
+		k8s.Access{Verb: "list", Resource: "persistentvolumes"},
+		k8s.Access{Verb: "list", Resource: "persistentvolumeclaims"})
+	add(cfg.EnableHPA, k8s.Access{Verb: "list", Group: "autoscaling", Resource: "horizontalpodautoscalers"})
+	add(cfg.EnableJobs,
+		k8s.Access{Verb: "list", Group: "batch", Resource: "jobs"},
+		k8s.Access{Verb: "list", Group: "batch", Resource: "cronjobs"})
+	add(cfg.EnableDeployments, k8s.Access{Verb: "list", Group: "apps", Resource: "deployments"})
+	add(cfg.EnableStatefulSets, k8s.Access{Verb: "list", Group: "apps", Resource: "statefulsets"})
+	add(cfg.EnableDaemonSets, k8s.Access{Verb: "list", Group: "apps", Resource: "daemonsets"})
+	add(cfg.EnableOrphans, k8s.Access{Verb: "list", Group: "apps", Resource: "replicasets"})
+	add(cfg.EnableEndpoints, k8s.Access{Verb: "list", Group: "discovery.k8s.io", Resource: "endpointslices"})
+	add(cfg.EnablePDB, k8s.Access{Verb: "list", Group: "policy", Resource: "poddisruptionbudgets"})
+	add(cfg.EnableVelero,
+		k8s.Access{Verb: "list", Group: "velero.io", Resource: "backups"},
+		k8s.Access{Verb: "list", Group: "velero.io", Resource: "restores"})
+	add(cfg.EnableArgoCD, k8s.Access{Verb: "list", Group: "argoproj.io", Resource: "applications"})
+	add(cfg.EnableFlux,
+		k8s.Access{Verb: "list", Group: "kustomize.toolkit.fluxcd.io", Resource: "kustomizations"},
+		k8s.Access{Verb: "list", Group: "helm.toolkit.fluxcd.io", Resource: "helmreleases"})
+	add(cfg.EnableTLSSecrets || cfg.EnableConfigInventory, secretsAccess...)
+	add(cfg.EnableConfigInventory, k8s.Access{Verb: "list", Resource: "configmaps"})
+	add(cfg.EnableNamespaceResources || cfg.EnableTopN || cfg.EnableUtilization, podMetricsAccess...)
+
+	// watchers
+	add(cfg.EnableEvictions, append([]k8s.Access{{Verb: "watch", Resource: "pods"}}, eventsAccess...)...)
+	add(cfg.EnableSpotInterruptions, append([]k8s.Access{{Verb: "watch", Resource: "nodes"}}, eventsAccess...)...)
+
+	if cfg.EnableCustomResources {
+		access, err := crstate.Access(cfg.CustomResourcesFile)
+		if err != nil {
+			return nil, errors.Wrap(err, "custom resources")
+		}
+		add(true, access...)
+	}
+
+	return required, nil
+}
+
+// Run validates the configuration, each cluster, and its circonus settings
+func Run(timeout time.Duration) *Report {
+	r := &Report{OK: true}
+
+	var cfg *config.Config
+	err := config.Validate()
+	if err == nil {
+		err = errors.Wrap(viper.Unmarshal(&cfg), "parsing config")
+	}
+	if err == nil && len(cfg.Clusters) > 0 {
+		err = errors.Wrap(config.ValidateClusters(cfg.Clusters), "clusters config")
+	}
+	r.add("config", "", err, viper.ConfigFileUsed())
+	if err != nil {
+		return r
+	}
+
+	clusters := cfg.Clusters
+	multiple := len(clusters) > 0
+	if !multiple {
+		clusters = []config.Cluster{cfg.Kubernetes}
+	}
+
+	for _, cluster := range clusters {
+		cluster := cluster
+		circCfg := cfg.Circonus
+		if multiple {
+			circCfg = config.ClusterCirconusConfig(cfg.Circonus, cluster)
+		}
+		r.cluster(&cluster, circCfg, timeout)
+	}
+
+	return r
+}
+
+// cluster validates a cluster and its circonus settings
+func (r *Report) cluster(cfg *config.Cluster, circCfg config.Circonus, timeout time.Duration) {
+	name := cfg.Name
+
+	if _, err := time.ParseDuration(cfg.Interval); err != nil {
+		r.add("interval", name, errors.Wrap(err, "parsing interval"), "")
+	}
+	if cfg.ConfigResource != "" {
+		_, _, err := config.ParseObjectRef(cfg.ConfigResource)
+		r.add("config resource", name, err, cfg.ConfigResource)
+	}
+	if cfg.NodeSelector != "" || cfg.PodSelector != "" {
+		r.add("label selectors", name, k8s.ValidateSelectors(cfg), "")
+	}
+
+	tlsConfig, err := k8s.ConfigureCredentials(cfg, zerolog.Nop())
+	r.add("credentials", name, err, "")
+	if err != nil {
+		r.skip(name, "k8s api", "permissions")
+	} else {
+		version, err := apiVersion(cfg, tlsConfig, timeout)
+		r.add("k8s api", name, err, strings.TrimSpace(cfg.URL+" "+version))
+		if err != nil {
+			r.skip(name, "permissions")
+		} else {
+			var denied []k8s.Access
+			required, err := requiredAccess(cfg)
+			if err == nil {
+				denied, err = k8s.DeniedAccess(cfg, required)
+			}
+			if err == nil && len(denied) > 0 {
+				err = errors.Errorf("denied: %s", k8s.AccessList(denied))
+			}
+			r.add("permissions", name, err, "")
+		}
+	}
+
+	if viper.GetString(keys.ForwardURL) != "" || viper.GetString(keys.ForwardStatsd) != "" {
+		r.Steps = append(r.Steps, Step{Name: "api token", Cluster: name, Status: StatusSkip, Message: "metrics are forwarded"})
+		r.Steps = append(r.Steps, Step{Name: "broker", Cluster: name, Status: StatusSkip, Message: "metrics are forwarded"})
+		return
+	}
+
+	if circCfg.Check.Target == "" {
+		circCfg.Check.Target = strings.Replace(name, " ", "_", -1)
+	}
+	if circCfg.API.KeySecret != "" {
+		key, err := k8s.SecretRefValue(cfg, circCfg.API.KeySecret)
+		if err != nil {
+			r.add("api token", name, errors.Wrap(err, "reading api key secret"), "")
+			r.skip(name, "broker")
+			return
+		}
+		circCfg.API.Key = key
+	}
+
+	email, err := circonus.ValidateAPIToken(&circCfg)
+	r.add("api token", name, err, email)
+	if err != nil {
+		r.skip(name, "broker")
+		return
+	}
+
+	addr, err := circonus.ValidateBroker(&circCfg, timeout)
+	if errors.Cause(err) == circonus.ErrNoCheck && circCfg.Check.Create {
+		r.Steps = append(r.Steps, Step{Name: "broker", Cluster: name, Status: StatusSkip, Message: "check " + circCfg.Check.Target + " will be created"})
+		return
+	}
+	r.add("broker", name, err, addr)
+}
+
+// apiVersion returns the api server's version
+func apiVersion(cfg *config.Cluster, tlsConfig *tls.Config, timeout time.Duration) (string, error) {
+	client, err := k8s.NewAPIClient(tlsConfig, timeout)
+	if err != nil {
+		return "", errors.Wrap(err, "api client")
+	}
+	defer client.CloseIdleConnections()
+
+	req, err := k8s.NewAPIRequest(cfg.Token(), cfg.URL+"/version")
+	if err != nil {
+		return "", err
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", errors.Wrap(err, "requesting api version")
+	}
+	defer resp.Body.Close()
+
+	data, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return "", errors.Wrap(err, "reading api version")
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", errors.Errorf("api version, %s (%s)", resp.Status, strings.TrimSpace(string(data)))
+	}
+
+	var v struct {
+		GitVersion string `json:"gitVersion"`
+	}
+	if err := json.Unmarshal(data, &v); err != nil {
+		return "", errors.Wrap(err, "parsing api version")
+	}
+	return v.GitVersion, nil
+}
+
+// add records the result of a step
+func (r *Report) add(name, cluster string, err error, msg string) {
+	step := Step{Name: name, Cluster: cluster, Status: StatusOK, Message: msg}
+	if err != nil {
+		step.Status = StatusFail
+		step.Message = err.Error()
+		r.OK = false
+	}
+	r.Steps = append(r.Steps, step)
+}
+
+// skip records steps skipped because a step they depend on failed
+func (r *Report) skip(cluster string, names ...string) {
+	for _, name := range names {
+		r.Steps = append(r.Steps, Step{Name: name, Cluster: cluster, Status: StatusSkip, Message: "skipped"})
+	}
+}
+
+// WriteJSON writes the report as json
+func (r *Report) WriteJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return errors.Wrap(enc.Encode(r), "encoding report")
+}
+
+// WriteText writes the report as text, one line per step
+func (r *Report) WriteText(w io.Writer) error {
+	for _, step := range r.Steps {
+		name := step.Name
+		if step.Cluster != "" {
+			name = step.Cluster + ": " + name
+		}
+		line := fmt.Sprintf("%-4s  %s", strings.ToUpper(step.Status), name)
+		if step.Message != "" {
+			line += " - " + step.Message
+		}
+		if _, err := fmt.Fprintln(w, line); err != nil {
+			return errors.Wrap(err, "writing report")
+		}
+	}
+	result := "valid"
+	if !r.OK {
+		result = "invalid"
+	}
+	_, err := fmt.Fprintf(w, "%s v%s configuration %s\n", release.NAME, release.VERSION, result)
+	return errors.Wrap(err, "writing report")
+}
@@ -0,0 +1,90 @@
+// Copyright © 2019 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+package validate
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/config"
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/k8s"
+	"github.com/pkg/errors"
+)
+
+func TestReport(t *testing.T) {
+	r := &Report{OK: true}
+	r.add("config", "", nil, "/etc/circonus-kubernetes-agent.yaml")
+	r.add("credentials", "prod", errors.New("invalid bearer credentials (empty)"), "")
+	r.skip("prod", "k8s api", "permissions")
+
+	if r.OK {
+		t.Fatal("expected report to fail")
+	}
+	if len(r.Steps) != 4 || r.Steps[1].Status != StatusFail || r.Steps[3].Status != StatusSkip {
+		t.Fatalf("unexpected steps %+v", r.Steps)
+	}
+
+	var text bytes.Buffer
+	if err := r.WriteText(&text); err != nil {
+		t.Fatalf("unexpected error (%s)", err)
+	}
+	lines := strings.Split(strings.TrimSpace(text.String()), "\n")
+	if len(lines) != 5 {
+		t.Fatalf("expected 5 lines, got %d (%s)", len(lines), text.String())
+	}
+	if lines[1] != "FAIL  prod: credentials - invalid bearer credentials (empty)" {
+		t.Fatalf("unexpected line %q", lines[1])
+	}
+	if !strings.HasSuffix(lines[4], "configuration invalid") {
+		t.Fatalf("unexpected result line %q", lines[4])
+	}
+
+	var data bytes.Buffer
+	if err := r.WriteJSON(&data); err != nil {
+		t.Fatalf("unexpected error (%s)", err)
+	}
+	var decoded Report
+	if err := json.Unmarshal(data.Bytes(), &decoded); err != nil {
+		t.Fatalf("unexpected error (%s)", err)
+	}
+	if decoded.OK || len(decoded.Steps) != 4 || decoded.Steps[0].Cluster != "" {
+		t.Fatalf("unexpected decoded report %+v", decoded)
+	}
+}
+
+func TestRequiredAccess(t *testing.T) {
+	required, err := requiredAccess(&config.Cluster{})
+	if err != nil {
+		t.Fatalf("unexpected error (%s)", err)
+	}
+	if len(required) != len(coreAccess) {
+		t.Fatalf("expected core access only, got %s", k8s.AccessList(required))
+	}
+
+	cfg := &config.Cluster{
+		EnableJobs:          true,
+		EnableKubeScheduler: true,
+		EnableKarpenter:     true,
+		EnableTLSSecrets:    true,
+		EnableUtilization:   true,
+	}
+	required, err = requiredAccess(cfg)
+	if err != nil {
+		t.Fatalf("unexpected error (%s)", err)
+	}
+	got := k8s.AccessList(required[len(coreAccess):])
+	want := "get pods/proxy, list jobs.batch, list cronjobs.batch, list secrets, list pods.metrics.k8s.io"
+	if got != want {
+		t.Fatalf("expected %q, got %q", want, got)
+	}
+
+	cfg = &config.Cluster{EnableCustomResources: true, CustomResourcesFile: "missing.json"}
+	if _, err := requiredAccess(cfg); err == nil {
+		t.Fatal("expected error")
+	}
+}
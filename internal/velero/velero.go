@@ -0,0 +1,287 @@
+// Copyright © 2019 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+// Package velero is the velero backup and restore status collector
+package velero
+
+import (
+	"context"
+	"crypto/tls"
+	"sync"
+	"time"
+
+	cgm "github.com/circonus-labs/circonus-gometrics/v3"
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/circonus"
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/config"
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/config/defaults"
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/k8s"
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/release"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+)
+
+const scheduleLabel = "velero.io/schedule-name"
+
+var (
+	backupsResource  = schema.GroupVersionResource{Group: "velero.io", Version: "v1", Resource: "backups"}
+	restoresResource = schema.GroupVersionResource{Group: "velero.io", Version: "v1", Resource: "restores"}
+)
+
+type Velero struct {
+	config       *config.Cluster
+	check        *circonus.Check
+	log          zerolog.Logger
+	dynamic      dynamic.Interface
+	running      bool
+	apiTimelimit time.Duration
+	sync.Mutex
+	ts *time.Time
+}
+
+// NOTES:
+// Backup and Restore resources (velero.io/v1) are listed in the velero namespace. Backups created
+// by a schedule are labeled with the schedule name, the age of the last successful backup and the
+// number of failed backups since then are emitted per schedule (manual backups as schedule:none).
+
+func New(cfg *config.Cluster, parentLog zerolog.Logger, check *circonus.Check) (*Velero, error) {
+	if cfg == nil {
+		return nil, errors.New("invalid cluster config (nil)")
+	}
+	if check == nil {
+		return nil, errors.New("invalid check (nil)")
+	}
+
+	vc := &Velero{
+		config: cfg,
+		check:  check,
+		log:    parentLog.With().Str("collector", "velero").Logger(),
+	}
+
+	if cfg.APITimelimit != "" {
+		v, err := time.ParseDuration(cfg.APITimelimit)
+		if err != nil {
+			vc.log.Error().Err(err).Msg("parsing api timelimit, using default")
+		} else {
+			vc.apiTimelimit = v
+		}
+	}
+
+	if vc.apiTimelimit == time.Duration(0) {
+		v, err := time.ParseDuration(defaults.K8SAPITimelimit)
+		if err != nil {
+			vc.log.Fatal().Err(err).Msg("parsing DEFAULT api timelimit")
+		}
+		vc.apiTimelimit = v
+	}
+
+	restCfg, err := k8s.RESTConfig(cfg)
+	if err != nil {
+		return nil, errors.Wrap(err, "rest config")
+	}
+	restCfg.Timeout = vc.apiTimelimit
+	dyn, err := dynamic.NewForConfig(restCfg)
+	if err != nil {
+		return nil, errors.Wrap(err, "dynamic client")
+	}
+	vc.dynamic = dyn
+
+	return vc, nil
+}
+
+func (vc *Velero) ID() string {
+	return "velero"
+}
+
+// Collect velero backup and restore status
+func (vc *Velero) Collect(ctx context.Context, _ *tls.Config, ts *time.Time) {
+	vc.Lock()
+	if vc.running {
+		vc.log.Warn().Msg("already running")
+		vc.Unlock()
+		return
+	}
+	vc.running = true
+	vc.ts = ts
+	vc.Unlock()
+
+	defer func() {
+		if r := recover(); r != nil {
+			vc.log.Error().Interface("panic", r).Msg("recover")
+			vc.Lock()
+			vc.running = false
+			vc.Unlock()
+		}
+	}()
+
+	collectStart := time.Now()
+
+	vc.backupMetrics(ctx)
+	vc.restoreMetrics(ctx)
+
+	vc.check.AddHistSample("collect_latency", cgm.Tags{
+		cgm.Tag{Category: "source", Value: release.NAME},
+		cgm.Tag{Category: "op", Value: "collect_velero"},
+		cgm.Tag{Category: "units", Value: "milliseconds"},
+	}, float64(time.Since(collectStart).Milliseconds()))
+	vc.log.Debug().Str("duration", time.Since(collectStart).String()).Msg("velero collect end")
+	vc.Lock()
+	vc.running = false
+	vc.Unlock()
+}
+
+// backupMetrics emits backup counts by phase, and per schedule the age of the last
+// successful backup and the number of failed backups since
+func (vc *Velero) backupMetrics(ctx context.Context) {
+	backups, err := vc.dynamic.Resource(backupsResource).Namespace(vc.config.VeleroNamespace).List(metav1.ListOptions{})
+	if err != nil {
+		vc.apiError("backup-list")
+		vc.log.Error().Err(err).Msg("listing backups")
+		return
+	}
+
+	now := time.Now()
+	metrics := make(map[string]circonus.MetricSample)
+
+	phases, schedules := backupSummary(backups.Items)
+	for phase, count := range phases {
+		streamTags := []string{
+			"source:velero",
+			"source_type:backups",
+			"phase:" + phase,
+		}
+		_ = vc.check.QueueMetricSample(metrics, "velero_backups", circonus.MetricTypeUint64, streamTags, []string{}, count, vc.ts)
+	}
+	for schedule, status := range schedules {
+		streamTags := []string{
+			"source:velero",
+			"source_type:backups",
+			"schedule:" + schedule,
+		}
+		_ = vc.check.QueueMetricSample(metrics, "velero_backup_failures_since_success", circonus.MetricTypeUint64, streamTags, []string{}, status.failures, vc.ts)
+		if !status.lastSuccess.IsZero() {
+			_ = vc.check.QueueMetricSample(metrics, "velero_backup_last_success_age", circonus.MetricTypeFloat64, append(streamTags, "units:seconds"), []string{}, now.Sub(status.lastSuccess).Seconds(), vc.ts)
+		}
+	}
+
+	vc.submit(ctx, metrics, "backups")
+}
+
+// restoreMetrics emits restore counts by phase
+func (vc *Velero) restoreMetrics(ctx context.Context) {
+	restores, err := vc.dynamic.Resource(restoresResource).Namespace(vc.config.VeleroNamespace).List(metav1.ListOptions{})
+	if err != nil {
+		vc.apiError("restore-list")
+		vc.log.Error().Err(err).Msg("listing restores")
+		return
+	}
+
+	metrics := make(map[string]circonus.MetricSample)
+	for phase, count := range phaseCounts(restores.Items) {
+		streamTags := []string{
+			"source:velero",
+			"source_type:restores",
+			"phase:" + phase,
+		}
+		_ = vc.check.QueueMetricSample(metrics, "velero_restores", circonus.MetricTypeUint64, streamTags, []string{}, count, vc.ts)
+	}
+
+	vc.submit(ctx, metrics, "restores")
+}
+
+func (vc *Velero) submit(ctx context.Context, metrics map[string]circonus.MetricSample, kind string) {
+	if len(metrics) == 0 {
+		return
+	}
+	if err := vc.check.SubmitQueue(ctx, metrics, vc.log.With().Str("type", kind).Logger()); err != nil {
+		vc.log.Warn().Err(err).Msg("submitting metrics")
+	}
+}
+
+func (vc *Velero) apiError(request string) {
+	vc.check.IncrementCounter("collect_api_errors", cgm.Tags{
+		cgm.Tag{Category: "source", Value: release.NAME},
+		cgm.Tag{Category: "request", Value: request},
+		cgm.Tag{Category: "target", Value: "api-server"},
+	})
+}
+
+type scheduleStatus struct {
+	lastSuccess time.Time
+	failures    uint64 // failed backups created after the last successful backup completed
+}
+
+// backupSummary returns the backup counts by phase and the backup status of each schedule
+func backupSummary(backups []unstructured.Unstructured) (map[string]uint64, map[string]*scheduleStatus) {
+	phases := phaseCounts(backups)
+	schedules := make(map[string]*scheduleStatus)
+
+	for i := range backups {
+		b := &backups[i]
+		status := scheduleFor(schedules, b)
+		if phase(b) != "Completed" {
+			continue
+		}
+		completed, found, err := unstructured.NestedString(b.Object, "status", "completionTimestamp")
+		if err != nil || !found {
+			continue
+		}
+		t, err := time.Parse(time.RFC3339, completed)
+		if err != nil {
+			continue
+		}
+		if t.After(status.lastSuccess) {
+			status.lastSuccess = t
+		}
+	}
+
+	for i := range backups {
+		b := &backups[i]
+		switch phase(b) {
+		case "Failed", "PartiallyFailed", "FailedValidation":
+			status := scheduleFor(schedules, b)
+			if b.GetCreationTimestamp().Time.After(status.lastSuccess) {
+				status.failures++
+			}
+		}
+	}
+
+	return phases, schedules
+}
+
+// scheduleFor returns the status of the schedule which created the backup, none for manual backups
+func scheduleFor(schedules map[string]*scheduleStatus, backup *unstructured.Unstructured) *scheduleStatus {
+	name := backup.GetLabels()[scheduleLabel]
+	if name == "" {
+		name = "none"
+	}
+	status, ok := schedules[name]
+	if !ok {
+		status = &scheduleStatus{}
+		schedules[name] = status
+	}
+	return status
+}
+
+// phaseCounts returns the number of objects in each status.phase
+func phaseCounts(items []unstructured.Unstructured) map[string]uint64 {
+	phases := make(map[string]uint64)
+	for i := range items {
+		phases[phase(&items[i])]++
+	}
+	return phases
+}
+
+// phase returns the status.phase of a backup or restore, New if it has not been set yet
+func phase(obj *unstructured.Unstructured) string {
+	p, found, err := unstructured.NestedString(obj.Object, "status", "phase")
+	if err != nil || !found || p == "" {
+		return "New"
+	}
+	return p
+}
@@ -0,0 +1,80 @@
+// Copyright © 2019 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+package velero
+
+import (
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func backup(schedule, phase string, created time.Time, completed string) unstructured.Unstructured {
+	b := unstructured.Unstructured{Object: map[string]interface{}{}}
+	if schedule != "" {
+		b.SetLabels(map[string]string{scheduleLabel: schedule})
+	}
+	b.SetCreationTimestamp(metav1.NewTime(created))
+	status := map[string]interface{}{}
+	if phase != "" {
+		status["phase"] = phase
+	}
+	if completed != "" {
+		status["completionTimestamp"] = completed
+	}
+	b.Object["status"] = status
+	return b
+}
+
+func TestBackupSummary(t *testing.T) {
+	day1 := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	day2 := day1.Add(24 * time.Hour)
+	day3 := day2.Add(24 * time.Hour)
+
+	backups := []unstructured.Unstructured{
+		backup("daily", "Completed", day1, "2020-01-01T00:10:00Z"),
+		backup("daily", "Failed", day1.Add(-time.Hour), ""), // before last success
+		backup("daily", "Completed", day2, "2020-01-02T00:10:00Z"),
+		backup("daily", "PartiallyFailed", day3, ""),
+		backup("", "Failed", day1, ""),
+		backup("", "", day3, ""),
+	}
+
+	phases, schedules := backupSummary(backups)
+
+	wantPhases := map[string]uint64{"Completed": 2, "Failed": 2, "PartiallyFailed": 1, "New": 1}
+	if len(phases) != len(wantPhases) {
+		t.Fatalf("phases = %v, want %v", phases, wantPhases)
+	}
+	for p, n := range wantPhases {
+		if phases[p] != n {
+			t.Errorf("phases[%s] = %d, want %d", p, phases[p], n)
+		}
+	}
+
+	daily, ok := schedules["daily"]
+	if !ok {
+		t.Fatal("expected daily schedule")
+	}
+	if want := day2.Add(10 * time.Minute); !daily.lastSuccess.Equal(want) {
+		t.Errorf("daily last success = %s, want %s", daily.lastSuccess, want)
+	}
+	if daily.failures != 1 {
+		t.Errorf("daily failures = %d, want 1", daily.failures)
+	}
+
+	manual, ok := schedules["none"]
+	if !ok {
+		t.Fatal("expected none schedule")
+	}
+	if !manual.lastSuccess.IsZero() {
+		t.Errorf("manual last success = %s, want zero", manual.lastSuccess)
+	}
+	if manual.failures != 1 {
+		t.Errorf("manual failures = %d, want 1", manual.failures)
+	}
+}
@@ -0,0 +1,307 @@
+// Copyright © 2019 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+// Package workloads is the workload (deployment, statefulset, daemonset) status collector
+package workloads
+
+import (
+	"context"
+	"crypto/tls"
+	"sync"
+	"time"
+
+	cgm "github.com/circonus-labs/circonus-gometrics/v3"
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/circonus"
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/config"
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/config/defaults"
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/k8s"
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/release"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+type Workloads struct {
+	config       *config.Cluster
+	check        *circonus.Check
+	log          zerolog.Logger
+	clientset    kubernetes.Interface
+	running      bool
+	apiTimelimit time.Duration
+	sync.Mutex
+	ts *time.Time
+}
+
+// NOTES:
+// Workload resources are listed from the api-server, each kind is enabled independently. This does
+// not rely on kube-state-metrics being installed in the cluster.
+
+func New(cfg *config.Cluster, parentLog zerolog.Logger, check *circonus.Check) (*Workloads, error) {
+	if cfg == nil {
+		return nil, errors.New("invalid cluster config (nil)")
+	}
+	if check == nil {
+		return nil, errors.New("invalid check (nil)")
+	}
+
+	w := &Workloads{
+		config: cfg,
+		check:  check,
+		log:    parentLog.With().Str("collector", "workloads").Logger(),
+	}
+
+	if cfg.APITimelimit != "" {
+		v, err := time.ParseDuration(cfg.APITimelimit)
+		if err != nil {
+			w.log.Error().Err(err).Msg("parsing api timelimit, using default")
+		} else {
+			w.apiTimelimit = v
+		}
+	}
+
+	if w.apiTimelimit == time.Duration(0) {
+		v, err := time.ParseDuration(defaults.K8SAPITimelimit)
+		if err != nil {
+			w.log.Fatal().Err(err).Msg("parsing DEFAULT api timelimit")
+		}
+		w.apiTimelimit = v
+	}
+
+	restCfg, err := k8s.RESTConfig(cfg)
+	if err != nil {
+		return nil, errors.Wrap(err, "rest config")
+	}
+	restCfg.Timeout = w.apiTimelimit
+	clientset, err := kubernetes.NewForConfig(restCfg)
+	if err != nil {
+		return nil, errors.Wrap(err, "clientset")
+	}
+	w.clientset = clientset
+
+	return w, nil
+}
+
+func (w *Workloads) ID() string {
+	return "workloads"
+}
+
+// Collect workload status
+func (w *Workloads) Collect(ctx context.Context, _ *tls.Config, ts *time.Time) {
+	w.Lock()
+	if w.running {
+		w.log.Warn().Msg("already running")
+		w.Unlock()
+		return
+	}
+	w.running = true
+	w.ts = ts
+	w.Unlock()
+
+	defer func() {
+		if r := recover(); r != nil {
+			w.log.Error().Interface("panic", r).Msg("recover")
+			w.Lock()
+			w.running = false
+			w.Unlock()
+		}
+	}()
+
+	collectStart := time.Now()
+
+	w.deploymentMetrics(ctx)
+	w.statefulSetMetrics(ctx)
+	w.daemonSetMetrics(ctx)
+
+	w.check.AddHistSample("collect_latency", cgm.Tags{
+		cgm.Tag{Category: "source", Value: release.NAME},
+		cgm.Tag{Category: "op", Value: "collect_workloads"},
+		cgm.Tag{Category: "units", Value: "milliseconds"},
+	}, float64(time.Since(collectStart).Milliseconds()))
+	w.log.Debug().Str("duration", time.Since(collectStart).String()).Msg("workloads collect end")
+	w.Lock()
+	w.running = false
+	w.Unlock()
+}
+
+// deploymentMetrics emits desired/ready/updated/available/unavailable replicas and whether
+// the rollout is stuck (progress deadline exceeded) for each deployment
+func (w *Workloads) deploymentMetrics(ctx context.Context) {
+	if !w.config.EnableDeployments {
+		return
+	}
+
+	deployments, err := w.clientset.AppsV1().Deployments(metav1.NamespaceAll).List(metav1.ListOptions{})
+	if err != nil {
+		w.apiError("deployment-list")
+		w.log.Error().Err(err).Msg("listing deployments")
+		return
+	}
+
+	metrics := make(map[string]circonus.MetricSample)
+	for i := range deployments.Items {
+		d := &deployments.Items[i]
+		streamTags := []string{
+			"source:workloads",
+			"source_type:deployment",
+			"namespace:" + d.Namespace,
+			"deployment:" + d.Name,
+			"__rollup:false", // prevent high cardinality metrics from rolling up
+		}
+		desired := int32(1)
+		if d.Spec.Replicas != nil {
+			desired = *d.Spec.Replicas
+		}
+		stuck := uint64(0)
+		if rolloutStuck(d) {
+			stuck = 1
+		}
+		paused := uint64(0)
+		if d.Spec.Paused {
+			paused = 1
+		}
+		_ = w.check.QueueMetricSample(metrics, "deployment_replicas_desired", circonus.MetricTypeInt32, streamTags, []string{}, desired, w.ts)
+		_ = w.check.QueueMetricSample(metrics, "deployment_replicas_ready", circonus.MetricTypeInt32, streamTags, []string{}, d.Status.ReadyReplicas, w.ts)
+		_ = w.check.QueueMetricSample(metrics, "deployment_replicas_updated", circonus.MetricTypeInt32, streamTags, []string{}, d.Status.UpdatedReplicas, w.ts)
+		_ = w.check.QueueMetricSample(metrics, "deployment_replicas_available", circonus.MetricTypeInt32, streamTags, []string{}, d.Status.AvailableReplicas, w.ts)
+		_ = w.check.QueueMetricSample(metrics, "deployment_replicas_unavailable", circonus.MetricTypeInt32, streamTags, []string{}, d.Status.UnavailableReplicas, w.ts)
+		_ = w.check.QueueMetricSample(metrics, "deployment_rollout_stuck", circonus.MetricTypeUint64, streamTags, []string{}, stuck, w.ts)
+		_ = w.check.QueueMetricSample(metrics, "deployment_paused", circonus.MetricTypeUint64, streamTags, []string{}, paused, w.ts)
+	}
+
+	w.submit(ctx, metrics, "deployments")
+}
+
+// statefulSetMetrics emits desired/ready/current/updated replicas, whether an update to a new
+// revision is pending, and the number of pods waiting on ordinal ordering for each statefulset
+func (w *Workloads) statefulSetMetrics(ctx context.Context) {
+	if !w.config.EnableStatefulSets {
+		return
+	}
+
+	statefulSets, err := w.clientset.AppsV1().StatefulSets(metav1.NamespaceAll).List(metav1.ListOptions{})
+	if err != nil {
+		w.apiError("statefulset-list")
+		w.log.Error().Err(err).Msg("listing statefulsets")
+		return
+	}
+
+	metrics := make(map[string]circonus.MetricSample)
+	for i := range statefulSets.Items {
+		ss := &statefulSets.Items[i]
+		streamTags := []string{
+			"source:workloads",
+			"source_type:statefulset",
+			"namespace:" + ss.Namespace,
+			"statefulset:" + ss.Name,
+			"__rollup:false", // prevent high cardinality metrics from rolling up
+		}
+		desired := int32(1)
+		if ss.Spec.Replicas != nil {
+			desired = *ss.Spec.Replicas
+		}
+		updatePending := uint64(0)
+		if ss.Status.UpdateRevision != "" && ss.Status.CurrentRevision != ss.Status.UpdateRevision {
+			updatePending = 1
+		}
+		_ = w.check.QueueMetricSample(metrics, "statefulset_replicas_desired", circonus.MetricTypeInt32, streamTags, []string{}, desired, w.ts)
+		_ = w.check.QueueMetricSample(metrics, "statefulset_replicas", circonus.MetricTypeInt32, streamTags, []string{}, ss.Status.Replicas, w.ts)
+		_ = w.check.QueueMetricSample(metrics, "statefulset_replicas_ready", circonus.MetricTypeInt32, streamTags, []string{}, ss.Status.ReadyReplicas, w.ts)
+		_ = w.check.QueueMetricSample(metrics, "statefulset_replicas_current", circonus.MetricTypeInt32, streamTags, []string{}, ss.Status.CurrentReplicas, w.ts)
+		_ = w.check.QueueMetricSample(metrics, "statefulset_replicas_updated", circonus.MetricTypeInt32, streamTags, []string{}, ss.Status.UpdatedReplicas, w.ts)
+		_ = w.check.QueueMetricSample(metrics, "statefulset_update_pending", circonus.MetricTypeUint64, streamTags, []string{}, updatePending, w.ts)
+		_ = w.check.QueueMetricSample(metrics, "statefulset_ordinal_waiting", circonus.MetricTypeInt32, streamTags, []string{}, ordinalWaiting(ss), w.ts)
+	}
+
+	w.submit(ctx, metrics, "statefulsets")
+}
+
+// daemonSetMetrics emits desired vs current/ready/available/updated pod counts, pods
+// running on nodes they should not be (misscheduled), and the number of nodes missing a
+// ready pod for each daemonset
+func (w *Workloads) daemonSetMetrics(ctx context.Context) {
+	if !w.config.EnableDaemonSets {
+		return
+	}
+
+	daemonSets, err := w.clientset.AppsV1().DaemonSets(metav1.NamespaceAll).List(metav1.ListOptions{})
+	if err != nil {
+		w.apiError("daemonset-list")
+		w.log.Error().Err(err).Msg("listing daemonsets")
+		return
+	}
+
+	metrics := make(map[string]circonus.MetricSample)
+	for i := range daemonSets.Items {
+		ds := &daemonSets.Items[i]
+		streamTags := []string{
+			"source:workloads",
+			"source_type:daemonset",
+			"namespace:" + ds.Namespace,
+			"daemonset:" + ds.Name,
+			"__rollup:false", // prevent high cardinality metrics from rolling up
+		}
+		missing := ds.Status.DesiredNumberScheduled - ds.Status.NumberReady
+		if missing < 0 {
+			missing = 0
+		}
+		_ = w.check.QueueMetricSample(metrics, "daemonset_pods_desired", circonus.MetricTypeInt32, streamTags, []string{}, ds.Status.DesiredNumberScheduled, w.ts)
+		_ = w.check.QueueMetricSample(metrics, "daemonset_pods_current", circonus.MetricTypeInt32, streamTags, []string{}, ds.Status.CurrentNumberScheduled, w.ts)
+		_ = w.check.QueueMetricSample(metrics, "daemonset_pods_ready", circonus.MetricTypeInt32, streamTags, []string{}, ds.Status.NumberReady, w.ts)
+		_ = w.check.QueueMetricSample(metrics, "daemonset_pods_available", circonus.MetricTypeInt32, streamTags, []string{}, ds.Status.NumberAvailable, w.ts)
+		_ = w.check.QueueMetricSample(metrics, "daemonset_pods_updated", circonus.MetricTypeInt32, streamTags, []string{}, ds.Status.UpdatedNumberScheduled, w.ts)
+		_ = w.check.QueueMetricSample(metrics, "daemonset_pods_misscheduled", circonus.MetricTypeInt32, streamTags, []string{}, ds.Status.NumberMisscheduled, w.ts)
+		_ = w.check.QueueMetricSample(metrics, "daemonset_pods_missing", circonus.MetricTypeInt32, streamTags, []string{}, missing, w.ts)
+	}
+
+	w.submit(ctx, metrics, "daemonsets")
+}
+
+// submit sends the queued metrics for a workload kind
+func (w *Workloads) submit(ctx context.Context, metrics map[string]circonus.MetricSample, kind string) {
+	if len(metrics) == 0 {
+		return
+	}
+	if err := w.check.SubmitQueue(ctx, metrics, w.log.With().Str("type", kind).Logger()); err != nil {
+		w.log.Warn().Err(err).Msg("submitting metrics")
+	}
+}
+
+func (w *Workloads) apiError(request string) {
+	w.check.IncrementCounter("collect_api_errors", cgm.Tags{
+		cgm.Tag{Category: "source", Value: release.NAME},
+		cgm.Tag{Category: "request", Value: request},
+		cgm.Tag{Category: "target", Value: "api-server"},
+	})
+}
+
+// rolloutStuck returns true if the deployment controller has marked the rollout as
+// not progressing because the progress deadline was exceeded
+func rolloutStuck(d *appsv1.Deployment) bool {
+	for _, cond := range d.Status.Conditions {
+		if cond.Type == appsv1.DeploymentProgressing {
+			return cond.Status == corev1.ConditionFalse && cond.Reason == "ProgressDeadlineExceeded"
+		}
+	}
+	return false
+}
+
+// ordinalWaiting returns the number of pods an OrderedReady statefulset has not created
+// because it is waiting for a lower ordinal pod to become ready
+func ordinalWaiting(ss *appsv1.StatefulSet) int32 {
+	if ss.Spec.PodManagementPolicy == appsv1.ParallelPodManagement {
+		return 0
+	}
+	desired := int32(1)
+	if ss.Spec.Replicas != nil {
+		desired = *ss.Spec.Replicas
+	}
+	if ss.Status.Replicas >= desired || ss.Status.ReadyReplicas >= ss.Status.Replicas {
+		return 0 // scaled up, or not blocked on an unready pod
+	}
+	return desired - ss.Status.Replicas
+}
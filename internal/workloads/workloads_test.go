@@ -0,0 +1,67 @@
+// Copyright © 2019 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+package workloads
+
+import (
+	"testing"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+)
+
+func TestRolloutStuck(t *testing.T) {
+	tests := []struct {
+		name  string
+		conds []appsv1.DeploymentCondition
+		want  bool
+	}{
+		{"no conditions", nil, false},
+		{"progressing", []appsv1.DeploymentCondition{{Type: appsv1.DeploymentProgressing, Status: corev1.ConditionTrue, Reason: "NewReplicaSetAvailable"}}, false},
+		{"deadline exceeded", []appsv1.DeploymentCondition{
+			{Type: appsv1.DeploymentAvailable, Status: corev1.ConditionFalse, Reason: "MinimumReplicasUnavailable"},
+			{Type: appsv1.DeploymentProgressing, Status: corev1.ConditionFalse, Reason: "ProgressDeadlineExceeded"},
+		}, true},
+	}
+
+	for _, tst := range tests {
+		tst := tst
+		t.Run(tst.name, func(t *testing.T) {
+			d := &appsv1.Deployment{Status: appsv1.DeploymentStatus{Conditions: tst.conds}}
+			if got := rolloutStuck(d); got != tst.want {
+				t.Errorf("rolloutStuck() = %t, want %t", got, tst.want)
+			}
+		})
+	}
+}
+
+func TestOrdinalWaiting(t *testing.T) {
+	three := int32(3)
+
+	tests := []struct {
+		name   string
+		policy appsv1.PodManagementPolicyType
+		status appsv1.StatefulSetStatus
+		want   int32
+	}{
+		{"scaled", appsv1.OrderedReadyPodManagement, appsv1.StatefulSetStatus{Replicas: 3, ReadyReplicas: 3}, 0},
+		{"creating", appsv1.OrderedReadyPodManagement, appsv1.StatefulSetStatus{Replicas: 1, ReadyReplicas: 1}, 0},
+		{"waiting", appsv1.OrderedReadyPodManagement, appsv1.StatefulSetStatus{Replicas: 1, ReadyReplicas: 0}, 2},
+		{"parallel", appsv1.ParallelPodManagement, appsv1.StatefulSetStatus{Replicas: 1, ReadyReplicas: 0}, 0},
+	}
+
+	for _, tst := range tests {
+		tst := tst
+		t.Run(tst.name, func(t *testing.T) {
+			ss := &appsv1.StatefulSet{
+				Spec:   appsv1.StatefulSetSpec{Replicas: &three, PodManagementPolicy: tst.policy},
+				Status: tst.status,
+			}
+			if got := ordinalWaiting(ss); got != tst.want {
+				t.Errorf("ordinalWaiting() = %d, want %d", got, tst.want)
+			}
+		})
+	}
+}